@@ -0,0 +1,389 @@
+// Copyright (c) 2016-2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package agentserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/docker/distribution/manifest/manifestlist"
+	"github.com/uber-go/tally"
+
+	"github.com/uber/kraken/build-index/tagclient"
+	"github.com/uber/kraken/core"
+	"github.com/uber/kraken/lib/store"
+	"github.com/uber/kraken/lib/torrent/scheduler"
+	"github.com/uber/kraken/utils/closers"
+	"github.com/uber/kraken/utils/dockerutil"
+	"github.com/uber/kraken/utils/handler"
+	"github.com/uber/kraken/utils/httputil"
+	"github.com/uber/kraken/utils/log"
+)
+
+// Prewarm job and entry states.
+const (
+	prewarmStateRunning = "running"
+	prewarmStateDone    = "done"
+
+	prewarmEntryPending = "pending"
+	prewarmEntryDone    = "done"
+	prewarmEntryFailed  = "failed"
+)
+
+// prewarmEntry is an image to preload into the agent cache.
+type prewarmEntry struct {
+	// Namespace of the image blobs. Defaults to the repo of Ref if Ref is a
+	// tag; required if Ref is a digest.
+	Namespace string `json:"namespace"`
+
+	// Ref is either a "repo:tag" tag or the "sha256:<hex>" manifest digest.
+	Ref string `json:"ref"`
+}
+
+type prewarmRequest struct {
+	Entries []prewarmEntry `json:"entries"`
+}
+
+type prewarmEntryStatus struct {
+	prewarmEntry
+	State string `json:"state"`
+	Error string `json:"error,omitempty"`
+}
+
+type prewarmJobStatus struct {
+	ID      string               `json:"id"`
+	State   string               `json:"state"`
+	Entries []prewarmEntryStatus `json:"entries"`
+}
+
+func (s prewarmJobStatus) failed() bool {
+	for _, e := range s.Entries {
+		if e.State == prewarmEntryFailed {
+			return true
+		}
+	}
+	return false
+}
+
+// prewarmer preloads batches of images into the agent cache in the
+// background. Entries are downloaded with limited concurrency across all jobs
+// so prewarming does not starve on-demand downloads.
+type prewarmer struct {
+	stats       tally.Scope
+	cads        *store.CADownloadStore
+	sched       scheduler.ReloadableScheduler
+	tags        tagclient.Client
+	parser      *dockerutil.Parser
+	concurrency int
+	sem         chan struct{}
+
+	mu      sync.Mutex
+	jobs    map[string]*prewarmJobStatus
+	order   []string // Job ids, oldest first.
+	maxJobs int
+}
+
+func newPrewarmer(
+	config Config,
+	stats tally.Scope,
+	cads *store.CADownloadStore,
+	sched scheduler.ReloadableScheduler,
+	tags tagclient.Client) *prewarmer {
+
+	return &prewarmer{
+		stats:       stats.SubScope("prewarm"),
+		cads:        cads,
+		sched:       sched,
+		tags:        tags,
+		parser:      dockerutil.NewParser(config.Manifest),
+		concurrency: config.PrewarmConcurrency,
+		sem:         make(chan struct{}, config.PrewarmConcurrency),
+		jobs:        make(map[string]*prewarmJobStatus),
+		maxJobs:     config.PrewarmMaxJobs,
+	}
+}
+
+// prewarmJobID deterministically identifies a set of entries, so that
+// resubmitting the same batch returns the existing job.
+func prewarmJobID(entries []prewarmEntry) (string, error) {
+	lines := make([]string, len(entries))
+	for i, e := range entries {
+		lines[i] = e.Namespace + "\t" + e.Ref
+	}
+	sort.Strings(lines)
+	d, err := core.NewDigester().FromBytes([]byte(strings.Join(lines, "\n")))
+	if err != nil {
+		return "", err
+	}
+	return d.Hex(), nil
+}
+
+// start starts a job for entries, unless an identical job is running or has
+// succeeded. Jobs which finished with failures are restarted.
+func (p *prewarmer) start(entries []prewarmEntry) (string, error) {
+	id, err := prewarmJobID(entries)
+	if err != nil {
+		return "", fmt.Errorf("job id: %s", err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if job, ok := p.jobs[id]; ok {
+		if job.State == prewarmStateRunning || !job.failed() {
+			return id, nil
+		}
+		p.removeLocked(id)
+	}
+	job := &prewarmJobStatus{ID: id, State: prewarmStateRunning}
+	for _, e := range entries {
+		job.Entries = append(job.Entries, prewarmEntryStatus{prewarmEntry: e, State: prewarmEntryPending})
+	}
+	p.jobs[id] = job
+	p.order = append(p.order, id)
+	p.evictLocked()
+
+	go p.run(job)
+
+	return id, nil
+}
+
+// evictLocked removes the oldest finished jobs while over capacity.
+func (p *prewarmer) evictLocked() {
+	for i := 0; len(p.jobs) > p.maxJobs && i < len(p.order); {
+		id := p.order[i]
+		if p.jobs[id].State == prewarmStateDone {
+			p.removeLocked(id)
+		} else {
+			i++
+		}
+	}
+}
+
+func (p *prewarmer) removeLocked(id string) {
+	delete(p.jobs, id)
+	for i, o := range p.order {
+		if o == id {
+			p.order = append(p.order[:i], p.order[i+1:]...)
+			break
+		}
+	}
+}
+
+// status returns a copy of the status of job id.
+func (p *prewarmer) status(id string) (prewarmJobStatus, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	job, ok := p.jobs[id]
+	if !ok {
+		return prewarmJobStatus{}, false
+	}
+	s := *job
+	s.Entries = append([]prewarmEntryStatus(nil), job.Entries...)
+	return s, true
+}
+
+// run prewarms the entries of job with a pool of at most p.concurrency
+// workers. Workers also share p.sem with the workers of other jobs, which
+// bounds concurrency across all jobs.
+func (p *prewarmer) run(job *prewarmJobStatus) {
+	p.mu.Lock()
+	n := len(job.Entries)
+	p.mu.Unlock()
+
+	indices := make(chan int, n)
+	for i := 0; i < n; i++ {
+		indices <- i
+	}
+	close(indices)
+
+	workers := p.concurrency
+	if workers > n {
+		workers = n
+	}
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indices {
+				p.runEntry(job, i)
+			}
+		}()
+	}
+	wg.Wait()
+
+	p.mu.Lock()
+	job.State = prewarmStateDone
+	p.mu.Unlock()
+}
+
+func (p *prewarmer) runEntry(job *prewarmJobStatus, i int) {
+	p.sem <- struct{}{}
+	defer func() { <-p.sem }()
+
+	p.mu.Lock()
+	e := job.Entries[i].prewarmEntry
+	p.mu.Unlock()
+
+	err := p.prewarm(e)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err != nil {
+		log.With("job", job.ID, "namespace", e.Namespace, "ref", e.Ref).Errorf("Error prewarming image: %s", err)
+		p.stats.Counter("entry_failures").Inc(1)
+		job.Entries[i].State = prewarmEntryFailed
+		job.Entries[i].Error = err.Error()
+	} else {
+		p.stats.Counter("entry_successes").Inc(1)
+		job.Entries[i].State = prewarmEntryDone
+	}
+}
+
+// prewarm downloads the manifest of e and every blob it references. Manifest
+// lists are prewarmed for all platforms.
+func (p *prewarmer) prewarm(e prewarmEntry) error {
+	namespace, d, err := p.resolve(e)
+	if err != nil {
+		return err
+	}
+	return p.prewarmManifest(namespace, d)
+}
+
+func (p *prewarmer) resolve(e prewarmEntry) (string, core.Digest, error) {
+	if d, err := core.ParseSHA256Digest(e.Ref); err == nil {
+		return e.Namespace, d, nil
+	}
+	d, err := p.tags.Get(e.Ref)
+	if err != nil {
+		if err == tagclient.ErrTagNotFound {
+			return "", core.Digest{}, fmt.Errorf("tag %s not found", e.Ref)
+		}
+		return "", core.Digest{}, fmt.Errorf("get tag: %s", err)
+	}
+	namespace := e.Namespace
+	if namespace == "" {
+		namespace = e.Ref[:strings.LastIndex(e.Ref, ":")]
+	}
+	return namespace, d, nil
+}
+
+func (p *prewarmer) prewarmManifest(namespace string, d core.Digest) error {
+	if err := p.download(namespace, d); err != nil {
+		return fmt.Errorf("manifest %s: %s", d, err)
+	}
+	f, err := p.cads.Cache().GetFileReader(d.Hex())
+	if err != nil {
+		return fmt.Errorf("manifest %s: store: %s", d, err)
+	}
+	defer closers.Close(f)
+	manifest, _, err := p.parser.ParseManifest(f)
+	if err != nil {
+		return fmt.Errorf("manifest %s: %s", d, err)
+	}
+	refs, err := dockerutil.GetManifestReferences(manifest)
+	if err != nil {
+		return fmt.Errorf("manifest %s: %s", d, err)
+	}
+	_, isList := manifest.(*manifestlist.DeserializedManifestList)
+	for _, ref := range refs {
+		if isList {
+			if err := p.prewarmManifest(namespace, ref); err != nil {
+				return err
+			}
+		} else if err := p.download(namespace, ref); err != nil {
+			return fmt.Errorf("blob %s: %s", ref, err)
+		}
+	}
+	return nil
+}
+
+// download downloads d through p2p, unless it is already cached.
+func (p *prewarmer) download(namespace string, d core.Digest) error {
+	if _, err := p.cads.Cache().GetFileStat(d.Hex()); err == nil {
+		return nil
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("stat cache: %s", err)
+	}
+	return p.sched.Download(namespace, d)
+}
+
+// prewarmHandler starts a background job preloading every image in the
+// request body, and returns the job id. Resubmitting the same set of images
+// returns the existing job.
+func (s *Server) prewarmHandler(w http.ResponseWriter, r *http.Request) error {
+	defer closers.Close(r.Body)
+	var req prewarmRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return handler.Errorf("json decode: %s", err).Status(http.StatusBadRequest)
+	}
+	if len(req.Entries) == 0 {
+		return handler.Errorf("no entries").Status(http.StatusBadRequest)
+	}
+	if len(req.Entries) > s.config.PrewarmMaxEntries {
+		return handler.Errorf(
+			"%d entries exceeds limit of %d", len(req.Entries), s.config.PrewarmMaxEntries).
+			Status(http.StatusBadRequest)
+	}
+	for i, e := range req.Entries {
+		if err := validatePrewarmEntry(e); err != nil {
+			return handler.Errorf("entry %d: %s", i, err).Status(http.StatusBadRequest)
+		}
+	}
+	id, err := s.prewarm.start(req.Entries)
+	if err != nil {
+		return handler.Errorf("start prewarm: %s", err)
+	}
+	w.WriteHeader(http.StatusAccepted)
+	if err := json.NewEncoder(w).Encode(map[string]string{"id": id}); err != nil {
+		return handler.Errorf("json encode: %s", err)
+	}
+	return nil
+}
+
+func validatePrewarmEntry(e prewarmEntry) error {
+	if _, err := core.ParseSHA256Digest(e.Ref); err == nil {
+		if e.Namespace == "" {
+			return fmt.Errorf("namespace required for digest %s", e.Ref)
+		}
+		return nil
+	}
+	if i := strings.LastIndex(e.Ref, ":"); i <= 0 || i == len(e.Ref)-1 || strings.Contains(e.Ref[i:], "/") {
+		return fmt.Errorf("ref %q is neither a repo:tag nor a sha256 digest", e.Ref)
+	}
+	return nil
+}
+
+// getPrewarmStatusHandler returns the status of a prewarm job.
+func (s *Server) getPrewarmStatusHandler(w http.ResponseWriter, r *http.Request) error {
+	id, err := httputil.ParseParam(r, "id")
+	if err != nil {
+		return err
+	}
+	status, ok := s.prewarm.status(id)
+	if !ok {
+		return handler.ErrorStatus(http.StatusNotFound)
+	}
+	if err := json.NewEncoder(w).Encode(&status); err != nil {
+		return handler.Errorf("json encode: %s", err)
+	}
+	return nil
+}
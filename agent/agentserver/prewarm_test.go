@@ -0,0 +1,181 @@
+// Copyright (c) 2016-2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package agentserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/uber/kraken/build-index/tagclient"
+	"github.com/uber/kraken/core"
+	"github.com/uber/kraken/lib/store"
+	"github.com/uber/kraken/utils/dockerutil"
+	"github.com/uber/kraken/utils/httputil"
+	"github.com/uber/kraken/utils/testutil"
+)
+
+func postPrewarm(addr string, entries []prewarmEntry) (string, error) {
+	b, err := json.Marshal(prewarmRequest{Entries: entries})
+	if err != nil {
+		return "", err
+	}
+	resp, err := httputil.Post(
+		fmt.Sprintf("http://%s/preload/batch", addr),
+		httputil.SendBody(bytes.NewReader(b)),
+		httputil.SendAcceptedCodes(http.StatusAccepted))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	var result map[string]string
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", err
+	}
+	return result["id"], nil
+}
+
+func getPrewarmStatus(addr, id string) (prewarmJobStatus, error) {
+	var status prewarmJobStatus
+	resp, err := httputil.Get(fmt.Sprintf("http://%s/preload/batch/%s", addr, id))
+	if err != nil {
+		return status, err
+	}
+	defer resp.Body.Close()
+	err = json.NewDecoder(resp.Body).Decode(&status)
+	return status, err
+}
+
+func TestPrewarm(t *testing.T) {
+	require := require.New(t)
+
+	mocks, cleanup := newServerMocks(t)
+	defer cleanup()
+
+	layers := core.DigestListFixture(3)
+	manifest, manifestBytes := dockerutil.ManifestFixture(layers[0], layers[1], layers[2])
+
+	mocks.tags.EXPECT().Get("repo1:tag1").Return(manifest, nil)
+	mocks.tags.EXPECT().Get("repo2:missing").Return(core.Digest{}, tagclient.ErrTagNotFound)
+
+	mocks.sched.EXPECT().Download("repo1", manifest).DoAndReturn(
+		func(namespace string, d core.Digest) error {
+			return store.RunDownload(mocks.cads, d, manifestBytes)
+		})
+	for _, l := range layers {
+		mocks.sched.EXPECT().Download("repo1", l).DoAndReturn(
+			func(namespace string, d core.Digest) error {
+				return store.RunDownload(mocks.cads, d, []byte(d.Hex()))
+			})
+	}
+
+	_, addr := mocks.startServer(Config{})
+
+	entries := []prewarmEntry{
+		{Ref: "repo1:tag1"},
+		{Ref: "repo2:missing"},
+		// Already cached from the first entry, so no downloads are expected.
+		{Namespace: "repo1", Ref: manifest.String()},
+	}
+	id, err := postPrewarm(addr, entries[:2])
+	require.NoError(err)
+
+	var status prewarmJobStatus
+	require.NoError(testutil.PollUntilTrue(5*time.Second, func() bool {
+		status, err = getPrewarmStatus(addr, id)
+		return err == nil && status.State == prewarmStateDone
+	}))
+	require.Equal(prewarmEntryDone, status.Entries[0].State)
+	require.Equal(prewarmEntryFailed, status.Entries[1].State)
+	require.Contains(status.Entries[1].Error, "not found")
+
+	id, err = postPrewarm(addr, entries[2:])
+	require.NoError(err)
+	require.NoError(testutil.PollUntilTrue(5*time.Second, func() bool {
+		status, err = getPrewarmStatus(addr, id)
+		return err == nil && status.State == prewarmStateDone
+	}))
+	require.Equal(prewarmEntryDone, status.Entries[0].State)
+}
+
+func TestPrewarmSameEntriesReturnsSameJob(t *testing.T) {
+	require := require.New(t)
+
+	mocks, cleanup := newServerMocks(t)
+	defer cleanup()
+
+	// Cache the whole image so the job needs no downloads.
+	layers := core.DigestListFixture(3)
+	manifest, manifestBytes := dockerutil.ManifestFixture(layers[0], layers[1], layers[2])
+	require.NoError(store.RunDownload(mocks.cads, manifest, manifestBytes))
+	for _, l := range layers {
+		require.NoError(store.RunDownload(mocks.cads, l, []byte(l.Hex())))
+	}
+
+	_, addr := mocks.startServer(Config{})
+
+	a := prewarmEntry{Namespace: "repo1", Ref: manifest.String()}
+	b := prewarmEntry{Namespace: "repo2", Ref: manifest.String()}
+
+	id1, err := postPrewarm(addr, []prewarmEntry{a, b})
+	require.NoError(err)
+	id2, err := postPrewarm(addr, []prewarmEntry{b, a})
+	require.NoError(err)
+	require.Equal(id1, id2)
+
+	require.NoError(testutil.PollUntilTrue(5*time.Second, func() bool {
+		status, err := getPrewarmStatus(addr, id1)
+		return err == nil && status.State == prewarmStateDone && !status.failed()
+	}))
+}
+
+func TestPrewarmInvalidRequest(t *testing.T) {
+	mocks, cleanup := newServerMocks(t)
+	defer cleanup()
+
+	_, addr := mocks.startServer(Config{PrewarmMaxEntries: 2})
+
+	tooMany := []prewarmEntry{{Ref: "repo1:tag1"}, {Ref: "repo2:tag2"}, {Ref: "repo3:tag3"}}
+
+	tests := []struct {
+		desc    string
+		entries []prewarmEntry
+	}{
+		{"no entries", nil},
+		{"too many entries", tooMany},
+		{"digest without namespace", []prewarmEntry{{Ref: core.DigestFixture().String()}}},
+		{"malformed ref", []prewarmEntry{{Ref: "repo1"}}},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			_, err := postPrewarm(addr, test.entries)
+			require.True(t, httputil.IsStatus(err, http.StatusBadRequest))
+		})
+	}
+}
+
+func TestGetPrewarmStatusNotFound(t *testing.T) {
+	mocks, cleanup := newServerMocks(t)
+	defer cleanup()
+
+	_, addr := mocks.startServer(Config{})
+
+	_, err := getPrewarmStatus(addr, "unknown")
+	require.True(t, httputil.IsNotFound(err))
+}
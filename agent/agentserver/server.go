@@ -33,9 +33,12 @@ import (
 	"github.com/uber/kraken/lib/torrent/scheduler"
 	"github.com/uber/kraken/tracker/announceclient"
 	"github.com/uber/kraken/utils/closers"
+	"github.com/uber/kraken/utils/dockerutil"
 	"github.com/uber/kraken/utils/handler"
 	"github.com/uber/kraken/utils/httputil"
+	"github.com/uber/kraken/utils/rwutil"
 
+	"github.com/c2h5oh/datasize"
 	"github.com/go-chi/chi"
 	"github.com/uber-go/tally"
 )
@@ -44,6 +47,38 @@ import (
 type Config struct {
 	// How long a successful readiness check is valid for. If 0, disable caching successful readiness.
 	readinessCacheTTL time.Duration `yaml:"readiness_cache_ttl"`
+
+	// PrewarmConcurrency limits how many images are prewarmed at once across
+	// all prewarm jobs.
+	PrewarmConcurrency int `yaml:"prewarm_concurrency"`
+
+	// PrewarmMaxJobs limits how many prewarm jobs are tracked. The oldest
+	// finished jobs are forgotten first.
+	PrewarmMaxJobs int `yaml:"prewarm_max_jobs"`
+
+	// PrewarmMaxEntries limits how many images a single prewarm request may
+	// contain. Larger requests are rejected.
+	PrewarmMaxEntries int `yaml:"prewarm_max_entries"`
+
+	// CopyBufferSize is the size of the pooled buffers used to stream blobs to
+	// clients. If unset, blobs are streamed with io.Copy, as before.
+	CopyBufferSize datasize.ByteSize `yaml:"copy_buffer_size"`
+
+	// Manifest configures parsing of prewarmed image manifests.
+	Manifest dockerutil.ParserConfig `yaml:"manifest"`
+}
+
+func (c Config) applyDefaults() Config {
+	if c.PrewarmConcurrency == 0 {
+		c.PrewarmConcurrency = 1
+	}
+	if c.PrewarmMaxJobs == 0 {
+		c.PrewarmMaxJobs = 100
+	}
+	if c.PrewarmMaxEntries == 0 {
+		c.PrewarmMaxEntries = 1000
+	}
+	return c
 }
 
 // Server defines the agent HTTP server.
@@ -55,6 +90,8 @@ type Server struct {
 	tags             tagclient.Client
 	ac               announceclient.Client
 	containerRuntime containerruntime.Factory
+	prewarm          *prewarmer
+	copyBuffers      *rwutil.BufferPool
 	lastReady        time.Time
 }
 
@@ -68,6 +105,8 @@ func New(
 	ac announceclient.Client,
 	containerRuntime containerruntime.Factory) *Server {
 
+	config = config.applyDefaults()
+
 	stats = stats.Tagged(map[string]string{
 		"module": "agentserver",
 	})
@@ -80,6 +119,8 @@ func New(
 		tags:             tags,
 		ac:               ac,
 		containerRuntime: containerRuntime,
+		prewarm:          newPrewarmer(config, stats, cads, sched, tags),
+		copyBuffers:      rwutil.NewBufferPool(int(config.CopyBufferSize)),
 	}
 }
 
@@ -96,17 +137,31 @@ func (s *Server) Handler() http.Handler {
 	r.Get("/tags/{tag}", handler.Wrap(s.getTagHandler))
 
 	r.Get("/namespace/{namespace}/blobs/{digest}", handler.Wrap(s.downloadBlobHandler))
+	r.Get("/namespace/{namespace}/blobs/{digest}/bandwidth", handler.Wrap(s.getBandwidthHandler))
 
 	r.Delete("/blobs/{digest}", handler.Wrap(s.deleteBlobHandler))
 
 	// Preheat/preload endpoints.
 	r.Get("/preload/tags/{tag}", handler.Wrap(s.preloadTagHandler))
+	r.Post("/preload/batch", handler.Wrap(s.prewarmHandler))
+	r.Get("/preload/batch/{id}", handler.Wrap(s.getPrewarmStatusHandler))
 
 	// Dangerous endpoint for running experiments.
 	r.Patch("/x/config/scheduler", handler.Wrap(s.patchSchedulerConfigHandler))
 
 	r.Get("/x/blacklist", handler.Wrap(s.getBlacklistHandler))
 
+	// Announced peers which could not be connected to, for debugging network
+	// policies.
+	r.Get("/x/peers/unreachable", handler.Wrap(s.getUnreachablePeersHandler))
+
+	// Read-only diagnostics for stalled torrents.
+	r.Get("/x/torrents/{infohash}/dump", handler.Wrap(s.dumpTorrentHandler))
+
+	// Quiesces p2p activity for maintenance.
+	r.Post("/x/scheduler/pause", handler.Wrap(s.pauseSchedulerHandler))
+	r.Post("/x/scheduler/resume", handler.Wrap(s.resumeSchedulerHandler))
+
 	// Serves /debug/pprof endpoints.
 	r.Mount("/", http.DefaultServeMux)
 
@@ -159,12 +214,52 @@ func (s *Server) downloadBlobHandler(w http.ResponseWriter, r *http.Request) err
 			return handler.Errorf("store: %s", err)
 		}
 	}
-	if _, err := io.Copy(w, f); err != nil {
+	if _, err := s.copyBuffers.Copy(w, f); err != nil {
 		return fmt.Errorf("copy file: %s", err)
 	}
 	return nil
 }
 
+// bandwidthResponse is the p2p bandwidth a torrent has consumed on this agent.
+type bandwidthResponse struct {
+	Namespace       string `json:"namespace"`
+	Digest          string `json:"digest"`
+	BytesUploaded   int64  `json:"bytes_uploaded"`
+	BytesDownloaded int64  `json:"bytes_downloaded"`
+}
+
+// getBandwidthHandler returns the monotonic bytes uploaded and downloaded for
+// a blob's torrent in a namespace. Counters survive the torrent going idle
+// for a while, and are reset when the blob is deleted from the agent or the
+// agent restarts.
+func (s *Server) getBandwidthHandler(w http.ResponseWriter, r *http.Request) error {
+	namespace, err := httputil.ParseParam(r, "namespace")
+	if err != nil {
+		return err
+	}
+	d, err := parseDigest(r)
+	if err != nil {
+		return err
+	}
+	stats, err := s.sched.TorrentBandwidth(namespace, d)
+	if err != nil {
+		if err == scheduler.ErrTorrentNotFound {
+			return handler.ErrorStatus(http.StatusNotFound)
+		}
+		return handler.Errorf("torrent bandwidth: %s", err)
+	}
+	resp := bandwidthResponse{
+		Namespace:       namespace,
+		Digest:          d.String(),
+		BytesUploaded:   stats.BytesUploaded,
+		BytesDownloaded: stats.BytesDownloaded,
+	}
+	if err := json.NewEncoder(w).Encode(&resp); err != nil {
+		return handler.Errorf("json encode: %s", err)
+	}
+	return nil
+}
+
 func (s *Server) deleteBlobHandler(w http.ResponseWriter, r *http.Request) error {
 	d, err := parseDigest(r)
 	if err != nil {
@@ -287,6 +382,50 @@ func (s *Server) getBlacklistHandler(w http.ResponseWriter, r *http.Request) err
 	return nil
 }
 
+func (s *Server) getUnreachablePeersHandler(w http.ResponseWriter, r *http.Request) error {
+	peers := s.sched.UnreachablePeers()
+	if err := json.NewEncoder(w).Encode(&peers); err != nil {
+		return handler.Errorf("json encode: %s", err)
+	}
+	return nil
+}
+
+func (s *Server) dumpTorrentHandler(w http.ResponseWriter, r *http.Request) error {
+	raw, err := httputil.ParseParam(r, "infohash")
+	if err != nil {
+		return err
+	}
+	h, err := core.NewInfoHashFromHex(raw)
+	if err != nil {
+		return handler.Errorf("parse infohash: %s", err).Status(http.StatusBadRequest)
+	}
+	dump, err := s.sched.DumpTorrent(h)
+	if err != nil {
+		if err == scheduler.ErrTorrentNotFound {
+			return handler.ErrorStatus(http.StatusNotFound)
+		}
+		return handler.Errorf("dump torrent: %s", err)
+	}
+	if err := json.NewEncoder(w).Encode(&dump); err != nil {
+		return handler.Errorf("json encode: %s", err)
+	}
+	return nil
+}
+
+func (s *Server) pauseSchedulerHandler(w http.ResponseWriter, r *http.Request) error {
+	if err := s.sched.PauseAll(); err != nil {
+		return handler.Errorf("pause scheduler: %s", err)
+	}
+	return nil
+}
+
+func (s *Server) resumeSchedulerHandler(w http.ResponseWriter, r *http.Request) error {
+	if err := s.sched.ResumeAll(); err != nil {
+		return handler.Errorf("resume scheduler: %s", err)
+	}
+	return nil
+}
+
 func parseDigest(r *http.Request) (core.Digest, error) {
 	raw, err := httputil.ParseParam(r, "digest")
 	if err != nil {
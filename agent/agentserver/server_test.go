@@ -31,6 +31,7 @@ import (
 	"github.com/uber/kraken/lib/store"
 	"github.com/uber/kraken/lib/torrent/scheduler"
 	"github.com/uber/kraken/lib/torrent/scheduler/connstate"
+	"github.com/uber/kraken/lib/torrent/scheduler/dispatch"
 	mocktagclient "github.com/uber/kraken/mocks/build-index/tagclient"
 	mockcontainerruntime "github.com/uber/kraken/mocks/lib/containerruntime"
 	mockcontainerd "github.com/uber/kraken/mocks/lib/containerruntime/containerd"
@@ -146,6 +147,30 @@ func TestDownload(t *testing.T) {
 	require.Equal(string(blob.Content), string(result))
 }
 
+func TestDownloadWithCopyBufferSize(t *testing.T) {
+	require := require.New(t)
+
+	mocks, cleanup := newServerMocks(t)
+	defer cleanup()
+
+	namespace := core.TagFixture()
+	blob := core.SizedBlobFixture(1000, 100)
+
+	mocks.sched.EXPECT().Download(namespace, blob.Digest).DoAndReturn(
+		func(namespace string, d core.Digest) error {
+			return store.RunDownload(mocks.cads, d, blob.Content)
+		})
+
+	_, addr := mocks.startServer(Config{CopyBufferSize: 64})
+	c := agentclient.New(addr)
+
+	r, err := c.Download(namespace, blob.Digest)
+	require.NoError(err)
+	result, err := io.ReadAll(r)
+	require.NoError(err)
+	require.Equal(string(blob.Content), string(result))
+}
+
 func TestDownloadNotFound(t *testing.T) {
 	require := require.New(t)
 
@@ -425,6 +450,159 @@ func TestGetBlacklistHandler(t *testing.T) {
 	require.Equal(blacklist, result)
 }
 
+func TestGetUnreachablePeersHandler(t *testing.T) {
+	require := require.New(t)
+
+	mocks, cleanup := newServerMocks(t)
+	defer cleanup()
+
+	peers := []scheduler.UnreachablePeer{{
+		PeerID:      core.PeerIDFixture(),
+		Addr:        "10.0.0.1:8080",
+		Failures:    map[string]int{"refused": 2, "timeout": 1},
+		LastFailure: time.Now().Truncate(time.Second).UTC(),
+		LastError:   "dial: connection refused",
+	}}
+	mocks.sched.EXPECT().UnreachablePeers().Return(peers)
+
+	_, addr := mocks.startServer(Config{})
+
+	resp, err := httputil.Get(fmt.Sprintf("http://%s/x/peers/unreachable", addr))
+	require.NoError(err)
+
+	var result []scheduler.UnreachablePeer
+	require.NoError(json.NewDecoder(resp.Body).Decode(&result))
+	require.Equal(peers, result)
+}
+
+func TestGetBandwidthHandler(t *testing.T) {
+	require := require.New(t)
+
+	mocks, cleanup := newServerMocks(t)
+	defer cleanup()
+
+	namespace := core.NamespaceFixture()
+	d := core.DigestFixture()
+
+	mocks.sched.EXPECT().TorrentBandwidth(namespace, d).Return(
+		dispatch.BandwidthStats{BytesUploaded: 10, BytesDownloaded: 20}, nil)
+
+	_, addr := mocks.startServer(Config{})
+
+	resp, err := httputil.Get(fmt.Sprintf(
+		"http://%s/namespace/%s/blobs/%s/bandwidth", addr, url.PathEscape(namespace), d))
+	require.NoError(err)
+
+	var result bandwidthResponse
+	require.NoError(json.NewDecoder(resp.Body).Decode(&result))
+	require.Equal(bandwidthResponse{
+		Namespace:       namespace,
+		Digest:          d.String(),
+		BytesUploaded:   10,
+		BytesDownloaded: 20,
+	}, result)
+}
+
+func TestGetBandwidthHandlerNotFound(t *testing.T) {
+	require := require.New(t)
+
+	mocks, cleanup := newServerMocks(t)
+	defer cleanup()
+
+	namespace := core.NamespaceFixture()
+	d := core.DigestFixture()
+
+	mocks.sched.EXPECT().TorrentBandwidth(namespace, d).Return(
+		dispatch.BandwidthStats{}, scheduler.ErrTorrentNotFound)
+
+	_, addr := mocks.startServer(Config{})
+
+	_, err := httputil.Get(fmt.Sprintf(
+		"http://%s/namespace/%s/blobs/%s/bandwidth", addr, url.PathEscape(namespace), d))
+	require.Error(err)
+	require.True(httputil.IsNotFound(err))
+}
+
+func TestDumpTorrentHandler(t *testing.T) {
+	require := require.New(t)
+
+	mocks, cleanup := newServerMocks(t)
+	defer cleanup()
+
+	h := core.InfoHashFixture()
+	dump := scheduler.TorrentDump{
+		TorrentDump: dispatch.TorrentDump{
+			InfoHash:      h.Hex(),
+			NumPieces:     2,
+			MissingPieces: []int{1},
+			PieceRequests: []dispatch.PieceRequestDump{{
+				Piece: 1, PeerID: core.PeerIDFixture().String(), Status: "pending", Age: time.Second,
+			}},
+			Peers: []dispatch.PeerDump{},
+		},
+		Namespace: core.NamespaceFixture(),
+		Conns:     []scheduler.ConnDump{{PeerID: core.PeerIDFixture().String(), Status: "active"}},
+	}
+	mocks.sched.EXPECT().DumpTorrent(h).Return(dump, nil)
+
+	_, addr := mocks.startServer(Config{})
+
+	resp, err := httputil.Get(fmt.Sprintf("http://%s/x/torrents/%s/dump", addr, h.Hex()))
+	require.NoError(err)
+
+	var result scheduler.TorrentDump
+	require.NoError(json.NewDecoder(resp.Body).Decode(&result))
+	require.Equal(dump, result)
+}
+
+func TestDumpTorrentHandlerErrors(t *testing.T) {
+	require := require.New(t)
+
+	mocks, cleanup := newServerMocks(t)
+	defer cleanup()
+
+	h := core.InfoHashFixture()
+	mocks.sched.EXPECT().DumpTorrent(h).Return(scheduler.TorrentDump{}, scheduler.ErrTorrentNotFound)
+
+	_, addr := mocks.startServer(Config{})
+
+	_, err := httputil.Get(fmt.Sprintf("http://%s/x/torrents/%s/dump", addr, h.Hex()))
+	require.True(httputil.IsNotFound(err))
+
+	_, err = httputil.Get(fmt.Sprintf("http://%s/x/torrents/not-hex/dump", addr))
+	require.True(httputil.IsStatus(err, 400))
+}
+
+func TestPauseAndResumeSchedulerHandlers(t *testing.T) {
+	require := require.New(t)
+
+	mocks, cleanup := newServerMocks(t)
+	defer cleanup()
+
+	_, addr := mocks.startServer(Config{})
+
+	mocks.sched.EXPECT().PauseAll().Return(nil)
+	_, err := httputil.Post(fmt.Sprintf("http://%s/x/scheduler/pause", addr))
+	require.NoError(err)
+
+	mocks.sched.EXPECT().ResumeAll().Return(nil)
+	_, err = httputil.Post(fmt.Sprintf("http://%s/x/scheduler/resume", addr))
+	require.NoError(err)
+}
+
+func TestPauseSchedulerHandlerError(t *testing.T) {
+	require := require.New(t)
+
+	mocks, cleanup := newServerMocks(t)
+	defer cleanup()
+
+	_, addr := mocks.startServer(Config{})
+
+	mocks.sched.EXPECT().PauseAll().Return(scheduler.ErrSchedulerStopped)
+	_, err := httputil.Post(fmt.Sprintf("http://%s/x/scheduler/pause", addr))
+	require.True(httputil.IsStatus(err, 500))
+}
+
 func TestDeleteBlobHandler(t *testing.T) {
 	require := require.New(t)
 
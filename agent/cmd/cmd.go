@@ -27,11 +27,14 @@ import (
 	"github.com/uber/kraken/lib/containerruntime"
 	"github.com/uber/kraken/lib/containerruntime/dockerdaemon"
 	"github.com/uber/kraken/lib/dockerregistry/transfer"
+	"github.com/uber/kraken/lib/healthcheck"
 	"github.com/uber/kraken/lib/store"
 	"github.com/uber/kraken/lib/torrent/networkevent"
 	"github.com/uber/kraken/lib/torrent/scheduler"
+	"github.com/uber/kraken/lib/upstream"
 	"github.com/uber/kraken/metrics"
 	"github.com/uber/kraken/nginx"
+	"github.com/uber/kraken/origin/blobclient"
 	"github.com/uber/kraken/tracker/announceclient"
 	"github.com/uber/kraken/utils/closers"
 	"github.com/uber/kraken/utils/configutil"
@@ -192,8 +195,19 @@ func Run(flags *Flags, opts ...Option) {
 	}
 
 	announceClient := announceclient.New(pctx, trackers, tls)
+
+	var origins scheduler.OriginFetcher
+	if config.Scheduler.OriginFallback.Enabled {
+		originHosts, err := config.Origin.Build(upstream.WithHealthCheck(healthcheck.Default(tls)))
+		if err != nil {
+			log.Fatalf("Error building origin host list: %s", err)
+		}
+		origins = blobclient.NewClusterClient(
+			blobclient.NewClientResolver(blobclient.NewProvider(blobclient.WithTLS(tls)), originHosts))
+	}
+
 	sched, err := scheduler.NewAgentScheduler(
-		config.Scheduler, stats, pctx, cads, netevents, trackers, announceClient, tls)
+		config.Scheduler, stats, pctx, cads, netevents, trackers, announceClient, origins, tls)
 	if err != nil {
 		log.Fatalf("Error creating scheduler: %s", err)
 	}
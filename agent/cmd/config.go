@@ -41,6 +41,7 @@ type Config struct {
 	NetworkEvent     networkevent.Config            `yaml:"network_event"`
 	Tracker          upstream.PassiveHashRingConfig `yaml:"tracker"`
 	BuildIndex       upstream.PassiveConfig         `yaml:"build_index"`
+	Origin           upstream.ActiveConfig          `yaml:"origin"` // Only used by scheduler origin_fallback.
 	AgentServer      agentserver.Config             `yaml:"agentserver"`
 	RegistryBackup   string                         `yaml:"registry_backup"`
 	Nginx            nginx.Config                   `yaml:"nginx"`
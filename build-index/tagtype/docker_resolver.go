@@ -30,6 +30,8 @@ import (
 type dockerResolver struct {
 	originClient  blobclient.ClusterClient
 	backoffConfig httputil.ExponentialBackOffConfig
+	maxReferences int
+	parser        *dockerutil.Parser
 }
 
 // Resolve returns all layers + manifest of given tag as its dependencies.
@@ -38,7 +40,7 @@ func (r *dockerResolver) Resolve(tag string, d core.Digest) (core.DigestList, er
 	if err != nil {
 		return nil, fmt.Errorf("download manifest: %w", err)
 	}
-	deps, err := dockerutil.GetManifestReferences(m)
+	deps, err := dockerutil.GetManifestReferencesLimited(m, r.maxReferences)
 	if err != nil {
 		return nil, fmt.Errorf("get manifest references: %w", err)
 	}
@@ -74,7 +76,7 @@ func (r *dockerResolver) downloadManifest(tag string, d core.Digest) (distributi
 	if err := backoff.Retry(retryFunc, r.backoffConfig.Build()); err != nil {
 		return nil, err
 	}
-	manifest, _, err := dockerutil.ParseManifest(buf)
+	manifest, _, err := r.parser.ParseManifest(buf)
 	if err != nil {
 		return nil, fmt.Errorf("parse manifest: %w", err)
 	}
@@ -14,6 +14,7 @@
 package tagtype
 
 import (
+	"errors"
 	"io"
 	"testing"
 	"time"
@@ -45,6 +46,7 @@ func newTestDockerResolver(ctrl *gomock.Controller) (*dockerResolver, *mockblobc
 	resolver := &dockerResolver{
 		originClient:  originClient,
 		backoffConfig: backoffConfig,
+		parser:        dockerutil.NewParser(dockerutil.ParserConfig{}),
 	}
 
 	return resolver, originClient
@@ -240,3 +242,18 @@ func TestDockerResolver_Resolve_WithRetries(t *testing.T) {
 	require.NotNil(deps)
 	require.Equal(core.DigestList(append(layers, manifest)), deps)
 }
+
+func TestDockerResolver_Resolve_TooManyReferences(t *testing.T) {
+	require, _, resolver, mockOrigin, tag, _, manifest, manifestBytes := setupDockerResolverTestWithManifest(t)
+
+	resolver.maxReferences = 2
+
+	mockOrigin.EXPECT().
+		DownloadBlob(tag, manifest, mockutil.MatchWriter(manifestBytes)).
+		Return(nil)
+
+	deps, err := resolver.Resolve(tag, manifest)
+	require.Error(err)
+	require.Nil(deps)
+	require.True(errors.Is(err, dockerutil.ErrTooManyReferences))
+}
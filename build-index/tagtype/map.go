@@ -21,6 +21,7 @@ import (
 
 	"github.com/uber/kraken/core"
 	"github.com/uber/kraken/origin/blobclient"
+	"github.com/uber/kraken/utils/dockerutil"
 	"github.com/uber/kraken/utils/httputil"
 )
 
@@ -38,6 +39,13 @@ const (
 type Config struct {
 	Namespace string `yaml:"namespace"`
 	Type      string `yaml:"type"`
+
+	// MaxReferences limits the number of blobs a docker manifest may
+	// reference. Defaults to dockerutil.DefaultMaxManifestReferences.
+	MaxReferences int `yaml:"max_references"`
+
+	// Manifest configures parsing of docker manifests.
+	Manifest dockerutil.ParserConfig `yaml:"manifest"`
 }
 
 // DependencyResolver returns a list of blob dependencies for a tag->digest mapping.
@@ -77,7 +85,12 @@ func NewMap(configs []Config, originClient blobclient.ClusterClient) (*Map, erro
 				MaxInterval:         defaultMaxInterval,
 				MaxRetries:          defaultMaxRetries,
 			}
-			sr = &subResolver{re, &dockerResolver{originClient, backoffConfig}}
+			sr = &subResolver{re, &dockerResolver{
+				originClient:  originClient,
+				backoffConfig: backoffConfig,
+				maxReferences: config.MaxReferences,
+				parser:        dockerutil.NewParser(config.Manifest),
+			}}
 		case "default":
 			sr = &subResolver{re, &defaultResolver{}}
 		default:
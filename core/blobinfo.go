@@ -16,9 +16,13 @@ package core
 // BlobInfo contains metadata about a blob.
 type BlobInfo struct {
 	Size int64
+
+	// Version identifies the stored revision of the blob, for storage
+	// backends which support conditional writes. Empty if unknown.
+	Version string
 }
 
 // NewBlobInfo creates a new BlobInfo.
 func NewBlobInfo(size int64) *BlobInfo {
-	return &BlobInfo{size}
+	return &BlobInfo{Size: size}
 }
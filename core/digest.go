@@ -55,8 +55,10 @@ type Digest struct {
 }
 
 // NewSHA256DigestFromHex constructs a Digest from a sha256 in hexadecimal
-// format. Returns error if hex is not a valid sha256.
+// format. Returns error if hex is not a valid sha256. Uppercase hex is
+// normalized to lowercase.
 func NewSHA256DigestFromHex(hex string) (Digest, error) {
+	hex = strings.ToLower(hex)
 	if err := ValidateSHA256(hex); err != nil {
 		return Digest{}, fmt.Errorf("invalid sha256: %s", err)
 	}
@@ -67,8 +69,37 @@ func NewSHA256DigestFromHex(hex string) (Digest, error) {
 	}, nil
 }
 
+// NewChecksumDigest constructs a Digest from a checksum reported by a storage
+// backend, where algo is MD5 or CRC32C and hex is the hex encoded checksum.
+// Such digests must never be used to address content.
+func NewChecksumDigest(algo, hex string) (Digest, error) {
+	var size int
+	switch algo {
+	case MD5:
+		size = 32
+	case CRC32C:
+		size = 8
+	default:
+		return Digest{}, fmt.Errorf("unsupported checksum algo %q", algo)
+	}
+	hex = strings.ToLower(hex)
+	if len(hex) != size {
+		return Digest{}, fmt.Errorf("invalid %s: expected %d characters, got %d", algo, size, len(hex))
+	}
+	if err := validateHex(hex); err != nil {
+		return Digest{}, fmt.Errorf("invalid %s: %s", algo, err)
+	}
+	return Digest{
+		algo: algo,
+		hex:  hex,
+		raw:  fmt.Sprintf("%s:%s", algo, hex),
+	}, nil
+}
+
 // ParseSHA256Digest parses a raw "<algo>:<hex>" sha256 digest. Returns error if the
-// algo is not sha256 or the hex is not a valid sha256.
+// algo is not sha256 or the hex is not a valid sha256. Uppercase hex is
+// normalized to lowercase, so digests from noncompliant clients compare equal
+// to their canonical form.
 func ParseSHA256Digest(raw string) (Digest, error) {
 	if raw == "" {
 		return Digest{}, errors.New("invalid digest: empty")
@@ -78,7 +109,7 @@ func ParseSHA256Digest(raw string) (Digest, error) {
 		return Digest{}, errors.New("invalid digest: expected '<algo>:<hex>'")
 	}
 	algo := parts[0]
-	hex := parts[1]
+	hex := strings.ToLower(parts[1])
 	if algo != SHA256 {
 		return Digest{}, errors.New("invalid digest algo: expected sha256")
 	}
@@ -88,7 +119,39 @@ func ParseSHA256Digest(raw string) (Digest, error) {
 	return Digest{
 		algo: algo,
 		hex:  hex,
-		raw:  raw,
+		raw:  fmt.Sprintf("%s:%s", algo, hex),
+	}, nil
+}
+
+// ParseDigest parses a raw "<algo>:<hex>" digest of any algorithm valid for
+// OCI descriptors, i.e. sha256 or sha512. Returns error if the algo is
+// unsupported or the hex length does not match the algo. Uppercase hex is
+// normalized to lowercase, like ParseSHA256Digest.
+func ParseDigest(raw string) (Digest, error) {
+	algo, hex, ok := strings.Cut(raw, ":")
+	if !ok || strings.Contains(hex, ":") {
+		return Digest{}, errors.New("invalid digest: expected '<algo>:<hex>'")
+	}
+	var size int
+	switch algo {
+	case SHA256:
+		return ParseSHA256Digest(raw)
+	case SHA512:
+		size = 128
+	default:
+		return Digest{}, fmt.Errorf("invalid digest algo %q: expected sha256 or sha512", algo)
+	}
+	hex = strings.ToLower(hex)
+	if len(hex) != size {
+		return Digest{}, fmt.Errorf("invalid %s: expected %d characters, got %d", algo, size, len(hex))
+	}
+	if err := validateHex(hex); err != nil {
+		return Digest{}, fmt.Errorf("invalid %s: %s", algo, err)
+	}
+	return Digest{
+		algo: algo,
+		hex:  hex,
+		raw:  fmt.Sprintf("%s:%s", algo, hex),
 	}, nil
 }
 
@@ -160,6 +223,10 @@ func ValidateSHA256(s string) error {
 	if len(s) != 64 {
 		return fmt.Errorf("expected 64 characters, got %d from %q", len(s), s)
 	}
+	return validateHex(s)
+}
+
+func validateHex(s string) error {
 	if _, err := hex.DecodeString(s); err != nil {
 		return fmt.Errorf("hex: %s", err)
 	}
@@ -16,6 +16,7 @@ package core
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -77,6 +78,40 @@ func TestParseSHA256Digest(t *testing.T) {
 	require.Equal("e3b0", d.ShardID())
 }
 
+func TestParseSHA256DigestNormalizesCase(t *testing.T) {
+	require := require.New(t)
+
+	lower, err := ParseSHA256Digest("sha256:e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855")
+	require.NoError(err)
+	upper, err := ParseSHA256Digest("sha256:E3B0C44298FC1C149AFBF4C8996FB92427AE41E4649B934CA495991B7852B855")
+	require.NoError(err)
+	require.Equal(lower, upper)
+	require.Equal(lower.String(), upper.String())
+
+	fromHex, err := NewSHA256DigestFromHex("E3B0C44298FC1C149AFBF4C8996FB92427AE41E4649B934CA495991B7852B855")
+	require.NoError(err)
+	require.Equal(lower, fromHex)
+}
+
+func TestNewChecksumDigest(t *testing.T) {
+	require := require.New(t)
+
+	d, err := NewChecksumDigest(MD5, "D41D8CD98F00B204E9800998ECF8427E")
+	require.NoError(err)
+	require.Equal("md5:d41d8cd98f00b204e9800998ecf8427e", d.String())
+
+	d, err = NewChecksumDigest(CRC32C, "00000000")
+	require.NoError(err)
+	require.Equal("crc32c:00000000", d.String())
+
+	_, err = NewChecksumDigest(MD5, "00000000")
+	require.Error(err)
+	_, err = NewChecksumDigest(CRC32C, "0000000g")
+	require.Error(err)
+	_, err = NewChecksumDigest(SHA256, "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855")
+	require.Error(err)
+}
+
 func TestParseSHA256DigestErrors(t *testing.T) {
 	tests := []struct {
 		desc  string
@@ -87,6 +122,7 @@ func TestParseSHA256DigestErrors(t *testing.T) {
 		{"no algo", "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"},
 		{"wrong algo", "sha1:e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"},
 		{"invalid hex", "sha256:invalid"},
+		{"non-hex chars", "sha256:g3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"},
 	}
 	for _, test := range tests {
 		t.Run(test.desc, func(t *testing.T) {
@@ -96,6 +132,45 @@ func TestParseSHA256DigestErrors(t *testing.T) {
 	}
 }
 
+func TestParseDigest(t *testing.T) {
+	require := require.New(t)
+
+	d, err := ParseDigest("sha256:E3B0C44298FC1C149AFBF4C8996FB92427AE41E4649B934CA495991B7852B855")
+	require.NoError(err)
+	require.Equal("sha256", d.Algo())
+	expected, err := ParseSHA256Digest("sha256:e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855")
+	require.NoError(err)
+	require.Equal(expected, d)
+
+	hex := strings.Repeat("cf83e135", 16)
+	d, err = ParseDigest("sha512:" + strings.ToUpper(hex))
+	require.NoError(err)
+	require.Equal("sha512", d.Algo())
+	require.Equal(hex, d.Hex())
+	require.Equal("sha512:"+hex, d.String())
+}
+
+func TestParseDigestErrors(t *testing.T) {
+	tests := []struct {
+		desc  string
+		input string
+	}{
+		{"empty", ""},
+		{"no algo", "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"},
+		{"extra part", "sha512:sha512:" + strings.Repeat("a", 128)},
+		{"unsupported algo", "sha384:" + strings.Repeat("a", 96)},
+		{"sha256 length for sha512", "sha512:e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"},
+		{"sha512 length for sha256", "sha256:" + strings.Repeat("a", 128)},
+		{"non-hex chars", "sha512:" + strings.Repeat("g", 128)},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			_, err := ParseDigest(test.input)
+			require.Error(t, err)
+		})
+	}
+}
+
 func TestDigestStringConversion(t *testing.T) {
 	d := DigestFixture()
 	result, err := ParseSHA256Digest(d.String())
@@ -21,8 +21,16 @@ import (
 )
 
 const (
-	// SHA256 is the only algorithm supported.
+	// SHA256 is the only algorithm supported for content addressing.
 	SHA256 = "sha256"
+
+	// SHA512 is only accepted for digests referenced by manifests, which the
+	// OCI spec allows, see ParseDigest.
+	SHA512 = "sha512"
+
+	// MD5 and CRC32C are only used for checksums reported by storage backends.
+	MD5    = "md5"
+	CRC32C = "crc32c"
 )
 
 // Digester calculates the digest of data stream.
@@ -15,6 +15,7 @@ package core
 
 import (
 	"bytes"
+	"crypto/sha512"
 	"fmt"
 
 	"github.com/uber/kraken/utils/randutil"
@@ -99,6 +100,16 @@ func DigestFixture() Digest {
 	return NewBlobFixture().Digest
 }
 
+// SHA512DigestFixture returns a random sha512 Digest, as OCI descriptors may
+// reference.
+func SHA512DigestFixture() Digest {
+	d, err := ParseDigest(fmt.Sprintf("%s:%x", SHA512, sha512.Sum512(randutil.Blob(32))))
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
 // DigestListFixture returns a list of random Digests.
 func DigestListFixture(n int) []Digest {
 	var l DigestList
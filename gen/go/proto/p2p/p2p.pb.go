@@ -57,14 +57,17 @@ const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
 type ErrorMessage_ErrorCode int32
 
 const (
-	ErrorMessage_PIECE_REQUEST_FAILED ErrorMessage_ErrorCode = 0
+	ErrorMessage_PIECE_REQUEST_FAILED   ErrorMessage_ErrorCode = 0
+	ErrorMessage_PIECE_REQUEST_REJECTED ErrorMessage_ErrorCode = 1
 )
 
 var ErrorMessage_ErrorCode_name = map[int32]string{
 	0: "PIECE_REQUEST_FAILED",
+	1: "PIECE_REQUEST_REJECTED",
 }
 var ErrorMessage_ErrorCode_value = map[string]int32{
-	"PIECE_REQUEST_FAILED": 0,
+	"PIECE_REQUEST_FAILED":   0,
+	"PIECE_REQUEST_REJECTED": 1,
 }
 
 func (x ErrorMessage_ErrorCode) String() string {
@@ -294,46 +297,46 @@ func init() {
 func init() { proto.RegisterFile("proto/p2p/p2p.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 647 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0xac, 0x54, 0x4f, 0x6f, 0xd3, 0x4e,
-	0x10, 0x6d, 0x12, 0x3b, 0x7f, 0x26, 0x69, 0xeb, 0x6c, 0xa3, 0xdf, 0xcf, 0x14, 0x0e, 0x95, 0x45,
-	0x45, 0x85, 0xa0, 0xad, 0xcc, 0x05, 0x10, 0x12, 0x4a, 0x1c, 0x57, 0x44, 0x4a, 0x9b, 0xb0, 0xa4,
-	0x07, 0xc4, 0xa1, 0x72, 0x9d, 0x49, 0x6b, 0x91, 0x7a, 0x8d, 0xed, 0x56, 0xcd, 0xd7, 0xe0, 0x43,
-	0x21, 0xf1, 0xad, 0xd0, 0x4e, 0xec, 0xc4, 0x6e, 0x02, 0xe2, 0xc0, 0x21, 0x92, 0xdf, 0xf3, 0x7b,
-	0xb3, 0xb3, 0x33, 0x2f, 0x86, 0x9d, 0x20, 0x14, 0xb1, 0x38, 0x0a, 0xcc, 0x40, 0xfe, 0x0e, 0x09,
-	0xb1, 0x52, 0x60, 0x06, 0xc6, 0x8f, 0x22, 0x6c, 0x77, 0xbc, 0x78, 0xe2, 0xe1, 0x74, 0x7c, 0x8a,
-	0x51, 0xe4, 0x5c, 0x21, 0xdb, 0x85, 0xaa, 0xe7, 0x4f, 0xc4, 0x07, 0x27, 0xba, 0xd6, 0x8b, 0x7b,
-	0x85, 0x83, 0x1a, 0x5f, 0x60, 0xc6, 0x40, 0xf1, 0x9d, 0x1b, 0xd4, 0x4b, 0xc4, 0xd3, 0x33, 0xfb,
-	0x0f, 0xca, 0x01, 0x62, 0xd8, 0xeb, 0xea, 0x0a, 0xb1, 0x09, 0x62, 0x4f, 0x61, 0xf3, 0x32, 0x29,
-	0xdd, 0x99, 0xc5, 0x18, 0xe9, 0xea, 0x5e, 0xe1, 0xa0, 0xc1, 0xf3, 0x24, 0x7b, 0x02, 0x35, 0x59,
-	0x25, 0x0a, 0x1c, 0x17, 0xf5, 0x32, 0x15, 0x58, 0x12, 0xec, 0x02, 0x76, 0x42, 0xbc, 0x11, 0x31,
-	0x76, 0x72, 0x95, 0x2a, 0x7b, 0xa5, 0x83, 0xba, 0xf9, 0xf2, 0x50, 0xde, 0xe6, 0x41, 0xfb, 0x87,
-	0x7c, 0x55, 0x6f, 0xfb, 0x71, 0x38, 0xe3, 0xeb, 0x2a, 0xed, 0x9e, 0x80, 0xfe, 0x3b, 0x03, 0xd3,
-	0xa0, 0xf4, 0x15, 0x67, 0x7a, 0x81, 0x9a, 0x92, 0x8f, 0xac, 0x05, 0xea, 0x9d, 0x33, 0xbd, 0x45,
-	0x9a, 0x4b, 0x83, 0xcf, 0xc1, 0xdb, 0xe2, 0xeb, 0x82, 0xf1, 0x05, 0x76, 0x86, 0x1e, 0xba, 0xc8,
-	0xf1, 0xdb, 0x2d, 0x46, 0x71, 0x3a, 0xcb, 0x16, 0xa8, 0x9e, 0x3f, 0xc6, 0x7b, 0x32, 0xa8, 0x7c,
-	0x0e, 0xe4, 0xc4, 0xc4, 0x64, 0x12, 0x61, 0x4c, 0x73, 0x54, 0x79, 0x82, 0x24, 0x3f, 0x45, 0xff,
-	0x2a, 0xbe, 0xa6, 0x49, 0xaa, 0x3c, 0x41, 0x46, 0x94, 0x14, 0x1f, 0x3a, 0xb3, 0xa9, 0x70, 0xc6,
-	0xff, 0xb4, 0xb8, 0xe4, 0xc7, 0xde, 0x15, 0x46, 0x31, 0xed, 0xa7, 0xc6, 0x13, 0x64, 0xbc, 0x80,
-	0x56, 0xdb, 0xf7, 0xc5, 0xad, 0xef, 0x22, 0x1d, 0xfe, 0xc7, 0x53, 0x8d, 0xe7, 0xc0, 0x2c, 0xc7,
-	0x77, 0x71, 0xfa, 0x17, 0xda, 0xef, 0x05, 0x68, 0xd8, 0x61, 0x28, 0xc2, 0x8c, 0x0c, 0x25, 0x4e,
-	0xe2, 0x36, 0x07, 0x4b, 0x73, 0x29, 0x7b, 0xbd, 0x23, 0x50, 0x5c, 0x31, 0x46, 0xba, 0xc4, 0x96,
-	0xf9, 0x98, 0x22, 0x90, 0x2d, 0x36, 0x07, 0x96, 0x18, 0x23, 0x27, 0xa1, 0xb1, 0x0f, 0xb5, 0x05,
-	0xc5, 0x74, 0x68, 0x0d, 0x7b, 0xb6, 0x65, 0x5f, 0x70, 0xfb, 0xe3, 0xb9, 0xfd, 0x69, 0x74, 0x71,
-	0xd2, 0xee, 0xf5, 0xed, 0xae, 0xb6, 0x61, 0x34, 0x61, 0xdb, 0x12, 0x37, 0xc1, 0x14, 0xe3, 0xb4,
-	0x7b, 0xe3, 0xa7, 0x02, 0x95, 0xb4, 0x45, 0x1d, 0x2a, 0x77, 0x18, 0x46, 0x9e, 0xf0, 0x93, 0x3c,
-	0xa4, 0x90, 0xed, 0x83, 0x12, 0xcf, 0x82, 0x79, 0x24, 0xb6, 0xcc, 0x26, 0x35, 0x94, 0xf6, 0x32,
-	0x9a, 0x05, 0xc8, 0xe9, 0x35, 0x3b, 0x86, 0x6a, 0x1a, 0x7c, 0xba, 0x50, 0xdd, 0x6c, 0xad, 0x8b,
-	0x2f, 0x5f, 0xa8, 0xd8, 0x3b, 0x68, 0x04, 0x99, 0x48, 0xd1, 0x8d, 0xeb, 0xa6, 0x4e, 0xae, 0x35,
-	0x59, 0xe3, 0x39, 0xf5, 0xc2, 0x9d, 0x64, 0x86, 0x96, 0x9b, 0x73, 0xe7, 0xc3, 0xc4, 0x73, 0x6a,
-	0xf6, 0x1e, 0x36, 0x9d, 0xec, 0xf2, 0xe9, 0x9f, 0x59, 0x37, 0x1f, 0x91, 0x7d, 0x5d, 0x2c, 0x78,
-	0x5e, 0xcf, 0xde, 0x40, 0xdd, 0x5d, 0xe6, 0x41, 0xaf, 0x90, 0xfd, 0x7f, 0xb2, 0xaf, 0xe6, 0x84,
-	0x67, 0xb5, 0xec, 0x59, 0x9a, 0x86, 0x2a, 0x99, 0x9a, 0x2b, 0x2b, 0x4e, 0x03, 0x72, 0x0c, 0x55,
-	0x37, 0x59, 0x99, 0x5e, 0xcb, 0x8c, 0xf4, 0xc1, 0x1e, 0xf9, 0x42, 0x65, 0xdc, 0x83, 0x22, 0x57,
-	0xc2, 0x1a, 0x50, 0xed, 0xf4, 0x46, 0x27, 0x3d, 0xbb, 0xdf, 0xd5, 0x36, 0x58, 0x13, 0x36, 0x73,
-	0xa1, 0xd0, 0x0a, 0x4b, 0x6a, 0xd8, 0xfe, 0xdc, 0x1f, 0xb4, 0xbb, 0x5a, 0x51, 0x52, 0xed, 0xb3,
-	0xb3, 0xc1, 0xb9, 0x24, 0xe5, 0x2b, 0xad, 0xc4, 0x34, 0x68, 0x58, 0xed, 0x33, 0xcb, 0xee, 0x27,
-	0x8c, 0xc2, 0x6a, 0xa0, 0xda, 0x9c, 0x0f, 0xb8, 0xa6, 0xca, 0x33, 0xac, 0xc1, 0xe9, 0xb0, 0x6f,
-	0x8f, 0x6c, 0xad, 0x7c, 0x59, 0xa6, 0x8f, 0xee, 0xab, 0x5f, 0x01, 0x00, 0x00, 0xff, 0xff, 0xfd,
-	0x11, 0xd8, 0xe6, 0x8b, 0x05, 0x00, 0x00,
+	// 655 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xac, 0x54, 0x4f, 0x6f, 0xd3, 0x4e,
+	0x10, 0xfd, 0x39, 0xb1, 0xf3, 0x67, 0x92, 0xb6, 0xce, 0x36, 0xea, 0x6f, 0x29, 0x1c, 0x2a, 0x0b,
+	0x44, 0x85, 0xa0, 0xad, 0xcc, 0x05, 0x10, 0x12, 0x72, 0x9c, 0xad, 0x08, 0x4a, 0x9b, 0xb0, 0xa4,
+	0x07, 0xc4, 0xa1, 0x72, 0x9d, 0x49, 0x6b, 0x91, 0xda, 0xc6, 0x76, 0xab, 0xe6, 0x4b, 0xf1, 0x31,
+	0x90, 0xf8, 0x56, 0x68, 0x37, 0x76, 0x62, 0x37, 0x01, 0x71, 0xe0, 0x10, 0xc9, 0xef, 0xf9, 0xbd,
+	0xd9, 0x9d, 0x99, 0xe7, 0xc0, 0x76, 0x18, 0x05, 0x49, 0x70, 0x18, 0x9a, 0xa1, 0xf8, 0x1d, 0x48,
+	0x44, 0xca, 0xa1, 0x19, 0x1a, 0x3f, 0x4a, 0xb0, 0xd5, 0xf1, 0x92, 0x89, 0x87, 0xd3, 0xf1, 0x09,
+	0xc6, 0xb1, 0x73, 0x89, 0x64, 0x17, 0x6a, 0x9e, 0x3f, 0x09, 0xde, 0x3b, 0xf1, 0x15, 0x2d, 0xed,
+	0x29, 0xfb, 0x75, 0xbe, 0xc0, 0x84, 0x80, 0xea, 0x3b, 0xd7, 0x48, 0xcb, 0x92, 0x97, 0xcf, 0x64,
+	0x07, 0x2a, 0x21, 0x62, 0xd4, 0xeb, 0x52, 0x55, 0xb2, 0x29, 0x22, 0x8f, 0x61, 0xe3, 0x22, 0x2d,
+	0xdd, 0x99, 0x25, 0x18, 0x53, 0x6d, 0x4f, 0xd9, 0x6f, 0xf2, 0x22, 0x49, 0x1e, 0x41, 0x5d, 0x54,
+	0x89, 0x43, 0xc7, 0x45, 0x5a, 0x91, 0x05, 0x96, 0x04, 0x39, 0x87, 0xed, 0x08, 0xaf, 0x83, 0x04,
+	0x3b, 0x85, 0x4a, 0xd5, 0xbd, 0xf2, 0x7e, 0xc3, 0x7c, 0x71, 0x20, 0xba, 0xb9, 0x77, 0xfd, 0x03,
+	0xbe, 0xaa, 0x67, 0x7e, 0x12, 0xcd, 0xf8, 0xba, 0x4a, 0xbb, 0xc7, 0x40, 0x7f, 0x67, 0x20, 0x3a,
+	0x94, 0xbf, 0xe2, 0x8c, 0x2a, 0xf2, 0x52, 0xe2, 0x91, 0xb4, 0x41, 0xbb, 0x75, 0xa6, 0x37, 0x28,
+	0xe7, 0xd2, 0xe4, 0x73, 0xf0, 0xa6, 0xf4, 0x4a, 0x31, 0xbe, 0xc0, 0xf6, 0xd0, 0x43, 0x17, 0x39,
+	0x7e, 0xbb, 0xc1, 0x38, 0xc9, 0x66, 0xd9, 0x06, 0xcd, 0xf3, 0xc7, 0x78, 0x27, 0x0d, 0x1a, 0x9f,
+	0x03, 0x31, 0xb1, 0x60, 0x32, 0x89, 0x31, 0x91, 0x73, 0xd4, 0x78, 0x8a, 0x04, 0x3f, 0x45, 0xff,
+	0x32, 0xb9, 0x92, 0x93, 0xd4, 0x78, 0x8a, 0x8c, 0x38, 0x2d, 0x3e, 0x74, 0x66, 0xd3, 0xc0, 0x19,
+	0xff, 0xd3, 0xe2, 0x82, 0x1f, 0x7b, 0x97, 0x18, 0x27, 0x72, 0x3f, 0x75, 0x9e, 0x22, 0xe3, 0x39,
+	0xb4, 0x2d, 0xdf, 0x0f, 0x6e, 0x7c, 0x17, 0xe5, 0xe1, 0x7f, 0x3c, 0xd5, 0x78, 0x06, 0xc4, 0x76,
+	0x7c, 0x17, 0xa7, 0x7f, 0xa1, 0xfd, 0xae, 0x40, 0x93, 0x45, 0x51, 0x10, 0xe5, 0x64, 0x28, 0x70,
+	0x1a, 0xb7, 0x39, 0x58, 0x9a, 0xcb, 0xf9, 0xf6, 0x0e, 0x41, 0x75, 0x83, 0x31, 0xca, 0x26, 0x36,
+	0xcd, 0x87, 0x32, 0x02, 0xf9, 0x62, 0x73, 0x60, 0x07, 0x63, 0xe4, 0x52, 0x68, 0x58, 0x50, 0x5f,
+	0x50, 0x84, 0x42, 0x7b, 0xd8, 0x63, 0x36, 0x3b, 0xe7, 0xec, 0xe3, 0x19, 0xfb, 0x34, 0x3a, 0x3f,
+	0xb6, 0x7a, 0x7d, 0xd6, 0xd5, 0xff, 0x23, 0xbb, 0xb0, 0x53, 0x7c, 0xc3, 0xd9, 0x07, 0x66, 0x8f,
+	0x58, 0x57, 0x57, 0x8c, 0x16, 0x6c, 0xd9, 0xc1, 0x75, 0x38, 0xc5, 0x24, 0xeb, 0xcc, 0xf8, 0xa9,
+	0x42, 0x35, 0xbb, 0x3e, 0x85, 0xea, 0x2d, 0x46, 0xb1, 0x17, 0xf8, 0x69, 0x56, 0x32, 0x48, 0x9e,
+	0x80, 0x9a, 0xcc, 0xc2, 0x79, 0x5c, 0x36, 0xcd, 0x96, 0xbc, 0x6c, 0x76, 0xcf, 0xd1, 0x2c, 0x44,
+	0x2e, 0x5f, 0x93, 0x23, 0xa8, 0x65, 0x1f, 0x85, 0x6c, 0xb6, 0x61, 0xb6, 0xd7, 0x45, 0x9b, 0x2f,
+	0x54, 0xe4, 0x2d, 0x34, 0xc3, 0x5c, 0xdc, 0xe4, 0x34, 0x1a, 0x26, 0x95, 0xae, 0x35, 0x39, 0xe4,
+	0x05, 0xf5, 0xc2, 0x9d, 0xe6, 0x89, 0x6a, 0xf7, 0xdd, 0xc5, 0xa0, 0xf1, 0x82, 0x9a, 0xbc, 0x83,
+	0x0d, 0x27, 0x1f, 0x0c, 0xf9, 0xd5, 0x36, 0xcc, 0x07, 0xd2, 0xbe, 0x2e, 0x32, 0xbc, 0xa8, 0x27,
+	0xaf, 0xa1, 0xe1, 0x2e, 0xb3, 0x42, 0xab, 0xd2, 0xfe, 0xbf, 0xb4, 0xaf, 0x66, 0x88, 0xe7, 0xb5,
+	0xe4, 0x69, 0x96, 0x94, 0x9a, 0x34, 0xb5, 0x56, 0xd6, 0x9f, 0x85, 0xe7, 0x08, 0x6a, 0x6e, 0xba,
+	0x32, 0x5a, 0xcf, 0x8d, 0xf4, 0xde, 0x1e, 0xf9, 0x42, 0x65, 0xdc, 0x81, 0x2a, 0x56, 0x42, 0x9a,
+	0x50, 0xeb, 0xf4, 0x46, 0xc7, 0x3d, 0xd6, 0x17, 0xb1, 0x68, 0xc1, 0x46, 0x21, 0x16, 0xba, 0xb2,
+	0xa4, 0x86, 0xd6, 0xe7, 0xfe, 0xc0, 0xea, 0xea, 0x25, 0x41, 0x59, 0xa7, 0xa7, 0x83, 0x33, 0x41,
+	0x8a, 0x57, 0x7a, 0x99, 0xe8, 0xd0, 0xb4, 0xad, 0x53, 0x9b, 0xf5, 0x53, 0x46, 0x25, 0x75, 0xd0,
+	0x18, 0xe7, 0x03, 0xae, 0x6b, 0xe2, 0x0c, 0x7b, 0x70, 0x32, 0xec, 0xb3, 0x11, 0xd3, 0x2b, 0x17,
+	0x15, 0xf9, 0x87, 0xfc, 0xf2, 0xd7, 0x00, 0xcd, 0x46, 0x3a, 0x27, 0xa7, 0x05, 0x00, 0x00,
 }
@@ -17,3 +17,19 @@ import "errors"
 
 // ErrBlobNotFound is returned when a blob is not found in a storage backend.
 var ErrBlobNotFound = errors.New("blob not found")
+
+// ErrPresignNotSupported is returned when a storage backend cannot generate
+// presigned download URLs.
+var ErrPresignNotSupported = errors.New("presigned urls not supported")
+
+// ErrChecksumNotSupported is returned when a storage backend cannot report a
+// checksum of a blob without downloading it.
+var ErrChecksumNotSupported = errors.New("server-side checksums not supported")
+
+// ErrConditionalWriteNotSupported is returned when a storage backend cannot
+// check write preconditions atomically with uploads.
+var ErrConditionalWriteNotSupported = errors.New("conditional writes not supported")
+
+// ErrPreconditionFailed is returned when a conditional write is rejected
+// because its precondition does not hold.
+var ErrPreconditionFailed = errors.New("write precondition failed")
@@ -0,0 +1,43 @@
+// Copyright (c) 2016-2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package backend
+
+import (
+	"context"
+
+	"github.com/uber/kraken/core"
+	"github.com/uber/kraken/lib/backend/backenderrors"
+)
+
+// Checksummer is implemented by Clients which can report the checksum of a
+// blob from stored metadata, without downloading it.
+//
+// Checksums are whatever the storage backend keeps, e.g. an md5 or crc32c,
+// not the sha256 blobs are addressed by, and so can only be compared to
+// checksums of the same algorithm.
+type Checksummer interface {
+	// Checksum returns the stored checksum of name. Returns
+	// backenderrors.ErrChecksumNotSupported if name has no usable checksum.
+	Checksum(ctx context.Context, name string) (core.Digest, error)
+}
+
+// Checksum returns the stored checksum of name from c. Returns
+// backenderrors.ErrChecksumNotSupported if c does not implement Checksummer.
+func Checksum(ctx context.Context, c Client, name string) (core.Digest, error) {
+	s, ok := c.(Checksummer)
+	if !ok {
+		return core.Digest{}, backenderrors.ErrChecksumNotSupported
+	}
+	return s.Checksum(ctx, name)
+}
@@ -0,0 +1,82 @@
+// Copyright (c) 2016-2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package backend
+
+import (
+	"errors"
+	"io"
+
+	"github.com/uber/kraken/lib/backend/backenderrors"
+)
+
+// WriteCondition is a precondition on the current state of a blob, which is
+// checked atomically with an upload. At most one field may be set.
+type WriteCondition struct {
+	// IfAbsent only writes the blob if it does not exist.
+	IfAbsent bool
+
+	// IfMatch only writes the blob if its current version, as reported by
+	// core.BlobInfo.Version, matches. Versions are backend specific.
+	IfMatch string
+}
+
+// Validate returns an error if c sets more than one precondition.
+func (c WriteCondition) Validate() error {
+	if c.IfAbsent && c.IfMatch != "" {
+		return errors.New("if_absent and if_match are mutually exclusive")
+	}
+	return nil
+}
+
+// ConditionalUploader is implemented by Clients which support conditional
+// writes.
+type ConditionalUploader interface {
+	// UploadConditional uploads src into name if cond holds. Returns
+	// backenderrors.ErrPreconditionFailed if it does not.
+	UploadConditional(namespace, name string, src io.Reader, cond WriteCondition) error
+}
+
+// UploadConditional uploads src into name using c if cond holds. Returns
+// backenderrors.ErrConditionalWriteNotSupported, without reading src, if c
+// does not implement ConditionalUploader.
+func UploadConditional(
+	c Client, namespace, name string, src io.Reader, cond WriteCondition) error {
+
+	if err := cond.Validate(); err != nil {
+		return err
+	}
+	u, ok := c.(ConditionalUploader)
+	if !ok {
+		return backenderrors.ErrConditionalWriteNotSupported
+	}
+	return u.UploadConditional(namespace, name, src, cond)
+}
+
+// supportsConditionalWrite returns true if c, or the client wrapped by c,
+// implements ConditionalUploader. Wrappers always implement it by forwarding.
+func supportsConditionalWrite(c Client) bool {
+	for {
+		switch w := c.(type) {
+		case *ThrottledClient:
+			c = w.Client
+		case *ReadAheadClient:
+			c = w.Client
+		case *ListLimitedClient:
+			c = w.Client
+		default:
+			_, ok := c.(ConditionalUploader)
+			return ok
+		}
+	}
+}
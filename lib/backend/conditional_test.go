@@ -0,0 +1,89 @@
+// Copyright (c) 2016-2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package backend
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/uber-go/tally"
+	"github.com/uber/kraken/lib/backend/backenderrors"
+	"github.com/uber/kraken/utils/bandwidth"
+)
+
+// conditionalClient records the conditions of conditional uploads.
+type conditionalClient struct {
+	NoopClient
+	conds []WriteCondition
+}
+
+func (c *conditionalClient) UploadConditional(
+	namespace, name string, src io.Reader, cond WriteCondition) error {
+
+	c.conds = append(c.conds, cond)
+	return nil
+}
+
+func TestWriteConditionValidate(t *testing.T) {
+	require.NoError(t, WriteCondition{}.Validate())
+	require.NoError(t, WriteCondition{IfAbsent: true}.Validate())
+	require.NoError(t, WriteCondition{IfMatch: "1"}.Validate())
+	require.Error(t, WriteCondition{IfAbsent: true, IfMatch: "1"}.Validate())
+}
+
+func TestUploadConditional(t *testing.T) {
+	limiter, err := bandwidth.NewLimiter(bandwidth.Config{Enable: false})
+	require.NoError(t, err)
+
+	wrappers := []struct {
+		desc string
+		wrap func(Client) Client
+	}{
+		{"unwrapped", func(c Client) Client { return c }},
+		{"throttled", func(c Client) Client { return throttle(c, limiter) }},
+		{"read ahead", func(c Client) Client { return readAhead(c, ReadAheadConfig{}) }},
+		{"list limited", func(c Client) Client { return limitList(c, 1, tally.NoopScope) }},
+		{"nested", func(c Client) Client {
+			return throttle(limitList(readAhead(c, ReadAheadConfig{}), 1, tally.NoopScope), limiter)
+		}},
+	}
+	for _, w := range wrappers {
+		t.Run(w.desc, func(t *testing.T) {
+			t.Run("supported", func(t *testing.T) {
+				require := require.New(t)
+
+				c := &conditionalClient{}
+				cond := WriteCondition{IfAbsent: true}
+				require.NoError(UploadConditional(w.wrap(c), "ns", "name", bytes.NewReader(nil), cond))
+				require.Equal([]WriteCondition{cond}, c.conds)
+			})
+			t.Run("not supported", func(t *testing.T) {
+				err := UploadConditional(
+					w.wrap(NoopClient{}), "ns", "name", bytes.NewReader(nil),
+					WriteCondition{IfAbsent: true})
+				require.Equal(t, backenderrors.ErrConditionalWriteNotSupported, err)
+			})
+		})
+	}
+}
+
+func TestUploadConditionalInvalidCondition(t *testing.T) {
+	c := &conditionalClient{}
+	err := UploadConditional(
+		c, "ns", "name", bytes.NewReader(nil), WriteCondition{IfAbsent: true, IfMatch: "1"})
+	require.Error(t, err)
+	require.Empty(t, c.conds)
+}
@@ -28,6 +28,16 @@ type Config struct {
 	Bandwidth bandwidth.Config `yaml:"bandwidth"`
 	// Whether the service readiness endpoint will check the backend's readiness.
 	MustReady bool `yaml:"must_ready"`
+	// If enabled, locks uploaded blobs for a retention period. Backends which
+	// do not implement RetentionClient are rejected.
+	Retention RetentionConfig `yaml:"retention"`
+	// If enabled, backends which do not encrypt every upload with a customer
+	// managed key are rejected, so no blob can be stored unencrypted.
+	RequireEncryption bool `yaml:"require_encryption"`
+	// If enabled, buffers downloads ahead of slow destinations.
+	ReadAhead ReadAheadConfig `yaml:"read_ahead"`
+	// If positive, caps the number of concurrent List calls to the backend.
+	ListConcurrency int `yaml:"list_concurrency"`
 }
 
 func (c Config) applyDefaults() Config {
@@ -42,6 +52,7 @@ func (c Config) applyDefaults() Config {
 			}
 		}
 	}
+	c.Retention = c.Retention.applyDefaults()
 	return c
 }
 
@@ -0,0 +1,35 @@
+// Copyright (c) 2016-2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package backend
+
+// Encryption modes. Keys are configured per backend:
+//
+//   - s3: SSE-KMS, with the KMS key ARN set by sse_kms_key_id.
+//   - gcs: CMEK, with the Cloud KMS key name set by kms_key_name.
+//
+// No other backend supports encryption with customer managed keys.
+const (
+	EncryptionModeSSEKMS = "sse-kms"
+	EncryptionModeCMEK   = "cmek"
+)
+
+// EncryptionClient is implemented by Clients which can encrypt uploaded blobs
+// at rest with customer managed keys.
+type EncryptionClient interface {
+	Client
+
+	// EncryptionMode returns the mode applied to every upload, or empty if no
+	// key is configured.
+	EncryptionMode() string
+}
@@ -25,3 +25,9 @@ func ManagerFixture() *Manager {
 	}
 	return m
 }
+
+// ReadAheadClientFixture wraps c with read-ahead buffering for testing
+// purposes.
+func ReadAheadClientFixture(c Client, config ReadAheadConfig) *ReadAheadClient {
+	return readAhead(c, config)
+}
@@ -15,10 +15,15 @@ package gcsbackend
 
 import (
 	"context"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"net/http"
 	"path"
+	"strconv"
+	"time"
 
 	"github.com/uber/kraken/utils/closers"
 
@@ -31,6 +36,7 @@ import (
 
 	"cloud.google.com/go/storage"
 	"go.uber.org/zap"
+	"google.golang.org/api/googleapi"
 	"google.golang.org/api/iterator"
 	"google.golang.org/api/option"
 	"gopkg.in/yaml.v2"
@@ -136,7 +142,7 @@ func NewClient(
 		config:  config,
 		pather:  pather,
 		stats:   stats,
-		gcs:     NewGCS(ctx, sClient.Bucket(config.Bucket), &config),
+		gcs:     NewGCS(ctx, sClient.Bucket(config.Bucket), &config, []byte(auth.GCS.AccessBlob)),
 		sClient: sClient,
 	}
 
@@ -159,7 +165,11 @@ func (c *Client) Stat(namespace, name string) (*core.BlobInfo, error) {
 		return nil, err
 	}
 
-	return core.NewBlobInfo(objectAttrs.Size), nil
+	bi := core.NewBlobInfo(objectAttrs.Size)
+	if objectAttrs.Generation != 0 {
+		bi.Version = strconv.FormatInt(objectAttrs.Generation, 10)
+	}
+	return bi, nil
 }
 
 // Download downloads the content from a configured bucket and writes the
@@ -174,6 +184,36 @@ func (c *Client) Download(namespace, name string, dst io.Writer) error {
 	return err
 }
 
+// Checksum returns the md5 of name, or its crc32c for composite objects,
+// which have no md5.
+func (c *Client) Checksum(ctx context.Context, name string) (core.Digest, error) {
+	path, err := c.pather.BlobPath(name)
+	if err != nil {
+		return core.Digest{}, fmt.Errorf("blob path: %s", err)
+	}
+	objectAttrs, err := c.gcs.ObjectAttrs(path)
+	if err != nil {
+		if isObjectNotFound(err) {
+			return core.Digest{}, backenderrors.ErrBlobNotFound
+		}
+		return core.Digest{}, err
+	}
+	if len(objectAttrs.MD5) > 0 {
+		return core.NewChecksumDigest(core.MD5, hex.EncodeToString(objectAttrs.MD5))
+	}
+	return core.NewChecksumDigest(core.CRC32C, fmt.Sprintf("%08x", objectAttrs.CRC32C))
+}
+
+// PresignDownload returns a signed GET url for name which expires after ttl.
+// Requires the configured credentials to be a service account key.
+func (c *Client) PresignDownload(ctx context.Context, name string, ttl time.Duration) (string, error) {
+	path, err := c.pather.BlobPath(name)
+	if err != nil {
+		return "", fmt.Errorf("blob path: %s", err)
+	}
+	return c.gcs.SignedURL(path, time.Now().Add(ttl))
+}
+
 // Upload uploads src to a configured bucket.
 func (c *Client) Upload(namespace, name string, src io.Reader) error {
 	path, err := c.pather.BlobPath(name)
@@ -185,6 +225,71 @@ func (c *Client) Upload(namespace, name string, src io.Reader) error {
 	return err
 }
 
+// UploadConditional uploads src into name if cond holds, using GCS generation
+// preconditions. IfMatch versions are generation numbers, as reported by Stat.
+func (c *Client) UploadConditional(
+	namespace, name string, src io.Reader, cond backend.WriteCondition) error {
+
+	if err := cond.Validate(); err != nil {
+		return err
+	}
+	var conds storage.Conditions
+	if cond.IfAbsent {
+		conds.DoesNotExist = true
+	} else if cond.IfMatch != "" {
+		generation, err := strconv.ParseInt(cond.IfMatch, 10, 64)
+		if err != nil || generation <= 0 {
+			return fmt.Errorf("invalid generation %q", cond.IfMatch)
+		}
+		conds.GenerationMatch = generation
+	} else {
+		return c.Upload(namespace, name, src)
+	}
+	path, err := c.pather.BlobPath(name)
+	if err != nil {
+		return fmt.Errorf("blob path: %s", err)
+	}
+	if _, err := c.gcs.UploadConditional(path, src, conds); err != nil {
+		if isPreconditionFailed(err) {
+			return backenderrors.ErrPreconditionFailed
+		}
+		return err
+	}
+	return nil
+}
+
+// EncryptionMode returns backend.EncryptionModeCMEK if a KMS key is
+// configured.
+func (c *Client) EncryptionMode() string {
+	if c.config.KMSKeyName == "" {
+		return ""
+	}
+	return backend.EncryptionModeCMEK
+}
+
+// SetRetention verifies that the configured bucket has a retention policy at
+// least as strict as config. GCS applies bucket retention policies to every
+// object on upload, so no per-upload handling is needed.
+func (c *Client) SetRetention(config backend.RetentionConfig) error {
+	attrs, err := c.gcs.BucketAttrs()
+	if err != nil {
+		return fmt.Errorf("get bucket attrs: %s", err)
+	}
+	policy := attrs.RetentionPolicy
+	if policy == nil {
+		return fmt.Errorf("no retention policy on bucket %s", c.config.Bucket)
+	}
+	if policy.RetentionPeriod < config.Period {
+		return fmt.Errorf(
+			"bucket %s retention period %s is shorter than %s",
+			c.config.Bucket, policy.RetentionPeriod, config.Period)
+	}
+	if config.Mode == backend.RetentionModeCompliance && !policy.IsLocked {
+		return fmt.Errorf("compliance mode requires locked retention policy on bucket %s", c.config.Bucket)
+	}
+	return nil
+}
+
 // List lists names that start with prefix.
 func (c *Client) List(prefix string, opts ...backend.ListOption) (*backend.ListResult, error) {
 	options := backend.DefaultListOptions()
@@ -236,22 +341,46 @@ func (c *Client) Close() error {
 	return c.sClient.Close()
 }
 
+// isPreconditionFailed is helper function for identify failed write condition error.
+func isPreconditionFailed(err error) bool {
+	var apiErr *googleapi.Error
+	return errors.As(err, &apiErr) && apiErr.Code == http.StatusPreconditionFailed
+}
+
 // isObjectNotFound is helper function for identify non-existing object error.
 func isObjectNotFound(err error) bool {
 	return err == storage.ErrObjectNotExist || err == storage.ErrBucketNotExist
 }
 
+// serviceAccountKey is the subset of a service account JSON key required for
+// signing urls.
+type serviceAccountKey struct {
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+}
+
 // GCSImpl implements GCS interaface.
 type GCSImpl struct {
 	ctx    context.Context
 	bucket *storage.BucketHandle
 	config *Config
+	key    serviceAccountKey
 }
 
+// NewGCS creates a new GCSImpl. accessBlob are the JSON credentials of the
+// client, which are used for signing urls if they are a service account key.
 func NewGCS(ctx context.Context, bucket *storage.BucketHandle,
-	config *Config) *GCSImpl {
+	config *Config, accessBlob []byte) *GCSImpl {
 
-	return &GCSImpl{ctx, bucket, config}
+	var key serviceAccountKey
+	if err := json.Unmarshal(accessBlob, &key); err != nil {
+		log.Infof("GCS credentials are not a service account key, url signing disabled: %s", err)
+	}
+	return &GCSImpl{ctx, bucket, config, key}
+}
+
+func (g *GCSImpl) BucketAttrs() (*storage.BucketAttrs, error) {
+	return g.bucket.Attrs(g.ctx)
 }
 
 func (g *GCSImpl) ObjectAttrs(objectName string) (*storage.ObjectAttrs, error) {
@@ -278,8 +407,21 @@ func (g *GCSImpl) Download(objectName string, w io.Writer) (int64, error) {
 }
 
 func (g *GCSImpl) Upload(objectName string, r io.Reader) (int64, error) {
-	wc := g.bucket.Object(objectName).NewWriter(g.ctx)
+	return g.upload(g.bucket.Object(objectName), r)
+}
+
+// UploadConditional is like Upload, but fails with a precondition error
+// unless conds hold when the object is written.
+func (g *GCSImpl) UploadConditional(
+	objectName string, r io.Reader, conds storage.Conditions) (int64, error) {
+
+	return g.upload(g.bucket.Object(objectName).If(conds), r)
+}
+
+func (g *GCSImpl) upload(handle *storage.ObjectHandle, r io.Reader) (int64, error) {
+	wc := handle.NewWriter(g.ctx)
 	wc.ChunkSize = int(g.config.UploadChunkSize)
+	wc.KMSKeyName = g.config.KMSKeyName
 
 	w, err := io.CopyN(wc, r, int64(g.config.UploadChunkSize))
 	if err != nil && err != io.EOF {
@@ -293,6 +435,18 @@ func (g *GCSImpl) Upload(objectName string, r io.Reader) (int64, error) {
 	return w, nil
 }
 
+func (g *GCSImpl) SignedURL(objectName string, expires time.Time) (string, error) {
+	if g.key.ClientEmail == "" || g.key.PrivateKey == "" {
+		return "", backenderrors.ErrPresignNotSupported
+	}
+	return storage.SignedURL(g.config.Bucket, objectName, &storage.SignedURLOptions{
+		GoogleAccessID: g.key.ClientEmail,
+		PrivateKey:     []byte(g.key.PrivateKey),
+		Method:         http.MethodGet,
+		Expires:        expires,
+	})
+}
+
 func (g *GCSImpl) GetObjectIterator(prefix string) iterator.Pageable {
 	var query storage.Query
 
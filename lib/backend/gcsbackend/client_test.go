@@ -15,15 +15,19 @@ package gcsbackend
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"math/rand"
+	"net/http"
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/uber-go/tally"
 	"github.com/uber/kraken/core"
 	"github.com/uber/kraken/lib/backend"
+	"github.com/uber/kraken/lib/backend/backenderrors"
 	mockgcsbackend "github.com/uber/kraken/mocks/lib/backend/gcsbackend"
 	"github.com/uber/kraken/utils/closers"
 	"github.com/uber/kraken/utils/mockutil"
@@ -31,6 +35,7 @@ import (
 	"github.com/uber/kraken/utils/rwutil"
 
 	"cloud.google.com/go/storage"
+	"google.golang.org/api/googleapi"
 	"google.golang.org/api/iterator"
 
 	"github.com/golang/mock/gomock"
@@ -111,6 +116,30 @@ func TestClientStat(t *testing.T) {
 	require.Equal(core.NewBlobInfo(100), info)
 }
 
+func TestClientChecksum(t *testing.T) {
+	require := require.New(t)
+
+	mocks, cleanup := newClientMocks(t)
+	defer cleanup()
+
+	client := mocks.new()
+	defer closers.Close(client)
+
+	objectAttrs := storage.ObjectAttrs{CRC32C: 0xdeadbeef}
+	mocks.gcs.EXPECT().ObjectAttrs("/root/test").Return(&objectAttrs, nil)
+
+	d, err := client.Checksum(context.Background(), "test")
+	require.NoError(err)
+	require.Equal("crc32c:deadbeef", d.String())
+
+	objectAttrs.MD5 = []byte{0xd4, 0x1d, 0x8c, 0xd9, 0x8f, 0x00, 0xb2, 0x04, 0xe9, 0x80, 0x09, 0x98, 0xec, 0xf8, 0x42, 0x7e}
+	mocks.gcs.EXPECT().ObjectAttrs("/root/test").Return(&objectAttrs, nil)
+
+	d, err = client.Checksum(context.Background(), "test")
+	require.NoError(err)
+	require.Equal("md5:d41d8cd98f00b204e9800998ecf8427e", d.String())
+}
+
 func TestClientDownload(t *testing.T) {
 	require := require.New(t)
 
@@ -131,6 +160,83 @@ func TestClientDownload(t *testing.T) {
 	require.Equal(data, []byte(w))
 }
 
+func TestClientStatVersion(t *testing.T) {
+	require := require.New(t)
+
+	mocks, cleanup := newClientMocks(t)
+	defer cleanup()
+
+	client := mocks.new()
+	defer closers.Close(client)
+
+	objectAttrs := storage.ObjectAttrs{Size: 100, Generation: 1234}
+
+	mocks.gcs.EXPECT().ObjectAttrs("/root/test").Return(&objectAttrs, nil)
+
+	info, err := client.Stat(core.NamespaceFixture(), "test")
+	require.NoError(err)
+	require.Equal(&core.BlobInfo{Size: 100, Version: "1234"}, info)
+}
+
+func TestClientUploadConditional(t *testing.T) {
+	tests := []struct {
+		desc     string
+		cond     backend.WriteCondition
+		expected storage.Conditions
+	}{
+		{"if absent", backend.WriteCondition{IfAbsent: true}, storage.Conditions{DoesNotExist: true}},
+		{"if match", backend.WriteCondition{IfMatch: "1234"}, storage.Conditions{GenerationMatch: 1234}},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			require := require.New(t)
+
+			mocks, cleanup := newClientMocks(t)
+			defer cleanup()
+
+			client := mocks.new()
+
+			data := randutil.Text(32)
+
+			mocks.gcs.EXPECT().UploadConditional(
+				"/root/test", gomock.Any(), test.expected).Return(int64(len(data)), nil)
+
+			require.NoError(client.UploadConditional(
+				core.NamespaceFixture(), "test", bytes.NewReader(data), test.cond))
+		})
+	}
+}
+
+func TestClientUploadConditionalPreconditionFailed(t *testing.T) {
+	require := require.New(t)
+
+	mocks, cleanup := newClientMocks(t)
+	defer cleanup()
+
+	client := mocks.new()
+
+	mocks.gcs.EXPECT().UploadConditional(
+		"/root/test", gomock.Any(), storage.Conditions{DoesNotExist: true}).Return(
+		int64(0), &googleapi.Error{Code: http.StatusPreconditionFailed})
+
+	err := client.UploadConditional(
+		core.NamespaceFixture(), "test", bytes.NewReader(randutil.Text(32)),
+		backend.WriteCondition{IfAbsent: true})
+	require.Equal(backenderrors.ErrPreconditionFailed, err)
+}
+
+func TestClientUploadConditionalInvalidGeneration(t *testing.T) {
+	mocks, cleanup := newClientMocks(t)
+	defer cleanup()
+
+	client := mocks.new()
+
+	err := client.UploadConditional(
+		core.NamespaceFixture(), "test", bytes.NewReader(randutil.Text(32)),
+		backend.WriteCondition{IfMatch: `"etag"`})
+	require.Error(t, err)
+}
+
 func TestClientUpload(t *testing.T) {
 	require := require.New(t)
 
@@ -189,6 +295,102 @@ func (it *AlphaIterator) next(pageSize int, pageToken string) (string, error) {
 	return strconv.Itoa(i), nil
 }
 
+func TestClientEncryptionMode(t *testing.T) {
+	require := require.New(t)
+
+	mocks, cleanup := newClientMocks(t)
+	defer cleanup()
+
+	require.Empty(mocks.new().EncryptionMode())
+
+	mocks.config.KMSKeyName = "projects/p/locations/l/keyRings/r/cryptoKeys/k"
+	require.Equal(backend.EncryptionModeCMEK, mocks.new().EncryptionMode())
+}
+
+func TestClientSetRetention(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  *storage.RetentionPolicy
+		mode    string
+		wantErr bool
+	}{
+		{
+			name:   "governance",
+			policy: &storage.RetentionPolicy{RetentionPeriod: 2 * time.Hour},
+			mode:   backend.RetentionModeGovernance,
+		},
+		{
+			name:   "compliance",
+			policy: &storage.RetentionPolicy{RetentionPeriod: time.Hour, IsLocked: true},
+			mode:   backend.RetentionModeCompliance,
+		},
+		{
+			name:    "compliance requires locked policy",
+			policy:  &storage.RetentionPolicy{RetentionPeriod: time.Hour},
+			mode:    backend.RetentionModeCompliance,
+			wantErr: true,
+		},
+		{
+			name:    "period too short",
+			policy:  &storage.RetentionPolicy{RetentionPeriod: time.Minute},
+			mode:    backend.RetentionModeGovernance,
+			wantErr: true,
+		},
+		{
+			name:    "no policy",
+			mode:    backend.RetentionModeGovernance,
+			wantErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require := require.New(t)
+
+			mocks, cleanup := newClientMocks(t)
+			defer cleanup()
+
+			client := mocks.new()
+
+			mocks.gcs.EXPECT().BucketAttrs().Return(
+				&storage.BucketAttrs{RetentionPolicy: test.policy}, nil)
+
+			err := client.SetRetention(backend.RetentionConfig{
+				Enabled: true,
+				Mode:    test.mode,
+				Period:  time.Hour,
+			})
+			if test.wantErr {
+				require.Error(err)
+			} else {
+				require.NoError(err)
+			}
+		})
+	}
+}
+
+func TestClientPresignDownload(t *testing.T) {
+	require := require.New(t)
+
+	mocks, cleanup := newClientMocks(t)
+	defer cleanup()
+
+	client := mocks.new()
+
+	mocks.gcs.EXPECT().SignedURL("/root/test", gomock.Any()).Return("https://test-bucket/root/test?sig", nil)
+
+	url, err := client.PresignDownload(context.Background(), "test", time.Minute)
+	require.NoError(err)
+	require.Equal("https://test-bucket/root/test?sig", url)
+}
+
+func TestGCSImplSignedURLRequiresServiceAccountKey(t *testing.T) {
+	require := require.New(t)
+
+	g := NewGCS(context.Background(), nil, &Config{Bucket: "test-bucket"}, []byte("access_blob"))
+	_, err := g.SignedURL("/root/test", time.Now().Add(time.Minute))
+	require.Equal(backenderrors.ErrPresignNotSupported, err)
+}
+
 func TestClientList(t *testing.T) {
 	require := require.New(t)
 	maxIterate := 100
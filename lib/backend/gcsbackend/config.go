@@ -38,6 +38,11 @@ type Config struct {
 
 	// NamePath identifies which namepath.Pather to use.
 	NamePath string `yaml:"name_path"`
+
+	// KMSKeyName is the Cloud KMS key used to encrypt uploads with CMEK, of
+	// the form "projects/P/locations/L/keyRings/R/cryptoKeys/K". Uploads use
+	// the bucket default key if empty.
+	KMSKeyName string `yaml:"kms_key_name"`
 }
 
 // UserAuthConfig defines authentication configuration overlayed by Langley.
@@ -15,6 +15,7 @@ package gcsbackend
 
 import (
 	"io"
+	"time"
 
 	"cloud.google.com/go/storage"
 	"google.golang.org/api/iterator"
@@ -22,9 +23,12 @@ import (
 
 // GCS defines the operations we use in the GCS api. Useful for mocking.
 type GCS interface {
+	BucketAttrs() (*storage.BucketAttrs, error)
 	ObjectAttrs(objectName string) (*storage.ObjectAttrs, error)
 	Download(objectName string, w io.Writer) (int64, error)
 	Upload(objectName string, r io.Reader) (int64, error)
+	UploadConditional(objectName string, r io.Reader, conds storage.Conditions) (int64, error)
 	GetObjectIterator(prefix string) iterator.Pageable
 	NextPage(pager *iterator.Pager) ([]string, string, error)
+	SignedURL(objectName string, expires time.Time) (string, error)
 }
@@ -0,0 +1,77 @@
+// Copyright (c) 2016-2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package backend
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/uber-go/tally"
+	"github.com/uber/kraken/core"
+	"go.uber.org/atomic"
+)
+
+// ListLimitedClient is a backend client which caps the number of concurrent
+// List calls, e.g. so GC scans cannot exhaust the API quota of a cloud
+// provider. Each page of a paginated listing counts as one call. The cap is
+// shared by all callers of the client.
+type ListLimitedClient struct {
+	Client
+	sem   chan struct{}
+	inUse *atomic.Int64
+	gauge tally.Gauge
+}
+
+// limitList wraps client with a List concurrency limit of n.
+func limitList(client Client, n int, stats tally.Scope) *ListLimitedClient {
+	return &ListLimitedClient{
+		Client: client,
+		sem:    make(chan struct{}, n),
+		inUse:  atomic.NewInt64(0),
+		gauge:  stats.Gauge("list_concurrency_in_use"),
+	}
+}
+
+// List lists entries whose names start with prefix, blocking while the
+// concurrency limit is reached.
+func (c *ListLimitedClient) List(prefix string, opts ...ListOption) (*ListResult, error) {
+	c.sem <- struct{}{}
+	c.gauge.Update(float64(c.inUse.Inc()))
+	defer func() {
+		c.gauge.Update(float64(c.inUse.Dec()))
+		<-c.sem
+	}()
+
+	return c.Client.List(prefix, opts...)
+}
+
+// Checksum forwards to the underlying client.
+func (c *ListLimitedClient) Checksum(ctx context.Context, name string) (core.Digest, error) {
+	return Checksum(ctx, c.Client, name)
+}
+
+// PresignDownload forwards to the underlying client.
+func (c *ListLimitedClient) PresignDownload(
+	ctx context.Context, name string, ttl time.Duration) (string, error) {
+
+	return PresignDownload(ctx, c.Client, name, ttl)
+}
+
+// UploadConditional forwards to the underlying client.
+func (c *ListLimitedClient) UploadConditional(
+	namespace, name string, src io.Reader, cond WriteCondition) error {
+
+	return UploadConditional(c.Client, namespace, name, src, cond)
+}
@@ -0,0 +1,81 @@
+// Copyright (c) 2016-2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package backend
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/uber-go/tally"
+	"go.uber.org/atomic"
+)
+
+// blockingListClient blocks List calls until release is closed, recording the
+// maximum number of concurrent calls.
+type blockingListClient struct {
+	NoopClient
+	release  chan struct{}
+	inFlight *atomic.Int64
+	max      *atomic.Int64
+}
+
+func (c blockingListClient) List(prefix string, opts ...ListOption) (*ListResult, error) {
+	n := c.inFlight.Inc()
+	defer c.inFlight.Dec()
+	for {
+		m := c.max.Load()
+		if n <= m || c.max.CAS(m, n) {
+			break
+		}
+	}
+	<-c.release
+	return &ListResult{}, nil
+}
+
+func TestListLimitedClient(t *testing.T) {
+	require := require.New(t)
+
+	stats := tally.NewTestScope("", nil)
+	bc := blockingListClient{
+		release:  make(chan struct{}),
+		inFlight: atomic.NewInt64(0),
+		max:      atomic.NewInt64(0),
+	}
+	c := limitList(bc, 2, stats)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := c.List("prefix")
+			require.NoError(err)
+		}()
+	}
+
+	require.Eventually(func() bool {
+		return bc.inFlight.Load() == 2
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Equal(
+		float64(2), stats.Snapshot().Gauges()["list_concurrency_in_use+"].Value())
+
+	close(bc.release)
+	wg.Wait()
+
+	require.Equal(int64(2), bc.max.Load())
+	require.Equal(
+		float64(0), stats.Snapshot().Gauges()["list_concurrency_in_use+"].Value())
+}
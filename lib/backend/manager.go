@@ -16,13 +16,17 @@ package backend
 import (
 	"errors"
 	"fmt"
+	"reflect"
 	"regexp"
+	"sync"
+	"time"
 
 	"github.com/uber/kraken/lib/backend/backenderrors"
 	"github.com/uber/kraken/utils/bandwidth"
 	"github.com/uber/kraken/utils/log"
 
 	"github.com/uber-go/tally"
+	"go.uber.org/zap"
 )
 
 // Manager errors.
@@ -34,6 +38,10 @@ type backend struct {
 	regexp    *regexp.Regexp
 	client    Client
 	mustReady bool
+
+	// config is the config the backend was created from, with defaults
+	// applied. Nil for backends added via Register.
+	config *Config
 }
 
 func newBackend(namespace string, c Client, mustReady bool) (*backend, error) {
@@ -50,12 +58,52 @@ func newBackend(namespace string, c Client, mustReady bool) (*backend, error) {
 
 // Manager manages backend clients for namespace regular expressions.
 type Manager struct {
+	config  ManagerConfig
+	auth    AuthConfig
+	stats   tally.Scope
+	slogger *zap.SugaredLogger
+
+	mu       sync.RWMutex
 	backends []*backend
+
+	// denominator is the last bandwidth denominator applied via
+	// AdjustBandwidth, so reloaded throttled clients start adjusted.
+	denominator int
 }
 
 // ManagerConfig is config for backend manager.
 type ManagerConfig struct {
 	Log log.Config `yaml:"log"`
+
+	// ReloadDrainTimeout is how long backends removed or replaced by Reload
+	// keep serving in-flight operations before they are closed.
+	ReloadDrainTimeout time.Duration `yaml:"reload_drain_timeout"`
+
+	// DefaultNamespace is the namespace of the configured backend which
+	// serves namespaces matching no backend. If empty, such namespaces are
+	// rejected with ErrNamespaceNotFound.
+	DefaultNamespace string `yaml:"default_namespace"`
+}
+
+func (c ManagerConfig) applyDefaults() ManagerConfig {
+	if c.ReloadDrainTimeout == 0 {
+		c.ReloadDrainTimeout = time.Minute
+	}
+	return c
+}
+
+// checkDefaultNamespace returns error if DefaultNamespace is set but is not
+// the namespace of any of configs.
+func (c ManagerConfig) checkDefaultNamespace(configs []Config) error {
+	if c.DefaultNamespace == "" {
+		return nil
+	}
+	for _, config := range configs {
+		if config.Namespace == c.DefaultNamespace {
+			return nil
+		}
+	}
+	return fmt.Errorf("default namespace %s has no configured backend", c.DefaultNamespace)
 }
 
 // NewManager creates a new backend Manager.
@@ -64,62 +112,237 @@ func NewManager(managerConfig ManagerConfig, configs []Config, auth AuthConfig,
 	if err != nil {
 		return nil, fmt.Errorf("log: %s", err)
 	}
-	slogger := logger.Sugar()
-
-	var backends []*backend
+	if err := managerConfig.checkDefaultNamespace(configs); err != nil {
+		return nil, err
+	}
+	m := &Manager{
+		config:      managerConfig.applyDefaults(),
+		auth:        auth,
+		stats:       stats,
+		slogger:     logger.Sugar(),
+		denominator: 1,
+	}
 	for _, config := range configs {
-		config = config.applyDefaults()
-		var c Client
+		b, err := m.createBackend(config.applyDefaults())
+		if err != nil {
+			return nil, err
+		}
+		m.backends = append(m.backends, b)
+	}
+	return m, nil
+}
+
+// createBackend creates a backend for config, which must have defaults applied.
+func (m *Manager) createBackend(config Config) (*backend, error) {
+	var c Client
+
+	if len(config.Backend) != 1 {
+		return nil, fmt.Errorf("no backend or more than one backend configured")
+	}
+	var backendName string
+	var backendConfig interface{}
+	for backendName, backendConfig = range config.Backend { // Pull the only key/value out of map
+	}
+	factory, err := getFactory(backendName)
+	if err != nil {
+		return nil, fmt.Errorf("get backend client factory: %s", err)
+	}
+	c, err = factory.Create(backendConfig, m.auth, m.stats, m.slogger)
+	if err != nil {
+		return nil, fmt.Errorf("create backend client: %s", err)
+	}
 
-		if len(config.Backend) != 1 {
-			return nil, fmt.Errorf("no backend or more than one backend configured")
+	if config.Retention.Enabled {
+		if err := setRetention(c, backendName, config.Retention); err != nil {
+			return nil, fmt.Errorf("retention for namespace %s: %s", config.Namespace, err)
 		}
-		var backendName string
-		var backendConfig interface{}
-		for backendName, backendConfig = range config.Backend { // Pull the only key/value out of map
+	}
+
+	if config.RequireEncryption {
+		if err := requireEncryption(c, backendName); err != nil {
+			return nil, fmt.Errorf("encryption for namespace %s: %s", config.Namespace, err)
 		}
-		factory, err := getFactory(backendName)
+	}
+
+	if config.ReadAhead.Enabled {
+		c = readAhead(c, config.ReadAhead)
+	}
+
+	if config.ListConcurrency > 0 {
+		c = limitList(c, config.ListConcurrency, m.stats.Tagged(map[string]string{
+			"namespace": config.Namespace,
+		}))
+	}
+
+	if config.Bandwidth.Enable {
+		l, err := bandwidth.NewLimiter(config.Bandwidth)
 		if err != nil {
-			return nil, fmt.Errorf("get backend client factory: %s", err)
+			return nil, fmt.Errorf("bandwidth: %s", err)
+		}
+		c = throttle(c, l)
+	}
+	b, err := newBackend(config.Namespace, c, config.MustReady)
+	if err != nil {
+		return nil, fmt.Errorf("new backend for namespace %s: %s", config.Namespace, err)
+	}
+	b.config = &config
+	return b, nil
+}
+
+// Reload replaces the configured backends with configs without disrupting
+// serving. Backends whose config is unchanged are kept as is. Every added or
+// changed backend is created before any are swapped in, so if any config is
+// invalid, Reload returns error and the current backends keep serving.
+//
+// Backends which are removed or replaced keep serving in-flight operations
+// on clients already returned by GetClient, and are closed after
+// ReloadDrainTimeout. Backends added via Register are removed. Reload returns
+// error if configs drop the backend of DefaultNamespace.
+func (m *Manager) Reload(configs []Config) error {
+	if err := m.config.checkDefaultNamespace(configs); err != nil {
+		return err
+	}
+
+	m.mu.RLock()
+	current := m.backends
+	denominator := m.denominator
+	m.mu.RUnlock()
+
+	var backends, created []*backend
+	kept := make(map[*backend]bool)
+	for _, config := range configs {
+		config = config.applyDefaults()
+		if b := findBackend(current, config, kept); b != nil {
+			kept[b] = true
+			backends = append(backends, b)
+			continue
+		}
+		b, err := m.createBackend(config)
+		if err == nil {
+			err = adjustBackendBandwidth(b, denominator)
 		}
-		c, err = factory.Create(backendConfig, auth, stats, slogger)
 		if err != nil {
-			return nil, fmt.Errorf("create backend client: %s", err)
+			closeBackends(created)
+			return fmt.Errorf("namespace %s: %s", config.Namespace, err)
 		}
+		created = append(created, b)
+		backends = append(backends, b)
+	}
 
-		if config.Bandwidth.Enable {
-			l, err := bandwidth.NewLimiter(config.Bandwidth)
-			if err != nil {
-				return nil, fmt.Errorf("bandwidth: %s", err)
+	m.mu.Lock()
+	if !sameBackends(m.backends, current) {
+		m.mu.Unlock()
+		closeBackends(created)
+		return errors.New("backends changed during reload")
+	}
+	if m.denominator != denominator {
+		for _, b := range created {
+			if err := adjustBackendBandwidth(b, m.denominator); err != nil {
+				log.With("namespace", b.regexp.String()).Errorf("Error adjusting reloaded backend bandwidth: %s", err)
 			}
-			c = throttle(c, l)
 		}
-		b, err := newBackend(config.Namespace, c, config.MustReady)
-		if err != nil {
-			return nil, fmt.Errorf("new backend for namespace %s: %s", config.Namespace, err)
+	}
+	m.backends = backends
+	m.mu.Unlock()
+
+	var removed []*backend
+	for _, b := range current {
+		if !kept[b] {
+			removed = append(removed, b)
 		}
-		backends = append(backends, b)
 	}
-	return &Manager{backends}, nil
+	if len(removed) > 0 {
+		time.AfterFunc(m.config.ReloadDrainTimeout, func() { closeBackends(removed) })
+	}
+	log.With(
+		"added", len(created),
+		"removed", len(removed),
+		"unchanged", len(kept),
+		"drain_timeout", m.config.ReloadDrainTimeout).Info("Reloaded backends")
+	return nil
+}
+
+// findBackend returns the backend in backends which was created from config
+// and is not yet kept, or nil if there is none.
+func findBackend(backends []*backend, config Config, kept map[*backend]bool) *backend {
+	for _, b := range backends {
+		if b.config != nil && !kept[b] && reflect.DeepEqual(*b.config, config) {
+			return b
+		}
+	}
+	return nil
+}
+
+func sameBackends(a, b []*backend) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func closeBackends(backends []*backend) {
+	for _, b := range backends {
+		if err := b.client.Close(); err != nil {
+			log.With("namespace", b.regexp.String()).Errorf("Error closing backend: %s", err)
+		}
+	}
+}
+
+func setRetention(c Client, backendName string, config RetentionConfig) error {
+	if err := config.Validate(); err != nil {
+		return fmt.Errorf("invalid config: %s", err)
+	}
+	rc, ok := c.(RetentionClient)
+	if !ok {
+		return fmt.Errorf("backend %s does not support retention", backendName)
+	}
+	return rc.SetRetention(config)
+}
+
+func requireEncryption(c Client, backendName string) error {
+	ec, ok := c.(EncryptionClient)
+	if !ok {
+		return fmt.Errorf("backend %s does not support encryption", backendName)
+	}
+	if ec.EncryptionMode() == "" {
+		return fmt.Errorf("backend %s has no encryption key configured", backendName)
+	}
+	return nil
 }
 
 // AdjustBandwidth adjusts bandwidth limits across all throttled clients to the
 // originally configured bandwidth divided by denominator.
 func (m *Manager) AdjustBandwidth(denominator int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	for _, b := range m.backends {
-		tc, ok := b.client.(*ThrottledClient)
-		if !ok {
-			continue
-		}
-		if err := tc.adjustBandwidth(denominator); err != nil {
+		if err := adjustBackendBandwidth(b, denominator); err != nil {
 			return err
 		}
-		log.With(
-			"namespace", b.regexp.String(),
-			"ingress", tc.IngressLimit(),
-			"egress", tc.EgressLimit(),
-			"denominator", denominator).Info("Adjusted backend bandwidth")
 	}
+	m.denominator = denominator
+	return nil
+}
+
+func adjustBackendBandwidth(b *backend, denominator int) error {
+	tc, ok := b.client.(*ThrottledClient)
+	if !ok {
+		return nil
+	}
+	if err := tc.adjustBandwidth(denominator); err != nil {
+		return err
+	}
+	log.With(
+		"namespace", b.regexp.String(),
+		"ingress", tc.IngressLimit(),
+		"egress", tc.EgressLimit(),
+		"denominator", denominator).Info("Adjusted backend bandwidth")
 	return nil
 }
 
@@ -127,6 +350,9 @@ func (m *Manager) AdjustBandwidth(denominator int) error {
 // should be primarily used for testing purposes -- normally, namespaces should
 // be statically configured and provided upon construction of the Manager.
 func (m *Manager) Register(namespace string, c Client, mustReady bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	for _, b := range m.backends {
 		if b.regexp.String() == namespace {
 			return fmt.Errorf("namespace %s already exists", namespace)
@@ -140,23 +366,50 @@ func (m *Manager) Register(namespace string, c Client, mustReady bool) error {
 	return nil
 }
 
-// GetClient matches namespace to the configured Client. Returns ErrNamespaceNotFound
-// if no clients match namespace.
+// GetClient matches namespace to the configured Client. If no clients match
+// namespace, the Client of DefaultNamespace is returned if configured, else
+// ErrNamespaceNotFound.
 func (m *Manager) GetClient(namespace string) (Client, error) {
 	if namespace == NoopNamespace {
 		return NoopClient{}, nil
 	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
 	for _, b := range m.backends {
 		if b.regexp.MatchString(namespace) {
 			return b.client, nil
 		}
 	}
+	if m.config.DefaultNamespace != "" {
+		for _, b := range m.backends {
+			if b.regexp.String() == m.config.DefaultNamespace {
+				return b.client, nil
+			}
+		}
+	}
 	return nil, ErrNamespaceNotFound
 }
 
+// Namespaces returns the namespace regular expressions of all backends, in
+// matching order.
+func (m *Manager) Namespaces() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	namespaces := make([]string, len(m.backends))
+	for i, b := range m.backends {
+		namespaces[i] = b.regexp.String()
+	}
+	return namespaces
+}
+
 // CheckReadiness returns whether the backends are ready (available).
 // A backend must be explicitly configured as required for readiness to be checked.
 func (m *Manager) CheckReadiness() error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
 	for _, b := range m.backends {
 		if !b.mustReady {
 			continue
@@ -170,6 +423,9 @@ func (m *Manager) CheckReadiness() error {
 }
 
 func (m *Manager) Close() error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
 	totalErrors := make([]error, 0)
 	for _, b := range m.backends {
 		if err := b.client.Close(); err != nil {
@@ -14,8 +14,10 @@
 package backend_test
 
 import (
+	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/uber-go/tally"
 	"github.com/uber/kraken/core"
@@ -130,6 +132,47 @@ func TestManagerNamespaceOrdering(t *testing.T) {
 	}
 }
 
+func TestManagerDefaultNamespace(t *testing.T) {
+	require := require.New(t)
+
+	configStr := `
+- namespace: foo/.*
+  backend:
+      testfs:
+          addr: testfs-foo
+          name_path: identity
+- namespace: fallback
+  backend:
+      testfs:
+          addr: testfs-fallback
+          name_path: identity
+`
+	var configs []Config
+	require.NoError(yaml.Unmarshal([]byte(configStr), &configs))
+
+	m, err := NewManager(
+		ManagerConfig{DefaultNamespace: "fallback"}, configs, AuthConfig{}, tally.NoopScope)
+	require.NoError(err)
+	require.Equal([]string{"foo/.*", "fallback"}, m.Namespaces())
+
+	for ns, expected := range map[string]string{
+		"foo/bar":  "testfs-foo",
+		"fallback": "testfs-fallback",
+		"unknown":  "testfs-fallback",
+	} {
+		c, err := m.GetClient(ns)
+		require.NoError(err)
+		require.Equal(expected, c.(*testfs.Client).Addr(), "Namespace: %s", ns)
+	}
+
+	// Reloads must keep the default namespace configured.
+	require.Error(m.Reload(configs[:1]))
+
+	_, err = NewManager(
+		ManagerConfig{DefaultNamespace: "missing"}, configs, AuthConfig{}, tally.NoopScope)
+	require.Error(err)
+}
+
 func TestManagerBandwidth(t *testing.T) {
 	require := require.New(t)
 
@@ -243,3 +286,226 @@ func TestManagerCheckReadiness(t *testing.T) {
 		})
 	}
 }
+
+func TestManagerListConcurrency(t *testing.T) {
+	require := require.New(t)
+
+	m, err := NewManager(
+		ManagerConfig{},
+		[]Config{{
+			Namespace: ".*",
+			Backend: map[string]interface{}{
+				"testfs": testfs.Config{Addr: "test-addr", NamePath: namepath.Identity},
+			},
+			ListConcurrency: 4,
+		}}, AuthConfig{}, tally.NoopScope)
+	require.NoError(err)
+
+	c, err := m.GetClient("foo")
+	require.NoError(err)
+	_, ok := c.(*ListLimitedClient)
+	require.True(ok)
+}
+
+func TestManagerRetention(t *testing.T) {
+	tests := []struct {
+		name      string
+		retention RetentionConfig
+	}{
+		{
+			name:      "unsupported backend",
+			retention: RetentionConfig{Enabled: true, Period: time.Hour},
+		},
+		{
+			name:      "invalid mode",
+			retention: RetentionConfig{Enabled: true, Mode: "foo", Period: time.Hour},
+		},
+		{
+			name:      "invalid period",
+			retention: RetentionConfig{Enabled: true},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require := require.New(t)
+
+			_, err := NewManager(
+				ManagerConfig{},
+				[]Config{{
+					Namespace: ".*",
+					Backend: map[string]interface{}{
+						"testfs": testfs.Config{Addr: "test-addr", NamePath: namepath.Identity},
+					},
+					Retention: test.retention,
+				}}, AuthConfig{}, tally.NoopScope)
+			require.Error(err)
+		})
+	}
+}
+
+func TestManagerRequireEncryptionUnsupportedBackend(t *testing.T) {
+	require := require.New(t)
+
+	_, err := NewManager(
+		ManagerConfig{},
+		[]Config{{
+			Namespace: ".*",
+			Backend: map[string]interface{}{
+				"testfs": testfs.Config{Addr: "test-addr", NamePath: namepath.Identity},
+			},
+			RequireEncryption: true,
+		}}, AuthConfig{}, tally.NoopScope)
+	require.Error(err)
+}
+
+func TestChecksumNotSupported(t *testing.T) {
+	_, err := Checksum(context.Background(), NoopClient{}, "foo")
+	require.Equal(t, backenderrors.ErrChecksumNotSupported, err)
+}
+
+func TestPresignDownloadNotSupported(t *testing.T) {
+	require := require.New(t)
+
+	_, err := PresignDownload(context.Background(), NoopClient{}, "foo", time.Minute)
+	require.Equal(backenderrors.ErrPresignNotSupported, err)
+}
+
+func testfsConfig(namespace, addr string) Config {
+	return Config{
+		Namespace: namespace,
+		Backend: map[string]interface{}{
+			"testfs": testfs.Config{Addr: addr, NamePath: namepath.Identity},
+		},
+	}
+}
+
+func requireTestFSAddr(t *testing.T, m *Manager, namespace, addr string) {
+	c, err := m.GetClient(namespace)
+	require.NoError(t, err)
+	client, ok := c.(*testfs.Client)
+	require.True(t, ok)
+	require.Equal(t, addr, client.Addr())
+}
+
+func TestManagerReload(t *testing.T) {
+	require := require.New(t)
+
+	m, err := NewManager(
+		ManagerConfig{},
+		[]Config{
+			testfsConfig("foo/.*", "testfs-foo"),
+			testfsConfig("bar/.*", "testfs-bar"),
+			testfsConfig(".*", "testfs-default"),
+		}, AuthConfig{}, tally.NoopScope)
+	require.NoError(err)
+
+	foo, err := m.GetClient("foo/x")
+	require.NoError(err)
+
+	require.NoError(m.Reload([]Config{
+		testfsConfig("foo/.*", "testfs-foo"),
+		testfsConfig("baz/.*", "testfs-baz"),
+		testfsConfig(".*", "testfs-default-2"),
+	}))
+
+	// Unchanged backends are kept as is.
+	c, err := m.GetClient("foo/x")
+	require.NoError(err)
+	require.True(foo == c)
+
+	requireTestFSAddr(t, m, "baz/x", "testfs-baz")
+	requireTestFSAddr(t, m, "bar/x", "testfs-default-2")
+	requireTestFSAddr(t, m, "x", "testfs-default-2")
+}
+
+func TestManagerReloadInvalidConfigKeepsBackends(t *testing.T) {
+	tests := []struct {
+		desc   string
+		config Config
+	}{
+		{"unknown backend", Config{
+			Namespace: "bar/.*",
+			Backend:   map[string]interface{}{"unknown": nil},
+		}},
+		{"no backend", Config{Namespace: "bar/.*"}},
+		{"invalid namespace", testfsConfig("(", "testfs-bar")},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			require := require.New(t)
+
+			m, err := NewManager(
+				ManagerConfig{},
+				[]Config{testfsConfig(".*", "testfs-default")}, AuthConfig{}, tally.NoopScope)
+			require.NoError(err)
+
+			require.Error(m.Reload([]Config{
+				testfsConfig("foo/.*", "testfs-foo"),
+				test.config,
+			}))
+
+			requireTestFSAddr(t, m, "foo/x", "testfs-default")
+		})
+	}
+}
+
+func TestManagerReloadDrainsRemovedBackends(t *testing.T) {
+	require := require.New(t)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	m, err := NewManager(
+		ManagerConfig{ReloadDrainTimeout: 100 * time.Millisecond},
+		nil, AuthConfig{}, tally.NoopScope)
+	require.NoError(err)
+
+	removed := mockbackend.NewMockClient(ctrl)
+	require.NoError(m.Register("foo/.*", removed, false))
+
+	closed := make(chan struct{})
+	removed.EXPECT().Close().DoAndReturn(func() error {
+		close(closed)
+		return nil
+	})
+
+	start := time.Now()
+	require.NoError(m.Reload([]Config{testfsConfig(".*", "testfs-default")}))
+	requireTestFSAddr(t, m, "foo/x", "testfs-default")
+
+	select {
+	case <-closed:
+		require.True(time.Since(start) >= 100*time.Millisecond)
+	case <-time.After(5 * time.Second):
+		require.FailNow("removed backend was not closed")
+	}
+}
+
+func TestManagerReloadKeepsBandwidthAdjustment(t *testing.T) {
+	require := require.New(t)
+
+	throttled := func(egress uint64) Config {
+		c := testfsConfig(".*", "testfs-default")
+		c.Bandwidth = bandwidth.Config{
+			EgressBitsPerSec:  egress,
+			IngressBitsPerSec: 50,
+			TokenSize:         1,
+			Enable:            true,
+		}
+		return c
+	}
+
+	m, err := NewManager(ManagerConfig{}, []Config{throttled(10)}, AuthConfig{}, tally.NoopScope)
+	require.NoError(err)
+
+	require.NoError(m.AdjustBandwidth(2))
+
+	require.NoError(m.Reload([]Config{throttled(20)}))
+
+	c, err := m.GetClient("foo")
+	require.NoError(err)
+	tc, ok := c.(*ThrottledClient)
+	require.True(ok)
+	require.Equal(int64(10), tc.EgressLimit())
+	require.Equal(int64(25), tc.IngressLimit())
+}
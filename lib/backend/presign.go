@@ -0,0 +1,41 @@
+// Copyright (c) 2016-2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package backend
+
+import (
+	"context"
+	"time"
+
+	"github.com/uber/kraken/lib/backend/backenderrors"
+)
+
+// Presigner is implemented by Clients which can generate presigned URLs,
+// allowing callers to download blobs directly from the storage backend.
+type Presigner interface {
+	// PresignDownload returns a URL which can be used to download name without
+	// credentials until ttl elapses.
+	PresignDownload(ctx context.Context, name string, ttl time.Duration) (string, error)
+}
+
+// PresignDownload returns a presigned download URL for name from c. Returns
+// backenderrors.ErrPresignNotSupported if c does not implement Presigner.
+func PresignDownload(
+	ctx context.Context, c Client, name string, ttl time.Duration) (string, error) {
+
+	p, ok := c.(Presigner)
+	if !ok {
+		return "", backenderrors.ErrPresignNotSupported
+	}
+	return p.PresignDownload(ctx, name, ttl)
+}
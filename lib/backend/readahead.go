@@ -0,0 +1,217 @@
+// Copyright (c) 2016-2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package backend
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/c2h5oh/datasize"
+	"github.com/uber/kraken/core"
+)
+
+const (
+	_defaultReadAheadBufferSize = 32 * datasize.MB
+	_defaultReadAheadChunkSize  = datasize.MB
+	_maxReadAheadBufferSize     = 512 * datasize.MB
+)
+
+// ReadAheadConfig configures read-ahead buffering of downloads. While the
+// destination of a download is busy, e.g. writing to disk, the backend keeps
+// downloading into a bounded in-memory buffer instead of stalling.
+type ReadAheadConfig struct {
+	Enabled bool `yaml:"enabled"`
+
+	// BufferSize bounds the memory buffered ahead of the destination per
+	// download. Capped at 512MB.
+	BufferSize datasize.ByteSize `yaml:"buffer_size"`
+
+	// ChunkSize is the size of writes to the destination.
+	ChunkSize datasize.ByteSize `yaml:"chunk_size"`
+}
+
+func (c ReadAheadConfig) applyDefaults() ReadAheadConfig {
+	if c.BufferSize == 0 {
+		c.BufferSize = _defaultReadAheadBufferSize
+	}
+	if c.BufferSize > _maxReadAheadBufferSize {
+		c.BufferSize = _maxReadAheadBufferSize
+	}
+	if c.ChunkSize == 0 {
+		c.ChunkSize = _defaultReadAheadChunkSize
+	}
+	if c.ChunkSize > c.BufferSize {
+		c.ChunkSize = c.BufferSize
+	}
+	return c
+}
+
+// RangedDownloader is implemented by Clients which download into io.WriterAt
+// destinations with concurrent ranged reads.
+type RangedDownloader interface {
+	// RangedDownloads returns true if Download writes to io.WriterAt
+	// destinations out of order.
+	RangedDownloads() bool
+}
+
+// ReadAheadClient is a backend client which buffers downloads ahead of the
+// destination.
+type ReadAheadClient struct {
+	Client
+	config ReadAheadConfig
+	ranged bool
+}
+
+// readAhead wraps client with read-ahead buffering.
+func readAhead(client Client, config ReadAheadConfig) *ReadAheadClient {
+	var ranged bool
+	if r, ok := client.(RangedDownloader); ok {
+		ranged = r.RangedDownloads()
+	}
+	return &ReadAheadClient{client, config.applyDefaults(), ranged}
+}
+
+// Download downloads name into dst through a read-ahead buffer. If the
+// underlying client is a RangedDownloader and dst implements io.WriterAt, dst
+// is passed through unbuffered, since a buffer would serialize the client's
+// concurrent ranged reads.
+func (c *ReadAheadClient) Download(namespace, name string, dst io.Writer) error {
+	if _, ok := dst.(io.WriterAt); ok && c.ranged {
+		return c.Client.Download(namespace, name, dst)
+	}
+	w := newReadAheadWriter(dst, int(c.config.BufferSize), int(c.config.ChunkSize))
+	err := c.Client.Download(namespace, name, w)
+	if cerr := w.close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// Checksum forwards to the underlying client.
+func (c *ReadAheadClient) Checksum(ctx context.Context, name string) (core.Digest, error) {
+	return Checksum(ctx, c.Client, name)
+}
+
+// PresignDownload forwards to the underlying client.
+func (c *ReadAheadClient) PresignDownload(
+	ctx context.Context, name string, ttl time.Duration) (string, error) {
+
+	return PresignDownload(ctx, c.Client, name, ttl)
+}
+
+// UploadConditional forwards to the underlying client.
+func (c *ReadAheadClient) UploadConditional(
+	namespace, name string, src io.Reader, cond WriteCondition) error {
+
+	return UploadConditional(c.Client, namespace, name, src, cond)
+}
+
+// readAheadWriter buffers writes into chunks which are flushed to dst by a
+// separate goroutine. At most BufferSize / ChunkSize chunks are allocated, so
+// writes block once the buffer is full.
+type readAheadWriter struct {
+	dst       io.Writer
+	chunkSize int
+	full      chan []byte
+	free      chan []byte
+	allocated int
+	limit     int
+	cur       []byte
+	done      chan struct{}
+
+	mu  sync.Mutex
+	err error // First error writing to dst.
+}
+
+func newReadAheadWriter(dst io.Writer, bufferSize, chunkSize int) *readAheadWriter {
+	limit := bufferSize / chunkSize
+	w := &readAheadWriter{
+		dst:       dst,
+		chunkSize: chunkSize,
+		full:      make(chan []byte, limit),
+		free:      make(chan []byte, limit),
+		limit:     limit,
+		done:      make(chan struct{}),
+	}
+	go w.flushLoop()
+	return w
+}
+
+func (w *readAheadWriter) flushLoop() {
+	defer close(w.done)
+	for b := range w.full {
+		if w.getErr() == nil {
+			if _, err := w.dst.Write(b); err != nil {
+				w.mu.Lock()
+				w.err = err
+				w.mu.Unlock()
+			}
+		}
+		// Keep draining after errors so writers never block forever.
+		w.free <- b[:0]
+	}
+}
+
+func (w *readAheadWriter) getErr() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.err
+}
+
+func (w *readAheadWriter) Write(p []byte) (int, error) {
+	var n int
+	for len(p) > 0 {
+		if err := w.getErr(); err != nil {
+			return n, err
+		}
+		if w.cur == nil {
+			w.cur = w.nextChunk()
+		}
+		k := copy(w.cur[len(w.cur):w.chunkSize], p)
+		w.cur = w.cur[:len(w.cur)+k]
+		p = p[k:]
+		n += k
+		if len(w.cur) == w.chunkSize {
+			w.full <- w.cur
+			w.cur = nil
+		}
+	}
+	return n, nil
+}
+
+func (w *readAheadWriter) nextChunk() []byte {
+	select {
+	case b := <-w.free:
+		return b
+	default:
+	}
+	if w.allocated < w.limit {
+		w.allocated++
+		return make([]byte, 0, w.chunkSize)
+	}
+	return <-w.free
+}
+
+// close flushes any buffered data and waits for it to be written to dst.
+func (w *readAheadWriter) close() error {
+	if len(w.cur) > 0 {
+		w.full <- w.cur
+		w.cur = nil
+	}
+	close(w.full)
+	<-w.done
+	return w.getErr()
+}
@@ -0,0 +1,140 @@
+// Copyright (c) 2016-2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package backend
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/c2h5oh/datasize"
+	"github.com/stretchr/testify/require"
+
+	"github.com/uber/kraken/utils/randutil"
+)
+
+// smallWritesClient downloads content in small writes, like a backend
+// copying from a network stream.
+type smallWritesClient struct {
+	NoopClient
+	content   []byte
+	writeSize int
+}
+
+func (c smallWritesClient) Download(namespace, name string, dst io.Writer) error {
+	for b := c.content; len(b) > 0; {
+		n := c.writeSize
+		if n > len(b) {
+			n = len(b)
+		}
+		if _, err := dst.Write(b[:n]); err != nil {
+			return err
+		}
+		b = b[n:]
+	}
+	return nil
+}
+
+// slowWriter records the writes it receives, sleeping on each.
+type slowWriter struct {
+	bytes.Buffer
+	writes int
+	delay  time.Duration
+	err    error
+}
+
+func (w *slowWriter) Write(p []byte) (int, error) {
+	w.writes++
+	time.Sleep(w.delay)
+	if w.err != nil {
+		return 0, w.err
+	}
+	return w.Buffer.Write(p)
+}
+
+func TestReadAheadClientDownload(t *testing.T) {
+	require := require.New(t)
+
+	content := randutil.Text(10 * 1024)
+	c := readAhead(smallWritesClient{content: content, writeSize: 100}, ReadAheadConfig{
+		BufferSize: 4 * datasize.KB,
+		ChunkSize:  datasize.KB,
+	})
+
+	w := &slowWriter{delay: time.Millisecond}
+	require.NoError(c.Download("ns", "name", w))
+	require.Equal(content, w.Bytes())
+
+	// Small backend writes are coalesced into chunk sized writes.
+	require.Equal(10, w.writes)
+}
+
+func TestReadAheadClientDownloadDestinationError(t *testing.T) {
+	require := require.New(t)
+
+	c := readAhead(smallWritesClient{content: randutil.Text(10 * 1024), writeSize: 100}, ReadAheadConfig{
+		BufferSize: 2 * datasize.KB,
+		ChunkSize:  datasize.KB,
+	})
+
+	err := errors.New("some error")
+	require.Equal(err, c.Download("ns", "name", &slowWriter{err: err}))
+}
+
+func TestReadAheadClientDownloadBackendError(t *testing.T) {
+	require := require.New(t)
+
+	c := readAhead(NoopClient{}, ReadAheadConfig{})
+
+	require.Error(c.Download("ns", "name", &bytes.Buffer{}))
+}
+
+func TestReadAheadConfigDefaults(t *testing.T) {
+	require := require.New(t)
+
+	c := ReadAheadConfig{}.applyDefaults()
+	require.Equal(_defaultReadAheadBufferSize, c.BufferSize)
+	require.Equal(_defaultReadAheadChunkSize, c.ChunkSize)
+
+	// Buffer size is bounded.
+	c = ReadAheadConfig{BufferSize: 10 * datasize.GB}.applyDefaults()
+	require.Equal(_maxReadAheadBufferSize, c.BufferSize)
+
+	c = ReadAheadConfig{BufferSize: datasize.KB, ChunkSize: datasize.MB}.applyDefaults()
+	require.Equal(datasize.KB, c.ChunkSize)
+}
+
+func BenchmarkReadAheadDownload(b *testing.B) {
+	content := randutil.Text(8 * 1024 * 1024)
+	for _, test := range []struct {
+		name   string
+		client Client
+	}{
+		{"unbuffered", smallWritesClient{content: content, writeSize: 32 * 1024}},
+		{"read_ahead", readAhead(
+			smallWritesClient{content: content, writeSize: 32 * 1024}, ReadAheadConfig{})},
+	} {
+		b.Run(test.name, func(b *testing.B) {
+			b.SetBytes(int64(len(content)))
+			for i := 0; i < b.N; i++ {
+				w := &slowWriter{delay: 10 * time.Microsecond}
+				if err := test.client.Download("ns", "name", w); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
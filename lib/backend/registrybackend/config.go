@@ -17,6 +17,7 @@ import (
 	"time"
 
 	"github.com/uber/kraken/lib/backend/registrybackend/security"
+	"github.com/uber/kraken/utils/dockerutil"
 )
 
 // Config defines the registry address, timeout and security options.
@@ -24,6 +25,10 @@ type Config struct {
 	Address  string          `yaml:"address"`
 	Timeout  time.Duration   `yaml:"timeout"`
 	Security security.Config `yaml:"security"`
+
+	// Manifest configures parsing of tag manifests and the manifest types
+	// requested from the registry.
+	Manifest dockerutil.ParserConfig `yaml:"manifest"`
 }
 
 // Set default configuration
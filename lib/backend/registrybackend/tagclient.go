@@ -61,6 +61,7 @@ const _tagquery = "http://%s/v2/%s/manifests/%s"
 type TagClient struct {
 	config        Config
 	authenticator security.Authenticator
+	parser        *dockerutil.Parser
 	stats         tally.Scope
 }
 
@@ -74,6 +75,7 @@ func NewTagClient(config Config, stats tally.Scope) (*TagClient, error) {
 	return &TagClient{
 		config:        config,
 		authenticator: authenticator,
+		parser:        dockerutil.NewParser(config.Manifest),
 		stats:         stats,
 	}, nil
 }
@@ -96,7 +98,7 @@ func (c *TagClient) Stat(namespace, name string) (*core.BlobInfo, error) {
 		URL,
 		append(
 			opts,
-			httputil.SendHeaders(map[string]string{"Accept": dockerutil.GetSupportedManifestTypes()}),
+			httputil.SendHeaders(map[string]string{"Accept": c.parser.GetSupportedManifestTypes()}),
 			httputil.SendAcceptedCodes(http.StatusOK, http.StatusNotFound),
 		)...,
 	)
@@ -132,7 +134,7 @@ func (c *TagClient) Download(namespace, name string, dst io.Writer) error {
 		URL,
 		append(
 			opts,
-			httputil.SendHeaders(map[string]string{"Accept": dockerutil.GetSupportedManifestTypes()}),
+			httputil.SendHeaders(map[string]string{"Accept": c.parser.GetSupportedManifestTypes()}),
 			httputil.SendAcceptedCodes(http.StatusOK, http.StatusNotFound),
 		)...,
 	)
@@ -145,7 +147,7 @@ func (c *TagClient) Download(namespace, name string, dst io.Writer) error {
 		return backenderrors.ErrBlobNotFound
 	}
 
-	_, digest, err := dockerutil.ParseManifest(resp.Body)
+	_, digest, err := c.parser.ParseManifest(resp.Body)
 	if err != nil {
 		return fmt.Errorf("parse manifest v2: %s", err)
 	}
@@ -21,6 +21,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/docker/distribution/manifest/schema1"
 	"github.com/go-chi/chi"
 	"github.com/stretchr/testify/require"
 	"github.com/uber-go/tally"
@@ -102,3 +103,31 @@ func TestTagDownloadFileNotFound(t *testing.T) {
 	var b bytes.Buffer
 	require.Equal(backenderrors.ErrBlobNotFound, client.Download(tag, tag, &b))
 }
+
+func TestTagStatAcceptsConfiguredManifestTypes(t *testing.T) {
+	require := require.New(t)
+
+	tag := core.TagFixture()
+	namespace := strings.Split(tag, ":")[0]
+
+	var accept string
+	r := chi.NewRouter()
+	r.Head(fmt.Sprintf("/v2/%s/manifests/{tag}", namespace), func(w http.ResponseWriter, req *http.Request) {
+		accept = req.Header.Get("Accept")
+		w.Header().Set("Content-Length", "1")
+	})
+	addr, stop := testutil.StartServer(r)
+	defer stop()
+
+	config := newTestConfig(addr)
+	config.Manifest = dockerutil.ParserConfig{DisableSchema1: true}
+	client, err := NewTagClient(config, tally.NoopScope)
+	require.NoError(err)
+	defer closers.Close(client)
+
+	_, err = client.Stat(tag, tag)
+	require.NoError(err)
+	require.Equal(
+		dockerutil.NewParser(config.Manifest).GetSupportedManifestTypes(), accept)
+	require.NotContains(accept, schema1.MediaTypeSignedManifest)
+}
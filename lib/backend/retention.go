@@ -0,0 +1,70 @@
+// Copyright (c) 2016-2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package backend
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Retention modes. In governance mode, privileged users may still shorten
+// retention or delete locked blobs. In compliance mode, nobody can until the
+// retention period expires.
+const (
+	RetentionModeGovernance = "governance"
+	RetentionModeCompliance = "compliance"
+)
+
+// RetentionConfig configures immutability of blobs uploaded to a namespace.
+// Enforcement is delegated to the storage provider, e.g. S3 Object Lock or
+// GCS bucket retention policies, so locked blobs cannot be overwritten or
+// deleted through any client until the retention period expires.
+type RetentionConfig struct {
+	Enabled bool          `yaml:"enabled"`
+	Mode    string        `yaml:"mode"`
+	Period  time.Duration `yaml:"period"`
+}
+
+func (c RetentionConfig) applyDefaults() RetentionConfig {
+	if c.Mode == "" {
+		c.Mode = RetentionModeGovernance
+	}
+	return c
+}
+
+// Validate returns an error if c is enabled but malformed.
+func (c RetentionConfig) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.Mode != RetentionModeGovernance && c.Mode != RetentionModeCompliance {
+		return fmt.Errorf("invalid mode %q", c.Mode)
+	}
+	if c.Period <= 0 {
+		return errors.New("period must be positive")
+	}
+	return nil
+}
+
+// RetentionClient is implemented by Clients which can make uploaded blobs
+// immutable for a retention period.
+type RetentionClient interface {
+	Client
+
+	// SetRetention configures all subsequent uploads to be locked according
+	// to config. Returns error if the underlying storage cannot enforce
+	// config. Called once at startup before the client is used.
+	SetRetention(config RetentionConfig) error
+}
@@ -14,10 +14,14 @@
 package s3backend
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
+	"net/http"
 	"path"
+	"strings"
+	"time"
 
 	"github.com/uber-go/tally"
 	"github.com/uber/kraken/core"
@@ -30,6 +34,7 @@ import (
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/s3/s3manager"
@@ -71,10 +76,11 @@ func (f *factory) Create(
 
 // Client implements a backend.Client for S3.
 type Client struct {
-	config Config
-	pather namepath.Pather
-	stats  tally.Scope
-	s3     S3
+	config    Config
+	pather    namepath.Pather
+	stats     tally.Scope
+	s3        S3
+	retention backend.RetentionConfig
 }
 
 // Option allows setting optional Client parameters.
@@ -145,7 +151,12 @@ func NewClient(
 		u.Concurrency = config.UploadConcurrency
 	})
 
-	client := &Client{config, pather, stats, join{api, downloader, uploader}}
+	client := &Client{
+		config: config,
+		pather: pather,
+		stats:  stats,
+		s3:     join{api, downloader, uploader},
+	}
 	for _, opt := range opts {
 		opt(client)
 	}
@@ -172,7 +183,46 @@ func (c *Client) Stat(namespace, name string) (*core.BlobInfo, error) {
 	if output.ContentLength != nil {
 		size = *output.ContentLength
 	}
-	return core.NewBlobInfo(size), nil
+	bi := core.NewBlobInfo(size)
+	bi.Version = aws.StringValue(output.ETag)
+	return bi, nil
+}
+
+// Checksum returns the md5 of name from its ETag. Returns
+// backenderrors.ErrChecksumNotSupported for multipart uploads and for objects
+// encrypted with SSE-KMS or SSE-C, whose ETags are not the md5 of the content.
+func (c *Client) Checksum(ctx context.Context, name string) (core.Digest, error) {
+	path, err := c.pather.BlobPath(name)
+	if err != nil {
+		return core.Digest{}, fmt.Errorf("blob path: %s", err)
+	}
+	output, err := c.s3.HeadObject(&s3.HeadObjectInput{
+		Bucket: aws.String(c.config.Bucket),
+		Key:    aws.String(path),
+	})
+	if err != nil {
+		if isNotFound(err) {
+			return core.Digest{}, backenderrors.ErrBlobNotFound
+		}
+		return core.Digest{}, err
+	}
+	// Matches both aws:kms and aws:kms:dsse.
+	sse := aws.StringValue(output.ServerSideEncryption)
+	if strings.HasPrefix(sse, s3.ServerSideEncryptionAwsKms) {
+		return core.Digest{}, fmt.Errorf("%w: etag of %s encrypted object", backenderrors.ErrChecksumNotSupported, sse)
+	}
+	if output.SSECustomerAlgorithm != nil {
+		return core.Digest{}, fmt.Errorf("%w: etag of SSE-C encrypted object", backenderrors.ErrChecksumNotSupported)
+	}
+	etag := strings.Trim(aws.StringValue(output.ETag), `"`)
+	if strings.Contains(etag, "-") {
+		return core.Digest{}, fmt.Errorf("%w: multipart etag %s", backenderrors.ErrChecksumNotSupported, etag)
+	}
+	d, err := core.NewChecksumDigest(core.MD5, etag)
+	if err != nil {
+		return core.Digest{}, fmt.Errorf("etag: %s", err)
+	}
+	return d, nil
 }
 
 // Download downloads the content from a configured bucket and writes the
@@ -211,8 +261,67 @@ func (c *Client) Download(namespace, name string, dst io.Writer) error {
 	return nil
 }
 
+// RangedDownloads returns true, since Download writes into io.WriterAt
+// destinations with concurrent ranged reads.
+func (c *Client) RangedDownloads() bool {
+	return true
+}
+
+// PresignDownload returns a presigned GET url for name which expires after ttl.
+func (c *Client) PresignDownload(ctx context.Context, name string, ttl time.Duration) (string, error) {
+	path, err := c.pather.BlobPath(name)
+	if err != nil {
+		return "", fmt.Errorf("blob path: %s", err)
+	}
+	return c.s3.PresignGetObject(&s3.GetObjectInput{
+		Bucket: aws.String(c.config.Bucket),
+		Key:    aws.String(path),
+	}, ttl)
+}
+
 // Upload uploads src to a configured bucket.
 func (c *Client) Upload(namespace, name string, src io.Reader) error {
+	return c.upload(name, src)
+}
+
+// UploadConditional uploads src into name if cond holds, using S3 conditional
+// writes. IfMatch versions are ETags, as reported by Stat.
+func (c *Client) UploadConditional(
+	namespace, name string, src io.Reader, cond backend.WriteCondition) error {
+
+	if err := cond.Validate(); err != nil {
+		return err
+	}
+	var opts []func(*s3manager.Uploader)
+	if cond.IfAbsent {
+		opts = append(opts, withPrecondition("If-None-Match", "*"))
+	} else if cond.IfMatch != "" {
+		opts = append(opts, withPrecondition("If-Match", cond.IfMatch))
+	}
+	if err := c.upload(name, src, opts...); err != nil {
+		if isPreconditionFailed(err) {
+			return backenderrors.ErrPreconditionFailed
+		}
+		return err
+	}
+	return nil
+}
+
+// withPrecondition sets a precondition header on the request which creates
+// the object: PutObject for single part uploads, or CompleteMultipartUpload
+// for multipart uploads. Sending it with individual parts is invalid.
+func withPrecondition(header, value string) func(*s3manager.Uploader) {
+	return func(u *s3manager.Uploader) {
+		u.RequestOptions = append(u.RequestOptions, func(r *request.Request) {
+			switch r.Operation.Name {
+			case "PutObject", "CompleteMultipartUpload":
+				r.HTTPRequest.Header.Set(header, value)
+			}
+		})
+	}
+}
+
+func (c *Client) upload(name string, src io.Reader, opts ...func(*s3manager.Uploader)) error {
 	path, err := c.pather.BlobPath(name)
 	if err != nil {
 		return fmt.Errorf("blob path: %s", err)
@@ -222,12 +331,66 @@ func (c *Client) Upload(namespace, name string, src io.Reader) error {
 		Key:    aws.String(path),
 		Body:   src,
 	}
-	_, err = c.s3.Upload(input, func(u *s3manager.Uploader) {
+	if c.config.SSEKMSKeyID != "" {
+		input.ServerSideEncryption = aws.String(s3.ServerSideEncryptionAwsKms)
+		input.SSEKMSKeyId = aws.String(c.config.SSEKMSKeyID)
+	}
+	if c.retention.Enabled {
+		input.ObjectLockMode = aws.String(strings.ToUpper(c.retention.Mode))
+		input.ObjectLockRetainUntilDate = aws.Time(time.Now().Add(c.retention.Period))
+	}
+	opts = append([]func(*s3manager.Uploader){func(u *s3manager.Uploader) {
 		u.LeavePartsOnError = false // Delete the parts if the upload fails.
-	})
+	}}, opts...)
+	_, err = c.s3.Upload(input, opts...)
 	return err
 }
 
+// SetRetention locks all subsequent uploads using S3 Object Lock. Returns
+// error if Object Lock is not enabled on the configured bucket.
+func (c *Client) SetRetention(config backend.RetentionConfig) error {
+	output, err := c.s3.GetObjectLockConfiguration(&s3.GetObjectLockConfigurationInput{
+		Bucket: aws.String(c.config.Bucket),
+	})
+	if err != nil {
+		return fmt.Errorf("get object lock configuration: %s", err)
+	}
+	if output.ObjectLockConfiguration == nil ||
+		aws.StringValue(output.ObjectLockConfiguration.ObjectLockEnabled) != s3.ObjectLockEnabledEnabled {
+		return fmt.Errorf("object lock not enabled on bucket %s", c.config.Bucket)
+	}
+	c.retention = config
+	return nil
+}
+
+// EncryptionMode returns backend.EncryptionModeSSEKMS if a KMS key is
+// configured.
+func (c *Client) EncryptionMode() string {
+	if c.config.SSEKMSKeyID == "" {
+		return ""
+	}
+	return backend.EncryptionModeSSEKMS
+}
+
+// isPreconditionFailed returns true if err, or any error it wraps, is an S3
+// precondition failure.
+func isPreconditionFailed(err error) bool {
+	for err != nil {
+		if rf, ok := err.(awserr.RequestFailure); ok && rf.StatusCode() == http.StatusPreconditionFailed {
+			return true
+		}
+		awsErr, ok := err.(awserr.Error)
+		if !ok {
+			return false
+		}
+		if awsErr.Code() == "PreconditionFailed" {
+			return true
+		}
+		err = awsErr.OrigErr()
+	}
+	return false
+}
+
 func isNotFound(err error) bool {
 	awsErr, ok := err.(awserr.Error)
 	return ok && (awsErr.Code() == s3.ErrCodeNoSuchKey || awsErr.Code() == "NotFound")
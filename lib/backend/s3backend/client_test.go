@@ -15,11 +15,18 @@ package s3backend
 
 import (
 	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
 	"testing"
+	"time"
 
 	"github.com/uber-go/tally"
 	"github.com/uber/kraken/core"
 	"github.com/uber/kraken/lib/backend"
+	"github.com/uber/kraken/lib/backend/backenderrors"
 	mocks3backend "github.com/uber/kraken/mocks/lib/backend/s3backend"
 	"github.com/uber/kraken/utils/closers"
 	"github.com/uber/kraken/utils/mockutil"
@@ -27,6 +34,8 @@ import (
 	"github.com/uber/kraken/utils/rwutil"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/s3/s3manager"
 	"github.com/golang/mock/gomock"
@@ -102,11 +111,81 @@ func TestClientStat(t *testing.T) {
 	mocks.s3.EXPECT().HeadObject(&s3.HeadObjectInput{
 		Bucket: aws.String("test-bucket"),
 		Key:    aws.String("/root/test"),
-	}).Return(&s3.HeadObjectOutput{ContentLength: &length}, nil)
+	}).Return(&s3.HeadObjectOutput{ContentLength: &length, ETag: aws.String(`"abc"`)}, nil)
 
 	info, err := client.Stat(core.NamespaceFixture(), "test")
 	require.NoError(err)
-	require.Equal(core.NewBlobInfo(100), info)
+	require.Equal(&core.BlobInfo{Size: 100, Version: `"abc"`}, info)
+}
+
+func TestClientChecksum(t *testing.T) {
+	require := require.New(t)
+
+	mocks, cleanup := newClientMocks(t)
+	defer cleanup()
+
+	client := mocks.new()
+	defer closers.Close(client)
+
+	mocks.s3.EXPECT().HeadObject(&s3.HeadObjectInput{
+		Bucket: aws.String("test-bucket"),
+		Key:    aws.String("/root/test"),
+	}).Return(&s3.HeadObjectOutput{ETag: aws.String(`"d41d8cd98f00b204e9800998ecf8427e"`)}, nil)
+
+	d, err := client.Checksum(context.Background(), "test")
+	require.NoError(err)
+	require.Equal("md5:d41d8cd98f00b204e9800998ecf8427e", d.String())
+}
+
+func TestClientChecksumMultipartNotSupported(t *testing.T) {
+	require := require.New(t)
+
+	mocks, cleanup := newClientMocks(t)
+	defer cleanup()
+
+	client := mocks.new()
+	defer closers.Close(client)
+
+	mocks.s3.EXPECT().HeadObject(&s3.HeadObjectInput{
+		Bucket: aws.String("test-bucket"),
+		Key:    aws.String("/root/test"),
+	}).Return(&s3.HeadObjectOutput{ETag: aws.String(`"d41d8cd98f00b204e9800998ecf8427e-3"`)}, nil)
+
+	_, err := client.Checksum(context.Background(), "test")
+	require.True(errors.Is(err, backenderrors.ErrChecksumNotSupported))
+}
+
+func TestClientChecksumEncryptedNotSupported(t *testing.T) {
+	for _, test := range []struct {
+		desc   string
+		output *s3.HeadObjectOutput
+	}{
+		{"sse-kms", &s3.HeadObjectOutput{ServerSideEncryption: aws.String("aws:kms")}},
+		{"dsse-kms", &s3.HeadObjectOutput{ServerSideEncryption: aws.String("aws:kms:dsse")}},
+		{"sse-c", &s3.HeadObjectOutput{
+			ServerSideEncryption: aws.String("AES256"),
+			SSECustomerAlgorithm: aws.String("AES256"),
+		}},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			require := require.New(t)
+
+			mocks, cleanup := newClientMocks(t)
+			defer cleanup()
+
+			client := mocks.new()
+			defer closers.Close(client)
+
+			test.output.ETag = aws.String(`"d41d8cd98f00b204e9800998ecf8427e"`)
+			mocks.s3.EXPECT().HeadObject(&s3.HeadObjectInput{
+				Bucket: aws.String("test-bucket"),
+				Key:    aws.String("/root/test"),
+			}).Return(test.output, nil)
+
+			_, err := client.Checksum(context.Background(), "test")
+			require.True(errors.Is(err, backenderrors.ErrChecksumNotSupported))
+		})
+	}
 }
 
 func TestClientDownload(t *testing.T) {
@@ -158,6 +237,38 @@ func TestClientDownloadWithBuffer(t *testing.T) {
 	require.Equal(data, []byte(w))
 }
 
+func TestClientDownloadReadAheadPassesThroughWriterAt(t *testing.T) {
+	require := require.New(t)
+
+	mocks, cleanup := newClientMocks(t)
+	defer cleanup()
+
+	client := mocks.new()
+	defer closers.Close(client)
+
+	data := randutil.Text(32)
+
+	dst, err := os.CreateTemp("", "s3readahead")
+	require.NoError(err)
+	defer os.Remove(dst.Name())
+	defer closers.Close(dst)
+
+	mocks.s3.EXPECT().Download(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(w io.WriterAt, input *s3.GetObjectInput, options ...func(*s3manager.Downloader)) (int64, error) {
+			// Concurrent ranged reads must write directly into dst.
+			require.True(w == dst)
+			_, err := w.WriteAt(data, 0)
+			return int64(len(data)), err
+		})
+
+	c := backend.ReadAheadClientFixture(client, backend.ReadAheadConfig{})
+	require.NoError(c.Download(core.NamespaceFixture(), "test", dst))
+
+	b, err := os.ReadFile(dst.Name())
+	require.NoError(err)
+	require.Equal(data, b)
+}
+
 func TestClientUpload(t *testing.T) {
 	require := require.New(t)
 
@@ -181,6 +292,237 @@ func TestClientUpload(t *testing.T) {
 	require.NoError(client.Upload(core.NamespaceFixture(), "test", data))
 }
 
+// applyRequestOptions applies the uploader options passed to s3.Upload to a
+// request for operation, returning the resulting request headers.
+func applyRequestOptions(opts []func(*s3manager.Uploader), operation string) http.Header {
+	var u s3manager.Uploader
+	for _, opt := range opts {
+		opt(&u)
+	}
+	r := &request.Request{
+		Operation:   &request.Operation{Name: operation},
+		HTTPRequest: &http.Request{Header: make(http.Header)},
+	}
+	r.ApplyOptions(u.RequestOptions...)
+	return r.HTTPRequest.Header
+}
+
+func TestClientUploadConditional(t *testing.T) {
+	tests := []struct {
+		desc   string
+		cond   backend.WriteCondition
+		header string
+		value  string
+	}{
+		{"if absent", backend.WriteCondition{IfAbsent: true}, "If-None-Match", "*"},
+		{"if match", backend.WriteCondition{IfMatch: `"abc"`}, "If-Match", `"abc"`},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			require := require.New(t)
+
+			mocks, cleanup := newClientMocks(t)
+			defer cleanup()
+
+			client := mocks.new()
+			defer closers.Close(client)
+
+			data := bytes.NewReader(randutil.Text(32))
+
+			mocks.s3.EXPECT().Upload(gomock.Any(), gomock.Any()).DoAndReturn(
+				func(input *s3manager.UploadInput, opts ...func(*s3manager.Uploader)) (*s3manager.UploadOutput, error) {
+					for _, op := range []string{"PutObject", "CompleteMultipartUpload"} {
+						require.Equal(test.value, applyRequestOptions(opts, op).Get(test.header), op)
+					}
+					require.Empty(applyRequestOptions(opts, "UploadPart").Get(test.header))
+					return nil, nil
+				})
+
+			require.NoError(client.UploadConditional(core.NamespaceFixture(), "test", data, test.cond))
+		})
+	}
+}
+
+func TestClientUploadConditionalPreconditionFailed(t *testing.T) {
+	preconditionFailed := awserr.NewRequestFailure(
+		awserr.New("PreconditionFailed", "At least one of the preconditions failed", nil),
+		http.StatusPreconditionFailed, "request-id")
+
+	tests := []struct {
+		desc string
+		err  error
+	}{
+		{"single part", preconditionFailed},
+		{"multipart", awserr.New("MultipartUpload", "upload multipart failed", preconditionFailed)},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			require := require.New(t)
+
+			mocks, cleanup := newClientMocks(t)
+			defer cleanup()
+
+			client := mocks.new()
+			defer closers.Close(client)
+
+			mocks.s3.EXPECT().Upload(gomock.Any(), gomock.Any()).Return(nil, test.err)
+
+			err := client.UploadConditional(
+				core.NamespaceFixture(), "test", bytes.NewReader(randutil.Text(32)),
+				backend.WriteCondition{IfAbsent: true})
+			require.Equal(backenderrors.ErrPreconditionFailed, err)
+		})
+	}
+}
+
+func TestClientUploadConditionalInvalid(t *testing.T) {
+	mocks, cleanup := newClientMocks(t)
+	defer cleanup()
+
+	client := mocks.new()
+	defer closers.Close(client)
+
+	err := client.UploadConditional(
+		core.NamespaceFixture(), "test", bytes.NewReader(randutil.Text(32)),
+		backend.WriteCondition{IfAbsent: true, IfMatch: `"abc"`})
+	require.Error(t, err)
+}
+
+func TestClientSetRetention(t *testing.T) {
+	tests := []struct {
+		name    string
+		output  *s3.GetObjectLockConfigurationOutput
+		err     error
+		wantErr bool
+	}{
+		{
+			name: "enabled",
+			output: &s3.GetObjectLockConfigurationOutput{
+				ObjectLockConfiguration: &s3.ObjectLockConfiguration{
+					ObjectLockEnabled: aws.String(s3.ObjectLockEnabledEnabled),
+				},
+			},
+		},
+		{
+			name:    "not configured",
+			output:  &s3.GetObjectLockConfigurationOutput{},
+			wantErr: true,
+		},
+		{
+			name:    "error",
+			err:     errors.New("some error"),
+			wantErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require := require.New(t)
+
+			mocks, cleanup := newClientMocks(t)
+			defer cleanup()
+
+			client := mocks.new()
+
+			mocks.s3.EXPECT().GetObjectLockConfiguration(&s3.GetObjectLockConfigurationInput{
+				Bucket: aws.String("test-bucket"),
+			}).Return(test.output, test.err)
+
+			err := client.SetRetention(backend.RetentionConfig{
+				Enabled: true,
+				Mode:    backend.RetentionModeCompliance,
+				Period:  time.Hour,
+			})
+			if test.wantErr {
+				require.Error(err)
+			} else {
+				require.NoError(err)
+			}
+		})
+	}
+}
+
+func TestClientUploadWithRetention(t *testing.T) {
+	require := require.New(t)
+
+	mocks, cleanup := newClientMocks(t)
+	defer cleanup()
+
+	client := mocks.new()
+	defer closers.Close(client)
+
+	mocks.s3.EXPECT().GetObjectLockConfiguration(gomock.Any()).Return(
+		&s3.GetObjectLockConfigurationOutput{
+			ObjectLockConfiguration: &s3.ObjectLockConfiguration{
+				ObjectLockEnabled: aws.String(s3.ObjectLockEnabledEnabled),
+			},
+		}, nil)
+	require.NoError(client.SetRetention(backend.RetentionConfig{
+		Enabled: true,
+		Mode:    backend.RetentionModeCompliance,
+		Period:  time.Hour,
+	}))
+
+	data := bytes.NewReader(randutil.Text(32))
+
+	start := time.Now()
+	mocks.s3.EXPECT().Upload(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(input *s3manager.UploadInput, _ ...func(*s3manager.Uploader)) (*s3manager.UploadOutput, error) {
+			require.Equal(s3.ObjectLockModeCompliance, aws.StringValue(input.ObjectLockMode))
+			require.False(aws.TimeValue(input.ObjectLockRetainUntilDate).Before(start.Add(time.Hour)))
+			return nil, nil
+		})
+
+	require.NoError(client.Upload(core.NamespaceFixture(), "test", data))
+}
+
+func TestClientUploadWithSSEKMS(t *testing.T) {
+	require := require.New(t)
+
+	mocks, cleanup := newClientMocks(t)
+	defer cleanup()
+
+	keyARN := "arn:aws:kms:us-west-1:123456789012:key/test-key"
+	mocks.config.SSEKMSKeyID = keyARN
+
+	client := mocks.new()
+	defer closers.Close(client)
+
+	require.Equal(backend.EncryptionModeSSEKMS, client.EncryptionMode())
+
+	data := bytes.NewReader(randutil.Text(32))
+
+	mocks.s3.EXPECT().Upload(
+		&s3manager.UploadInput{
+			Bucket:               aws.String("test-bucket"),
+			Key:                  aws.String("/root/test"),
+			Body:                 data,
+			ServerSideEncryption: aws.String(s3.ServerSideEncryptionAwsKms),
+			SSEKMSKeyId:          aws.String(keyARN),
+		},
+		gomock.Any(),
+	).Return(nil, nil)
+
+	require.NoError(client.Upload(core.NamespaceFixture(), "test", data))
+}
+
+func TestClientPresignDownload(t *testing.T) {
+	require := require.New(t)
+
+	mocks, cleanup := newClientMocks(t)
+	defer cleanup()
+
+	client := mocks.new()
+
+	mocks.s3.EXPECT().PresignGetObject(&s3.GetObjectInput{
+		Bucket: aws.String("test-bucket"),
+		Key:    aws.String("/root/test"),
+	}, time.Minute).Return("https://test-bucket/root/test?sig", nil)
+
+	url, err := client.PresignDownload(context.Background(), "test", time.Minute)
+	require.NoError(err)
+	require.Equal("https://test-bucket/root/test?sig", url)
+}
+
 func TestClientList(t *testing.T) {
 	require := require.New(t)
 
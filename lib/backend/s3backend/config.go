@@ -45,6 +45,10 @@ type Config struct {
 
 	// NamePath identifies which namepath.Pather to use.
 	NamePath string `yaml:"name_path"`
+
+	// SSEKMSKeyID is the ARN of the KMS key used to encrypt uploads with
+	// SSE-KMS. Uploads use the bucket default encryption if empty.
+	SSEKMSKeyID string `yaml:"sse_kms_key_id"`
 }
 
 // UserAuthConfig defines authentication configuration overlayed by Langley.
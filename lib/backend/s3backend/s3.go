@@ -15,6 +15,7 @@ package s3backend
 
 import (
 	"io"
+	"time"
 
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/s3/s3iface"
@@ -25,6 +26,9 @@ import (
 type S3 interface {
 	HeadObject(input *s3.HeadObjectInput) (*s3.HeadObjectOutput, error)
 
+	GetObjectLockConfiguration(
+		input *s3.GetObjectLockConfigurationInput) (*s3.GetObjectLockConfigurationOutput, error)
+
 	Download(
 		w io.WriterAt,
 		input *s3.GetObjectInput,
@@ -35,6 +39,8 @@ type S3 interface {
 		options ...func(*s3manager.Uploader)) (*s3manager.UploadOutput, error)
 
 	ListObjectsV2Pages(input *s3.ListObjectsV2Input, fn func(*s3.ListObjectsV2Output, bool) bool) error
+
+	PresignGetObject(input *s3.GetObjectInput, ttl time.Duration) (string, error)
 }
 
 type join struct {
@@ -44,3 +50,8 @@ type join struct {
 }
 
 var _ S3 = (*join)(nil)
+
+func (j join) PresignGetObject(input *s3.GetObjectInput, ttl time.Duration) (string, error) {
+	req, _ := j.S3API.GetObjectRequest(input)
+	return req.Presign(ttl)
+}
@@ -14,8 +14,12 @@
 package backend
 
 import (
+	"context"
 	"io"
+	"time"
 
+	"github.com/uber/kraken/core"
+	"github.com/uber/kraken/lib/backend/backenderrors"
 	"github.com/uber/kraken/lib/store"
 	"github.com/uber/kraken/utils/bandwidth"
 	"github.com/uber/kraken/utils/log"
@@ -42,6 +46,24 @@ var _ sizer = (store.FileReader)(nil)
 
 // Upload uploads src into name.
 func (c *ThrottledClient) Upload(namespace, name string, src io.Reader) error {
+	c.reserveUpload(name, src)
+	return c.Client.Upload(namespace, name, src)
+}
+
+// UploadConditional forwards to the underlying client, throttling like Upload.
+func (c *ThrottledClient) UploadConditional(
+	namespace, name string, src io.Reader, cond WriteCondition) error {
+
+	// Check before reserving, so callers falling back to Upload are not
+	// throttled twice.
+	if !supportsConditionalWrite(c.Client) {
+		return backenderrors.ErrConditionalWriteNotSupported
+	}
+	c.reserveUpload(name, src)
+	return UploadConditional(c.Client, namespace, name, src, cond)
+}
+
+func (c *ThrottledClient) reserveUpload(name string, src io.Reader) {
 	if s, ok := src.(sizer); ok {
 		// Only throttle if the src implements a Size method.
 		if err := c.bandwidth.ReserveEgress(s.Size()); err != nil {
@@ -49,7 +71,6 @@ func (c *ThrottledClient) Upload(namespace, name string, src io.Reader) error {
 			// Ignore error.
 		}
 	}
-	return c.Client.Upload(namespace, name, src)
 }
 
 // Download downloads name into dst.
@@ -65,6 +86,19 @@ func (c *ThrottledClient) Download(namespace, name string, dst io.Writer) error
 	return c.Client.Download(namespace, name, dst)
 }
 
+// Checksum forwards to the underlying client.
+func (c *ThrottledClient) Checksum(ctx context.Context, name string) (core.Digest, error) {
+	return Checksum(ctx, c.Client, name)
+}
+
+// PresignDownload forwards to the underlying client. Presigned downloads
+// bypass the backend client entirely, so they are not throttled.
+func (c *ThrottledClient) PresignDownload(
+	ctx context.Context, name string, ttl time.Duration) (string, error) {
+
+	return PresignDownload(ctx, c.Client, name, ttl)
+}
+
 func (c *ThrottledClient) adjustBandwidth(denominator int) error {
 	return c.bandwidth.Adjust(denominator)
 }
@@ -35,3 +35,10 @@ func (c *Config) applyDefaults() {
 		c.RefreshInterval = 10 * time.Second
 	}
 }
+
+// MaxReplicaOrDefault returns the max replica used by rings built from c,
+// applying the default if MaxReplica is unset.
+func (c Config) MaxReplicaOrDefault() int {
+	c.applyDefaults()
+	return c.MaxReplica
+}
@@ -45,6 +45,7 @@ type Watcher interface {
 // to be healthy (see Locations).
 type Ring interface {
 	Locations(d core.Digest) []string
+	LocationsN(d core.Digest, n int) []string
 	Contains(addr string) bool
 	Monitor(stop <-chan struct{})
 	Refresh()
@@ -94,6 +95,12 @@ func New(
 // the first address which owns d (regardless of health). As such, Locations
 // always returns a non-empty list.
 func (r *ring) Locations(d core.Digest) []string {
+	return r.LocationsN(d, r.config.MaxReplica)
+}
+
+// LocationsN is the same as Locations, except the replica set is limited to
+// the first n addresses which own d instead of the configured MaxReplica.
+func (r *ring) LocationsN(d core.Digest, n int) []string {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
@@ -108,7 +115,7 @@ func (r *ring) Locations(d core.Digest) []string {
 	}
 
 	var locs []string
-	for i := 0; i < len(nodes) && (len(locs) == 0 || i < r.config.MaxReplica); i++ {
+	for i := 0; i < len(nodes) && (len(locs) == 0 || i < n); i++ {
 		addr := nodes[i].Label
 		if r.healthy.Has(addr) {
 			locs = append(locs, addr)
@@ -76,6 +76,23 @@ func TestRingLocationsDistribution(t *testing.T) {
 	}
 }
 
+func TestRingLocationsN(t *testing.T) {
+	require := require.New(t)
+
+	r := New(
+		Config{MaxReplica: 2},
+		hostlist.Fixture(addrsFixture(6)...),
+		healthcheck.IdentityFilter{})
+
+	d := core.DigestFixture()
+
+	locs := r.LocationsN(d, 5)
+	require.Len(locs, 5)
+	require.Equal(r.Locations(d), locs[:2])
+	require.Equal(locs[:1], r.LocationsN(d, 1))
+	require.Len(r.LocationsN(d, 10), 6)
+}
+
 func TestRingLocationsFiltersOutUnhealthyHosts(t *testing.T) {
 	require := require.New(t)
 
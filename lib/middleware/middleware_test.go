@@ -17,6 +17,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
@@ -163,3 +164,36 @@ func TestStatusCounter(t *testing.T) {
 		})
 	}
 }
+
+func TestTokenAuth(t *testing.T) {
+	tests := []struct {
+		desc     string
+		token    string
+		header   string
+		expected int
+	}{
+		{"valid token", "secret", "Bearer secret", http.StatusOK},
+		{"wrong token", "secret", "Bearer wrong", http.StatusUnauthorized},
+		{"missing token", "secret", "", http.StatusUnauthorized},
+		{"token without bearer scheme", "secret", "secret", http.StatusUnauthorized},
+		{"token with other scheme", "secret", "Basic secret", http.StatusUnauthorized},
+		{"unconfigured token", "", "Bearer ", http.StatusForbidden},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			require := require.New(t)
+
+			r := chi.NewRouter()
+			r.Use(TokenAuth(test.token))
+			r.Get("/", func(w http.ResponseWriter, r *http.Request) {})
+
+			req := httptest.NewRequest("GET", "/", nil)
+			if test.header != "" {
+				req.Header.Set("Authorization", test.header)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			require.Equal(test.expected, w.Code)
+		})
+	}
+}
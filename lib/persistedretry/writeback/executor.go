@@ -20,6 +20,7 @@ import (
 
 	"github.com/uber-go/tally"
 	"github.com/uber/kraken/lib/backend"
+	"github.com/uber/kraken/lib/backend/backenderrors"
 	"github.com/uber/kraken/lib/persistedretry"
 	"github.com/uber/kraken/lib/store"
 	"github.com/uber/kraken/lib/store/metadata"
@@ -107,7 +108,18 @@ func (e *Executor) upload(t *Task) error {
 	}
 	defer closers.Close(f)
 
-	if err := client.Upload(t.Namespace, t.Name, f); err != nil {
+	// Create-if-absent avoids overwriting the blob if another origin uploaded
+	// it since the Stat above.
+	err = backend.UploadConditional(
+		client, t.Namespace, t.Name, f, backend.WriteCondition{IfAbsent: true})
+	if err == backenderrors.ErrConditionalWriteNotSupported {
+		err = client.Upload(t.Namespace, t.Name, f)
+	}
+	if err == backenderrors.ErrPreconditionFailed {
+		log.With("namespace", t.Namespace, "name", t.Name).Info("Cache file already uploaded by another origin")
+		return nil
+	}
+	if err != nil {
 		return fmt.Errorf("upload: %s", err)
 	}
 	log.With("namespace", t.Namespace, "name", t.Name).Info("Uploaded cache file to remote backend")
@@ -16,6 +16,7 @@ package writeback
 import (
 	"bytes"
 	"errors"
+	"io"
 	"testing"
 
 	"github.com/golang/mock/gomock"
@@ -187,3 +188,56 @@ func TestExecUploadFailure(t *testing.T) {
 	// metadata is still present.
 	require.Error(mocks.cas.DeleteCacheFile(blob.Digest.Hex()))
 }
+
+// conditionalClient is a mock client which supports conditional uploads.
+type conditionalClient struct {
+	*mockbackend.MockClient
+	err   error
+	conds []backend.WriteCondition
+}
+
+func (c *conditionalClient) UploadConditional(
+	namespace, name string, src io.Reader, cond backend.WriteCondition) error {
+
+	c.conds = append(c.conds, cond)
+	return c.err
+}
+
+func TestExecConditionalUpload(t *testing.T) {
+	tests := []struct {
+		desc     string
+		err      error
+		hasError bool
+	}{
+		{"uploaded", nil, false},
+		{"uploaded by another origin", backenderrors.ErrPreconditionFailed, false},
+		{"failure", errors.New("some error"), true},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			require := require.New(t)
+
+			mocks, cleanup := newExecutorMocks(t)
+			defer cleanup()
+
+			blob := core.NewBlobFixture()
+
+			setupBlob(t, mocks.cas, blob)
+
+			task := NewTask(core.TagFixture(), blob.Digest.Hex(), 0)
+
+			client := &conditionalClient{MockClient: mockbackend.NewMockClient(mocks.ctrl), err: test.err}
+			require.NoError(mocks.backends.Register(task.Namespace, client, false))
+			client.EXPECT().Stat(task.Namespace, blob.Digest.Hex()).Return(nil, backenderrors.ErrBlobNotFound)
+
+			executor := mocks.new()
+
+			if test.hasError {
+				require.Error(executor.Exec(task))
+			} else {
+				require.NoError(executor.Exec(task))
+			}
+			require.Equal([]backend.WriteCondition{{IfAbsent: true}}, client.conds)
+		})
+	}
+}
@@ -17,10 +17,12 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
 	"sync"
+	"syscall"
 
 	"github.com/uber/kraken/lib/store/metadata"
 	"github.com/uber/kraken/utils/closers"
@@ -77,6 +79,7 @@ type FileEntry interface {
 	Create(targetState FileState, len int64) error
 	Reload() error
 	MoveFrom(targetState FileState, sourcePath string) error
+	LinkFrom(targetState FileState, sourcePath string) error
 	Move(targetState FileState) error
 	LinkTo(targetPath string) error
 	Delete() error
@@ -369,6 +372,45 @@ func (entry *localFileEntry) MoveFrom(targetState FileState, sourcePath string)
 	return os.Rename(sourcePath, targetPath)
 }
 
+// LinkFrom hard links an unmanaged file in, so both paths share the same
+// physical bytes. Deleting either path leaves the other intact; the data is
+// only freed once the last link is removed. If sourcePath is on a different
+// filesystem, the file is copied instead.
+func (entry *localFileEntry) LinkFrom(targetState FileState, sourcePath string) error {
+	if entry.state != targetState {
+		return &FileStateError{
+			Op:    "LinkFrom",
+			Name:  entry.name,
+			State: entry.state,
+			Msg:   fmt.Sprintf("localFileEntry obj has state: %v", entry.state),
+		}
+	}
+
+	// Verify if file was already created.
+	targetPath := entry.GetPath()
+	if _, err := os.Stat(targetPath); err == nil {
+		return os.ErrExist
+	}
+
+	// Verify the source file exists.
+	if _, err := os.Stat(sourcePath); err != nil {
+		// Return os.ErrNotExist.
+		return err
+	}
+
+	// Create dir.
+	if err := os.MkdirAll(filepath.Dir(targetPath), DefaultDirPermission); err != nil {
+		return err
+	}
+
+	// Link data.
+	err := os.Link(sourcePath, targetPath)
+	if errors.Is(err, syscall.EXDEV) {
+		return copyFile(sourcePath, targetPath)
+	}
+	return err
+}
+
 // Move moves file to target dir under the same name, moves all metadata that's `movable`, and
 // updates state in memory.
 // If for any reason the target path already exists, it will be overwritten.
@@ -584,6 +626,32 @@ func (entry *localFileEntry) RangeMetadata(f func(md metadata.Metadata) error) e
 	return nil
 }
 
+// copyFile copies sourcePath to a new file at targetPath. targetPath is
+// removed if the copy fails.
+func copyFile(sourcePath, targetPath string) (err error) {
+	src, err := os.Open(sourcePath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(targetPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0775)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := dst.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			os.Remove(targetPath)
+		}
+	}()
+
+	_, err = io.Copy(dst, src)
+	return err
+}
+
 // compareAndWriteFile updates file with given bytes and returns true only if the file is updated
 // correctly.
 // It returns false if error happened or file already contains desired content.
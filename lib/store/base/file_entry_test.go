@@ -171,6 +171,8 @@ func TestFileEntry(t *testing.T) {
 		testMoveFromExisting,
 		testMoveFromWrongState,
 		testMoveFromWrongSourcePath,
+		testLinkFrom,
+		testLinkFromExisting,
 		testMove,
 		testLinkTo,
 		testDelete,
@@ -325,6 +327,76 @@ func testMoveFromWrongSourcePath(require *require.Assertions, bundle *fileEntryT
 	require.True(os.IsNotExist(err))
 }
 
+func testLinkFrom(require *require.Assertions, bundle *fileEntryTestBundle) {
+	fe := bundle.entry
+	s1 := bundle.state1
+	s3 := bundle.state3
+
+	fp := fe.GetPath()
+	testSourceFile, err := os.CreateTemp(s3.GetDirectory(), "")
+	require.NoError(err)
+	_, err = testSourceFile.WriteString("content")
+	require.NoError(err)
+	require.NoError(testSourceFile.Close())
+
+	// LinkFrom succeeds and keeps the source file.
+	require.NoError(fe.LinkFrom(s1, testSourceFile.Name()))
+	info, err := os.Stat(fp)
+	require.NoError(err)
+	sourceInfo, err := os.Stat(testSourceFile.Name())
+	require.NoError(err)
+	require.True(os.SameFile(info, sourceInfo))
+
+	// Deleting the entry only removes its link.
+	require.NoError(fe.Delete())
+	_, err = os.Stat(fp)
+	require.True(os.IsNotExist(err))
+	b, err := os.ReadFile(testSourceFile.Name())
+	require.NoError(err)
+	require.Equal("content", string(b))
+}
+
+func testLinkFromExisting(require *require.Assertions, bundle *fileEntryTestBundle) {
+	fe := bundle.entry
+	s1 := bundle.state1
+	s3 := bundle.state3
+
+	testSourceFile, err := os.CreateTemp(s3.GetDirectory(), "")
+	require.NoError(err)
+	require.NoError(fe.LinkFrom(s1, testSourceFile.Name()))
+
+	// LinkFrom fails with existing file.
+	testSourceFile2, err := os.CreateTemp(s3.GetDirectory(), "")
+	require.NoError(err)
+	require.True(os.IsExist(fe.LinkFrom(s1, testSourceFile2.Name())))
+
+	// LinkFrom fails with wrong state.
+	require.True(IsFileStateError(fe.LinkFrom(bundle.state2, testSourceFile2.Name())))
+}
+
+func TestCopyFile(t *testing.T) {
+	require := require.New(t)
+
+	dir := t.TempDir()
+	source := filepath.Join(dir, "source")
+	target := filepath.Join(dir, "target")
+	require.NoError(os.WriteFile(source, []byte("content"), 0775))
+
+	require.NoError(copyFile(source, target))
+	b, err := os.ReadFile(target)
+	require.NoError(err)
+	require.Equal("content", string(b))
+
+	info, err := os.Stat(target)
+	require.NoError(err)
+	sourceInfo, err := os.Stat(source)
+	require.NoError(err)
+	require.False(os.SameFile(info, sourceInfo))
+
+	// Existing targets are not overwritten.
+	require.True(os.IsExist(copyFile(source, target)))
+}
+
 func testMove(require *require.Assertions, bundle *fileEntryTestBundle) {
 	fe := bundle.entry
 	s1 := bundle.state1
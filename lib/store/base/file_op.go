@@ -40,6 +40,7 @@ type FileOp interface {
 
 	CreateFile(name string, createState FileState, len int64) error
 	MoveFileFrom(name string, createState FileState, sourcePath string) error
+	LinkFileFrom(name string, createState FileState, sourcePath string) error
 	MoveFile(name string, goalState FileState) error
 	LinkFileTo(name string, targetPath string) error
 	DeleteFile(name string) error
@@ -204,12 +205,12 @@ func (op *localFileOp) deleteHelper(
 }
 
 // createFileHelper is a helper function that adds a new file to store.
-// it either moves the new file from a unmanaged location, or creates an empty
-// file with specified size.
+// create either moves or links the new file from a unmanaged location, or
+// creates an empty file with specified size.
 // If file exists and is in an acceptable state, returns os.ErrExist.
 // If file exists but not in an acceptable state, returns FileStateError.
 func (op *localFileOp) createFileHelper(
-	name string, targetState FileState, sourcePath string, len int64) (err error) {
+	name string, targetState FileState, create func(entry FileEntry) error) (err error) {
 	// Check if file exists in in-memory map and is in an acceptable state.
 	loaded := op.s.fileMap.LoadForRead(name, func(name string, entry FileEntry) {
 		err = op.verifyStateHelper(name, entry)
@@ -237,18 +238,8 @@ func (op *localFileOp) createFileHelper(
 		return fmt.Errorf("create: %s", err)
 	}
 	if stored := op.s.fileMap.TryStore(name, newEntry, func(name string, entry FileEntry) bool {
-		if sourcePath != "" {
-			err = newEntry.MoveFrom(targetState, sourcePath)
-			if err != nil {
-				return false
-			}
-		} else {
-			err = newEntry.Create(targetState, len)
-			if err != nil {
-				return false
-			}
-		}
-		return true
+		err = create(newEntry)
+		return err == nil
 	}); err != nil {
 		return err
 	} else if !stored {
@@ -269,14 +260,29 @@ func (op *localFileOp) createFileHelper(
 // If file exists and is in an acceptable state, returns os.ErrExist.
 // If file exists but not in an acceptable state, returns FileStateError.
 func (op *localFileOp) CreateFile(name string, targetState FileState, len int64) (err error) {
-	return op.createFileHelper(name, targetState, "", len)
+	return op.createFileHelper(name, targetState, func(entry FileEntry) error {
+		return entry.Create(targetState, len)
+	})
 }
 
 // MoveFileFrom moves an unmanaged file into file store.
 // If file exists and is in an acceptable state, returns os.ErrExist.
 // If file exists but not in an acceptable state, returns FileStateError.
 func (op *localFileOp) MoveFileFrom(name string, targetState FileState, sourcePath string) (err error) {
-	return op.createFileHelper(name, targetState, sourcePath, -1)
+	return op.createFileHelper(name, targetState, func(entry FileEntry) error {
+		return entry.MoveFrom(targetState, sourcePath)
+	})
+}
+
+// LinkFileFrom hard links an unmanaged file into file store, falling back to
+// copy across filesystems. The file must not be modified through either path
+// afterwards, since both share the same physical bytes.
+// If file exists and is in an acceptable state, returns os.ErrExist.
+// If file exists but not in an acceptable state, returns FileStateError.
+func (op *localFileOp) LinkFileFrom(name string, targetState FileState, sourcePath string) (err error) {
+	return op.createFileHelper(name, targetState, func(entry FileEntry) error {
+		return entry.LinkFrom(targetState, sourcePath)
+	})
 }
 
 // MoveFile moves a file to a different directory and updates its state
@@ -70,11 +70,16 @@ type CAStore struct {
 	*cacheStore
 	cleanup *cleanupManager
 
-	memCache *cache.BlobMemoryCache
+	memCache  *cache.BlobMemoryCache
+	mmapCache *mmapCache
+	tmpfsTier *tmpfsTier
 
 	drain       *drain
 	ttlStopChan chan struct{}
 	ttlWg       sync.WaitGroup
+
+	verifyStopChan chan struct{}
+	verifyWg       sync.WaitGroup
 }
 
 // NewCAStore creates a new CAStore.
@@ -90,7 +95,8 @@ func newCAStore(config CAStoreConfig, stats tally.Scope, clk clock.Clock) (*CASt
 		"module": "castore",
 	})
 
-	uploadStore, err := newUploadStore(config.UploadDir, config.ReadPartSize, config.WritePartSize)
+	uploadStore, err := newUploadStore(
+		config.UploadDir, config.ReadPartSize, config.WritePartSize, config.ResumableUploads)
 	if err != nil {
 		return nil, fmt.Errorf("new upload store: %s", err)
 	}
@@ -109,9 +115,6 @@ func newCAStore(config CAStoreConfig, stats tally.Scope, clk clock.Clock) (*CASt
 	if err != nil {
 		return nil, fmt.Errorf("new cleanup manager: %s", err)
 	}
-	cleanup.addJob("upload", config.UploadCleanup, uploadStore.newFileOp())
-	cleanup.addJob("cache", config.CacheCleanup, cacheStore.newFileOp())
-
 	cas := &CAStore{
 		config:      config,
 		stats:       stats,
@@ -128,9 +131,45 @@ func newCAStore(config CAStoreConfig, stats tally.Scope, clk clock.Clock) (*CASt
 		startDrainWorkers(cas)
 	}
 
+	if config.MmapCache.Enabled {
+		cas.mmapCache = newMmapCache(config.MmapCache, stats)
+	}
+
+	if config.TmpfsTier.Enabled {
+		tier, err := newTmpfsTier(config.TmpfsTier, stats)
+		if err != nil {
+			return nil, fmt.Errorf("new tmpfs tier: %s", err)
+		}
+		cas.tmpfsTier = tier
+	}
+
+	if config.VerificationScan.Enabled {
+		cas.verifyStopChan = make(chan struct{})
+		cas.verifyWg.Add(1)
+		go cas.verificationScanWorker()
+	}
+
+	cleanup.addJob("upload", config.UploadCleanup, uploadStore.newFileOp())
+	cleanup.addJob("cache", config.CacheCleanup, &releasingFileOp{cacheStore.newFileOp(), cas})
+
 	return cas, nil
 }
 
+// releasingFileOp releases any memory mapping or tmpfs copy of files deleted
+// through it, such that files evicted by cleanup do not linger in either.
+type releasingFileOp struct {
+	base.FileOp
+	cas *CAStore
+}
+
+func (op *releasingFileOp) DeleteFile(name string) error {
+	if err := op.FileOp.DeleteFile(name); err != nil {
+		return err
+	}
+	op.cas.releaseCacheFile(name)
+	return nil
+}
+
 func createMemoryCache(config *CAStoreConfig, stats tally.Scope) *cache.BlobMemoryCache {
 	return cache.NewBlobMemoryCache(cache.BlobMemoryCacheConfig{
 		MaxSize: config.MemoryCache.MaxSize,
@@ -167,7 +206,26 @@ func (s *CAStore) Close() {
 		s.ttlWg.Wait()
 	}
 
+	if s.verifyStopChan != nil {
+		close(s.verifyStopChan)
+		s.verifyWg.Wait()
+	}
+
 	s.cleanup.stop()
+
+	if s.mmapCache != nil {
+		s.mmapCache.close()
+	}
+
+	if s.tmpfsTier != nil {
+		s.tmpfsTier.close()
+	}
+}
+
+// ResumableUploads returns true if uploads and their sessions are kept
+// across restarts.
+func (s *CAStore) ResumableUploads() bool {
+	return s.config.ResumableUploads
 }
 
 // MoveUploadFileToCache commits uploadName as cacheName. Clients are expected
@@ -356,6 +414,51 @@ func (s *CAStore) verify(r io.Reader, name string) error {
 	return nil
 }
 
+// VerifyCacheFile checks that the content of cache file name on disk matches
+// its digest, recording the verification in its metadata. Verification is
+// skipped, returning true, if the file was verified within VerificationMaxAge
+// and its modification time has not changed since. Unlike writes, this always
+// hashes the content, regardless of SkipHashVerification.
+func (s *CAStore) VerifyCacheFile(name string) (skipped bool, err error) {
+	d, err := core.NewSHA256DigestFromHex(name)
+	if err != nil {
+		return false, fmt.Errorf("new digest from file name: %s", err)
+	}
+	info, err := s.cacheStore.GetCacheFileStat(name)
+	if err != nil {
+		return false, err
+	}
+	var lv metadata.LastVerified
+	if err := s.cacheStore.GetCacheFileMetadata(name, &lv); err == nil {
+		if lv.Digest == d.String() &&
+			lv.ModTime.Equal(info.ModTime()) &&
+			s.clk.Now().Sub(lv.Time) < s.config.VerificationMaxAge {
+			return true, nil
+		}
+	} else if !os.IsNotExist(err) {
+		return false, fmt.Errorf("get last verified: %s", err)
+	}
+
+	f, err := s.cacheStore.GetCacheFileReader(name)
+	if err != nil {
+		return false, fmt.Errorf("get cache file: %s", err)
+	}
+	defer f.Close()
+	computed, err := core.NewDigester().FromReader(f)
+	if err != nil {
+		return false, fmt.Errorf("calculate digest: %s", err)
+	}
+	if computed != d {
+		return false, fmt.Errorf(
+			"%w: computed digest %s doesn't match expected value %s", errCacheFileCorrupt, computed, d)
+	}
+	lv = metadata.LastVerified{Digest: d.String(), Time: s.clk.Now(), ModTime: info.ModTime()}
+	if _, err := s.cacheStore.SetCacheFileMetadata(name, &lv); err != nil {
+		return false, fmt.Errorf("set last verified: %s", err)
+	}
+	return false, nil
+}
+
 func (s *CAStore) memoryCacheCleanupWorker() {
 	defer s.ttlWg.Done()
 
@@ -466,9 +569,78 @@ func (s *CAStore) GetCacheFileReader(name string) (FileReader, error) {
 		}
 	}
 
+	if s.mmapCache != nil {
+		if r, ok := s.getMmapCacheFileReader(name); ok {
+			return r, nil
+		}
+	}
+
+	if s.tmpfsTier != nil {
+		if r, ok := s.getTmpfsTierFileReader(name); ok {
+			return r, nil
+		}
+	}
+
 	return s.cacheStore.GetCacheFileReader(name)
 }
 
+// getMmapCacheFileReader returns a reader backed by a memory mapping of name
+// if the file is small enough to be mapped. The file is stat'ed on every call
+// so that mappings of evicted or rewritten files are never served.
+func (s *CAStore) getMmapCacheFileReader(name string) (FileReader, bool) {
+	info, err := s.cacheStore.GetCacheFileStat(name)
+	if err != nil {
+		s.mmapCache.evict(name)
+		return nil, false
+	}
+	if !s.mmapCache.eligible(info.Size()) {
+		return nil, false
+	}
+	p, err := s.cacheStore.newFileOp().GetFilePath(name)
+	if err != nil {
+		return nil, false
+	}
+	r, err := s.mmapCache.get(name, p, info)
+	if err != nil {
+		log.With("name", name).Errorf("Error mapping cache file: %s", err)
+		return nil, false
+	}
+	return r, true
+}
+
+// getTmpfsTierFileReader returns a reader over the tmpfs tier copy of name, if
+// name is hot. As with mmap, the disk file is stat'ed on every call so that
+// copies of evicted or rewritten files are never served.
+func (s *CAStore) getTmpfsTierFileReader(name string) (FileReader, bool) {
+	info, err := s.cacheStore.GetCacheFileStat(name)
+	if err != nil {
+		s.tmpfsTier.remove(name)
+		return nil, false
+	}
+	p, err := s.cacheStore.newFileOp().GetFilePath(name)
+	if err != nil {
+		return nil, false
+	}
+	return s.tmpfsTier.get(name, p, info)
+}
+
+// DeleteCacheFile overrides cacheStore.DeleteCacheFile to release any memory
+// mapping or tmpfs copy of the deleted file.
+func (s *CAStore) DeleteCacheFile(name string) error {
+	defer s.releaseCacheFile(name)
+	return s.cacheStore.DeleteCacheFile(name)
+}
+
+// releaseCacheFile releases any memory mapping or tmpfs copy of name.
+func (s *CAStore) releaseCacheFile(name string) {
+	if s.mmapCache != nil {
+		s.mmapCache.evict(name)
+	}
+	if s.tmpfsTier != nil {
+		s.tmpfsTier.remove(name)
+	}
+}
+
 // GetCacheFileMetadata overrides cacheStore.GetCacheFileMetadata to serve
 // TorrentMeta from memory cache when available.
 func (s *CAStore) GetCacheFileMetadata(name string, md metadata.Metadata) error {
@@ -191,6 +191,167 @@ func TestCAStoreCreateUploadFileAndMoveToCache(t *testing.T) {
 	require.NoError(err)
 }
 
+func TestCAStoreVerifyCacheFile(t *testing.T) {
+	require := require.New(t)
+
+	config, cleanup := CAStoreConfigFixture()
+	defer cleanup()
+	config.VerificationMaxAge = time.Hour
+
+	clk := clock.NewMock()
+	clk.Set(time.Now())
+	s, err := newCAStore(config, tally.NoopScope, clk)
+	require.NoError(err)
+	defer s.Close()
+
+	blob := core.NewBlobFixture()
+	name := blob.Digest.Hex()
+	require.NoError(s.CreateCacheFile(name, bytes.NewReader(blob.Content)))
+
+	skipped, err := s.VerifyCacheFile(name)
+	require.NoError(err)
+	require.False(skipped)
+
+	skipped, err = s.VerifyCacheFile(name)
+	require.NoError(err)
+	require.True(skipped)
+
+	// Records older than max age are not trusted.
+	clk.Add(time.Hour)
+	skipped, err = s.VerifyCacheFile(name)
+	require.NoError(err)
+	require.False(skipped)
+
+	// Neither are records of files modified since.
+	p, err := s.GetCacheFilePath(name)
+	require.NoError(err)
+	require.NoError(os.WriteFile(p, []byte("corrupted"), 0644))
+	mtime := time.Now().Add(time.Minute)
+	require.NoError(os.Chtimes(p, mtime, mtime))
+	_, err = s.VerifyCacheFile(name)
+	require.Error(err)
+	_, err = s.VerifyCacheFile(name)
+	require.Error(err)
+}
+
+func TestCAStoreVerificationScan(t *testing.T) {
+	require := require.New(t)
+
+	config, cleanup := CAStoreConfigFixture()
+	defer cleanup()
+	config.VerificationMaxAge = time.Hour
+
+	clk := clock.NewMock()
+	clk.Set(time.Now())
+	s, err := newCAStore(config, tally.NoopScope, clk)
+	require.NoError(err)
+	defer s.Close()
+
+	a := core.NewBlobFixture()
+	b := core.NewBlobFixture()
+	for _, blob := range []*core.BlobFixture{a, b} {
+		require.NoError(s.CreateCacheFile(blob.Digest.Hex(), bytes.NewReader(blob.Content)))
+	}
+
+	scan := func() []int {
+		verified, skipped, corrupt := s.verifyCacheFiles()
+		return []int{verified, skipped, corrupt}
+	}
+	touch := func(blob *core.BlobFixture, content []byte) {
+		p, err := s.GetCacheFilePath(blob.Digest.Hex())
+		require.NoError(err)
+		require.NoError(os.WriteFile(p, content, 0644))
+		mtime := time.Now().Add(time.Minute)
+		require.NoError(os.Chtimes(p, mtime, mtime))
+	}
+
+	require.Equal([]int{2, 0, 0}, scan())
+
+	// Unchanged files are skipped.
+	require.Equal([]int{0, 2, 0}, scan())
+
+	// Files whose modification time changed are re-verified.
+	touch(b, b.Content)
+	require.Equal([]int{1, 1, 0}, scan())
+
+	// Records older than max age are not trusted.
+	clk.Add(time.Hour)
+	require.Equal([]int{2, 0, 0}, scan())
+
+	// Corrupt files are deleted.
+	touch(a, []byte("corrupted"))
+	require.Equal([]int{0, 1, 1}, scan())
+	_, err = s.GetCacheFileStat(a.Digest.Hex())
+	require.True(os.IsNotExist(err))
+}
+
+func TestCAStoreVerificationScanOnStartup(t *testing.T) {
+	require := require.New(t)
+
+	config, cleanup := CAStoreConfigFixture()
+	defer cleanup()
+
+	blob := core.NewBlobFixture()
+	s, err := NewCAStore(config, tally.NoopScope)
+	require.NoError(err)
+	require.NoError(s.CreateCacheFile(blob.Digest.Hex(), bytes.NewReader(blob.Content)))
+	s.Close()
+
+	config.VerificationScan.Enabled = true
+	s, err = NewCAStore(config, tally.NoopScope)
+	require.NoError(err)
+	defer s.Close()
+
+	require.NoError(testutil.PollUntilTrue(5*time.Second, func() bool {
+		var lv metadata.LastVerified
+		return s.GetCacheFileMetadata(blob.Digest.Hex(), &lv) == nil
+	}))
+}
+
+func TestCAStoreResumableUploadsSurviveRestart(t *testing.T) {
+	for _, resumable := range []bool{true, false} {
+		t.Run(fmt.Sprintf("resumable=%t", resumable), func(t *testing.T) {
+			require := require.New(t)
+
+			config, cleanup := CAStoreConfigFixture()
+			defer cleanup()
+			config.ResumableUploads = resumable
+
+			s, err := NewCAStore(config, tally.NoopScope)
+			require.NoError(err)
+
+			d := core.DigestFixture()
+			require.NoError(s.CreateUploadFile("uid", 0))
+			w, err := s.GetUploadFileReadWriter("uid")
+			require.NoError(err)
+			_, err = w.Write([]byte("partial"))
+			require.NoError(err)
+			require.NoError(w.Close())
+			require.NoError(s.SetUploadSession("uid", metadata.NewUploadSession(d.String(), 7)))
+			s.Close()
+
+			s, err = NewCAStore(config, tally.NoopScope)
+			require.NoError(err)
+			defer s.Close()
+
+			session, err := s.GetUploadSession("uid")
+			if !resumable {
+				require.True(os.IsNotExist(err))
+				return
+			}
+			require.NoError(err)
+			require.Equal(metadata.NewUploadSession(d.String(), 7), session)
+
+			r, err := s.GetUploadFileReader("uid")
+			require.NoError(err)
+			defer r.Close()
+			b, err := io.ReadAll(r)
+			require.NoError(err)
+			require.Equal("partial", string(b))
+		})
+	}
+}
+
 func TestCAStoreCreateUploadFileAndMoveToCacheFailure(t *testing.T) {
 	require := require.New(t)
 
@@ -243,6 +404,45 @@ func TestCAStoreCreateCacheFile(t *testing.T) {
 	require.NoError(err)
 	require.Equal(s1, string(b2))
 }
+func TestCAStoreLinkCacheFileFrom(t *testing.T) {
+	require := require.New(t)
+
+	s1, cleanup := CAStoreFixture()
+	defer cleanup()
+
+	s2, cleanup := CAStoreFixture()
+	defer cleanup()
+
+	blob := core.NewBlobFixture()
+	name := blob.Digest.Hex()
+
+	require.NoError(s1.CreateCacheFile(name, bytes.NewReader(blob.Content)))
+	p, err := s1.GetCacheFilePath(name)
+	require.NoError(err)
+
+	require.NoError(s2.LinkCacheFileFrom(name, p))
+	require.True(os.IsExist(s2.LinkCacheFileFrom(name, p)))
+
+	// Both stores share the same physical file.
+	info1, err := s1.GetCacheFileStat(name)
+	require.NoError(err)
+	info2, err := s2.GetCacheFileStat(name)
+	require.NoError(err)
+	require.True(os.SameFile(info1, info2))
+
+	// Deleting from one store leaves the other's link intact.
+	require.NoError(s1.DeleteCacheFile(name))
+	_, err = s1.GetCacheFileStat(name)
+	require.True(os.IsNotExist(err))
+
+	r, err := s2.GetCacheFileReader(name)
+	require.NoError(err)
+	defer r.Close()
+	b, err := io.ReadAll(r)
+	require.NoError(err)
+	require.Equal(blob.Content, b)
+}
+
 func TestCAStoreConfig_WithMemoryCache(t *testing.T) {
 	require := require.New(t)
 
@@ -1208,3 +1408,259 @@ func TestCAStore_ListCacheFiles(t *testing.T) {
 		})
 	}
 }
+
+func TestCAStore_GetCacheFileReader_MmapCache(t *testing.T) {
+	require := require.New(t)
+
+	config, cleanup := CAStoreConfigFixture()
+	defer cleanup()
+
+	config.MmapCache = MmapCacheConfig{
+		Enabled:     true,
+		MaxBlobSize: 64,
+	}
+
+	s, err := NewCAStore(config, tally.NoopScope)
+	require.NoError(err)
+	defer s.Close()
+
+	small := core.SizedBlobFixture(32, 8)
+	require.NoError(s.CreateCacheFile(small.Digest.Hex(), bytes.NewReader(small.Content)))
+	large := core.SizedBlobFixture(128, 8)
+	require.NoError(s.CreateCacheFile(large.Digest.Hex(), bytes.NewReader(large.Content)))
+
+	// Hot small blob is mapped once and shared across readers.
+	r1, err := s.GetCacheFileReader(small.Digest.Hex())
+	require.NoError(err)
+	r2, err := s.GetCacheFileReader(small.Digest.Hex())
+	require.NoError(err)
+	require.IsType(&mmapFileReader{}, r1)
+	require.Len(s.mmapCache.entries, 1)
+
+	b, err := io.ReadAll(r1)
+	require.NoError(err)
+	require.Equal(small.Content, b)
+	require.NoError(r1.Close())
+
+	// Blobs above the threshold fall back to normal reads.
+	r3, err := s.GetCacheFileReader(large.Digest.Hex())
+	require.NoError(err)
+	_, ok := r3.(*mmapFileReader)
+	require.False(ok)
+	b, err = io.ReadAll(r3)
+	require.NoError(err)
+	require.Equal(large.Content, b)
+	require.NoError(r3.Close())
+	require.Len(s.mmapCache.entries, 1)
+
+	// Deleting the blob evicts the mapping, but open readers stay valid.
+	require.NoError(s.DeleteCacheFile(small.Digest.Hex()))
+	require.Empty(s.mmapCache.entries)
+	b, err = io.ReadAll(r2)
+	require.NoError(err)
+	require.Equal(small.Content, b)
+	require.NoError(r2.Close())
+
+	_, err = s.GetCacheFileReader(small.Digest.Hex())
+	require.True(os.IsNotExist(err))
+}
+
+func TestCAStore_MmapCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	require := require.New(t)
+
+	config, cleanup := CAStoreConfigFixture()
+	defer cleanup()
+
+	config.MmapCache = MmapCacheConfig{
+		Enabled:     true,
+		MaxBlobSize: 64,
+		MaxEntries:  1,
+	}
+
+	s, err := NewCAStore(config, tally.NoopScope)
+	require.NoError(err)
+	defer s.Close()
+
+	b1 := core.SizedBlobFixture(32, 8)
+	require.NoError(s.CreateCacheFile(b1.Digest.Hex(), bytes.NewReader(b1.Content)))
+	b2 := core.SizedBlobFixture(32, 8)
+	require.NoError(s.CreateCacheFile(b2.Digest.Hex(), bytes.NewReader(b2.Content)))
+
+	r1, err := s.GetCacheFileReader(b1.Digest.Hex())
+	require.NoError(err)
+	defer r1.Close()
+
+	// Mapping b2 evicts b1 instead of falling back to disk reads.
+	r2, err := s.GetCacheFileReader(b2.Digest.Hex())
+	require.NoError(err)
+	defer r2.Close()
+	require.IsType(&mmapFileReader{}, r2)
+	require.Len(s.mmapCache.entries, 1)
+	require.Contains(s.mmapCache.entries, b2.Digest.Hex())
+
+	// Readers of evicted mappings stay valid.
+	b, err := io.ReadAll(r1)
+	require.NoError(err)
+	require.Equal(b1.Content, b)
+}
+
+func TestCAStore_CleanupReleasesMmapCache(t *testing.T) {
+	require := require.New(t)
+
+	config, cleanup := CAStoreConfigFixture()
+	defer cleanup()
+
+	config.MmapCache = MmapCacheConfig{
+		Enabled:     true,
+		MaxBlobSize: 64,
+	}
+
+	s, err := NewCAStore(config, tally.NoopScope)
+	require.NoError(err)
+	defer s.Close()
+
+	blob := core.SizedBlobFixture(32, 8)
+	require.NoError(s.CreateCacheFile(blob.Digest.Hex(), bytes.NewReader(blob.Content)))
+
+	r, err := s.GetCacheFileReader(blob.Digest.Hex())
+	require.NoError(err)
+	require.NoError(r.Close())
+	require.Len(s.mmapCache.entries, 1)
+
+	op := &releasingFileOp{s.cacheStore.newFileOp(), s}
+	_, err = s.cleanup.ttlBasedCleanup(op, time.Nanosecond, 0, 0, 0, nil)
+	require.NoError(err)
+
+	_, err = s.GetCacheFileStat(blob.Digest.Hex())
+	require.True(os.IsNotExist(err))
+	require.Empty(s.mmapCache.entries)
+}
+
+func TestCAStore_GetCacheFileReader_TmpfsTier(t *testing.T) {
+	require := require.New(t)
+
+	config, cleanup := CAStoreConfigFixture()
+	defer cleanup()
+
+	hot := core.SizedBlobFixture(32, 8)
+	warm1 := core.SizedBlobFixture(32, 8)
+	warm2 := core.SizedBlobFixture(32, 8)
+
+	config.TmpfsTier = TmpfsTierConfig{
+		Enabled:  true,
+		Dir:      t.TempDir(),
+		MaxSize:  96,
+		HotBlobs: []string{hot.Digest.Hex()},
+		TopK:     1,
+	}
+
+	s, err := NewCAStore(config, tally.NoopScope)
+	require.NoError(err)
+	defer s.Close()
+
+	for _, blob := range []*core.BlobFixture{hot, warm1, warm2} {
+		require.NoError(s.CreateCacheFile(blob.Digest.Hex(), bytes.NewReader(blob.Content)))
+	}
+
+	read := func(blob *core.BlobFixture) FileReader {
+		r, err := s.GetCacheFileReader(blob.Digest.Hex())
+		require.NoError(err)
+		b, err := io.ReadAll(r)
+		require.NoError(err)
+		require.Equal(blob.Content, b)
+		require.NoError(r.Close())
+		return r
+	}
+
+	// Pinned blobs are served from the tier on first read.
+	require.IsType(&tmpfsFileReader{}, read(hot))
+
+	// warm1 takes the single top-K slot.
+	require.IsType(&tmpfsFileReader{}, read(warm1))
+
+	// warm2 is not accessed more than warm1, so it is served from disk...
+	_, ok := read(warm2).(*tmpfsFileReader)
+	require.False(ok)
+
+	// ...until it is.
+	read(warm2)
+	require.IsType(&tmpfsFileReader{}, read(warm2))
+	require.Len(s.tmpfsTier.entries, 2)
+	require.NotContains(s.tmpfsTier.entries, warm1.Digest.Hex())
+
+	// Files lost from the tier, e.g. under memory pressure, fall back to disk
+	// and are re-promoted.
+	require.NoError(os.Remove(s.tmpfsTier.path(hot.Digest.Hex())))
+	require.IsType(&tmpfsFileReader{}, read(hot))
+
+	// Deleting the blob removes its copy.
+	require.NoError(s.DeleteCacheFile(hot.Digest.Hex()))
+	require.NotContains(s.tmpfsTier.entries, hot.Digest.Hex())
+	_, err = os.Stat(s.tmpfsTier.path(hot.Digest.Hex()))
+	require.True(os.IsNotExist(err))
+	_, err = s.GetCacheFileReader(hot.Digest.Hex())
+	require.True(os.IsNotExist(err))
+}
+
+func TestCAStore_CleanupReleasesTmpfsTier(t *testing.T) {
+	require := require.New(t)
+
+	config, cleanup := CAStoreConfigFixture()
+	defer cleanup()
+
+	blob := core.SizedBlobFixture(32, 8)
+
+	config.TmpfsTier = TmpfsTierConfig{
+		Enabled:  true,
+		Dir:      t.TempDir(),
+		HotBlobs: []string{blob.Digest.Hex()},
+	}
+
+	s, err := NewCAStore(config, tally.NoopScope)
+	require.NoError(err)
+	defer s.Close()
+
+	require.NoError(s.CreateCacheFile(blob.Digest.Hex(), bytes.NewReader(blob.Content)))
+
+	r, err := s.GetCacheFileReader(blob.Digest.Hex())
+	require.NoError(err)
+	require.NoError(r.Close())
+	require.Contains(s.tmpfsTier.entries, blob.Digest.Hex())
+
+	op := &releasingFileOp{s.cacheStore.newFileOp(), s}
+	_, err = s.cleanup.ttlBasedCleanup(op, time.Nanosecond, 0, 0, 0, nil)
+	require.NoError(err)
+
+	require.Empty(s.tmpfsTier.entries)
+	require.Empty(s.tmpfsTier.accesses)
+	_, err = os.Stat(s.tmpfsTier.path(blob.Digest.Hex()))
+	require.True(os.IsNotExist(err))
+}
+
+func TestTmpfsTierDecaysAccessCounts(t *testing.T) {
+	require := require.New(t)
+
+	tier, err := newTmpfsTier(TmpfsTierConfig{Dir: t.TempDir()}, tally.NoopScope)
+	require.NoError(err)
+
+	tier.mu.Lock()
+	defer tier.mu.Unlock()
+
+	for range 4 {
+		tier.touchLocked("hot")
+	}
+	for i := range tmpfsAccessDecayPeriod - 4 {
+		tier.touchLocked(fmt.Sprintf("cold-%d", i))
+	}
+
+	// Files read once are forgotten; popular files keep half their count.
+	require.Equal(map[string]int{"hot": 2}, tier.accesses)
+}
+
+func TestCAStoreConfigDefaultTmpfsTierDirPerStore(t *testing.T) {
+	c1 := CAStoreConfig{CacheDir: "/var/cache/kraken/agent"}.applyDefaults()
+	c2 := CAStoreConfig{CacheDir: "/var/cache/kraken/origin/"}.applyDefaults()
+
+	require.Equal(t, "/dev/shm/kraken/var_cache_kraken_agent", c1.TmpfsTier.Dir)
+	require.Equal(t, "/dev/shm/kraken/var_cache_kraken_origin", c2.TmpfsTier.Dir)
+}
@@ -45,6 +45,20 @@ func (s *cacheStore) GetCacheFileStat(name string) (os.FileInfo, error) {
 	return s.newFileOp().GetFileStat(name)
 }
 
+// GetCacheFilePath returns the path of cache file name.
+func (s *cacheStore) GetCacheFilePath(name string) (string, error) {
+	return s.newFileOp().GetFilePath(name)
+}
+
+// LinkCacheFileFrom adds the file at sourcePath, typically another store's
+// cache file, to the cache as name via hard link. Logically separate stores on
+// the same filesystem then share the physical bytes; deleting name from one
+// store only drops that store's link. Falls back to copy across filesystems.
+// Clients are expected to validate the content of sourcePath matches name.
+func (s *cacheStore) LinkCacheFileFrom(name, sourcePath string) error {
+	return s.newFileOp().LinkFileFrom(name, s.state, sourcePath)
+}
+
 func (s *cacheStore) DeleteCacheFile(name string) error {
 	return s.newFileOp().DeleteFile(name)
 }
@@ -38,6 +38,7 @@ type CleanupConfig struct {
 	AggressiveThreshold      int           `yaml:"aggressive_threshold"`       // The disk util threshold to trigger aggressive cleanup. If 0, disables aggressive cleanup.
 	AggressiveTTL            time.Duration `yaml:"aggressive_ttL"`             // Time to live regardless of access if aggressive cleanup is triggered.
 	AggressiveLowerThreshold int           `yaml:"aggressive_lower_threshold"` // The lower disk util threshold in percent, below which aggressive cleanup will stop. If 0, no lower threshold.
+	MinAge                   time.Duration `yaml:"min_age"`                    // Grace period based on modification time, during which files are never deleted regardless of the above. If 0, disables the grace period.
 }
 
 type (
@@ -203,7 +204,7 @@ func (m *cleanupManager) cleanup(op base.FileOp, config CleanupConfig, customPol
 		lowerThreshold = config.AggressiveLowerThreshold
 	}
 
-	return m.ttlBasedCleanup(op, config.TTI, ttl, lowerThreshold, diskspaceutil.Usage)
+	return m.ttlBasedCleanup(op, config.TTI, ttl, config.MinAge, lowerThreshold, diskspaceutil.Usage)
 }
 
 func (m *cleanupManager) customPolicyBasedCleanup(op base.FileOp, config CleanupConfig, customPolicy func(a, b fInfo) int, diskUsageFn diskUsageFn) (usage int64, err error) {
@@ -226,6 +227,9 @@ func (m *cleanupManager) customPolicyBasedCleanup(op base.FileOp, config Cleanup
 			size:         fStat.Size(),
 		}
 		totalUsage += fStat.Size()
+		if m.withinMinAge(fStat, config.MinAge) {
+			continue
+		}
 
 		var accessTime metadata.LastAccessTime
 		err = op.GetFileMetadata(name, &accessTime)
@@ -266,7 +270,7 @@ func (m *cleanupManager) customPolicyBasedCleanup(op base.FileOp, config Cleanup
 }
 
 func (m *cleanupManager) ttlBasedCleanup(
-	op base.FileOp, tti time.Duration, ttl time.Duration, aggroUtilLowerThreshold int, diskUsageFn diskUsageFn) (scannedBytes int64, err error) {
+	op base.FileOp, tti, ttl, minAge time.Duration, aggroUtilLowerThreshold int, diskUsageFn diskUsageFn) (scannedBytes int64, err error) {
 
 	var lowThresholdBytes uint64 = 0
 	respectLowThreshold := false
@@ -291,7 +295,7 @@ func (m *cleanupManager) ttlBasedCleanup(
 			log.With("name", name).Errorf("Error getting file stat: %s", err)
 			continue
 		}
-		ready, err := m.readyForDeletion(op, name, info, tti, ttl)
+		ready, err := m.readyForDeletion(op, name, info, tti, ttl, minAge)
 		if err != nil {
 			log.With("name", name).Errorf("Error checking if file expired: %s", err)
 		}
@@ -312,8 +316,12 @@ func (m *cleanupManager) readyForDeletion(
 	name string,
 	info os.FileInfo,
 	tti time.Duration,
-	ttl time.Duration) (bool, error) {
+	ttl time.Duration,
+	minAge time.Duration) (bool, error) {
 
+	if m.withinMinAge(info, minAge) {
+		return false, nil
+	}
 	if ttl > 0 && m.clk.Now().Sub(info.ModTime()) > ttl {
 		return true, nil
 	}
@@ -327,6 +335,13 @@ func (m *cleanupManager) readyForDeletion(
 	return m.clk.Now().Sub(lat.Time) > tti, nil
 }
 
+// withinMinAge returns true if info was modified less than minAge ago, in
+// which case the file may still be referenced by an in-flight push and must not
+// be deleted.
+func (m *cleanupManager) withinMinAge(info os.FileInfo, minAge time.Duration) bool {
+	return minAge > 0 && m.clk.Now().Sub(info.ModTime()) < minAge
+}
+
 func (m *cleanupManager) shouldAggro(op base.FileOp, config CleanupConfig, diskUsageFn diskUsageFn) bool {
 	if config.AggressiveThreshold == 0 {
 		return false
@@ -220,6 +220,62 @@ func TestCleanupManagerSkipsPersistedFiles(t *testing.T) {
 	}
 }
 
+func TestCleanupManagerSkipsFilesWithinMinAge(t *testing.T) {
+	require := require.New(t)
+
+	clk := clock.NewMock()
+	clk.Set(time.Now())
+
+	config := CleanupConfig{
+		TTI:    time.Hour,
+		TTL:    2 * time.Hour,
+		MinAge: 3 * time.Hour,
+	}
+
+	m, err := newCleanupManager(clk, tally.NoopScope)
+	require.NoError(err)
+	defer m.stop()
+
+	state, op, cleanup := fileOpFixture(clk)
+	defer cleanup()
+
+	var names []string
+	for i := 0; i < 10; i++ {
+		names = append(names, core.DigestFixture().Hex())
+	}
+	for _, name := range names {
+		require.NoError(op.CreateFile(name, state, 0))
+	}
+
+	// Idle and expired, but within the grace period.
+	clk.Add(config.TTL + 1)
+
+	_, err = m.cleanup(op, config, nil)
+	require.NoError(err)
+
+	// Aggressive cleanup respects the grace period too.
+	_, err = m.customPolicyBasedCleanup(
+		op, config, cachedInAgentPolicy, func() (diskspaceutil.UsageInfo, error) {
+			return diskspaceutil.UsageInfo{TotalBytes: 100, UsedBytes: 100}, nil
+		})
+	require.NoError(err)
+
+	for _, name := range names {
+		_, err := op.GetFileStat(name)
+		require.NoError(err)
+	}
+
+	clk.Add(config.MinAge)
+
+	_, err = m.cleanup(op, config, nil)
+	require.NoError(err)
+
+	for _, name := range names {
+		_, err := op.GetFileStat(name)
+		require.True(os.IsNotExist(err))
+	}
+}
+
 func TestCleanupManageDiskUsage(t *testing.T) {
 	require := require.New(t)
 
@@ -14,6 +14,8 @@
 package store
 
 import (
+	"path/filepath"
+	"strings"
 	"time"
 )
 
@@ -35,6 +37,38 @@ type MemoryCacheConfig struct {
 	TTLInterval     time.Duration `yaml:"ttl_interval"`
 }
 
+// MmapCacheConfig defines configuration for serving small cache files from
+// memory mappings. Files larger than MaxBlobSize are read normally.
+type MmapCacheConfig struct {
+	Enabled     bool  `yaml:"enabled"`
+	MaxBlobSize int64 `yaml:"max_blob_size"`
+	MaxEntries  int   `yaml:"max_entries"`
+}
+
+// TmpfsTierConfig defines configuration for mirroring hot cache files into a
+// memory-backed directory, e.g. a tmpfs mount. Files named in HotBlobs are
+// always mirrored; in addition, the TopK most accessed files are mirrored.
+// The total size of mirrored files is bounded by MaxSize. Dir must not be
+// shared with other stores; it defaults to a directory under /dev/shm/kraken
+// derived from the cache directory.
+type TmpfsTierConfig struct {
+	Enabled  bool     `yaml:"enabled"`
+	Dir      string   `yaml:"dir"`
+	MaxSize  int64    `yaml:"max_size"`
+	HotBlobs []string `yaml:"hot_blobs"`
+	TopK     int      `yaml:"top_k"`
+}
+
+// VerificationScanConfig configures a scan which verifies the content of every
+// cache file against its digest on startup and every Interval thereafter,
+// deleting corrupt files. Files verified within VerificationMaxAge whose
+// modification time is unchanged are skipped, so restarts do not re-hash an
+// unchanged cache.
+type VerificationScanConfig struct {
+	Enabled  bool          `yaml:"enabled"`
+	Interval time.Duration `yaml:"interval"`
+}
+
 // CAStoreConfig defines CAStore configuration.
 type CAStoreConfig struct {
 	UploadDir     string        `yaml:"upload_dir"`
@@ -50,7 +84,24 @@ type CAStoreConfig struct {
 
 	SkipHashVerification bool `yaml:"skip_hash_verification"`
 
+	// VerificationMaxAge is how long a cache file verified by VerifyCacheFile
+	// is trusted without re-verification, as long as its modification time
+	// is unchanged. Defaults to 24h.
+	VerificationMaxAge time.Duration `yaml:"verification_max_age"`
+
+	VerificationScan VerificationScanConfig `yaml:"verification_scan"`
+
+	// ResumableUploads keeps in-progress uploads, and their sessions, across
+	// restarts instead of wiping UploadDir on startup, so clients can resume
+	// chunked uploads. Abandoned uploads are deleted by UploadCleanup, whose
+	// TTL defaults to 24h when enabled.
+	ResumableUploads bool `yaml:"resumable_uploads"`
+
 	MemoryCache MemoryCacheConfig `yaml:"memory_cache"`
+
+	MmapCache MmapCacheConfig `yaml:"mmap_cache"`
+
+	TmpfsTier TmpfsTierConfig `yaml:"tmpfs_tier"`
 }
 
 func (c CAStoreConfig) applyDefaults() CAStoreConfig {
@@ -69,6 +120,30 @@ func (c CAStoreConfig) applyDefaults() CAStoreConfig {
 	if c.MemoryCache.TTLInterval == 0 {
 		c.MemoryCache.TTLInterval = 1 * time.Minute
 	}
+	if c.ResumableUploads && c.UploadCleanup.TTL == 0 {
+		c.UploadCleanup.TTL = 24 * time.Hour
+	}
+	if c.VerificationMaxAge == 0 {
+		c.VerificationMaxAge = 24 * time.Hour
+	}
+	if c.VerificationScan.Interval == 0 {
+		c.VerificationScan.Interval = 24 * time.Hour
+	}
+	if c.MmapCache.MaxBlobSize == 0 {
+		c.MmapCache.MaxBlobSize = 1 << 20 // 1MB
+	}
+	if c.MmapCache.MaxEntries == 0 {
+		c.MmapCache.MaxEntries = 1024
+	}
+	if c.TmpfsTier.Dir == "" {
+		c.TmpfsTier.Dir = defaultTmpfsTierDir(c.CacheDir)
+	}
+	if c.TmpfsTier.MaxSize == 0 {
+		c.TmpfsTier.MaxSize = 256 << 20 // 256MB
+	}
+	if c.TmpfsTier.TopK == 0 {
+		c.TmpfsTier.TopK = 64
+	}
 	return c
 }
 
@@ -96,3 +171,11 @@ type CADownloadStoreConfig struct {
 	// Part size limit for each file write. 0 means no limit.
 	WritePartSize int `yaml:"write_part_size"`
 }
+
+// defaultTmpfsTierDir returns a tmpfs directory dedicated to the store whose
+// cache lives in cacheDir, such that stores sharing a host never share tier
+// files.
+func defaultTmpfsTierDir(cacheDir string) string {
+	name := strings.ReplaceAll(strings.Trim(filepath.Clean(cacheDir), "/"), "/", "_")
+	return filepath.Join("/dev/shm/kraken", name)
+}
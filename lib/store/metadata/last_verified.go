@@ -0,0 +1,71 @@
+// Copyright (c) 2016-2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package metadata
+
+import (
+	"encoding/json"
+	"regexp"
+	"time"
+)
+
+const _lastVerifiedSuffix = "_last_verified"
+
+func init() {
+	Register(regexp.MustCompile(_lastVerifiedSuffix), &lastVerifiedFactory{})
+}
+
+type lastVerifiedFactory struct{}
+
+func (f lastVerifiedFactory) Create(suffix string) Metadata {
+	return &LastVerified{}
+}
+
+// LastVerified records when a file's content was last verified against its
+// digest, so verification can be skipped while the file is unchanged.
+type LastVerified struct {
+	// Digest is the digest the content was verified against.
+	Digest string `json:"digest"`
+
+	// Time is when the content was verified.
+	Time time.Time `json:"time"`
+
+	// ModTime is the modification time of the file when it was verified. A
+	// different modification time means the file may have changed since.
+	ModTime time.Time `json:"mod_time"`
+}
+
+// NewLastVerified creates a new LastVerified.
+func NewLastVerified(digest string, t, modTime time.Time) *LastVerified {
+	return &LastVerified{digest, t, modTime}
+}
+
+// GetSuffix returns a static suffix.
+func (m *LastVerified) GetSuffix() string {
+	return _lastVerifiedSuffix
+}
+
+// Movable is false, since files are verified when moved into the cache.
+func (m *LastVerified) Movable() bool {
+	return false
+}
+
+// Serialize converts m to bytes.
+func (m *LastVerified) Serialize() ([]byte, error) {
+	return json.Marshal(m)
+}
+
+// Deserialize loads b into m.
+func (m *LastVerified) Deserialize(b []byte) error {
+	return json.Unmarshal(b, m)
+}
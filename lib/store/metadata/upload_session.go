@@ -0,0 +1,67 @@
+// Copyright (c) 2016-2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package metadata
+
+import (
+	"encoding/json"
+	"regexp"
+)
+
+const _uploadSessionSuffix = "_upload_session"
+
+func init() {
+	Register(regexp.MustCompile(_uploadSessionSuffix), &uploadSessionFactory{})
+}
+
+type uploadSessionFactory struct{}
+
+func (f uploadSessionFactory) Create(suffix string) Metadata {
+	return &UploadSession{}
+}
+
+// UploadSession tracks the progress of a chunked upload, so that the upload
+// can be resumed after a restart.
+type UploadSession struct {
+	// Digest is the digest of the blob being uploaded.
+	Digest string `json:"digest"`
+
+	// Offset is the number of bytes received without gaps from the start of
+	// the blob, i.e. where the upload should continue from.
+	Offset int64 `json:"offset"`
+}
+
+// NewUploadSession creates a new UploadSession.
+func NewUploadSession(digest string, offset int64) *UploadSession {
+	return &UploadSession{digest, offset}
+}
+
+// GetSuffix returns a static suffix.
+func (m *UploadSession) GetSuffix() string {
+	return _uploadSessionSuffix
+}
+
+// Movable is false, since sessions end once their upload is committed.
+func (m *UploadSession) Movable() bool {
+	return false
+}
+
+// Serialize converts m to bytes.
+func (m *UploadSession) Serialize() ([]byte, error) {
+	return json.Marshal(m)
+}
+
+// Deserialize loads b into m.
+func (m *UploadSession) Deserialize(b []byte) error {
+	return json.Unmarshal(b, m)
+}
@@ -0,0 +1,189 @@
+// Copyright (c) 2016-2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package store
+
+import (
+	"bytes"
+	"container/list"
+	"fmt"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/uber-go/tally"
+	"github.com/uber/kraken/utils/log"
+)
+
+// mmapEntry is a read-only memory mapping of a cache file. The mapping is
+// reference counted: the cache holds one reference while the entry is live,
+// and every open reader holds one more. The mapping is released once the
+// entry is evicted and the last reader is closed.
+type mmapEntry struct {
+	name    string
+	elem    *list.Element
+	data    []byte
+	size    int64
+	modTime time.Time
+	refs    int
+}
+
+// mmapCache caches memory mappings of small cache files so hot blobs can be
+// served from mapped memory instead of going through open/read/close. Once
+// MaxEntries is reached, the least recently used mapping is evicted.
+type mmapCache struct {
+	config MmapCacheConfig
+	stats  tally.Scope
+
+	mu      sync.Mutex
+	entries map[string]*mmapEntry
+	lru     *list.List // Front is most recently used.
+}
+
+func newMmapCache(config MmapCacheConfig, stats tally.Scope) *mmapCache {
+	return &mmapCache{
+		config:  config,
+		stats:   stats.SubScope("mmap_cache"),
+		entries: make(map[string]*mmapEntry),
+		lru:     list.New(),
+	}
+}
+
+// eligible returns true if a file of the given size should be mapped.
+func (c *mmapCache) eligible(size int64) bool {
+	// Zero length mappings are invalid.
+	return size > 0 && size <= c.config.MaxBlobSize
+}
+
+// get returns a reader over the mapping of name, creating the mapping from
+// path if needed. info must be a fresh stat of the file; if it no longer
+// matches the mapping, the stale mapping is evicted and a new one is created.
+func (c *mmapCache) get(name, path string, info os.FileInfo) (FileReader, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.entries[name]; ok {
+		if e.size == info.Size() && e.modTime.Equal(info.ModTime()) {
+			c.stats.Counter("hit").Inc(1)
+			c.lru.MoveToFront(e.elem)
+			e.refs++
+			return newMmapFileReader(c, e), nil
+		}
+		c.evictLocked(name)
+	}
+	c.stats.Counter("miss").Inc(1)
+
+	data, err := mmapFile(path, info.Size())
+	if err != nil {
+		return nil, err
+	}
+	for len(c.entries) > 0 && len(c.entries) >= c.config.MaxEntries {
+		oldest := c.lru.Back().Value.(*mmapEntry)
+		c.evictLocked(oldest.name)
+		c.stats.Counter("evict").Inc(1)
+	}
+	e := &mmapEntry{
+		name:    name,
+		data:    data,
+		size:    info.Size(),
+		modTime: info.ModTime(),
+		refs:    2, // One for the cache, one for the returned reader.
+	}
+	e.elem = c.lru.PushFront(e)
+	c.entries[name] = e
+	c.stats.Gauge("entries").Update(float64(len(c.entries)))
+	return newMmapFileReader(c, e), nil
+}
+
+// evict drops the cache's reference to the mapping of name. The mapping
+// itself is released once all open readers are closed.
+func (c *mmapCache) evict(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.evictLocked(name)
+}
+
+func (c *mmapCache) evictLocked(name string) {
+	e, ok := c.entries[name]
+	if !ok {
+		return
+	}
+	delete(c.entries, name)
+	c.lru.Remove(e.elem)
+	c.stats.Gauge("entries").Update(float64(len(c.entries)))
+	c.releaseLocked(e)
+}
+
+func (c *mmapCache) release(e *mmapEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.releaseLocked(e)
+}
+
+func (c *mmapCache) releaseLocked(e *mmapEntry) {
+	e.refs--
+	if e.refs > 0 {
+		return
+	}
+	if err := syscall.Munmap(e.data); err != nil {
+		log.Errorf("Error unmapping cache file: %s", err)
+	}
+	e.data = nil
+}
+
+// close evicts all entries. Mappings with open readers are released when
+// those readers are closed.
+func (c *mmapCache) close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for name := range c.entries {
+		c.evictLocked(name)
+	}
+}
+
+func mmapFile(path string, size int64) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open: %s", err)
+	}
+	// The mapping remains valid after the descriptor is closed.
+	defer f.Close()
+
+	data, err := syscall.Mmap(int(f.Fd()), 0, int(size), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, fmt.Errorf("mmap: %s", err)
+	}
+	return data, nil
+}
+
+// mmapFileReader is a FileReader backed by a shared memory mapping. Closing
+// the reader drops its reference to the mapping.
+type mmapFileReader struct {
+	*bytes.Reader
+	cache *mmapCache
+	entry *mmapEntry
+	once  sync.Once
+}
+
+func newMmapFileReader(c *mmapCache, e *mmapEntry) *mmapFileReader {
+	return &mmapFileReader{Reader: bytes.NewReader(e.data), cache: c, entry: e}
+}
+
+func (r *mmapFileReader) Close() error {
+	r.once.Do(func() { r.cache.release(r.entry) })
+	return nil
+}
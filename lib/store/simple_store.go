@@ -38,7 +38,7 @@ func NewSimpleStore(config SimpleStoreConfig, stats tally.Scope) (*SimpleStore,
 		"module": "simplestore",
 	})
 
-	uploadStore, err := newUploadStore(config.UploadDir, config.ReadPartSize, config.WritePartSize)
+	uploadStore, err := newUploadStore(config.UploadDir, config.ReadPartSize, config.WritePartSize, false)
 	if err != nil {
 		return nil, fmt.Errorf("new upload store: %s", err)
 	}
@@ -0,0 +1,333 @@
+// Copyright (c) 2016-2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package store
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/uber-go/tally"
+	"github.com/uber/kraken/utils/log"
+)
+
+// tmpfsEntry is a copy of a cache file in the tmpfs tier, along with the size
+// and modification time of the disk file it was copied from.
+type tmpfsEntry struct {
+	size    int64
+	modTime time.Time
+	pinned  bool
+}
+
+// tmpfsAccessDecayPeriod is the number of accesses after which all access
+// counts are halved, such that counts track recent popularity and counts of
+// files which are no longer read are dropped.
+const tmpfsAccessDecayPeriod = 4096
+
+// tmpfsTier mirrors hot cache files into a memory-backed directory. Files in
+// HotBlobs are always mirrored; otherwise the TopK most accessed files are.
+// The tier is best-effort: any file missing from it, e.g. because the kernel
+// or an operator removed it under memory pressure, is served from disk.
+type tmpfsTier struct {
+	config TmpfsTierConfig
+	stats  tally.Scope
+	hot    map[string]bool
+
+	mu        sync.Mutex
+	entries   map[string]*tmpfsEntry
+	promoting map[string]*tmpfsEntry // Copies in progress, reserved in size.
+	size      int64
+	accesses  map[string]int
+	ticks     int
+}
+
+func newTmpfsTier(config TmpfsTierConfig, stats tally.Scope) (*tmpfsTier, error) {
+	if err := os.MkdirAll(config.Dir, 0775); err != nil {
+		return nil, fmt.Errorf("mkdir: %s", err)
+	}
+	hot := make(map[string]bool)
+	for _, name := range config.HotBlobs {
+		hot[name] = true
+	}
+	return &tmpfsTier{
+		config:    config,
+		stats:     stats.SubScope("tmpfs_tier"),
+		hot:       hot,
+		entries:   make(map[string]*tmpfsEntry),
+		promoting: make(map[string]*tmpfsEntry),
+		accesses:  make(map[string]int),
+	}, nil
+}
+
+func (t *tmpfsTier) path(name string) string {
+	return filepath.Join(t.config.Dir, name)
+}
+
+// get returns a reader over the tmpfs copy of name, promoting the disk file at
+// diskPath into the tier if name is hot enough. info must be a fresh stat of
+// the disk file; stale copies are dropped. Returns false if name should be
+// read from disk.
+func (t *tmpfsTier) get(name, diskPath string, info os.FileInfo) (FileReader, bool) {
+	r, e := t.lookupOrReserve(name, info)
+	if r != nil {
+		return r, true
+	}
+	if e == nil {
+		return nil, false
+	}
+
+	// Copy outside of the lock, such that reads of other files are not
+	// blocked behind the copy.
+	err := copyToTmpfs(diskPath, t.path(name))
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.promoting[name] != e {
+		// Removed while copying.
+		if err == nil && t.entries[name] == nil && t.promoting[name] == nil {
+			t.removeFileLocked(name)
+		}
+		return nil, false
+	}
+	delete(t.promoting, name)
+	if err != nil {
+		// Most likely the tmpfs is out of memory. Fall back to disk.
+		log.With("name", name).Infof("Error promoting file to tmpfs tier: %s", err)
+		t.stats.Counter("promote_errors").Inc(1)
+		t.size -= e.size
+		t.stats.Gauge("size").Update(float64(t.size))
+		return nil, false
+	}
+	t.entries[name] = e
+	t.stats.Counter("promotions").Inc(1)
+	r, err = openTmpfsFile(t.path(name), e.size)
+	if err != nil {
+		t.removeLocked(name)
+		return nil, false
+	}
+	return r, true
+}
+
+// lookupOrReserve returns a reader over the tmpfs copy of name if it is in the
+// tier. Otherwise, if name should be promoted, reserves room for it and
+// returns the entry to be copied. Returns neither if name should be read from
+// disk.
+func (t *tmpfsTier) lookupOrReserve(name string, info os.FileInfo) (FileReader, *tmpfsEntry) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.touchLocked(name)
+
+	if e, ok := t.entries[name]; ok {
+		if e.size == info.Size() && e.modTime.Equal(info.ModTime()) {
+			if r, err := openTmpfsFile(t.path(name), e.size); err == nil {
+				t.stats.Counter("hit").Inc(1)
+				return r, nil
+			}
+			t.stats.Counter("lost").Inc(1)
+		}
+		t.removeLocked(name)
+	}
+	t.stats.Counter("miss").Inc(1)
+
+	if _, ok := t.promoting[name]; ok {
+		// Another reader is copying name.
+		return nil, nil
+	}
+	if !t.admitLocked(name, info.Size()) {
+		return nil, nil
+	}
+	e := &tmpfsEntry{size: info.Size(), modTime: info.ModTime(), pinned: t.hot[name]}
+	t.promoting[name] = e
+	t.size += e.size
+	t.stats.Gauge("size").Update(float64(t.size))
+	return nil, e
+}
+
+// touchLocked counts an access of name, periodically halving all counts.
+func (t *tmpfsTier) touchLocked(name string) {
+	t.accesses[name]++
+	t.ticks++
+	if t.ticks < tmpfsAccessDecayPeriod {
+		return
+	}
+	t.ticks = 0
+	for n, c := range t.accesses {
+		if c /= 2; c == 0 {
+			delete(t.accesses, n)
+		} else {
+			t.accesses[n] = c
+		}
+	}
+}
+
+// admitLocked makes room for a copy of name if it is pinned, or if the tier
+// has room for another top-K entry, or if name is accessed more often than
+// the least accessed unpinned entry, which is evicted in its place.
+func (t *tmpfsTier) admitLocked(name string, size int64) bool {
+	if size > t.config.MaxSize {
+		return false
+	}
+	pinned := t.hot[name]
+	if !pinned {
+		if t.unpinnedLocked() >= t.config.TopK {
+			victim, ok := t.leastAccessedLocked()
+			if !ok || t.accesses[victim] >= t.accesses[name] {
+				return false
+			}
+			t.removeLocked(victim)
+		}
+	}
+	for t.size+size > t.config.MaxSize {
+		victim, ok := t.leastAccessedLocked()
+		if !ok || (!pinned && t.accesses[victim] >= t.accesses[name]) {
+			return false
+		}
+		t.removeLocked(victim)
+	}
+	return true
+}
+
+func (t *tmpfsTier) unpinnedLocked() int {
+	var n int
+	for _, e := range t.entries {
+		if !e.pinned {
+			n++
+		}
+	}
+	for _, e := range t.promoting {
+		if !e.pinned {
+			n++
+		}
+	}
+	return n
+}
+
+// leastAccessedLocked returns the least accessed unpinned entry.
+func (t *tmpfsTier) leastAccessedLocked() (string, bool) {
+	var victim string
+	var found bool
+	for name, e := range t.entries {
+		if e.pinned {
+			continue
+		}
+		if !found || t.accesses[name] < t.accesses[victim] {
+			victim = name
+			found = true
+		}
+	}
+	return victim, found
+}
+
+// remove drops name from the tier, e.g. when it is deleted from disk.
+func (t *tmpfsTier) remove(name string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.removeLocked(name)
+	delete(t.accesses, name)
+}
+
+// removeLocked drops name from the tier. A copy of name in progress is
+// discarded once it completes.
+func (t *tmpfsTier) removeLocked(name string) {
+	if e, ok := t.promoting[name]; ok {
+		delete(t.promoting, name)
+		t.size -= e.size
+		t.stats.Gauge("size").Update(float64(t.size))
+		return
+	}
+	e, ok := t.entries[name]
+	if !ok {
+		return
+	}
+	delete(t.entries, name)
+	t.size -= e.size
+	t.stats.Gauge("size").Update(float64(t.size))
+	t.removeFileLocked(name)
+}
+
+func (t *tmpfsTier) removeFileLocked(name string) {
+	// Open readers keep the file contents until closed.
+	if err := os.Remove(t.path(name)); err != nil && !os.IsNotExist(err) {
+		log.With("name", name).Errorf("Error removing tmpfs tier file: %s", err)
+	}
+}
+
+// close removes all files from the tier. Copies in progress are discarded once
+// they complete.
+func (t *tmpfsTier) close() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for name := range t.entries {
+		t.removeLocked(name)
+	}
+	for name := range t.promoting {
+		t.removeLocked(name)
+	}
+}
+
+// copyToTmpfs copies src to dst atomically, such that dst is either absent or
+// complete.
+func copyToTmpfs(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("open src: %s", err)
+	}
+	defer in.Close()
+
+	// Concurrent copies of the same file each write their own tmp file.
+	out, err := os.CreateTemp(filepath.Dir(dst), filepath.Base(dst)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("create tmp: %s", err)
+	}
+	tmp := out.Name()
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(tmp)
+		return fmt.Errorf("copy: %s", err)
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("close tmp: %s", err)
+	}
+	if err := os.Rename(tmp, dst); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("rename: %s", err)
+	}
+	return nil
+}
+
+// tmpfsFileReader is a FileReader over a file in the tmpfs tier.
+type tmpfsFileReader struct {
+	*os.File
+	size int64
+}
+
+func openTmpfsFile(path string, size int64) (*tmpfsFileReader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return &tmpfsFileReader{f, size}, nil
+}
+
+func (r *tmpfsFileReader) Size() int64 {
+	return r.size
+}
@@ -33,10 +33,15 @@ type uploadStore struct {
 	writePartSize int
 }
 
-func newUploadStore(dir string, readPartSize, writePartSize int) (*uploadStore, error) {
-	// Always wipe upload directory on startup.
-	if err := os.RemoveAll(dir); err != nil {
-		log.Errorf("Error removing upload directory: %s", err)
+// newUploadStore creates a new uploadStore. Unless resumable is set, any
+// uploads left in dir are wiped.
+func newUploadStore(
+	dir string, readPartSize, writePartSize int, resumable bool) (*uploadStore, error) {
+
+	if !resumable {
+		if err := os.RemoveAll(dir); err != nil {
+			log.Errorf("Error removing upload directory: %s", err)
+		}
 	}
 
 	if err := os.MkdirAll(dir, 0775); err != nil {
@@ -76,6 +81,21 @@ func (s *uploadStore) RangeUploadMetadata(name string, f func(metadata.Metadata)
 	return s.newFileOp().RangeFileMetadata(name, f)
 }
 
+// GetUploadSession returns the session of upload name. Returns os.ErrNotExist
+// if the upload has no session.
+func (s *uploadStore) GetUploadSession(name string) (*metadata.UploadSession, error) {
+	var session metadata.UploadSession
+	if err := s.GetUploadFileMetadata(name, &session); err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// SetUploadSession persists the session of upload name.
+func (s *uploadStore) SetUploadSession(name string, session *metadata.UploadSession) error {
+	return s.SetUploadFileMetadata(name, session)
+}
+
 func (s *uploadStore) DeleteUploadFile(name string) error {
 	return s.newFileOp().DeleteFile(name)
 }
@@ -15,9 +15,18 @@ package store
 
 import (
 	"bytes"
+	"errors"
 	"os"
+	"syscall"
 )
 
+// IsDiskFull returns true if err was caused by the filesystem running out of
+// space or quota. Such errors are transient: writes may succeed once space is
+// reclaimed, e.g. by cache eviction.
+func IsDiskFull(err error) bool {
+	return errors.Is(err, syscall.ENOSPC) || errors.Is(err, syscall.EDQUOT)
+}
+
 func createOrUpdateSymlink(sourcePath, targetPath string) error {
 	if _, err := os.Stat(targetPath); err == nil {
 		if existingSource, err := os.Readlink(targetPath); err != nil {
@@ -0,0 +1,83 @@
+// Copyright (c) 2016-2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package store
+
+import (
+	"errors"
+	"os"
+
+	"github.com/uber/kraken/utils/log"
+)
+
+// errCacheFileCorrupt is returned by VerifyCacheFile when the content of a
+// cache file does not match its digest.
+var errCacheFileCorrupt = errors.New("cache file corrupt")
+
+func (s *CAStore) verificationScanWorker() {
+	defer s.verifyWg.Done()
+
+	ticker := s.clk.Ticker(s.config.VerificationScan.Interval)
+	defer ticker.Stop()
+
+	for {
+		s.verifyCacheFiles()
+		select {
+		case <-ticker.C:
+		case <-s.verifyStopChan:
+			return
+		}
+	}
+}
+
+// verifyCacheFiles verifies every cache file with VerifyCacheFile, deleting
+// files whose content does not match their digest.
+func (s *CAStore) verifyCacheFiles() (verified, skipped, corrupt int) {
+	names, err := s.ListCacheFiles()
+	if err != nil {
+		log.Errorf("Error listing cache files for verification: %s", err)
+		return 0, 0, 0
+	}
+	for _, name := range names {
+		select {
+		case <-s.verifyStopChan:
+			return verified, skipped, corrupt
+		default:
+		}
+		ok, err := s.VerifyCacheFile(name)
+		switch {
+		case errors.Is(err, errCacheFileCorrupt):
+			log.With("name", name).Errorf("Deleting corrupt cache file: %s", err)
+			if err := s.DeleteCacheFile(name); err != nil && !os.IsNotExist(err) {
+				log.With("name", name).Errorf("Error deleting corrupt cache file: %s", err)
+			}
+			corrupt++
+		case os.IsNotExist(err):
+			// Deleted since listing.
+		case err != nil:
+			log.With("name", name).Errorf("Error verifying cache file: %s", err)
+		case ok:
+			skipped++
+		default:
+			verified++
+		}
+	}
+	s.stats.Counter("verification_scan_verified").Inc(int64(verified))
+	s.stats.Counter("verification_scan_skipped").Inc(int64(skipped))
+	s.stats.Counter("verification_scan_corrupt").Inc(int64(corrupt))
+	log.With(
+		"verified", verified,
+		"skipped", skipped,
+		"corrupt", corrupt).Info("Cache verification scan completed")
+	return verified, skipped, corrupt
+}
@@ -36,6 +36,8 @@ const (
 	ReceivePiece     Name = "receive_piece"
 	TorrentComplete  Name = "torrent_complete"
 	TorrentCancelled Name = "torrent_cancelled"
+
+	TorrentCompleteTiming Name = "torrent_complete_timing"
 )
 
 // Event consolidates all possible event fields.
@@ -51,6 +53,13 @@ type Event struct {
 	Bitfield     []bool `json:"bitfield,omitempty"`
 	DurationMS   int64  `json:"duration_ms,omitempty"`
 	ConnCapacity int    `json:"conn_capacity,omitempty"`
+
+	// Completion timing fields.
+	FirstPieceMS int64 `json:"first_piece_ms,omitempty"`
+	CompleteMS   int64 `json:"complete_ms,omitempty"`
+	OriginBytes  int64 `json:"origin_bytes,omitempty"`
+	PeerBytes    int64 `json:"peer_bytes,omitempty"`
+	PeakPeers    int   `json:"peak_peers,omitempty"`
 }
 
 func baseEvent(name Name, h core.InfoHash, self core.PeerID) *Event {
@@ -131,3 +140,26 @@ func TorrentCompleteEvent(h core.InfoHash, self core.PeerID) *Event {
 func TorrentCancelledEvent(h core.InfoHash, self core.PeerID) *Event {
 	return baseEvent(TorrentCancelled, h, self)
 }
+
+// CompletionTiming is the timing breakdown of a completed download.
+type CompletionTiming struct {
+	// Durations are measured from when the torrent was added.
+	FirstPiece time.Duration
+	Complete   time.Duration
+
+	OriginBytes int64
+	PeerBytes   int64
+	PeakPeers   int
+}
+
+// TorrentCompleteTimingEvent returns an event summarizing how a completed
+// torrent was downloaded.
+func TorrentCompleteTimingEvent(h core.InfoHash, self core.PeerID, t CompletionTiming) *Event {
+	e := baseEvent(TorrentCompleteTiming, h, self)
+	e.FirstPieceMS = t.FirstPiece.Milliseconds()
+	e.CompleteMS = t.Complete.Milliseconds()
+	e.OriginBytes = t.OriginBytes
+	e.PeerBytes = t.PeerBytes
+	e.PeakPeers = t.PeakPeers
+	return e
+}
@@ -0,0 +1,166 @@
+// Copyright (c) 2016-2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+
+	"github.com/uber/kraken/core"
+	"github.com/uber/kraken/lib/torrent/storage"
+	"github.com/uber/kraken/utils/log"
+)
+
+var errPeerNotAllowed = errors.New("peer not in torrent allowlist")
+
+// PeerAllowlist restricts the peers a torrent connects to and serves. A peer
+// is allowed if its peer id is in PeerIDs, or its IP is within one of Networks.
+type PeerAllowlist struct {
+	PeerIDs  []core.PeerID
+	Networks []*net.IPNet
+}
+
+// NewPeerAllowlist creates a PeerAllowlist from peer ids and CIDR address
+// ranges.
+func NewPeerAllowlist(peerIDs []core.PeerID, cidrs []string) (*PeerAllowlist, error) {
+	a := &PeerAllowlist{PeerIDs: peerIDs}
+	for _, s := range cidrs {
+		_, n, err := net.ParseCIDR(s)
+		if err != nil {
+			return nil, err
+		}
+		a.Networks = append(a.Networks, n)
+	}
+	return a, nil
+}
+
+// Allows returns true if the peer with peerID at ip is allowed. ip may be nil
+// if the address of the peer is unknown, in which case only peerID is checked.
+func (a *PeerAllowlist) Allows(peerID core.PeerID, ip net.IP) bool {
+	for _, id := range a.PeerIDs {
+		if id == peerID {
+			return true
+		}
+	}
+	if ip != nil {
+		for _, n := range a.Networks {
+			if n.Contains(ip) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// addrIP returns the IP of addr, or nil if addr has no IP.
+func addrIP(addr net.Addr) net.IP {
+	if addr == nil {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return nil
+	}
+	return net.ParseIP(host)
+}
+
+// torrentAllowlists maps torrents to the allowlists they were added with.
+// Allowlists outlive torrents removed for inactivity, such that a torrent
+// re-added by an incoming connection stays restricted, and are carried over
+// on reload. They are only dropped when the torrent is removed via
+// RemoveTorrent. If the torrent archive is a storage.AllowlistStore,
+// allowlists are also persisted with their torrents and loaded on first use,
+// such that torrents stay restricted across restarts.
+type torrentAllowlists struct {
+	mu    sync.RWMutex
+	m     map[core.Digest]*PeerAllowlist
+	store storage.AllowlistStore
+}
+
+func newTorrentAllowlists(ta storage.TorrentArchive) *torrentAllowlists {
+	store, _ := ta.(storage.AllowlistStore)
+	return &torrentAllowlists{m: make(map[core.Digest]*PeerAllowlist), store: store}
+}
+
+func (a *torrentAllowlists) set(d core.Digest, allowlist *PeerAllowlist) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.store != nil {
+		persisted := &storage.Allowlist{PeerIDs: allowlist.PeerIDs}
+		for _, n := range allowlist.Networks {
+			persisted.CIDRs = append(persisted.CIDRs, n.String())
+		}
+		if err := a.store.SetAllowlist(d, persisted); err != nil {
+			return err
+		}
+	}
+	a.m[d] = allowlist
+	return nil
+}
+
+func (a *torrentAllowlists) delete(d core.Digest) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	delete(a.m, d)
+}
+
+// allows returns true if the peer is allowed to exchange d. Torrents without
+// an allowlist allow all peers. Peers are refused if a persisted allowlist
+// cannot be loaded.
+func (a *torrentAllowlists) allows(d core.Digest, peerID core.PeerID, ip net.IP) bool {
+	a.mu.RLock()
+	allowlist, ok := a.m[d]
+	a.mu.RUnlock()
+	if ok {
+		return allowlist.Allows(peerID, ip)
+	}
+	if a.store == nil {
+		return true
+	}
+
+	allowlist, err := a.load(d)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return true
+		}
+		log.With("digest", d).Errorf("Error loading torrent allowlist: %s", err)
+		return false
+	}
+	return allowlist.Allows(peerID, ip)
+}
+
+// load loads the persisted allowlist of d, unless one was set meanwhile.
+func (a *torrentAllowlists) load(d core.Digest) (*PeerAllowlist, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if allowlist, ok := a.m[d]; ok {
+		return allowlist, nil
+	}
+	persisted, err := a.store.GetAllowlist(d)
+	if err != nil {
+		return nil, err
+	}
+	allowlist, err := NewPeerAllowlist(persisted.PeerIDs, persisted.CIDRs)
+	if err != nil {
+		return nil, fmt.Errorf("parse allowlist: %s", err)
+	}
+	a.m[d] = allowlist
+	return allowlist, nil
+}
@@ -0,0 +1,172 @@
+// Copyright (c) 2016-2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package scheduler
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/uber-go/tally"
+	"github.com/uber/kraken/core"
+)
+
+func TestPeerAllowlistAllows(t *testing.T) {
+	allowed := core.PeerIDFixture()
+
+	a, err := NewPeerAllowlist([]core.PeerID{allowed}, []string{"10.0.0.0/8"})
+	require.NoError(t, err)
+
+	tests := []struct {
+		desc     string
+		peerID   core.PeerID
+		ip       net.IP
+		expected bool
+	}{
+		{"peer id", allowed, nil, true},
+		{"peer id outside network", allowed, net.ParseIP("192.168.0.1"), true},
+		{"network", core.PeerIDFixture(), net.ParseIP("10.1.2.3"), true},
+		{"outside network", core.PeerIDFixture(), net.ParseIP("192.168.0.1"), false},
+		{"unknown ip", core.PeerIDFixture(), nil, false},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			require.Equal(t, test.expected, a.Allows(test.peerID, test.ip))
+		})
+	}
+}
+
+func TestNewPeerAllowlistInvalidCIDR(t *testing.T) {
+	_, err := NewPeerAllowlist(nil, []string{"10.0.0.0"})
+	require.Error(t, err)
+}
+
+func TestAddrIP(t *testing.T) {
+	require := require.New(t)
+
+	require.Equal(
+		net.ParseIP("127.0.0.1"), addrIP(&net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 80}))
+	require.Nil(addrIP(nil))
+	require.Nil(addrIP(&net.UnixAddr{Name: "/tmp/sock", Net: "unix"}))
+}
+
+func TestDownloadWithAllowlistIncludingSeeder(t *testing.T) {
+	require := require.New(t)
+
+	mocks, cleanup := newTestMocks(t)
+	defer cleanup()
+
+	config := configFixture()
+
+	seeder := mocks.newPeer(config)
+	leecher := mocks.newPeer(config)
+
+	blob := core.NewBlobFixture()
+	namespace := core.TagFixture()
+
+	mocks.metaInfoClient.EXPECT().Download(
+		namespace, blob.Digest).Return(blob.MetaInfo, nil).Times(2)
+
+	seeder.writeTorrent(namespace, blob)
+	require.NoError(seeder.scheduler.DownloadWithAllowlist(
+		namespace, blob.Digest, &PeerAllowlist{PeerIDs: []core.PeerID{leecher.pctx.PeerID}}))
+
+	require.NoError(leecher.scheduler.DownloadWithAllowlist(
+		namespace, blob.Digest, &PeerAllowlist{PeerIDs: []core.PeerID{seeder.pctx.PeerID}}))
+	leecher.checkTorrent(t, namespace, blob)
+}
+
+func TestDownloadWithAllowlistRefusesPeers(t *testing.T) {
+	tests := []struct {
+		desc             string
+		seederAllowlist  *PeerAllowlist
+		leecherAllowlist *PeerAllowlist
+	}{
+		{"leecher excludes seeder", nil, &PeerAllowlist{}},
+		{"seeder excludes leecher", &PeerAllowlist{}, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			require := require.New(t)
+
+			mocks, cleanup := newTestMocks(t)
+			defer cleanup()
+
+			config := configFixture()
+
+			seeder := mocks.newPeer(config)
+			leecher := mocks.newPeer(config)
+
+			blob := core.NewBlobFixture()
+			namespace := core.TagFixture()
+
+			mocks.metaInfoClient.EXPECT().Download(
+				namespace, blob.Digest).Return(blob.MetaInfo, nil).Times(2)
+
+			seeder.writeTorrent(namespace, blob)
+			require.NoError(seeder.scheduler.DownloadWithAllowlist(
+				namespace, blob.Digest, test.seederAllowlist))
+
+			errc := make(chan error, 1)
+			go func() {
+				errc <- leecher.scheduler.DownloadWithAllowlist(
+					namespace, blob.Digest, test.leecherAllowlist)
+			}()
+
+			select {
+			case err := <-errc:
+				t.Fatalf("Download from refused peer returned: %v", err)
+			case <-time.After(time.Second):
+			}
+			require.False(hasConn(leecher.scheduler, seeder.pctx.PeerID, blob.MetaInfo.InfoHash()))
+		})
+	}
+}
+
+func TestAllowlistSurvivesRestart(t *testing.T) {
+	require := require.New(t)
+
+	mocks, cleanup := newTestMocks(t)
+	defer cleanup()
+
+	config := configFixture()
+
+	seeder := mocks.newPeer(config)
+
+	blob := core.NewBlobFixture()
+	namespace := core.TagFixture()
+
+	mocks.metaInfoClient.EXPECT().Download(
+		namespace, blob.Digest).Return(blob.MetaInfo, nil).Times(1)
+
+	allowed := core.PeerIDFixture()
+	other := core.PeerIDFixture()
+
+	seeder.writeTorrent(namespace, blob)
+	require.NoError(seeder.scheduler.DownloadWithAllowlist(
+		namespace, blob.Digest, &PeerAllowlist{PeerIDs: []core.PeerID{allowed}}))
+	seeder.scheduler.Stop()
+
+	// A restarted agent only has the torrent on disk.
+	s, err := newScheduler(
+		config, seeder.torrentArchive, tally.NoopScope, seeder.pctx, nil, seeder.testProducer)
+	require.NoError(err)
+
+	require.True(s.allowlists.allows(blob.Digest, allowed, nil))
+	require.False(s.allowlists.allows(blob.Digest, other, nil))
+
+	// Torrents without an allowlist still allow all peers.
+	require.True(s.allowlists.allows(core.DigestFixture(), other, nil))
+}
@@ -0,0 +1,151 @@
+// Copyright (c) 2016-2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package scheduler
+
+import (
+	"sync"
+	"time"
+
+	"github.com/andres-erbsen/clock"
+	"github.com/uber/kraken/core"
+	"github.com/uber/kraken/lib/torrent/scheduler/dispatch"
+)
+
+// torrentBandwidth accumulates per-torrent bandwidth across the Dispatchers
+// a torrent is served by, keyed by the namespace the torrent was scheduled
+// under and its digest. Torrents which are removed for idleness and re-added
+// within ttl keep counting from their previous totals, as do torrents which
+// survive a scheduler reload. Totals are reset when the torrent stays out of
+// the scheduler for longer than ttl, when it is removed via RemoveTorrent,
+// or when the agent restarts, since they are not persisted.
+type torrentBandwidth struct {
+	clk clock.Clock
+	ttl time.Duration
+
+	mu sync.Mutex
+	m  map[bandwidthKey]*bandwidthEntry
+}
+
+type bandwidthKey struct {
+	namespace string
+	digest    core.Digest
+}
+
+// bandwidthSource is the subset of *dispatch.Dispatcher torrentBandwidth
+// reads from.
+type bandwidthSource interface {
+	Digest() core.Digest
+	BandwidthStats() dispatch.BandwidthStats
+}
+
+type bandwidthEntry struct {
+	// archived holds the totals of Dispatchers which have been removed.
+	archived dispatch.BandwidthStats
+
+	// live is the Dispatcher currently serving the torrent, if any.
+	live bandwidthSource
+
+	// idleSince is when the last Dispatcher was removed, if live is nil.
+	idleSince time.Time
+}
+
+func newTorrentBandwidth(clk clock.Clock, ttl time.Duration) *torrentBandwidth {
+	return &torrentBandwidth{
+		clk: clk,
+		ttl: ttl,
+		m:   make(map[bandwidthKey]*bandwidthEntry),
+	}
+}
+
+// setTTL changes how long the totals of idle torrents are kept.
+func (b *torrentBandwidth) setTTL(ttl time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.ttl = ttl
+}
+
+// track starts counting the bandwidth of d towards its torrent in namespace.
+func (b *torrentBandwidth) track(namespace string, d bandwidthSource) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.pruneLocked()
+
+	k := bandwidthKey{namespace, d.Digest()}
+	e, ok := b.m[k]
+	if !ok {
+		e = &bandwidthEntry{}
+		b.m[k] = e
+	}
+	if e.live != nil && e.live != d {
+		e.archived = e.archived.Add(e.live.BandwidthStats())
+	}
+	e.live = d
+}
+
+// untrack folds the current totals of d into its torrent's totals.
+func (b *torrentBandwidth) untrack(namespace string, d bandwidthSource) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	e, ok := b.m[bandwidthKey{namespace, d.Digest()}]
+	if !ok || e.live != d {
+		return
+	}
+	e.archived = e.archived.Add(d.BandwidthStats())
+	e.live = nil
+	e.idleSince = b.clk.Now()
+}
+
+// delete drops the totals of d in all namespaces.
+func (b *torrentBandwidth) delete(d core.Digest) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for k := range b.m {
+		if k.digest == d {
+			delete(b.m, k)
+		}
+	}
+}
+
+// get returns the totals of d in namespace. Returns false if d has not been
+// tracked in namespace since its totals were last reset.
+func (b *torrentBandwidth) get(namespace string, d core.Digest) (dispatch.BandwidthStats, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.pruneLocked()
+
+	e, ok := b.m[bandwidthKey{namespace, d}]
+	if !ok {
+		return dispatch.BandwidthStats{}, false
+	}
+	s := e.archived
+	if e.live != nil {
+		s = s.Add(e.live.BandwidthStats())
+	}
+	return s, true
+}
+
+// pruneLocked drops the totals of torrents which have been idle for ttl.
+func (b *torrentBandwidth) pruneLocked() {
+	cutoff := b.clk.Now().Add(-b.ttl)
+	for k, e := range b.m {
+		if e.live == nil && !e.idleSince.After(cutoff) {
+			delete(b.m, k)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+// Copyright (c) 2016-2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/andres-erbsen/clock"
+	"github.com/uber/kraken/core"
+	"github.com/uber/kraken/lib/torrent/scheduler/dispatch"
+
+	"github.com/stretchr/testify/require"
+)
+
+type fakeBandwidthSource struct {
+	digest core.Digest
+	stats  dispatch.BandwidthStats
+}
+
+func (s *fakeBandwidthSource) Digest() core.Digest { return s.digest }
+
+func (s *fakeBandwidthSource) BandwidthStats() dispatch.BandwidthStats { return s.stats }
+
+func TestTorrentBandwidthCarriesOverDispatchers(t *testing.T) {
+	require := require.New(t)
+
+	ns := core.NamespaceFixture()
+	d := core.DigestFixture()
+	b := newTorrentBandwidth(clock.NewMock(), time.Hour)
+
+	_, ok := b.get(ns, d)
+	require.False(ok)
+
+	d1 := &fakeBandwidthSource{d, dispatch.BandwidthStats{}}
+	b.track(ns, d1)
+	d1.stats = dispatch.BandwidthStats{BytesUploaded: 1, BytesDownloaded: 2}
+
+	stats, ok := b.get(ns, d)
+	require.True(ok)
+	require.Equal(dispatch.BandwidthStats{BytesUploaded: 1, BytesDownloaded: 2}, stats)
+
+	// Idle removal keeps the totals.
+	b.untrack(ns, d1)
+	stats, ok = b.get(ns, d)
+	require.True(ok)
+	require.Equal(dispatch.BandwidthStats{BytesUploaded: 1, BytesDownloaded: 2}, stats)
+
+	// Re-adding the torrent counts on from the previous totals.
+	d2 := &fakeBandwidthSource{d, dispatch.BandwidthStats{BytesUploaded: 10}}
+	b.track(ns, d2)
+	stats, ok = b.get(ns, d)
+	require.True(ok)
+	require.Equal(dispatch.BandwidthStats{BytesUploaded: 11, BytesDownloaded: 2}, stats)
+
+	// A Dispatcher replaced without being untracked, e.g. on reload, is
+	// folded into the totals.
+	d3 := &fakeBandwidthSource{d, dispatch.BandwidthStats{BytesDownloaded: 5}}
+	b.track(ns, d3)
+	b.untrack(ns, d2)
+	stats, ok = b.get(ns, d)
+	require.True(ok)
+	require.Equal(dispatch.BandwidthStats{BytesUploaded: 11, BytesDownloaded: 7}, stats)
+
+	b.delete(d)
+	_, ok = b.get(ns, d)
+	require.False(ok)
+}
+
+func TestTorrentBandwidthSeparatesNamespaces(t *testing.T) {
+	require := require.New(t)
+
+	d := core.DigestFixture()
+	b := newTorrentBandwidth(clock.NewMock(), time.Hour)
+
+	b.track("ns1", &fakeBandwidthSource{d, dispatch.BandwidthStats{BytesDownloaded: 1}})
+	b.track("ns2", &fakeBandwidthSource{d, dispatch.BandwidthStats{BytesDownloaded: 2}})
+
+	stats, ok := b.get("ns1", d)
+	require.True(ok)
+	require.Equal(int64(1), stats.BytesDownloaded)
+
+	stats, ok = b.get("ns2", d)
+	require.True(ok)
+	require.Equal(int64(2), stats.BytesDownloaded)
+
+	_, ok = b.get("ns3", d)
+	require.False(ok)
+
+	// Deleting the blob resets every namespace.
+	b.delete(d)
+	_, ok = b.get("ns1", d)
+	require.False(ok)
+	_, ok = b.get("ns2", d)
+	require.False(ok)
+}
+
+func TestTorrentBandwidthExpiresIdleTorrents(t *testing.T) {
+	require := require.New(t)
+
+	clk := clock.NewMock()
+	ns := core.NamespaceFixture()
+	d := core.DigestFixture()
+	b := newTorrentBandwidth(clk, time.Hour)
+
+	src := &fakeBandwidthSource{d, dispatch.BandwidthStats{BytesDownloaded: 1}}
+	b.track(ns, src)
+
+	// Torrents in the scheduler never expire.
+	clk.Add(2 * time.Hour)
+	_, ok := b.get(ns, d)
+	require.True(ok)
+
+	b.untrack(ns, src)
+	clk.Add(59 * time.Minute)
+	_, ok = b.get(ns, d)
+	require.True(ok)
+
+	clk.Add(time.Minute)
+	_, ok = b.get(ns, d)
+	require.False(ok)
+	require.Empty(b.m)
+}
+
+func TestSchedulerTorrentBandwidth(t *testing.T) {
+	require := require.New(t)
+
+	mocks, cleanup := newTestMocks(t)
+	defer cleanup()
+
+	config := configFixture()
+
+	seeder := mocks.newPeer(config)
+	leecher := mocks.newPeer(config)
+
+	blob := core.NewBlobFixture()
+	namespace := core.TagFixture()
+
+	mocks.metaInfoClient.EXPECT().Download(
+		namespace, blob.Digest).Return(blob.MetaInfo, nil).Times(2)
+
+	_, err := leecher.scheduler.TorrentBandwidth(namespace, blob.Digest)
+	require.Equal(ErrTorrentNotFound, err)
+
+	seeder.writeTorrent(namespace, blob)
+	require.NoError(seeder.scheduler.Download(namespace, blob.Digest))
+
+	require.NoError(leecher.scheduler.Download(namespace, blob.Digest))
+	leecher.checkTorrent(t, namespace, blob)
+
+	size := blob.MetaInfo.Length()
+
+	stats, err := leecher.scheduler.TorrentBandwidth(namespace, blob.Digest)
+	require.NoError(err)
+	require.Equal(size, stats.BytesDownloaded)
+	require.Equal(int64(0), stats.BytesUploaded)
+
+	// The seeder counts sent pieces after the send returns, which may race
+	// with the leecher completing.
+	require.Eventually(func() bool {
+		stats, err := seeder.scheduler.TorrentBandwidth(namespace, blob.Digest)
+		return err == nil && stats == dispatch.BandwidthStats{BytesUploaded: size}
+	}, 5*time.Second, 10*time.Millisecond)
+
+	require.NoError(leecher.scheduler.RemoveTorrent(blob.Digest))
+
+	_, err = leecher.scheduler.TorrentBandwidth(namespace, blob.Digest)
+	require.Equal(ErrTorrentNotFound, err)
+}
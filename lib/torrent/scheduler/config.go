@@ -54,6 +54,35 @@ type Config struct {
 
 	ProbeTimeout time.Duration `yaml:"probe_timeout"`
 
+	// BitfieldSnapshotInterval is the interval in which the bitfields of
+	// in-progress torrents are persisted to disk, so a restarted agent only
+	// needs to verify pieces written since the last snapshot. Zero disables
+	// snapshots and restore-time verification.
+	BitfieldSnapshotInterval time.Duration `yaml:"bitfield_snapshot_interval"`
+
+	// StarvationWindow is how long an incomplete torrent with peers may make no
+	// download progress while other torrents advance before it is reported as
+	// starved. Checked every EmitStatsInterval. Zero disables starvation
+	// detection; download rate fairness is emitted regardless.
+	StarvationWindow time.Duration `yaml:"starvation_window"`
+
+	// UnreachablePeerTTL is how long failures to connect to announced peers
+	// are reported by UnreachablePeers.
+	UnreachablePeerTTL time.Duration `yaml:"unreachable_peer_ttl"`
+
+	// BandwidthStatsTTL is how long per-torrent bandwidth totals are kept
+	// after a torrent is removed from the scheduler for idleness.
+	BandwidthStatsTTL time.Duration `yaml:"bandwidth_stats_ttl"`
+
+	// SuperSeedNamespaces lists regular expressions of namespaces whose
+	// torrents are super-seeded while complete. Use Dispatch.SuperSeed to
+	// super-seed every torrent.
+	SuperSeedNamespaces []string `yaml:"super_seed_namespaces"`
+
+	// OriginFallback configures agents to download directly from origin while
+	// the tracker is unavailable.
+	OriginFallback OriginFallbackConfig `yaml:"origin_fallback"`
+
 	ConnState connstate.Config `yaml:"connstate"`
 
 	Conn conn.Config `yaml:"conn"`
@@ -86,5 +115,11 @@ func (c Config) applyDefaults() Config {
 	if c.ProbeTimeout == 0 {
 		c.ProbeTimeout = 3 * time.Second
 	}
+	if c.UnreachablePeerTTL == 0 {
+		c.UnreachablePeerTTL = 10 * time.Minute
+	}
+	if c.BandwidthStatsTTL == 0 {
+		c.BandwidthStatsTTL = time.Hour
+	}
 	return c
 }
@@ -23,10 +23,19 @@ import (
 // Config is the configuration for individual live connections.
 type Config struct {
 
-	// HandshakeTimeout is the timeout for dialing, writing, and reading connections
+	// HandshakeTimeout is the timeout for writing and reading connections
 	// during handshake.
 	HandshakeTimeout time.Duration `yaml:"handshake_timeout"`
 
+	// DialTimeout is the timeout for dialing remote peers. Defaults to
+	// HandshakeTimeout.
+	DialTimeout time.Duration `yaml:"dial_timeout"`
+
+	// ConnectDeadline bounds the total time spent dialing and handshaking an
+	// outgoing connection, such that unresponsive peers are abandoned quickly
+	// even if each individual step is within its timeout.
+	ConnectDeadline time.Duration `yaml:"connect_deadline"`
+
 	// SenderBufferSize is the size of the sender channel for a connection.
 	// Prevents writers to the connection from being blocked if there are many
 	// writers trying to send messages at the same time.
@@ -44,6 +53,13 @@ func (c Config) applyDefaults() Config {
 	if c.HandshakeTimeout == 0 {
 		c.HandshakeTimeout = 5 * time.Second
 	}
+	if c.DialTimeout == 0 {
+		c.DialTimeout = c.HandshakeTimeout
+	}
+	if c.ConnectDeadline == 0 {
+		// Dial, send handshake, read handshake.
+		c.ConnectDeadline = c.DialTimeout + 2*c.HandshakeTimeout
+	}
 	if c.SenderBufferSize == 0 {
 		c.SenderBufferSize = 10000
 	}
@@ -142,6 +142,11 @@ func (c *Conn) InfoHash() core.InfoHash {
 	return c.infoHash
 }
 
+// RemoteAddr returns the address of the remote peer.
+func (c *Conn) RemoteAddr() net.Addr {
+	return c.nc.RemoteAddr()
+}
+
 // CreatedAt returns the time at which the Conn was created.
 func (c *Conn) CreatedAt() time.Time {
 	return c.createdAt
@@ -17,6 +17,8 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"syscall"
+	"time"
 
 	"github.com/andres-erbsen/clock"
 	"github.com/uber-go/tally"
@@ -170,6 +172,11 @@ func (pc *PendingConn) Namespace() string {
 	return pc.handshake.namespace
 }
 
+// RemoteAddr returns the address of the remote peer.
+func (pc *PendingConn) RemoteAddr() net.Addr {
+	return pc.nc.RemoteAddr()
+}
+
 // Close closes the connection.
 func (pc *PendingConn) Close() {
 	closers.Close(pc.nc)
@@ -229,7 +236,7 @@ func NewHandshaker(
 // Accept upgrades a raw network connection opened by a remote peer into a
 // PendingConn.
 func (h *Handshaker) Accept(nc net.Conn) (*PendingConn, error) {
-	hs, err := h.readHandshake(nc)
+	hs, err := h.readHandshake(nc, h.handshakeDeadline(time.Time{}))
 	if err != nil {
 		return nil, fmt.Errorf("read handshake: %s", err)
 	}
@@ -245,7 +252,7 @@ func (h *Handshaker) Establish(
 
 	// Namespace is one-directional: it is only supplied by the connection opener
 	// and is not reciprocated by the connection acceptor.
-	if err := h.sendHandshake(pc.nc, info, remoteBitfields, ""); err != nil {
+	if err := h.sendHandshake(pc.nc, info, remoteBitfields, "", h.handshakeDeadline(time.Time{})); err != nil {
 		return nil, fmt.Errorf("send handshake: %s", err)
 	}
 	c, err := h.newConn(pc.nc, pc.handshake.peerID, info, true)
@@ -257,7 +264,7 @@ func (h *Handshaker) Establish(
 
 // Initialize returns a fully established Conn for the given torrent to the
 // given peer / address. Also returns the bitfield of the remote peer and
-// its connections for the torrent.
+// its connections for the torrent. Errors are *InitializeErrors.
 func (h *Handshaker) Initialize(
 	peerID core.PeerID,
 	addr string,
@@ -265,23 +272,88 @@ func (h *Handshaker) Initialize(
 	remoteBitfields RemoteBitfields,
 	namespace string) (*HandshakeResult, error) {
 
-	nc, err := net.DialTimeout("tcp", addr, h.config.HandshakeTimeout)
+	// NOTE: We do not use the clock interface here because the net package uses
+	// the system clock when evaluating deadlines.
+	deadline := time.Now().Add(h.config.ConnectDeadline)
+
+	dialer := net.Dialer{Timeout: h.config.DialTimeout, Deadline: deadline}
+	nc, err := dialer.Dial("tcp", addr)
 	if err != nil {
-		return nil, fmt.Errorf("dial: %s", err)
+		reason := FailureUnreachable
+		if isTimeout(err) {
+			h.stats.Counter("dial_timeouts").Inc(1)
+			reason = FailureTimeout
+		} else if errors.Is(err, syscall.ECONNREFUSED) {
+			reason = FailureRefused
+		}
+		return nil, &InitializeError{reason, fmt.Errorf("dial: %s", err)}
 	}
-	r, err := h.fullHandshake(nc, peerID, info, remoteBitfields, namespace)
+	r, err := h.fullHandshake(nc, peerID, info, remoteBitfields, namespace, deadline)
 	if err != nil {
+		if isTimeout(err) {
+			h.stats.Counter("handshake_timeouts").Inc(1)
+		}
 		closers.Close(nc)
-		return nil, err
+		return nil, &InitializeError{FailureHandshake, err}
 	}
 	return r, nil
 }
 
+// Reasons an outgoing conn may fail to initialize.
+const (
+	// FailureRefused means the peer actively refused the dial.
+	FailureRefused = "refused"
+
+	// FailureTimeout means the dial timed out, e.g. due to a firewall dropping
+	// packets.
+	FailureTimeout = "timeout"
+
+	// FailureUnreachable means the dial failed for any other reason, e.g. no
+	// route to the peer.
+	FailureUnreachable = "unreachable"
+
+	// FailureHandshake means the peer was dialed but the handshake failed.
+	FailureHandshake = "handshake"
+)
+
+// InitializeError is returned by Initialize when an outgoing conn cannot be
+// established.
+type InitializeError struct {
+	// Reason is one of the Failure reasons.
+	Reason string
+	Err    error
+}
+
+func (e *InitializeError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *InitializeError) Unwrap() error {
+	return e.Err
+}
+
+// handshakeDeadline returns the deadline for a single handshake read or write,
+// which is HandshakeTimeout from now but no later than connectDeadline, if
+// set.
+func (h *Handshaker) handshakeDeadline(connectDeadline time.Time) time.Time {
+	d := time.Now().Add(h.config.HandshakeTimeout)
+	if !connectDeadline.IsZero() && connectDeadline.Before(d) {
+		return connectDeadline
+	}
+	return d
+}
+
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
 func (h *Handshaker) sendHandshake(
 	nc net.Conn,
 	info *storage.TorrentInfo,
 	remoteBitfields RemoteBitfields,
-	namespace string) error {
+	namespace string,
+	deadline time.Time) error {
 
 	hs := &handshake{
 		peerID:          h.peerID,
@@ -295,13 +367,13 @@ func (h *Handshaker) sendHandshake(
 	if err != nil {
 		return err
 	}
-	return sendMessageWithTimeout(nc, msg, h.config.HandshakeTimeout)
+	return sendMessageWithDeadline(nc, msg, deadline)
 }
 
-func (h *Handshaker) readHandshake(nc net.Conn) (*handshake, error) {
-	m, err := readMessageWithTimeout(nc, h.config.HandshakeTimeout)
+func (h *Handshaker) readHandshake(nc net.Conn, deadline time.Time) (*handshake, error) {
+	m, err := readMessageWithDeadline(nc, deadline)
 	if err != nil {
-		return nil, fmt.Errorf("read message: %s", err)
+		return nil, fmt.Errorf("read message: %w", err)
 	}
 	hs, err := handshakeFromP2PMessage(m)
 	if err != nil {
@@ -315,14 +387,15 @@ func (h *Handshaker) fullHandshake(
 	peerID core.PeerID,
 	info *storage.TorrentInfo,
 	remoteBitfields RemoteBitfields,
-	namespace string) (*HandshakeResult, error) {
+	namespace string,
+	connectDeadline time.Time) (*HandshakeResult, error) {
 
-	if err := h.sendHandshake(nc, info, remoteBitfields, namespace); err != nil {
-		return nil, fmt.Errorf("send handshake: %s", err)
+	if err := h.sendHandshake(nc, info, remoteBitfields, namespace, h.handshakeDeadline(connectDeadline)); err != nil {
+		return nil, fmt.Errorf("send handshake: %w", err)
 	}
-	hs, err := h.readHandshake(nc)
+	hs, err := h.readHandshake(nc, h.handshakeDeadline(connectDeadline))
 	if err != nil {
-		return nil, fmt.Errorf("read handshake: %s", err)
+		return nil, fmt.Errorf("read handshake: %w", err)
 	}
 	if hs.peerID != peerID {
 		return nil, errors.New("unexpected peer id")
@@ -14,15 +14,20 @@
 package conn
 
 import (
+	"errors"
 	"net"
 	"sync"
 	"testing"
 	"time"
 
+	"github.com/andres-erbsen/clock"
 	"github.com/stretchr/testify/require"
+	"github.com/uber-go/tally"
+	"go.uber.org/zap"
 
 	"github.com/uber/kraken/core"
 	"github.com/uber/kraken/gen/go/proto/p2p"
+	"github.com/uber/kraken/lib/torrent/networkevent"
 	"github.com/uber/kraken/lib/torrent/storage"
 	"github.com/uber/kraken/utils/bitsetutil"
 )
@@ -129,3 +134,74 @@ func TestHandshakerHandlesEmptyBitfield(t *testing.T) {
 
 	wg.Wait()
 }
+
+func TestHandshakerInitializeConnectDeadline(t *testing.T) {
+	require := require.New(t)
+
+	l, err := net.Listen("tcp", "localhost:0")
+	require.NoError(err)
+	t.Cleanup(func() {
+		require.NoError(l.Close())
+	})
+
+	// Accepts connections but never responds to handshakes.
+	go func() {
+		for {
+			nc, err := l.Accept()
+			if err != nil {
+				return
+			}
+			t.Cleanup(func() { nc.Close() })
+		}
+	}()
+
+	stats := tally.NewTestScope("", nil)
+	h, err := NewHandshaker(
+		Config{HandshakeTimeout: 5 * time.Second, ConnectDeadline: 200 * time.Millisecond},
+		stats,
+		clock.New(),
+		networkevent.NewTestProducer(),
+		core.PeerIDFixture(),
+		noopEvents{},
+		zap.NewNop().Sugar())
+	require.NoError(err)
+
+	start := time.Now()
+	_, err = h.Initialize(
+		core.PeerIDFixture(), l.Addr().String(), storage.TorrentInfoFixture(4, 1), nil, core.TagFixture())
+	require.Error(err)
+	require.True(time.Since(start) < h.config.HandshakeTimeout)
+	var initErr *InitializeError
+	require.True(errors.As(err, &initErr))
+	require.Equal(FailureHandshake, initErr.Reason)
+
+	counters := stats.Snapshot().Counters()
+	require.Equal(int64(1), counters["handshake_timeouts+module=conn"].Value())
+	require.NotContains(counters, "dial_timeouts+module=conn")
+}
+
+func TestHandshakerInitializeRefused(t *testing.T) {
+	require := require.New(t)
+
+	// Grab a free port with nothing listening on it.
+	l, err := net.Listen("tcp", "localhost:0")
+	require.NoError(err)
+	addr := l.Addr().String()
+	require.NoError(l.Close())
+
+	h, err := NewHandshaker(
+		Config{},
+		tally.NoopScope,
+		clock.New(),
+		networkevent.NewTestProducer(),
+		core.PeerIDFixture(),
+		noopEvents{},
+		zap.NewNop().Sugar())
+	require.NoError(err)
+
+	_, err = h.Initialize(
+		core.PeerIDFixture(), addr, storage.TorrentInfoFixture(4, 1), nil, core.TagFixture())
+	var initErr *InitializeError
+	require.True(errors.As(err, &initErr))
+	require.Equal(FailureRefused, initErr.Reason)
+}
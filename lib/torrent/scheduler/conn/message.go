@@ -102,12 +102,12 @@ func sendMessage(nc net.Conn, msg *p2p.Message) error {
 		return fmt.Errorf("proto marshal: %s", err)
 	}
 	if err := binary.Write(nc, binary.BigEndian, uint32(len(data))); err != nil {
-		return fmt.Errorf("write data length: %s", err)
+		return fmt.Errorf("write data length: %w", err)
 	}
 	for len(data) > 0 {
 		n, err := nc.Write(data)
 		if err != nil {
-			return fmt.Errorf("write data: %s", err)
+			return fmt.Errorf("write data: %w", err)
 		}
 		data = data[n:]
 	}
@@ -117,7 +117,11 @@ func sendMessage(nc net.Conn, msg *p2p.Message) error {
 func sendMessageWithTimeout(nc net.Conn, msg *p2p.Message, timeout time.Duration) error {
 	// NOTE: We do not use the clock interface here because the net package uses
 	// the system clock when evaluating deadlines.
-	if err := nc.SetWriteDeadline(time.Now().Add(timeout)); err != nil {
+	return sendMessageWithDeadline(nc, msg, time.Now().Add(timeout))
+}
+
+func sendMessageWithDeadline(nc net.Conn, msg *p2p.Message, deadline time.Time) error {
+	if err := nc.SetWriteDeadline(deadline); err != nil {
 		return fmt.Errorf("set write deadline: %s", err)
 	}
 	return sendMessage(nc, msg)
@@ -126,7 +130,7 @@ func sendMessageWithTimeout(nc net.Conn, msg *p2p.Message, timeout time.Duration
 func readMessage(nc net.Conn) (*p2p.Message, error) {
 	var msglen [4]byte
 	if _, err := io.ReadFull(nc, msglen[:]); err != nil {
-		return nil, fmt.Errorf("read message length: %s", err)
+		return nil, fmt.Errorf("read message length: %w", err)
 	}
 	dataLen := binary.BigEndian.Uint32(msglen[:])
 	if uint64(dataLen) > maxMessageSize {
@@ -134,7 +138,7 @@ func readMessage(nc net.Conn) (*p2p.Message, error) {
 	}
 	data := make([]byte, dataLen)
 	if _, err := io.ReadFull(nc, data); err != nil {
-		return nil, fmt.Errorf("read data: %s", err)
+		return nil, fmt.Errorf("read data: %w", err)
 	}
 	p2pMessage := new(p2p.Message)
 	if err := proto.Unmarshal(data, p2pMessage); err != nil {
@@ -146,7 +150,11 @@ func readMessage(nc net.Conn) (*p2p.Message, error) {
 func readMessageWithTimeout(nc net.Conn, timeout time.Duration) (*p2p.Message, error) {
 	// NOTE: We do not use the clock interface here because the net package uses
 	// the system clock when evaluating deadlines.
-	if err := nc.SetReadDeadline(time.Now().Add(timeout)); err != nil {
+	return readMessageWithDeadline(nc, time.Now().Add(timeout))
+}
+
+func readMessageWithDeadline(nc net.Conn, deadline time.Time) (*p2p.Message, error) {
+	if err := nc.SetReadDeadline(deadline); err != nil {
 		return nil, fmt.Errorf("set read deadline: %s", err)
 	}
 	return readMessage(nc)
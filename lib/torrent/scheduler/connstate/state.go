@@ -15,6 +15,7 @@ package connstate
 
 import (
 	"errors"
+	"sort"
 	"time"
 
 	"github.com/andres-erbsen/clock"
@@ -275,6 +276,50 @@ func (s *State) BlacklistSnapshot() []BlacklistedConn {
 	return conns
 }
 
+// TorrentConn describes a connection of a torrent.
+type TorrentConn struct {
+	PeerID core.PeerID
+
+	// Status is "pending", "active" or "blacklisted".
+	Status string
+
+	// BlacklistRemaining is set for blacklisted conns.
+	BlacklistRemaining time.Duration
+}
+
+// TorrentConns returns the pending, active and blacklisted conns of h, sorted
+// by peer id.
+func (s *State) TorrentConns(h core.InfoHash) []TorrentConn {
+	var conns []TorrentConn
+	for peerID, e := range s.conns[h] {
+		c := TorrentConn{PeerID: peerID}
+		switch e.status {
+		case _pending:
+			c.Status = "pending"
+		case _active:
+			c.Status = "active"
+		default:
+			c.Status = "unknown"
+		}
+		conns = append(conns, c)
+	}
+	now := s.clk.Now()
+	for k, e := range s.blacklist {
+		if k.hash != h || !e.Blacklisted(now) {
+			continue
+		}
+		conns = append(conns, TorrentConn{
+			PeerID:             k.peerID,
+			Status:             "blacklisted",
+			BlacklistRemaining: e.Remaining(now),
+		})
+	}
+	sort.Slice(conns, func(i, j int) bool {
+		return conns[i].PeerID.LessThan(conns[j].PeerID)
+	})
+	return conns
+}
+
 func (s *State) get(h core.InfoHash, peerID core.PeerID) entry {
 	peers, ok := s.conns[h]
 	if !ok {
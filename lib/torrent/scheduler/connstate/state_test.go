@@ -302,3 +302,37 @@ func TestMaxMutualConns(t *testing.T) {
 	require.Equal(s.AddPending(core.PeerIDFixture(), h, neighbors[:mutualConnLimit+1]), ErrTooManyMutualConns)
 	require.NoError(s.AddPending(core.PeerIDFixture(), h, neighbors[:mutualConnLimit]))
 }
+
+func TestStateTorrentConns(t *testing.T) {
+	require := require.New(t)
+
+	config := Config{
+		BlacklistDuration: 30 * time.Second,
+	}
+	clk := clock.NewMock()
+	s := testState(config, clk)
+
+	c, cleanup := conn.Fixture()
+	defer cleanup()
+
+	h := c.InfoHash()
+	pending := core.PeerIDFixture()
+	blacklisted := core.PeerIDFixture()
+	expired := core.PeerIDFixture()
+
+	require.NoError(s.AddPending(c.PeerID(), h, nil))
+	require.NoError(s.MovePendingToActive(c))
+	require.NoError(s.AddPending(pending, h, nil))
+	require.NoError(s.Blacklist(expired, h))
+	clk.Add(config.BlacklistDuration)
+	require.NoError(s.Blacklist(blacklisted, h))
+
+	// Conns of other torrents are excluded.
+	require.NoError(s.AddPending(core.PeerIDFixture(), core.InfoHashFixture(), nil))
+
+	require.ElementsMatch([]TorrentConn{
+		{PeerID: c.PeerID(), Status: "active"},
+		{PeerID: pending, Status: "pending"},
+		{PeerID: blacklisted, Status: "blacklisted", BlacklistRemaining: config.BlacklistDuration},
+	}, s.TorrentConns(h))
+}
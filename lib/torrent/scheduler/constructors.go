@@ -15,6 +15,7 @@ package scheduler
 
 import (
 	"crypto/tls"
+	"errors"
 	"fmt"
 
 	"github.com/uber/kraken/core"
@@ -31,7 +32,9 @@ import (
 	"github.com/uber-go/tally"
 )
 
-// NewAgentScheduler creates and starts a ReloadableScheduler configured for an agent.
+// NewAgentScheduler creates and starts a ReloadableScheduler configured for an
+// agent. If origins is not nil, downloads fall back to origins while the
+// tracker is unavailable, per config.OriginFallback.
 func NewAgentScheduler(
 	config Config,
 	stats tally.Scope,
@@ -40,11 +43,17 @@ func NewAgentScheduler(
 	netevents networkevent.Producer,
 	trackers hashring.PassiveRing,
 	announceClient announceclient.Client,
+	origins OriginFetcher,
 	tls *tls.Config) (ReloadableScheduler, error) {
 
+	if config.OriginFallback.Enabled && origins == nil {
+		return nil, errors.New("origin fallback enabled without origins")
+	}
 	s, err := newScheduler(
 		config,
-		agentstorage.NewTorrentArchive(stats, cads, metainfoclient.New(trackers, tls)),
+		agentstorage.NewTorrentArchive(
+			agentstorage.Config{BitfieldSnapshots: config.BitfieldSnapshotInterval > 0},
+			stats, cads, metainfoclient.New(trackers, tls)),
 		stats,
 		pctx,
 		announceClient,
@@ -52,6 +61,9 @@ func NewAgentScheduler(
 	if err != nil {
 		return nil, fmt.Errorf("new scheduler: %s", err)
 	}
+	if origins != nil {
+		s.originFallback = newOriginFallback(config.OriginFallback, cads, origins, s.clock, s.stats)
+	}
 
 	aq := func() announcequeue.Queue { return announcequeue.New() }
 	rs := makeReloadable(s, aq)
@@ -0,0 +1,43 @@
+// Copyright (c) 2016-2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package dispatch
+
+// BandwidthStats counts piece payload bytes exchanged over a Dispatcher's
+// lifetime. Counters are monotonic and are never reset while the Dispatcher
+// exists.
+type BandwidthStats struct {
+	// BytesUploaded is the bytes of piece payloads sent to peers.
+	BytesUploaded int64
+
+	// BytesDownloaded is the bytes of piece payloads received from peers,
+	// including duplicate and rejected payloads, since they were transferred
+	// nonetheless.
+	BytesDownloaded int64
+}
+
+// Add returns the sum of s and o.
+func (s BandwidthStats) Add(o BandwidthStats) BandwidthStats {
+	return BandwidthStats{
+		BytesUploaded:   s.BytesUploaded + o.BytesUploaded,
+		BytesDownloaded: s.BytesDownloaded + o.BytesDownloaded,
+	}
+}
+
+// BandwidthStats returns the bytes uploaded and downloaded by d.
+func (d *Dispatcher) BandwidthStats() BandwidthStats {
+	return BandwidthStats{
+		BytesUploaded:   d.bytesUploaded.Load(),
+		BytesDownloaded: d.bytesDownloaded.Load(),
+	}
+}
@@ -0,0 +1,115 @@
+// Copyright (c) 2016-2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package dispatch
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/uber/kraken/core"
+)
+
+// CompletionStats summarizes how a Dispatcher's torrent was downloaded.
+type CompletionStats struct {
+	CreatedAt time.Time
+
+	// FirstPieceAt is when the first good piece was received. Zero if no
+	// pieces were received, e.g. the torrent was already complete.
+	FirstPieceAt time.Time
+
+	// CompletedAt is zero if the torrent is not complete.
+	CompletedAt time.Time
+
+	// BytesReceived maps each peer to the bytes of good pieces received from
+	// it. Includes peers which have since been removed.
+	BytesReceived map[core.PeerID]int64
+
+	// PeakPeers is the maximum number of peers connected at once.
+	PeakPeers int
+}
+
+// completionTracker tracks Dispatcher-wide timing for CompletionStats.
+// Per-peer byte counts live in peerStats.
+type completionTracker struct {
+	mu           sync.Mutex
+	firstPieceAt time.Time
+	completedAt  time.Time
+	numPeers     int
+	peakPeers    int
+}
+
+func (t *completionTracker) pieceReceived(now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.firstPieceAt.IsZero() {
+		t.firstPieceAt = now
+	}
+}
+
+func (t *completionTracker) completed(now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.completedAt.IsZero() {
+		t.completedAt = now
+	}
+}
+
+func (t *completionTracker) peerAdded() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.numPeers++
+	if t.numPeers > t.peakPeers {
+		t.peakPeers = t.numPeers
+	}
+}
+
+func (t *completionTracker) peerRemoved() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.numPeers--
+}
+
+// CompletionStats returns timing and byte accounting for d's download.
+func (d *Dispatcher) CompletionStats() CompletionStats {
+	d.completion.mu.Lock()
+	s := CompletionStats{
+		CreatedAt:     d.createdAt,
+		FirstPieceAt:  d.completion.firstPieceAt,
+		CompletedAt:   d.completion.completedAt,
+		BytesReceived: make(map[core.PeerID]int64),
+		PeakPeers:     d.completion.peakPeers,
+	}
+	d.completion.mu.Unlock()
+
+	d.peerStats.Range(func(k, v interface{}) bool {
+		peerID, ok := k.(core.PeerID)
+		if !ok {
+			panic(fmt.Sprintf("dispatcher: stored key is not core.PeerID: %T", k))
+		}
+		pstats, ok := v.(*peerStats)
+		if !ok {
+			panic(fmt.Sprintf("dispatcher: stored value is not *peerStats: %T", v))
+		}
+		if n := pstats.getGoodBytesReceived(); n > 0 {
+			s.BytesReceived[peerID] = n
+		}
+		return true
+	})
+	return s
+}
@@ -47,6 +47,16 @@ type Config struct {
 	EndgameThreshold int `yaml:"endgame_threshold"`
 
 	DisableEndgame bool `yaml:"disable_endgame"`
+
+	// SuperSeed enables super-seeding while the torrent is complete: each piece
+	// is served to a single peer until every piece has been served once, so
+	// that peers must exchange pieces amongst themselves. Intended for origins
+	// seeding brand-new blobs to large fleets.
+	SuperSeed bool `yaml:"super_seed"`
+
+	// DiskFullRetryInterval is how long a torrent stays paused after a piece
+	// write fails due to the disk being full, before piece requests resume.
+	DiskFullRetryInterval time.Duration `yaml:"disk_full_retry_interval"`
 }
 
 func (c Config) applyDefaults() Config {
@@ -65,6 +75,9 @@ func (c Config) applyDefaults() Config {
 	if c.EndgameThreshold == 0 {
 		c.EndgameThreshold = c.PipelineLimit
 	}
+	if c.DiskFullRetryInterval == 0 {
+		c.DiskFullRetryInterval = 30 * time.Second
+	}
 	return c
 }
 
@@ -0,0 +1,64 @@
+// Copyright (c) 2016-2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package dispatch
+
+import "fmt"
+
+// PausedForDisk returns true if d has stopped requesting pieces because its
+// torrent could not be written to a full disk.
+func (d *Dispatcher) PausedForDisk() bool {
+	return d.diskFull.Load()
+}
+
+// pauseForDiskFull stops d from requesting pieces until the disk full retry
+// interval elapses. Requesting pieces which cannot be written only wastes
+// bandwidth, so the torrent idles while space is reclaimed, e.g. by eviction.
+// If the disk is still full on resume, the next failed write pauses again.
+func (d *Dispatcher) pauseForDiskFull() {
+	if !d.diskFull.CAS(false, true) {
+		return
+	}
+	d.stats.Counter("disk_full_pauses").Inc(1)
+	d.log().Warnf(
+		"Pausing torrent for %s: disk full", d.config.DiskFullRetryInterval)
+
+	go func() {
+		select {
+		case <-d.clk.After(d.config.DiskFullRetryInterval):
+		case <-d.pendingPiecesDone:
+			return
+		}
+		d.resumeFromDiskFull()
+	}()
+}
+
+// resumeFromDiskFull resumes piece requests to all peers of d.
+func (d *Dispatcher) resumeFromDiskFull() {
+	if !d.diskFull.CAS(true, false) {
+		return
+	}
+	d.stats.Counter("disk_full_resumes").Inc(1)
+	d.log().Info("Resuming torrent paused for disk full")
+
+	d.peers.Range(func(k, v interface{}) bool {
+		p, ok := v.(*peer)
+		if !ok {
+			panic(fmt.Sprintf("dispatcher: stored value is not *peer: %T", v))
+		}
+		if _, err := d.maybeRequestMorePieces(p); err != nil {
+			d.log("peer", p).Errorf("Error requesting more pieces: %s", err)
+		}
+		return true
+	})
+}
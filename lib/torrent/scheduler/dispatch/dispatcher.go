@@ -32,12 +32,15 @@ import (
 	"github.com/andres-erbsen/clock"
 	"github.com/uber-go/tally"
 	"github.com/willf/bitset"
+	"go.uber.org/atomic"
 	"go.uber.org/zap"
 	"golang.org/x/sync/syncmap"
 )
 
 var (
 	errChunkNotSupported = errors.New("reading / writing chunk of piece not supported")
+	errSuperSeeding      = errors.New("super-seeding: piece already served, request from other peers")
+	errPaused            = errors.New("torrent paused, request from other peers")
 )
 
 // Events defines Dispatcher events.
@@ -70,9 +73,15 @@ type Dispatcher struct {
 	netevents             networkevent.Producer
 	pieceRequestTimeout   time.Duration
 	pieceRequestManager   *piecerequest.Manager
+	superSeeder           *superSeeder // Nil if super-seeding is disabled.
 	pendingPiecesDoneOnce sync.Once
 	pendingPiecesDone     chan struct{}
 	completeOnce          sync.Once
+	completion            completionTracker
+	diskFull              *atomic.Bool
+	paused                *atomic.Bool
+	bytesUploaded         *atomic.Int64
+	bytesDownloaded       *atomic.Int64
 	events                Events
 	logger                *zap.SugaredLogger
 	torrentlog            *torrentlog.Logger
@@ -130,6 +139,11 @@ func newDispatcher(
 		return nil, fmt.Errorf("piece request manager: %s", err)
 	}
 
+	var ss *superSeeder
+	if config.SuperSeed {
+		ss = newSuperSeeder(t.NumPieces())
+	}
+
 	return &Dispatcher{
 		config:              config,
 		stats:               stats,
@@ -141,6 +155,11 @@ func newDispatcher(
 		netevents:           netevents,
 		pieceRequestTimeout: pieceRequestTimeout,
 		pieceRequestManager: pieceRequestManager,
+		superSeeder:         ss,
+		diskFull:            atomic.NewBool(false),
+		paused:              atomic.NewBool(false),
+		bytesUploaded:       atomic.NewInt64(0),
+		bytesDownloaded:     atomic.NewInt64(0),
 		pendingPiecesDone:   make(chan struct{}),
 		events:              events,
 		logger:              logger,
@@ -148,6 +167,11 @@ func newDispatcher(
 	}, nil
 }
 
+// SuperSeeds returns true if d super-seeds its torrent while complete.
+func (d *Dispatcher) SuperSeeds() bool {
+	return d.superSeeder != nil
+}
+
 // Digest returns the blob digest for d's torrent.
 func (d *Dispatcher) Digest() core.Digest {
 	return d.torrent.Digest()
@@ -215,6 +239,15 @@ func (d *Dispatcher) LastWriteTime() time.Time {
 	return d.torrent.getLastWriteTime()
 }
 
+// SnapshotBitfield persists the bitfield of d's torrent, if supported by the
+// underlying storage.
+func (d *Dispatcher) SnapshotBitfield() error {
+	if s, ok := d.torrent.Torrent.(storage.BitfieldSnapshotter); ok {
+		return s.SnapshotBitfield()
+	}
+	return nil
+}
+
 // Empty returns true if the Dispatcher has no peers.
 func (d *Dispatcher) Empty() bool {
 	empty := true
@@ -283,6 +316,7 @@ func (d *Dispatcher) addPeer(
 	for _, i := range p.bitfield.GetAllSet() {
 		d.numPeersByPiece.Increment(int(i))
 	}
+	d.completion.peerAdded()
 	return p, nil
 }
 
@@ -293,6 +327,7 @@ func (d *Dispatcher) removePeer(p *peer) error {
 	for _, i := range p.bitfield.GetAllSet() {
 		d.numPeersByPiece.Decrement(int(i))
 	}
+	d.completion.peerRemoved()
 	return nil
 }
 
@@ -341,6 +376,7 @@ func (d *Dispatcher) String() string {
 }
 
 func (d *Dispatcher) complete() {
+	d.completion.completed(d.clk.Now())
 	d.completeOnce.Do(func() { go d.events.DispatcherComplete(d) })
 	d.pendingPiecesDoneOnce.Do(func() { close(d.pendingPiecesDone) })
 
@@ -382,6 +418,7 @@ func (d *Dispatcher) complete() {
 			RequestsSent:            requested,
 			GoodPiecesReceived:      pstats.getGoodPiecesReceived(),
 			DuplicatePiecesReceived: pstats.getDuplicatePiecesReceived(),
+			InvalidPiecesReceived:   pstats.getInvalidPiecesReceived(),
 		}
 		summaries = append(summaries, summary)
 		return true
@@ -411,6 +448,9 @@ func (d *Dispatcher) maybeRequestMorePieces(p *peer) (bool, error) {
 }
 
 func (d *Dispatcher) maybeSendPieceRequests(p *peer, pieceCandidates *bitset.BitSet) (bool, error) {
+	if d.diskFull.Load() || d.paused.Load() {
+		return false, nil
+	}
 	pieces, err := d.pieceRequestManager.ReservePieces(p.id, pieceCandidates, d.numPeersByPiece, d.endgame())
 	if err != nil {
 		return false, err
@@ -440,27 +480,14 @@ func (d *Dispatcher) resendFailedPieceRequests() {
 
 	var sent int
 	for _, r := range failedRequests {
-		d.peers.Range(func(k, v interface{}) bool {
-			p, ok := v.(*peer)
-			if !ok {
-				panic(fmt.Sprintf("dispatcher: stored value is not *peer: %T", v))
-			}
-			if (r.Status == piecerequest.StatusExpired || r.Status == piecerequest.StatusInvalid) &&
-				r.PeerID == p.id {
-				// Do not resend to the same peer for expired or invalid requests.
-				return true
-			}
-
-			b := d.torrent.Bitfield()
-			candidates := p.bitfield.Intersection(b.Complement())
-			if candidates.Test(uint(r.Piece)) {
-				nb := bitset.New(b.Len()).Set(uint(r.Piece))
-				if sent, err := d.maybeSendPieceRequests(p, nb); sent && err == nil {
-					return false
-				}
-			}
-			return true
-		})
+		var exclude core.PeerID
+		if r.Status == piecerequest.StatusExpired || r.Status == piecerequest.StatusInvalid {
+			// Do not resend to the same peer for expired or invalid requests.
+			exclude = r.PeerID
+		}
+		if d.resendPiece(r.Piece, exclude) {
+			sent++
+		}
 	}
 
 	unsent := len(failedRequests) - sent
@@ -469,6 +496,33 @@ func (d *Dispatcher) resendFailedPieceRequests() {
 	}
 }
 
+// resendPiece requests piece i from the first peer other than exclude which
+// has it. Returns true if a request was sent.
+func (d *Dispatcher) resendPiece(i int, exclude core.PeerID) bool {
+	var sent bool
+	d.peers.Range(func(k, v interface{}) bool {
+		p, ok := v.(*peer)
+		if !ok {
+			panic(fmt.Sprintf("dispatcher: stored value is not *peer: %T", v))
+		}
+		if p.id == exclude {
+			return true
+		}
+
+		b := d.torrent.Bitfield()
+		candidates := p.bitfield.Intersection(b.Complement())
+		if candidates.Test(uint(i)) {
+			nb := bitset.New(b.Len()).Set(uint(i))
+			if ok, err := d.maybeSendPieceRequests(p, nb); ok && err == nil {
+				sent = true
+				return false
+			}
+		}
+		return true
+	})
+	return sent
+}
+
 func (d *Dispatcher) watchPendingPieceRequests() {
 	for {
 		select {
@@ -521,6 +575,9 @@ func (d *Dispatcher) handleError(p *peer, msg *p2p.ErrorMessage) {
 	case p2p.ErrorMessage_PIECE_REQUEST_FAILED:
 		d.log().Errorf("Piece request failed: %s", msg.Error)
 		d.pieceRequestManager.MarkInvalid(p.id, int(msg.Index))
+	case p2p.ErrorMessage_PIECE_REQUEST_REJECTED:
+		d.log("peer", p, "piece", msg.Index).Debugf("Piece request rejected: %s", msg.Error)
+		d.pieceRequestManager.MarkInvalid(p.id, int(msg.Index))
 	}
 }
 
@@ -554,9 +611,28 @@ func (d *Dispatcher) handlePieceRequest(p *peer, msg *p2p.PieceRequestMessage) {
 		return
 	}
 
+	if d.paused.Load() {
+		if err := p.messages.Send(conn.NewErrorMessage(i, p2p.ErrorMessage_PIECE_REQUEST_FAILED, errPaused)); err != nil {
+			d.log("peer", p, "piece", i).Errorf("Error sending error message: %s", err)
+		}
+		return
+	}
+
+	superSeeding := d.superSeeder != nil && d.torrent.Complete()
+	if superSeeding && !d.superSeeder.reserve(i) {
+		d.stats.Counter("super_seed_rejections").Inc(1)
+		if err := p.messages.Send(conn.NewErrorMessage(i, p2p.ErrorMessage_PIECE_REQUEST_REJECTED, errSuperSeeding)); err != nil {
+			d.log("peer", p, "piece", i).Errorf("Error sending error message: %s", err)
+		}
+		return
+	}
+
 	payload, err := d.torrent.GetPieceReader(i)
 	if err != nil {
 		d.log("peer", p, "piece", i).Errorf("Error getting reader for requested piece: %s", err)
+		if superSeeding {
+			d.superSeeder.release(i)
+		}
 		if err := p.messages.Send(conn.NewErrorMessage(i, p2p.ErrorMessage_PIECE_REQUEST_FAILED, err)); err != nil {
 			d.log("peer", p, "piece", i).Errorf("Error sending error message: %s", err)
 		}
@@ -564,11 +640,15 @@ func (d *Dispatcher) handlePieceRequest(p *peer, msg *p2p.PieceRequestMessage) {
 	}
 
 	if err := p.messages.Send(conn.NewPiecePayloadMessage(i, payload)); err != nil {
+		if superSeeding {
+			d.superSeeder.release(i)
+		}
 		return
 	}
 
 	p.touchLastPieceSent()
 	p.pstats.incrementPiecesSent()
+	d.bytesUploaded.Add(d.torrent.PieceLength(i))
 
 	// Assume that the peer successfully received the piece.
 	p.bitfield.Set(uint(i), true)
@@ -579,6 +659,8 @@ func (d *Dispatcher) handlePiecePayload(
 
 	defer closers.Close(payload)
 
+	d.bytesDownloaded.Add(int64(msg.Length))
+
 	i := int(msg.Index)
 	if !d.isFullPiece(i, int(msg.Offset), int(msg.Length)) {
 		d.log("peer", p, "piece", i).Error("Rejecting piece payload: chunk not supported")
@@ -587,7 +669,20 @@ func (d *Dispatcher) handlePiecePayload(
 	}
 
 	if err := d.torrent.WritePiece(payload, i); err != nil {
-		if err != storage.ErrPieceComplete {
+		if err == storage.ErrDiskFull {
+			// Not the peer's fault: release the piece so it can be requested
+			// again once the torrent resumes.
+			d.pieceRequestManager.Clear(i)
+			d.pauseForDiskFull()
+		} else if err == storage.ErrInvalidPieceSum {
+			// Fail fast: re-request just this piece from another peer, rather
+			// than waiting for the next resend of failed requests.
+			d.log("peer", p, "piece", i).Info("Rejecting piece payload: invalid piece sum")
+			d.stats.Counter("invalid_pieces").Inc(1)
+			p.pstats.incrementInvalidPiecesReceived()
+			d.pieceRequestManager.MarkInvalid(p.id, i)
+			d.resendPiece(i, p.id)
+		} else if err != storage.ErrPieceComplete {
 			d.log("peer", p, "piece", i).Errorf("Error writing piece payload: %s", err)
 			d.pieceRequestManager.MarkInvalid(p.id, i)
 		} else {
@@ -600,7 +695,9 @@ func (d *Dispatcher) handlePiecePayload(
 		networkevent.ReceivePieceEvent(d.torrent.InfoHash(), d.localPeerID, p.id, i))
 
 	p.pstats.incrementGoodPiecesReceived()
+	p.pstats.addGoodBytesReceived(d.torrent.PieceLength(i))
 	p.touchLastGoodPieceReceived()
+	d.completion.pieceReceived(d.clk.Now())
 	if d.torrent.Complete() {
 		d.complete()
 	}
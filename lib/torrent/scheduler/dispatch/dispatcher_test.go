@@ -30,6 +30,7 @@ import (
 	"github.com/uber/kraken/lib/torrent/storage/piecereader"
 	"github.com/uber/kraken/utils/bitsetutil"
 	"github.com/uber/kraken/utils/memsize"
+	"github.com/uber/kraken/utils/testutil"
 	"go.uber.org/zap"
 
 	"github.com/andres-erbsen/clock"
@@ -421,6 +422,138 @@ func TestDispatcherClosesCompletedPeersWhenComplete(t *testing.T) {
 	require.True(closed(incompletePeer.messages))
 }
 
+func TestDispatcherCompletionStats(t *testing.T) {
+	require := require.New(t)
+
+	blob := core.SizedBlobFixture(2, 1)
+
+	torrent, cleanup := agentstorage.TorrentFixture(blob.MetaInfo)
+	defer cleanup()
+
+	clk := clock.NewMock()
+	d := testDispatcher(Config{}, clk, torrent)
+	created := clk.Now()
+
+	p1, err := d.addPeer(core.PeerIDFixture(), bitsetutil.FromBools(true, true), newMockMessages())
+	require.NoError(err)
+	p2, err := d.addPeer(core.PeerIDFixture(), bitsetutil.FromBools(true, true), newMockMessages())
+	require.NoError(err)
+	require.NoError(d.removePeer(p2))
+	_, err = d.addPeer(core.PeerIDFixture(), bitsetutil.FromBools(true, true), newMockMessages())
+	require.NoError(err)
+
+	clk.Add(time.Second)
+	require.NoError(d.dispatch(p1, conn.NewPiecePayloadMessage(0, piecereader.NewBuffer(blob.Content[0:1]))))
+
+	clk.Add(time.Second)
+	require.NoError(d.dispatch(p2, conn.NewPiecePayloadMessage(1, piecereader.NewBuffer(blob.Content[1:2]))))
+
+	require.Equal(CompletionStats{
+		CreatedAt:     created,
+		FirstPieceAt:  created.Add(time.Second),
+		CompletedAt:   created.Add(2 * time.Second),
+		BytesReceived: map[core.PeerID]int64{p1.id: 1, p2.id: 1},
+		PeakPeers:     2,
+	}, d.CompletionStats())
+}
+
+func TestDispatcherBandwidthStats(t *testing.T) {
+	require := require.New(t)
+
+	blob := core.SizedBlobFixture(2, 1)
+
+	torrent, cleanup := agentstorage.TorrentFixture(blob.MetaInfo)
+	defer cleanup()
+
+	d := testDispatcher(Config{}, clock.NewMock(), torrent)
+
+	p1, err := d.addPeer(core.PeerIDFixture(), bitsetutil.FromBools(true, true), newMockMessages())
+	require.NoError(err)
+	p2, err := d.addPeer(core.PeerIDFixture(), bitsetutil.FromBools(false, false), newMockMessages())
+	require.NoError(err)
+
+	require.NoError(d.dispatch(p1, conn.NewPiecePayloadMessage(0, piecereader.NewBuffer(blob.Content[0:1]))))
+
+	// Duplicate payloads still count towards bytes downloaded.
+	require.NoError(d.dispatch(p1, conn.NewPiecePayloadMessage(0, piecereader.NewBuffer(blob.Content[0:1]))))
+
+	require.NoError(d.dispatch(p2, conn.NewPieceRequestMessage(0, 1)))
+
+	require.Equal(BandwidthStats{BytesUploaded: 1, BytesDownloaded: 2}, d.BandwidthStats())
+
+	// Counters persist after peers are removed.
+	require.NoError(d.removePeer(p1))
+	require.NoError(d.removePeer(p2))
+	require.Equal(BandwidthStats{BytesUploaded: 1, BytesDownloaded: 2}, d.BandwidthStats())
+}
+
+func TestDispatcherDump(t *testing.T) {
+	require := require.New(t)
+
+	blob := core.SizedBlobFixture(3, 1)
+
+	torrent, cleanup := agentstorage.TorrentFixture(blob.MetaInfo)
+	defer cleanup()
+
+	clk := clock.NewMock()
+	d := testDispatcher(Config{}, clk, torrent)
+
+	require.NoError(torrent.WritePiece(piecereader.NewBuffer(blob.Content[0:1]), 0))
+
+	p, err := d.addPeer(core.PeerIDFixture(), bitsetutil.FromBools(false, true, true), newMockMessages())
+	require.NoError(err)
+
+	_, err = d.maybeRequestMorePieces(p)
+	require.NoError(err)
+
+	clk.Add(time.Second)
+
+	dump := d.Dump()
+	require.Equal(torrent.InfoHash().Hex(), dump.InfoHash)
+	require.Equal(blob.Digest.String(), dump.Digest)
+	require.False(dump.Complete)
+	require.Equal(3, dump.NumPieces)
+	require.Equal([]int{1, 2}, dump.MissingPieces)
+
+	require.NotEmpty(dump.PieceRequests)
+	for _, r := range dump.PieceRequests {
+		require.Equal(p.id.String(), r.PeerID)
+		require.Equal("pending", r.Status)
+		require.Equal(time.Second, r.Age)
+	}
+
+	require.Len(dump.Peers, 1)
+	require.Equal(p.id.String(), dump.Peers[0].PeerID)
+	require.Equal(2, dump.Peers[0].NumPieces)
+	require.Equal(len(dump.PieceRequests), dump.Peers[0].PieceRequestsSent)
+}
+
+func TestDispatcherInvalidPiecePayloadRerequestsFromOtherPeer(t *testing.T) {
+	require := require.New(t)
+
+	blob := core.SizedBlobFixture(2, 1)
+
+	torrent, cleanup := agentstorage.TorrentFixture(blob.MetaInfo)
+	defer cleanup()
+
+	d := testDispatcher(Config{}, clock.NewMock(), torrent)
+
+	bad, err := d.addPeer(core.PeerIDFixture(), bitsetutil.FromBools(true, true), newMockMessages())
+	require.NoError(err)
+	good, err := d.addPeer(core.PeerIDFixture(), bitsetutil.FromBools(true, true), newMockMessages())
+	require.NoError(err)
+
+	corrupt := []byte{blob.Content[0] + 1}
+	require.NoError(d.dispatch(bad, conn.NewPiecePayloadMessage(0, piecereader.NewBuffer(corrupt))))
+
+	require.False(torrent.HasPiece(0))
+	require.Equal(1, bad.pstats.getInvalidPiecesReceived())
+
+	// Only the corrupt piece is re-requested, and not from the peer which sent it.
+	require.Equal(map[int]int{0: 1}, numRequestsPerPiece(good.messages))
+	require.Empty(numRequestsPerPiece(bad.messages))
+}
+
 func TestDispatcherHandleCompleteRequestsPieces(t *testing.T) {
 	require := require.New(t)
 
@@ -497,3 +630,155 @@ func TestDispatcherPeerPieceCounts(t *testing.T) {
 	require.Equal(1, d.numPeersByPiece.Get(1))
 	require.Equal(2, d.numPeersByPiece.Get(2))
 }
+
+func TestDispatcherSuperSeedServesEachPieceOncePerRound(t *testing.T) {
+	require := require.New(t)
+
+	blob := core.SizedBlobFixture(3, 1)
+
+	torrent, cleanup := agentstorage.TorrentFixture(blob.MetaInfo)
+	defer cleanup()
+
+	for i := 0; i < 3; i++ {
+		require.NoError(torrent.WritePiece(piecereader.NewBuffer(blob.Content[i:i+1]), i))
+	}
+
+	d := testDispatcher(Config{SuperSeed: true}, clock.NewMock(), torrent)
+
+	p1, err := d.addPeer(core.PeerIDFixture(), bitsetutil.FromBools(false, false, false), newMockMessages())
+	require.NoError(err)
+
+	p2, err := d.addPeer(core.PeerIDFixture(), bitsetutil.FromBools(false, false, false), newMockMessages())
+	require.NoError(err)
+
+	lastSent := func(p *peer) p2p.Message_Type {
+		m := p.messages.(*mockMessages)
+		return m.sent[len(m.sent)-1].Message.Type
+	}
+
+	request := func(p *peer, i int) p2p.Message_Type {
+		require.NoError(d.dispatch(p, conn.NewPieceRequestMessage(i, 1)))
+		return lastSent(p)
+	}
+
+	require.Equal(p2p.Message_PIECE_PAYLOAD, request(p1, 0))
+	require.Equal(p2p.Message_ERROR, request(p2, 0))
+	rejected := p2.messages.(*mockMessages).sent
+	require.Equal(p2p.ErrorMessage_PIECE_REQUEST_REJECTED, rejected[len(rejected)-1].Message.Error.Code)
+	require.Equal(p2p.Message_PIECE_PAYLOAD, request(p2, 1))
+	require.Equal(p2p.Message_ERROR, request(p1, 1))
+
+	// Serving the last piece starts a new round.
+	require.Equal(p2p.Message_PIECE_PAYLOAD, request(p1, 2))
+	require.Equal(p2p.Message_PIECE_PAYLOAD, request(p2, 0))
+	require.Equal(p2p.Message_ERROR, request(p1, 0))
+}
+
+// diskFullTorrent fails piece writes with storage.ErrDiskFull while full is set.
+type diskFullTorrent struct {
+	storage.Torrent
+	full bool
+}
+
+func (t *diskFullTorrent) WritePiece(src storage.PieceReader, piece int) error {
+	if t.full {
+		return storage.ErrDiskFull
+	}
+	return t.Torrent.WritePiece(src, piece)
+}
+
+func TestDispatcherPausesForDiskFull(t *testing.T) {
+	require := require.New(t)
+
+	blob := core.SizedBlobFixture(2, 1)
+
+	torrent, cleanup := agentstorage.TorrentFixture(blob.MetaInfo)
+	defer cleanup()
+
+	dft := &diskFullTorrent{Torrent: torrent, full: true}
+	d := testDispatcher(Config{}, clock.NewMock(), dft)
+
+	p, err := d.addPeer(core.PeerIDFixture(), bitsetutil.FromBools(true, true), newMockMessages())
+	require.NoError(err)
+
+	require.NoError(d.dispatch(p, conn.NewPiecePayloadMessage(0, piecereader.NewBuffer(blob.Content[0:1]))))
+	require.True(d.PausedForDisk())
+	require.False(torrent.HasPiece(0))
+
+	// No pieces are requested while paused.
+	sent, err := d.maybeRequestMorePieces(p)
+	require.NoError(err)
+	require.False(sent)
+	require.Empty(numRequestsPerPiece(p.messages))
+
+	// Once space is reclaimed, resuming requests the failed piece again.
+	dft.full = false
+	d.resumeFromDiskFull()
+	require.False(d.PausedForDisk())
+	require.Equal(map[int]int{0: 1, 1: 1}, numRequestsPerPiece(p.messages))
+
+	require.NoError(d.dispatch(p, conn.NewPiecePayloadMessage(0, piecereader.NewBuffer(blob.Content[0:1]))))
+	require.True(torrent.HasPiece(0))
+}
+
+func TestDispatcherResumesAfterDiskFullRetryInterval(t *testing.T) {
+	require := require.New(t)
+
+	blob := core.SizedBlobFixture(2, 1)
+
+	torrent, cleanup := agentstorage.TorrentFixture(blob.MetaInfo)
+	defer cleanup()
+
+	clk := clock.NewMock()
+	config := Config{DiskFullRetryInterval: time.Minute}
+	d := testDispatcher(config, clk, &diskFullTorrent{Torrent: torrent, full: true})
+
+	// The peer has no pieces, so resuming does not send any requests.
+	p, err := d.addPeer(core.PeerIDFixture(), bitsetutil.FromBools(false, false), newMockMessages())
+	require.NoError(err)
+
+	require.NoError(d.dispatch(p, conn.NewPiecePayloadMessage(0, piecereader.NewBuffer(blob.Content[0:1]))))
+	require.True(d.PausedForDisk())
+
+	require.NoError(testutil.PollUntilTrue(5*time.Second, func() bool {
+		clk.Add(config.DiskFullRetryInterval)
+		return !d.PausedForDisk()
+	}))
+}
+
+func TestDispatcherPauseAndResume(t *testing.T) {
+	require := require.New(t)
+
+	blob := core.SizedBlobFixture(2, 1)
+
+	torrent, cleanup := agentstorage.TorrentFixture(blob.MetaInfo)
+	defer cleanup()
+
+	require.NoError(torrent.WritePiece(piecereader.NewBuffer(blob.Content[0:1]), 0))
+
+	d := testDispatcher(Config{}, clock.NewMock(), torrent)
+
+	p, err := d.addPeer(core.PeerIDFixture(), bitsetutil.FromBools(false, true), newMockMessages())
+	require.NoError(err)
+
+	d.Pause()
+	require.True(d.Paused())
+
+	// No pieces are requested while paused.
+	sent, err := d.maybeRequestMorePieces(p)
+	require.NoError(err)
+	require.False(sent)
+	require.Empty(numRequestsPerPiece(p.messages))
+
+	// No pieces are served while paused.
+	require.NoError(d.dispatch(p, conn.NewPieceRequestMessage(0, 1)))
+	m := p.messages.(*mockMessages)
+	require.Equal(p2p.Message_ERROR, m.sent[len(m.sent)-1].Message.Type)
+
+	d.Resume()
+	require.False(d.Paused())
+	require.Equal(map[int]int{1: 1}, numRequestsPerPiece(p.messages))
+
+	require.NoError(d.dispatch(p, conn.NewPieceRequestMessage(0, 1)))
+	require.Equal(p2p.Message_PIECE_PAYLOAD, m.sent[len(m.sent)-1].Message.Type)
+}
@@ -0,0 +1,108 @@
+// Copyright (c) 2016-2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package dispatch
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// TorrentDump is a diagnostic snapshot of a Dispatcher's torrent, for
+// root-causing stalled downloads.
+type TorrentDump struct {
+	InfoHash  string `json:"info_hash"`
+	Digest    string `json:"digest"`
+	Complete  bool   `json:"complete"`
+	Paused    bool   `json:"paused"`
+	DiskFull  bool   `json:"disk_full"`
+	NumPieces int    `json:"num_pieces"`
+
+	// MissingPieces are the pieces not yet written.
+	MissingPieces []int `json:"missing_pieces"`
+
+	// PieceRequests are requests sent to peers which have not been fulfilled.
+	PieceRequests []PieceRequestDump `json:"piece_requests"`
+
+	Peers []PeerDump `json:"peers"`
+}
+
+// PieceRequestDump describes an outstanding piece request.
+type PieceRequestDump struct {
+	Piece  int           `json:"piece"`
+	PeerID string        `json:"peer_id"`
+	Status string        `json:"status"`
+	Age    time.Duration `json:"age"`
+}
+
+// PeerDump describes a peer connected to the Dispatcher.
+type PeerDump struct {
+	PeerID string `json:"peer_id"`
+
+	// NumPieces is the number of pieces the peer has.
+	NumPieces             int       `json:"num_pieces"`
+	PieceRequestsSent     int       `json:"piece_requests_sent"`
+	GoodPiecesReceived    int       `json:"good_pieces_received"`
+	LastGoodPieceReceived time.Time `json:"last_good_piece_received"`
+	LastPieceSent         time.Time `json:"last_piece_sent"`
+}
+
+// Dump returns a diagnostic snapshot of d. Only copies of d's state are
+// returned, so Dump is safe to call while d is serving.
+func (d *Dispatcher) Dump() TorrentDump {
+	bitfield := d.torrent.Bitfield()
+	dump := TorrentDump{
+		InfoHash:      d.InfoHash().Hex(),
+		Digest:        d.Digest().String(),
+		Complete:      d.Complete(),
+		Paused:        d.Paused(),
+		DiskFull:      d.diskFull.Load(),
+		NumPieces:     d.torrent.NumPieces(),
+		MissingPieces: []int{},
+		PieceRequests: []PieceRequestDump{},
+		Peers:         []PeerDump{},
+	}
+	for i := 0; i < dump.NumPieces; i++ {
+		if !bitfield.Test(uint(i)) {
+			dump.MissingPieces = append(dump.MissingPieces, i)
+		}
+	}
+	for _, r := range d.pieceRequestManager.Snapshot() {
+		dump.PieceRequests = append(dump.PieceRequests, PieceRequestDump{
+			Piece:  r.Piece,
+			PeerID: r.PeerID.String(),
+			Status: r.Status.String(),
+			Age:    r.Age,
+		})
+	}
+	d.peers.Range(func(k, v interface{}) bool {
+		p, ok := v.(*peer)
+		if !ok {
+			panic(fmt.Sprintf("dispatcher: stored value is not *peer: %T", v))
+		}
+		dump.Peers = append(dump.Peers, PeerDump{
+			PeerID:                p.id.String(),
+			NumPieces:             len(p.bitfield.GetAllSet()),
+			PieceRequestsSent:     p.pstats.getPieceRequestsSent(),
+			GoodPiecesReceived:    p.pstats.getGoodPiecesReceived(),
+			LastGoodPieceReceived: p.getLastGoodPieceReceived(),
+			LastPieceSent:         p.getLastPieceSent(),
+		})
+		return true
+	})
+	sort.Slice(dump.Peers, func(i, j int) bool {
+		return dump.Peers[i].PeerID < dump.Peers[j].PeerID
+	})
+	return dump
+}
@@ -0,0 +1,55 @@
+// Copyright (c) 2016-2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package dispatch
+
+import "fmt"
+
+// Pause stops d from requesting and serving pieces. Peers remain connected,
+// and piece requests from peers are rejected so they request elsewhere.
+func (d *Dispatcher) Pause() {
+	if !d.paused.CAS(false, true) {
+		return
+	}
+	d.log().Info("Pausing torrent")
+}
+
+// Resume undoes Pause and resumes piece requests to all peers of d. Access
+// times are reset, so the time spent paused does not count towards idle
+// timeouts.
+func (d *Dispatcher) Resume() {
+	if !d.paused.CAS(true, false) {
+		return
+	}
+	d.log().Info("Resuming torrent")
+
+	d.torrent.touchLastWrite()
+	d.torrent.touchLastRead()
+	d.peers.Range(func(k, v interface{}) bool {
+		p, ok := v.(*peer)
+		if !ok {
+			panic(fmt.Sprintf("dispatcher: stored value is not *peer: %T", v))
+		}
+		p.touchLastGoodPieceReceived()
+		p.touchLastPieceSent()
+		if _, err := d.maybeRequestMorePieces(p); err != nil {
+			d.log("peer", p).Errorf("Error requesting more pieces: %s", err)
+		}
+		return true
+	})
+}
+
+// Paused returns true if d is paused.
+func (d *Dispatcher) Paused() bool {
+	return d.paused.Load()
+}
@@ -100,6 +100,10 @@ type peerStats struct {
 	goodPiecesReceived int
 	// Pieces we received from the peer that we already had.
 	duplicatePiecesReceived int
+	// Pieces we received from the peer that failed piece sum verification.
+	invalidPiecesReceived int
+	// Bytes of good pieces received from the peer.
+	goodBytesReceived int64
 }
 
 func (s *peerStats) getPieceRequestsSent() int {
@@ -171,3 +175,31 @@ func (s *peerStats) incrementDuplicatePiecesReceived() {
 
 	s.duplicatePiecesReceived++
 }
+
+func (s *peerStats) getGoodBytesReceived() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.goodBytesReceived
+}
+
+func (s *peerStats) addGoodBytesReceived(n int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.goodBytesReceived += n
+}
+
+func (s *peerStats) getInvalidPiecesReceived() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.invalidPiecesReceived
+}
+
+func (s *peerStats) incrementInvalidPiecesReceived() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.invalidPiecesReceived++
+}
@@ -43,6 +43,21 @@ const (
 	StatusInvalid
 )
 
+func (s Status) String() string {
+	switch s {
+	case StatusPending:
+		return "pending"
+	case StatusExpired:
+		return "expired"
+	case StatusUnsent:
+		return "unsent"
+	case StatusInvalid:
+		return "invalid"
+	default:
+		return fmt.Sprintf("unknown(%d)", int(s))
+	}
+}
+
 // Request represents a piece request to peer.
 type Request struct {
 	Piece  int
@@ -221,6 +236,47 @@ func (m *Manager) GetFailedRequests() []Request {
 	return failed
 }
 
+// RequestSnapshot is a copy of a Request, for diagnostics.
+type RequestSnapshot struct {
+	Piece  int
+	PeerID core.PeerID
+	Status Status
+
+	// Age is the time since the request was reserved.
+	Age time.Duration
+}
+
+// Snapshot returns a copy of all requests, sorted by piece. Pending requests
+// which have timed out are reported as expired.
+func (m *Manager) Snapshot() []RequestSnapshot {
+	m.RLock()
+	defer m.RUnlock()
+
+	now := m.clock.Now()
+	var snapshot []RequestSnapshot
+	for _, rs := range m.requests {
+		for _, r := range rs {
+			status := r.Status
+			if status == StatusPending && m.expired(r) {
+				status = StatusExpired
+			}
+			snapshot = append(snapshot, RequestSnapshot{
+				Piece:  r.Piece,
+				PeerID: r.PeerID,
+				Status: status,
+				Age:    now.Sub(r.sentAt),
+			})
+		}
+	}
+	sort.Slice(snapshot, func(i, j int) bool {
+		if snapshot[i].Piece != snapshot[j].Piece {
+			return snapshot[i].Piece < snapshot[j].Piece
+		}
+		return snapshot[i].PeerID.LessThan(snapshot[j].PeerID)
+	})
+	return snapshot
+}
+
 func (m *Manager) validRequest(peerID core.PeerID, pieceIdx int, allowDuplicates bool) bool {
 	for _, r := range m.requests[pieceIdx] {
 		if r.Status == StatusPending && !m.expired(r) {
@@ -406,3 +406,36 @@ func TestRarestFirstPolicy(t *testing.T) {
 	require.NoError(err)
 	require.Empty(pieces)
 }
+
+func TestManagerSnapshot(t *testing.T) {
+	require := require.New(t)
+
+	clk := clock.NewMock()
+	timeout := 5 * time.Second
+
+	m := newManager(clk, timeout, DefaultPolicy, 1)
+
+	p1 := core.PeerIDFixture()
+	p2 := core.PeerIDFixture()
+
+	pieces, err := m.ReservePieces(p1, bitsetutil.FromBools(true, false), countsFromInts(0, 0), false)
+	require.NoError(err)
+	require.Equal([]int{0}, pieces)
+
+	clk.Add(timeout + 1)
+
+	pieces, err = m.ReservePieces(p2, bitsetutil.FromBools(false, true), countsFromInts(0, 0), false)
+	require.NoError(err)
+	require.Equal([]int{1}, pieces)
+
+	clk.Add(time.Second)
+
+	require.Equal([]RequestSnapshot{
+		{Piece: 0, PeerID: p1, Status: StatusExpired, Age: timeout + 1 + time.Second},
+		{Piece: 1, PeerID: p2, Status: StatusPending, Age: time.Second},
+	}, m.Snapshot())
+
+	m.MarkInvalid(p2, 1)
+	require.Equal(StatusInvalid, m.Snapshot()[1].Status)
+	require.Equal("invalid", StatusInvalid.String())
+}
@@ -0,0 +1,58 @@
+// Copyright (c) 2016-2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package dispatch
+
+import (
+	"sync"
+
+	"github.com/willf/bitset"
+)
+
+// superSeeder implements super-seeding for a seeding Dispatcher: each piece is
+// served to at most one peer per round, forcing peers to fetch the rest of
+// the torrent from each other instead of all pulling the same pieces from the
+// seeder. Once every piece has been served, a new round begins, so a swarm
+// which fails to spread a piece can never starve.
+type superSeeder struct {
+	mu     sync.Mutex
+	served *bitset.BitSet
+}
+
+func newSuperSeeder(numPieces int) *superSeeder {
+	return &superSeeder{served: bitset.New(uint(numPieces))}
+}
+
+// reserve returns true if piece i has not been served in the current round,
+// marking it as served.
+func (s *superSeeder) reserve(i int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.served.Test(uint(i)) {
+		return false
+	}
+	s.served.Set(uint(i))
+	if s.served.All() {
+		s.served.ClearAll()
+	}
+	return true
+}
+
+// release unmarks piece i, for when serving a reserved piece fails.
+func (s *superSeeder) release(i int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.served.Clear(uint(i))
+}
@@ -0,0 +1,76 @@
+// Copyright (c) 2016-2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package scheduler
+
+import (
+	"time"
+
+	"github.com/uber/kraken/core"
+	"github.com/uber/kraken/lib/torrent/scheduler/dispatch"
+)
+
+// TorrentDump is a diagnostic snapshot of a torrent and its connections.
+type TorrentDump struct {
+	dispatch.TorrentDump
+
+	Namespace string     `json:"namespace"`
+	Conns     []ConnDump `json:"conns"`
+
+	// DownloadRate is in bytes per second, as of the last stats interval.
+	DownloadRate float64 `json:"download_rate"`
+	Starved      bool    `json:"starved"`
+}
+
+// ConnDump describes a connection of a torrent.
+type ConnDump struct {
+	PeerID string `json:"peer_id"`
+
+	// Status is "pending", "active" or "blacklisted".
+	Status             string        `json:"status"`
+	BlacklistRemaining time.Duration `json:"blacklist_remaining,omitempty"`
+}
+
+// torrentDumpEvent occurs when a torrent is dumped via scheduler API.
+type torrentDumpEvent struct {
+	infoHash core.InfoHash
+	result   chan torrentDumpResult
+}
+
+type torrentDumpResult struct {
+	dump TorrentDump
+	err  error
+}
+
+func (e torrentDumpEvent) apply(s *state) {
+	ctrl, ok := s.torrentControls[e.infoHash]
+	if !ok {
+		e.result <- torrentDumpResult{err: ErrTorrentNotFound}
+		return
+	}
+	dump := TorrentDump{
+		TorrentDump:  ctrl.dispatcher.Dump(),
+		Namespace:    ctrl.namespace,
+		Conns:        []ConnDump{},
+		DownloadRate: ctrl.progress.rate,
+		Starved:      ctrl.progress.starved,
+	}
+	for _, c := range s.conns.TorrentConns(e.infoHash) {
+		dump.Conns = append(dump.Conns, ConnDump{
+			PeerID:             c.PeerID.String(),
+			Status:             c.Status,
+			BlacklistRemaining: c.BlacklistRemaining,
+		})
+	}
+	e.result <- torrentDumpResult{dump: dump}
+}
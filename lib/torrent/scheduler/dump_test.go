@@ -0,0 +1,60 @@
+// Copyright (c) 2016-2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package scheduler
+
+import (
+	"testing"
+
+	"github.com/uber/kraken/core"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchedulerDumpTorrent(t *testing.T) {
+	require := require.New(t)
+
+	mocks, cleanup := newTestMocks(t)
+	defer cleanup()
+
+	config := configFixture()
+
+	seeder := mocks.newPeer(config)
+	leecher := mocks.newPeer(config)
+
+	blob := core.NewBlobFixture()
+	namespace := core.TagFixture()
+	h := blob.MetaInfo.InfoHash()
+
+	mocks.metaInfoClient.EXPECT().Download(
+		namespace, blob.Digest).Return(blob.MetaInfo, nil).Times(2)
+
+	_, err := seeder.scheduler.DumpTorrent(h)
+	require.Equal(ErrTorrentNotFound, err)
+
+	seeder.writeTorrent(namespace, blob)
+	require.NoError(seeder.scheduler.Download(namespace, blob.Digest))
+
+	require.NoError(leecher.scheduler.Download(namespace, blob.Digest))
+
+	dump, err := leecher.scheduler.DumpTorrent(h)
+	require.NoError(err)
+	require.Equal(h.Hex(), dump.InfoHash)
+	require.Equal(blob.Digest.String(), dump.Digest)
+	require.Equal(namespace, dump.Namespace)
+	require.True(dump.Complete)
+	require.Empty(dump.MissingPieces)
+	require.Empty(dump.PieceRequests)
+	// The leecher may have closed its conn to the seeder on completion, so
+	// conns are not checked.
+}
@@ -14,6 +14,8 @@
 package scheduler
 
 import (
+	"fmt"
+	"net"
 	"time"
 
 	"github.com/uber/kraken/core"
@@ -149,6 +151,14 @@ func (e incomingHandshakeEvent) apply(s *state) {
 		peerNeighbors[i] = peerID
 		i++
 	}
+	if !s.sched.allowlists.allows(e.pc.Digest(), e.pc.PeerID(), addrIP(e.pc.RemoteAddr())) {
+		s.log("peer", e.pc.PeerID(), "hash", e.pc.InfoHash()).Infof(
+			"Rejecting incoming handshake: %s", errPeerNotAllowed)
+		s.sched.torrentlog.IncomingConnectionReject(
+			e.pc.Digest(), e.pc.InfoHash(), e.pc.PeerID(), errPeerNotAllowed)
+		e.pc.Close()
+		return
+	}
 	if err := s.conns.AddPending(e.pc.PeerID(), e.pc.InfoHash(), peerNeighbors); err != nil {
 		s.log("peer", e.pc.PeerID(), "hash", e.pc.InfoHash()).Infof(
 			"Rejecting incoming handshake: %s", err)
@@ -268,8 +278,14 @@ type announceResultEvent struct {
 // if there is capacity. These connections are added to the scheduler's pending
 // connections and handshaked asynchronously.
 //
-// Also marks the dispatcher as ready to announce again.
+// Also marks the dispatcher as ready to announce again, and records which
+// peers are origins for completion timing.
 func (e announceResultEvent) apply(s *state) {
+	for _, p := range e.peers {
+		if p.Origin {
+			s.originPeers[p.PeerID] = true
+		}
+	}
 	ctrl, ok := s.torrentControls[e.infoHash]
 	if !ok {
 		s.log("hash", e.infoHash).Info("Dispatcher closed after announce response received")
@@ -288,6 +304,9 @@ func (e announceResultEvent) apply(s *state) {
 		if s.conns.Blacklisted(p.PeerID, e.infoHash) {
 			continue
 		}
+		if !s.sched.allowlists.allows(ctrl.dispatcher.Digest(), p.PeerID, net.ParseIP(p.IP)) {
+			continue
+		}
 		if err := s.conns.AddPending(p.PeerID, e.infoHash, nil); err != nil {
 			if err == connstate.ErrTorrentAtCapacity {
 				break
@@ -305,21 +324,65 @@ type announceErrEvent struct {
 	err      error
 }
 
-// apply marks the dispatcher as ready to announce again.
+// apply marks the dispatcher as ready to announce again, or hands the torrent
+// off to origin if the tracker is degraded.
 func (e announceErrEvent) apply(s *state) {
 	s.log("hash", e.infoHash).Errorf("Error announcing: %s", e.err)
+	if ctrl, ok := s.torrentControls[e.infoHash]; ok &&
+		!ctrl.dispatcher.Complete() && s.sched.originFallback.isDegraded() {
+
+		s.log("hash", e.infoHash).Warn("Tracker degraded, downloading torrent from origin")
+		s.fallBackToOrigin(e.infoHash, nil)
+		return
+	}
 	s.announceQueue.Ready(e.infoHash)
 }
 
+// originFallbackEvent occurs when a blob must be downloaded from origin
+// because the tracker is degraded.
+type originFallbackEvent struct {
+	namespace string
+	digest    core.Digest
+	errc      chan error
+}
+
+// apply hands off any p2p download of the blob in progress to origin, such
+// that the two never write the same download file.
+func (e originFallbackEvent) apply(s *state) {
+	for h, ctrl := range s.torrentControls {
+		if ctrl.dispatcher.Digest() != e.digest {
+			continue
+		}
+		if ctrl.dispatcher.Complete() {
+			e.errc <- nil
+		} else {
+			s.fallBackToOrigin(h, e.errc)
+		}
+		return
+	}
+	go func() {
+		_, err := s.sched.originFallback.download(e.namespace, e.digest)
+		e.errc <- err
+	}()
+}
+
 // newTorrentEvent occurs when a new torrent was requested for download.
 type newTorrentEvent struct {
 	namespace string
 	torrent   storage.Torrent
+	allowlist *PeerAllowlist
 	errc      chan error
 }
 
 // apply begins seeding / leeching a new torrent.
 func (e newTorrentEvent) apply(s *state) {
+	if e.allowlist != nil {
+		if err := s.sched.allowlists.set(e.torrent.Digest(), e.allowlist); err != nil {
+			e.errc <- fmt.Errorf("set allowlist: %s", err)
+			return
+		}
+		s.closeDisallowedConns(e.torrent.Digest(), e.torrent.InfoHash())
+	}
 	ctrl, ok := s.torrentControls[e.torrent.InfoHash()]
 	if !ok {
 		var err error
@@ -372,6 +435,10 @@ func (e dispatcherCompleteEvent) apply(s *state) {
 
 	s.log("hash", infoHash).Info("Torrent complete")
 	s.sched.netevents.Produce(networkevent.TorrentCompleteEvent(infoHash, s.sched.pctx.PeerID))
+	if timing, ok := s.completionTiming(ctrl.dispatcher.CompletionStats()); ok {
+		s.sched.netevents.Produce(
+			networkevent.TorrentCompleteTimingEvent(infoHash, s.sched.pctx.PeerID, timing))
+	}
 
 	// Immediately announce completed torrents.
 	go s.sched.announce(ctrl.dispatcher.Digest(), ctrl.dispatcher.InfoHash(), true)
@@ -391,6 +458,10 @@ func (e peerRemovedEvent) apply(s *state) {}
 type preemptionTickEvent struct{}
 
 func (e preemptionTickEvent) apply(s *state) {
+	if s.sched.paused.Load() {
+		// Paused torrents make no progress, so nothing is idle.
+		return
+	}
 	for _, c := range s.conns.ActiveConns() {
 		ctrl, ok := s.torrentControls[c.InfoHash()]
 		if !ok {
@@ -440,6 +511,33 @@ type emitStatsEvent struct{}
 
 func (e emitStatsEvent) apply(s *state) {
 	s.sched.stats.Gauge("torrents").Update(float64(len(s.torrentControls)))
+
+	var pausedForDisk int
+	for _, ctrl := range s.torrentControls {
+		if ctrl.dispatcher.PausedForDisk() {
+			pausedForDisk++
+		}
+	}
+	s.sched.stats.Gauge("torrents_paused_for_disk").Update(float64(pausedForDisk))
+
+	s.emitPausedGauge()
+	s.checkProgress()
+}
+
+// bitfieldSnapshotTickEvent occurs periodically to persist the bitfields of
+// in-progress torrents.
+type bitfieldSnapshotTickEvent struct{}
+
+func (e bitfieldSnapshotTickEvent) apply(s *state) {
+	var dispatchers []*dispatch.Dispatcher
+	for _, ctrl := range s.torrentControls {
+		if !ctrl.dispatcher.Complete() {
+			dispatchers = append(dispatchers, ctrl.dispatcher)
+		}
+	}
+	if len(dispatchers) > 0 {
+		go s.sched.snapshotBitfields(dispatchers)
+	}
 }
 
 type blacklistSnapshotEvent struct {
@@ -465,9 +563,45 @@ func (e removeTorrentEvent) apply(s *state) {
 			s.removeTorrent(h, ErrTorrentRemoved)
 		}
 	}
+	s.sched.allowlists.delete(e.digest)
+	s.sched.bandwidth.delete(e.digest)
 	e.errc <- s.sched.torrentArchive.DeleteTorrent(e.digest)
 }
 
+// pauseAllEvent occurs when all torrents are paused via scheduler API.
+type pauseAllEvent struct {
+	errc chan error
+}
+
+func (e pauseAllEvent) apply(s *state) {
+	if !s.sched.paused.Load() {
+		s.log("torrents", len(s.torrentControls)).Info("Pausing all torrents")
+		s.sched.paused.Store(true)
+		for _, ctrl := range s.torrentControls {
+			ctrl.dispatcher.Pause()
+		}
+		s.emitPausedGauge()
+	}
+	e.errc <- nil
+}
+
+// resumeAllEvent occurs when all torrents are resumed via scheduler API.
+type resumeAllEvent struct {
+	errc chan error
+}
+
+func (e resumeAllEvent) apply(s *state) {
+	if s.sched.paused.Load() {
+		s.log("torrents", len(s.torrentControls)).Info("Resuming all torrents")
+		s.sched.paused.Store(false)
+		for _, ctrl := range s.torrentControls {
+			ctrl.dispatcher.Resume()
+		}
+		s.emitPausedGauge()
+	}
+	e.errc <- nil
+}
+
 // probeEvent occurs when a probe is manually requested via scheduler API.
 // The event loop is unbuffered, so if a probe can be successfully sent, then
 // the event loop is healthy.
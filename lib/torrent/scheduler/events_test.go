@@ -26,6 +26,7 @@ import (
 	"github.com/uber/kraken/lib/torrent/scheduler/announcequeue"
 	"github.com/uber/kraken/lib/torrent/scheduler/conn"
 	"github.com/uber/kraken/lib/torrent/scheduler/connstate"
+	"github.com/uber/kraken/lib/torrent/scheduler/dispatch"
 	"github.com/uber/kraken/lib/torrent/storage"
 	"github.com/uber/kraken/lib/torrent/storage/agentstorage"
 	mockannounceclient "github.com/uber/kraken/mocks/tracker/announceclient"
@@ -95,7 +96,7 @@ func newStateMocks(t *testing.T) (*stateMocks, func()) {
 		metainfoClient: metainfoClient,
 		announceClient: announceClient,
 		announceQueue:  announcequeue.New(),
-		torrentArchive: agentstorage.NewTorrentArchive(tally.NoopScope, cads, metainfoClient),
+		torrentArchive: agentstorage.NewTorrentArchive(agentstorage.Config{}, tally.NoopScope, cads, metainfoClient),
 		eventLoop:      &mockEventLoop{t, make(chan event)},
 	}
 	return mocks, cleanup.Run
@@ -238,3 +239,64 @@ func TestAnnounceTickEventSkipsFullTorrents(t *testing.T) {
 		infoHash: full.dispatcher.InfoHash(),
 	})
 }
+
+func TestCompletionTimingAttributesOriginBytes(t *testing.T) {
+	require := require.New(t)
+
+	mocks, cleanup := newStateMocks(t)
+	defer cleanup()
+
+	state := mocks.newState(Config{})
+
+	origin := core.PeerIDFixture()
+	peer := core.PeerIDFixture()
+
+	// Origins are learned from announce responses.
+	announceResultEvent{
+		infoHash: core.InfoHashFixture(),
+		peers:    []*core.PeerInfo{{PeerID: origin, Origin: true}, {PeerID: peer}},
+	}.apply(state)
+
+	created := time.Now()
+	timing, ok := state.completionTiming(dispatch.CompletionStats{
+		CreatedAt:     created,
+		FirstPieceAt:  created.Add(time.Second),
+		CompletedAt:   created.Add(5 * time.Second),
+		BytesReceived: map[core.PeerID]int64{origin: 100, peer: 300},
+		PeakPeers:     2,
+	})
+	require.True(ok)
+	require.Equal(networkevent.CompletionTiming{
+		FirstPiece:  time.Second,
+		Complete:    5 * time.Second,
+		OriginBytes: 100,
+		PeerBytes:   300,
+		PeakPeers:   2,
+	}, timing)
+
+	// No timing for torrents which downloaded nothing.
+	_, ok = state.completionTiming(dispatch.CompletionStats{
+		CreatedAt:   created,
+		CompletedAt: created,
+	})
+	require.False(ok)
+}
+
+func TestAddTorrentSuperSeedsMatchingNamespaces(t *testing.T) {
+	require := require.New(t)
+
+	mocks, cleanup := newStateMocks(t)
+	defer cleanup()
+
+	state := mocks.newState(Config{
+		SuperSeedNamespaces: []string{"^cold-images/.*"},
+	})
+
+	seeded, err := state.addTorrent("cold-images/foo", mocks.newTorrent(), true)
+	require.NoError(err)
+	require.True(seeded.dispatcher.SuperSeeds())
+
+	other, err := state.addTorrent("warm-images/foo", mocks.newTorrent(), true)
+	require.NoError(err)
+	require.False(other.dispatcher.SuperSeeds())
+}
@@ -0,0 +1,254 @@
+// Copyright (c) 2016-2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/andres-erbsen/clock"
+	"github.com/uber-go/tally"
+	"github.com/uber/kraken/core"
+	"github.com/uber/kraken/lib/store"
+	"github.com/uber/kraken/origin/blobclient"
+	"github.com/uber/kraken/utils/log"
+)
+
+// OriginFallbackConfig configures downloading blobs directly from origin,
+// without p2p, while the tracker is unavailable.
+type OriginFallbackConfig struct {
+	Enabled bool `yaml:"enabled"`
+
+	// Threshold is how long tracker requests must fail, without any success
+	// in between, before downloads fall back to origin. Downloads keep trying
+	// the tracker first, so p2p resumes on the first success.
+	Threshold time.Duration `yaml:"threshold"`
+}
+
+func (c OriginFallbackConfig) applyDefaults() OriginFallbackConfig {
+	if c.Threshold == 0 {
+		c.Threshold = time.Minute
+	}
+	return c
+}
+
+// OriginFetcher downloads blobs from the origin cluster.
+type OriginFetcher interface {
+	DownloadBlob(namespace string, d core.Digest, dst io.Writer) error
+}
+
+// originFallback tracks tracker health and downloads blobs from origin while
+// the tracker is degraded. A nil *originFallback is valid, and never degraded.
+type originFallback struct {
+	cads     *store.CADownloadStore
+	origins  OriginFetcher
+	clk      clock.Clock
+	degraded tally.Gauge
+
+	mu           sync.Mutex
+	config       OriginFallbackConfig
+	failingSince time.Time // Zero if the last tracker request succeeded.
+
+	downloadsMu sync.Mutex
+	downloads   map[string]*fallbackDownload // In-flight downloads by name.
+}
+
+// fallbackDownload is an in-flight download from origin, shared by all
+// concurrent downloads of the same blob.
+type fallbackDownload struct {
+	done chan struct{}
+	size int64
+	err  error
+}
+
+func newOriginFallback(
+	config OriginFallbackConfig,
+	cads *store.CADownloadStore,
+	origins OriginFetcher,
+	clk clock.Clock,
+	stats tally.Scope) *originFallback {
+
+	f := &originFallback{
+		cads:      cads,
+		origins:   origins,
+		clk:       clk,
+		degraded:  stats.Gauge("tracker_degraded"),
+		config:    config.applyDefaults(),
+		downloads: make(map[string]*fallbackDownload),
+	}
+	f.degraded.Update(0)
+	return f
+}
+
+func (f *originFallback) setConfig(config OriginFallbackConfig) {
+	if f == nil {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.config = config.applyDefaults()
+}
+
+// trackerSuccess records a successful tracker request, leaving degraded mode.
+func (f *originFallback) trackerSuccess() {
+	if f == nil {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.failingSince = time.Time{}
+	f.degraded.Update(0)
+}
+
+// trackerFailure records a failed tracker request.
+func (f *originFallback) trackerFailure() {
+	if f == nil {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.failingSince.IsZero() {
+		f.failingSince = f.clk.Now()
+	}
+	f.updateGaugeLocked()
+}
+
+// isDegraded returns true if tracker requests have been failing for longer
+// than the threshold, and downloads should fall back to origin.
+func (f *originFallback) isDegraded() bool {
+	if f == nil {
+		return false
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.updateGaugeLocked()
+}
+
+func (f *originFallback) updateGaugeLocked() bool {
+	degraded := f.config.Enabled &&
+		!f.failingSince.IsZero() &&
+		f.clk.Now().Sub(f.failingSince) >= f.config.Threshold
+	if degraded {
+		f.degraded.Update(1)
+	} else {
+		f.degraded.Update(0)
+	}
+	return degraded
+}
+
+// download downloads d from origin into the cache, returning its size.
+// Concurrent downloads of d share a single transfer. Must not be called while
+// a p2p download of d is in progress; see state.fallBackToOrigin.
+func (f *originFallback) download(namespace string, d core.Digest) (int64, error) {
+	name := d.Hex()
+
+	f.downloadsMu.Lock()
+	dl, ok := f.downloads[name]
+	if !ok {
+		dl = &fallbackDownload{done: make(chan struct{})}
+		f.downloads[name] = dl
+	}
+	f.downloadsMu.Unlock()
+
+	if ok {
+		<-dl.done
+		return dl.size, dl.err
+	}
+
+	dl.size, dl.err = f.doDownload(namespace, d)
+
+	f.downloadsMu.Lock()
+	delete(f.downloads, name)
+	f.downloadsMu.Unlock()
+	close(dl.done)
+
+	return dl.size, dl.err
+}
+
+// size returns the size of d, once downloaded.
+func (f *originFallback) size(d core.Digest) (int64, error) {
+	info, err := f.cads.Cache().GetFileStat(d.Hex())
+	if err != nil {
+		return 0, fmt.Errorf("stat cache file: %s", err)
+	}
+	return info.Size(), nil
+}
+
+// doDownload downloads d from origin. Callers must ensure no p2p download of
+// d is in progress, since any existing download file of d is discarded.
+func (f *originFallback) doDownload(namespace string, d core.Digest) (int64, error) {
+	name := d.Hex()
+	if info, err := f.cads.Cache().GetFileStat(name); err == nil {
+		return info.Size(), nil
+	}
+	if err := f.cads.CreateDownloadFile(name, 0); err != nil {
+		if !os.IsExist(err) && !f.cads.InDownloadError(err) {
+			return 0, fmt.Errorf("create download file: %s", err)
+		}
+		// Left behind by a p2p download which is no longer running.
+		if err := f.cads.Download().DeleteFile(name); err != nil {
+			return 0, fmt.Errorf("delete stale download file: %s", err)
+		}
+		if err := f.cads.CreateDownloadFile(name, 0); err != nil {
+			return 0, fmt.Errorf("create download file: %s", err)
+		}
+	}
+	size, err := f.write(namespace, d)
+	if err != nil {
+		if err := f.cads.Download().DeleteFile(name); err != nil {
+			log.With("name", name).Errorf("Error deleting download file: %s", err)
+		}
+		if errors.Is(err, blobclient.ErrBlobNotFound) {
+			return 0, ErrTorrentNotFound
+		}
+		return 0, err
+	}
+	if err := f.cads.MoveDownloadFileToCache(name); err != nil {
+		return 0, fmt.Errorf("move download file to cache: %s", err)
+	}
+	return size, nil
+}
+
+// write writes d from origin into its download file and verifies its digest.
+func (f *originFallback) write(namespace string, d core.Digest) (int64, error) {
+	w, err := f.cads.GetDownloadFileReadWriter(d.Hex())
+	if err != nil {
+		return 0, fmt.Errorf("get download file: %s", err)
+	}
+	defer w.Close()
+
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(f.origins.DownloadBlob(namespace, d, pw))
+	}()
+	defer pr.Close()
+
+	digester := core.NewDigester()
+	size, err := io.Copy(w, digester.Tee(pr))
+	if err != nil {
+		return 0, fmt.Errorf("download from origin: %w", err)
+	}
+	if computed := digester.Digest(); computed != d {
+		return 0, fmt.Errorf("computed digest %s does not match %s", computed, d)
+	}
+	return size, nil
+}
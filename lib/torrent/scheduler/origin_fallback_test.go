@@ -0,0 +1,268 @@
+// Copyright (c) 2016-2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package scheduler
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/andres-erbsen/clock"
+	"github.com/uber-go/tally"
+	"github.com/uber/kraken/core"
+	"github.com/uber/kraken/lib/store"
+	"github.com/uber/kraken/origin/blobclient"
+
+	"github.com/stretchr/testify/require"
+)
+
+type fakeOriginFetcher struct {
+	blobs map[core.Digest][]byte
+}
+
+func (f fakeOriginFetcher) DownloadBlob(namespace string, d core.Digest, dst io.Writer) error {
+	b, ok := f.blobs[d]
+	if !ok {
+		return blobclient.ErrBlobNotFound
+	}
+	_, err := dst.Write(b)
+	return err
+}
+
+// blockingOriginFetcher counts downloads, and blocks each one until release
+// is closed.
+type blockingOriginFetcher struct {
+	fakeOriginFetcher
+	release chan struct{}
+	calls   atomic.Int32
+}
+
+func (f *blockingOriginFetcher) DownloadBlob(namespace string, d core.Digest, dst io.Writer) error {
+	f.calls.Add(1)
+	<-f.release
+	return f.fakeOriginFetcher.DownloadBlob(namespace, d, dst)
+}
+
+func TestOriginFallbackNil(t *testing.T) {
+	require := require.New(t)
+
+	var f *originFallback
+	f.trackerFailure()
+	f.trackerSuccess()
+	f.setConfig(OriginFallbackConfig{Enabled: true})
+	require.False(f.isDegraded())
+}
+
+func TestOriginFallbackDegradesAfterThreshold(t *testing.T) {
+	require := require.New(t)
+
+	clk := clock.NewMock()
+	clk.Set(time.Now())
+	stats := tally.NewTestScope("", nil)
+	f := newOriginFallback(
+		OriginFallbackConfig{Enabled: true, Threshold: time.Minute}, nil, nil, clk, stats)
+
+	f.trackerFailure()
+	require.False(f.isDegraded())
+
+	clk.Add(30 * time.Second)
+	f.trackerFailure()
+	require.False(f.isDegraded())
+
+	clk.Add(30 * time.Second)
+	require.True(f.isDegraded())
+	require.Equal(1.0, stats.Snapshot().Gauges()["tracker_degraded+"].Value())
+
+	f.trackerSuccess()
+	require.False(f.isDegraded())
+	require.Equal(0.0, stats.Snapshot().Gauges()["tracker_degraded+"].Value())
+}
+
+func TestOriginFallbackDisabledNeverDegrades(t *testing.T) {
+	require := require.New(t)
+
+	clk := clock.NewMock()
+	f := newOriginFallback(OriginFallbackConfig{}, nil, nil, clk, tally.NoopScope)
+
+	f.trackerFailure()
+	clk.Add(time.Hour)
+	require.False(f.isDegraded())
+
+	f.setConfig(OriginFallbackConfig{Enabled: true})
+	require.True(f.isDegraded())
+}
+
+func TestOriginFallbackDownload(t *testing.T) {
+	require := require.New(t)
+
+	cads, cleanup := store.CADownloadStoreFixture()
+	defer cleanup()
+
+	blob := core.NewBlobFixture()
+	origins := fakeOriginFetcher{map[core.Digest][]byte{blob.Digest: blob.Content}}
+	f := newOriginFallback(OriginFallbackConfig{Enabled: true}, cads, origins, clock.New(), tally.NoopScope)
+
+	size, err := f.download(core.TagFixture(), blob.Digest)
+	require.NoError(err)
+	require.Equal(int64(len(blob.Content)), size)
+
+	r, err := cads.Cache().GetFileReader(blob.Digest.Hex())
+	require.NoError(err)
+	defer r.Close()
+	result, err := io.ReadAll(r)
+	require.NoError(err)
+	require.Equal(blob.Content, result)
+
+	// Already cached blobs are not downloaded again.
+	size, err = f.download(core.TagFixture(), blob.Digest)
+	require.NoError(err)
+	require.Equal(int64(len(blob.Content)), size)
+}
+
+func TestOriginFallbackDownloadNotFound(t *testing.T) {
+	require := require.New(t)
+
+	cads, cleanup := store.CADownloadStoreFixture()
+	defer cleanup()
+
+	f := newOriginFallback(
+		OriginFallbackConfig{Enabled: true}, cads, fakeOriginFetcher{}, clock.New(), tally.NoopScope)
+
+	d := core.DigestFixture()
+	_, err := f.download(core.TagFixture(), d)
+	require.Equal(ErrTorrentNotFound, err)
+
+	_, err = cads.Download().GetFileStat(d.Hex())
+	require.Error(err)
+}
+
+func TestOriginFallbackDownloadDigestMismatch(t *testing.T) {
+	require := require.New(t)
+
+	cads, cleanup := store.CADownloadStoreFixture()
+	defer cleanup()
+
+	d := core.DigestFixture()
+	origins := fakeOriginFetcher{map[core.Digest][]byte{d: bytes.Repeat([]byte("a"), 16)}}
+	f := newOriginFallback(OriginFallbackConfig{Enabled: true}, cads, origins, clock.New(), tally.NoopScope)
+
+	_, err := f.download(core.TagFixture(), d)
+	require.Error(err)
+	require.Contains(err.Error(), "does not match")
+
+	_, err = cads.Cache().GetFileStat(d.Hex())
+	require.Error(err)
+	_, err = cads.Download().GetFileStat(d.Hex())
+	require.Error(err)
+}
+
+func TestOriginFallbackConcurrentDownloadsShareTransfer(t *testing.T) {
+	require := require.New(t)
+
+	cads, cleanup := store.CADownloadStoreFixture()
+	defer cleanup()
+
+	blob := core.NewBlobFixture()
+	origins := &blockingOriginFetcher{
+		fakeOriginFetcher: fakeOriginFetcher{map[core.Digest][]byte{blob.Digest: blob.Content}},
+		release:           make(chan struct{}),
+	}
+	f := newOriginFallback(OriginFallbackConfig{Enabled: true}, cads, origins, clock.New(), tally.NoopScope)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 5)
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			size, err := f.download(core.TagFixture(), blob.Digest)
+			if err == nil && size != int64(len(blob.Content)) {
+				err = fmt.Errorf("unexpected size %d", size)
+			}
+			errs <- err
+		}()
+	}
+	require.Eventually(func() bool { return origins.calls.Load() == 1 }, time.Second, time.Millisecond)
+	close(origins.release)
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		require.NoError(err)
+	}
+	require.Equal(int32(1), origins.calls.Load())
+}
+
+func TestOriginFallbackDownloadReplacesStaleDownloadFile(t *testing.T) {
+	require := require.New(t)
+
+	cads, cleanup := store.CADownloadStoreFixture()
+	defer cleanup()
+
+	blob := core.NewBlobFixture()
+
+	// Partial download file left by a p2p download which is no longer running.
+	require.NoError(cads.CreateDownloadFile(blob.Digest.Hex(), 2*int64(len(blob.Content))))
+
+	origins := fakeOriginFetcher{map[core.Digest][]byte{blob.Digest: blob.Content}}
+	f := newOriginFallback(OriginFallbackConfig{Enabled: true}, cads, origins, clock.New(), tally.NoopScope)
+
+	size, err := f.download(core.TagFixture(), blob.Digest)
+	require.NoError(err)
+	require.Equal(int64(len(blob.Content)), size)
+
+	r, err := cads.Cache().GetFileReader(blob.Digest.Hex())
+	require.NoError(err)
+	defer r.Close()
+	result, err := io.ReadAll(r)
+	require.NoError(err)
+	require.Equal(blob.Content, result)
+}
+
+func TestOriginFallbackHandsOffTorrentWhenAnnouncesFail(t *testing.T) {
+	require := require.New(t)
+
+	mocks, cleanup := newTestMocks(t)
+	defer cleanup()
+
+	// Nothing listens on the tracker, so every announce fails.
+	mocks.trackerAddr = fmt.Sprintf("localhost:%d", findFreePort())
+
+	config := configFixture()
+	peer := mocks.newPeer(config)
+
+	blob := core.NewBlobFixture()
+	namespace := core.TagFixture()
+
+	// Metainfo is available, so the torrent is created and goes p2p.
+	mocks.metaInfoClient.EXPECT().Download(namespace, blob.Digest).Return(blob.MetaInfo, nil)
+
+	origins := fakeOriginFetcher{map[core.Digest][]byte{blob.Digest: blob.Content}}
+	peer.scheduler.originFallback = newOriginFallback(
+		OriginFallbackConfig{Enabled: true, Threshold: time.Nanosecond},
+		peer.cads, origins, clock.New(), tally.NoopScope)
+
+	require.NoError(peer.scheduler.Download(namespace, blob.Digest))
+
+	r, err := peer.cads.Cache().GetFileReader(blob.Digest.Hex())
+	require.NoError(err)
+	defer r.Close()
+	result, err := io.ReadAll(r)
+	require.NoError(err)
+	require.Equal(blob.Content, result)
+}
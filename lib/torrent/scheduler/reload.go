@@ -59,10 +59,22 @@ func (rs *reloadableScheduler) reload(config Config) error {
 	if err != nil {
 		return fmt.Errorf("create new scheduler: %s", err)
 	}
+	n.allowlists = s.allowlists
+	n.unreachable = s.unreachable
+	n.unreachable.setTTL(n.config.UnreachablePeerTTL)
+	n.bandwidth = s.bandwidth
+	n.bandwidth.setTTL(n.config.BandwidthStatsTTL)
+	n.originFallback = s.originFallback
+	n.originFallback.setConfig(n.config.OriginFallback)
 	rs.scheduler = n
 
 	if err := rs.start(rs.aq()); err != nil {
 		return fmt.Errorf("start new scheduler: %s", err)
 	}
+	if s.paused.Load() {
+		if err := n.PauseAll(); err != nil {
+			return fmt.Errorf("pause new scheduler: %s", err)
+		}
+	}
 	return nil
 }
@@ -17,11 +17,13 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"regexp"
 	"sync"
 	"time"
 
 	"github.com/andres-erbsen/clock"
 	"github.com/uber-go/tally"
+	"go.uber.org/atomic"
 	"go.uber.org/zap"
 
 	"github.com/uber/kraken/core"
@@ -30,6 +32,7 @@ import (
 	"github.com/uber/kraken/lib/torrent/scheduler/announcer"
 	"github.com/uber/kraken/lib/torrent/scheduler/conn"
 	"github.com/uber/kraken/lib/torrent/scheduler/connstate"
+	"github.com/uber/kraken/lib/torrent/scheduler/dispatch"
 	"github.com/uber/kraken/lib/torrent/scheduler/torrentlog"
 	"github.com/uber/kraken/lib/torrent/storage"
 	"github.com/uber/kraken/tracker/announceclient"
@@ -50,9 +53,15 @@ var (
 type Scheduler interface {
 	Stop()
 	Download(namespace string, d core.Digest) error
+	DownloadWithAllowlist(namespace string, d core.Digest, allowlist *PeerAllowlist) error
 	BlacklistSnapshot() ([]connstate.BlacklistedConn, error)
+	TorrentBandwidth(namespace string, d core.Digest) (dispatch.BandwidthStats, error)
+	DumpTorrent(h core.InfoHash) (TorrentDump, error)
+	UnreachablePeers() []UnreachablePeer
 	RemoveTorrent(d core.Digest) error
 	Probe() error
+	PauseAll() error
+	ResumeAll() error
 }
 
 // scheduler manages global state for the peer. This includes:
@@ -75,8 +84,9 @@ type scheduler struct {
 
 	listener net.Listener
 
-	preemptionTick <-chan time.Time
-	emitStatsTick  <-chan time.Time
+	preemptionTick       <-chan time.Time
+	emitStatsTick        <-chan time.Time
+	bitfieldSnapshotTick <-chan time.Time
 
 	// TODO(codyg): We only need this hold on this reference for reloading the scheduler...
 	announceClient announceclient.Client
@@ -89,6 +99,22 @@ type scheduler struct {
 
 	logger *zap.SugaredLogger
 
+	// paused is only written from the event loop.
+	paused *atomic.Bool
+
+	allowlists *torrentAllowlists
+
+	bandwidth *torrentBandwidth
+
+	unreachable *unreachablePeers
+
+	// superSeedNamespaces matches namespaces whose torrents are super-seeded.
+	superSeedNamespaces []*regexp.Regexp
+
+	// originFallback is nil unless the scheduler was created with an
+	// OriginFetcher.
+	originFallback *originFallback
+
 	// The following fields orchestrate the stopping of the scheduler.
 	stopOnce sync.Once      // Ensures the stop sequence is executed only once.
 	done     chan struct{}  // Signals all goroutines to exit.
@@ -151,6 +177,11 @@ func newScheduler(
 		preemptionTick = overrides.clock.Tick(config.PreemptionInterval)
 	}
 
+	var bitfieldSnapshotTick <-chan time.Time
+	if config.BitfieldSnapshotInterval > 0 {
+		bitfieldSnapshotTick = overrides.clock.Tick(config.BitfieldSnapshotInterval)
+	}
+
 	handshaker, err := conn.NewHandshaker(
 		config.Conn, stats, overrides.clock, netevents, pctx.PeerID, eventLoop, slogger)
 	if err != nil {
@@ -162,22 +193,37 @@ func newScheduler(
 		return nil, fmt.Errorf("torrentlog: %s", err)
 	}
 
+	var superSeedNamespaces []*regexp.Regexp
+	for _, ns := range config.SuperSeedNamespaces {
+		re, err := regexp.Compile(ns)
+		if err != nil {
+			return nil, fmt.Errorf("regexp compile super seed namespace %s: %s", ns, err)
+		}
+		superSeedNamespaces = append(superSeedNamespaces, re)
+	}
+
 	s := &scheduler{
-		pctx:           pctx,
-		config:         config,
-		clock:          overrides.clock,
-		torrentArchive: ta,
-		stats:          stats,
-		handshaker:     handshaker,
-		eventLoop:      eventLoop,
-		preemptionTick: preemptionTick,
-		emitStatsTick:  overrides.clock.Tick(config.EmitStatsInterval),
-		announceClient: announceClient,
-		announcer:      announcer.Default(announceClient, eventLoop, overrides.clock, slogger),
-		netevents:      netevents,
-		torrentlog:     tlog,
-		logger:         slogger,
-		done:           done,
+		pctx:                 pctx,
+		config:               config,
+		clock:                overrides.clock,
+		torrentArchive:       ta,
+		stats:                stats,
+		handshaker:           handshaker,
+		eventLoop:            eventLoop,
+		preemptionTick:       preemptionTick,
+		emitStatsTick:        overrides.clock.Tick(config.EmitStatsInterval),
+		bitfieldSnapshotTick: bitfieldSnapshotTick,
+		announceClient:       announceClient,
+		announcer:            announcer.Default(announceClient, eventLoop, overrides.clock, slogger),
+		netevents:            netevents,
+		torrentlog:           tlog,
+		logger:               slogger,
+		paused:               atomic.NewBool(false),
+		allowlists:           newTorrentAllowlists(ta),
+		bandwidth:            newTorrentBandwidth(overrides.clock, config.BandwidthStatsTTL),
+		unreachable:          newUnreachablePeers(overrides.clock, config.UnreachablePeerTTL),
+		superSeedNamespaces:  superSeedNamespaces,
+		done:                 done,
 	}
 
 	if config.DisablePreemption {
@@ -233,28 +279,64 @@ func (s *scheduler) Stop() {
 }
 
 // doDownload schedules a blob for download, returning only once it's downloaded.
-func (s *scheduler) doDownload(namespace string, d core.Digest) (size int64, err error) {
+func (s *scheduler) doDownload(
+	namespace string, d core.Digest, allowlist *PeerAllowlist) (size int64, err error) {
+
 	t, err := s.torrentArchive.CreateTorrent(namespace, d)
 	if err != nil {
 		if err == storage.ErrNotFound {
+			s.originFallback.trackerSuccess()
 			return 0, ErrTorrentNotFound
 		}
+		s.originFallback.trackerFailure()
+		if s.originFallback.isDegraded() {
+			s.log("namespace", namespace, "digest", d).Warnf(
+				"Tracker degraded, downloading from origin: %s", err)
+			return s.downloadFromOrigin(namespace, d)
+		}
 		return 0, fmt.Errorf("create torrent: %s", err)
 	}
+	// Note, tracker success is not recorded here, since the metainfo may have
+	// been read from disk. Announces of the new torrent report tracker health,
+	// and fall back to origin if the tracker stays degraded.
 
 	// Buffer size of 1 so sends do not block.
 	errc := make(chan error, 1)
-	if !s.eventLoop.send(newTorrentEvent{namespace, t, errc}) {
+	if !s.eventLoop.send(newTorrentEvent{namespace, t, allowlist, errc}) {
 		return 0, ErrSchedulerStopped
 	}
 	return t.Length(), <-errc
 }
 
+// downloadFromOrigin downloads d from origin via the event loop, which first
+// hands off any p2p download of d in progress.
+func (s *scheduler) downloadFromOrigin(namespace string, d core.Digest) (int64, error) {
+	// Buffer size of 1 so sends do not block.
+	errc := make(chan error, 1)
+	if !s.eventLoop.send(originFallbackEvent{namespace, d, errc}) {
+		return 0, ErrSchedulerStopped
+	}
+	if err := <-errc; err != nil {
+		return 0, err
+	}
+	return s.originFallback.size(d)
+}
+
 // Download downloads the torrent given metainfo. Once the torrent is downloaded,
 // it will begin seeding asynchronously.
 func (s *scheduler) Download(namespace string, d core.Digest) error {
+	return s.DownloadWithAllowlist(namespace, d, nil)
+}
+
+// DownloadWithAllowlist downloads the torrent like Download, but only connects
+// to and serves peers allowed by allowlist. The allowlist replaces any previous
+// allowlist of the torrent. A nil allowlist keeps the existing allowlist, if
+// any, such that a restricted torrent cannot be opened up by plain downloads.
+func (s *scheduler) DownloadWithAllowlist(
+	namespace string, d core.Digest, allowlist *PeerAllowlist) error {
+
 	start := time.Now()
-	size, err := s.doDownload(namespace, d)
+	size, err := s.doDownload(namespace, d, allowlist)
 	if err != nil {
 		var errTag string
 		switch err {
@@ -290,6 +372,39 @@ func (s *scheduler) BlacklistSnapshot() ([]connstate.BlacklistedConn, error) {
 	return <-result, nil
 }
 
+// TorrentBandwidth returns the monotonic bytes uploaded and downloaded for
+// the torrent of d in namespace. Totals carry over when an idle torrent is
+// removed and re-added within BandwidthStatsTTL, and are reset when d is
+// removed via RemoveTorrent. Returns ErrTorrentNotFound if d has not been
+// scheduled in namespace since the last reset.
+func (s *scheduler) TorrentBandwidth(namespace string, d core.Digest) (dispatch.BandwidthStats, error) {
+	stats, ok := s.bandwidth.get(namespace, d)
+	if !ok {
+		return dispatch.BandwidthStats{}, ErrTorrentNotFound
+	}
+	return stats, nil
+}
+
+// UnreachablePeers returns the announced peers which the scheduler recently
+// failed to connect to, with failures bucketed by reason. Failures expire
+// after UnreachablePeerTTL, or once a conn to the peer succeeds.
+func (s *scheduler) UnreachablePeers() []UnreachablePeer {
+	return s.unreachable.snapshot()
+}
+
+// DumpTorrent returns a diagnostic snapshot of the torrent for h: its missing
+// pieces, outstanding piece requests, peers and connections. The torrent is
+// not modified. Returns ErrTorrentNotFound if h is not scheduled.
+func (s *scheduler) DumpTorrent(h core.InfoHash) (TorrentDump, error) {
+	// Buffer size of 1 so sends do not block.
+	result := make(chan torrentDumpResult, 1)
+	if !s.eventLoop.send(torrentDumpEvent{h, result}) {
+		return TorrentDump{}, ErrSchedulerStopped
+	}
+	r := <-result
+	return r.dump, r.err
+}
+
 // RemoveTorrent forcibly stops leeching / seeding torrent for d and removes
 // the torrent from disk.
 func (s *scheduler) RemoveTorrent(d core.Digest) error {
@@ -306,6 +421,28 @@ func (s *scheduler) Probe() error {
 	return s.eventLoop.sendTimeout(probeEvent{}, s.config.ProbeTimeout)
 }
 
+// PauseAll stops requesting and serving pieces for all torrents, including
+// torrents added while paused, until ResumeAll is called. Connections are kept
+// open, and idle torrents and connections are not removed while paused.
+func (s *scheduler) PauseAll() error {
+	// Buffer size of 1 so sends do not block.
+	errc := make(chan error, 1)
+	if !s.eventLoop.send(pauseAllEvent{errc}) {
+		return ErrSchedulerStopped
+	}
+	return <-errc
+}
+
+// ResumeAll resumes all torrents paused by PauseAll.
+func (s *scheduler) ResumeAll() error {
+	// Buffer size of 1 so sends do not block.
+	errc := make(chan error, 1)
+	if !s.eventLoop.send(resumeAllEvent{errc}) {
+		return ErrSchedulerStopped
+	}
+	return <-errc
+}
+
 func (s *scheduler) runEventLoop(aq announcequeue.Queue) {
 	defer s.wg.Done()
 
@@ -346,6 +483,8 @@ func (s *scheduler) tickerLoop() {
 			s.eventLoop.send(preemptionTickEvent{})
 		case <-s.emitStatsTick:
 			s.eventLoop.send(emitStatsEvent{})
+		case <-s.bitfieldSnapshotTick:
+			s.eventLoop.send(bitfieldSnapshotTickEvent{})
 		case <-s.done:
 			return
 		}
@@ -363,10 +502,12 @@ func (s *scheduler) announce(d core.Digest, h core.InfoHash, complete bool) {
 	peers, err := s.announcer.Announce(d, h, complete)
 	if err != nil {
 		if err != announceclient.ErrDisabled {
+			s.originFallback.trackerFailure()
 			s.eventLoop.send(announceErrEvent{h, err})
 		}
 		return
 	}
+	s.originFallback.trackerSuccess()
 	s.eventLoop.send(announceResultEvent{h, peers})
 }
 
@@ -407,14 +548,36 @@ func (s *scheduler) initializeOutgoingHandshake(
 			"peer", p.PeerID,
 			"hash", info.InfoHash(),
 			"addr", addr).Infof("Error initializing outgoing handshake: %s", err)
+		s.unreachable.record(p.PeerID, addr, err)
 		s.eventLoop.send(failedOutgoingHandshakeEvent{p.PeerID, info.InfoHash()})
 		s.torrentlog.OutgoingConnectionReject(info.Digest(), info.InfoHash(), p.PeerID, err)
 		return
 	}
+	s.unreachable.clear(p.PeerID)
 	s.torrentlog.OutgoingConnectionAccept(info.Digest(), info.InfoHash(), p.PeerID)
 	s.eventLoop.send(outgoingConnEvent{result.Conn, result.Bitfield, info})
 }
 
+// snapshotBitfields persists the bitfields of the given in-progress torrents.
+func (s *scheduler) snapshotBitfields(dispatchers []*dispatch.Dispatcher) {
+	for _, d := range dispatchers {
+		if err := d.SnapshotBitfield(); err != nil {
+			s.log("dispatcher", d).Errorf("Error snapshotting bitfield: %s", err)
+			s.stats.Counter("bitfield_snapshot_errors").Inc(1)
+		}
+	}
+}
+
+// superSeeds returns true if torrents in namespace should be super-seeded.
+func (s *scheduler) superSeeds(namespace string) bool {
+	for _, re := range s.superSeedNamespaces {
+		if re.MatchString(namespace) {
+			return true
+		}
+	}
+	return false
+}
+
 func (s *scheduler) log(args ...interface{}) *zap.SugaredLogger {
 	return s.logger.With(args...)
 }
@@ -348,6 +348,10 @@ func TestNetworkEvents(t *testing.T) {
 		networkevent.RequestPieceEvent(h, lid, sid, 0),
 		networkevent.ReceivePieceEvent(h, lid, sid, 0),
 		networkevent.TorrentCompleteEvent(h, lid),
+		networkevent.TorrentCompleteTimingEvent(h, lid, networkevent.CompletionTiming{
+			PeerBytes: 1,
+			PeakPeers: 1,
+		}),
 		networkevent.DropActiveConnEvent(h, lid, sid),
 		networkevent.BlacklistConnEvent(h, lid, sid, config.ConnState.BlacklistDuration),
 	}
@@ -356,9 +360,17 @@ func TestNetworkEvents(t *testing.T) {
 		networkevent.StripTimestamps(seederExpected),
 		networkevent.StripTimestamps(seeder.testProducer.Events()))
 
+	leecherEvents := leecher.testProducer.Events()
+	for _, e := range leecherEvents {
+		if e.Name == networkevent.TorrentCompleteTiming {
+			require.True(e.CompleteMS >= e.FirstPieceMS)
+			e.FirstPieceMS, e.CompleteMS = 0, 0
+		}
+	}
+
 	require.Equal(
 		networkevent.StripTimestamps(leecherExpected),
-		networkevent.StripTimestamps(leecher.testProducer.Events()))
+		networkevent.StripTimestamps(leecherEvents))
 }
 
 func TestPullInactiveTorrent(t *testing.T) {
@@ -501,3 +513,46 @@ func TestSchedulerProbeTimeoutsIfDeadlocked(t *testing.T) {
 
 	close(release)
 }
+
+func TestSchedulerPauseAllAndResumeAll(t *testing.T) {
+	require := require.New(t)
+
+	mocks, cleanup := newTestMocks(t)
+	defer cleanup()
+
+	config := configFixture()
+
+	seeder := mocks.newPeer(config)
+	leecher := mocks.newPeer(config)
+
+	blob := core.NewBlobFixture()
+	namespace := core.TagFixture()
+
+	mocks.metaInfoClient.EXPECT().Download(
+		namespace, blob.Digest).Return(blob.MetaInfo, nil).Times(2)
+
+	seeder.writeTorrent(namespace, blob)
+	require.NoError(seeder.scheduler.Download(namespace, blob.Digest))
+
+	require.NoError(leecher.scheduler.PauseAll())
+	require.Equal(
+		float64(1),
+		leecher.stats.Snapshot().Gauges()["paused+module=scheduler"].Value())
+
+	errc := make(chan error)
+	go func() { errc <- leecher.scheduler.Download(namespace, blob.Digest) }()
+
+	select {
+	case err := <-errc:
+		t.Fatalf("Download of paused torrent returned: %v", err)
+	case <-time.After(500 * time.Millisecond):
+	}
+
+	require.NoError(leecher.scheduler.ResumeAll())
+	require.Equal(
+		float64(0),
+		leecher.stats.Snapshot().Gauges()["paused+module=scheduler"].Value())
+
+	require.NoError(<-errc)
+	leecher.checkTorrent(t, namespace, blob)
+}
@@ -0,0 +1,161 @@
+// Copyright (c) 2016-2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package scheduler
+
+import (
+	"time"
+
+	"github.com/uber/kraken/lib/torrent/scheduler/dispatch"
+)
+
+// torrentProgress tracks the download rate of a torrent, for detecting
+// torrents starved by others under contention.
+type torrentProgress struct {
+	// bytes is the payload bytes downloaded as of lastCheck.
+	bytes     int64
+	lastCheck time.Time
+
+	// rate is the download rate in bytes per second between the last two
+	// checks.
+	rate float64
+
+	// lastAdvanced is the last check at which bytes advanced.
+	lastAdvanced time.Time
+
+	// stalledSince is when the torrent last either advanced or became able
+	// to advance, i.e. it was incomplete, unpaused and had peers.
+	stalledSince time.Time
+
+	starved bool
+}
+
+func newTorrentProgress(now time.Time) torrentProgress {
+	return torrentProgress{lastCheck: now, stalledSince: now}
+}
+
+// update records that bytes were downloaded as of now. eligible is whether
+// the torrent can progress, see canProgress. Returns true if the torrent was
+// starved but no longer is.
+func (p *torrentProgress) update(now time.Time, bytes int64, eligible bool) (recovered bool) {
+	if elapsed := now.Sub(p.lastCheck); elapsed > 0 {
+		p.rate = float64(bytes-p.bytes) / elapsed.Seconds()
+	}
+	advanced := bytes > p.bytes
+	p.bytes = bytes
+	p.lastCheck = now
+	if advanced {
+		p.lastAdvanced = now
+	}
+	if !eligible || advanced {
+		p.stalledSince = now
+		recovered = p.starved
+		p.starved = false
+	}
+	return recovered
+}
+
+// detectStarvation flags the torrents of progresses, which must all be
+// eligible to progress, which have been stalled for window while another
+// torrent advanced within window. If every torrent is stalled, the cause is
+// likely a network or origin issue rather than unfair scheduling, so none are
+// flagged. Returns the torrents which are starved, and which of them are newly
+// starved.
+func detectStarvation(
+	progresses []*torrentProgress, now time.Time, window time.Duration) (starved, newly []int) {
+
+	var advancing bool
+	var stalled []int
+	for i, p := range progresses {
+		if !p.lastAdvanced.IsZero() && now.Sub(p.lastAdvanced) < window {
+			advancing = true
+		} else if now.Sub(p.stalledSince) >= window {
+			stalled = append(stalled, i)
+		}
+	}
+	if !advancing {
+		for _, i := range stalled {
+			progresses[i].starved = false
+		}
+		return nil, nil
+	}
+	for _, i := range stalled {
+		if !progresses[i].starved {
+			progresses[i].starved = true
+			newly = append(newly, i)
+		}
+	}
+	return stalled, newly
+}
+
+// canProgress returns true if d is expected to make download progress, given
+// enough bandwidth. Complete and paused torrents, and torrents without
+// peers, are never considered starved.
+func canProgress(d *dispatch.Dispatcher) bool {
+	return !d.Complete() && !d.Paused() && !d.PausedForDisk() && !d.Empty()
+}
+
+// checkProgress updates the download rate of each torrent, and flags torrents
+// which made no progress for StarvationWindow while other torrents advanced.
+// Emits the fairness of download rates among torrents which can progress as
+// Jain's index, from 1/n (one torrent gets all bandwidth) to 1 (equal rates).
+func (s *state) checkProgress() {
+	now := s.sched.clock.Now()
+
+	var ctrls []*torrentControl
+	var progresses []*torrentProgress
+	var rates []float64
+	for _, ctrl := range s.torrentControls {
+		eligible := canProgress(ctrl.dispatcher)
+		bytes := ctrl.dispatcher.BandwidthStats().BytesDownloaded
+		if ctrl.progress.update(now, bytes, eligible) {
+			s.log("hash", ctrl.dispatcher.InfoHash()).Info("Torrent no longer starved")
+		}
+		if eligible {
+			ctrls = append(ctrls, ctrl)
+			progresses = append(progresses, &ctrl.progress)
+			rates = append(rates, ctrl.progress.rate)
+		}
+	}
+	if len(rates) > 0 {
+		s.sched.stats.Gauge("download_rate_fairness").Update(jainIndex(rates))
+	}
+
+	window := s.sched.config.StarvationWindow
+	if window <= 0 {
+		return
+	}
+	starved, newly := detectStarvation(progresses, now, window)
+	for _, i := range newly {
+		s.sched.stats.Counter("torrent_starvations").Inc(1)
+		s.log(
+			"namespace", ctrls[i].namespace,
+			"hash", ctrls[i].dispatcher.InfoHash(),
+			"stalled", now.Sub(progresses[i].stalledSince)).Warn("Torrent starved while others advance")
+	}
+	s.sched.stats.Gauge("starved_torrents").Update(float64(len(starved)))
+}
+
+// jainIndex returns Jain's fairness index of rates. Returns 1 if all rates are
+// zero.
+func jainIndex(rates []float64) float64 {
+	var sum, sumSquares float64
+	for _, r := range rates {
+		sum += r
+		sumSquares += r * r
+	}
+	if sumSquares == 0 {
+		return 1
+	}
+	return sum * sum / (float64(len(rates)) * sumSquares)
+}
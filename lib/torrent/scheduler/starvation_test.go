@@ -0,0 +1,111 @@
+// Copyright (c) 2016-2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTorrentProgressRate(t *testing.T) {
+	require := require.New(t)
+
+	start := time.Now()
+	p := newTorrentProgress(start)
+
+	p.update(start.Add(2*time.Second), 100, true)
+	require.Equal(50.0, p.rate)
+	require.Equal(start.Add(2*time.Second), p.lastAdvanced)
+
+	p.update(start.Add(3*time.Second), 100, true)
+	require.Equal(0.0, p.rate)
+	require.Equal(start.Add(2*time.Second), p.stalledSince)
+}
+
+func TestDetectStarvation(t *testing.T) {
+	require := require.New(t)
+
+	window := time.Minute
+	now := time.Now()
+	fast := newTorrentProgress(now)
+	slow := newTorrentProgress(now)
+
+	for i := 1; i <= 61; i++ {
+		now = now.Add(time.Second)
+		fast.update(now, int64(i*100), true)
+		slow.update(now, 0, true)
+	}
+	starved, newly := detectStarvation([]*torrentProgress{&fast, &slow}, now, window)
+	require.Equal([]int{1}, starved)
+	require.Equal([]int{1}, newly)
+	require.True(slow.starved)
+
+	// Already flagged torrents are not newly starved.
+	starved, newly = detectStarvation([]*torrentProgress{&fast, &slow}, now, window)
+	require.Equal([]int{1}, starved)
+	require.Empty(newly)
+
+	// Progress clears the flag.
+	now = now.Add(time.Second)
+	require.True(slow.update(now, 10, true))
+	require.False(slow.starved)
+}
+
+func TestDetectStarvationIgnoresAllStalled(t *testing.T) {
+	require := require.New(t)
+
+	now := time.Now()
+	a := newTorrentProgress(now)
+	b := newTorrentProgress(now)
+
+	now = now.Add(2 * time.Minute)
+	a.update(now, 0, true)
+	b.update(now, 0, true)
+
+	starved, _ := detectStarvation([]*torrentProgress{&a, &b}, now, time.Minute)
+	require.Empty(starved)
+}
+
+func TestTorrentProgressIneligibleResetsStall(t *testing.T) {
+	require := require.New(t)
+
+	window := time.Minute
+	now := time.Now()
+	fast := newTorrentProgress(now)
+	idle := newTorrentProgress(now)
+
+	// idle had no peers for longer than the window, e.g. before announcing.
+	for i := 1; i <= 61; i++ {
+		now = now.Add(time.Second)
+		fast.update(now, int64(i*100), true)
+		idle.update(now, 0, false)
+	}
+	// Once peers are found, it is not immediately flagged.
+	now = now.Add(time.Second)
+	fast.update(now, 10000, true)
+	idle.update(now, 0, true)
+
+	starved, _ := detectStarvation([]*torrentProgress{&fast, &idle}, now, window)
+	require.Empty(starved)
+}
+
+func TestJainIndex(t *testing.T) {
+	require := require.New(t)
+
+	require.Equal(1.0, jainIndex([]float64{10, 10, 10}))
+	require.Equal(0.25, jainIndex([]float64{10, 0, 0, 0}))
+	require.Equal(1.0, jainIndex([]float64{0, 0}))
+}
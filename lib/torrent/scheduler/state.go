@@ -35,6 +35,7 @@ type torrentControl struct {
 	dispatcher   *dispatch.Dispatcher
 	errors       []chan error
 	localRequest bool
+	progress     torrentProgress
 }
 
 // state is a superset of scheduler, which includes protected state which can
@@ -52,6 +53,10 @@ type state struct {
 	torrentControls map[core.InfoHash]*torrentControl
 	conns           *connstate.State
 	announceQueue   announcequeue.Queue
+
+	// Peers which announce responses identified as origins. Used to attribute
+	// downloaded bytes to origins versus peers.
+	originPeers map[core.PeerID]bool
 }
 
 func newState(s *scheduler, aq announcequeue.Queue) *state {
@@ -61,6 +66,7 @@ func newState(s *scheduler, aq announcequeue.Queue) *state {
 		conns: connstate.New(
 			s.config.ConnState, s.clock, s.pctx.PeerID, s.netevents, s.logger),
 		announceQueue: aq,
+		originPeers:   make(map[core.PeerID]bool),
 	}
 }
 
@@ -69,8 +75,12 @@ func newState(s *scheduler, aq announcequeue.Queue) *state {
 func (s *state) addTorrent(
 	namespace string, t storage.Torrent, localRequest bool) (*torrentControl, error) {
 
+	dconfig := s.sched.config.Dispatch
+	if s.sched.superSeeds(namespace) {
+		dconfig.SuperSeed = true
+	}
 	d, err := dispatch.New(
-		s.sched.config.Dispatch,
+		dconfig,
 		s.sched.stats,
 		s.sched.clock,
 		s.sched.netevents,
@@ -82,10 +92,14 @@ func (s *state) addTorrent(
 	if err != nil {
 		return nil, fmt.Errorf("new dispatcher: %s", err)
 	}
+	if s.sched.paused.Load() {
+		d.Pause()
+	}
 	ctrl := &torrentControl{
 		namespace:    namespace,
 		dispatcher:   d,
 		localRequest: localRequest,
+		progress:     newTorrentProgress(s.sched.clock.Now()),
 	}
 	s.announceQueue.Add(t.InfoHash())
 	s.sched.netevents.Produce(networkevent.AddTorrentEvent(
@@ -94,6 +108,7 @@ func (s *state) addTorrent(
 		t.Bitfield(),
 		s.sched.config.ConnState.MaxOpenConnectionsPerTorrent))
 	s.torrentControls[t.InfoHash()] = ctrl
+	s.sched.bandwidth.track(namespace, d)
 	return ctrl, nil
 }
 
@@ -115,9 +130,44 @@ func (s *state) removeTorrent(h core.InfoHash, err error) {
 			s.sched.log().Errorf("Error deleting torrent from archive: %s", err)
 		}
 	}
+	s.sched.bandwidth.untrack(ctrl.namespace, ctrl.dispatcher)
 	delete(s.torrentControls, h)
 }
 
+// fallBackToOrigin tears down the in-progress torrent h and downloads its blob
+// from origin instead. All clients waiting on h, plus errc if non-nil, receive
+// the result of the origin download.
+func (s *state) fallBackToOrigin(h core.InfoHash, errc chan error) {
+	ctrl, ok := s.torrentControls[h]
+	if !ok {
+		return
+	}
+	waiters := ctrl.errors
+	if errc != nil {
+		waiters = append(waiters, errc)
+	}
+	// Clients are notified once origin finishes, not with the removal.
+	ctrl.errors = nil
+	s.removeTorrent(h, nil)
+
+	namespace, d := ctrl.namespace, ctrl.dispatcher.Digest()
+	go func() {
+		_, err := s.sched.originFallback.download(namespace, d)
+		for _, errc := range waiters {
+			errc <- err
+		}
+	}()
+}
+
+// emitPausedGauge reports whether the scheduler is paused.
+func (s *state) emitPausedGauge() {
+	var v float64
+	if s.sched.paused.Load() {
+		v = 1
+	}
+	s.sched.stats.Gauge("paused").Update(v)
+}
+
 // addOutgoingConn adds a conn, initialized by us, to state. The conn must already
 // be in a pending state, and the torrent control must already be initialized.
 func (s *state) addOutgoingConn(c *conn.Conn, b *bitset.BitSet, info *storage.TorrentInfo) error {
@@ -125,6 +175,10 @@ func (s *state) addOutgoingConn(c *conn.Conn, b *bitset.BitSet, info *storage.To
 		return fmt.Errorf("move pending to active: %s", err)
 	}
 	c.Start()
+	// The allowlist may have changed while the conn was pending.
+	if !s.sched.allowlists.allows(info.Digest(), c.PeerID(), addrIP(c.RemoteAddr())) {
+		return errPeerNotAllowed
+	}
 	ctrl, ok := s.torrentControls[info.InfoHash()]
 	if !ok {
 		return errors.New("torrent controls must be created before sending handshake")
@@ -145,6 +199,9 @@ func (s *state) addIncomingConn(
 		return fmt.Errorf("move pending to active: %s", err)
 	}
 	c.Start()
+	if !s.sched.allowlists.allows(info.Digest(), c.PeerID(), addrIP(c.RemoteAddr())) {
+		return errPeerNotAllowed
+	}
 	ctrl, ok := s.torrentControls[info.InfoHash()]
 	if !ok {
 		t, err := s.sched.torrentArchive.GetTorrent(namespace, info.Digest())
@@ -162,6 +219,44 @@ func (s *state) addIncomingConn(
 	return nil
 }
 
+// closeDisallowedConns closes active conns of the torrent for d which are not
+// allowed by its allowlist.
+func (s *state) closeDisallowedConns(d core.Digest, h core.InfoHash) {
+	for _, c := range s.conns.ActiveConns() {
+		if c.InfoHash() != h {
+			continue
+		}
+		if !s.sched.allowlists.allows(d, c.PeerID(), addrIP(c.RemoteAddr())) {
+			s.log("conn", c).Info("Closing conn not in torrent allowlist")
+			c.Close()
+		}
+	}
+}
+
+// completionTiming converts dispatcher completion stats into a timing
+// breakdown, attributing received bytes to origins or peers. Returns false if
+// no pieces were downloaded.
+func (s *state) completionTiming(
+	stats dispatch.CompletionStats) (networkevent.CompletionTiming, bool) {
+
+	if stats.FirstPieceAt.IsZero() || stats.CompletedAt.IsZero() {
+		return networkevent.CompletionTiming{}, false
+	}
+	t := networkevent.CompletionTiming{
+		FirstPiece: stats.FirstPieceAt.Sub(stats.CreatedAt),
+		Complete:   stats.CompletedAt.Sub(stats.CreatedAt),
+		PeakPeers:  stats.PeakPeers,
+	}
+	for peerID, n := range stats.BytesReceived {
+		if s.originPeers[peerID] {
+			t.OriginBytes += n
+		} else {
+			t.PeerBytes += n
+		}
+	}
+	return t, true
+}
+
 func (s *state) log(args ...interface{}) *zap.SugaredLogger {
 	return s.sched.log(args...)
 }
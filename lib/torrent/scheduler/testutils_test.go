@@ -100,7 +100,7 @@ func (m *testMocks) newPeer(config Config, options ...option) *testPeer {
 
 	stats := tally.NewTestScope("", nil)
 
-	ta := agentstorage.NewTorrentArchive(stats, cads, m.metaInfoClient)
+	ta := agentstorage.NewTorrentArchive(agentstorage.Config{}, stats, cads, m.metaInfoClient)
 
 	pctx := core.PeerContext{
 		PeerID: core.PeerIDFixture(),
@@ -193,6 +193,7 @@ type SeederSummary struct {
 	RequestsSent            int
 	GoodPiecesReceived      int
 	DuplicatePiecesReceived int
+	InvalidPiecesReceived   int
 }
 
 // MarshalLogObject marshals a SeederSummary for logging.
@@ -201,6 +202,7 @@ func (s SeederSummary) MarshalLogObject(enc zapcore.ObjectEncoder) error {
 	enc.AddInt("requests_sent", s.RequestsSent)
 	enc.AddInt("good_pieces_received", s.GoodPiecesReceived)
 	enc.AddInt("duplicate_pieces_received", s.DuplicatePiecesReceived)
+	enc.AddInt("invalid_pieces_received", s.InvalidPiecesReceived)
 	return nil
 }
 
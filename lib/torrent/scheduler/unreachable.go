@@ -0,0 +1,150 @@
+// Copyright (c) 2016-2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package scheduler
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/andres-erbsen/clock"
+	"github.com/uber/kraken/core"
+	"github.com/uber/kraken/lib/torrent/scheduler/conn"
+)
+
+// UnreachablePeer reports recent failures to initialize outgoing conns to a
+// peer announced by the tracker, bucketed by conn failure reason.
+type UnreachablePeer struct {
+	PeerID      core.PeerID    `json:"peer_id"`
+	Addr        string         `json:"addr"`
+	Failures    map[string]int `json:"failures"`
+	LastFailure time.Time      `json:"last_failure"`
+	LastError   string         `json:"last_error"`
+}
+
+// unreachablePeers records outgoing conn failures per peer. Failures older
+// than ttl are aged out, and a successful conn to a peer clears its failures.
+type unreachablePeers struct {
+	clk clock.Clock
+	ttl time.Duration
+
+	mu sync.Mutex
+	m  map[core.PeerID]*unreachableEntry
+}
+
+type unreachableEntry struct {
+	addr      string
+	lastError string
+
+	// failures holds the failure times of each reason, oldest first.
+	failures map[string][]time.Time
+}
+
+func newUnreachablePeers(clk clock.Clock, ttl time.Duration) *unreachablePeers {
+	return &unreachablePeers{
+		clk: clk,
+		ttl: ttl,
+		m:   make(map[core.PeerID]*unreachableEntry),
+	}
+}
+
+// record records err as a failure to connect to peerID at addr. Errors which
+// are not conn.InitializeErrors are bucketed as handshake failures.
+func (u *unreachablePeers) record(peerID core.PeerID, addr string, err error) {
+	reason := conn.FailureHandshake
+	var initErr *conn.InitializeError
+	if errors.As(err, &initErr) {
+		reason = initErr.Reason
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	now := u.clk.Now()
+	u.pruneLocked(now)
+
+	e, ok := u.m[peerID]
+	if !ok {
+		e = &unreachableEntry{failures: make(map[string][]time.Time)}
+		u.m[peerID] = e
+	}
+	e.addr = addr
+	e.lastError = err.Error()
+	e.failures[reason] = append(e.failures[reason], now)
+}
+
+// setTTL changes the age after which failures expire.
+func (u *unreachablePeers) setTTL(ttl time.Duration) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	u.ttl = ttl
+}
+
+// clear drops the failures of peerID.
+func (u *unreachablePeers) clear(peerID core.PeerID) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	delete(u.m, peerID)
+}
+
+// snapshot returns the peers with unexpired failures, ordered by peer id.
+func (u *unreachablePeers) snapshot() []UnreachablePeer {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	u.pruneLocked(u.clk.Now())
+
+	peers := make([]UnreachablePeer, 0, len(u.m))
+	for peerID, e := range u.m {
+		p := UnreachablePeer{
+			PeerID:    peerID,
+			Addr:      e.addr,
+			Failures:  make(map[string]int),
+			LastError: e.lastError,
+		}
+		for reason, times := range e.failures {
+			p.Failures[reason] = len(times)
+			if last := times[len(times)-1]; last.After(p.LastFailure) {
+				p.LastFailure = last
+			}
+		}
+		peers = append(peers, p)
+	}
+	sort.Slice(peers, func(i, j int) bool {
+		return peers[i].PeerID.LessThan(peers[j].PeerID)
+	})
+	return peers
+}
+
+// pruneLocked drops failures which occurred ttl before now, and peers left
+// without failures.
+func (u *unreachablePeers) pruneLocked(now time.Time) {
+	cutoff := now.Add(-u.ttl)
+	for peerID, e := range u.m {
+		for reason, times := range e.failures {
+			i := sort.Search(len(times), func(i int) bool { return times[i].After(cutoff) })
+			if i == len(times) {
+				delete(e.failures, reason)
+			} else {
+				e.failures[reason] = times[i:]
+			}
+		}
+		if len(e.failures) == 0 {
+			delete(u.m, peerID)
+		}
+	}
+}
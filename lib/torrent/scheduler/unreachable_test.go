@@ -0,0 +1,93 @@
+// Copyright (c) 2016-2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package scheduler
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/andres-erbsen/clock"
+	"github.com/uber/kraken/core"
+	"github.com/uber/kraken/lib/torrent/scheduler/conn"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnreachablePeersBucketsByReason(t *testing.T) {
+	require := require.New(t)
+
+	clk := clock.NewMock()
+	clk.Set(time.Now())
+	u := newUnreachablePeers(clk, time.Minute)
+
+	p1 := core.PeerIDFixture()
+	p2 := core.PeerIDFixture()
+
+	u.record(p1, "10.0.0.1:80", &conn.InitializeError{Reason: conn.FailureRefused, Err: errors.New("refused")})
+	clk.Add(time.Second)
+	u.record(p1, "10.0.0.1:80", &conn.InitializeError{Reason: conn.FailureRefused, Err: errors.New("refused")})
+	u.record(p1, "10.0.0.1:80", &conn.InitializeError{Reason: conn.FailureTimeout, Err: errors.New("timeout")})
+	u.record(p2, "10.0.0.2:80", errors.New("some error"))
+
+	peers := u.snapshot()
+	require.Len(peers, 2)
+	byPeer := map[core.PeerID]UnreachablePeer{peers[0].PeerID: peers[0], peers[1].PeerID: peers[1]}
+
+	require.Equal(UnreachablePeer{
+		PeerID:      p1,
+		Addr:        "10.0.0.1:80",
+		Failures:    map[string]int{conn.FailureRefused: 2, conn.FailureTimeout: 1},
+		LastFailure: clk.Now(),
+		LastError:   "timeout",
+	}, byPeer[p1])
+	require.Equal(map[string]int{conn.FailureHandshake: 1}, byPeer[p2].Failures)
+}
+
+func TestUnreachablePeersAgeOut(t *testing.T) {
+	require := require.New(t)
+
+	clk := clock.NewMock()
+	clk.Set(time.Now())
+	u := newUnreachablePeers(clk, time.Minute)
+
+	p := core.PeerIDFixture()
+	err := &conn.InitializeError{Reason: conn.FailureTimeout, Err: errors.New("timeout")}
+
+	u.record(p, "10.0.0.1:80", err)
+	clk.Add(40 * time.Second)
+	u.record(p, "10.0.0.1:80", err)
+
+	// Only the first failure has expired.
+	clk.Add(30 * time.Second)
+	peers := u.snapshot()
+	require.Len(peers, 1)
+	require.Equal(map[string]int{conn.FailureTimeout: 1}, peers[0].Failures)
+
+	clk.Add(30 * time.Second)
+	require.Empty(u.snapshot())
+}
+
+func TestUnreachablePeersClear(t *testing.T) {
+	require := require.New(t)
+
+	u := newUnreachablePeers(clock.NewMock(), time.Minute)
+
+	p := core.PeerIDFixture()
+	u.record(p, "10.0.0.1:80", &conn.InitializeError{Reason: conn.FailureRefused, Err: errors.New("refused")})
+	require.Len(u.snapshot(), 1)
+
+	u.clear(p)
+	require.Empty(u.snapshot())
+}
@@ -0,0 +1,97 @@
+// Copyright (c) 2016-2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package agentstorage
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+
+	"github.com/uber/kraken/core"
+	"github.com/uber/kraken/lib/store/metadata"
+	"github.com/uber/kraken/lib/torrent/storage"
+)
+
+const _allowlistSuffix = "_allowlist"
+
+func init() {
+	metadata.Register(regexp.MustCompile(_allowlistSuffix), allowlistMetadataFactory{})
+}
+
+type allowlistMetadataFactory struct{}
+
+func (m allowlistMetadataFactory) Create(suffix string) metadata.Metadata {
+	return &allowlistMetadata{}
+}
+
+// allowlistMetadata persists the peer allowlist of a torrent.
+type allowlistMetadata struct {
+	PeerIDs []string `json:"peer_ids"`
+	CIDRs   []string `json:"cidrs"`
+}
+
+func (m *allowlistMetadata) GetSuffix() string {
+	return _allowlistSuffix
+}
+
+// Movable is true, since a torrent must stay restricted once it is complete
+// and seeded from the cache.
+func (m *allowlistMetadata) Movable() bool {
+	return true
+}
+
+func (m *allowlistMetadata) Serialize() ([]byte, error) {
+	return json.Marshal(m)
+}
+
+func (m *allowlistMetadata) Deserialize(b []byte) error {
+	return json.Unmarshal(b, m)
+}
+
+// SetAllowlist persists the allowlist of torrent d, replacing any previous
+// allowlist.
+func (a *TorrentArchive) SetAllowlist(d core.Digest, allowlist *storage.Allowlist) error {
+	md := &allowlistMetadata{
+		PeerIDs: make([]string, len(allowlist.PeerIDs)),
+		CIDRs:   allowlist.CIDRs,
+	}
+	for i, id := range allowlist.PeerIDs {
+		md.PeerIDs[i] = id.String()
+	}
+	if _, err := a.cads.Any().SetMetadata(d.Hex(), md); err != nil {
+		return fmt.Errorf("set allowlist: %s", err)
+	}
+	return nil
+}
+
+// GetAllowlist returns the persisted allowlist of torrent d. Returns
+// os.ErrNotExist if d has no allowlist.
+func (a *TorrentArchive) GetAllowlist(d core.Digest) (*storage.Allowlist, error) {
+	var md allowlistMetadata
+	if err := a.cads.Any().GetMetadata(d.Hex(), &md); err != nil {
+		return nil, err
+	}
+	allowlist := &storage.Allowlist{
+		PeerIDs: make([]core.PeerID, len(md.PeerIDs)),
+		CIDRs:   md.CIDRs,
+	}
+	for i, s := range md.PeerIDs {
+		id, err := core.NewPeerID(s)
+		if err != nil {
+			return nil, fmt.Errorf("parse peer id: %s", err)
+		}
+		allowlist.PeerIDs[i] = id
+	}
+	return allowlist, nil
+}
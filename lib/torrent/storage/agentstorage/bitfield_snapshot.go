@@ -0,0 +1,184 @@
+// Copyright (c) 2016-2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package agentstorage
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"time"
+
+	"github.com/uber/kraken/core"
+	"github.com/uber/kraken/lib/store/metadata"
+	"github.com/uber/kraken/utils/closers"
+	"github.com/uber/kraken/utils/log"
+)
+
+const _bitfieldSnapshotSuffix = "_bitfield_snapshot"
+
+func init() {
+	metadata.Register(regexp.MustCompile(_bitfieldSnapshotSuffix), bitfieldSnapshotMetadataFactory{})
+}
+
+type bitfieldSnapshotMetadataFactory struct{}
+
+func (m bitfieldSnapshotMetadataFactory) Create(suffix string) metadata.Metadata {
+	return &bitfieldSnapshotMetadata{}
+}
+
+// bitfieldSnapshotMetadata records the set of pieces which were verified
+// complete at a point in time, along with the size and modification time of
+// the download file at that point and the info hash of the metainfo the
+// pieces were verified against. It is used on restore to limit piece
+// verification to pieces written since the snapshot.
+type bitfieldSnapshotMetadata struct {
+	size     int64
+	modTime  time.Time
+	infoHash core.InfoHash
+	complete []bool
+}
+
+// _bitfieldSnapshotHeaderLen is the serialized length of size, modTime and
+// infoHash.
+const _bitfieldSnapshotHeaderLen = 8 + 8 + len(core.InfoHash{})
+
+func (m *bitfieldSnapshotMetadata) GetSuffix() string {
+	return _bitfieldSnapshotSuffix
+}
+
+// Movable is false, since snapshots are meaningless once the file is in cache.
+func (m *bitfieldSnapshotMetadata) Movable() bool {
+	return false
+}
+
+func (m *bitfieldSnapshotMetadata) Serialize() ([]byte, error) {
+	b := make([]byte, _bitfieldSnapshotHeaderLen+len(m.complete))
+	binary.BigEndian.PutUint64(b[0:8], uint64(m.size))
+	binary.BigEndian.PutUint64(b[8:16], uint64(m.modTime.UnixNano()))
+	copy(b[16:_bitfieldSnapshotHeaderLen], m.infoHash[:])
+	for i, c := range m.complete {
+		if c {
+			b[_bitfieldSnapshotHeaderLen+i] = 1
+		}
+	}
+	return b, nil
+}
+
+func (m *bitfieldSnapshotMetadata) Deserialize(b []byte) error {
+	if len(b) < _bitfieldSnapshotHeaderLen {
+		return errors.New("bitfield snapshot too short")
+	}
+	m.size = int64(binary.BigEndian.Uint64(b[0:8]))
+	m.modTime = time.Unix(0, int64(binary.BigEndian.Uint64(b[8:16])))
+	copy(m.infoHash[:], b[16:_bitfieldSnapshotHeaderLen])
+	m.complete = make([]bool, len(b)-_bitfieldSnapshotHeaderLen)
+	for i := range m.complete {
+		m.complete[i] = b[_bitfieldSnapshotHeaderLen+i] == 1
+	}
+	return nil
+}
+
+// stale returns true if the snapshot was taken against different metainfo
+// than mi, or if the download file changed in a way which cannot be explained
+// by pieces being written since the snapshot was taken.
+func (m *bitfieldSnapshotMetadata) stale(info os.FileInfo, mi *core.MetaInfo) bool {
+	return m.infoHash != mi.InfoHash() ||
+		info.Size() != m.size ||
+		info.ModTime().Before(m.modTime) ||
+		len(m.complete) != mi.NumPieces()
+}
+
+// SnapshotBitfield persists the current bitfield of t, so that a restarted
+// agent only needs to verify pieces written after the snapshot. No-op if t is
+// already complete.
+func (t *Torrent) SnapshotBitfield() error {
+	if t.Complete() {
+		return nil
+	}
+	info, err := t.cads.Download().GetFileStat(t.Digest().Hex())
+	if err != nil {
+		return fmt.Errorf("stat download file: %s", err)
+	}
+	md := &bitfieldSnapshotMetadata{
+		size:     info.Size(),
+		modTime:  info.ModTime(),
+		infoHash: t.InfoHash(),
+		complete: make([]bool, len(t.pieces)),
+	}
+	for i, p := range t.pieces {
+		md.complete[i] = p.complete()
+	}
+	if _, err := t.cads.Download().SetMetadata(t.Digest().Hex(), md); err != nil {
+		return fmt.Errorf("set bitfield snapshot: %s", err)
+	}
+	return nil
+}
+
+// verifyRestoredPieces verifies pieces restored as complete against the piece
+// sums in t's metainfo, if t has a bitfield snapshot. If the snapshot is
+// valid, only pieces completed since the snapshot are verified; if it is
+// stale, every complete piece is verified. Without a snapshot, no pieces are
+// verified. Pieces which fail verification are reset to empty.
+func (t *Torrent) verifyRestoredPieces() error {
+	name := t.Digest().Hex()
+
+	var snapshot bitfieldSnapshotMetadata
+	if err := t.cads.Download().GetMetadata(name, &snapshot); err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("get bitfield snapshot: %s", err)
+	}
+	info, err := t.cads.Download().GetFileStat(name)
+	if err != nil {
+		return fmt.Errorf("stat download file: %s", err)
+	}
+	trusted := make([]bool, len(t.pieces))
+	if snapshot.stale(info, t.metaInfo) {
+		log.With("name", name).Info("Bitfield snapshot is stale, verifying all pieces")
+	} else {
+		trusted = snapshot.complete
+	}
+
+	f, err := t.cads.Download().GetFileReader(name)
+	if err != nil {
+		return fmt.Errorf("get download reader: %s", err)
+	}
+	defer closers.Close(f)
+
+	for i, p := range t.pieces {
+		if p.status != _complete || trusted[i] {
+			continue
+		}
+		h := core.PieceHash()
+		r := io.NewSectionReader(f, t.getFileOffset(i), t.PieceLength(i))
+		if _, err := io.Copy(h, r); err != nil {
+			return fmt.Errorf("read piece %d: %s", i, err)
+		}
+		if h.Sum32() == t.metaInfo.GetPieceSum(i) {
+			continue
+		}
+		log.With("name", name, "piece", i).Info("Restored piece failed verification")
+		if _, err := t.cads.Download().SetMetadataAt(
+			name, &pieceStatusMetadata{}, []byte{byte(_empty)}, int64(i)); err != nil {
+			return fmt.Errorf("reset piece %d status: %s", i, err)
+		}
+		p.status = _empty
+		t.numComplete.Dec()
+	}
+	return nil
+}
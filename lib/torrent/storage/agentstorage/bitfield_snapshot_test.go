@@ -0,0 +1,152 @@
+// Copyright (c) 2016-2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package agentstorage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/uber/kraken/core"
+	"github.com/uber/kraken/lib/store"
+	"github.com/uber/kraken/lib/torrent/storage/piecereader"
+	"github.com/uber/kraken/utils/bitsetutil"
+
+	"github.com/stretchr/testify/require"
+)
+
+// corruptPiece flips the bits of the first byte of piece pi on disk.
+func corruptPiece(t *testing.T, cads *store.CADownloadStore, blob *core.BlobFixture, pi int) {
+	f, err := cads.GetDownloadFileReadWriter(blob.Digest.Hex())
+	require.NoError(t, err)
+	defer f.Close()
+	offset := blob.MetaInfo.PieceLength() * int64(pi)
+	_, err = f.WriteAt([]byte{^blob.Content[offset]}, offset)
+	require.NoError(t, err)
+}
+
+func TestTorrentRestoreVerifiesPiecesSinceSnapshot(t *testing.T) {
+	require := require.New(t)
+
+	cads, cleanup := store.CADownloadStoreFixture()
+	defer cleanup()
+
+	blob := core.SizedBlobFixture(4, 1)
+	prepareStore(cads, blob.MetaInfo)
+
+	tor, err := NewTorrent(cads, blob.MetaInfo)
+	require.NoError(err)
+	require.NoError(tor.WritePiece(piecereader.NewBuffer(blob.Content[0:1]), 0))
+	require.NoError(tor.WritePiece(piecereader.NewBuffer(blob.Content[1:2]), 1))
+	require.NoError(tor.SnapshotBitfield())
+	require.NoError(tor.WritePiece(piecereader.NewBuffer(blob.Content[2:3]), 2))
+
+	// Piece 0 is covered by the snapshot and is trusted, while piece 2 was
+	// written since the snapshot and must be verified.
+	corruptPiece(t, cads, blob, 0)
+	corruptPiece(t, cads, blob, 2)
+
+	restored, err := newTorrent(cads, blob.MetaInfo, true)
+	require.NoError(err)
+	require.Equal(bitsetutil.FromBools(true, true, false, false), restored.Bitfield())
+	require.Equal(int64(2), restored.BytesDownloaded())
+
+	// The failed piece is persisted as empty.
+	restored, err = NewTorrent(cads, blob.MetaInfo)
+	require.NoError(err)
+	require.Equal(bitsetutil.FromBools(true, true, false, false), restored.Bitfield())
+}
+
+func TestTorrentRestoreWithoutSnapshotSkipsVerification(t *testing.T) {
+	require := require.New(t)
+
+	cads, cleanup := store.CADownloadStoreFixture()
+	defer cleanup()
+
+	blob := core.SizedBlobFixture(4, 1)
+	prepareStore(cads, blob.MetaInfo)
+
+	tor, err := NewTorrent(cads, blob.MetaInfo)
+	require.NoError(err)
+	require.NoError(tor.WritePiece(piecereader.NewBuffer(blob.Content[0:1]), 0))
+	require.NoError(tor.WritePiece(piecereader.NewBuffer(blob.Content[1:2]), 1))
+
+	corruptPiece(t, cads, blob, 0)
+
+	restored, err := newTorrent(cads, blob.MetaInfo, true)
+	require.NoError(err)
+	require.Equal(bitsetutil.FromBools(true, true, false, false), restored.Bitfield())
+}
+
+func TestTorrentRestoreStaleSnapshotVerifiesAllPieces(t *testing.T) {
+	require := require.New(t)
+
+	cads, cleanup := store.CADownloadStoreFixture()
+	defer cleanup()
+
+	blob := core.SizedBlobFixture(4, 1)
+	prepareStore(cads, blob.MetaInfo)
+
+	tor, err := NewTorrent(cads, blob.MetaInfo)
+	require.NoError(err)
+	require.NoError(tor.WritePiece(piecereader.NewBuffer(blob.Content[0:1]), 0))
+
+	info, err := cads.Download().GetFileStat(blob.Digest.Hex())
+	require.NoError(err)
+
+	for desc, snapshot := range map[string]*bitfieldSnapshotMetadata{
+		"file changed size": {
+			size:     info.Size() + 1,
+			modTime:  info.ModTime(),
+			infoHash: blob.MetaInfo.InfoHash(),
+		},
+		"different metainfo": {
+			size:     info.Size(),
+			modTime:  info.ModTime(),
+			infoHash: core.InfoHashFixture(),
+		},
+	} {
+		snapshot.complete = []bool{true, false, false, false}
+		_, err := cads.Download().SetMetadata(blob.Digest.Hex(), snapshot)
+		require.NoError(err, desc)
+
+		corruptPiece(t, cads, blob, 0)
+
+		restored, err := newTorrent(cads, blob.MetaInfo, true)
+		require.NoError(err, desc)
+		require.Equal(bitsetutil.FromBools(false, false, false, false), restored.Bitfield(), desc)
+
+		// Rewrite the piece for the next case.
+		require.NoError(restored.WritePiece(piecereader.NewBuffer(blob.Content[0:1]), 0), desc)
+	}
+}
+
+func TestBitfieldSnapshotMetadataSerialization(t *testing.T) {
+	require := require.New(t)
+
+	md := &bitfieldSnapshotMetadata{
+		size:     42,
+		modTime:  time.Unix(0, 1234),
+		infoHash: core.InfoHashFixture(),
+		complete: []bool{true, false, true},
+	}
+	b, err := md.Serialize()
+	require.NoError(err)
+
+	var result bitfieldSnapshotMetadata
+	require.NoError(result.Deserialize(b))
+	require.Equal(md.size, result.size)
+	require.Equal(md.infoHash, result.infoHash)
+	require.Equal(md.complete, result.complete)
+	require.True(md.modTime.Equal(result.modTime))
+}
@@ -26,7 +26,7 @@ import (
 // TorrentArchiveFixture returns a TorrrentArchive for testing purposes.
 func TorrentArchiveFixture() (*TorrentArchive, func()) {
 	cads, cleanup := store.CADownloadStoreFixture()
-	archive := NewTorrentArchive(tally.NoopScope, cads, nil)
+	archive := NewTorrentArchive(Config{}, tally.NoopScope, cads, nil)
 	return archive, cleanup
 }
 
@@ -40,7 +40,7 @@ func TorrentFixture(mi *core.MetaInfo) (*Torrent, func()) {
 
 	tc := metainfoclient.NewTestClient()
 
-	ta := NewTorrentArchive(tally.NoopScope, cads, tc)
+	ta := NewTorrentArchive(Config{}, tally.NoopScope, cads, tc)
 
 	if err := tc.Upload(mi); err != nil {
 		panic(err)
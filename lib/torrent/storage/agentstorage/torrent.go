@@ -59,26 +59,40 @@ type Torrent struct {
 
 // NewTorrent creates a new Torrent.
 func NewTorrent(cads caDownloadStore, mi *core.MetaInfo) (*Torrent, error) {
+	return newTorrent(cads, mi, false)
+}
+
+// newTorrent creates a new Torrent. If verify is set, pieces restored from
+// disk are verified against the last bitfield snapshot before the Torrent is
+// returned.
+func newTorrent(cads caDownloadStore, mi *core.MetaInfo, verify bool) (*Torrent, error) {
 	pieces, numComplete, err := restorePieces(mi.Digest(), cads, mi.NumPieces())
 	if err != nil {
 		return nil, fmt.Errorf("restore pieces: %s", err)
 	}
 
-	committed := false
-	if numComplete == len(pieces) {
+	t := &Torrent{
+		cads:        cads,
+		metaInfo:    mi,
+		pieces:      pieces,
+		numComplete: atomic.NewInt32(int32(numComplete)),
+		committed:   atomic.NewBool(false),
+	}
+
+	if verify && numComplete > 0 && numComplete < len(pieces) {
+		if err := t.verifyRestoredPieces(); err != nil {
+			return nil, fmt.Errorf("verify restored pieces: %s", err)
+		}
+	}
+
+	if int(t.numComplete.Load()) == len(pieces) {
 		if err := cads.MoveDownloadFileToCache(mi.Digest().Hex()); err != nil && !os.IsExist(err) {
 			return nil, fmt.Errorf("move file to cache: %s", err)
 		}
-		committed = true
+		t.committed.Store(true)
 	}
 
-	return &Torrent{
-		cads:        cads,
-		metaInfo:    mi,
-		pieces:      pieces,
-		numComplete: atomic.NewInt32(int32(numComplete)),
-		committed:   atomic.NewBool(committed),
-	}, nil
+	return t, nil
 }
 
 // Digest returns the digest of the target blob.
@@ -187,10 +201,13 @@ func (t *Torrent) writePiece(src storage.PieceReader, pi int) error {
 		return fmt.Errorf("seek: %s", err)
 	}
 	if _, err := io.Copy(f, r); err != nil {
+		if store.IsDiskFull(err) {
+			return storage.ErrDiskFull
+		}
 		return fmt.Errorf("copy: %s", err)
 	}
 	if h.Sum32() != t.metaInfo.GetPieceSum(pi) {
-		return errors.New("invalid piece sum")
+		return storage.ErrInvalidPieceSum
 	}
 
 	if err := t.markPieceComplete(pi); err != nil {
@@ -232,6 +249,9 @@ func (t *Torrent) WritePiece(src storage.PieceReader, pi int) error {
 	if err := t.writePiece(src, pi); err != nil {
 		// Allow other threads to write this piece since we mysteriously failed.
 		piece.markEmpty()
+		if err == storage.ErrDiskFull || err == storage.ErrInvalidPieceSum {
+			return err
+		}
 		return fmt.Errorf("write piece: %s", err)
 	}
 
@@ -16,6 +16,7 @@ package agentstorage
 import (
 	"fmt"
 	"os"
+	"sync"
 
 	"github.com/uber-go/tally"
 	"github.com/willf/bitset"
@@ -27,16 +28,33 @@ import (
 	"github.com/uber/kraken/tracker/metainfoclient"
 )
 
+// Config defines TorrentArchive configuration.
+type Config struct {
+	// BitfieldSnapshots enables verification of restored pieces against the
+	// last bitfield snapshot when a torrent is first reopened from disk after
+	// a restart. Pieces completed since the snapshot are re-hashed, and if the
+	// snapshot is stale, all completed pieces are re-hashed. Torrents without
+	// a snapshot are not verified.
+	BitfieldSnapshots bool `yaml:"bitfield_snapshots"`
+}
+
 // TorrentArchive is capable of initializing torrents in the download directory
 // and serving torrents from either the download or cache directory.
 type TorrentArchive struct {
+	config         Config
 	stats          tally.Scope
 	cads           *store.CADownloadStore
 	metaInfoClient metainfoclient.Client
+
+	// restored holds the torrents opened since the archive was created, so
+	// pieces are only verified when a torrent is restored, not on every open.
+	restoredMu sync.Mutex
+	restored   map[core.Digest]bool
 }
 
 // NewTorrentArchive creates a new TorrentArchive.
 func NewTorrentArchive(
+	config Config,
 	stats tally.Scope,
 	cads *store.CADownloadStore,
 	mic metainfoclient.Client) *TorrentArchive {
@@ -45,7 +63,13 @@ func NewTorrentArchive(
 		"module": "agenttorrentarchive",
 	})
 
-	return &TorrentArchive{stats, cads, mic}
+	return &TorrentArchive{
+		config:         config,
+		stats:          stats,
+		cads:           cads,
+		metaInfoClient: mic,
+		restored:       make(map[core.Digest]bool),
+	}
 }
 
 // Stat returns TorrentInfo for the given digest. Returns os.ErrNotExist if the
@@ -101,7 +125,7 @@ func (a *TorrentArchive) CreateTorrent(namespace string, d core.Digest) (storage
 	} else if err != nil {
 		return nil, fmt.Errorf("get metainfo: %s", err)
 	}
-	t, err := NewTorrent(a.cads, tm.MetaInfo)
+	t, err := a.openTorrent(tm.MetaInfo)
 	if err != nil {
 		return nil, fmt.Errorf("initialize torrent: %s", err)
 	}
@@ -114,7 +138,7 @@ func (a *TorrentArchive) GetTorrent(namespace string, d core.Digest) (storage.To
 	if err := a.cads.Any().GetMetadata(d.Hex(), &tm); err != nil {
 		return nil, fmt.Errorf("get metainfo: %s", err)
 	}
-	t, err := NewTorrent(a.cads, tm.MetaInfo)
+	t, err := a.openTorrent(tm.MetaInfo)
 	if err != nil {
 		return nil, fmt.Errorf("initialize torrent: %s", err)
 	}
@@ -123,8 +147,36 @@ func (a *TorrentArchive) GetTorrent(namespace string, d core.Digest) (storage.To
 
 // DeleteTorrent deletes a torrent from disk.
 func (a *TorrentArchive) DeleteTorrent(d core.Digest) error {
+	a.restoredMu.Lock()
+	delete(a.restored, d)
+	a.restoredMu.Unlock()
+
 	if err := a.cads.Any().DeleteFile(d.Hex()); err != nil && !os.IsNotExist(err) {
 		return err
 	}
 	return nil
 }
+
+// openTorrent opens the torrent of mi, verifying its restored pieces if
+// bitfield snapshots are enabled and this is the first time the torrent is
+// opened by a.
+func (a *TorrentArchive) openTorrent(mi *core.MetaInfo) (*Torrent, error) {
+	if !a.config.BitfieldSnapshots {
+		return newTorrent(a.cads, mi, false)
+	}
+	d := mi.Digest()
+
+	a.restoredMu.Lock()
+	restore := !a.restored[d]
+	a.restored[d] = true
+	a.restoredMu.Unlock()
+
+	t, err := newTorrent(a.cads, mi, restore)
+	if err != nil && restore {
+		// Retry the restore on the next open.
+		a.restoredMu.Lock()
+		delete(a.restored, d)
+		a.restoredMu.Unlock()
+	}
+	return t, err
+}
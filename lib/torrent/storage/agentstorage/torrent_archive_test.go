@@ -53,7 +53,7 @@ func newArchiveMocks(t *testing.T) (*archiveMocks, func()) {
 }
 
 func (m *archiveMocks) new() *TorrentArchive {
-	return NewTorrentArchive(tally.NoopScope, m.cads, m.metaInfoClient)
+	return NewTorrentArchive(Config{}, tally.NoopScope, m.cads, m.metaInfoClient)
 }
 
 func TestTorrentArchiveStatBitfield(t *testing.T) {
@@ -216,3 +216,72 @@ func TestTorrentArchiveGetTorrent(t *testing.T) {
 	require.NoError(err)
 	require.NotNil(tor)
 }
+
+func TestTorrentArchiveAllowlist(t *testing.T) {
+	require := require.New(t)
+
+	mocks, cleanup := newArchiveMocks(t)
+	defer cleanup()
+
+	archive := mocks.new()
+
+	namespace := core.TagFixture()
+	mi := core.SizedBlobFixture(4, 1).MetaInfo
+
+	mocks.metaInfoClient.EXPECT().Download(namespace, mi.Digest()).Return(mi, nil).Times(1)
+
+	_, err := archive.CreateTorrent(namespace, mi.Digest())
+	require.NoError(err)
+
+	_, err = archive.GetAllowlist(mi.Digest())
+	require.True(os.IsNotExist(err))
+
+	allowlist := &storage.Allowlist{
+		PeerIDs: []core.PeerID{core.PeerIDFixture()},
+		CIDRs:   []string{"10.0.0.0/8"},
+	}
+	require.NoError(archive.SetAllowlist(mi.Digest(), allowlist))
+
+	result, err := archive.GetAllowlist(mi.Digest())
+	require.NoError(err)
+	require.Equal(allowlist, result)
+
+	// Deleted along with the torrent.
+	require.NoError(archive.DeleteTorrent(mi.Digest()))
+	_, err = archive.GetAllowlist(mi.Digest())
+	require.True(os.IsNotExist(err))
+}
+
+func TestTorrentArchiveVerifiesOnlyOnRestore(t *testing.T) {
+	require := require.New(t)
+
+	mocks, cleanup := newArchiveMocks(t)
+	defer cleanup()
+
+	config := Config{BitfieldSnapshots: true}
+	archive := NewTorrentArchive(config, tally.NoopScope, mocks.cads, mocks.metaInfoClient)
+
+	namespace := core.TagFixture()
+	blob := core.SizedBlobFixture(4, 1)
+	mi := blob.MetaInfo
+
+	mocks.metaInfoClient.EXPECT().Download(namespace, mi.Digest()).Return(mi, nil).Times(1)
+
+	tor, err := archive.CreateTorrent(namespace, mi.Digest())
+	require.NoError(err)
+	require.NoError(tor.WritePiece(piecereader.NewBuffer(blob.Content[0:1]), 0))
+	require.NoError(tor.(storage.BitfieldSnapshotter).SnapshotBitfield())
+	require.NoError(tor.WritePiece(piecereader.NewBuffer(blob.Content[1:2]), 1))
+	corruptPiece(t, mocks.cads, blob, 1)
+
+	// Torrents already opened by this archive are not verified again.
+	tor, err = archive.GetTorrent(namespace, mi.Digest())
+	require.NoError(err)
+	require.Equal(bitsetutil.FromBools(true, true, false, false), tor.Bitfield())
+
+	// A restarted agent verifies pieces written since the snapshot.
+	restarted := NewTorrentArchive(config, tally.NoopScope, mocks.cads, mocks.metaInfoClient)
+	tor, err = restarted.GetTorrent(namespace, mi.Digest())
+	require.NoError(err)
+	require.Equal(bitsetutil.FromBools(true, false, false, false), tor.Bitfield())
+}
@@ -120,6 +120,30 @@ func TestTorrentWriteComplete(t *testing.T) {
 	require.Equal(storage.ErrPieceComplete, tor.WritePiece(piecereader.NewBuffer(blob.Content[:1]), 0))
 }
 
+func TestTorrentWriteInvalidPieceSum(t *testing.T) {
+	require := require.New(t)
+
+	cads, cleanup := store.CADownloadStoreFixture()
+	defer cleanup()
+
+	blob := core.SizedBlobFixture(2, 1)
+
+	prepareStore(cads, blob.MetaInfo)
+
+	tor, err := NewTorrent(cads, blob.MetaInfo)
+	require.NoError(err)
+
+	corrupt := []byte{blob.Content[0] + 1}
+	require.Equal(storage.ErrInvalidPieceSum, tor.WritePiece(piecereader.NewBuffer(corrupt), 0))
+	require.False(tor.HasPiece(0))
+	require.Equal(int64(0), tor.BytesDownloaded())
+
+	// Only the corrupt piece needs to be written again.
+	require.NoError(tor.WritePiece(piecereader.NewBuffer(blob.Content[1:2]), 1))
+	require.NoError(tor.WritePiece(piecereader.NewBuffer(blob.Content[:1]), 0))
+	require.True(tor.Complete())
+}
+
 func TestTorrentWriteMultiplePieceConcurrent(t *testing.T) {
 	require := require.New(t)
 
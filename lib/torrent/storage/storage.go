@@ -29,6 +29,14 @@ var ErrNotFound = errors.New("torrent not found")
 // complete.
 var ErrPieceComplete = errors.New("piece is already complete")
 
+// ErrDiskFull occurs when Torrent cannot write a piece because the underlying
+// store is out of space.
+var ErrDiskFull = errors.New("disk full")
+
+// ErrInvalidPieceSum occurs when Torrent rejects a piece because its data does
+// not match the piece sum in the torrent's metainfo.
+var ErrInvalidPieceSum = errors.New("invalid piece sum")
+
 // PieceReader defines operations for lazy piece reading.
 type PieceReader interface {
 	io.ReadCloser
@@ -56,6 +64,31 @@ type Torrent interface {
 	GetPieceReader(piece int) (PieceReader, error)
 }
 
+// BitfieldSnapshotter is implemented by Torrents which can persist a snapshot
+// of their bitfield to speed up restoring them from disk.
+type BitfieldSnapshotter interface {
+	SnapshotBitfield() error
+}
+
+// Allowlist is the persisted form of the peer allowlist a torrent was
+// downloaded with.
+type Allowlist struct {
+	PeerIDs []core.PeerID
+	CIDRs   []string
+}
+
+// AllowlistStore is implemented by TorrentArchives which persist torrent
+// allowlists alongside the torrent, so restricted torrents stay restricted
+// across restarts.
+type AllowlistStore interface {
+	// SetAllowlist persists the allowlist of torrent d, which must exist.
+	SetAllowlist(d core.Digest, allowlist *Allowlist) error
+
+	// GetAllowlist returns the persisted allowlist of torrent d. Returns
+	// os.ErrNotExist if d has no allowlist or does not exist.
+	GetAllowlist(d core.Digest) (*Allowlist, error)
+}
+
 // TorrentArchive creates and open torrent file
 type TorrentArchive interface {
 	Stat(namespace string, d core.Digest) (*TorrentInfo, error)
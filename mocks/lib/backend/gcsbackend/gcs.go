@@ -7,6 +7,7 @@ package mockgcsbackend
 import (
 	io "io"
 	reflect "reflect"
+	time "time"
 
 	storage "cloud.google.com/go/storage"
 	gomock "github.com/golang/mock/gomock"
@@ -36,6 +37,21 @@ func (m *MockGCS) EXPECT() *MockGCSMockRecorder {
 	return m.recorder
 }
 
+// BucketAttrs mocks base method
+func (m *MockGCS) BucketAttrs() (*storage.BucketAttrs, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BucketAttrs")
+	ret0, _ := ret[0].(*storage.BucketAttrs)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// BucketAttrs indicates an expected call of BucketAttrs
+func (mr *MockGCSMockRecorder) BucketAttrs() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BucketAttrs", reflect.TypeOf((*MockGCS)(nil).BucketAttrs))
+}
+
 // Download mocks base method
 func (m *MockGCS) Download(arg0 string, arg1 io.Writer) (int64, error) {
 	m.ctrl.T.Helper()
@@ -96,6 +112,21 @@ func (mr *MockGCSMockRecorder) ObjectAttrs(arg0 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ObjectAttrs", reflect.TypeOf((*MockGCS)(nil).ObjectAttrs), arg0)
 }
 
+// SignedURL mocks base method
+func (m *MockGCS) SignedURL(arg0 string, arg1 time.Time) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SignedURL", arg0, arg1)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SignedURL indicates an expected call of SignedURL
+func (mr *MockGCSMockRecorder) SignedURL(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SignedURL", reflect.TypeOf((*MockGCS)(nil).SignedURL), arg0, arg1)
+}
+
 // Upload mocks base method
 func (m *MockGCS) Upload(arg0 string, arg1 io.Reader) (int64, error) {
 	m.ctrl.T.Helper()
@@ -110,3 +141,18 @@ func (mr *MockGCSMockRecorder) Upload(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Upload", reflect.TypeOf((*MockGCS)(nil).Upload), arg0, arg1)
 }
+
+// UploadConditional mocks base method
+func (m *MockGCS) UploadConditional(arg0 string, arg1 io.Reader, arg2 storage.Conditions) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UploadConditional", arg0, arg1, arg2)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UploadConditional indicates an expected call of UploadConditional
+func (mr *MockGCSMockRecorder) UploadConditional(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UploadConditional", reflect.TypeOf((*MockGCS)(nil).UploadConditional), arg0, arg1, arg2)
+}
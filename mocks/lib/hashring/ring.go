@@ -62,6 +62,20 @@ func (mr *MockRingMockRecorder) Locations(arg0 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Locations", reflect.TypeOf((*MockRing)(nil).Locations), arg0)
 }
 
+// LocationsN mocks base method
+func (m *MockRing) LocationsN(arg0 core.Digest, arg1 int) []string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LocationsN", arg0, arg1)
+	ret0, _ := ret[0].([]string)
+	return ret0
+}
+
+// LocationsN indicates an expected call of LocationsN
+func (mr *MockRingMockRecorder) LocationsN(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LocationsN", reflect.TypeOf((*MockRing)(nil).LocationsN), arg0, arg1)
+}
+
 // Monitor mocks base method
 func (m *MockRing) Monitor(arg0 <-chan struct{}) {
 	m.ctrl.T.Helper()
@@ -11,6 +11,7 @@ import (
 	core "github.com/uber/kraken/core"
 	scheduler "github.com/uber/kraken/lib/torrent/scheduler"
 	connstate "github.com/uber/kraken/lib/torrent/scheduler/connstate"
+	dispatch "github.com/uber/kraken/lib/torrent/scheduler/dispatch"
 )
 
 // MockReloadableScheduler is a mock of ReloadableScheduler interface
@@ -65,6 +66,49 @@ func (mr *MockReloadableSchedulerMockRecorder) Download(arg0, arg1 interface{})
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Download", reflect.TypeOf((*MockReloadableScheduler)(nil).Download), arg0, arg1)
 }
 
+// DownloadWithAllowlist mocks base method
+func (m *MockReloadableScheduler) DownloadWithAllowlist(arg0 string, arg1 core.Digest, arg2 *scheduler.PeerAllowlist) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DownloadWithAllowlist", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DownloadWithAllowlist indicates an expected call of DownloadWithAllowlist
+func (mr *MockReloadableSchedulerMockRecorder) DownloadWithAllowlist(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DownloadWithAllowlist", reflect.TypeOf((*MockReloadableScheduler)(nil).DownloadWithAllowlist), arg0, arg1, arg2)
+}
+
+// DumpTorrent mocks base method
+func (m *MockReloadableScheduler) DumpTorrent(arg0 core.InfoHash) (scheduler.TorrentDump, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DumpTorrent", arg0)
+	ret0, _ := ret[0].(scheduler.TorrentDump)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DumpTorrent indicates an expected call of DumpTorrent
+func (mr *MockReloadableSchedulerMockRecorder) DumpTorrent(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DumpTorrent", reflect.TypeOf((*MockReloadableScheduler)(nil).DumpTorrent), arg0)
+}
+
+// PauseAll mocks base method
+func (m *MockReloadableScheduler) PauseAll() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PauseAll")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// PauseAll indicates an expected call of PauseAll
+func (mr *MockReloadableSchedulerMockRecorder) PauseAll() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PauseAll", reflect.TypeOf((*MockReloadableScheduler)(nil).PauseAll))
+}
+
 // Probe mocks base method
 func (m *MockReloadableScheduler) Probe() error {
 	m.ctrl.T.Helper()
@@ -105,6 +149,20 @@ func (mr *MockReloadableSchedulerMockRecorder) RemoveTorrent(arg0 interface{}) *
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveTorrent", reflect.TypeOf((*MockReloadableScheduler)(nil).RemoveTorrent), arg0)
 }
 
+// ResumeAll mocks base method
+func (m *MockReloadableScheduler) ResumeAll() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResumeAll")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ResumeAll indicates an expected call of ResumeAll
+func (mr *MockReloadableSchedulerMockRecorder) ResumeAll() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResumeAll", reflect.TypeOf((*MockReloadableScheduler)(nil).ResumeAll))
+}
+
 // Stop mocks base method
 func (m *MockReloadableScheduler) Stop() {
 	m.ctrl.T.Helper()
@@ -116,3 +174,32 @@ func (mr *MockReloadableSchedulerMockRecorder) Stop() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Stop", reflect.TypeOf((*MockReloadableScheduler)(nil).Stop))
 }
+
+// TorrentBandwidth mocks base method
+func (m *MockReloadableScheduler) TorrentBandwidth(arg0 string, arg1 core.Digest) (dispatch.BandwidthStats, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TorrentBandwidth", arg0, arg1)
+	ret0, _ := ret[0].(dispatch.BandwidthStats)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TorrentBandwidth indicates an expected call of TorrentBandwidth
+func (mr *MockReloadableSchedulerMockRecorder) TorrentBandwidth(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TorrentBandwidth", reflect.TypeOf((*MockReloadableScheduler)(nil).TorrentBandwidth), arg0, arg1)
+}
+
+// UnreachablePeers mocks base method
+func (m *MockReloadableScheduler) UnreachablePeers() []scheduler.UnreachablePeer {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UnreachablePeers")
+	ret0, _ := ret[0].([]scheduler.UnreachablePeer)
+	return ret0
+}
+
+// UnreachablePeers indicates an expected call of UnreachablePeers
+func (mr *MockReloadableSchedulerMockRecorder) UnreachablePeers() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UnreachablePeers", reflect.TypeOf((*MockReloadableScheduler)(nil).UnreachablePeers))
+}
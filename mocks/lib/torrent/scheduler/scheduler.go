@@ -9,7 +9,9 @@ import (
 
 	gomock "github.com/golang/mock/gomock"
 	core "github.com/uber/kraken/core"
+	scheduler "github.com/uber/kraken/lib/torrent/scheduler"
 	connstate "github.com/uber/kraken/lib/torrent/scheduler/connstate"
+	dispatch "github.com/uber/kraken/lib/torrent/scheduler/dispatch"
 )
 
 // MockScheduler is a mock of Scheduler interface
@@ -64,6 +66,49 @@ func (mr *MockSchedulerMockRecorder) Download(arg0, arg1 interface{}) *gomock.Ca
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Download", reflect.TypeOf((*MockScheduler)(nil).Download), arg0, arg1)
 }
 
+// DownloadWithAllowlist mocks base method
+func (m *MockScheduler) DownloadWithAllowlist(arg0 string, arg1 core.Digest, arg2 *scheduler.PeerAllowlist) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DownloadWithAllowlist", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DownloadWithAllowlist indicates an expected call of DownloadWithAllowlist
+func (mr *MockSchedulerMockRecorder) DownloadWithAllowlist(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DownloadWithAllowlist", reflect.TypeOf((*MockScheduler)(nil).DownloadWithAllowlist), arg0, arg1, arg2)
+}
+
+// DumpTorrent mocks base method
+func (m *MockScheduler) DumpTorrent(arg0 core.InfoHash) (scheduler.TorrentDump, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DumpTorrent", arg0)
+	ret0, _ := ret[0].(scheduler.TorrentDump)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DumpTorrent indicates an expected call of DumpTorrent
+func (mr *MockSchedulerMockRecorder) DumpTorrent(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DumpTorrent", reflect.TypeOf((*MockScheduler)(nil).DumpTorrent), arg0)
+}
+
+// PauseAll mocks base method
+func (m *MockScheduler) PauseAll() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PauseAll")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// PauseAll indicates an expected call of PauseAll
+func (mr *MockSchedulerMockRecorder) PauseAll() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PauseAll", reflect.TypeOf((*MockScheduler)(nil).PauseAll))
+}
+
 // Probe mocks base method
 func (m *MockScheduler) Probe() error {
 	m.ctrl.T.Helper()
@@ -92,6 +137,20 @@ func (mr *MockSchedulerMockRecorder) RemoveTorrent(arg0 interface{}) *gomock.Cal
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveTorrent", reflect.TypeOf((*MockScheduler)(nil).RemoveTorrent), arg0)
 }
 
+// ResumeAll mocks base method
+func (m *MockScheduler) ResumeAll() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResumeAll")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ResumeAll indicates an expected call of ResumeAll
+func (mr *MockSchedulerMockRecorder) ResumeAll() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResumeAll", reflect.TypeOf((*MockScheduler)(nil).ResumeAll))
+}
+
 // Stop mocks base method
 func (m *MockScheduler) Stop() {
 	m.ctrl.T.Helper()
@@ -103,3 +162,32 @@ func (mr *MockSchedulerMockRecorder) Stop() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Stop", reflect.TypeOf((*MockScheduler)(nil).Stop))
 }
+
+// TorrentBandwidth mocks base method
+func (m *MockScheduler) TorrentBandwidth(arg0 string, arg1 core.Digest) (dispatch.BandwidthStats, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TorrentBandwidth", arg0, arg1)
+	ret0, _ := ret[0].(dispatch.BandwidthStats)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TorrentBandwidth indicates an expected call of TorrentBandwidth
+func (mr *MockSchedulerMockRecorder) TorrentBandwidth(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TorrentBandwidth", reflect.TypeOf((*MockScheduler)(nil).TorrentBandwidth), arg0, arg1)
+}
+
+// UnreachablePeers mocks base method
+func (m *MockScheduler) UnreachablePeers() []scheduler.UnreachablePeer {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UnreachablePeers")
+	ret0, _ := ret[0].([]scheduler.UnreachablePeer)
+	return ret0
+}
+
+// UnreachablePeers indicates an expected call of UnreachablePeers
+func (mr *MockSchedulerMockRecorder) UnreachablePeers() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UnreachablePeers", reflect.TypeOf((*MockScheduler)(nil).UnreachablePeers))
+}
@@ -9,6 +9,7 @@ import (
 
 	gomock "github.com/golang/mock/gomock"
 	core "github.com/uber/kraken/core"
+	peerstore "github.com/uber/kraken/tracker/peerstore"
 )
 
 // MockStore is a mock of Store interface
@@ -46,6 +47,21 @@ func (mr *MockStoreMockRecorder) Close() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Close", reflect.TypeOf((*MockStore)(nil).Close))
 }
 
+// EvictPeer mocks base method
+func (m *MockStore) EvictPeer(arg0 peerstore.PeerSelector) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EvictPeer", arg0)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// EvictPeer indicates an expected call of EvictPeer
+func (mr *MockStoreMockRecorder) EvictPeer(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EvictPeer", reflect.TypeOf((*MockStore)(nil).EvictPeer), arg0)
+}
+
 // GetPeers mocks base method
 func (m *MockStore) GetPeers(arg0 core.InfoHash, arg1 int) ([]*core.PeerInfo, error) {
 	m.ctrl.T.Helper()
@@ -61,6 +77,21 @@ func (mr *MockStoreMockRecorder) GetPeers(arg0, arg1 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPeers", reflect.TypeOf((*MockStore)(nil).GetPeers), arg0, arg1)
 }
 
+// ListSwarms mocks base method
+func (m *MockStore) ListSwarms() ([]peerstore.SwarmInfo, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListSwarms")
+	ret0, _ := ret[0].([]peerstore.SwarmInfo)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListSwarms indicates an expected call of ListSwarms
+func (mr *MockStoreMockRecorder) ListSwarms() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListSwarms", reflect.TypeOf((*MockStore)(nil).ListSwarms))
+}
+
 // UpdatePeer mocks base method
 func (m *MockStore) UpdatePeer(arg0 core.InfoHash, arg1 *core.PeerInfo) error {
 	m.ctrl.T.Helper()
@@ -14,20 +14,142 @@
 package blobserver
 
 import (
+	"fmt"
+	"regexp"
 	"time"
 
+	"github.com/c2h5oh/datasize"
 	"github.com/uber/kraken/utils/listener"
 )
 
 // Config defines the configuration used by Origin cluster for hashing blob digests.
 type Config struct {
-	Listener                  listener.Config `yaml:"listener"`
-	DuplicateWriteBackStagger time.Duration   `yaml:"duplicate_write_back_stagger"`
+	Listener                  listener.Config         `yaml:"listener"`
+	DuplicateWriteBackStagger time.Duration           `yaml:"duplicate_write_back_stagger"`
+	PresignedRedirect         PresignedRedirectConfig `yaml:"presigned_redirect"`
+
+	// NamespaceReplication overrides the hash ring replication factor for
+	// matching namespaces. The first matching entry wins; namespaces which
+	// match no entry use the hash ring's max_replica. The factor only limits
+	// which origins a blob is pushed to and repaired on: blob locations
+	// reported to clients and cache ownership are not namespaced, and remain
+	// the hash ring's max_replica origins. Since a namespace's replica set is
+	// a prefix of those locations, clients still upload to and read from a
+	// replica first. Factors may not exceed max_replica for the same reason.
+	NamespaceReplication []NamespaceReplicationConfig `yaml:"namespace_replication"`
+
+	// ReplicationStatusConcurrency bounds the number of origins probed at once
+	// when reporting the replication status of a blob.
+	ReplicationStatusConcurrency int `yaml:"replication_status_concurrency"`
+
+	// CopyBufferSize is the size of the pooled buffers used to stream blobs to
+	// clients. If unset, blobs are streamed with io.Copy, as before.
+	CopyBufferSize datasize.ByteSize `yaml:"copy_buffer_size"`
+
+	// RejectUnknownNamespaces rejects requests for namespaces which match no
+	// backend with 404, before touching storage. Namespaces routed to the
+	// backend manager's default_namespace are not rejected.
+	RejectUnknownNamespaces bool `yaml:"reject_unknown_namespaces"`
+
+	// ReplicationRepair bounds the blob transfers made when repairing
+	// under-replicated blobs.
+	ReplicationRepair ReplicationRepairConfig `yaml:"replication_repair"`
+}
+
+// ReplicationRepairConfig configures the concurrency of replication repair.
+type ReplicationRepairConfig struct {
+	// Parallelism is the number of blob transfers in flight at once.
+	Parallelism int `yaml:"parallelism"`
+
+	// PerSourceConcurrency is the number of transfers read from any single
+	// origin at once, such that no origin is overwhelmed by the repair.
+	PerSourceConcurrency int `yaml:"per_source_concurrency"`
+}
+
+// validateConcurrency checks that the replication status and repair
+// concurrency limits, after defaults are applied, are positive.
+func (c Config) validateConcurrency() error {
+	if c.ReplicationStatusConcurrency <= 0 {
+		return fmt.Errorf(
+			"replication_status_concurrency must be positive, got %d", c.ReplicationStatusConcurrency)
+	}
+	if c.ReplicationRepair.Parallelism <= 0 {
+		return fmt.Errorf(
+			"replication_repair.parallelism must be positive, got %d", c.ReplicationRepair.Parallelism)
+	}
+	if c.ReplicationRepair.PerSourceConcurrency <= 0 {
+		return fmt.Errorf(
+			"replication_repair.per_source_concurrency must be positive, got %d",
+			c.ReplicationRepair.PerSourceConcurrency)
+	}
+	return nil
+}
+
+// NamespaceReplicationConfig sets the number of origins which hold a copy of
+// each blob in namespaces matching Namespace, a regular expression.
+type NamespaceReplicationConfig struct {
+	Namespace string `yaml:"namespace"`
+	Factor    int    `yaml:"factor"`
+}
+
+// ValidateReplication checks that every namespace replication entry is well
+// formed and can be satisfied by a cluster of clusterSize origins whose hash
+// ring replicates blobs across maxReplica origins.
+func (c Config) ValidateReplication(clusterSize, maxReplica int) error {
+	for _, nr := range c.NamespaceReplication {
+		if _, err := regexp.Compile(nr.Namespace); err != nil {
+			return fmt.Errorf("namespace %q: regexp: %s", nr.Namespace, err)
+		}
+		if nr.Factor <= 0 {
+			return fmt.Errorf("namespace %q: factor must be positive", nr.Namespace)
+		}
+		if nr.Factor > clusterSize {
+			return fmt.Errorf(
+				"namespace %q: factor %d exceeds cluster size %d",
+				nr.Namespace, nr.Factor, clusterSize)
+		}
+		if nr.Factor > maxReplica {
+			return fmt.Errorf(
+				"namespace %q: factor %d exceeds hash ring max replica %d",
+				nr.Namespace, nr.Factor, maxReplica)
+		}
+	}
+	return nil
+}
+
+// PresignedRedirectConfig configures redirecting downloads of large blobs to
+// presigned backend urls, such that clients download directly from the
+// backend instead of through origin. Only blobs which are not in the local
+// cache are redirected, since cached blobs may not be written back yet.
+// Backends which do not support presigning fall back to proxying.
+type PresignedRedirectConfig struct {
+	Enabled bool `yaml:"enabled"`
+
+	// MinBlobSize is the size above which blobs are redirected.
+	MinBlobSize datasize.ByteSize `yaml:"min_blob_size"`
+
+	// TTL is how long presigned urls are valid for.
+	TTL time.Duration `yaml:"ttl"`
 }
 
 func (c Config) applyDefaults() Config {
 	if c.DuplicateWriteBackStagger == 0 {
 		c.DuplicateWriteBackStagger = 30 * time.Minute
 	}
+	if c.PresignedRedirect.MinBlobSize == 0 {
+		c.PresignedRedirect.MinBlobSize = datasize.GB
+	}
+	if c.PresignedRedirect.TTL == 0 {
+		c.PresignedRedirect.TTL = 15 * time.Minute
+	}
+	if c.ReplicationStatusConcurrency == 0 {
+		c.ReplicationStatusConcurrency = 8
+	}
+	if c.ReplicationRepair.Parallelism == 0 {
+		c.ReplicationRepair.Parallelism = 8
+	}
+	if c.ReplicationRepair.PerSourceConcurrency == 0 {
+		c.ReplicationRepair.PerSourceConcurrency = 2
+	}
 	return c
 }
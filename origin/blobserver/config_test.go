@@ -0,0 +1,72 @@
+// Copyright (c) 2016-2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package blobserver
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigValidateReplication(t *testing.T) {
+	tests := []struct {
+		desc       string
+		config     NamespaceReplicationConfig
+		maxReplica int
+		valid      bool
+	}{
+		{"valid", NamespaceReplicationConfig{Namespace: "prod/.*", Factor: 3}, 3, true},
+		{"below max replica", NamespaceReplicationConfig{Namespace: "prod/.*", Factor: 1}, 3, true},
+		{"exceeds cluster size", NamespaceReplicationConfig{Namespace: "prod/.*", Factor: 4}, 4, false},
+		{"exceeds max replica", NamespaceReplicationConfig{Namespace: "prod/.*", Factor: 3}, 2, false},
+		{"zero factor", NamespaceReplicationConfig{Namespace: "prod/.*", Factor: 0}, 3, false},
+		{"invalid regexp", NamespaceReplicationConfig{Namespace: "prod/(", Factor: 1}, 3, false},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			config := Config{NamespaceReplication: []NamespaceReplicationConfig{test.config}}
+			err := config.ValidateReplication(3, test.maxReplica)
+			if test.valid {
+				require.NoError(t, err)
+			} else {
+				require.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestConfigValidateConcurrency(t *testing.T) {
+	tests := []struct {
+		desc   string
+		config Config
+		valid  bool
+	}{
+		{"defaults", Config{}, true},
+		{"negative status concurrency", Config{ReplicationStatusConcurrency: -1}, false},
+		{"negative parallelism", Config{
+			ReplicationRepair: ReplicationRepairConfig{Parallelism: -1}}, false},
+		{"negative per source concurrency", Config{
+			ReplicationRepair: ReplicationRepairConfig{PerSourceConcurrency: -2}}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			err := test.config.applyDefaults().validateConcurrency()
+			if test.valid {
+				require.NoError(t, err)
+			} else {
+				require.Error(t, err)
+			}
+		})
+	}
+}
@@ -8,6 +8,9 @@ type metrics struct {
 	replicateBlobTimer       tally.Timer
 	replicateBlobErrors      tally.Counter
 	duplicateWritebackErrors tally.Counter
+	presignedRedirects       tally.Counter
+	unknownNamespaces        tally.Counter
+	replicationsInFlight     tally.Gauge
 }
 
 func newMetrics(s tally.Scope) *metrics {
@@ -15,5 +18,8 @@ func newMetrics(s tally.Scope) *metrics {
 		replicateBlobTimer:       s.Timer("replicate_blob"),
 		replicateBlobErrors:      s.Counter("replicate_blob_errors"),
 		duplicateWritebackErrors: s.Counter("duplicate_write_back_errors"),
+		presignedRedirects:       s.Counter("presigned_redirects"),
+		unknownNamespaces:        s.Counter("unknown_namespaces"),
+		replicationsInFlight:     s.Gauge("replications_in_flight"),
 	}
 }
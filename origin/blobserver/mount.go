@@ -0,0 +1,102 @@
+// Copyright (c) 2016-2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package blobserver
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/uber/kraken/core"
+	"github.com/uber/kraken/lib/backend/backenderrors"
+	"github.com/uber/kraken/lib/blobrefresh"
+	"github.com/uber/kraken/utils/handler"
+	"github.com/uber/kraken/utils/log"
+)
+
+// parseMount parses the "mount" and "from" query arguments of a cross
+// repository mount request for d. Returns empty from if r is not a mount.
+func parseMount(r *http.Request, d core.Digest) (string, error) {
+	mount := r.URL.Query().Get("mount")
+	from := r.URL.Query().Get("from")
+	if mount == "" && from == "" {
+		return "", nil
+	}
+	if mount == "" || from == "" {
+		return "", handler.Errorf("mount and from must both be set").Status(http.StatusBadRequest)
+	}
+	md, err := core.ParseSHA256Digest(mount)
+	if err != nil {
+		return "", handler.Errorf("parse mount digest: %s", err).Status(http.StatusBadRequest)
+	}
+	if md != d {
+		return "", handler.Errorf("mount digest %s does not match %s", md, d).Status(http.StatusBadRequest)
+	}
+	return from, nil
+}
+
+// mountBlob commits d, which already exists under namespace from, to namespace
+// without a re-upload. Returns false if d could not be found, in which case
+// callers should fall back to a regular upload.
+func (s *Server) mountBlob(namespace, from string, d core.Digest) (bool, error) {
+	if _, err := s.cas.GetCacheFileStat(d.Hex()); err == nil {
+		log.With("namespace", namespace, "from", from, "digest", d.Hex()).Info("Mounting blob from local cache")
+		if err := s.writeBack(namespace, d, 0); err != nil {
+			return false, err
+		}
+		return true, nil
+	} else if !os.IsNotExist(err) {
+		return false, fmt.Errorf("stat cache file: %s", err)
+	}
+
+	// Namespaces may share a backend, in which case the blob is already
+	// persisted for namespace.
+	client, err := s.backends.GetClient(namespace)
+	if err != nil {
+		return false, fmt.Errorf("get backend client: %s", err)
+	}
+	if _, err := client.Stat(namespace, d.Hex()); err == nil {
+		log.With("namespace", namespace, "from", from, "digest", d.Hex()).Info("Mounting blob already in backend")
+		return true, nil
+	} else if err != backenderrors.ErrBlobNotFound {
+		return false, fmt.Errorf("backend stat: %s", err)
+	}
+
+	// Pull the blob from the source namespace and write it back to namespace
+	// once downloaded. If a download from the source is already pending, the
+	// hook cannot be attached, so fall back to a regular upload.
+	switch err := s.blobRefresher.Refresh(from, d, &mountWriteBackHook{s, namespace}); err {
+	case nil:
+		log.With("namespace", namespace, "from", from, "digest", d.Hex()).Info("Mounting blob from source backend")
+		return true, nil
+	case blobrefresh.ErrNotFound, blobrefresh.ErrPending:
+		return false, nil
+	default:
+		log.With("namespace", namespace, "from", from, "digest", d.Hex()).Errorf("Cannot mount blob from source backend: %s", err)
+		return false, nil
+	}
+}
+
+// mountWriteBackHook writes back a blob mounted from another namespace once it
+// has been downloaded from the source namespace's backend.
+type mountWriteBackHook struct {
+	server    *Server
+	namespace string
+}
+
+func (h *mountWriteBackHook) Run(d core.Digest) {
+	if err := h.server.writeBack(h.namespace, d, 0); err != nil {
+		log.With("namespace", h.namespace, "digest", d.Hex()).Errorf("Error writing back mounted blob: %s", err)
+	}
+}
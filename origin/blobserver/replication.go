@@ -0,0 +1,52 @@
+// Copyright (c) 2016-2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package blobserver
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/uber/kraken/core"
+)
+
+type namespaceReplication struct {
+	regexp *regexp.Regexp
+	factor int
+}
+
+func compileNamespaceReplication(
+	configs []NamespaceReplicationConfig) ([]namespaceReplication, error) {
+
+	var nrs []namespaceReplication
+	for _, c := range configs {
+		re, err := regexp.Compile(c.Namespace)
+		if err != nil {
+			return nil, fmt.Errorf("namespace %q: regexp: %s", c.Namespace, err)
+		}
+		nrs = append(nrs, namespaceReplication{re, c.Factor})
+	}
+	return nrs, nil
+}
+
+// replicaLocations returns the replica set of d, sized according to the
+// replication factor configured for namespace. It is a prefix of the hash
+// ring locations of d, and only governs push replication and repair.
+func (s *Server) replicaLocations(namespace string, d core.Digest) []string {
+	for _, nr := range s.namespaceReplication {
+		if nr.regexp.MatchString(namespace) {
+			return s.hashRing.LocationsN(d, nr.factor)
+		}
+	}
+	return s.hashRing.Locations(d)
+}
@@ -0,0 +1,371 @@
+// Copyright (c) 2016-2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package blobserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/uber/kraken/core"
+	"github.com/uber/kraken/lib/backend"
+	"github.com/uber/kraken/lib/backend/backenderrors"
+	"github.com/uber/kraken/utils/handler"
+	"github.com/uber/kraken/utils/httputil"
+	"github.com/uber/kraken/utils/log"
+)
+
+// repairTask copies blob d from source, one of its holders, to target, one of
+// its missing replicas.
+type repairTask struct {
+	d      core.Digest
+	copies int
+	source string
+	target string
+}
+
+// repairState is the state of a replication repair.
+type repairState string
+
+const (
+	repairRunning repairState = "running"
+	repairDone    repairState = "done"
+)
+
+// repairJob reports the progress of a replication repair of a namespace.
+type repairJob struct {
+	State      repairState `json:"state"`
+	StartedAt  time.Time   `json:"started_at"`
+	FinishedAt *time.Time  `json:"finished_at,omitempty"`
+
+	// Transfers is the number of copies planned, set once planning completes.
+	Transfers int      `json:"transfers"`
+	Repaired  int      `json:"repaired"`
+	Errors    []string `json:"errors"`
+}
+
+// repairReplicationHandler starts copying every blob in the local cache to
+// the replicas it is missing from and returns 202 immediately. Progress is
+// reported by getRepairReplicationHandler. Only one repair of a namespace
+// runs at once.
+func (s *Server) repairReplicationHandler(w http.ResponseWriter, r *http.Request) error {
+	// Note, like forcecleanup, this API is intended to be executed manually,
+	// e.g. after replacing a lost origin.
+	namespace, err := httputil.ParseParam(r, "namespace")
+	if err != nil {
+		return err
+	}
+
+	s.repairsMu.Lock()
+	if job, ok := s.repairs[namespace]; ok && job.State == repairRunning {
+		s.repairsMu.Unlock()
+		return handler.Errorf("repair of namespace %s already running", namespace).Status(http.StatusConflict)
+	}
+	job := &repairJob{
+		State:     repairRunning,
+		StartedAt: s.clk.Now(),
+		Errors:    []string{},
+	}
+	s.repairs[namespace] = job
+	status := *job
+	s.repairsMu.Unlock()
+
+	go s.repairReplication(namespace, job)
+
+	w.WriteHeader(http.StatusAccepted)
+	if err := json.NewEncoder(w).Encode(status); err != nil {
+		return handler.Errorf("json encode: %s", err)
+	}
+	return nil
+}
+
+// getRepairReplicationHandler reports the progress of the latest replication
+// repair of a namespace.
+func (s *Server) getRepairReplicationHandler(w http.ResponseWriter, r *http.Request) error {
+	namespace, err := httputil.ParseParam(r, "namespace")
+	if err != nil {
+		return err
+	}
+	s.repairsMu.Lock()
+	job, ok := s.repairs[namespace]
+	var status repairJob
+	if ok {
+		status = *job
+	}
+	s.repairsMu.Unlock()
+	if !ok {
+		return handler.ErrorStatus(http.StatusNotFound)
+	}
+	if err := json.NewEncoder(w).Encode(status); err != nil {
+		return handler.Errorf("json encode: %s", err)
+	}
+	return nil
+}
+
+// repairReplication repairs every blob in the local cache which belongs to
+// namespace, i.e. which the namespace's backend holds. Under-replicated blobs are repaired first, fewest copies first, with at
+// most ReplicationRepair.Parallelism transfers in flight and at most
+// ReplicationRepair.PerSourceConcurrency transfers read from any single
+// origin. The result is recorded in job.
+func (s *Server) repairReplication(namespace string, job *repairJob) {
+	var repaired int
+	var errs []string
+	defer func() {
+		now := s.clk.Now()
+		s.repairsMu.Lock()
+		defer s.repairsMu.Unlock()
+		job.State = repairDone
+		job.FinishedAt = &now
+		job.Repaired = repaired
+		job.Errors = append(job.Errors, errs...)
+	}()
+
+	names, err := s.cas.ListCacheFiles()
+	if err != nil {
+		log.With("namespace", namespace).Errorf("Error listing cache files for repair: %s", err)
+		errs = append(errs, fmt.Sprintf("list cache files: %s", err))
+		return
+	}
+	var digests []core.Digest
+	for _, name := range names {
+		d, err := core.NewSHA256DigestFromHex(name)
+		if err != nil {
+			log.With("name", name).Errorf("Skipping repair of invalid cache file: %s", err)
+			continue
+		}
+		digests = append(digests, d)
+	}
+
+	client, err := s.backends.GetClient(namespace)
+	if err != nil {
+		log.With("namespace", namespace).Errorf("Error getting backend client for repair: %s", err)
+		errs = append(errs, fmt.Sprintf("get backend client: %s", err))
+		return
+	}
+
+	log.With("namespace", namespace, "blob_count", len(digests)).Info("Starting replication repair")
+	statuses, statErrs := s.replicationStatuses(namespace, client, digests)
+	tasks := planRepairs(statuses)
+	s.repairsMu.Lock()
+	job.Transfers = len(tasks)
+	s.repairsMu.Unlock()
+
+	repairErrs := s.runRepairs(namespace, tasks)
+	repaired = len(tasks) - len(repairErrs)
+	errs = append(statErrs, repairErrs...)
+	log.With(
+		"namespace", namespace,
+		"transfer_count", len(tasks),
+		"error_count", len(errs)).Info("Replication repair completed")
+}
+
+// replicationStatuses computes the replication status of every digest held
+// by client, the backend of namespace, at most ReplicationRepair.Parallelism
+// at once. Digests the backend does not hold belong to other namespaces and
+// are skipped. Origin probes are shared across digests, so at most
+// ReplicationStatusConcurrency are in flight in total. Returns the errors of
+// digests whose backend stat failed.
+func (s *Server) replicationStatuses(
+	namespace string, client backend.Client, digests []core.Digest,
+) (map[core.Digest]*ReplicationStatus, []string) {
+
+	var mu sync.Mutex
+	statuses := make(map[core.Digest]*ReplicationStatus, len(digests))
+	errs := []string{}
+
+	probes := make(chan struct{}, s.config.ReplicationStatusConcurrency)
+	sem := make(chan struct{}, s.config.ReplicationRepair.Parallelism)
+	var wg sync.WaitGroup
+	for _, d := range digests {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(d core.Digest) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			if _, err := client.Stat(namespace, d.Hex()); err != nil {
+				if err == backenderrors.ErrBlobNotFound {
+					return
+				}
+				log.With("namespace", namespace, "digest", d.Hex()).Errorf(
+					"Failed to stat blob for repair: %s", err)
+				mu.Lock()
+				errs = append(errs, fmt.Sprintf("%s: backend stat: %s", d.Hex(), err))
+				mu.Unlock()
+				return
+			}
+			status := s.replicationStatus(namespace, d, probes)
+			mu.Lock()
+			statuses[d] = status
+			mu.Unlock()
+		}(d)
+	}
+	wg.Wait()
+
+	return statuses, errs
+}
+
+// planRepairs returns a transfer for every missing replica in statuses,
+// ordered by the number of copies of the blob, fewest first. Each transfer is
+// sourced from the holder with the fewest transfers planned so far.
+func planRepairs(statuses map[core.Digest]*ReplicationStatus) []repairTask {
+	digests := make([]core.Digest, 0, len(statuses))
+	for d := range statuses {
+		digests = append(digests, d)
+	}
+	sort.Slice(digests, func(i, j int) bool {
+		ci, cj := len(statuses[digests[i]].Holders), len(statuses[digests[j]].Holders)
+		if ci != cj {
+			return ci < cj
+		}
+		return digests[i].Hex() < digests[j].Hex()
+	})
+
+	load := make(map[string]int)
+	var tasks []repairTask
+	for _, d := range digests {
+		status := statuses[d]
+		if len(status.Holders) == 0 {
+			continue
+		}
+		for _, target := range status.Missing {
+			source := status.Holders[0]
+			for _, h := range status.Holders[1:] {
+				if load[h] < load[source] {
+					source = h
+				}
+			}
+			load[source]++
+			tasks = append(tasks, repairTask{d, len(status.Holders), source, target})
+		}
+	}
+	return tasks
+}
+
+// runRepairs executes tasks in order, returning the errors of failed tasks.
+func (s *Server) runRepairs(namespace string, tasks []repairTask) []string {
+	var mu sync.Mutex
+	errs := []string{}
+
+	dispatchRepairs(s.config.ReplicationRepair, tasks, func(t repairTask) {
+		s.metrics.replicationsInFlight.Update(float64(s.replicationsInFlight.Inc()))
+		err := s.repair(namespace, t)
+		s.metrics.replicationsInFlight.Update(float64(s.replicationsInFlight.Dec()))
+		if err != nil {
+			s.metrics.replicateBlobErrors.Inc(1)
+			log.With(
+				"digest", t.d.Hex(),
+				"source", t.source,
+				"target", t.target).Errorf("Failed to repair replica: %s", err)
+			mu.Lock()
+			errs = append(errs, fmt.Sprintf("%s -> %s: %s", t.d.Hex(), t.target, err))
+			mu.Unlock()
+		}
+	})
+
+	return errs
+}
+
+// dispatchRepairs calls repair on every task in order, with at most
+// config.Parallelism calls in flight and at most config.PerSourceConcurrency
+// calls per source. Tasks whose source is busy are passed over in favor of
+// the next task with a free source, such that one busy source does not hold
+// up transfers from the others. Returns once all calls have returned.
+func dispatchRepairs(
+	config ReplicationRepairConfig, tasks []repairTask, repair func(repairTask)) {
+
+	// Tasks are queued per source, along with their index in tasks such that
+	// the order of tasks is preserved across sources.
+	type queued struct {
+		index int
+		task  repairTask
+	}
+	queues := make(map[string][]queued)
+	for i, t := range tasks {
+		queues[t.source] = append(queues[t.source], queued{i, t})
+	}
+	inFlight := make(map[string]int)
+
+	// next dequeues the earliest task whose source has a free slot.
+	next := func() (repairTask, bool) {
+		var source string
+		var found bool
+		for src, q := range queues {
+			if len(q) == 0 || inFlight[src] >= config.PerSourceConcurrency {
+				continue
+			}
+			if !found || q[0].index < queues[source][0].index {
+				source = src
+				found = true
+			}
+		}
+		if !found {
+			return repairTask{}, false
+		}
+		t := queues[source][0].task
+		queues[source] = queues[source][1:]
+		return t, true
+	}
+
+	done := make(chan repairTask)
+	var running int
+	for remaining := len(tasks); remaining > 0; remaining-- {
+		for running < config.Parallelism {
+			t, ok := next()
+			if !ok {
+				break
+			}
+			inFlight[t.source]++
+			running++
+			go func() {
+				repair(t)
+				done <- t
+			}()
+		}
+		t := <-done
+		inFlight[t.source]--
+		running--
+	}
+}
+
+// repair transfers t.d from t.source to t.target. Blobs held by other origins
+// are streamed through the current origin.
+func (s *Server) repair(namespace string, t repairTask) error {
+	target := s.clientProvider.Provide(t.target)
+	if t.source == s.addr {
+		f, err := s.cas.GetCacheFileReader(t.d.Hex())
+		if err != nil {
+			return fmt.Errorf("get cache reader: %s", err)
+		}
+		defer f.Close()
+		if err := target.TransferBlob(t.d, f); err != nil {
+			return fmt.Errorf("transfer blob: %s", err)
+		}
+		return nil
+	}
+	pr, pw := io.Pipe()
+	defer pr.Close()
+	go func() {
+		pw.CloseWithError(s.clientProvider.Provide(t.source).DownloadBlob(namespace, t.d, pw))
+	}()
+	if err := target.TransferBlob(t.d, pr); err != nil {
+		return fmt.Errorf("transfer blob from %s: %s", t.source, err)
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+// Copyright (c) 2016-2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package blobserver
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/uber/kraken/core"
+	"github.com/uber/kraken/origin/blobclient"
+	"github.com/uber/kraken/utils/handler"
+	"github.com/uber/kraken/utils/httputil"
+	"github.com/uber/kraken/utils/stringset"
+)
+
+// ReplicationStatus reports where a blob is expected to be replicated and
+// where it actually is.
+type ReplicationStatus struct {
+	// Expected is the replica set of the blob, per the hash ring and the
+	// namespace replication factor.
+	Expected []string `json:"expected"`
+
+	// Holders are the origins which have the blob in their local cache.
+	Holders []string `json:"holders"`
+
+	// Missing are expected replicas which do not hold the blob.
+	Missing []string `json:"missing"`
+
+	// Extra are holders which are not expected replicas.
+	Extra []string `json:"extra"`
+
+	// Errors maps origins which could not be probed to the probe error. Such
+	// origins are neither holders nor missing.
+	Errors map[string]string `json:"errors,omitempty"`
+
+	UnderReplicated bool `json:"under_replicated"`
+	OverReplicated  bool `json:"over_replicated"`
+}
+
+// replicationStatusHandler reports the replication status of a blob across
+// all healthy origins in the hash ring. It is read-only: origins are probed
+// with local stats, which never trigger backend downloads.
+func (s *Server) replicationStatusHandler(w http.ResponseWriter, r *http.Request) error {
+	namespace, err := httputil.ParseParam(r, "namespace")
+	if err != nil {
+		return err
+	}
+	d, err := httputil.ParseDigest(r, "digest")
+	if err != nil {
+		return err
+	}
+	probes := make(chan struct{}, s.config.ReplicationStatusConcurrency)
+	status := s.replicationStatus(namespace, d, probes)
+	if err := json.NewEncoder(w).Encode(status); err != nil {
+		return handler.Errorf("json encode: %s", err)
+	}
+	return nil
+}
+
+// replicationStatus computes the replication status of d, probing origins
+// while holding a slot of probes.
+func (s *Server) replicationStatus(
+	namespace string, d core.Digest, probes chan struct{}) *ReplicationStatus {
+
+	expected := s.replicaLocations(namespace, d)
+	holders, errs := s.probeHolders(namespace, d, s.hashRing.LocationsN(d, math.MaxInt32), probes)
+
+	status := &ReplicationStatus{
+		Expected: expected,
+		Holders:  holders,
+		Missing:  []string{},
+		Extra:    []string{},
+	}
+	held := stringset.FromSlice(holders)
+	for _, addr := range expected {
+		if _, ok := errs[addr]; !ok && !held.Has(addr) {
+			status.Missing = append(status.Missing, addr)
+		}
+	}
+	want := stringset.FromSlice(expected)
+	for _, addr := range holders {
+		if !want.Has(addr) {
+			status.Extra = append(status.Extra, addr)
+		}
+	}
+	if len(errs) > 0 {
+		status.Errors = make(map[string]string, len(errs))
+		for addr, err := range errs {
+			status.Errors[addr] = err.Error()
+		}
+	}
+	status.UnderReplicated = len(status.Missing) > 0
+	status.OverReplicated = len(status.Extra) > 0
+	return status
+}
+
+// probeHolders checks which of addrs have d in their local cache, probing at
+// most cap(probes) origins at once. probes may be shared with concurrent
+// callers to bound their combined fan-out. Returns the sorted holders and the
+// errors of origins which could not be probed.
+func (s *Server) probeHolders(
+	namespace string, d core.Digest, addrs []string, probes chan struct{}) ([]string, map[string]error) {
+
+	var mu sync.Mutex
+	holders := []string{}
+	errs := make(map[string]error)
+
+	var wg sync.WaitGroup
+	for _, addr := range addrs {
+		wg.Add(1)
+		probes <- struct{}{}
+		go func(addr string) {
+			defer func() {
+				<-probes
+				wg.Done()
+			}()
+			ok, err := s.holdsLocally(namespace, d, addr)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs[addr] = err
+			} else if ok {
+				holders = append(holders, addr)
+			}
+		}(addr)
+	}
+	wg.Wait()
+
+	sort.Strings(holders)
+	return holders, errs
+}
+
+func (s *Server) holdsLocally(namespace string, d core.Digest, addr string) (bool, error) {
+	if addr == s.addr {
+		_, err := s.cas.GetCacheFileStat(d.Hex())
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return err == nil, err
+	}
+	_, err := s.clientProvider.Provide(addr).StatLocal(namespace, d)
+	if errors.Is(err, blobclient.ErrBlobNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
@@ -46,7 +46,9 @@ import (
 	"github.com/uber/kraken/utils/httputil"
 	"github.com/uber/kraken/utils/listener"
 	"github.com/uber/kraken/utils/log"
+	"github.com/uber/kraken/utils/rwutil"
 	"github.com/uber/kraken/utils/stringset"
+	"go.uber.org/atomic"
 )
 
 // Server defines a server that serves blob data for agent.
@@ -65,6 +67,15 @@ type Server struct {
 	metaInfoGenerator *metainfogen.Generator
 	uploader          *uploader
 	writeBackManager  persistedretry.Manager
+	copyBuffers       *rwutil.BufferPool
+
+	replicationsInFlight *atomic.Int64
+
+	namespaceReplication []namespaceReplication
+
+	// repairs holds the latest replication repair of each namespace.
+	repairsMu sync.Mutex
+	repairs   map[string]*repairJob
 
 	// This is an unfortunate coupling between the p2p client and the blob server.
 	// Tracker queries the origin cluster to discover which origins can seed
@@ -90,11 +101,19 @@ func New(
 	writeBackManager persistedretry.Manager,
 ) (*Server, error) {
 	config = config.applyDefaults()
+	if err := config.validateConcurrency(); err != nil {
+		return nil, fmt.Errorf("config: %s", err)
+	}
 
 	stats = stats.Tagged(map[string]string{
 		"module": "blobserver",
 	})
 
+	nrs, err := compileNamespaceReplication(config.NamespaceReplication)
+	if err != nil {
+		return nil, fmt.Errorf("namespace replication: %s", err)
+	}
+
 	return &Server{
 		config:            config,
 		stats:             stats,
@@ -110,7 +129,12 @@ func New(
 		metaInfoGenerator: metaInfoGenerator,
 		uploader:          newUploader(cas),
 		writeBackManager:  writeBackManager,
+		copyBuffers:       rwutil.NewBufferPool(int(config.CopyBufferSize)),
 		pctx:              pctx,
+
+		namespaceReplication: nrs,
+		replicationsInFlight: atomic.NewInt64(0),
+		repairs:              make(map[string]*repairJob),
 	}, nil
 }
 
@@ -133,14 +157,18 @@ func (s *Server) Handler() http.Handler {
 
 	r.Get("/blobs/{digest}/locations", handler.Wrap(s.getLocationsHandler))
 
-	r.Post("/namespace/{namespace}/blobs/{digest}/uploads", handler.Wrap(s.startClusterUploadHandler))
-	r.Patch("/namespace/{namespace}/blobs/{digest}/uploads/{uid}", handler.Wrap(s.patchClusterUploadHandler))
-	r.Put("/namespace/{namespace}/blobs/{digest}/uploads/{uid}", handler.Wrap(s.commitClusterUploadHandler))
+	r.Post("/namespace/{namespace}/blobs/{digest}/uploads", handler.Wrap(s.namespaced(s.startClusterUploadHandler)))
+	r.Get("/namespace/{namespace}/blobs/{digest}/uploads/{uid}", handler.Wrap(s.namespaced(s.getClusterUploadStatusHandler)))
+	r.Patch("/namespace/{namespace}/blobs/{digest}/uploads/{uid}", handler.Wrap(s.namespaced(s.patchClusterUploadHandler)))
+	r.Put("/namespace/{namespace}/blobs/{digest}/uploads/{uid}", handler.Wrap(s.namespaced(s.commitClusterUploadHandler)))
 
-	r.Get("/namespace/{namespace}/blobs/{digest}", handler.Wrap(s.downloadBlobHandler))
-	r.Post("/namespace/{namespace}/blobs/{digest}/prefetch", handler.Wrap(s.prefetchBlobHandler))
+	r.Get("/namespace/{namespace}/blobs/{digest}", handler.Wrap(s.namespaced(s.downloadBlobHandler)))
+	r.Post("/namespace/{namespace}/blobs/{digest}/prefetch", handler.Wrap(s.namespaced(s.prefetchBlobHandler)))
+	r.Get("/namespace/{namespace}/blobs/{digest}/replication", handler.Wrap(s.namespaced(s.replicationStatusHandler)))
+	r.Post("/namespace/{namespace}/repair", handler.Wrap(s.namespaced(s.repairReplicationHandler)))
+	r.Get("/namespace/{namespace}/repair", handler.Wrap(s.namespaced(s.getRepairReplicationHandler)))
 
-	r.Post("/namespace/{namespace}/blobs/{digest}/remote/{remote}", handler.Wrap(s.replicateToRemoteHandler))
+	r.Post("/namespace/{namespace}/blobs/{digest}/remote/{remote}", handler.Wrap(s.namespaced(s.replicateToRemoteHandler)))
 
 	r.Post("/forcecleanup", handler.Wrap(s.forceCleanupHandler))
 
@@ -156,19 +184,43 @@ func (s *Server) Handler() http.Handler {
 
 	r.Get("/internal/peercontext", handler.Wrap(s.getPeerContextHandler))
 
-	r.Head("/internal/namespace/{namespace}/blobs/{digest}", handler.Wrap(s.statHandler))
+	r.Head("/internal/namespace/{namespace}/blobs/{digest}", handler.Wrap(s.namespaced(s.statHandler)))
 
-	r.Get("/internal/namespace/{namespace}/blobs/{digest}/metainfo", handler.Wrap(s.getMetaInfoHandler))
+	r.Get("/internal/namespace/{namespace}/blobs/{digest}/metainfo", handler.Wrap(s.namespaced(s.getMetaInfoHandler)))
 
 	r.Put(
 		"/internal/duplicate/namespace/{namespace}/blobs/{digest}/uploads/{uid}",
-		handler.Wrap(s.duplicateCommitClusterUploadHandler))
+		handler.Wrap(s.namespaced(s.duplicateCommitClusterUploadHandler)))
 
 	r.Mount("/", http.DefaultServeMux) // Serves /debug/pprof endpoints.
 
 	return r
 }
 
+// namespaced wraps h to reject requests with 404 when the "namespace" param
+// matches no configured backend, before any storage is touched. No-op unless
+// RejectUnknownNamespaces is set.
+func (s *Server) namespaced(h handler.ErrHandler) handler.ErrHandler {
+	if !s.config.RejectUnknownNamespaces {
+		return h
+	}
+	return func(w http.ResponseWriter, r *http.Request) error {
+		namespace, err := httputil.ParseParam(r, "namespace")
+		if err != nil {
+			return err
+		}
+		if _, err := s.backends.GetClient(namespace); err == backend.ErrNamespaceNotFound {
+			s.metrics.unknownNamespaces.Inc(1)
+			return handler.Errorf(
+				"namespace %q is not configured, configured namespaces: [%s]",
+				namespace, strings.Join(s.backends.Namespaces(), ", ")).Status(http.StatusNotFound)
+		} else if err != nil {
+			return handler.Errorf("get backend client: %s", err)
+		}
+		return h(w, r)
+	}
+}
+
 // ListenAndServe is a blocking call which runs s.
 func (s *Server) ListenAndServe(h http.Handler) error {
 	log.Infof("Starting blob server on %s", s.config.Listener)
@@ -257,6 +309,14 @@ func (s *Server) downloadBlobHandler(w http.ResponseWriter, r *http.Request) err
 	if err != nil {
 		return err
 	}
+	if s.config.PresignedRedirect.Enabled {
+		if url, ok := s.presignedDownloadURL(r, namespace, d); ok {
+			s.metrics.presignedRedirects.Inc(1)
+			log.With("namespace", namespace, "digest", d.Hex()).Info("Redirecting blob download to backend")
+			http.Redirect(w, r, url, http.StatusTemporaryRedirect)
+			return nil
+		}
+	}
 	log.With("namespace", namespace, "digest", d.Hex()).Info("Starting blob download")
 	if err := s.downloadBlob(namespace, d, w); err != nil {
 		log.With("namespace", namespace, "digest", d.Hex(), "error", err).
@@ -268,6 +328,31 @@ func (s *Server) downloadBlobHandler(w http.ResponseWriter, r *http.Request) err
 	return nil
 }
 
+// presignedDownloadURL returns a presigned backend url for d if d is large,
+// not cached locally, and the backend of namespace supports presigning. Any
+// failure falls back to proxying the blob.
+func (s *Server) presignedDownloadURL(r *http.Request, namespace string, d core.Digest) (string, bool) {
+	if _, err := s.cas.GetCacheFileStat(d.Hex()); !os.IsNotExist(err) {
+		return "", false
+	}
+	client, err := s.backends.GetClient(namespace)
+	if err != nil {
+		return "", false
+	}
+	bi, err := client.Stat(namespace, d.Hex())
+	if err != nil || bi.Size < int64(s.config.PresignedRedirect.MinBlobSize) {
+		return "", false
+	}
+	url, err := backend.PresignDownload(r.Context(), client, d.Hex(), s.config.PresignedRedirect.TTL)
+	if err != nil {
+		if err != backenderrors.ErrPresignNotSupported {
+			log.With("namespace", namespace, "digest", d.Hex()).Errorf("Error presigning download: %s", err)
+		}
+		return "", false
+	}
+	return url, true
+}
+
 // prefetchBlobHandler is an idempotent operation that preheats the origin's cache with the given blob.
 // If the blob is not present, it is downloaded asynchronously and "202 Accepted" is returned.
 // If the blob is already present, "200 OK" is returned.
@@ -365,6 +450,8 @@ func (s *Server) getLocationsHandler(w http.ResponseWriter, r *http.Request) err
 	if err != nil {
 		return err
 	}
+	// Locations are requested without a namespace, so they span the hash
+	// ring's max_replica origins regardless of namespace replication factors.
 	locs := s.hashRing.Locations(d)
 	w.Header().Set("Origin-Locations", strings.Join(locs, ","))
 	w.WriteHeader(http.StatusOK)
@@ -461,14 +548,15 @@ func (s *Server) getMetaInfo(namespace string, d core.Digest) ([]byte, error) {
 }
 
 type localReplicationHook struct {
-	server *Server
+	server    *Server
+	namespace string
 }
 
 func (h *localReplicationHook) Run(d core.Digest) {
 	start := time.Now()
 	log.With("digest", d.Hex()).Info("Starting local replication")
 	timer := h.server.metrics.replicateBlobTimer.Start()
-	if err := h.server.replicateBlobLocally(d); err != nil {
+	if err := h.server.replicateBlobLocally(h.namespace, d); err != nil {
 		// Don't return error here as we only want to cache storage backend errors.
 		duration := time.Since(start)
 		log.With("digest", d.Hex(), "duration_s", duration.Seconds()).Errorf("Error replicating remote blob: %s", err)
@@ -486,7 +574,7 @@ func (s *Server) startRemoteBlobDownload(
 	log.With("namespace", namespace, "digest", d.Hex(), "replicate_locally", replicateLocally).Info("Initiating remote blob download")
 	var hooks []blobrefresh.PostHook
 	if replicateLocally {
-		hooks = append(hooks, &localReplicationHook{s})
+		hooks = append(hooks, &localReplicationHook{s, namespace})
 	}
 	err := s.blobRefresher.Refresh(namespace, d, hooks...)
 	switch err {
@@ -505,7 +593,7 @@ func (s *Server) startRemoteBlobDownload(
 	}
 }
 
-func (s *Server) replicateBlobLocally(d core.Digest) error {
+func (s *Server) replicateBlobLocally(namespace string, d core.Digest) error {
 	fi, err := s.cas.GetCacheFileStat(d.Hex())
 	var blobSize int64
 	if err == nil {
@@ -513,7 +601,7 @@ func (s *Server) replicateBlobLocally(d core.Digest) error {
 	}
 
 	log.With("digest", d.Hex(), "size_bytes", blobSize).Debug("Starting replication to local replicas")
-	return s.applyToReplicas(d, func(i int, client blobclient.Client) error {
+	return s.applyToReplicas(namespace, d, func(i int, client blobclient.Client) error {
 		start := time.Now()
 		f, err := s.cas.GetCacheFileReader(d.Hex())
 		if err != nil {
@@ -531,10 +619,13 @@ func (s *Server) replicateBlobLocally(d core.Digest) error {
 	})
 }
 
-// applyToReplicas applies f to the replicas of d concurrently in random order,
-// not including the current origin. Passes the index of the iteration to f.
-func (s *Server) applyToReplicas(d core.Digest, f func(i int, c blobclient.Client) error) error {
-	replicas := stringset.FromSlice(s.hashRing.Locations(d))
+// applyToReplicas applies f to the replicas of d in namespace concurrently in
+// random order, not including the current origin. Passes the index of the
+// iteration to f.
+func (s *Server) applyToReplicas(
+	namespace string, d core.Digest, f func(i int, c blobclient.Client) error) error {
+
+	replicas := stringset.FromSlice(s.replicaLocations(namespace, d))
 	replicas.Remove(s.addr)
 
 	var mu sync.Mutex
@@ -577,7 +668,7 @@ func (s *Server) downloadBlob(namespace string, d core.Digest, dst io.Writer) er
 	}
 	defer closers.Close(f)
 
-	if _, err := io.Copy(dst, f); err != nil {
+	if _, err := s.copyBuffers.Copy(dst, f); err != nil {
 		log.With("namespace", namespace, "digest", d.Hex(), "error", fmt.Sprintf("Failed to copy blob data: %s", err)).
 			Error("Download blob failure")
 		return handler.Errorf("copy blob: %s", err)
@@ -703,7 +794,9 @@ func (s *Server) handleUploadConflict(err error, namespace string, d core.Digest
 	return err
 }
 
-// startClusterUploadHandler initializes an upload for external uploads.
+// startClusterUploadHandler initializes an upload for external uploads. If
+// "mount=<digest>&from=<namespace>" query args are given and the blob exists,
+// it is mounted from the other namespace with a 201 instead.
 func (s *Server) startClusterUploadHandler(w http.ResponseWriter, r *http.Request) error {
 	d, err := httputil.ParseDigest(r, "digest")
 	if err != nil {
@@ -713,6 +806,21 @@ func (s *Server) startClusterUploadHandler(w http.ResponseWriter, r *http.Reques
 	if err != nil {
 		return err
 	}
+	from, err := parseMount(r, d)
+	if err != nil {
+		return err
+	}
+	if from != "" {
+		mounted, err := s.mountBlob(namespace, from, d)
+		if err != nil {
+			return err
+		}
+		if mounted {
+			w.WriteHeader(http.StatusCreated)
+			return nil
+		}
+		log.With("namespace", namespace, "from", from, "digest", d.Hex()).Info("Blob not found for mount, falling back to upload")
+	}
 	log.With("namespace", namespace, "digest", d.Hex()).Info("Starting cluster upload")
 	uid, err := s.uploader.start(d)
 	if err != nil {
@@ -752,6 +860,26 @@ func (s *Server) patchClusterUploadHandler(w http.ResponseWriter, r *http.Reques
 	return nil
 }
 
+// getClusterUploadStatusHandler returns the offset an external upload should
+// continue from in the Upload-Offset header, e.g. to resume it after a restart.
+func (s *Server) getClusterUploadStatusHandler(w http.ResponseWriter, r *http.Request) error {
+	d, err := httputil.ParseDigest(r, "digest")
+	if err != nil {
+		return err
+	}
+	uid, err := httputil.ParseParam(r, "uid")
+	if err != nil {
+		return err
+	}
+	offset, err := s.uploader.status(d, uid)
+	if err != nil {
+		return err
+	}
+	setUploadLocation(w, uid)
+	w.Header().Set("Upload-Offset", strconv.FormatInt(offset, 10))
+	return nil
+}
+
 // commitClusterUploadHandler commits an external blob upload asynchronously,
 // meaning the blob will be written back to remote storage in a non-blocking
 // fashion.
@@ -787,7 +915,7 @@ func (s *Server) commitClusterUploadHandler(w http.ResponseWriter, r *http.Reque
 
 	replicateStart := time.Now()
 	log.With("namespace", namespace, "digest", d.Hex(), "size_bytes", blobSize).Debug("Replicating upload to other origins")
-	err = s.applyToReplicas(d, func(i int, client blobclient.Client) error {
+	err = s.applyToReplicas(namespace, d, func(i int, client blobclient.Client) error {
 		replicaStart := time.Now()
 		delay := s.config.DuplicateWriteBackStagger * time.Duration(i+1)
 		f, err := s.cas.GetCacheFileReader(d.Hex())
@@ -913,6 +1041,9 @@ func (s *Server) maybeDelete(name string, ttl time.Duration) (deleted bool, err
 		return false, fmt.Errorf("store: %s", err)
 	}
 	expired := s.clk.Now().Sub(info.ModTime()) > ttl
+	// Cache files do not record their namespace, so ownership is judged by
+	// the hash ring's max_replica origins rather than a namespace's replica
+	// set.
 	owns := stringset.FromSlice(s.hashRing.Locations(d)).Has(s.addr)
 	if expired || !owns {
 		log.With("digest", name, "expired", expired, "owns", owns).Debug("Candidate for cleanup")
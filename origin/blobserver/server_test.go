@@ -15,11 +15,16 @@ package blobserver
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"os"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -31,7 +36,9 @@ import (
 	"github.com/uber/kraken/lib/backend/backenderrors"
 	"github.com/uber/kraken/lib/persistedretry"
 	"github.com/uber/kraken/lib/persistedretry/writeback"
+	"github.com/uber/kraken/lib/store"
 	"github.com/uber/kraken/lib/store/metadata"
+	mockbackend "github.com/uber/kraken/mocks/lib/backend"
 	"github.com/uber/kraken/origin/blobclient"
 	"github.com/uber/kraken/utils/httputil"
 	"github.com/uber/kraken/utils/mockutil"
@@ -269,6 +276,197 @@ func TestDownloadBlobNotFound(t *testing.T) {
 	require.Equal(http.StatusNotFound, statusErr.Status)
 }
 
+func TestRejectUnknownNamespaces(t *testing.T) {
+	require := require.New(t)
+
+	cp := newTestClientProvider()
+
+	s := newTestServerWithConfig(
+		t, Config{RejectUnknownNamespaces: true}, master1, hashRingMaxReplica(), cp)
+	defer s.cleanup()
+
+	d := core.DigestFixture()
+	known := core.TagFixture()
+	s.backendClient(known, false)
+
+	// The unknown namespace is rejected without consulting any backend.
+	_, err := httputil.Get(fmt.Sprintf("http://%s/namespace/unknown/blobs/%s", s.addr, d))
+	require.Error(err)
+	statusErr, ok := err.(httputil.StatusError)
+	require.True(ok, "expected httputil.StatusError")
+	require.Equal(http.StatusNotFound, statusErr.Status)
+	require.Contains(statusErr.ResponseDump, `namespace "unknown" is not configured`)
+	require.Contains(statusErr.ResponseDump, known)
+}
+
+func TestResumeClusterUpload(t *testing.T) {
+	require := require.New(t)
+
+	cp := newTestClientProvider()
+
+	s := newTestServerWithStoreConfig(
+		t, Config{}, store.CAStoreConfig{ResumableUploads: true}, master1, hashRingMaxReplica(), cp)
+	defer s.cleanup()
+
+	namespace := core.TagFixture()
+	blob := core.SizedBlobFixture(8, 1)
+	base := fmt.Sprintf(
+		"http://%s/namespace/%s/blobs/%s/uploads", s.addr, url.PathEscape(namespace), blob.Digest)
+
+	resp, err := httputil.Post(base)
+	require.NoError(err)
+	uid := resp.Header.Get("Location")
+
+	patch := func(start, end int64) error {
+		_, err := httputil.Patch(
+			base+"/"+uid,
+			httputil.SendBody(bytes.NewReader(blob.Content[start:end])),
+			httputil.SendHeaders(map[string]string{
+				"Content-Range": fmt.Sprintf("%d-%d", start, end),
+			}))
+		return err
+	}
+	offset := func() string {
+		resp, err := httputil.Get(base + "/" + uid)
+		require.NoError(err)
+		return resp.Header.Get("Upload-Offset")
+	}
+
+	require.Equal("0", offset())
+	require.NoError(patch(0, 4))
+	require.Equal("4", offset())
+
+	// Chunks may not skip ahead of the offset.
+	err = patch(6, 8)
+	require.True(httputil.IsStatus(err, http.StatusRequestedRangeNotSatisfiable))
+
+	require.NoError(patch(4, 8))
+	require.Equal("8", offset())
+
+	_, err = httputil.Get(base + "/unknown")
+	require.True(httputil.IsNotFound(err))
+}
+
+func TestClusterUploadWithoutResumableUploads(t *testing.T) {
+	require := require.New(t)
+
+	cp := newTestClientProvider()
+
+	s := newTestServer(t, master1, hashRingMaxReplica(), cp)
+	defer s.cleanup()
+
+	namespace := core.TagFixture()
+	blob := core.SizedBlobFixture(8, 1)
+	base := fmt.Sprintf(
+		"http://%s/namespace/%s/blobs/%s/uploads", s.addr, url.PathEscape(namespace), blob.Digest)
+
+	resp, err := httputil.Post(base)
+	require.NoError(err)
+	uid := resp.Header.Get("Location")
+
+	// Chunks are written where they start, without tracking an offset.
+	for _, r := range [][2]int64{{4, 8}, {0, 4}} {
+		_, err := httputil.Patch(
+			base+"/"+uid,
+			httputil.SendBody(bytes.NewReader(blob.Content[r[0]:r[1]])),
+			httputil.SendHeaders(map[string]string{
+				"Content-Range": fmt.Sprintf("%d-%d", r[0], r[1]),
+			}))
+		require.NoError(err)
+	}
+
+	_, err = s.cas.GetUploadSession(uid)
+	require.True(os.IsNotExist(err))
+
+	_, err = httputil.Get(base + "/" + uid)
+	require.True(httputil.IsNotFound(err))
+}
+
+type presigningClient struct {
+	*mockbackend.MockClient
+	url string
+}
+
+func (c *presigningClient) PresignDownload(
+	ctx context.Context, name string, ttl time.Duration) (string, error) {
+
+	return c.url, nil
+}
+
+func TestDownloadBlobPresignedRedirect(t *testing.T) {
+	require := require.New(t)
+
+	cp := newTestClientProvider()
+
+	config := Config{PresignedRedirect: PresignedRedirectConfig{Enabled: true, MinBlobSize: 8}}
+	s := newTestServerWithConfig(t, config, master1, hashRingMaxReplica(), cp)
+	defer s.cleanup()
+
+	namespace := core.TagFixture()
+	large := core.DigestFixture()
+	small := core.DigestFixture()
+
+	client := &presigningClient{mockbackend.NewMockClient(s.ctrl), "http://backend/presigned"}
+	require.NoError(s.backendManager.Register(namespace, client, false))
+
+	client.EXPECT().Stat(namespace, large.Hex()).Return(core.NewBlobInfo(8), nil)
+	client.EXPECT().Stat(namespace, small.Hex()).Return(core.NewBlobInfo(7), nil).MinTimes(1)
+	client.EXPECT().Download(namespace, small.Hex(), gomock.Any()).Return(nil).AnyTimes()
+
+	noRedirect := &http.Client{
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+
+	resp, err := noRedirect.Get(fmt.Sprintf("http://%s/namespace/%s/blobs/%s", s.addr, url.PathEscape(namespace), large))
+	require.NoError(err)
+	require.NoError(resp.Body.Close())
+	require.Equal(http.StatusTemporaryRedirect, resp.StatusCode)
+	require.Equal("http://backend/presigned", resp.Header.Get("Location"))
+
+	// Blobs under the size threshold are proxied.
+	resp, err = noRedirect.Get(fmt.Sprintf("http://%s/namespace/%s/blobs/%s", s.addr, url.PathEscape(namespace), small))
+	require.NoError(err)
+	require.NoError(resp.Body.Close())
+	require.Equal(http.StatusAccepted, resp.StatusCode)
+}
+
+func TestDownloadBlobPresignedRedirectNotSupported(t *testing.T) {
+	require := require.New(t)
+
+	cp := newTestClientProvider()
+
+	config := Config{PresignedRedirect: PresignedRedirectConfig{Enabled: true, MinBlobSize: 1}}
+	s := newTestServerWithConfig(t, config, master1, hashRingMaxReplica(), cp)
+	defer s.cleanup()
+
+	d := core.DigestFixture()
+	namespace := core.TagFixture()
+
+	backendClient := s.backendClient(namespace, false)
+	backendClient.EXPECT().Stat(namespace, d.Hex()).Return(nil, backenderrors.ErrBlobNotFound).MinTimes(1)
+
+	// Falls back to proxying, which fails since the blob does not exist.
+	err := cp.Provide(master1).DownloadBlob(namespace, d, io.Discard)
+	require.Error(err)
+	require.True(httputil.IsNotFound(err))
+}
+
+func TestDownloadBlobWithCopyBufferSize(t *testing.T) {
+	require := require.New(t)
+
+	cp := newTestClientProvider()
+
+	s := newTestServerWithConfig(t, Config{CopyBufferSize: 64}, master1, hashRingMaxReplica(), cp)
+	defer s.cleanup()
+
+	blob := core.SizedBlobFixture(1000, 100)
+	namespace := core.TagFixture()
+
+	require.NoError(s.cas.CreateCacheFile(blob.Digest.Hex(), bytes.NewReader(blob.Content)))
+
+	ensureHasBlob(t, cp.Provide(master1), namespace, blob)
+}
+
 func TestDeleteBlob(t *testing.T) {
 	require := require.New(t)
 
@@ -321,6 +519,255 @@ func TestGetLocationsOK(t *testing.T) {
 	require.ElementsMatch([]string{master1, master2}, locs)
 }
 
+func getReplicationStatus(
+	t *testing.T, addr, namespace string, d core.Digest) *ReplicationStatus {
+
+	resp, err := httputil.Get(fmt.Sprintf(
+		"http://%s/namespace/%s/blobs/%s/replication", addr, url.PathEscape(namespace), d))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	var status ReplicationStatus
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&status))
+	return &status
+}
+
+func TestReplicationStatus(t *testing.T) {
+	ring := hashRingSomeReplica()
+	blob := computeBlobForHosts(ring, master1, master2)
+
+	tests := []struct {
+		desc            string
+		holders         []string
+		missing         []string
+		extra           []string
+		underReplicated bool
+		overReplicated  bool
+	}{
+		{"fully replicated", []string{master1, master2}, []string{}, []string{}, false, false},
+		{"under replicated", []string{master1}, []string{master2}, []string{}, true, false},
+		{"over replicated", []string{master1, master2, master3}, []string{}, []string{master3}, false, true},
+		{"under and over replicated", []string{master3}, []string{master1, master2}, []string{master3}, true, true},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			require := require.New(t)
+
+			cp := newTestClientProvider()
+			namespace := core.TagFixture()
+
+			servers := make(map[string]*testServer)
+			for _, host := range []string{master1, master2, master3} {
+				s := newTestServer(t, host, ring, cp)
+				defer s.cleanup()
+				servers[host] = s
+			}
+			for _, host := range test.holders {
+				require.NoError(servers[host].cas.CreateCacheFile(
+					blob.Digest.Hex(), bytes.NewReader(blob.Content)))
+			}
+
+			status := getReplicationStatus(t, servers[master1].addr, namespace, blob.Digest)
+			require.ElementsMatch([]string{master1, master2}, status.Expected)
+			require.ElementsMatch(test.holders, status.Holders)
+			require.ElementsMatch(test.missing, status.Missing)
+			require.ElementsMatch(test.extra, status.Extra)
+			require.Empty(status.Errors)
+			require.Equal(test.underReplicated, status.UnderReplicated)
+			require.Equal(test.overReplicated, status.OverReplicated)
+		})
+	}
+}
+
+func TestReplicationStatusUnreachableOrigin(t *testing.T) {
+	require := require.New(t)
+
+	ring := hashRingSomeReplica()
+	cp := newTestClientProvider()
+	namespace := core.TagFixture()
+
+	s1 := newTestServer(t, master1, ring, cp)
+	defer s1.cleanup()
+
+	s2 := newTestServer(t, master2, ring, cp)
+	defer s2.cleanup()
+
+	// Nothing listens on master3.
+	cp.register(master3, blobclient.New("localhost:0"))
+
+	blob := computeBlobForHosts(ring, master1, master3)
+	require.NoError(s1.cas.CreateCacheFile(blob.Digest.Hex(), bytes.NewReader(blob.Content)))
+
+	status := getReplicationStatus(t, s1.addr, namespace, blob.Digest)
+	require.Equal([]string{master1}, status.Holders)
+	require.Empty(status.Missing)
+	require.Contains(status.Errors, master3)
+	require.False(status.UnderReplicated)
+}
+
+func TestReplicationStatusRespectsNamespaceReplication(t *testing.T) {
+	require := require.New(t)
+
+	ring := hashRingMaxReplica()
+	cp := newTestClientProvider()
+	namespace := "single-replica"
+
+	config := Config{
+		NamespaceReplication: []NamespaceReplicationConfig{{Namespace: "^single-", Factor: 1}},
+	}
+	servers := make(map[string]*testServer)
+	for _, host := range []string{master1, master2, master3} {
+		s := newTestServerWithConfig(t, config, host, ring, cp)
+		defer s.cleanup()
+		servers[host] = s
+	}
+	blob := core.SizedBlobFixture(32, 4)
+	primary := ring.LocationsN(blob.Digest, 1)[0]
+	for _, s := range servers {
+		require.NoError(s.cas.CreateCacheFile(blob.Digest.Hex(), bytes.NewReader(blob.Content)))
+	}
+
+	status := getReplicationStatus(t, servers[master1].addr, namespace, blob.Digest)
+	require.Equal([]string{primary}, status.Expected)
+	require.Len(status.Holders, 3)
+	require.Len(status.Extra, 2)
+	require.False(status.UnderReplicated)
+	require.True(status.OverReplicated)
+}
+
+func TestPlanRepairs(t *testing.T) {
+	require := require.New(t)
+
+	d1 := core.DigestFixture()
+	d2 := core.DigestFixture()
+	d3 := core.DigestFixture()
+
+	tasks := planRepairs(map[core.Digest]*ReplicationStatus{
+		d1: {Holders: []string{master1, master2}, Missing: []string{master3}},
+		d2: {Holders: []string{master1}, Missing: []string{master2, master3}},
+		d3: {Holders: []string{}, Missing: []string{master1}},
+	})
+	require.Equal([]repairTask{
+		{d2, 1, master1, master2},
+		{d2, 1, master1, master3},
+		{d1, 2, master2, master3},
+	}, tasks)
+}
+
+func TestDispatchRepairsSkipsBusySources(t *testing.T) {
+	require := require.New(t)
+
+	d := core.DigestFixture()
+
+	// Tasks from master1 block until the task from master2, which is queued
+	// behind them, runs.
+	tasks := []repairTask{
+		{d, 1, master1, master2},
+		{d, 1, master1, master3},
+		{d, 1, master1, master2},
+		{d, 1, master2, master3},
+	}
+	config := ReplicationRepairConfig{Parallelism: 3, PerSourceConcurrency: 1}
+
+	release := make(chan struct{})
+	var mu sync.Mutex
+	var timeouts, maxPerSource int
+	inFlight := make(map[string]int)
+	dispatchRepairs(config, tasks, func(t repairTask) {
+		mu.Lock()
+		inFlight[t.source]++
+		maxPerSource = max(maxPerSource, inFlight[t.source])
+		mu.Unlock()
+
+		if t.source == master1 {
+			select {
+			case <-release:
+			case <-time.After(5 * time.Second):
+				mu.Lock()
+				timeouts++
+				mu.Unlock()
+			}
+		} else {
+			close(release)
+		}
+
+		mu.Lock()
+		inFlight[t.source]--
+		mu.Unlock()
+	})
+
+	require.Zero(timeouts)
+	require.Equal(1, maxPerSource)
+}
+
+func TestRepairReplication(t *testing.T) {
+	require := require.New(t)
+
+	ring := hashRingSomeReplica()
+	cp := newTestClientProvider()
+	namespace := core.TagFixture()
+
+	servers := make(map[string]*testServer)
+	for _, host := range []string{master1, master2, master3} {
+		s := newTestServer(t, host, ring, cp)
+		defer s.cleanup()
+		servers[host] = s
+	}
+
+	// Both blobs are missing from master2. local can only be sourced from
+	// master1, while remote can be sourced from master1 or master3.
+	local := computeBlobForHosts(ring, master1, master2)
+	remote := computeBlobForHosts(ring, master2, master3)
+	require.NoError(servers[master1].cas.CreateCacheFile(
+		local.Digest.Hex(), bytes.NewReader(local.Content)))
+	for _, host := range []string{master1, master3} {
+		require.NoError(servers[host].cas.CreateCacheFile(
+			remote.Digest.Hex(), bytes.NewReader(remote.Content)))
+	}
+
+	// other belongs to another namespace, so it is not repaired.
+	other := computeBlobForHosts(ring, master1, master2)
+	require.NoError(servers[master1].cas.CreateCacheFile(
+		other.Digest.Hex(), bytes.NewReader(other.Content)))
+
+	backendClient := servers[master1].backendClient(namespace, false)
+	for _, blob := range []*core.BlobFixture{local, remote} {
+		backendClient.EXPECT().Stat(namespace, blob.Digest.Hex()).Return(blob.Info(), nil)
+	}
+	backendClient.EXPECT().Stat(namespace, other.Digest.Hex()).Return(nil, backenderrors.ErrBlobNotFound)
+
+	repairURL := fmt.Sprintf(
+		"http://%s/namespace/%s/repair", servers[master1].addr, url.PathEscape(namespace))
+
+	resp, err := httputil.Post(repairURL, httputil.SendAcceptedCodes(http.StatusAccepted))
+	require.NoError(err)
+	defer resp.Body.Close()
+
+	var job repairJob
+	require.NoError(json.NewDecoder(resp.Body).Decode(&job))
+	require.Equal(repairRunning, job.State)
+
+	require.NoError(testutil.PollUntilTrue(5*time.Second, func() bool {
+		resp, err := httputil.Get(repairURL)
+		require.NoError(err)
+		defer resp.Body.Close()
+		require.NoError(json.NewDecoder(resp.Body).Decode(&job))
+		return job.State == repairDone
+	}))
+	require.Equal(2, job.Transfers)
+	require.Equal(2, job.Repaired)
+	require.Empty(job.Errors)
+	require.NotNil(job.FinishedAt)
+
+	for _, blob := range []*core.BlobFixture{local, remote} {
+		status := getReplicationStatus(t, servers[master1].addr, namespace, blob.Digest)
+		require.False(status.UnderReplicated)
+		ensureHasBlob(t, cp.Provide(master2), namespace, blob)
+	}
+	_, err = servers[master2].cas.GetCacheFileStat(other.Digest.Hex())
+	require.True(os.IsNotExist(err))
+}
+
 func TestGetPeerContextOK(t *testing.T) {
 	require := require.New(t)
 
@@ -700,6 +1147,222 @@ func TestUploadBlobDuplicatesWriteBackTaskToReplicas(t *testing.T) {
 	require.Error(cp.Provide(s2.host).DeleteBlob(blob.Digest))
 }
 
+func TestUploadBlobHonorsNamespaceReplicationFactor(t *testing.T) {
+	require := require.New(t)
+
+	ring := hashRingMaxReplica()
+	namespace := "production/base"
+
+	config := Config{
+		NamespaceReplication: []NamespaceReplicationConfig{
+			{Namespace: "production/.*", Factor: 3},
+		},
+	}
+
+	cp := newTestClientProvider()
+
+	s1 := newTestServerWithConfig(t, config, master1, ring, cp)
+	defer s1.cleanup()
+
+	s2 := newTestServerWithConfig(t, config, master2, ring, cp)
+	defer s2.cleanup()
+
+	s3 := newTestServerWithConfig(t, config, master3, ring, cp)
+	defer s3.cleanup()
+
+	blob := computeBlobForHosts(ring, s1.host, s2.host, s3.host)
+
+	s1.writeBackManager.EXPECT().Add(
+		writeback.MatchTask(writeback.NewTask(namespace, blob.Digest.Hex(), 0))).Return(nil)
+	s2.writeBackManager.EXPECT().Add(gomock.Any()).Return(nil)
+	s3.writeBackManager.EXPECT().Add(gomock.Any()).Return(nil)
+
+	err := cp.Provide(s1.host).UploadBlob(namespace, blob.Digest, bytes.NewReader(blob.Content))
+	require.NoError(err)
+
+	ensureHasBlob(t, cp.Provide(s2.host), namespace, blob)
+	ensureHasBlob(t, cp.Provide(s3.host), namespace, blob)
+}
+
+func TestUploadBlobNamespaceReplicationFactorLimitsReplicas(t *testing.T) {
+	require := require.New(t)
+
+	ring := hashRingSomeReplica()
+	namespace := "scratch/ci"
+
+	config := Config{
+		NamespaceReplication: []NamespaceReplicationConfig{
+			{Namespace: "scratch/.*", Factor: 1},
+		},
+	}
+
+	cp := newTestClientProvider()
+
+	s1 := newTestServerWithConfig(t, config, master1, ring, cp)
+	defer s1.cleanup()
+
+	s2 := newTestServerWithConfig(t, config, master2, ring, cp)
+	defer s2.cleanup()
+
+	// Blob whose single replica is s1.
+	var blob *core.BlobFixture
+	for blob == nil || ring.LocationsN(blob.Digest, 1)[0] != s1.host {
+		blob = computeBlobForHosts(ring, s1.host, s2.host)
+	}
+
+	// s2 receives no duplicate write-back task.
+	s1.writeBackManager.EXPECT().Add(
+		writeback.MatchTask(writeback.NewTask(namespace, blob.Digest.Hex(), 0))).Return(nil)
+
+	err := cp.Provide(s1.host).UploadBlob(namespace, blob.Digest, bytes.NewReader(blob.Content))
+	require.NoError(err)
+
+	_, err = cp.Provide(s2.host).StatLocal(namespace, blob.Digest)
+	require.Error(err)
+}
+
+func mountURL(addr, namespace, from string, d core.Digest) string {
+	return fmt.Sprintf(
+		"http://%s/namespace/%s/blobs/%s/uploads?mount=%s&from=%s",
+		addr, url.PathEscape(namespace), d, d, url.QueryEscape(from))
+}
+
+func TestMountBlobFromLocalCache(t *testing.T) {
+	require := require.New(t)
+
+	ring := hashRingNoReplica()
+	from := core.TagFixture()
+	namespace := core.TagFixture()
+
+	cp := newTestClientProvider()
+
+	s := newTestServer(t, master1, ring, cp)
+	defer s.cleanup()
+
+	blob := computeBlobForHosts(ring, s.host)
+
+	s.writeBackManager.EXPECT().Add(
+		writeback.MatchTask(writeback.NewTask(from, blob.Digest.Hex(), 0))).Return(nil)
+	s.writeBackManager.EXPECT().Add(
+		writeback.MatchTask(writeback.NewTask(namespace, blob.Digest.Hex(), 0))).Return(nil)
+
+	require.NoError(cp.Provide(s.host).UploadBlob(from, blob.Digest, bytes.NewReader(blob.Content)))
+
+	resp, err := httputil.Post(
+		mountURL(s.addr, namespace, from, blob.Digest),
+		httputil.SendAcceptedCodes(http.StatusCreated))
+	require.NoError(err)
+	require.Equal(http.StatusCreated, resp.StatusCode)
+}
+
+func TestMountBlobAlreadyInBackend(t *testing.T) {
+	require := require.New(t)
+
+	from := core.TagFixture()
+	namespace := core.TagFixture()
+
+	s := newTestServer(t, master1, hashRingNoReplica(), newTestClientProvider())
+	defer s.cleanup()
+
+	blob := core.NewBlobFixture()
+
+	backendClient := s.backendClient(namespace, false)
+	backendClient.EXPECT().Stat(namespace, blob.Digest.Hex()).Return(blob.Info(), nil)
+
+	resp, err := httputil.Post(
+		mountURL(s.addr, namespace, from, blob.Digest),
+		httputil.SendAcceptedCodes(http.StatusCreated))
+	require.NoError(err)
+	require.Equal(http.StatusCreated, resp.StatusCode)
+}
+
+func TestMountBlobFromSourceBackend(t *testing.T) {
+	require := require.New(t)
+
+	from := core.TagFixture()
+	namespace := core.TagFixture()
+
+	s := newTestServer(t, master1, hashRingNoReplica(), newTestClientProvider())
+	defer s.cleanup()
+
+	blob := core.NewBlobFixture()
+
+	s.backendClient(namespace, false).EXPECT().Stat(
+		namespace, blob.Digest.Hex()).Return(nil, backenderrors.ErrBlobNotFound)
+
+	fromClient := s.backendClient(from, false)
+	fromClient.EXPECT().Stat(from, blob.Digest.Hex()).Return(blob.Info(), nil).AnyTimes()
+	fromClient.EXPECT().Download(from, blob.Digest.Hex(), mockutil.MatchWriter(blob.Content)).Return(nil)
+
+	done := make(chan struct{})
+	s.writeBackManager.EXPECT().Add(
+		writeback.MatchTask(writeback.NewTask(namespace, blob.Digest.Hex(), 0))).DoAndReturn(
+		func(persistedretry.Task) error {
+			close(done)
+			return nil
+		})
+
+	resp, err := httputil.Post(
+		mountURL(s.addr, namespace, from, blob.Digest),
+		httputil.SendAcceptedCodes(http.StatusCreated))
+	require.NoError(err)
+	require.Equal(http.StatusCreated, resp.StatusCode)
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		require.FailNow("mounted blob was not written back")
+	}
+}
+
+func TestMountBlobNotFoundFallsBackToUpload(t *testing.T) {
+	require := require.New(t)
+
+	from := core.TagFixture()
+	namespace := core.TagFixture()
+
+	s := newTestServer(t, master1, hashRingNoReplica(), newTestClientProvider())
+	defer s.cleanup()
+
+	blob := core.NewBlobFixture()
+
+	s.backendClient(namespace, false).EXPECT().Stat(
+		namespace, blob.Digest.Hex()).Return(nil, backenderrors.ErrBlobNotFound)
+	s.backendClient(from, false).EXPECT().Stat(
+		from, blob.Digest.Hex()).Return(nil, backenderrors.ErrBlobNotFound)
+
+	resp, err := httputil.Post(mountURL(s.addr, namespace, from, blob.Digest))
+	require.NoError(err)
+	require.Equal(http.StatusOK, resp.StatusCode)
+	require.NotEmpty(resp.Header.Get("Location"))
+}
+
+func TestMountBlobInvalidParam(t *testing.T) {
+	s := newTestServer(t, master1, hashRingNoReplica(), newTestClientProvider())
+	defer s.cleanup()
+
+	d := core.DigestFixture()
+	namespace := url.PathEscape(core.TagFixture())
+
+	tests := []struct {
+		desc  string
+		query string
+	}{
+		{"digest mismatch", fmt.Sprintf("mount=%s&from=foo", core.DigestFixture())},
+		{"invalid digest", "mount=abc&from=foo"},
+		{"missing from", fmt.Sprintf("mount=%s", d)},
+		{"missing mount", "from=foo"},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			_, err := httputil.Post(fmt.Sprintf(
+				"http://%s/namespace/%s/blobs/%s/uploads?%s", s.addr, namespace, d, test.query))
+			require.Error(t, err)
+			require.True(t, httputil.IsStatus(err, http.StatusBadRequest))
+		})
+	}
+}
+
 func TestUploadBlobRetriesWriteBackFailure(t *testing.T) {
 	require := require.New(t)
 
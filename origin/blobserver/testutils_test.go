@@ -107,6 +107,21 @@ type testServer struct {
 func newTestServer(
 	t *testing.T, host string, ring hashring.Ring, cp *testClientProvider) *testServer {
 
+	return newTestServerWithConfig(t, Config{}, host, ring, cp)
+}
+
+func newTestServerWithConfig(
+	t *testing.T, config Config, host string, ring hashring.Ring, cp *testClientProvider) *testServer {
+
+	return newTestServerWithStoreConfig(t, config, store.CAStoreConfig{}, host, ring, cp)
+}
+
+// newTestServerWithStoreConfig creates a test server whose CAStore is
+// configured by storeConfig, with temporary upload and cache directories.
+func newTestServerWithStoreConfig(
+	t *testing.T, config Config, storeConfig store.CAStoreConfig,
+	host string, ring hashring.Ring, cp *testClientProvider) *testServer {
+
 	var cleanup testutil.Cleanup
 	defer cleanup.Recover()
 
@@ -117,8 +132,16 @@ func newTestServer(
 
 	pctx := core.PeerContextFixture()
 
-	cas, c := store.CAStoreFixture()
+	fixture, c := store.CAStoreConfigFixture()
 	cleanup.Add(c)
+	storeConfig.UploadDir = fixture.UploadDir
+	storeConfig.CacheDir = fixture.CacheDir
+
+	cas, err := store.NewCAStore(storeConfig, tally.NoopScope)
+	if err != nil {
+		panic(err)
+	}
+	cleanup.Add(cas.Close)
 
 	bm := backend.ManagerFixture()
 
@@ -132,7 +155,7 @@ func newTestServer(
 	clk.Set(time.Now())
 
 	s, err := New(
-		Config{}, tally.NoopScope, clk, host, ring, cas, cp, clusterProvider, pctx,
+		config, tally.NoopScope, clk, host, ring, cas, cp, clusterProvider, pctx,
 		bm, br, mg, writeBackManager)
 	if err != nil {
 		panic(err)
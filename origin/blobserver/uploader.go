@@ -21,6 +21,7 @@ import (
 	"github.com/docker/distribution/uuid"
 	"github.com/uber/kraken/core"
 	"github.com/uber/kraken/lib/store"
+	"github.com/uber/kraken/lib/store/metadata"
 	"github.com/uber/kraken/utils/closers"
 	"github.com/uber/kraken/utils/handler"
 	"github.com/uber/kraken/utils/log"
@@ -29,10 +30,14 @@ import (
 // uploader executes a chunked upload.
 type uploader struct {
 	cas *store.CAStore
+
+	// resumable enables upload sessions, which track the offset an upload
+	// continues from.
+	resumable bool
 }
 
 func newUploader(cas *store.CAStore) *uploader {
-	return &uploader{cas}
+	return &uploader{cas, cas.ResumableUploads()}
 }
 
 func (u *uploader) start(d core.Digest) (uid string, err error) {
@@ -48,10 +53,54 @@ func (u *uploader) start(d core.Digest) (uid string, err error) {
 		log.With("digest", d.Hex(), "uid", uid).Errorf("Failed to create upload file: %s", err)
 		return "", handler.Errorf("create upload file: %s", err)
 	}
+	if u.resumable {
+		if err := u.cas.SetUploadSession(uid, metadata.NewUploadSession(d.String(), 0)); err != nil {
+			log.With("digest", d.Hex(), "uid", uid).Errorf("Failed to set upload session: %s", err)
+			return "", handler.Errorf("set upload session: %s", err)
+		}
+	}
 	log.With("digest", d.Hex(), "uid", uid).Debug("Created upload file")
 	return uid, nil
 }
 
+// status returns the offset an upload should continue from.
+func (u *uploader) status(d core.Digest, uid string) (offset int64, err error) {
+	session, err := u.session(d, uid)
+	if err != nil {
+		return 0, err
+	}
+	if session == nil {
+		return 0, handler.ErrorStatus(http.StatusNotFound)
+	}
+	return session.Offset, nil
+}
+
+// session returns the session of upload uid, or nil if the upload predates
+// sessions or uploads are not resumable.
+func (u *uploader) session(d core.Digest, uid string) (*metadata.UploadSession, error) {
+	if !u.resumable {
+		return nil, nil
+	}
+	if _, err := u.cas.GetUploadFileStat(uid); err != nil {
+		if os.IsNotExist(err) {
+			return nil, handler.ErrorStatus(http.StatusNotFound)
+		}
+		return nil, handler.Errorf("stat upload file: %s", err)
+	}
+	session, err := u.cas.GetUploadSession(uid)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, handler.Errorf("get upload session: %s", err)
+	}
+	if session.Digest != d.String() {
+		return nil, handler.Errorf(
+			"upload %s is for %s, not %s", uid, session.Digest, d).Status(http.StatusBadRequest)
+	}
+	return session, nil
+}
+
 func (u *uploader) patch(
 	d core.Digest, uid string, chunk io.Reader, start, end int64,
 ) error {
@@ -62,6 +111,16 @@ func (u *uploader) patch(
 		log.With("digest", d.Hex(), "uid", uid).Debug("Blob already exists, cannot patch upload")
 		return handler.ErrorStatus(http.StatusConflict)
 	}
+	session, err := u.session(d, uid)
+	if err != nil {
+		log.With("digest", d.Hex(), "uid", uid).Warnf("Failed to get upload session: %s", err)
+		return err
+	}
+	if session != nil && start > session.Offset {
+		return handler.Errorf(
+			"chunk starts at %d, but upload continues from %d", start, session.Offset).
+			Status(http.StatusRequestedRangeNotSatisfiable)
+	}
 	f, err := u.cas.GetUploadFileReadWriter(uid)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -81,6 +140,13 @@ func (u *uploader) patch(
 		log.With("digest", d.Hex(), "uid", uid, "start", start, "end", end, "chunk_size", chunkSize).Errorf("Failed to copy chunk data: %s", err)
 		return handler.Errorf("copy: %s", err)
 	}
+	if session != nil && end > session.Offset {
+		session.Offset = end
+		if err := u.cas.SetUploadSession(uid, session); err != nil {
+			log.With("digest", d.Hex(), "uid", uid).Errorf("Failed to set upload session: %s", err)
+			return handler.Errorf("set upload session: %s", err)
+		}
+	}
 	log.With("digest", d.Hex(), "uid", uid, "start", start, "end", end, "chunk_size", chunkSize).Debug("Successfully patched upload chunk")
 	return nil
 }
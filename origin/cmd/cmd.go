@@ -17,6 +17,7 @@ import (
 	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
 	"net/http"
 	"os"
 
@@ -27,6 +28,7 @@ import (
 	"github.com/uber/kraken/lib/healthcheck"
 	"github.com/uber/kraken/lib/hostlist"
 	"github.com/uber/kraken/lib/metainfogen"
+	"github.com/uber/kraken/lib/middleware"
 	"github.com/uber/kraken/lib/persistedretry"
 	"github.com/uber/kraken/lib/persistedretry/writeback"
 	"github.com/uber/kraken/lib/store"
@@ -47,6 +49,7 @@ import (
 	"github.com/go-chi/chi"
 	"github.com/uber-go/tally"
 	"go.uber.org/zap"
+	"gopkg.in/yaml.v2"
 )
 
 // Flags defines origin CLI flags.
@@ -233,6 +236,10 @@ func Run(flags *Flags, opts ...Option) {
 	if err != nil {
 		log.Fatalf("Error creating cluster host list: %s", err)
 	}
+	if err := config.BlobServer.ValidateReplication(
+		len(cluster.Resolve()), config.HashRing.MaxReplicaOrDefault()); err != nil {
+		log.Fatalf("Invalid blob server replication config: %s", err)
+	}
 
 	tls, err := config.TLS.BuildClient()
 	if err != nil {
@@ -282,7 +289,8 @@ func Run(flags *Flags, opts ...Option) {
 		log.Fatalf("Error initializing blob server: %s", err)
 	}
 
-	h := addTorrentDebugEndpoints(server.Handler(), sched)
+	h := addBackendDebugEndpoints(
+		addTorrentDebugEndpoints(server.Handler(), sched), backendManager, config.AdminToken)
 
 	go func() { log.Fatal(server.ListenAndServe(h)) }()
 
@@ -314,3 +322,32 @@ func addTorrentDebugEndpoints(h http.Handler, sched scheduler.ReloadableSchedule
 
 	return r
 }
+
+// addBackendDebugEndpoints mounts endpoints for reconfiguring backends
+// without restarting origin. They require adminToken, and are disabled if it
+// is empty.
+func addBackendDebugEndpoints(h http.Handler, backends *backend.Manager, adminToken string) http.Handler {
+	r := chi.NewRouter()
+
+	// Accepts the same list of backend configs as the "backends" section of
+	// the config file, as YAML or JSON.
+	r.With(middleware.TokenAuth(adminToken)).Patch("/x/config/backends", handler.Wrap(func(w http.ResponseWriter, r *http.Request) error {
+		defer closers.Close(r.Body)
+		b, err := io.ReadAll(r.Body)
+		if err != nil {
+			return handler.Errorf("read body: %s", err)
+		}
+		var configs []backend.Config
+		if err := yaml.Unmarshal(b, &configs); err != nil {
+			return handler.Errorf("decode body: %s", err).Status(http.StatusBadRequest)
+		}
+		if err := backends.Reload(configs); err != nil {
+			return handler.Errorf("reload backends: %s", err).Status(http.StatusBadRequest)
+		}
+		return nil
+	}))
+
+	r.Mount("/", h)
+
+	return r
+}
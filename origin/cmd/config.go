@@ -57,4 +57,8 @@ type Config struct {
 	WriteBack      persistedretry.Config    `yaml:"writeback"`
 	Nginx          nginx.Config             `yaml:"nginx"`
 	TLS            httputil.TLSConfig       `yaml:"tls"`
+
+	// AdminToken authorizes mutating admin endpoints, e.g. backend reloads.
+	// Admin endpoints are disabled if empty.
+	AdminToken string `yaml:"admin_token"`
 }
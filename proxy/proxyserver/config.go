@@ -2,6 +2,7 @@ package proxyserver
 
 import (
 	"github.com/c2h5oh/datasize"
+	"github.com/uber/kraken/utils/dockerutil"
 	"github.com/uber/kraken/utils/listener"
 )
 
@@ -13,7 +14,8 @@ const (
 )
 
 type Config struct {
-	Listener            listener.Config   `yaml:"listener"`
-	PrefetchMinBlobSize datasize.ByteSize `yaml:"prefetch_min_blob_size"` // Minimum size for a blob to be prefetched (e.g., "50M", "1G"). 0 means no minimum.
-	PrefetchMaxBlobSize datasize.ByteSize `yaml:"prefetch_max_blob_size"` // Maximum size for a blob to be prefetched (e.g., "10G", "50G"). 0 means no maximum.
+	Listener            listener.Config         `yaml:"listener"`
+	PrefetchMinBlobSize datasize.ByteSize       `yaml:"prefetch_min_blob_size"` // Minimum size for a blob to be prefetched (e.g., "50M", "1G"). 0 means no minimum.
+	PrefetchMaxBlobSize datasize.ByteSize       `yaml:"prefetch_max_blob_size"` // Maximum size for a blob to be prefetched (e.g., "10G", "50G"). 0 means no maximum.
+	Manifest            dockerutil.ParserConfig `yaml:"manifest"`               // Parsing of manifests preheated on registry push notifications.
 }
@@ -36,12 +36,15 @@ var _manifestRegexp = regexp.MustCompile(`^application/vnd.docker.distribution.m
 // PreheatHandler defines the handler of preheat.
 type PreheatHandler struct {
 	clusterClient blobclient.ClusterClient
+	parser        *dockerutil.Parser
 	synchronous   bool
 }
 
 // NewPreheatHandler creates a new preheat handler.
-func NewPreheatHandler(client blobclient.ClusterClient, synchronous bool) *PreheatHandler {
-	return &PreheatHandler{client, synchronous}
+func NewPreheatHandler(
+	client blobclient.ClusterClient, parser *dockerutil.Parser, synchronous bool) *PreheatHandler {
+
+	return &PreheatHandler{client, parser, synchronous}
 }
 
 // Handle notifies origins to cache the blob related to the image.
@@ -119,7 +122,7 @@ func (ph *PreheatHandler) fetchManifest(repo, digest string) (distribution.Manif
 		return nil, fmt.Errorf("manifest not found")
 	}
 
-	manifest, _, err := dockerutil.ParseManifest(buf)
+	manifest, _, err := ph.parser.ParseManifest(buf)
 	if err != nil {
 		return nil, fmt.Errorf("parse manifest: %s", err)
 	}
@@ -131,7 +134,7 @@ func filterEvents(notification *Notification) []*Event {
 	events := []*Event{}
 
 	for _, event := range notification.Events {
-		isManifest := _manifestRegexp.MatchString(event.Target.MediaType)
+		isManifest := _manifestRegexp.MatchString(dockerutil.NormalizeMediaType(event.Target.MediaType))
 		if !isManifest {
 			continue
 		}
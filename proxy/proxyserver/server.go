@@ -26,6 +26,7 @@ import (
 	"github.com/uber-go/tally"
 	"github.com/uber/kraken/lib/middleware"
 	"github.com/uber/kraken/origin/blobclient"
+	"github.com/uber/kraken/utils/dockerutil"
 	"github.com/uber/kraken/utils/handler"
 )
 
@@ -47,7 +48,7 @@ func New(
 ) *Server {
 	return &Server{
 		stats,
-		NewPreheatHandler(client, synchronous),
+		NewPreheatHandler(client, dockerutil.NewParser(config.Manifest), synchronous),
 		NewPrefetchHandler(client, tagClient, &DefaultTagParser{}, stats, int64(config.PrefetchMinBlobSize), int64(config.PrefetchMaxBlobSize), synchronous),
 		config,
 	}
@@ -53,6 +53,15 @@ type peerGroup struct {
 	deleted       bool
 }
 
+// removeLocked removes the entry at index i of g.peerList by swapping in the
+// last entry. Callers iterating over g.peerList must do so in reverse order.
+func (g *peerGroup) removeLocked(i int) {
+	e := g.peerList[i]
+	g.peerList[i] = g.peerList[len(g.peerList)-1]
+	g.peerList = g.peerList[:len(g.peerList)-1]
+	delete(g.peerMap, e.id)
+}
+
 type peerEntry struct {
 	id        core.PeerID
 	ip        string
@@ -139,6 +148,66 @@ func (s *LocalStore) UpdatePeer(h core.InfoHash, p *core.PeerInfo) error {
 	return nil
 }
 
+// EvictPeer implements Store.
+func (s *LocalStore) EvictPeer(sel PeerSelector) (int, error) {
+	s.mu.RLock()
+	groups := make([]*peerGroup, 0, len(s.peerGroups))
+	for _, g := range s.peerGroups {
+		groups = append(groups, g)
+	}
+	s.mu.RUnlock()
+
+	var n int
+	for _, g := range groups {
+		g.mu.Lock()
+		for i := len(g.peerList) - 1; i >= 0; i-- {
+			e := g.peerList[i]
+			if !sel.matches(e.id, e.ip, e.port) {
+				continue
+			}
+			g.removeLocked(i)
+			n++
+		}
+		g.mu.Unlock()
+	}
+	return n, nil
+}
+
+// ListSwarms implements Store. Expired peers which have not been cleaned up
+// yet are excluded.
+func (s *LocalStore) ListSwarms() ([]SwarmInfo, error) {
+	s.mu.RLock()
+	hashes := make([]core.InfoHash, 0, len(s.peerGroups))
+	groups := make([]*peerGroup, 0, len(s.peerGroups))
+	for h, g := range s.peerGroups {
+		hashes = append(hashes, h)
+		groups = append(groups, g)
+	}
+	s.mu.RUnlock()
+
+	now := s.clk.Now()
+	var swarms []SwarmInfo
+	for i, g := range groups {
+		info := SwarmInfo{InfoHash: hashes[i]}
+		g.mu.RLock()
+		for _, e := range g.peerList {
+			if now.After(e.expiresAt) {
+				continue
+			}
+			if e.complete {
+				info.Seeders++
+			} else {
+				info.Leechers++
+			}
+		}
+		g.mu.RUnlock()
+		if info.Size() > 0 {
+			swarms = append(swarms, info)
+		}
+	}
+	return swarms, nil
+}
+
 func (s *LocalStore) getOrInitLockedPeerGroup(h core.InfoHash) *peerGroup {
 	// We must take care to handle a race condition against
 	// cleanupExpiredPeerGroups. Consider two goroutines, A and B, where A
@@ -196,46 +265,31 @@ func (s *LocalStore) cleanupExpiredPeerEntries() {
 	s.mu.RUnlock()
 
 	for _, g := range groups {
-		var expired []int
-
 		g.mu.RLock()
-		for i, e := range g.peerList {
+		var expired bool
+		for _, e := range g.peerList {
 			if s.clk.Now().After(e.expiresAt) {
-				expired = append(expired, i)
+				expired = true
+				break
 			}
 		}
 		g.mu.RUnlock()
 
-		if len(expired) == 0 {
+		if !expired {
 			// Fast path -- no need to acquire a write lock if there are no
 			// expired entries.
 			continue
 		}
 
+		// Expired entries are found again under the write lock, since peer
+		// entries may have been updated or evicted since the check above.
 		g.mu.Lock()
-		for j := len(expired) - 1; j >= 0; j-- {
-			// Loop over expired indexes in reverse orders to perform fast slice
+		for i := len(g.peerList) - 1; i >= 0; i-- {
+			// Loop over indexes in reverse order to perform fast slice
 			// element removal.
-			i := expired[j]
-
-			if i >= len(g.peerList) {
-				// Technically we're the only goroutine deleting peer entries,
-				// but let's play it safe.
-				continue
+			if s.clk.Now().After(g.peerList[i].expiresAt) {
+				g.removeLocked(i)
 			}
-			e := g.peerList[i]
-
-			// Must re-check the expiresAt timestamp in case an update occurred
-			// before we could acquire the write lock.
-			if s.clk.Now().Before(e.expiresAt) {
-				continue
-			}
-
-			// Remove the expired index.
-			g.peerList[i] = g.peerList[len(g.peerList)-1]
-			g.peerList = g.peerList[:len(g.peerList)-1]
-
-			delete(g.peerMap, e.id)
 		}
 		g.mu.Unlock()
 	}
@@ -14,6 +14,7 @@
 package peerstore
 
 import (
+	"fmt"
 	"sync"
 	"testing"
 	"time"
@@ -144,3 +145,71 @@ func TestLocalStoreConcurrency(t *testing.T) {
 	}
 	wg.Wait()
 }
+
+func TestLocalStoreEvictPeer(t *testing.T) {
+	require := require.New(t)
+
+	s := NewLocalStore(LocalConfig{}, clock.New())
+	defer s.Close()
+
+	h1 := core.InfoHashFixture()
+	h2 := core.InfoHashFixture()
+
+	p1 := core.PeerInfoFixture()
+	p2 := core.PeerInfoFixture()
+	p3 := core.PeerInfoFixture()
+
+	require.NoError(s.UpdatePeer(h1, p1))
+	require.NoError(s.UpdatePeer(h1, p2))
+	require.NoError(s.UpdatePeer(h2, p1))
+	require.NoError(s.UpdatePeer(h2, p3))
+
+	n, err := s.EvictPeer(PeerSelector{PeerID: p1.PeerID})
+	require.NoError(err)
+	require.Equal(2, n)
+
+	n, err = s.EvictPeer(PeerSelector{Addr: fmt.Sprintf("%s:%d", p3.IP, p3.Port)})
+	require.NoError(err)
+	require.Equal(1, n)
+
+	peers, err := s.GetPeers(h1, 10)
+	require.NoError(err)
+	require.Equal([]*core.PeerInfo{p2}, peers)
+
+	peers, err = s.GetPeers(h2, 10)
+	require.NoError(err)
+	require.Empty(peers)
+
+	// Evicted peers are re-added on announce.
+	require.NoError(s.UpdatePeer(h2, p1))
+	peers, err = s.GetPeers(h2, 10)
+	require.NoError(err)
+	require.Equal([]*core.PeerInfo{p1}, peers)
+}
+
+func TestLocalStoreListSwarms(t *testing.T) {
+	require := require.New(t)
+
+	s := NewLocalStore(LocalConfig{}, clock.New())
+	defer s.Close()
+
+	swarms, err := s.ListSwarms()
+	require.NoError(err)
+	require.Empty(swarms)
+
+	h1 := core.InfoHashFixture()
+	h2 := core.InfoHashFixture()
+
+	seeder := core.PeerInfoFixture()
+	seeder.Complete = true
+	require.NoError(s.UpdatePeer(h1, seeder))
+	require.NoError(s.UpdatePeer(h1, core.PeerInfoFixture()))
+	require.NoError(s.UpdatePeer(h2, core.PeerInfoFixture()))
+
+	swarms, err = s.ListSwarms()
+	require.NoError(err)
+	require.ElementsMatch([]SwarmInfo{
+		{InfoHash: h1, Seeders: 1, Leechers: 1},
+		{InfoHash: h2, Leechers: 1},
+	}, swarms)
+}
@@ -198,3 +198,109 @@ func (s *RedisStore) GetPeers(h core.InfoHash, n int) ([]*core.PeerInfo, error)
 	}
 	return peers, nil
 }
+
+// EvictPeer scans every peer set in Redis and removes members matching sel.
+// Intended for infrequent administrative use, since it touches every key.
+func (s *RedisStore) EvictPeer(sel PeerSelector) (int, error) {
+	c := s.pool.Get()
+	defer closers.Close(c)
+
+	var n int
+	cursor := 0
+	for {
+		values, err := redis.Values(c.Do("SCAN", cursor, "MATCH", "peerset:*", "COUNT", 1000))
+		if err != nil {
+			return n, fmt.Errorf("SCAN: %s", err)
+		}
+		var keys []string
+		if _, err := redis.Scan(values, &cursor, &keys); err != nil {
+			return n, fmt.Errorf("parse SCAN reply: %s", err)
+		}
+		for _, k := range keys {
+			members, err := redis.Strings(c.Do("SMEMBERS", k))
+			if err != nil {
+				return n, fmt.Errorf("SMEMBERS %s: %s", k, err)
+			}
+			for _, m := range members {
+				id, _, err := deserializePeer(m)
+				if err != nil {
+					continue
+				}
+				if !sel.matches(id.peerID, id.ip, id.port) {
+					continue
+				}
+				removed, err := redis.Int(c.Do("SREM", k, m))
+				if err != nil {
+					return n, fmt.Errorf("SREM %s: %s", k, err)
+				}
+				n += removed
+			}
+		}
+		if cursor == 0 {
+			return n, nil
+		}
+	}
+}
+
+// ListSwarms scans every peer set in Redis, merging the windows of each info
+// hash. Intended for infrequent administrative use, since it touches every key.
+func (s *RedisStore) ListSwarms() ([]SwarmInfo, error) {
+	c := s.pool.Get()
+	defer closers.Close(c)
+
+	peers := make(map[core.InfoHash]map[peerIdentity]bool)
+	cursor := 0
+	for {
+		values, err := redis.Values(c.Do("SCAN", cursor, "MATCH", "peerset:*", "COUNT", 1000))
+		if err != nil {
+			return nil, fmt.Errorf("SCAN: %s", err)
+		}
+		var keys []string
+		if _, err := redis.Scan(values, &cursor, &keys); err != nil {
+			return nil, fmt.Errorf("parse SCAN reply: %s", err)
+		}
+		for _, k := range keys {
+			parts := strings.Split(k, ":")
+			if len(parts) != 3 {
+				continue
+			}
+			h, err := core.NewInfoHashFromHex(parts[1])
+			if err != nil {
+				continue
+			}
+			members, err := redis.Strings(c.Do("SMEMBERS", k))
+			if err != nil {
+				return nil, fmt.Errorf("SMEMBERS %s: %s", k, err)
+			}
+			if peers[h] == nil {
+				peers[h] = make(map[peerIdentity]bool)
+			}
+			for _, m := range members {
+				id, complete, err := deserializePeer(m)
+				if err != nil {
+					continue
+				}
+				peers[h][id] = peers[h][id] || complete
+			}
+		}
+		if cursor == 0 {
+			break
+		}
+	}
+
+	var swarms []SwarmInfo
+	for h, ids := range peers {
+		info := SwarmInfo{InfoHash: h}
+		for _, complete := range ids {
+			if complete {
+				info.Seeders++
+			} else {
+				info.Leechers++
+			}
+		}
+		if info.Size() > 0 {
+			swarms = append(swarms, info)
+		}
+	}
+	return swarms, nil
+}
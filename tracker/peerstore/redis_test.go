@@ -14,6 +14,7 @@
 package peerstore
 
 import (
+	"fmt"
 	"testing"
 	"time"
 
@@ -179,3 +180,63 @@ func TestRedisStorePeerExpiration(t *testing.T) {
 	require.NoError(err)
 	require.Empty(result)
 }
+
+func TestRedisStoreEvictPeer(t *testing.T) {
+	require := require.New(t)
+
+	config := redisConfigFixture()
+
+	s, err := NewRedisStore(config, clock.New())
+	require.NoError(err)
+
+	h1 := core.InfoHashFixture()
+	h2 := core.InfoHashFixture()
+
+	p1 := core.PeerInfoFixture()
+	p2 := core.PeerInfoFixture()
+
+	for _, h := range []core.InfoHash{h1, h2} {
+		require.NoError(s.UpdatePeer(h, p1))
+		require.NoError(s.UpdatePeer(h, p2))
+	}
+
+	n, err := s.EvictPeer(PeerSelector{PeerID: p1.PeerID})
+	require.NoError(err)
+	require.Equal(2, n)
+
+	n, err = s.EvictPeer(PeerSelector{Addr: fmt.Sprintf("%s:%d", p2.IP, p2.Port)})
+	require.NoError(err)
+	require.Equal(2, n)
+
+	for _, h := range []core.InfoHash{h1, h2} {
+		peers, err := s.GetPeers(h, 10)
+		require.NoError(err)
+		require.Empty(peers)
+	}
+}
+
+func TestRedisStoreListSwarms(t *testing.T) {
+	require := require.New(t)
+
+	config := redisConfigFixture()
+
+	s, err := NewRedisStore(config, clock.New())
+	require.NoError(err)
+
+	h1 := core.InfoHashFixture()
+	h2 := core.InfoHashFixture()
+
+	seeder := core.PeerInfoFixture()
+	require.NoError(s.UpdatePeer(h1, seeder))
+	seeder.Complete = true
+	require.NoError(s.UpdatePeer(h1, seeder))
+	require.NoError(s.UpdatePeer(h1, core.PeerInfoFixture()))
+	require.NoError(s.UpdatePeer(h2, core.PeerInfoFixture()))
+
+	swarms, err := s.ListSwarms()
+	require.NoError(err)
+	require.ElementsMatch([]SwarmInfo{
+		{InfoHash: h1, Seeders: 1, Leechers: 1},
+		{InfoHash: h2, Leechers: 1},
+	}, swarms)
+}
@@ -15,6 +15,8 @@ package peerstore
 
 import (
 	"fmt"
+	"net"
+	"strconv"
 
 	"github.com/andres-erbsen/clock"
 	"github.com/uber/kraken/core"
@@ -31,6 +33,41 @@ type Store interface {
 
 	// UpdatePeer updates peer fields.
 	UpdatePeer(h core.InfoHash, peer *core.PeerInfo) error
+
+	// EvictPeer removes every peer matching sel from all swarms, returning the
+	// number of swarm entries removed. Evicted peers which announce again are
+	// re-added.
+	EvictPeer(sel PeerSelector) (int, error)
+
+	// ListSwarms returns a snapshot of every swarm with at least one peer, in
+	// no particular order.
+	ListSwarms() ([]SwarmInfo, error)
+}
+
+// SwarmInfo summarizes the peers announcing for an info hash.
+type SwarmInfo struct {
+	InfoHash core.InfoHash
+	Seeders  int
+	Leechers int
+}
+
+// Size returns the number of peers in the swarm.
+func (i SwarmInfo) Size() int {
+	return i.Seeders + i.Leechers
+}
+
+// PeerSelector identifies a peer either by id or by "ip:port" address. If
+// PeerID is set, Addr is ignored.
+type PeerSelector struct {
+	PeerID core.PeerID
+	Addr   string
+}
+
+func (s PeerSelector) matches(peerID core.PeerID, ip string, port int) bool {
+	if s.PeerID != (core.PeerID{}) {
+		return peerID == s.PeerID
+	}
+	return s.Addr != "" && net.JoinHostPort(ip, strconv.Itoa(port)) == s.Addr
 }
 
 // New creates a new Store implementation based on config.
@@ -68,3 +68,43 @@ func (s *testStore) GetPeers(h core.InfoHash, n int) ([]*core.PeerInfo, error) {
 	}
 	return copies, nil
 }
+
+func (s *testStore) EvictPeer(sel PeerSelector) (int, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	var n int
+	for h, peers := range s.torrents {
+		var remaining []core.PeerInfo
+		for _, p := range peers {
+			if sel.matches(p.PeerID, p.IP, p.Port) {
+				n++
+				continue
+			}
+			remaining = append(remaining, p)
+		}
+		s.torrents[h] = remaining
+	}
+	return n, nil
+}
+
+func (s *testStore) ListSwarms() ([]SwarmInfo, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	var swarms []SwarmInfo
+	for h, peers := range s.torrents {
+		info := SwarmInfo{InfoHash: h}
+		for _, p := range peers {
+			if p.Complete {
+				info.Seeders++
+			} else {
+				info.Leechers++
+			}
+		}
+		if info.Size() > 0 {
+			swarms = append(swarms, info)
+		}
+	}
+	return swarms, nil
+}
@@ -29,6 +29,10 @@ type Config struct {
 
 	AnnounceInterval time.Duration `yaml:"announce_interval"`
 
+	// AdminToken authorizes mutating admin endpoints, e.g. peer eviction.
+	// Admin endpoints are disabled if empty.
+	AdminToken string `yaml:"admin_token"`
+
 	Listener listener.Config `yaml:"listener"`
 }
 
@@ -0,0 +1,69 @@
+// Copyright (c) 2016-2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package trackerserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/uber/kraken/core"
+	"github.com/uber/kraken/tracker/peerstore"
+	"github.com/uber/kraken/utils/handler"
+	"github.com/uber/kraken/utils/log"
+)
+
+// evictPeerHandler immediately removes a peer from every swarm. The peer is
+// selected by either the "peer_id" or "addr" (ip:port) query argument.
+func (s *Server) evictPeerHandler(w http.ResponseWriter, r *http.Request) error {
+	sel, err := parsePeerSelector(r)
+	if err != nil {
+		return handler.Errorf("%s", err).Status(http.StatusBadRequest)
+	}
+	n, err := s.peerStore.EvictPeer(sel)
+	if err != nil {
+		return fmt.Errorf("evict peer: %s", err)
+	}
+	s.stats.Counter("peer_evictions").Inc(int64(n))
+	log.With("peer_id", sel.PeerID, "addr", sel.Addr, "evicted", n).Info("Evicted peer from swarms")
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]int{"evicted": n}); err != nil {
+		return fmt.Errorf("write response: %s", err)
+	}
+	return nil
+}
+
+func parsePeerSelector(r *http.Request) (peerstore.PeerSelector, error) {
+	q := r.URL.Query()
+	id, addr := q.Get("peer_id"), q.Get("addr")
+	switch {
+	case id != "" && addr != "":
+		return peerstore.PeerSelector{}, fmt.Errorf("only one of peer_id or addr may be set")
+	case id != "":
+		pid, err := core.NewPeerID(id)
+		if err != nil {
+			return peerstore.PeerSelector{}, fmt.Errorf("parse peer_id: %s", err)
+		}
+		return peerstore.PeerSelector{PeerID: pid}, nil
+	case addr != "":
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			return peerstore.PeerSelector{}, fmt.Errorf("parse addr: %s", err)
+		}
+		return peerstore.PeerSelector{Addr: addr}, nil
+	default:
+		return peerstore.PeerSelector{}, fmt.Errorf("one of peer_id or addr is required")
+	}
+}
@@ -0,0 +1,116 @@
+// Copyright (c) 2016-2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package trackerserver
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/uber/kraken/core"
+	"github.com/uber/kraken/tracker/peerstore"
+	"github.com/uber/kraken/utils/httputil"
+	"github.com/uber/kraken/utils/testutil"
+
+	"github.com/stretchr/testify/require"
+)
+
+func evictPeer(addr, token string, query url.Values) (*http.Response, error) {
+	return httputil.Delete(
+		fmt.Sprintf("http://%s/peers?%s", addr, query.Encode()),
+		httputil.SendHeaders(map[string]string{"Authorization": "Bearer " + token}))
+}
+
+func TestEvictPeerHandlerByPeerID(t *testing.T) {
+	require := require.New(t)
+
+	mocks, cleanup := newServerMocks(t, Config{AdminToken: "secret"})
+	defer cleanup()
+
+	addr, stop := testutil.StartServer(mocks.handler())
+	defer stop()
+
+	pid := core.PeerIDFixture()
+
+	mocks.peerStore.EXPECT().EvictPeer(peerstore.PeerSelector{PeerID: pid}).Return(3, nil)
+
+	resp, err := evictPeer(addr, "secret", url.Values{"peer_id": {pid.String()}})
+	require.NoError(err)
+	b, err := ioutil.ReadAll(resp.Body)
+	require.NoError(err)
+	require.JSONEq(`{"evicted": 3}`, string(b))
+}
+
+func TestEvictPeerHandlerByAddr(t *testing.T) {
+	require := require.New(t)
+
+	mocks, cleanup := newServerMocks(t, Config{AdminToken: "secret"})
+	defer cleanup()
+
+	addr, stop := testutil.StartServer(mocks.handler())
+	defer stop()
+
+	mocks.peerStore.EXPECT().EvictPeer(peerstore.PeerSelector{Addr: "10.0.0.1:8080"}).Return(1, nil)
+
+	_, err := evictPeer(addr, "secret", url.Values{"addr": {"10.0.0.1:8080"}})
+	require.NoError(err)
+}
+
+func TestEvictPeerHandlerErrors(t *testing.T) {
+	tests := []struct {
+		desc   string
+		token  string
+		query  url.Values
+		status int
+	}{
+		{"wrong token", "wrong", url.Values{"addr": {"10.0.0.1:8080"}}, http.StatusUnauthorized},
+		{"no selector", "secret", url.Values{}, http.StatusBadRequest},
+		{"both selectors", "secret", url.Values{
+			"addr":    {"10.0.0.1:8080"},
+			"peer_id": {core.PeerIDFixture().String()},
+		}, http.StatusBadRequest},
+		{"invalid peer id", "secret", url.Values{"peer_id": {"x"}}, http.StatusBadRequest},
+		{"invalid addr", "secret", url.Values{"addr": {"10.0.0.1"}}, http.StatusBadRequest},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			require := require.New(t)
+
+			mocks, cleanup := newServerMocks(t, Config{AdminToken: "secret"})
+			defer cleanup()
+
+			addr, stop := testutil.StartServer(mocks.handler())
+			defer stop()
+
+			_, err := evictPeer(addr, test.token, test.query)
+			require.Error(err)
+			require.True(httputil.IsStatus(err, test.status))
+		})
+	}
+}
+
+func TestEvictPeerHandlerDisabledWithoutAdminToken(t *testing.T) {
+	require := require.New(t)
+
+	mocks, cleanup := newServerMocks(t, Config{})
+	defer cleanup()
+
+	addr, stop := testutil.StartServer(mocks.handler())
+	defer stop()
+
+	_, err := evictPeer(addr, "", url.Values{"addr": {"10.0.0.1:8080"}})
+	require.True(httputil.IsForbidden(err))
+}
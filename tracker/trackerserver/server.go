@@ -82,6 +82,10 @@ func (s *Server) Handler() http.Handler {
 	r.Get("/announce", handler.Wrap(s.announceHandlerV1))
 	r.Post("/announce/{infohash}", handler.Wrap(s.announceHandlerV2))
 	r.Get("/namespace/{namespace}/blobs/{digest}/metainfo", handler.Wrap(s.getMetaInfoHandler))
+	r.Get("/swarms", handler.Wrap(s.listSwarmsHandler))
+
+	r.With(middleware.TokenAuth(s.config.AdminToken)).
+		Delete("/peers", handler.Wrap(s.evictPeerHandler))
 
 	r.Mount("/debug", chimiddleware.Profiler())
 
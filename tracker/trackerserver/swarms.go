@@ -0,0 +1,120 @@
+// Copyright (c) 2016-2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package trackerserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/uber/kraken/utils/handler"
+	"github.com/uber/kraken/utils/httputil"
+)
+
+const (
+	_defaultSwarmsLimit = 100
+	_maxSwarmsLimit     = 1000
+)
+
+// swarm is a swarm as listed by listSwarmsHandler.
+type swarm struct {
+	InfoHash string `json:"infohash"`
+	Seeders  int    `json:"seeders"`
+	Leechers int    `json:"leechers"`
+	Size     int    `json:"size"`
+}
+
+// listSwarmsResponse is a page of swarms. NextOffset is zero on the last page.
+type listSwarmsResponse struct {
+	Swarms     []swarm `json:"swarms"`
+	Total      int     `json:"total"`
+	NextOffset int     `json:"next_offset,omitempty"`
+}
+
+// listSwarmsHandler lists every active swarm with its seeder and leecher
+// counts. Swarms are sorted by the "sort" query argument, either "size"
+// (largest first, the default) or "infohash", and paginated by the "offset"
+// and "limit" query arguments. Pages are cut from a fresh snapshot on every
+// request, so swarms may shift between pages as peers announce.
+func (s *Server) listSwarmsHandler(w http.ResponseWriter, r *http.Request) error {
+	limit, err := parseIntQueryArg(r, "limit", _defaultSwarmsLimit)
+	if err != nil {
+		return err
+	}
+	if limit <= 0 || limit > _maxSwarmsLimit {
+		return handler.Errorf("limit must be in [1, %d]", _maxSwarmsLimit).Status(http.StatusBadRequest)
+	}
+	offset, err := parseIntQueryArg(r, "offset", 0)
+	if err != nil {
+		return err
+	}
+	if offset < 0 {
+		return handler.Errorf("offset must not be negative").Status(http.StatusBadRequest)
+	}
+	order := httputil.GetQueryArg(r, "sort", "size")
+	if order != "size" && order != "infohash" {
+		return handler.Errorf("unknown sort %q: expected size or infohash", order).Status(http.StatusBadRequest)
+	}
+
+	infos, err := s.peerStore.ListSwarms()
+	if err != nil {
+		return fmt.Errorf("list swarms: %s", err)
+	}
+	swarms := make([]swarm, len(infos))
+	for i, info := range infos {
+		swarms[i] = swarm{
+			InfoHash: info.InfoHash.String(),
+			Seeders:  info.Seeders,
+			Leechers: info.Leechers,
+			Size:     info.Size(),
+		}
+	}
+	sort.Slice(swarms, func(i, j int) bool {
+		if order == "size" && swarms[i].Size != swarms[j].Size {
+			return swarms[i].Size > swarms[j].Size
+		}
+		return swarms[i].InfoHash < swarms[j].InfoHash
+	})
+
+	resp := listSwarmsResponse{Swarms: []swarm{}, Total: len(swarms)}
+	if offset < len(swarms) {
+		end := offset + limit
+		if end < len(swarms) {
+			resp.NextOffset = end
+		} else {
+			end = len(swarms)
+		}
+		resp.Swarms = swarms[offset:end]
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		return fmt.Errorf("write response: %s", err)
+	}
+	return nil
+}
+
+func parseIntQueryArg(r *http.Request, name string, defaultVal int) (int, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return defaultVal, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, handler.Errorf("parse %s: %s", name, err).Status(http.StatusBadRequest)
+	}
+	return n, nil
+}
@@ -0,0 +1,84 @@
+// Copyright (c) 2016-2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package trackerserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/uber/kraken/core"
+	"github.com/uber/kraken/tracker/peerstore"
+	"github.com/uber/kraken/utils/httputil"
+	"github.com/uber/kraken/utils/testutil"
+
+	"github.com/stretchr/testify/require"
+)
+
+func listSwarms(addr, query string) (listSwarmsResponse, error) {
+	var result listSwarmsResponse
+	resp, err := httputil.Get(fmt.Sprintf("http://%s/swarms?%s", addr, query))
+	if err != nil {
+		return result, err
+	}
+	defer resp.Body.Close()
+	err = json.NewDecoder(resp.Body).Decode(&result)
+	return result, err
+}
+
+func TestListSwarmsHandler(t *testing.T) {
+	require := require.New(t)
+
+	mocks, cleanup := newServerMocks(t, Config{})
+	defer cleanup()
+
+	addr, stop := testutil.StartServer(mocks.handler())
+	defer stop()
+
+	small := peerstore.SwarmInfo{InfoHash: core.InfoHashFixture(), Seeders: 1}
+	medium := peerstore.SwarmInfo{InfoHash: core.InfoHashFixture(), Seeders: 1, Leechers: 2}
+	large := peerstore.SwarmInfo{InfoHash: core.InfoHashFixture(), Leechers: 5}
+	mocks.peerStore.EXPECT().ListSwarms().Return(
+		[]peerstore.SwarmInfo{small, large, medium}, nil).Times(2)
+
+	page, err := listSwarms(addr, "limit=2")
+	require.NoError(err)
+	require.Equal(3, page.Total)
+	require.Equal(2, page.NextOffset)
+	require.Equal([]swarm{
+		{large.InfoHash.String(), 0, 5, 5},
+		{medium.InfoHash.String(), 1, 2, 3},
+	}, page.Swarms)
+
+	page, err = listSwarms(addr, "limit=2&offset=2")
+	require.NoError(err)
+	require.Equal(0, page.NextOffset)
+	require.Equal([]swarm{{small.InfoHash.String(), 1, 0, 1}}, page.Swarms)
+}
+
+func TestListSwarmsHandlerErrors(t *testing.T) {
+	for _, query := range []string{"limit=0", "limit=1001", "limit=x", "offset=-1", "sort=age"} {
+		t.Run(query, func(t *testing.T) {
+			mocks, cleanup := newServerMocks(t, Config{})
+			defer cleanup()
+
+			addr, stop := testutil.StartServer(mocks.handler())
+			defer stop()
+
+			_, err := listSwarms(addr, query)
+			require.True(t, httputil.IsStatus(err, http.StatusBadRequest))
+		})
+	}
+}
@@ -1,23 +1,182 @@
 package closers
 
 import (
+	"context"
+	"errors"
 	"io"
+	"os"
+	"sync/atomic"
 
+	"github.com/uber-go/tally"
 	"github.com/uber/kraken/utils/log"
 	"go.uber.org/zap"
 )
 
+// Closer closes io.Closers and logs close failures to Logger.
+type Closer struct {
+	// Logger defaults to the global logger if nil.
+	Logger *zap.Logger
+}
+
+// CloseFunc adapts a cleanup function to io.Closer, so it can be closed and
+// logged like any other closer, e.g.
+//
+//	defer closers.Close(closers.CloseFunc(func() error { return os.RemoveAll(dir) }))
+type CloseFunc func() error
+
+// Close calls f.
+func (f CloseFunc) Close() error {
+	return f()
+}
+
+// _default logs to the global logger, which may be replaced after init.
+var _default Closer
+
+// _metrics holds a metricsScope, since atomic.Value requires every stored
+// value to have the same concrete type.
+var _metrics atomic.Value
+
+type metricsScope struct {
+	tally.Scope
+}
+
+func init() {
+	SetMetricsScope(nil)
+}
+
+// SetMetricsScope sets the scope on which Close and CloseContext count close
+// failures, as "close.error", and already closed closers, as
+// "close.already_closed". A nil scope disables the counters, the default.
+func SetMetricsScope(scope tally.Scope) {
+	if scope == nil {
+		scope = tally.NoopScope
+	}
+	_metrics.Store(metricsScope{scope.SubScope("close")})
+}
+
 // Close closes the closer. A message will be logged.
 // The main reason for the helper existence is to have a utility for defer io.Closer() statements.
+func (c Closer) Close(closer io.Closer) {
+	if closer == nil {
+		return
+	}
+	if err := closer.Close(); err != nil {
+		c.logFailure(err)
+	}
+}
+
+func (c Closer) logger() *zap.Logger {
+	if c.Logger == nil {
+		return log.Desugar()
+	}
+	return c.Logger
+}
+
+// logFailure logs close failures at debug level, since most are harmless,
+// e.g. closing a closed connection. Closing an already closed closer is
+// logged without a stack.
+func (c Closer) logFailure(err error) {
+	logger := c.logger()
+	stats := _metrics.Load().(metricsScope)
+	if isAlreadyClosed(err) {
+		stats.Counter("already_closed").Inc(1)
+		logger.Debug("closer already closed", zap.Error(err))
+		return
+	}
+	stats.Counter("error").Inc(1)
+	logger.Debug("failed to close a closer", zap.Error(err), zap.Stack("stack"))
+}
+
+// Close closes the closer with the default Closer, which logs to the global
+// logger.
 func Close(closer io.Closer) {
-	if closer != nil {
-		err := closer.Close()
-		if err != nil {
-			log.Desugar().Debug(
-				"failed to close a closer",
-				zap.Error(err),
-				zap.Stack("stack"),
-			)
+	_default.Close(closer)
+}
+
+// CloseContext is like Close, but stops waiting for the closer once ctx is
+// done, e.g. to bound each resource's window during shutdown. The underlying
+// Close keeps running in the background and its goroutine exits when Close
+// returns; a failure after ctx is done is still logged.
+func (c Closer) CloseContext(ctx context.Context, closer io.Closer) {
+	if closer == nil {
+		return
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if err := closer.Close(); err != nil {
+			c.logFailure(err)
+		}
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		c.logger().Warn(
+			"timed out closing a closer, close continues in background",
+			zap.Error(ctx.Err()),
+			zap.Stack("stack"),
+		)
+	}
+}
+
+// CloseContext closes the closer with the default Closer, waiting at most
+// until ctx is done.
+func CloseContext(ctx context.Context, closer io.Closer) {
+	_default.CloseContext(ctx, closer)
+}
+
+// CloseAll closes closers in reverse order, matching the order of one defer
+// per closer, and logs each failure like Close. Nil closers are skipped.
+func CloseAll(closers ...io.Closer) {
+	for i := len(closers) - 1; i >= 0; i-- {
+		Close(closers[i])
+	}
+}
+
+// CloseAllForward is like CloseAll, but closes closers in the given order.
+func CloseAllForward(closers ...io.Closer) {
+	for _, c := range closers {
+		Close(c)
+	}
+}
+
+// CloseErr closes the closer and returns its error, for callers which must
+// surface close failures, e.g. when a failed close loses written data.
+// Closing an already closed file is not an error. Nil closers return nil.
+func CloseErr(closer io.Closer) error {
+	if closer == nil {
+		return nil
+	}
+	if err := closer.Close(); err != nil && !isAlreadyClosed(err) {
+		return err
+	}
+	return nil
+}
+
+// CloseAllErr closes every closer in the given order, like CloseAllForward,
+// and returns the errors of all failed closes joined with errors.Join.
+func CloseAllErr(closers ...io.Closer) error {
+	var errs []error
+	for _, c := range closers {
+		if err := CloseErr(c); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// isAlreadyClosed returns true if err reports that the closer was already
+// closed. The os.ErrClosed sentinel is checked first; the string fallback
+// covers third-party closers which return their own "already closed" errors.
+func isAlreadyClosed(err error) bool {
+	if errors.Is(err, os.ErrClosed) {
+		return true
+	}
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		switch e.Error() {
+		case "file already closed", "close: file already closed":
+			return true
 		}
 	}
+	return false
 }
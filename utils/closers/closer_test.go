@@ -2,11 +2,19 @@ package closers
 
 import (
 	"bytes"
+	"context"
 	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/require"
+	"github.com/uber-go/tally"
 	mocks_io "github.com/uber/kraken/mocks/io"
 	"github.com/uber/kraken/utils/log"
 	"go.uber.org/zap"
@@ -56,3 +64,277 @@ func TestClose_LogsError(t *testing.T) {
 
 	require.Contains(t, buf.String(), "custom error for the test")
 }
+
+func TestCloseAll_ReverseOrder(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	a := mocks_io.NewMockCloser(ctrl)
+	b := mocks_io.NewMockCloser(ctrl)
+	c := mocks_io.NewMockCloser(ctrl)
+
+	gomock.InOrder(
+		c.EXPECT().Close().Return(nil),
+		b.EXPECT().Close().Return(errors.New("close error")),
+		a.EXPECT().Close().Return(nil),
+	)
+
+	CloseAll(a, nil, b, c)
+}
+
+func TestCloseAllForward_GivenOrder(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	a := mocks_io.NewMockCloser(ctrl)
+	b := mocks_io.NewMockCloser(ctrl)
+
+	gomock.InOrder(
+		a.EXPECT().Close().Return(errors.New("close error")),
+		b.EXPECT().Close().Return(nil),
+	)
+
+	CloseAllForward(a, nil, b)
+}
+
+func TestCloseAll_Empty(t *testing.T) {
+	CloseAll()
+	CloseAllForward()
+}
+
+func TestCloseErr(t *testing.T) {
+	require := require.New(t)
+
+	require.NoError(CloseErr(nil))
+
+	closeErr := errors.New("close error")
+	mockCloser := mocks_io.NewMockCloser(gomock.NewController(t))
+	mockCloser.EXPECT().Close().Return(closeErr)
+	require.Equal(closeErr, CloseErr(mockCloser))
+}
+
+func TestCloseErr_AlreadyClosedFile(t *testing.T) {
+	require := require.New(t)
+
+	f, err := os.Create(filepath.Join(t.TempDir(), "file"))
+	require.NoError(err)
+	require.NoError(CloseErr(f))
+	require.NoError(CloseErr(f))
+}
+
+func TestCloseAllErr(t *testing.T) {
+	require := require.New(t)
+
+	ctrl := gomock.NewController(t)
+	a := mocks_io.NewMockCloser(ctrl)
+	b := mocks_io.NewMockCloser(ctrl)
+	c := mocks_io.NewMockCloser(ctrl)
+
+	errA := errors.New("a failed")
+	errC := errors.New("c failed")
+	gomock.InOrder(
+		a.EXPECT().Close().Return(errA),
+		b.EXPECT().Close().Return(fmt.Errorf("wrapped: %w", os.ErrClosed)),
+		c.EXPECT().Close().Return(errC),
+	)
+
+	err := CloseAllErr(a, nil, b, c)
+	require.True(errors.Is(err, errA))
+	require.True(errors.Is(err, errC))
+	require.False(errors.Is(err, os.ErrClosed))
+
+	require.NoError(CloseAllErr())
+}
+
+func bufferLogger(buf *bytes.Buffer) *zap.Logger {
+	return zap.New(
+		zapcore.NewCore(
+			zapcore.NewConsoleEncoder(zap.NewProductionEncoderConfig()),
+			zapcore.AddSync(buf),
+			zapcore.DebugLevel,
+		),
+	)
+}
+
+func TestCloserLogsToLogger(t *testing.T) {
+	var buf bytes.Buffer
+	c := Closer{Logger: bufferLogger(&buf)}
+
+	mockCloser := mocks_io.NewMockCloser(gomock.NewController(t))
+	mockCloser.EXPECT().Close().Return(errors.New("injected logger error"))
+
+	c.Close(mockCloser)
+
+	require.Contains(t, buf.String(), "failed to close a closer")
+	require.Contains(t, buf.String(), "injected logger error")
+}
+
+func TestCloserLogsAlreadyClosed(t *testing.T) {
+	var buf bytes.Buffer
+	c := Closer{Logger: bufferLogger(&buf)}
+
+	f, err := os.Create(filepath.Join(t.TempDir(), "f"))
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	c.Close(f)
+
+	require.Contains(t, buf.String(), "closer already closed")
+}
+
+func TestCloserNilLoggerUsesGlobal(t *testing.T) {
+	mockCloser := mocks_io.NewMockCloser(gomock.NewController(t))
+	mockCloser.EXPECT().Close().Return(errors.New("close error"))
+
+	Closer{}.Close(mockCloser)
+}
+
+func TestIsAlreadyClosed(t *testing.T) {
+	for _, tc := range []struct {
+		desc     string
+		err      error
+		expected bool
+	}{
+		{"sentinel", os.ErrClosed, true},
+		{"wrapped sentinel", fmt.Errorf("foo: %w", os.ErrClosed), true},
+		{"path error", &os.PathError{Op: "close", Path: "f", Err: os.ErrClosed}, true},
+		{"string", errors.New("file already closed"), true},
+		{"close string", errors.New("close: file already closed"), true},
+		{"wrapped string", fmt.Errorf("foo: %w", errors.New("file already closed")), true},
+		{"other", errors.New("disk on fire"), false},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			require.Equal(t, tc.expected, isAlreadyClosed(tc.err))
+		})
+	}
+}
+
+func TestCloserLogLevels(t *testing.T) {
+	require := require.New(t)
+
+	var buf bytes.Buffer
+	c := Closer{Logger: bufferLogger(&buf)}
+
+	ctrl := gomock.NewController(t)
+	closed := mocks_io.NewMockCloser(ctrl)
+	closed.EXPECT().Close().Return(fmt.Errorf("foo: %w", os.ErrClosed))
+	failed := mocks_io.NewMockCloser(ctrl)
+	failed.EXPECT().Close().Return(errors.New("disk on fire"))
+
+	c.Close(closed)
+	require.Contains(buf.String(), "debug\tcloser already closed")
+
+	buf.Reset()
+	c.Close(failed)
+	require.Contains(buf.String(), "debug\tfailed to close a closer")
+}
+
+type blockingCloser struct {
+	release chan struct{}
+	closed  chan struct{}
+}
+
+func (c blockingCloser) Close() error {
+	<-c.release
+	close(c.closed)
+	return errors.New("closed late")
+}
+
+func TestCloseContext(t *testing.T) {
+	require := require.New(t)
+
+	var buf bytes.Buffer
+	c := Closer{Logger: bufferLogger(&buf)}
+
+	mockCloser := mocks_io.NewMockCloser(gomock.NewController(t))
+	mockCloser.EXPECT().Close().Return(errors.New("custom error for the test"))
+
+	c.CloseContext(context.Background(), mockCloser)
+	require.Contains(buf.String(), "custom error for the test")
+
+	c.CloseContext(context.Background(), nil)
+}
+
+func TestCloseContext_Timeout(t *testing.T) {
+	require := require.New(t)
+
+	var buf syncBuffer
+	c := Closer{Logger: zap.New(
+		zapcore.NewCore(
+			zapcore.NewConsoleEncoder(zap.NewProductionEncoderConfig()),
+			zapcore.AddSync(&buf),
+			zapcore.DebugLevel,
+		),
+	)}
+
+	closer := blockingCloser{make(chan struct{}), make(chan struct{})}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	c.CloseContext(ctx, closer)
+	require.Contains(buf.String(), "timed out closing a closer")
+
+	// The underlying Close keeps running and its failure is still logged.
+	close(closer.release)
+	<-closer.closed
+	require.Eventually(func() bool {
+		return strings.Contains(buf.String(), "closed late")
+	}, time.Second, time.Millisecond)
+}
+
+// syncBuffer is a bytes.Buffer which is safe to log to from the background
+// close goroutine.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestSetMetricsScope(t *testing.T) {
+	require := require.New(t)
+
+	stats := tally.NewTestScope("", nil)
+	SetMetricsScope(stats)
+	t.Cleanup(func() { SetMetricsScope(nil) })
+
+	ctrl := gomock.NewController(t)
+	failed := mocks_io.NewMockCloser(ctrl)
+	failed.EXPECT().Close().Return(errors.New("disk on fire")).Times(2)
+	closed := mocks_io.NewMockCloser(ctrl)
+	closed.EXPECT().Close().Return(os.ErrClosed)
+
+	Close(failed)
+	Close(failed)
+	Close(closed)
+
+	counters := stats.Snapshot().Counters()
+	require.Equal(int64(2), counters["close.error+"].Value())
+	require.Equal(int64(1), counters["close.already_closed+"].Value())
+}
+
+func TestCloseFunc(t *testing.T) {
+	require := require.New(t)
+
+	var buf bytes.Buffer
+	c := Closer{Logger: bufferLogger(&buf)}
+
+	var called bool
+	c.Close(CloseFunc(func() error {
+		called = true
+		return nil
+	}))
+	require.True(called)
+	require.Empty(buf.String())
+
+	c.Close(CloseFunc(func() error { return errors.New("cleanup failed") }))
+	require.Contains(buf.String(), "cleanup failed")
+
+	require.NoError(CloseErr(CloseFunc(func() error { return os.ErrClosed })))
+}
@@ -0,0 +1,71 @@
+// Copyright (c) 2016-2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package dockerutil
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/docker/distribution"
+	"github.com/docker/distribution/manifest/manifestlist"
+	"github.com/uber/kraken/core"
+)
+
+// ErrBlobNotAllowed is returned when a manifest references a blob rejected by
+// an allowlist.
+var ErrBlobNotAllowed = errors.New("blob not allowed")
+
+// ManifestFetcher returns the manifest with digest d.
+type ManifestFetcher func(d core.Digest) (distribution.Manifest, error)
+
+// ValidateAllowedLayers checks every blob referenced by manifest against
+// allowed, returning an ErrBlobNotAllowed error naming the first disallowed
+// digest. Returns error for manifest lists, whose blobs are only known by
+// their children; use ValidateAllowedLayersRecursive instead.
+func ValidateAllowedLayers(manifest distribution.Manifest, allowed func(core.Digest) bool) error {
+	return ValidateAllowedLayersRecursive(manifest, allowed, nil)
+}
+
+// ValidateAllowedLayersRecursive is like ValidateAllowedLayers, but validates
+// manifest lists by fetching each child manifest with fetch and validating
+// its blobs. If fetch is nil, manifest lists return error.
+func ValidateAllowedLayersRecursive(
+	manifest distribution.Manifest, allowed func(core.Digest) bool, fetch ManifestFetcher) error {
+
+	refs, err := GetManifestReferences(manifest)
+	if err != nil {
+		return err
+	}
+	if _, ok := manifest.(*manifestlist.DeserializedManifestList); !ok {
+		for _, d := range refs {
+			if !allowed(d) {
+				return fmt.Errorf("%w: %s", ErrBlobNotAllowed, d)
+			}
+		}
+		return nil
+	}
+	if fetch == nil {
+		return errors.New("cannot validate manifest list without fetching its manifests")
+	}
+	for _, d := range refs {
+		child, err := fetch(d)
+		if err != nil {
+			return fmt.Errorf("fetch manifest %s: %s", d, err)
+		}
+		if err := ValidateAllowedLayersRecursive(child, allowed, fetch); err != nil {
+			return fmt.Errorf("manifest %s: %w", d, err)
+		}
+	}
+	return nil
+}
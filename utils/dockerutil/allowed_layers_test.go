@@ -0,0 +1,138 @@
+// Copyright (c) 2016-2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package dockerutil_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/docker/distribution"
+	"github.com/docker/distribution/manifest/manifestlist"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/kraken/core"
+	"github.com/uber/kraken/utils/dockerutil"
+)
+
+func allowlist(ds ...core.Digest) func(core.Digest) bool {
+	allowed := make(map[core.Digest]bool)
+	for _, d := range ds {
+		allowed[d] = true
+	}
+	return func(d core.Digest) bool { return allowed[d] }
+}
+
+func manifestFixture(t *testing.T, config core.Digest, layers ...core.Digest) (core.Digest, distribution.Manifest) {
+	_, b := dockerutil.ManifestFixtureWithLayers(config, layers...)
+	manifest, d, err := dockerutil.ParseManifestV2(b)
+	require.NoError(t, err)
+	return d, manifest
+}
+
+func TestValidateAllowedLayers(t *testing.T) {
+	config := core.DigestFixture()
+	layer1 := core.DigestFixture()
+	layer2 := core.DigestFixture()
+
+	_, manifest := manifestFixture(t, config, layer1, layer2)
+
+	tests := []struct {
+		desc       string
+		allowed    func(core.Digest) bool
+		disallowed *core.Digest
+	}{
+		{"all allowed", allowlist(config, layer1, layer2), nil},
+		{"first disallowed layer", allowlist(config), &layer1},
+		{"disallowed config", allowlist(layer1, layer2), &config},
+		{"nothing allowed", allowlist(), &config},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			require := require.New(t)
+
+			err := dockerutil.ValidateAllowedLayers(manifest, test.allowed)
+			if test.disallowed == nil {
+				require.NoError(err)
+				return
+			}
+			require.True(errors.Is(err, dockerutil.ErrBlobNotAllowed))
+			require.Contains(err.Error(), test.disallowed.String())
+		})
+	}
+}
+
+func TestValidateAllowedLayersManifestList(t *testing.T) {
+	config := core.DigestFixture()
+	base := core.DigestFixture()
+	amd64Layer := core.DigestFixture()
+	arm64Layer := core.DigestFixture()
+
+	amd64, amd64Manifest := manifestFixture(t, config, base, amd64Layer)
+	arm64, arm64Manifest := manifestFixture(t, config, base, arm64Layer)
+
+	index, _, err := dockerutil.BuildOCIIndex([]dockerutil.IndexEntry{{
+		Digest:    amd64.String(),
+		Size:      100,
+		MediaType: "application/vnd.docker.distribution.manifest.v2+json",
+		Platform:  manifestlist.PlatformSpec{OS: "linux", Architecture: "amd64"},
+	}, {
+		Digest:    arm64.String(),
+		Size:      100,
+		MediaType: "application/vnd.docker.distribution.manifest.v2+json",
+		Platform:  manifestlist.PlatformSpec{OS: "linux", Architecture: "arm64"},
+	}})
+	require.NoError(t, err)
+
+	manifests := map[core.Digest]distribution.Manifest{
+		amd64: amd64Manifest,
+		arm64: arm64Manifest,
+	}
+	fetch := func(d core.Digest) (distribution.Manifest, error) {
+		m, ok := manifests[d]
+		if !ok {
+			return nil, fmt.Errorf("manifest %s not found", d)
+		}
+		return m, nil
+	}
+
+	t.Run("all allowed", func(t *testing.T) {
+		require.NoError(t, dockerutil.ValidateAllowedLayersRecursive(
+			index, allowlist(config, base, amd64Layer, arm64Layer), fetch))
+	})
+
+	t.Run("disallowed child layer", func(t *testing.T) {
+		require := require.New(t)
+
+		err := dockerutil.ValidateAllowedLayersRecursive(
+			index, allowlist(config, base, amd64Layer), fetch)
+		require.True(errors.Is(err, dockerutil.ErrBlobNotAllowed))
+		require.Contains(err.Error(), arm64.String())
+		require.Contains(err.Error(), arm64Layer.String())
+	})
+
+	t.Run("fetch error", func(t *testing.T) {
+		delete(manifests, arm64)
+		defer func() { manifests[arm64] = arm64Manifest }()
+
+		err := dockerutil.ValidateAllowedLayersRecursive(
+			index, allowlist(config, base, amd64Layer, arm64Layer), fetch)
+		require.Error(t, err)
+		require.False(t, errors.Is(err, dockerutil.ErrBlobNotAllowed))
+	})
+
+	t.Run("no fetch", func(t *testing.T) {
+		require.Error(t, dockerutil.ValidateAllowedLayers(
+			index, allowlist(config, base, amd64Layer, arm64Layer)))
+	})
+}
@@ -0,0 +1,161 @@
+// Copyright (c) 2016-2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package dockerutil
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/docker/distribution"
+	"github.com/docker/distribution/manifest/manifestlist"
+	"github.com/docker/distribution/manifest/ocischema"
+	"github.com/uber/kraken/core"
+)
+
+// GetManifestAnnotations returns the top-level annotations of an OCI manifest
+// or index. Returns an empty map for Docker manifest types, which have no
+// annotations.
+func GetManifestAnnotations(manifest distribution.Manifest) (map[string]string, error) {
+	annotations := make(map[string]string)
+	switch m := manifest.(type) {
+	case *ocischema.DeserializedManifest:
+		for k, v := range m.Annotations {
+			annotations[k] = v
+		}
+	case *manifestlist.DeserializedManifestList:
+		if m.MediaType != _ociIndexType {
+			break
+		}
+		// manifestlist does not decode index annotations.
+		_, payload, err := m.Payload()
+		if err != nil {
+			return nil, fmt.Errorf("payload: %s", err)
+		}
+		var index struct {
+			Annotations map[string]string `json:"annotations"`
+		}
+		if err := json.Unmarshal(payload, &index); err != nil {
+			return nil, fmt.Errorf("unmarshal payload: %s", err)
+		}
+		for k, v := range index.Annotations {
+			annotations[k] = v
+		}
+	}
+	return annotations, nil
+}
+
+// GetReferenceAnnotations returns the annotations of each descriptor
+// referenced by manifest, i.e. the config and layers of a manifest or the
+// entries of an index, keyed by digest. Descriptors without annotations are
+// omitted, and the annotations of descriptors sharing a digest are merged.
+func GetReferenceAnnotations(manifest distribution.Manifest) (map[core.Digest]map[string]string, error) {
+	annotations := make(map[core.Digest]map[string]string)
+	for _, desc := range manifest.References() {
+		if len(desc.Annotations) == 0 {
+			continue
+		}
+		d, err := core.ParseDigest(string(desc.Digest))
+		if err != nil {
+			return nil, fmt.Errorf("parse digest: %s", err)
+		}
+		if annotations[d] == nil {
+			annotations[d] = make(map[string]string, len(desc.Annotations))
+		}
+		for k, v := range desc.Annotations {
+			annotations[d][k] = v
+		}
+	}
+	return annotations, nil
+}
+
+// PropagateAnnotations copies the top-level annotations of index onto child,
+// the OCI manifest with digest childDigest selected from index, without
+// overwriting annotations child already has. Returns the updated child and
+// its digest, which differs from childDigest if any annotation was copied.
+// Fields of child unknown to ocischema, such as subject, are preserved.
+func PropagateAnnotations(
+	index distribution.Manifest, childDigest core.Digest, child distribution.Manifest,
+) (distribution.Manifest, core.Digest, error) {
+
+	if _, ok := index.(*manifestlist.DeserializedManifestList); !ok {
+		return nil, core.Digest{}, fmt.Errorf("index is %T, not a manifest list", index)
+	}
+	if _, ok := child.(*ocischema.DeserializedManifest); !ok {
+		return nil, core.Digest{}, fmt.Errorf("child is %T, not an OCI manifest", child)
+	}
+	if !referencesDigest(index, childDigest) {
+		return nil, core.Digest{}, fmt.Errorf("index does not reference %s", childDigest)
+	}
+	_, payload, err := child.Payload()
+	if err != nil {
+		return nil, core.Digest{}, fmt.Errorf("child payload: %s", err)
+	}
+	d, err := core.NewDigester().FromBytes(payload)
+	if err != nil {
+		return nil, core.Digest{}, fmt.Errorf("digest child: %s", err)
+	}
+	if d != childDigest {
+		return nil, core.Digest{}, errors.New("child digest does not match child")
+	}
+
+	indexAnnotations, err := GetManifestAnnotations(index)
+	if err != nil {
+		return nil, core.Digest{}, fmt.Errorf("index annotations: %s", err)
+	}
+	annotations, err := GetManifestAnnotations(child)
+	if err != nil {
+		return nil, core.Digest{}, fmt.Errorf("child annotations: %s", err)
+	}
+	var copied bool
+	for k, v := range indexAnnotations {
+		if _, ok := annotations[k]; !ok {
+			annotations[k] = v
+			copied = true
+		}
+	}
+	if !copied {
+		return child, childDigest, nil
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(payload, &fields); err != nil {
+		return nil, core.Digest{}, fmt.Errorf("unmarshal child: %s", err)
+	}
+	if fields["annotations"], err = json.Marshal(annotations); err != nil {
+		return nil, core.Digest{}, fmt.Errorf("marshal annotations: %s", err)
+	}
+	b, err := json.Marshal(fields)
+	if err != nil {
+		return nil, core.Digest{}, fmt.Errorf("marshal child: %s", err)
+	}
+	updated, _, err := distribution.UnmarshalManifest(ocischema.SchemaVersion.MediaType, b)
+	if err != nil {
+		return nil, core.Digest{}, fmt.Errorf("unmarshal updated child: %s", err)
+	}
+	d, err = core.NewDigester().FromBytes(b)
+	if err != nil {
+		return nil, core.Digest{}, fmt.Errorf("digest updated child: %s", err)
+	}
+	return updated, d, nil
+}
+
+func referencesDigest(manifest distribution.Manifest, d core.Digest) bool {
+	for _, desc := range manifest.References() {
+		if string(desc.Digest) == d.String() {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,211 @@
+// Copyright (c) 2016-2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package dockerutil_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/docker/distribution"
+	"github.com/docker/distribution/manifest/ocischema"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/kraken/core"
+	"github.com/uber/kraken/utils/dockerutil"
+)
+
+func TestGetManifestAnnotations(t *testing.T) {
+	require := require.New(t)
+
+	config := core.DigestFixture()
+	layer := core.DigestFixture()
+	b := []byte(fmt.Sprintf(`{
+		"schemaVersion": 2,
+		"mediaType": "application/vnd.oci.image.manifest.v1+json",
+		"config": {"mediaType": "application/vnd.oci.image.config.v1+json", "size": 1, "digest": %q},
+		"layers": [
+			{
+				"mediaType": "application/vnd.oci.image.layer.v1.tar+gzip",
+				"size": 1,
+				"digest": %q,
+				"annotations": {"org.opencontainers.image.title": "layer.tar.gz"}
+			}
+		],
+		"annotations": {"org.example.signed": "true"}
+	}`, config, layer))
+	manifest, _, err := distribution.UnmarshalManifest(ocischema.SchemaVersion.MediaType, b)
+	require.NoError(err)
+
+	annotations, err := dockerutil.GetManifestAnnotations(manifest)
+	require.NoError(err)
+	require.Equal(map[string]string{"org.example.signed": "true"}, annotations)
+
+	refs, err := dockerutil.GetReferenceAnnotations(manifest)
+	require.NoError(err)
+	require.Equal(map[core.Digest]map[string]string{
+		layer: {"org.opencontainers.image.title": "layer.tar.gz"},
+	}, refs)
+}
+
+func TestGetManifestAnnotationsOCIIndex(t *testing.T) {
+	require := require.New(t)
+
+	child := core.DigestFixture()
+	b := []byte(fmt.Sprintf(`{
+		"schemaVersion": 2,
+		"mediaType": "application/vnd.oci.image.index.v1+json",
+		"manifests": [
+			{
+				"mediaType": "application/vnd.oci.image.manifest.v1+json",
+				"size": 1,
+				"digest": %q,
+				"annotations": {"vnd.docker.reference.type": "attestation-manifest"}
+			}
+		],
+		"annotations": {"org.example.signed": "true"}
+	}`, child))
+	manifest, _, err := distribution.UnmarshalManifest("application/vnd.oci.image.index.v1+json", b)
+	require.NoError(err)
+
+	annotations, err := dockerutil.GetManifestAnnotations(manifest)
+	require.NoError(err)
+	require.Equal(map[string]string{"org.example.signed": "true"}, annotations)
+
+	refs, err := dockerutil.GetReferenceAnnotations(manifest)
+	require.NoError(err)
+	require.Equal(map[core.Digest]map[string]string{
+		child: {"vnd.docker.reference.type": "attestation-manifest"},
+	}, refs)
+}
+
+func TestGetManifestAnnotationsDockerTypes(t *testing.T) {
+	_, v2 := manifestFixture(t, core.DigestFixture(), core.DigestFixture(), core.DigestFixture())
+	list, _, err := dockerutil.ParseManifestV2List(testManifestListBytes)
+	require.NoError(t, err)
+
+	for name, manifest := range map[string]distribution.Manifest{"v2": v2, "list": list} {
+		t.Run(name, func(t *testing.T) {
+			require := require.New(t)
+
+			annotations, err := dockerutil.GetManifestAnnotations(manifest)
+			require.NoError(err)
+			require.NotNil(annotations)
+			require.Empty(annotations)
+
+			refs, err := dockerutil.GetReferenceAnnotations(manifest)
+			require.NoError(err)
+			require.Empty(refs)
+		})
+	}
+}
+
+func propagateFixture(t *testing.T, indexAnnotations string) (
+	index distribution.Manifest, childDigest core.Digest, child distribution.Manifest, subject core.Digest) {
+
+	subject = core.DigestFixture()
+	b := []byte(fmt.Sprintf(`{
+		"schemaVersion": 2,
+		"mediaType": "application/vnd.oci.image.manifest.v1+json",
+		"config": {"mediaType": "application/vnd.oci.image.config.v1+json", "size": 1, "digest": %q},
+		"layers": [],
+		"subject": {"mediaType": "application/vnd.oci.image.manifest.v1+json", "size": 1, "digest": %q},
+		"annotations": {"org.example.owner": "child"}
+	}`, core.DigestFixture(), subject))
+	child, _, err := distribution.UnmarshalManifest(ocischema.SchemaVersion.MediaType, b)
+	require.NoError(t, err)
+	childDigest, err = core.NewDigester().FromBytes(b)
+	require.NoError(t, err)
+
+	b = []byte(fmt.Sprintf(`{
+		"schemaVersion": 2,
+		"mediaType": "application/vnd.oci.image.index.v1+json",
+		"manifests": [
+			{"mediaType": "application/vnd.oci.image.manifest.v1+json", "size": 1, "digest": %q}
+		],
+		"annotations": %s
+	}`, childDigest, indexAnnotations))
+	index, _, err = distribution.UnmarshalManifest("application/vnd.oci.image.index.v1+json", b)
+	require.NoError(t, err)
+
+	return index, childDigest, child, subject
+}
+
+func TestPropagateAnnotations(t *testing.T) {
+	require := require.New(t)
+
+	index, childDigest, child, subject := propagateFixture(t, `{
+		"org.example.owner": "index",
+		"org.opencontainers.image.created": "2019-11-01T00:00:00Z"
+	}`)
+
+	updated, d, err := dockerutil.PropagateAnnotations(index, childDigest, child)
+	require.NoError(err)
+	require.NotEqual(childDigest, d)
+
+	_, payload, err := updated.Payload()
+	require.NoError(err)
+	expected, err := core.NewDigester().FromBytes(payload)
+	require.NoError(err)
+	require.Equal(expected, d)
+
+	annotations, err := dockerutil.GetManifestAnnotations(updated)
+	require.NoError(err)
+	require.Equal(map[string]string{
+		"org.example.owner":                "child",
+		"org.opencontainers.image.created": "2019-11-01T00:00:00Z",
+	}, annotations)
+
+	s, ok, err := dockerutil.GetManifestSubject(updated)
+	require.NoError(err)
+	require.True(ok)
+	require.Equal(subject, s)
+}
+
+func TestPropagateAnnotationsNoop(t *testing.T) {
+	require := require.New(t)
+
+	index, childDigest, child, _ := propagateFixture(t, `{"org.example.owner": "index"}`)
+
+	updated, d, err := dockerutil.PropagateAnnotations(index, childDigest, child)
+	require.NoError(err)
+	require.Equal(childDigest, d)
+	require.Equal(child, updated)
+}
+
+func TestPropagateAnnotationsErrors(t *testing.T) {
+	index, childDigest, child, _ := propagateFixture(t, `{"org.example.signed": "true"}`)
+	_, v2 := manifestFixture(t, core.DigestFixture(), core.DigestFixture(), core.DigestFixture())
+
+	tests := []struct {
+		desc        string
+		index       distribution.Manifest
+		childDigest core.Digest
+		child       distribution.Manifest
+	}{
+		{"index not a list", child, childDigest, child},
+		{"child not oci", index, childDigest, v2},
+		{"child not in index", index, core.DigestFixture(), child},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			_, _, err := dockerutil.PropagateAnnotations(test.index, test.childDigest, test.child)
+			require.Error(t, err)
+		})
+	}
+
+	t.Run("digest mismatch", func(t *testing.T) {
+		other, otherDigest, _, _ := propagateFixture(t, `{}`)
+		_, _, err := dockerutil.PropagateAnnotations(other, otherDigest, child)
+		require.Error(t, err)
+	})
+}
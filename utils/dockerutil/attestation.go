@@ -0,0 +1,108 @@
+// Copyright (c) 2016-2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package dockerutil
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/docker/distribution"
+	"github.com/docker/distribution/manifest/manifestlist"
+)
+
+const (
+	// _referenceTypeAnnotation is set by buildkit on manifest list entries
+	// which point at attestation manifests rather than runnable images.
+	_referenceTypeAnnotation = "vnd.docker.reference.type"
+	_attestationManifestType = "attestation-manifest"
+)
+
+// _attestationMediaTypes is the set of normalized media types which mark a
+// manifest as an attestation. By default:
+//
+//   - application/vnd.in-toto+json: in-toto statements, e.g. SLSA provenance
+//     and SBOMs produced by buildkit.
+//   - application/vnd.dsse.envelope.v1+json: DSSE envelopes wrapping in-toto
+//     statements, as produced by cosign attest.
+//
+// More can be added with RegisterAttestationMediaType.
+var _attestationMediaTypes = struct {
+	sync.RWMutex
+	m map[string]bool
+}{m: map[string]bool{
+	"application/vnd.in-toto+json":          true,
+	"application/vnd.dsse.envelope.v1+json": true,
+}}
+
+// RegisterAttestationMediaType adds mediaType to the set of media types
+// recognized by IsAttestation.
+func RegisterAttestationMediaType(mediaType string) {
+	_attestationMediaTypes.Lock()
+	defer _attestationMediaTypes.Unlock()
+
+	_attestationMediaTypes.m[NormalizeMediaType(mediaType)] = true
+}
+
+func isAttestationMediaType(mediaType string) bool {
+	_attestationMediaTypes.RLock()
+	defer _attestationMediaTypes.RUnlock()
+
+	return _attestationMediaTypes.m[NormalizeMediaType(mediaType)]
+}
+
+// IsAttestation returns true if manifest is an attestation, i.e. its
+// artifactType or config media type is a recognized attestation media type,
+// or all of its layers are. Manifest lists are never attestations themselves;
+// use IsAttestationDescriptor to classify their entries.
+func IsAttestation(manifest distribution.Manifest) (bool, error) {
+	if _, ok := manifest.(*manifestlist.DeserializedManifestList); ok {
+		return false, nil
+	}
+	layers, err := getLayers(manifest)
+	if err != nil {
+		return false, err
+	}
+	_, payload, err := manifest.Payload()
+	if err != nil {
+		return false, fmt.Errorf("payload: %s", err)
+	}
+	var m struct {
+		ArtifactType string `json:"artifactType"`
+		Config       struct {
+			MediaType string `json:"mediaType"`
+		} `json:"config"`
+	}
+	if err := json.Unmarshal(payload, &m); err != nil {
+		return false, fmt.Errorf("unmarshal payload: %s", err)
+	}
+	if isAttestationMediaType(m.ArtifactType) || isAttestationMediaType(m.Config.MediaType) {
+		return true, nil
+	}
+	if len(layers) == 0 {
+		return false, nil
+	}
+	for _, l := range layers {
+		if !isAttestationMediaType(l.MediaType) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// IsAttestationDescriptor returns true if manifest list entry desc is marked
+// as pointing at an attestation manifest.
+func IsAttestationDescriptor(desc manifestlist.ManifestDescriptor) bool {
+	return desc.Annotations[_referenceTypeAnnotation] == _attestationManifestType
+}
@@ -0,0 +1,148 @@
+// Copyright (c) 2016-2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package dockerutil_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/docker/distribution"
+	"github.com/docker/distribution/manifest/manifestlist"
+	"github.com/docker/distribution/manifest/ocischema"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/kraken/core"
+	"github.com/uber/kraken/utils/dockerutil"
+)
+
+func ociManifestFixture(t *testing.T, artifactType, configType string, layerTypes ...string) distribution.Manifest {
+	return ociManifestWithSubjectFixture(t, "", artifactType, configType, layerTypes...)
+}
+
+// ociManifestWithSubjectFixture is ociManifestFixture, with a subject field
+// referring to subject unless it is empty.
+func ociManifestWithSubjectFixture(
+	t *testing.T, subject, artifactType, configType string, layerTypes ...string) distribution.Manifest {
+
+	var layers string
+	for i, lt := range layerTypes {
+		if i > 0 {
+			layers += ","
+		}
+		layers += fmt.Sprintf(`{"mediaType": %q, "size": 1, "digest": %q}`, lt, core.DigestFixture())
+	}
+	var subjectField string
+	if subject != "" {
+		subjectField = fmt.Sprintf(
+			`, "subject": {"mediaType": "application/vnd.oci.image.manifest.v1+json", "size": 1, "digest": %q}`, subject)
+	}
+	b := []byte(fmt.Sprintf(`{
+		"schemaVersion": 2,
+		"mediaType": "application/vnd.oci.image.manifest.v1+json",
+		"artifactType": %q,
+		"config": {"mediaType": %q, "size": 1, "digest": %q},
+		"layers": [%s]%s
+	}`, artifactType, configType, core.DigestFixture(), layers, subjectField))
+	manifest, _, err := distribution.UnmarshalManifest(ocischema.SchemaVersion.MediaType, b)
+	require.NoError(t, err)
+	return manifest
+}
+
+func TestIsAttestation(t *testing.T) {
+	const (
+		ociConfig = "application/vnd.oci.image.config.v1+json"
+		ociLayer  = "application/vnd.oci.image.layer.v1.tar+gzip"
+		inToto    = "application/vnd.in-toto+json"
+		dsse      = "application/vnd.dsse.envelope.v1+json"
+	)
+
+	tests := []struct {
+		desc     string
+		manifest distribution.Manifest
+		expected bool
+	}{
+		{"image", ociManifestFixture(t, "", ociConfig, ociLayer, ociLayer), false},
+		{"buildkit attestation", ociManifestFixture(t, "", ociConfig, inToto, inToto), true},
+		{"cosign attestation", ociManifestFixture(t, "", ociConfig, dsse), true},
+		{"artifact type", ociManifestFixture(t, inToto, "application/vnd.oci.empty.v1+json", ociLayer), true},
+		{"config media type", ociManifestFixture(t, "", inToto), true},
+		{"media type parameters", ociManifestFixture(t, "", ociConfig, inToto+"; charset=utf-8"), true},
+		{"mixed layers", ociManifestFixture(t, "", ociConfig, inToto, ociLayer), false},
+		{"no layers", ociManifestFixture(t, "", ociConfig), false},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			require := require.New(t)
+
+			result, err := dockerutil.IsAttestation(test.manifest)
+			require.NoError(err)
+			require.Equal(test.expected, result)
+		})
+	}
+}
+
+func TestIsAttestationDockerManifest(t *testing.T) {
+	require := require.New(t)
+
+	_, manifest := manifestFixture(t, core.DigestFixture(), core.DigestFixture(), core.DigestFixture())
+
+	result, err := dockerutil.IsAttestation(manifest)
+	require.NoError(err)
+	require.False(result)
+}
+
+func TestIsAttestationManifestList(t *testing.T) {
+	require := require.New(t)
+
+	index, _, err := dockerutil.BuildOCIIndex([]dockerutil.IndexEntry{{
+		Digest:    core.DigestFixture().String(),
+		Size:      100,
+		MediaType: "application/vnd.oci.image.manifest.v1+json",
+		Platform:  manifestlist.PlatformSpec{OS: "linux", Architecture: "amd64"},
+	}})
+	require.NoError(err)
+
+	result, err := dockerutil.IsAttestation(index)
+	require.NoError(err)
+	require.False(result)
+}
+
+func TestRegisterAttestationMediaType(t *testing.T) {
+	require := require.New(t)
+
+	const custom = "application/vnd.example.attestation.v1+json"
+
+	manifest := ociManifestFixture(t, custom, "application/vnd.oci.empty.v1+json")
+
+	result, err := dockerutil.IsAttestation(manifest)
+	require.NoError(err)
+	require.False(result)
+
+	dockerutil.RegisterAttestationMediaType(custom)
+
+	result, err = dockerutil.IsAttestation(manifest)
+	require.NoError(err)
+	require.True(result)
+}
+
+func TestIsAttestationDescriptor(t *testing.T) {
+	require := require.New(t)
+
+	desc := manifestlist.ManifestDescriptor{
+		Descriptor: distribution.Descriptor{
+			Annotations: map[string]string{"vnd.docker.reference.type": "attestation-manifest"},
+		},
+	}
+	require.True(dockerutil.IsAttestationDescriptor(desc))
+	require.False(dockerutil.IsAttestationDescriptor(manifestlist.ManifestDescriptor{}))
+}
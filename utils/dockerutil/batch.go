@@ -0,0 +1,91 @@
+// Copyright (c) 2016-2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package dockerutil
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+
+	"github.com/docker/distribution"
+	"github.com/uber/kraken/core"
+)
+
+// ParseResult is the result of parsing one input of ParseManifests.
+type ParseResult struct {
+	Manifest distribution.Manifest
+	Digest   core.Digest
+	Err      error
+}
+
+// ParseManifests parses each of inputs like ParseManifest, with up to
+// concurrency inputs parsed at once, e.g. for bulk ingestion. If concurrency
+// is not positive, GOMAXPROCS is used. Results are in the same order as
+// inputs, and an input which fails to parse only sets the Err of its result.
+// If ctx is done before every input is parsed, the remaining results have
+// ctx.Err() as their Err, which is also returned.
+func ParseManifests(ctx context.Context, inputs [][]byte, concurrency int) ([]ParseResult, error) {
+	if concurrency <= 0 {
+		concurrency = runtime.GOMAXPROCS(0)
+	}
+	results := make([]ParseResult, len(inputs))
+	parsed := make([]bool, len(inputs))
+
+	work := make(chan int)
+	go func() {
+		defer close(work)
+		for i := range inputs {
+			select {
+			case work <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency && i < len(inputs); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range work {
+				if ctx.Err() != nil {
+					continue
+				}
+				results[i] = parseManifestInput(inputs[i])
+				parsed[i] = true
+			}
+		}()
+	}
+	wg.Wait()
+
+	var err error
+	for i := range results {
+		if !parsed[i] {
+			err = ctx.Err()
+			results[i].Err = err
+		}
+	}
+	return results, err
+}
+
+func parseManifestInput(b []byte) ParseResult {
+	if int64(len(b)) > DefaultMaxManifestBytes {
+		return ParseResult{Err: fmt.Errorf(
+			"%w: exceeds limit of %d bytes", ErrManifestTooLarge, DefaultMaxManifestBytes)}
+	}
+	manifest, d, err := ParseManifestBytes(b)
+	return ParseResult{Manifest: manifest, Digest: d, Err: err}
+}
@@ -0,0 +1,71 @@
+// Copyright (c) 2016-2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package dockerutil_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/uber/kraken/core"
+	"github.com/uber/kraken/utils/dockerutil"
+)
+
+func TestParseManifests(t *testing.T) {
+	require := require.New(t)
+
+	var inputs [][]byte
+	var expected []core.Digest
+	for i := 0; i < 20; i++ {
+		d, b := dockerutil.ManifestFixture(core.DigestFixture(), core.DigestFixture(), core.DigestFixture())
+		inputs = append(inputs, b)
+		expected = append(expected, d)
+	}
+	// Malformed inputs do not abort the batch.
+	inputs = append(inputs, []byte("not json"))
+
+	for _, concurrency := range []int{0, 1, 4, 100} {
+		results, err := dockerutil.ParseManifests(context.Background(), inputs, concurrency)
+		require.NoError(err)
+		require.Len(results, len(inputs))
+		for i, d := range expected {
+			require.NoError(results[i].Err)
+			require.NotNil(results[i].Manifest)
+			require.Equal(d, results[i].Digest)
+		}
+		require.True(errors.Is(results[len(results)-1].Err, dockerutil.ErrMalformedManifest))
+	}
+}
+
+func TestParseManifestsEmpty(t *testing.T) {
+	results, err := dockerutil.ParseManifests(context.Background(), nil, 4)
+	require.NoError(t, err)
+	require.Empty(t, results)
+}
+
+func TestParseManifestsCanceled(t *testing.T) {
+	require := require.New(t)
+
+	_, b := dockerutil.ManifestFixture(core.DigestFixture(), core.DigestFixture(), core.DigestFixture())
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	results, err := dockerutil.ParseManifests(ctx, [][]byte{b, b, b}, 2)
+	require.Equal(context.Canceled, err)
+	require.Len(results, 3)
+	for _, r := range results {
+		require.Equal(context.Canceled, r.Err)
+	}
+}
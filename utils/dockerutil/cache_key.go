@@ -0,0 +1,66 @@
+// Copyright (c) 2016-2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package dockerutil
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/docker/distribution"
+	"github.com/docker/distribution/manifest/manifestlist"
+	"github.com/uber/kraken/core"
+)
+
+// ImageCacheKey returns a deterministic key identifying the content of an
+// image pull, covering every platform of a manifest list.
+//
+// The key is computed as follows:
+//
+//   - For an image manifest, the key is the manifest digest, i.e.
+//     "sha256:" + hex(sha256(payload)).
+//   - For a manifest list / OCI index, the child manifest digests are
+//     formatted as "sha256:<hex>", sorted lexicographically and joined with
+//     "\n" (no trailing newline). The key is "sha256:" + hex(sha256(joined)).
+//
+// Sorting makes the key of a manifest list independent of the order in which
+// platforms are listed.
+func ImageCacheKey(manifest distribution.Manifest) (string, error) {
+	list, ok := manifest.(*manifestlist.DeserializedManifestList)
+	if !ok {
+		_, payload, err := manifest.Payload()
+		if err != nil {
+			return "", fmt.Errorf("payload: %s", err)
+		}
+		d, err := core.NewDigester().FromBytes(payload)
+		if err != nil {
+			return "", fmt.Errorf("digest payload: %s", err)
+		}
+		return d.String(), nil
+	}
+	children := make([]string, len(list.Manifests))
+	for i, desc := range list.Manifests {
+		d, err := core.ParseDigest(string(desc.Digest))
+		if err != nil {
+			return "", fmt.Errorf("parse digest: %s", err)
+		}
+		children[i] = d.String()
+	}
+	sort.Strings(children)
+	d, err := core.NewDigester().FromBytes([]byte(strings.Join(children, "\n")))
+	if err != nil {
+		return "", fmt.Errorf("digest children: %s", err)
+	}
+	return d.String(), nil
+}
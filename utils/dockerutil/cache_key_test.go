@@ -0,0 +1,78 @@
+// Copyright (c) 2016-2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package dockerutil_test
+
+import (
+	"testing"
+
+	"github.com/docker/distribution/manifest/manifestlist"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/kraken/core"
+	"github.com/uber/kraken/utils/dockerutil"
+)
+
+func TestImageCacheKeyManifest(t *testing.T) {
+	require := require.New(t)
+
+	manifest, d, err := dockerutil.ParseManifestV2(testManifestBytes)
+	require.NoError(err)
+
+	key, err := dockerutil.ImageCacheKey(manifest)
+	require.NoError(err)
+	require.Equal(d.String(), key)
+}
+
+func TestImageCacheKeyManifestListIgnoresOrder(t *testing.T) {
+	require := require.New(t)
+
+	manifest, _, err := dockerutil.ParseManifestV2List(testManifestListBytes)
+	require.NoError(err)
+	list := manifest.(*manifestlist.DeserializedManifestList)
+
+	reversed, err := manifestlist.FromDescriptors([]manifestlist.ManifestDescriptor{
+		list.Manifests[1], list.Manifests[0],
+	})
+	require.NoError(err)
+
+	key, err := dockerutil.ImageCacheKey(list)
+	require.NoError(err)
+	reversedKey, err := dockerutil.ImageCacheKey(reversed)
+	require.NoError(err)
+	require.Equal(key, reversedKey)
+
+	// Documented algorithm: sha256 over the sorted child digests joined by "\n".
+	expected, err := core.NewDigester().FromBytes([]byte(
+		"sha256:1a9ec845ee94c202b2d5da74a24f0ed2058318bfa9879fa541efaecba272e86b\n" +
+			"sha256:6346340964309634683409684360934680934608934608934608934068934608"))
+	require.NoError(err)
+	require.Equal(expected.String(), key)
+}
+
+func TestImageCacheKeyDiffersByContent(t *testing.T) {
+	require := require.New(t)
+
+	_, raw1 := dockerutil.ManifestFixture(core.DigestFixture(), core.DigestFixture(), core.DigestFixture())
+	_, raw2 := dockerutil.ManifestFixture(core.DigestFixture(), core.DigestFixture(), core.DigestFixture())
+
+	m1, _, err := dockerutil.ParseManifestV2(raw1)
+	require.NoError(err)
+	m2, _, err := dockerutil.ParseManifestV2(raw2)
+	require.NoError(err)
+
+	key1, err := dockerutil.ImageCacheKey(m1)
+	require.NoError(err)
+	key2, err := dockerutil.ImageCacheKey(m2)
+	require.NoError(err)
+	require.NotEqual(key1, key2)
+}
@@ -0,0 +1,66 @@
+// Copyright (c) 2016-2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package dockerutil
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/docker/distribution"
+	"github.com/docker/distribution/manifest/schema1"
+	"github.com/uber/kraken/core"
+)
+
+// CanonicalManifestBytes returns the bytes manifest was parsed from, which
+// hash to its digest, and the digest, e.g. for re-emitting a manifest without
+// re-marshaling it. Returns error if parsing the bytes again would not yield
+// the same bytes and digest. For signed schema1 manifests, the returned bytes
+// include signatures and, like a registry, the digest is computed over the
+// canonical payload with signatures removed.
+func CanonicalManifestBytes(manifest distribution.Manifest) ([]byte, core.Digest, error) {
+	mediaType, payload, err := manifest.Payload()
+	if err != nil {
+		return nil, core.Digest{}, fmt.Errorf("payload: %s", err)
+	}
+	reparsed, desc, err := distribution.UnmarshalManifest(mediaType, payload)
+	if err != nil {
+		return nil, core.Digest{}, fmt.Errorf("unmarshal manifest: %s", err)
+	}
+	d, err := core.ParseSHA256Digest(string(desc.Digest))
+	if err != nil {
+		return nil, core.Digest{}, fmt.Errorf("parse digest: %s", err)
+	}
+
+	hashed := payload
+	if sm, ok := reparsed.(*schema1.SignedManifest); ok {
+		hashed = sm.Canonical
+	}
+	computed, err := core.NewDigester().FromBytes(hashed)
+	if err != nil {
+		return nil, core.Digest{}, fmt.Errorf("compute digest: %s", err)
+	}
+	if computed != d {
+		return nil, core.Digest{}, fmt.Errorf(
+			"manifest digest changed on re-serialization: %s != %s", computed, d)
+	}
+	_, reserialized, err := reparsed.Payload()
+	if err != nil {
+		return nil, core.Digest{}, fmt.Errorf("reparsed payload: %s", err)
+	}
+	if !bytes.Equal(payload, reserialized) {
+		return nil, core.Digest{}, errors.New("manifest bytes changed on re-serialization")
+	}
+	return payload, d, nil
+}
@@ -0,0 +1,98 @@
+// Copyright (c) 2016-2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package dockerutil_test
+
+import (
+	"testing"
+
+	"github.com/docker/distribution"
+	"github.com/docker/distribution/manifest/schema1"
+	"github.com/docker/distribution/manifest/schema2"
+	"github.com/docker/libtrust"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/kraken/core"
+	"github.com/uber/kraken/utils/dockerutil"
+)
+
+func TestCanonicalManifestBytes(t *testing.T) {
+	// Whitespace and key order which re-marshaling would not preserve.
+	raw := []byte(`{"schemaVersion":2,
+		"mediaType": "application/vnd.oci.image.manifest.v1+json",
+		"layers": [],
+		"config": {"size": 1, "mediaType": "application/vnd.oci.image.config.v1+json", "digest": "` +
+		core.DigestFixture().String() + `"}}`)
+	oci, _, err := distribution.UnmarshalManifest("application/vnd.oci.image.manifest.v1+json", raw)
+	require.NoError(t, err)
+
+	manifest, _, err := dockerutil.ParseManifestV2(testManifestBytes)
+	require.NoError(t, err)
+	list, _, err := dockerutil.ParseManifestV2List(testManifestListBytes)
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		desc     string
+		manifest distribution.Manifest
+		expected []byte
+	}{
+		{"manifest", manifest, testManifestBytes},
+		{"manifest list", list, testManifestListBytes},
+		{"oci manifest", oci, raw},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			require := require.New(t)
+
+			b, d, err := dockerutil.CanonicalManifestBytes(tc.manifest)
+			require.NoError(err)
+			require.Equal(tc.expected, b)
+
+			computed, err := core.NewDigester().FromBytes(tc.expected)
+			require.NoError(err)
+			require.Equal(computed, d)
+		})
+	}
+}
+
+func TestCanonicalManifestBytesSchema1(t *testing.T) {
+	require := require.New(t)
+
+	key, err := libtrust.GenerateECP256PrivateKey()
+	require.NoError(err)
+	sm, err := schema1.Sign(&schema1.Manifest{
+		Versioned: schema1.SchemaVersion,
+		Name:      "library/hello-world",
+		Tag:       "latest",
+		FSLayers:  []schema1.FSLayer{{BlobSum: "sha256:2db29710123e3e53a794f2694094b9b4338aa9ee5c40b930cb8063a1be392c54"}},
+		History:   []schema1.History{{V1Compatibility: `{"id":"1"}`}},
+	}, key)
+	require.NoError(err)
+
+	_, signed, err := sm.Payload()
+	require.NoError(err)
+	expected, err := core.NewDigester().FromBytes(sm.Canonical)
+	require.NoError(err)
+
+	b, d, err := dockerutil.CanonicalManifestBytes(sm)
+	require.NoError(err)
+	require.Equal(signed, b)
+	require.Equal(expected, d)
+}
+
+func TestCanonicalManifestBytesInvalid(t *testing.T) {
+	manifest, err := schema2.FromStruct(schema2.Manifest{Versioned: schema2.SchemaVersion})
+	require.NoError(t, err)
+	manifest.MediaType = "application/vnd.unknown"
+
+	_, _, err = dockerutil.CanonicalManifestBytes(manifest)
+	require.Error(t, err)
+}
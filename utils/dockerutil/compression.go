@@ -0,0 +1,66 @@
+// Copyright (c) 2016-2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package dockerutil
+
+import "strings"
+
+// Compression is the compression algorithm of a blob, as implied by its media
+// type.
+type Compression int
+
+// Supported compression algorithms.
+const (
+	CompressionNone Compression = iota
+	CompressionGzip
+	CompressionZstd
+)
+
+func (c Compression) String() string {
+	switch c {
+	case CompressionGzip:
+		return "gzip"
+	case CompressionZstd:
+		return "zstd"
+	default:
+		return "none"
+	}
+}
+
+// LayerCompression returns the compression of a layer with the given media
+// type, e.g. CompressionGzip for both
+// "application/vnd.docker.image.rootfs.diff.tar.gzip" and
+// "application/vnd.oci.image.layer.v1.tar+gzip".
+func LayerCompression(mediaType string) Compression {
+	return compressionFromMediaType(mediaType)
+}
+
+// ConfigCompression returns the compression of a config blob with the given
+// media type. Image configs are plain JSON, but OCI artifacts may use
+// compressed configs, which must be decompressed before parsing. Unknown and
+// plain media types return CompressionNone.
+func ConfigCompression(mediaType string) Compression {
+	return compressionFromMediaType(mediaType)
+}
+
+func compressionFromMediaType(mediaType string) Compression {
+	mediaType = NormalizeMediaType(mediaType)
+	switch {
+	case strings.HasSuffix(mediaType, "+gzip"), strings.HasSuffix(mediaType, ".gzip"):
+		return CompressionGzip
+	case strings.HasSuffix(mediaType, "+zstd"), strings.HasSuffix(mediaType, ".zstd"):
+		return CompressionZstd
+	default:
+		return CompressionNone
+	}
+}
@@ -0,0 +1,66 @@
+// Copyright (c) 2016-2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package dockerutil_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/uber/kraken/utils/dockerutil"
+)
+
+func TestLayerCompression(t *testing.T) {
+	tests := []struct {
+		mediaType string
+		expected  dockerutil.Compression
+	}{
+		{"application/vnd.docker.image.rootfs.diff.tar.gzip", dockerutil.CompressionGzip},
+		{"application/vnd.docker.image.rootfs.foreign.diff.tar.gzip", dockerutil.CompressionGzip},
+		{"application/vnd.oci.image.layer.v1.tar+gzip", dockerutil.CompressionGzip},
+		{"application/vnd.oci.image.layer.v1.tar+zstd", dockerutil.CompressionZstd},
+		{"application/vnd.oci.image.layer.v1.tar", dockerutil.CompressionNone},
+		{"", dockerutil.CompressionNone},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mediaType, func(t *testing.T) {
+			require.Equal(t, tt.expected, dockerutil.LayerCompression(tt.mediaType))
+		})
+	}
+}
+
+func TestConfigCompression(t *testing.T) {
+	tests := []struct {
+		mediaType string
+		expected  dockerutil.Compression
+	}{
+		{"application/vnd.docker.container.image.v1+json", dockerutil.CompressionNone},
+		{"application/vnd.oci.image.config.v1+json", dockerutil.CompressionNone},
+		{"application/vnd.example.artifact.config.v1+gzip", dockerutil.CompressionGzip},
+		{"application/vnd.example.artifact.config.v1+ZSTD", dockerutil.CompressionZstd},
+		{"application/vnd.example.artifact.config.v1+gzip; charset=utf-8", dockerutil.CompressionGzip},
+		{"application/octet-stream", dockerutil.CompressionNone},
+		{"", dockerutil.CompressionNone},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mediaType, func(t *testing.T) {
+			require.Equal(t, tt.expected, dockerutil.ConfigCompression(tt.mediaType))
+		})
+	}
+}
+
+func TestCompressionString(t *testing.T) {
+	require.Equal(t, "none", dockerutil.CompressionNone.String())
+	require.Equal(t, "gzip", dockerutil.CompressionGzip.String())
+	require.Equal(t, "zstd", dockerutil.CompressionZstd.String())
+}
@@ -0,0 +1,53 @@
+// Copyright (c) 2016-2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package dockerutil
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/docker/distribution/manifest/schema1"
+	"github.com/uber/kraken/core"
+)
+
+// ContentTrustDigest returns the digest Docker reports for manifest b, which
+// is the digest pinned by Docker Content Trust. For image manifests and
+// manifest lists, Docker digests the bytes exactly as served, so b must not be
+// re-encoded before calling. For signed schema1 manifests, Docker digests the
+// canonical payload with the signatures removed.
+func ContentTrustDigest(b []byte) (core.Digest, error) {
+	if !json.Valid(b) {
+		return core.Digest{}, errors.New("manifest is not valid json")
+	}
+	var versioned struct {
+		SchemaVersion int `json:"schemaVersion"`
+	}
+	if err := json.Unmarshal(b, &versioned); err != nil {
+		return core.Digest{}, fmt.Errorf("unmarshal schema version: %s", err)
+	}
+	payload := b
+	if versioned.SchemaVersion == 1 {
+		var sm schema1.SignedManifest
+		if err := sm.UnmarshalJSON(b); err != nil {
+			return core.Digest{}, fmt.Errorf("unmarshal signed manifest: %s", err)
+		}
+		payload = sm.Canonical
+	}
+	d, err := core.NewDigester().FromBytes(payload)
+	if err != nil {
+		return core.Digest{}, fmt.Errorf("digest: %s", err)
+	}
+	return d, nil
+}
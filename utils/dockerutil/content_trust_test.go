@@ -0,0 +1,111 @@
+// Copyright (c) 2016-2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package dockerutil_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/docker/distribution/manifest/schema1"
+	"github.com/docker/libtrust"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/kraken/core"
+	"github.com/uber/kraken/utils/dockerutil"
+)
+
+// _helloWorldManifest is the linux/amd64 manifest of the hello-world image,
+// byte for byte as served by Docker Hub. Its digest is the one reported by
+// `docker manifest inspect` and `docker pull`.
+const _helloWorldManifest = `{
+   "schemaVersion": 2,
+   "mediaType": "application/vnd.docker.distribution.manifest.v2+json",
+   "config": {
+      "mediaType": "application/vnd.docker.container.image.v1+json",
+      "size": 1469,
+      "digest": "sha256:feb5d9fea6a5e9606aa995e879d862b825965ba48de054caab5ef356dc6b3412"
+   },
+   "layers": [
+      {
+         "mediaType": "application/vnd.docker.image.rootfs.diff.tar.gzip",
+         "size": 2479,
+         "digest": "sha256:2db29710123e3e53a794f2694094b9b4338aa9ee5c40b930cb8063a1be392c54"
+      }
+   ]
+}`
+
+const _helloWorldDigest = "sha256:f54a58bc1aac5ea1a25d796ae155dc228b3f0e11d046ae276b39c4bf2f13d8c4"
+
+func TestContentTrustDigestGolden(t *testing.T) {
+	require := require.New(t)
+
+	d, err := dockerutil.ContentTrustDigest([]byte(_helloWorldManifest))
+	require.NoError(err)
+	require.Equal(_helloWorldDigest, d.String())
+
+	// Agrees with the digest computed when parsing.
+	_, parsed, err := dockerutil.ParseManifest(bytes.NewReader([]byte(_helloWorldManifest)))
+	require.NoError(err)
+	require.Equal(parsed, d)
+}
+
+func TestContentTrustDigestDoesNotReencode(t *testing.T) {
+	require := require.New(t)
+
+	var compact bytes.Buffer
+	require.NoError(json.Compact(&compact, []byte(_helloWorldManifest)))
+
+	d, err := dockerutil.ContentTrustDigest(compact.Bytes())
+	require.NoError(err)
+	require.NotEqual(_helloWorldDigest, d.String())
+}
+
+func TestContentTrustDigestSchema1(t *testing.T) {
+	require := require.New(t)
+
+	key, err := libtrust.GenerateECP256PrivateKey()
+	require.NoError(err)
+
+	sm, err := schema1.Sign(&schema1.Manifest{
+		Versioned:    schema1.SchemaVersion,
+		Name:         "library/hello-world",
+		Tag:          "latest",
+		Architecture: "amd64",
+		FSLayers:     []schema1.FSLayer{{BlobSum: "sha256:2db29710123e3e53a794f2694094b9b4338aa9ee5c40b930cb8063a1be392c54"}},
+		History:      []schema1.History{{V1Compatibility: `{"id":"1"}`}},
+	}, key)
+	require.NoError(err)
+
+	_, b, err := sm.Payload()
+	require.NoError(err)
+
+	expected, err := core.NewDigester().FromBytes(sm.Canonical)
+	require.NoError(err)
+
+	d, err := dockerutil.ContentTrustDigest(b)
+	require.NoError(err)
+	require.Equal(expected, d)
+
+	// Signatures are excluded from the digest.
+	whole, err := core.NewDigester().FromBytes(b)
+	require.NoError(err)
+	require.NotEqual(whole, d)
+}
+
+func TestContentTrustDigestInvalid(t *testing.T) {
+	for _, b := range []string{"", "{", `{"schemaVersion": 1}`} {
+		_, err := dockerutil.ContentTrustDigest([]byte(b))
+		require.Error(t, err, "%q", b)
+	}
+}
@@ -0,0 +1,124 @@
+// Copyright (c) 2016-2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package dockerutil
+
+import (
+	"fmt"
+
+	"github.com/docker/distribution"
+	"github.com/uber/kraken/core"
+)
+
+// DeltaManifest returns the layers of new which must be downloaded by a host
+// which already has the layers of old. added holds the layers of new missing
+// from old, and reusable the layers of new present in old, both in the order
+// they appear in new so the image can be reconstructed. Layers repeated in new
+// are only returned once. Returns error for manifest lists, which do not
+// reference layers directly.
+func DeltaManifest(old, new distribution.Manifest) (
+	added []distribution.Descriptor, reusable []core.Digest, err error) {
+
+	oldLayers, err := getLayers(old)
+	if err != nil {
+		return nil, nil, fmt.Errorf("old: %s", err)
+	}
+	newLayers, err := getLayers(new)
+	if err != nil {
+		return nil, nil, fmt.Errorf("new: %s", err)
+	}
+	inOld := make(map[core.Digest]bool)
+	for _, desc := range oldLayers {
+		d, err := core.ParseDigest(string(desc.Digest))
+		if err != nil {
+			return nil, nil, fmt.Errorf("old: parse digest: %s", err)
+		}
+		inOld[d] = true
+	}
+	seen := make(map[core.Digest]bool)
+	for _, desc := range newLayers {
+		d, err := core.ParseDigest(string(desc.Digest))
+		if err != nil {
+			return nil, nil, fmt.Errorf("new: parse digest: %s", err)
+		}
+		if seen[d] {
+			continue
+		}
+		seen[d] = true
+		if inOld[d] {
+			reusable = append(reusable, d)
+		} else {
+			added = append(added, desc)
+		}
+	}
+	return added, reusable, nil
+}
+
+// DiffManifests returns the digests referenced by a but not b, and by b but
+// not a, each in the order they appear and without repeats, e.g. to find the
+// blobs which still need copying between clusters. References include the
+// config of image manifests and the child manifests of manifest lists.
+func DiffManifests(a, b distribution.Manifest) (onlyInA, onlyInB []core.Digest, err error) {
+	aRefs, err := diffReferences(a)
+	if err != nil {
+		return nil, nil, fmt.Errorf("a: %s", err)
+	}
+	bRefs, err := diffReferences(b)
+	if err != nil {
+		return nil, nil, fmt.Errorf("b: %s", err)
+	}
+	return missingFrom(aRefs, bRefs), missingFrom(bRefs, aRefs), nil
+}
+
+// ManifestsEqual returns true if a and b reference the same set of digests
+// and, for image manifests, the same config, regardless of key order,
+// whitespace or layer order in their payloads.
+func ManifestsEqual(a, b distribution.Manifest) (bool, error) {
+	onlyInA, onlyInB, err := DiffManifests(a, b)
+	if err != nil {
+		return false, err
+	}
+	if len(onlyInA) > 0 || len(onlyInB) > 0 {
+		return false, nil
+	}
+	aConfig, _, aErr := GetManifestBlobs(a)
+	bConfig, _, bErr := GetManifestBlobs(b)
+	if aErr == nil && bErr == nil && aConfig != bConfig {
+		return false, nil
+	}
+	return true, nil
+}
+
+func diffReferences(manifest distribution.Manifest) ([]core.Digest, error) {
+	if err := checkNotNil(manifest); err != nil {
+		return nil, err
+	}
+	return GetManifestReferences(manifest)
+}
+
+// missingFrom returns the digests of refs which are not in other, in order
+// and without repeats.
+func missingFrom(refs, other []core.Digest) []core.Digest {
+	seen := make(map[core.Digest]bool, len(other))
+	for _, d := range other {
+		seen[d] = true
+	}
+	var missing []core.Digest
+	for _, d := range refs {
+		if !seen[d] {
+			missing = append(missing, d)
+			seen[d] = true
+		}
+	}
+	return missing
+}
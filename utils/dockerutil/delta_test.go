@@ -0,0 +1,189 @@
+// Copyright (c) 2016-2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package dockerutil_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/docker/distribution"
+	"github.com/docker/distribution/manifest/schema2"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/kraken/core"
+	"github.com/uber/kraken/utils/dockerutil"
+)
+
+func layeredManifest(t *testing.T, layers ...core.Digest) distribution.Manifest {
+	_, manifest := manifestFixture(t, core.DigestFixture(), layers...)
+	return manifest
+}
+
+func descriptorDigests(t *testing.T, descs []distribution.Descriptor) []core.Digest {
+	var ds []core.Digest
+	for _, desc := range descs {
+		d, err := core.ParseSHA256Digest(string(desc.Digest))
+		require.NoError(t, err)
+		ds = append(ds, d)
+	}
+	return ds
+}
+
+func TestDeltaManifest(t *testing.T) {
+	l := core.DigestListFixture(6)
+
+	tests := []struct {
+		name             string
+		old              []core.Digest
+		new              []core.Digest
+		expectedAdded    []core.Digest
+		expectedReusable []core.Digest
+	}{
+		{
+			name:             "identical",
+			old:              []core.Digest{l[0], l[1]},
+			new:              []core.Digest{l[0], l[1]},
+			expectedAdded:    nil,
+			expectedReusable: []core.Digest{l[0], l[1]},
+		},
+		{
+			name:             "top layers changed",
+			old:              []core.Digest{l[0], l[1], l[2]},
+			new:              []core.Digest{l[0], l[1], l[3], l[4]},
+			expectedAdded:    []core.Digest{l[3], l[4]},
+			expectedReusable: []core.Digest{l[0], l[1]},
+		},
+		{
+			name:             "interleaved changes keep new order",
+			old:              []core.Digest{l[0], l[1], l[2]},
+			new:              []core.Digest{l[5], l[2], l[3], l[0], l[4]},
+			expectedAdded:    []core.Digest{l[5], l[3], l[4]},
+			expectedReusable: []core.Digest{l[2], l[0]},
+		},
+		{
+			name:             "disjoint",
+			old:              []core.Digest{l[0]},
+			new:              []core.Digest{l[1], l[2]},
+			expectedAdded:    []core.Digest{l[1], l[2]},
+			expectedReusable: nil,
+		},
+		{
+			name:             "duplicate layers returned once",
+			old:              []core.Digest{l[0]},
+			new:              []core.Digest{l[0], l[1], l[0], l[1]},
+			expectedAdded:    []core.Digest{l[1]},
+			expectedReusable: []core.Digest{l[0]},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require := require.New(t)
+
+			added, reusable, err := dockerutil.DeltaManifest(
+				layeredManifest(t, tt.old...), layeredManifest(t, tt.new...))
+			require.NoError(err)
+			require.Equal(tt.expectedAdded, descriptorDigests(t, added))
+			require.Equal(tt.expectedReusable, reusable)
+		})
+	}
+}
+
+func TestDeltaManifestAddedKeepsDescriptors(t *testing.T) {
+	require := require.New(t)
+
+	l := core.DigestListFixture(2)
+	new := layeredManifest(t, l[0], l[1])
+
+	added, _, err := dockerutil.DeltaManifest(layeredManifest(t, l[0]), new)
+	require.NoError(err)
+	require.Equal(new.(*schema2.DeserializedManifest).Layers[1:], added)
+}
+
+func TestDeltaManifestManifestListError(t *testing.T) {
+	require := require.New(t)
+
+	list, _, err := dockerutil.ParseManifestV2List(testManifestListBytes)
+	require.NoError(err)
+	manifest := layeredManifest(t, core.DigestFixture())
+
+	_, _, err = dockerutil.DeltaManifest(list, manifest)
+	require.Error(err)
+
+	_, _, err = dockerutil.DeltaManifest(manifest, list)
+	require.Error(err)
+}
+
+func TestDiffManifests(t *testing.T) {
+	require := require.New(t)
+
+	l := core.DigestListFixture(4)
+	config := core.DigestFixture()
+	_, a := manifestFixture(t, config, l[0], l[1])
+	_, b := manifestFixture(t, config, l[1], l[2])
+
+	onlyInA, onlyInB, err := dockerutil.DiffManifests(a, b)
+	require.NoError(err)
+	require.Equal([]core.Digest{l[0]}, onlyInA)
+	require.Equal([]core.Digest{l[2]}, onlyInB)
+
+	onlyInA, onlyInB, err = dockerutil.DiffManifests(a, a)
+	require.NoError(err)
+	require.Empty(onlyInA)
+	require.Empty(onlyInB)
+
+	// Configs differ too.
+	_, c := manifestFixture(t, core.DigestFixture(), l[0], l[0])
+	onlyInA, _, err = dockerutil.DiffManifests(a, c)
+	require.NoError(err)
+	require.Equal([]core.Digest{config, l[1]}, onlyInA)
+
+	_, _, err = dockerutil.DiffManifests(a, nil)
+	require.Error(err)
+}
+
+func TestManifestsEqual(t *testing.T) {
+	require := require.New(t)
+
+	l := core.DigestListFixture(3)
+	config := core.DigestFixture()
+	_, a := manifestFixture(t, config, l[0], l[1])
+
+	// Same references, different formatting.
+	_, raw := dockerutil.ManifestFixture(config, l[0], l[1])
+	var compact bytes.Buffer
+	require.NoError(json.Compact(&compact, raw))
+	b, _, err := dockerutil.ParseManifestV2(compact.Bytes())
+	require.NoError(err)
+
+	equal, err := dockerutil.ManifestsEqual(a, b)
+	require.NoError(err)
+	require.True(equal)
+
+	_, c := manifestFixture(t, config, l[0], l[2])
+	equal, err = dockerutil.ManifestsEqual(a, c)
+	require.NoError(err)
+	require.False(equal)
+
+	// The same digests, with config and layer swapped.
+	_, d := manifestFixture(t, l[0], config, l[1])
+	equal, err = dockerutil.ManifestsEqual(a, d)
+	require.NoError(err)
+	require.False(equal)
+
+	list, _, err := dockerutil.ParseManifestV2List(testManifestListBytes)
+	require.NoError(err)
+	equal, err = dockerutil.ManifestsEqual(list, list)
+	require.NoError(err)
+	require.True(equal)
+}
@@ -0,0 +1,50 @@
+// Copyright (c) 2016-2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package dockerutil
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/docker/distribution"
+	"github.com/uber/kraken/core"
+)
+
+// ErrDescriptorSizeMismatch is returned when a manifest declares a size for a
+// blob which differs from the blob's actual size.
+var ErrDescriptorSizeMismatch = errors.New("descriptor size mismatch")
+
+// ValidateDescriptorSizes checks the size declared by each descriptor in
+// manifest against the size returned by actualSize, returning an
+// ErrDescriptorSizeMismatch error naming the first mismatching digest.
+// Descriptors which actualSize cannot resolve are skipped.
+func ValidateDescriptorSizes(
+	manifest distribution.Manifest, actualSize func(core.Digest) (int64, bool)) error {
+
+	for _, desc := range manifest.References() {
+		d, err := core.ParseDigest(string(desc.Digest))
+		if err != nil {
+			return fmt.Errorf("parse digest: %w", err)
+		}
+		size, ok := actualSize(d)
+		if !ok {
+			continue
+		}
+		if size != desc.Size {
+			return fmt.Errorf(
+				"%w: %s declares %d bytes, actual %d", ErrDescriptorSizeMismatch, d, desc.Size, size)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+// Copyright (c) 2016-2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package dockerutil_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/uber/kraken/core"
+	"github.com/uber/kraken/utils/dockerutil"
+)
+
+func sizes(m map[core.Digest]int64) func(core.Digest) (int64, bool) {
+	return func(d core.Digest) (int64, bool) {
+		size, ok := m[d]
+		return size, ok
+	}
+}
+
+func TestValidateDescriptorSizes(t *testing.T) {
+	config := core.DigestFixture()
+	layer1 := core.DigestFixture()
+	layer2 := core.DigestFixture()
+
+	// Sizes declared by dockerutil.ManifestFixture.
+	const configSize, layer1Size, layer2Size = 2940, 1902063, 2345077
+
+	_, manifest := manifestFixture(t, config, layer1, layer2)
+
+	tests := []struct {
+		desc       string
+		actualSize func(core.Digest) (int64, bool)
+		mismatch   *core.Digest
+	}{
+		{
+			"all match",
+			sizes(map[core.Digest]int64{config: configSize, layer1: layer1Size, layer2: layer2Size}),
+			nil,
+		}, {
+			"unresolved skipped",
+			sizes(map[core.Digest]int64{layer2: layer2Size}),
+			nil,
+		}, {
+			"nothing resolved",
+			sizes(nil),
+			nil,
+		}, {
+			"under reported",
+			sizes(map[core.Digest]int64{config: configSize, layer1: layer1Size + 1}),
+			&layer1,
+		}, {
+			"over reported",
+			sizes(map[core.Digest]int64{layer2: layer2Size - 1}),
+			&layer2,
+		}, {
+			"config mismatch",
+			sizes(map[core.Digest]int64{config: 0}),
+			&config,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			require := require.New(t)
+
+			err := dockerutil.ValidateDescriptorSizes(manifest, test.actualSize)
+			if test.mismatch == nil {
+				require.NoError(err)
+				return
+			}
+			require.True(errors.Is(err, dockerutil.ErrDescriptorSizeMismatch))
+			require.Contains(err.Error(), test.mismatch.String())
+		})
+	}
+}
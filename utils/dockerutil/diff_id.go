@@ -0,0 +1,47 @@
+// Copyright (c) 2016-2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package dockerutil
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+
+	"github.com/uber/kraken/core"
+)
+
+// ComputeDiffID returns the diff id of the layer read from r, i.e. the digest
+// of its uncompressed tar, which can be checked against the diff ids an image
+// config declares. The layer is decompressed with compression as it is read,
+// which is typically derived from the layer's media type via
+// LayerCompression. Zstd layers are not supported.
+func ComputeDiffID(r io.Reader, compression Compression) (core.Digest, error) {
+	switch compression {
+	case CompressionNone:
+	case CompressionGzip:
+		gr, err := gzip.NewReader(r)
+		if err != nil {
+			return core.Digest{}, fmt.Errorf("gzip: %s", err)
+		}
+		defer gr.Close()
+		r = gr
+	default:
+		return core.Digest{}, fmt.Errorf("unsupported compression %s", compression)
+	}
+	d, err := core.NewDigester().FromReader(r)
+	if err != nil {
+		return core.Digest{}, fmt.Errorf("digest: %s", err)
+	}
+	return d, nil
+}
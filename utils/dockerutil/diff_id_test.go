@@ -0,0 +1,80 @@
+// Copyright (c) 2016-2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package dockerutil_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/uber/kraken/core"
+	"github.com/uber/kraken/utils/dockerutil"
+	"github.com/uber/kraken/utils/randutil"
+)
+
+func TestComputeDiffID(t *testing.T) {
+	require := require.New(t)
+
+	layer := randutil.Blob(4096)
+	expected, err := core.NewDigester().FromBytes(layer)
+	require.NoError(err)
+
+	var gzipped bytes.Buffer
+	w := gzip.NewWriter(&gzipped)
+	_, err = w.Write(layer)
+	require.NoError(err)
+	require.NoError(w.Close())
+
+	tests := []struct {
+		desc        string
+		blob        []byte
+		compression dockerutil.Compression
+	}{
+		{"none", layer, dockerutil.CompressionNone},
+		{"gzip", gzipped.Bytes(), dockerutil.LayerCompression("application/vnd.oci.image.layer.v1.tar+gzip")},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			d, err := dockerutil.ComputeDiffID(bytes.NewReader(test.blob), test.compression)
+			require.NoError(err)
+			require.Equal(expected, d)
+		})
+	}
+}
+
+func TestComputeDiffIDErrors(t *testing.T) {
+	var gzipped bytes.Buffer
+	w := gzip.NewWriter(&gzipped)
+	_, err := w.Write(randutil.Blob(4096))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	truncated := gzipped.Bytes()[:gzipped.Len()-4]
+
+	tests := []struct {
+		desc        string
+		blob        []byte
+		compression dockerutil.Compression
+	}{
+		{"not gzip", []byte("not gzip"), dockerutil.CompressionGzip},
+		{"truncated gzip", truncated, dockerutil.CompressionGzip},
+		{"zstd", []byte("zstd"), dockerutil.CompressionZstd},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			_, err := dockerutil.ComputeDiffID(bytes.NewReader(test.blob), test.compression)
+			require.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,41 @@
+// Copyright (c) 2016-2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package dockerutil
+
+import (
+	_ "crypto/sha512" // Registers sha384 / sha512 for digest validation.
+	"fmt"
+	"sort"
+
+	"github.com/docker/distribution"
+)
+
+// DigestAlgorithmsUsed returns the distinct digest algorithms, sorted, of the
+// descriptors referenced by manifest: config and layers for image manifests,
+// child manifests for manifest lists. Returns error if any digest is invalid.
+func DigestAlgorithmsUsed(manifest distribution.Manifest) ([]string, error) {
+	seen := make(map[string]bool)
+	for _, desc := range manifest.References() {
+		if err := desc.Digest.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid digest %q: %s", desc.Digest, err)
+		}
+		seen[desc.Digest.Algorithm().String()] = true
+	}
+	algs := make([]string, 0, len(seen))
+	for alg := range seen {
+		algs = append(algs, alg)
+	}
+	sort.Strings(algs)
+	return algs, nil
+}
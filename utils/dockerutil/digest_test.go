@@ -0,0 +1,86 @@
+// Copyright (c) 2016-2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package dockerutil_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/docker/distribution"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/kraken/core"
+	"github.com/uber/kraken/utils/dockerutil"
+)
+
+func TestDigestAlgorithmsUsed(t *testing.T) {
+	sha512Digest := "sha512:" + strings.Repeat("ab", 64)
+
+	ociManifest := func(config, layer string) []byte {
+		return []byte(fmt.Sprintf(`{
+			"schemaVersion": 2,
+			"mediaType": "application/vnd.oci.image.manifest.v1+json",
+			"config": {
+				"mediaType": "application/vnd.oci.image.config.v1+json",
+				"size": 985,
+				"digest": %q
+			},
+			"layers": [{
+				"mediaType": "application/vnd.oci.image.layer.v1.tar+gzip",
+				"size": 153263,
+				"digest": %q
+			}]
+		}`, config, layer))
+	}
+
+	tests := []struct {
+		desc     string
+		raw      []byte
+		expected []string
+		hasError bool
+	}{
+		{"sha256 only", testOCIManifestBytes, []string{"sha256"}, false},
+		{"sha512 only", ociManifest(sha512Digest, sha512Digest), []string{"sha512"}, false},
+		{"mixed", ociManifest(core.DigestFixture().String(), sha512Digest), []string{"sha256", "sha512"}, false},
+		{"invalid digest", ociManifest(core.DigestFixture().String(), "sha512:abc"), nil, true},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			require := require.New(t)
+
+			manifest, _, err := distribution.UnmarshalManifest(
+				"application/vnd.oci.image.manifest.v1+json", test.raw)
+			require.NoError(err)
+
+			algs, err := dockerutil.DigestAlgorithmsUsed(manifest)
+			if test.hasError {
+				require.Error(err)
+				return
+			}
+			require.NoError(err)
+			require.Equal(test.expected, algs)
+		})
+	}
+}
+
+func TestDigestAlgorithmsUsedManifestList(t *testing.T) {
+	require := require.New(t)
+
+	list, _, err := dockerutil.ParseManifestV2List(testManifestListBytes)
+	require.NoError(err)
+
+	algs, err := dockerutil.DigestAlgorithmsUsed(list)
+	require.NoError(err)
+	require.Equal([]string{"sha256"}, algs)
+}
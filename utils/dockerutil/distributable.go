@@ -0,0 +1,56 @@
+// Copyright (c) 2016-2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package dockerutil
+
+import (
+	"github.com/docker/distribution"
+	"github.com/docker/distribution/manifest/schema2"
+	"github.com/uber/kraken/core"
+	"github.com/uber/kraken/utils/stringset"
+)
+
+// _foreignLayerTypes are the media types of nondistributable layers, whose
+// content is hosted elsewhere, e.g. Windows base layers.
+var _foreignLayerTypes = stringset.New(
+	schema2.MediaTypeForeignLayer,
+	"application/vnd.oci.image.layer.nondistributable.v1.tar",
+	"application/vnd.oci.image.layer.nondistributable.v1.tar+gzip",
+	"application/vnd.oci.image.layer.nondistributable.v1.tar+zstd")
+
+// IsForeignLayer returns true if desc is a nondistributable layer, i.e. has a
+// foreign layer media type or lists URLs to fetch its content from, which
+// Kraken cannot serve.
+func IsForeignLayer(desc distribution.Descriptor) bool {
+	return _foreignLayerTypes.Has(NormalizeMediaType(desc.MediaType)) || len(desc.URLs) > 0
+}
+
+// GetDistributableReferences is like GetManifestReferences, but separates the
+// references Kraken can distribute from foreign layers, per IsForeignLayer,
+// which callers should skip rather than download.
+func GetDistributableReferences(
+	manifest distribution.Manifest) (distributable []core.Digest, foreign []core.Digest, err error) {
+
+	for i, desc := range manifest.References() {
+		d, err := parseReferenceDigest(i, desc)
+		if err != nil {
+			return nil, nil, err
+		}
+		if IsForeignLayer(desc) {
+			foreign = append(foreign, d)
+		} else {
+			distributable = append(distributable, d)
+		}
+	}
+	return distributable, foreign, nil
+}
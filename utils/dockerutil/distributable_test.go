@@ -0,0 +1,79 @@
+// Copyright (c) 2016-2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package dockerutil_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/docker/distribution"
+	"github.com/docker/distribution/manifest/schema2"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/kraken/core"
+	"github.com/uber/kraken/utils/dockerutil"
+)
+
+func TestGetDistributableReferences(t *testing.T) {
+	require := require.New(t)
+
+	config := core.DigestFixture()
+	foreign := core.DigestFixture()
+	layer := core.DigestFixture()
+	b := []byte(fmt.Sprintf(`{
+		"schemaVersion": 2,
+		"mediaType": "application/vnd.docker.distribution.manifest.v2+json",
+		"config": {"mediaType": "application/vnd.docker.container.image.v1+json", "size": 1, "digest": %q},
+		"layers": [
+			{
+				"mediaType": "application/vnd.docker.image.rootfs.foreign.diff.tar.gzip",
+				"size": 1,
+				"digest": %q,
+				"urls": ["https://mcr.microsoft.com/v2/windows/servercore/blobs/foo"]
+			},
+			{"mediaType": "application/vnd.docker.image.rootfs.diff.tar.gzip", "size": 1, "digest": %q}
+		]
+	}`, config, foreign, layer))
+	manifest, _, err := dockerutil.ParseManifestV2(b)
+	require.NoError(err)
+
+	distributable, foreignLayers, err := dockerutil.GetDistributableReferences(manifest)
+	require.NoError(err)
+	require.Equal([]core.Digest{config, layer}, distributable)
+	require.Equal([]core.Digest{foreign}, foreignLayers)
+
+	_, manifest = manifestFixture(t, core.DigestFixture(), core.DigestFixture(), core.DigestFixture())
+	distributable, foreignLayers, err = dockerutil.GetDistributableReferences(manifest)
+	require.NoError(err)
+	require.Len(distributable, 3)
+	require.Empty(foreignLayers)
+}
+
+func TestIsForeignLayer(t *testing.T) {
+	for _, tc := range []struct {
+		desc     string
+		d        distribution.Descriptor
+		expected bool
+	}{
+		{"layer", distribution.Descriptor{MediaType: schema2.MediaTypeLayer}, false},
+		{"docker foreign", distribution.Descriptor{MediaType: schema2.MediaTypeForeignLayer}, true},
+		{"oci nondistributable", distribution.Descriptor{
+			MediaType: "application/vnd.oci.image.layer.nondistributable.v1.tar+gzip"}, true},
+		{"urls", distribution.Descriptor{
+			MediaType: "application/vnd.oci.image.layer.v1.tar+gzip", URLs: []string{"https://example.com"}}, true},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			require.Equal(t, tc.expected, dockerutil.IsForeignLayer(tc.d))
+		})
+	}
+}
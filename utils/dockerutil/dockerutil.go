@@ -17,9 +17,13 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"reflect"
+	"strings"
 
 	"github.com/docker/distribution"
 	"github.com/docker/distribution/manifest/manifestlist"
+	"github.com/docker/distribution/manifest/ocischema"
+	"github.com/docker/distribution/manifest/schema1"
 	"github.com/docker/distribution/manifest/schema2"
 	"github.com/uber/kraken/core"
 )
@@ -27,78 +31,444 @@ import (
 const (
 	_v2ManifestType     = "application/vnd.docker.distribution.manifest.v2+json"
 	_v2ManifestListType = "application/vnd.docker.distribution.manifest.list.v2+json"
+	_ociManifestType    = "application/vnd.oci.image.manifest.v1+json"
+	_ociIndexType       = "application/vnd.oci.image.index.v1+json"
 )
 
+// DefaultMaxManifestReferences is the reference limit applied by
+// GetManifestReferencesLimited when no limit is given. It is far above the
+// layer count of any legitimate image.
+const DefaultMaxManifestReferences = 10000
+
+// DefaultMaxManifestBytes is the size limit applied by ParseManifest and
+// ParseManifestWithMediaType. Operators may override it at startup.
+var DefaultMaxManifestBytes int64 = 16 << 20 // 16MB
+
+// ErrTooManyReferences is returned when a manifest exceeds its reference limit.
+var ErrTooManyReferences = errors.New("manifest has too many references")
+
+// ErrManifestTooLarge is returned when a manifest exceeds its size limit.
+var ErrManifestTooLarge = errors.New("manifest too large")
+
+// ErrMalformedManifest is returned when a manifest is not a JSON object.
+var ErrMalformedManifest = errors.New("malformed manifest")
+
+// ErrUnsupportedMediaType is returned when a manifest declares a media type
+// which cannot be parsed, e.g. an OCI artifact type.
+type ErrUnsupportedMediaType struct {
+	MediaType string
+}
+
+func (e *ErrUnsupportedMediaType) Error() string {
+	return fmt.Sprintf("unsupported manifest media type %q", e.MediaType)
+}
+
 func ParseManifest(r io.Reader) (distribution.Manifest, core.Digest, error) {
-	b, err := io.ReadAll(r)
+	return ParseManifestLimit(r, DefaultMaxManifestBytes)
+}
+
+// ParseManifestLimit is ParseManifest, but returns an ErrManifestTooLarge
+// error, without reading further, if r holds more than maxBytes.
+func ParseManifestLimit(r io.Reader, maxBytes int64) (distribution.Manifest, core.Digest, error) {
+	b, err := readManifest(r, maxBytes)
+	if err != nil {
+		return nil, core.Digest{}, err
+	}
+	return ParseManifestBytes(b)
+}
+
+// ParseManifestBytes is ParseManifest for manifest bytes already in memory.
+// Unlike ParseManifest, b is not checked against DefaultMaxManifestBytes.
+func ParseManifestBytes(b []byte) (distribution.Manifest, core.Digest, error) {
+	manifest, _, d, err := parseManifest(b)
+	return manifest, d, err
+}
+
+// readManifest reads r, reading one byte past maxBytes to detect manifests
+// which exceed it.
+func readManifest(r io.Reader, maxBytes int64) ([]byte, error) {
+	b, err := io.ReadAll(io.LimitReader(r, maxBytes+1))
 	if err != nil {
-		return nil, core.Digest{}, fmt.Errorf("read: %s", err)
+		return nil, fmt.Errorf("read: %s", err)
+	}
+	if int64(len(b)) > maxBytes {
+		return nil, fmt.Errorf("%w: exceeds limit of %d bytes", ErrManifestTooLarge, maxBytes)
 	}
+	return b, nil
+}
 
-	manifest, d, err := ParseManifestV2(b)
-	if err == nil {
-		return manifest, d, err
+// ParseManifestWithMediaType is ParseManifest, but also returns the media type
+// of the parser the manifest was detected as, i.e. either a v2 manifest, a v2
+// manifest list or a signed schema1 manifest, so callers need not serialize
+// the payload to find out.
+func ParseManifestWithMediaType(r io.Reader) (distribution.Manifest, string, core.Digest, error) {
+	b, err := readManifest(r, DefaultMaxManifestBytes)
+	if err != nil {
+		return nil, "", core.Digest{}, err
 	}
+	return parseManifest(b)
+}
 
-	// Retry with v2 manifest list.
-	return ParseManifestV2List(b)
+// parseManifest parses b with the default Parser.
+func parseManifest(b []byte) (distribution.Manifest, string, core.Digest, error) {
+	return _defaultParser.parseManifest(b)
 }
 
-// ParseManifestV2 returns a parsed v2 manifest and its digest.
-func ParseManifestV2(bytes []byte) (distribution.Manifest, core.Digest, error) {
-	manifest, desc, err := distribution.UnmarshalManifest(schema2.MediaTypeManifest, bytes)
+// ErrDigestMismatch is returned by ParseManifestExpecting when a manifest does
+// not hash to the expected digest.
+type ErrDigestMismatch struct {
+	Expected core.Digest
+	Actual   core.Digest
+}
+
+func (e *ErrDigestMismatch) Error() string {
+	return fmt.Sprintf("manifest digest mismatch: expected %s, got %s", e.Expected, e.Actual)
+}
+
+// ParseManifestExpecting is ParseManifest, but also verifies that the manifest
+// bytes hash to expected, for manifests received over untrusted links. Returns
+// *ErrDigestMismatch if they do not.
+func ParseManifestExpecting(r io.Reader, expected core.Digest) (distribution.Manifest, error) {
+	manifest, d, err := ParseManifest(r)
 	if err != nil {
-		return nil, core.Digest{}, fmt.Errorf("unmarshal manifest: %s", err)
+		return nil, err
 	}
-	deserializedManifest, ok := manifest.(*schema2.DeserializedManifest)
-	if !ok {
-		return nil, core.Digest{}, errors.New("expected schema2.DeserializedManifest")
+	if d != expected {
+		return nil, &ErrDigestMismatch{Expected: expected, Actual: d}
 	}
-	version := deserializedManifest.SchemaVersion
-	if version != 2 {
-		return nil, core.Digest{}, fmt.Errorf("unsupported manifest version: %d", version)
+	return manifest, nil
+}
+
+// ParseManifestV2 returns a parsed v2 manifest and its digest. Non-standard
+// spellings of the v2 media type, per NormalizeMediaType, are accepted and
+// rewritten to the canonical type in the parsed payload, while the digest
+// remains that of bytes as given.
+func ParseManifestV2(bytes []byte) (distribution.Manifest, core.Digest, error) {
+	manifest, err := unmarshalManifestV2(bytes)
+	if err != nil {
+		return nil, core.Digest{}, err
 	}
-	d, err := core.ParseSHA256Digest(string(desc.Digest))
+	d, err := core.NewDigester().FromBytes(bytes)
 	if err != nil {
-		return nil, core.Digest{}, fmt.Errorf("parse digest: %s", err)
+		return nil, core.Digest{}, fmt.Errorf("compute digest: %s", err)
 	}
 	return manifest, d, nil
 }
 
+// unmarshalManifestV2 is ParseManifestV2 without computing the digest.
+func unmarshalManifestV2(bytes []byte) (distribution.Manifest, error) {
+	manifest := new(schema2.DeserializedManifest)
+	if err := manifest.UnmarshalJSON(canonicalizeMediaType(bytes, _v2ManifestType)); err != nil {
+		return nil, fmt.Errorf("unmarshal manifest: %s", err)
+	}
+	version := manifest.SchemaVersion
+	if version != 2 {
+		return nil, fmt.Errorf("unsupported manifest version: %d", version)
+	}
+	return manifest, nil
+}
+
 // ParseManifestV2List returns a parsed v2 manifest list and its digest.
 func ParseManifestV2List(bytes []byte) (distribution.Manifest, core.Digest, error) {
-	manifestList, desc, err := distribution.UnmarshalManifest(manifestlist.MediaTypeManifestList, bytes)
+	return parseManifestList(bytes, _v2ManifestListType)
+}
+
+// ErrMediaTypeMismatch is returned when a manifest declares a different media
+// type than the one it is parsed as, e.g. an OCI index parsed as a Docker
+// manifest list, whose structures are otherwise interchangeable.
+var ErrMediaTypeMismatch = errors.New("manifest media type mismatch")
+
+// parseManifestList parses a Docker manifest list or OCI index, which must
+// declare mediaType or a non-standard spelling of it, and returns it along
+// with the digest of bytes.
+func parseManifestList(bytes []byte, mediaType string) (distribution.Manifest, core.Digest, error) {
+	manifestList, err := unmarshalManifestList(bytes, mediaType)
 	if err != nil {
-		return nil, core.Digest{}, fmt.Errorf("unmarshal manifestlist: %s", err)
+		return nil, core.Digest{}, err
 	}
-	deserializedManifestList, ok := manifestList.(*manifestlist.DeserializedManifestList)
-	if !ok {
-		return nil, core.Digest{}, errors.New("expected manifestlist.DeserializedManifestList")
+	d, err := core.NewDigester().FromBytes(bytes)
+	if err != nil {
+		return nil, core.Digest{}, fmt.Errorf("compute digest: %s", err)
 	}
-	version := deserializedManifestList.SchemaVersion
+	return manifestList, d, nil
+}
+
+// unmarshalManifestList is parseManifestList without computing the digest.
+func unmarshalManifestList(bytes []byte, mediaType string) (distribution.Manifest, error) {
+	manifestList := new(manifestlist.DeserializedManifestList)
+	if err := manifestList.UnmarshalJSON(canonicalizeMediaType(bytes, mediaType)); err != nil {
+		return nil, fmt.Errorf("unmarshal manifestlist: %s", err)
+	}
+	if err := checkListMediaType(manifestList, mediaType); err != nil {
+		return nil, err
+	}
+	version := manifestList.SchemaVersion
 	if version != 2 {
-		return nil, core.Digest{}, fmt.Errorf("unsupported manifest list version: %d", version)
+		return nil, fmt.Errorf("unsupported manifest list version: %d", version)
+	}
+	return manifestList, nil
+}
+
+// checkListMediaType returns an ErrMediaTypeMismatch error if manifestList
+// does not declare mediaType.
+func checkListMediaType(manifestList *manifestlist.DeserializedManifestList, mediaType string) error {
+	if manifestList.MediaType != mediaType {
+		return fmt.Errorf("%w: expected %q, got %q", ErrMediaTypeMismatch, mediaType, manifestList.MediaType)
+	}
+	return nil
+}
+
+// ParseManifestV1 returns a parsed signed schema1 manifest and its digest.
+// Like a registry, the digest is computed over the canonical payload with
+// signatures removed.
+func ParseManifestV1(bytes []byte) (distribution.Manifest, core.Digest, error) {
+	manifest, desc, err := distribution.UnmarshalManifest(schema1.MediaTypeSignedManifest, bytes)
+	if err != nil {
+		return nil, core.Digest{}, fmt.Errorf("unmarshal signed manifest: %s", err)
+	}
+	signedManifest, ok := manifest.(*schema1.SignedManifest)
+	if !ok {
+		return nil, core.Digest{}, errors.New("expected schema1.SignedManifest")
+	}
+	version := signedManifest.SchemaVersion
+	if version != 1 {
+		return nil, core.Digest{}, fmt.Errorf("unsupported manifest version: %d", version)
 	}
 	d, err := core.ParseSHA256Digest(string(desc.Digest))
 	if err != nil {
 		return nil, core.Digest{}, fmt.Errorf("parse digest: %s", err)
 	}
-	return manifestList, d, nil
+	return manifest, d, nil
 }
 
 // GetManifestReferences returns a list of references by a V2 manifest
 func GetManifestReferences(manifest distribution.Manifest) ([]core.Digest, error) {
 	var refs []core.Digest
-	for _, desc := range manifest.References() {
-		d, err := core.ParseSHA256Digest(string(desc.Digest))
+	for i, desc := range manifest.References() {
+		d, err := parseReferenceDigest(i, desc)
 		if err != nil {
-			return nil, fmt.Errorf("parse digest: %w", err)
+			return nil, err
 		}
 		refs = append(refs, d)
 	}
 	return refs, nil
 }
 
+// ErrUnsupportedDigestAlgorithm is returned when a manifest references a
+// descriptor by a digest which is neither sha256 nor sha512.
+var ErrUnsupportedDigestAlgorithm = errors.New("unsupported digest algorithm")
+
+// parseReferenceDigest parses the digest of desc, the i-th reference of a
+// manifest, naming the descriptor and its algorithm in the error.
+func parseReferenceDigest(i int, desc distribution.Descriptor) (core.Digest, error) {
+	if algo, _, ok := strings.Cut(string(desc.Digest), ":"); ok && algo != core.SHA256 && algo != core.SHA512 {
+		return core.Digest{}, fmt.Errorf("descriptor %d: %w %s", i, ErrUnsupportedDigestAlgorithm, algo)
+	}
+	d, err := core.ParseDigest(string(desc.Digest))
+	if err != nil {
+		return core.Digest{}, fmt.Errorf("descriptor %d: parse digest: %w", i, err)
+	}
+	return d, nil
+}
+
+// ValidateManifestDigests checks the digest of every reference of manifest,
+// returning the errors of all invalid references joined with errors.Join.
+func ValidateManifestDigests(manifest distribution.Manifest) error {
+	var errs []error
+	for i, desc := range manifest.References() {
+		if _, err := parseReferenceDigest(i, desc); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// GetManifestDescriptors returns the descriptors referenced by manifest, in
+// the order of manifest.References(): the config and then the layers of an
+// image manifest, or the child manifests of a manifest list. Unlike
+// GetManifestReferences, descriptors keep their media type, size, URLs and
+// annotations, and digests are not validated. The returned slice may be
+// modified by the caller. Returns error if manifest is nil.
+func GetManifestDescriptors(manifest distribution.Manifest) ([]distribution.Descriptor, error) {
+	if err := checkNotNil(manifest); err != nil {
+		return nil, err
+	}
+	refs := manifest.References()
+	descs := make([]distribution.Descriptor, len(refs))
+	copy(descs, refs)
+	return descs, nil
+}
+
+// checkNotNil returns error if manifest is nil, including a nil pointer of a
+// manifest type, whose methods would panic.
+func checkNotNil(manifest distribution.Manifest) error {
+	if manifest == nil {
+		return errors.New("nil manifest")
+	}
+	if v := reflect.ValueOf(manifest); v.Kind() == reflect.Ptr && v.IsNil() {
+		return fmt.Errorf("nil %T manifest", manifest)
+	}
+	return nil
+}
+
+// ReferenceWithSize is a blob referenced by a manifest and its size, as
+// declared by the manifest.
+type ReferenceWithSize struct {
+	Digest core.Digest
+	Size   int64
+}
+
+// GetManifestReferencesWithSize is like GetManifestReferences, but keeps the
+// size of each reference. Returns error if any reference is neither sha256
+// nor sha512.
+func GetManifestReferencesWithSize(manifest distribution.Manifest) ([]ReferenceWithSize, error) {
+	var refs []ReferenceWithSize
+	for i, desc := range manifest.References() {
+		d, err := parseReferenceDigest(i, desc)
+		if err != nil {
+			return nil, err
+		}
+		refs = append(refs, ReferenceWithSize{Digest: d, Size: desc.Size})
+	}
+	return refs, nil
+}
+
+// GetManifestReferencesLimited is like GetManifestReferences, but returns
+// ErrTooManyReferences if manifest references more than max blobs. If max is
+// not positive, DefaultMaxManifestReferences is used.
+func GetManifestReferencesLimited(manifest distribution.Manifest, max int) ([]core.Digest, error) {
+	if max <= 0 {
+		max = DefaultMaxManifestReferences
+	}
+	if n := len(manifest.References()); n > max {
+		return nil, fmt.Errorf("%w: %d exceeds limit of %d", ErrTooManyReferences, n, max)
+	}
+	return GetManifestReferences(manifest)
+}
+
+// SharesLayers returns whether manifests a and b reference any common layers,
+// along with the shared layer digests in the order they appear in a. Returns
+// error for manifest lists, which do not reference layers directly.
+func SharesLayers(a, b distribution.Manifest) (bool, []core.Digest, error) {
+	aLayers, err := getLayers(a)
+	if err != nil {
+		return false, nil, fmt.Errorf("a: %s", err)
+	}
+	bLayers, err := getLayers(b)
+	if err != nil {
+		return false, nil, fmt.Errorf("b: %s", err)
+	}
+	inB := make(map[core.Digest]bool)
+	for _, desc := range bLayers {
+		d, err := core.ParseDigest(string(desc.Digest))
+		if err != nil {
+			return false, nil, fmt.Errorf("b: parse digest: %s", err)
+		}
+		inB[d] = true
+	}
+	var shared []core.Digest
+	seen := make(map[core.Digest]bool)
+	for _, desc := range aLayers {
+		d, err := core.ParseDigest(string(desc.Digest))
+		if err != nil {
+			return false, nil, fmt.Errorf("a: parse digest: %s", err)
+		}
+		if inB[d] && !seen[d] {
+			shared = append(shared, d)
+			seen[d] = true
+		}
+	}
+	return len(shared) > 0, shared, nil
+}
+
+// LayerMediaTypeCounts returns the number of layers of manifest per media
+// type. Returns error for manifest lists, whose layer types are only known by
+// their children.
+func LayerMediaTypeCounts(manifest distribution.Manifest) (map[string]int, error) {
+	layers, err := getLayers(manifest)
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int)
+	for _, desc := range layers {
+		counts[desc.MediaType]++
+	}
+	return counts, nil
+}
+
+// GetManifestBlobs returns the config and layer digests of an image manifest
+// separately. Config-only artifacts, e.g. some signatures, have no layers, for
+// which an empty slice is returned. Returns error for manifest lists and OCI
+// indexes, which have no config.
+func GetManifestBlobs(manifest distribution.Manifest) (config core.Digest, layers []core.Digest, err error) {
+	var configDesc distribution.Descriptor
+	var layerDescs []distribution.Descriptor
+	switch m := manifest.(type) {
+	case *schema2.DeserializedManifest:
+		configDesc, layerDescs = m.Config, m.Layers
+	case *ocischema.DeserializedManifest:
+		configDesc, layerDescs = m.Config, m.Layers
+	case *manifestlist.DeserializedManifestList:
+		return core.Digest{}, nil, errors.New("manifest list has no config")
+	default:
+		return core.Digest{}, nil, fmt.Errorf("unsupported manifest type %T", manifest)
+	}
+	config, err = core.ParseDigest(string(configDesc.Digest))
+	if err != nil {
+		return core.Digest{}, nil, fmt.Errorf("parse config digest: %s", err)
+	}
+	layers = make([]core.Digest, 0, len(layerDescs))
+	for _, desc := range layerDescs {
+		d, err := core.ParseDigest(string(desc.Digest))
+		if err != nil {
+			return core.Digest{}, nil, fmt.Errorf("parse layer digest: %s", err)
+		}
+		layers = append(layers, d)
+	}
+	return config, layers, nil
+}
+
+// GetConfigMediaType returns the media type of the config descriptor of an
+// image manifest, as declared, which OCI artifacts such as Helm charts use to
+// identify their type. Returns error for manifest lists and OCI indexes, which
+// have no config.
+func GetConfigMediaType(manifest distribution.Manifest) (string, error) {
+	switch m := manifest.(type) {
+	case *schema2.DeserializedManifest:
+		return m.Config.MediaType, nil
+	case *ocischema.DeserializedManifest:
+		return m.Config.MediaType, nil
+	case *manifestlist.DeserializedManifestList:
+		return "", errors.New("manifest list has no config")
+	default:
+		return "", fmt.Errorf("unsupported manifest type %T", manifest)
+	}
+}
+
+// HasLayers returns true if manifest is an image manifest with at least one
+// layer. Config-only artifacts and manifest lists have none.
+func HasLayers(manifest distribution.Manifest) bool {
+	layers, err := getLayers(manifest)
+	return err == nil && len(layers) > 0
+}
+
+// getLayers returns the layer descriptors of an image manifest. Returns error
+// for manifest lists and other types which do not reference layers directly.
+func getLayers(manifest distribution.Manifest) ([]distribution.Descriptor, error) {
+	switch m := manifest.(type) {
+	case *schema2.DeserializedManifest:
+		return m.Layers, nil
+	case *ocischema.DeserializedManifest:
+		return m.Layers, nil
+	case *manifestlist.DeserializedManifestList:
+		return nil, errors.New("manifest list does not reference layers")
+	default:
+		return nil, fmt.Errorf("unsupported manifest type %T", manifest)
+	}
+}
+
+// GetSupportedManifestTypes returns the media types of the registered manifest
+// types, in order of preference, for use as an Accept header.
 func GetSupportedManifestTypes() string {
-	return fmt.Sprintf("%s,%s", _v2ManifestType, _v2ManifestListType)
+	return _defaultParser.GetSupportedManifestTypes()
 }
@@ -1,10 +1,19 @@
 package dockerutil_test
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
 	"testing"
 
+	"github.com/docker/distribution"
 	"github.com/docker/distribution/manifest/manifestlist"
+	"github.com/docker/distribution/manifest/schema1"
+	"github.com/docker/distribution/manifest/schema2"
+	"github.com/docker/libtrust"
 	"github.com/stretchr/testify/require"
+	"github.com/uber/kraken/core"
 	"github.com/uber/kraken/utils/dockerutil"
 )
 
@@ -53,6 +62,23 @@ var testManifestBytes = []byte(`{
 	]
  }`)
 
+var testOCIManifestBytes = []byte(`{
+	"schemaVersion": 2,
+	"mediaType": "application/vnd.oci.image.manifest.v1+json",
+	"config": {
+	   "mediaType": "application/vnd.oci.image.config.v1+json",
+	   "size": 985,
+	   "digest": "sha256:1a9ec845ee94c202b2d5da74a24f0ed2058318bfa9879fa541efaecba272e86b"
+	},
+	"layers": [
+	   {
+		  "mediaType": "application/vnd.oci.image.layer.v1.tar+gzip",
+		  "size": 153263,
+		  "digest": "sha256:62d8908bee94c202b2d35224a221aaa2058318bfa9879fa541efaecba272331b"
+	   }
+	]
+ }`)
+
 func TestParseManifestV2List(t *testing.T) {
 	require := require.New(t)
 
@@ -88,3 +114,482 @@ func TestParseManifestV2List(t *testing.T) {
 		})
 	}
 }
+
+func TestGetManifestDescriptors(t *testing.T) {
+	require := require.New(t)
+
+	config := core.DigestFixture()
+	layer1 := core.DigestFixture()
+	layer2 := core.DigestFixture()
+	_, manifest := manifestFixture(t, config, layer1, layer2)
+
+	descs, err := dockerutil.GetManifestDescriptors(manifest)
+	require.NoError(err)
+	require.Len(descs, 3)
+	require.Equal(config.String(), string(descs[0].Digest))
+	require.Equal(schema2.MediaTypeImageConfig, descs[0].MediaType)
+	require.Equal(int64(2940), descs[0].Size)
+	require.Equal(layer1.String(), string(descs[1].Digest))
+	require.Equal(schema2.MediaTypeLayer, descs[1].MediaType)
+	require.Equal(int64(1902063), descs[1].Size)
+	require.Equal(layer2.String(), string(descs[2].Digest))
+
+	// The descriptors are a copy.
+	descs[0].Size = 0
+	again, err := dockerutil.GetManifestDescriptors(manifest)
+	require.NoError(err)
+	require.Equal(int64(2940), again[0].Size)
+}
+
+func TestGetManifestDescriptorsNil(t *testing.T) {
+	_, err := dockerutil.GetManifestDescriptors(nil)
+	require.Error(t, err)
+
+	var manifest *schema2.DeserializedManifest
+	_, err = dockerutil.GetManifestDescriptors(manifest)
+	require.Error(t, err)
+}
+
+func TestGetManifestReferencesWithSize(t *testing.T) {
+	require := require.New(t)
+
+	manifest, _, err := dockerutil.ParseManifestV2List(testManifestListBytes)
+	require.NoError(err)
+
+	refs, err := dockerutil.GetManifestReferencesWithSize(manifest)
+	require.NoError(err)
+	require.Len(refs, 2)
+	var total int64
+	for i, desc := range manifest.References() {
+		require.Equal(string(desc.Digest), refs[i].Digest.String())
+		total += refs[i].Size
+	}
+	require.Equal(int64(985+2392), total)
+}
+
+func TestGetManifestReferencesWithSizeSHA512(t *testing.T) {
+	require := require.New(t)
+
+	hex := strings.Repeat("a", 128)
+	manifest, _, err := distribution.UnmarshalManifest(
+		"application/vnd.oci.image.manifest.v1+json", []byte(`{
+	"schemaVersion": 2,
+	"mediaType": "application/vnd.oci.image.manifest.v1+json",
+	"config": {
+		"mediaType": "application/vnd.oci.image.config.v1+json",
+		"size": 100,
+		"digest": "sha512:`+hex+`"
+	},
+	"layers": []
+}`))
+	require.NoError(err)
+
+	refs, err := dockerutil.GetManifestReferencesWithSize(manifest)
+	require.NoError(err)
+	require.Len(refs, 1)
+	require.Equal("sha512", refs[0].Digest.Algo())
+	require.Equal(hex, refs[0].Digest.Hex())
+	require.Equal(int64(100), refs[0].Size)
+}
+
+func TestGetManifestReferencesWithSizeUnsupportedAlgorithm(t *testing.T) {
+	manifest, _, err := distribution.UnmarshalManifest(
+		"application/vnd.oci.image.manifest.v1+json", []byte(`{
+	"schemaVersion": 2,
+	"mediaType": "application/vnd.oci.image.manifest.v1+json",
+	"config": {
+		"mediaType": "application/vnd.oci.image.config.v1+json",
+		"size": 100,
+		"digest": "sha384:`+strings.Repeat("a", 96)+`"
+	},
+	"layers": []
+}`))
+	require.NoError(t, err)
+
+	_, err = dockerutil.GetManifestReferencesWithSize(manifest)
+	require.True(t, errors.Is(err, dockerutil.ErrUnsupportedDigestAlgorithm))
+	require.EqualError(t, err, "descriptor 0: unsupported digest algorithm sha384")
+}
+
+func TestValidateManifestDigests(t *testing.T) {
+	require := require.New(t)
+
+	manifest, _, err := dockerutil.ParseManifestV2List(testManifestListBytes)
+	require.NoError(err)
+	require.NoError(dockerutil.ValidateManifestDigests(manifest))
+
+	manifest, _, err = distribution.UnmarshalManifest(
+		"application/vnd.oci.image.manifest.v1+json", []byte(fmt.Sprintf(`{
+	"schemaVersion": 2,
+	"mediaType": "application/vnd.oci.image.manifest.v1+json",
+	"config": {"mediaType": "application/vnd.oci.image.config.v1+json", "size": 1, "digest": %q},
+	"layers": [
+		{"mediaType": "application/vnd.oci.image.layer.v1.tar+gzip", "size": 1, "digest": "sha384:%s"},
+		{"mediaType": "application/vnd.oci.image.layer.v1.tar+gzip", "size": 1, "digest": "sha256:abc"}
+	]
+}`, core.DigestFixture(), strings.Repeat("a", 96))))
+	require.NoError(err)
+
+	err = dockerutil.ValidateManifestDigests(manifest)
+	require.True(errors.Is(err, dockerutil.ErrUnsupportedDigestAlgorithm))
+	require.Contains(err.Error(), "descriptor 1: unsupported digest algorithm sha384")
+	require.Contains(err.Error(), "descriptor 2: parse digest")
+	require.NotContains(err.Error(), "descriptor 0")
+
+	_, err = dockerutil.GetManifestReferences(manifest)
+	require.EqualError(err, "descriptor 1: unsupported digest algorithm sha384")
+}
+
+func TestSharesLayers(t *testing.T) {
+	layers := core.DigestListFixture(4)
+
+	parse := func(config, layer1, layer2 core.Digest) distribution.Manifest {
+		_, raw := dockerutil.ManifestFixture(config, layer1, layer2)
+		manifest, _, err := dockerutil.ParseManifestV2(raw)
+		require.NoError(t, err)
+		return manifest
+	}
+
+	tests := []struct {
+		name     string
+		a        distribution.Manifest
+		b        distribution.Manifest
+		expected []core.Digest
+	}{
+		{
+			name:     "identical",
+			a:        parse(core.DigestFixture(), layers[0], layers[1]),
+			b:        parse(core.DigestFixture(), layers[0], layers[1]),
+			expected: []core.Digest{layers[0], layers[1]},
+		},
+		{
+			name:     "one shared",
+			a:        parse(core.DigestFixture(), layers[0], layers[1]),
+			b:        parse(core.DigestFixture(), layers[2], layers[1]),
+			expected: []core.Digest{layers[1]},
+		},
+		{
+			name:     "disjoint",
+			a:        parse(core.DigestFixture(), layers[0], layers[1]),
+			b:        parse(core.DigestFixture(), layers[2], layers[3]),
+			expected: nil,
+		},
+		{
+			name:     "duplicate layers reported once",
+			a:        parse(core.DigestFixture(), layers[0], layers[0]),
+			b:        parse(core.DigestFixture(), layers[0], layers[2]),
+			expected: []core.Digest{layers[0]},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require := require.New(t)
+
+			shared, digests, err := dockerutil.SharesLayers(tt.a, tt.b)
+			require.NoError(err)
+			require.Equal(len(tt.expected) > 0, shared)
+			require.Equal(tt.expected, digests)
+		})
+	}
+}
+
+func TestSharesLayersManifestListError(t *testing.T) {
+	require := require.New(t)
+
+	list, _, err := dockerutil.ParseManifestV2List(testManifestListBytes)
+	require.NoError(err)
+	manifest, _, err := dockerutil.ParseManifestV2(testManifestBytes)
+	require.NoError(err)
+
+	_, _, err = dockerutil.SharesLayers(list, manifest)
+	require.Error(err)
+	_, _, err = dockerutil.SharesLayers(manifest, list)
+	require.Error(err)
+}
+
+func TestGetManifestReferencesLimited(t *testing.T) {
+	require := require.New(t)
+
+	layers := core.DigestListFixture(3)
+	manifestDigest, raw := dockerutil.ManifestFixture(layers[0], layers[1], layers[2])
+	manifest, _, err := dockerutil.ParseManifestV2(raw)
+	require.NoError(err)
+	require.NotEmpty(manifestDigest)
+
+	refs, err := dockerutil.GetManifestReferencesLimited(manifest, 3)
+	require.NoError(err)
+	require.Equal([]core.Digest(layers), refs)
+
+	// Non-positive limits use the default.
+	refs, err = dockerutil.GetManifestReferencesLimited(manifest, 0)
+	require.NoError(err)
+	require.Len(refs, 3)
+
+	_, err = dockerutil.GetManifestReferencesLimited(manifest, 2)
+	require.Error(err)
+	require.True(errors.Is(err, dockerutil.ErrTooManyReferences))
+}
+
+func TestLayerMediaTypeCounts(t *testing.T) {
+	require := require.New(t)
+
+	raw := []byte(`{
+	"schemaVersion": 2,
+	"mediaType": "application/vnd.oci.image.manifest.v1+json",
+	"config": {
+		"mediaType": "application/vnd.oci.image.config.v1+json",
+		"size": 100,
+		"digest": "sha256:1a9ec845ee94c202b2d5da74a24f0ed2058318bfa9879fa541efaecba272e86b"
+	},
+	"layers": [
+		{
+			"mediaType": "application/vnd.oci.image.layer.v1.tar+gzip",
+			"size": 200,
+			"digest": "sha256:2a9ec845ee94c202b2d5da74a24f0ed2058318bfa9879fa541efaecba272e86b"
+		},
+		{
+			"mediaType": "application/vnd.oci.image.layer.v1.tar+zstd",
+			"size": 300,
+			"digest": "sha256:3a9ec845ee94c202b2d5da74a24f0ed2058318bfa9879fa541efaecba272e86b"
+		},
+		{
+			"mediaType": "application/vnd.oci.image.layer.v1.tar+gzip",
+			"size": 400,
+			"digest": "sha256:4a9ec845ee94c202b2d5da74a24f0ed2058318bfa9879fa541efaecba272e86b"
+		}
+	]
+}`)
+	manifest, _, err := distribution.UnmarshalManifest("application/vnd.oci.image.manifest.v1+json", raw)
+	require.NoError(err)
+
+	counts, err := dockerutil.LayerMediaTypeCounts(manifest)
+	require.NoError(err)
+	require.Equal(map[string]int{
+		"application/vnd.oci.image.layer.v1.tar+gzip": 2,
+		"application/vnd.oci.image.layer.v1.tar+zstd": 1,
+	}, counts)
+}
+
+func TestLayerMediaTypeCountsManifestListError(t *testing.T) {
+	manifest, _, err := dockerutil.ParseManifestV2List(testManifestListBytes)
+	require.NoError(t, err)
+
+	_, err = dockerutil.LayerMediaTypeCounts(manifest)
+	require.Error(t, err)
+}
+
+func TestParseManifestExpecting(t *testing.T) {
+	require := require.New(t)
+
+	_, d, err := dockerutil.ParseManifestV2(testManifestBytes)
+	require.NoError(err)
+
+	manifest, err := dockerutil.ParseManifestExpecting(bytes.NewReader(testManifestBytes), d)
+	require.NoError(err)
+	_, ok := manifest.(*schema2.DeserializedManifest)
+	require.True(ok)
+
+	// Valid manifest bytes which hash to a different digest.
+	expected := core.DigestFixture()
+	_, err = dockerutil.ParseManifestExpecting(bytes.NewReader(testManifestBytes), expected)
+	var mismatch *dockerutil.ErrDigestMismatch
+	require.True(errors.As(err, &mismatch))
+	require.Equal(expected, mismatch.Expected)
+	require.Equal(d, mismatch.Actual)
+
+	_, err = dockerutil.ParseManifestExpecting(bytes.NewReader([]byte("{}")), d)
+	require.Error(err)
+	require.False(errors.As(err, &mismatch))
+}
+
+func TestParseManifestV1(t *testing.T) {
+	require := require.New(t)
+
+	key, err := libtrust.GenerateECP256PrivateKey()
+	require.NoError(err)
+
+	sm, err := schema1.Sign(&schema1.Manifest{
+		Versioned:    schema1.SchemaVersion,
+		Name:         "library/hello-world",
+		Tag:          "latest",
+		Architecture: "amd64",
+		FSLayers:     []schema1.FSLayer{{BlobSum: "sha256:2db29710123e3e53a794f2694094b9b4338aa9ee5c40b930cb8063a1be392c54"}},
+		History:      []schema1.History{{V1Compatibility: `{"id":"1"}`}},
+	}, key)
+	require.NoError(err)
+
+	_, b, err := sm.Payload()
+	require.NoError(err)
+
+	// Digest is over the canonical payload, as a registry would report it.
+	expected, err := core.NewDigester().FromBytes(sm.Canonical)
+	require.NoError(err)
+
+	_, d, err := dockerutil.ParseManifestV1(b)
+	require.NoError(err)
+	require.Equal(expected, d)
+
+	_, mediaType, d, err := dockerutil.ParseManifestWithMediaType(bytes.NewReader(b))
+	require.NoError(err)
+	require.Equal(schema1.MediaTypeSignedManifest, mediaType)
+	require.Equal(expected, d)
+	require.Contains(dockerutil.GetSupportedManifestTypes(), schema1.MediaTypeSignedManifest)
+
+	parser := dockerutil.NewParser(dockerutil.ParserConfig{DisableSchema1: true})
+
+	_, _, err = parser.ParseManifest(bytes.NewReader(b))
+	require.Error(err)
+	require.NotContains(parser.GetSupportedManifestTypes(), schema1.MediaTypeSignedManifest)
+
+	// Other parsers are unaffected.
+	_, d, err = dockerutil.ParseManifest(bytes.NewReader(b))
+	require.NoError(err)
+	require.Equal(expected, d)
+	require.Contains(dockerutil.GetSupportedManifestTypes(), schema1.MediaTypeSignedManifest)
+
+	_, _, err = parser.ParseManifest(bytes.NewReader(testManifestBytes))
+	require.NoError(err)
+
+	_, _, err = dockerutil.ParseManifestV1(testManifestBytes)
+	require.Error(err)
+}
+
+func TestGetManifestBlobs(t *testing.T) {
+	require := require.New(t)
+
+	config := core.DigestFixture()
+	layer1 := core.DigestFixture()
+	layer2 := core.DigestFixture()
+	_, manifest := manifestFixture(t, config, layer1, layer2)
+
+	c, layers, err := dockerutil.GetManifestBlobs(manifest)
+	require.NoError(err)
+	require.Equal(config, c)
+	require.Equal([]core.Digest{layer1, layer2}, layers)
+}
+
+func TestGetManifestBlobsSHA512(t *testing.T) {
+	require := require.New(t)
+
+	config := core.SHA512DigestFixture()
+	layer1 := core.SHA512DigestFixture()
+	layer2 := core.DigestFixture()
+	_, manifest := manifestFixture(t, config, layer1, layer2)
+
+	c, layers, err := dockerutil.GetManifestBlobs(manifest)
+	require.NoError(err)
+	require.Equal("sha512", c.Algo())
+	require.Equal(config, c)
+	require.Equal([]core.Digest{layer1, layer2}, layers)
+
+	refs, err := dockerutil.GetManifestReferences(manifest)
+	require.NoError(err)
+	require.Equal([]core.Digest{config, layer1, layer2}, refs)
+}
+
+func TestGetManifestBlobsManifestListError(t *testing.T) {
+	manifest, _, err := dockerutil.ParseManifestV2List(testManifestListBytes)
+	require.NoError(t, err)
+
+	_, _, err = dockerutil.GetManifestBlobs(manifest)
+	require.Error(t, err)
+}
+
+func TestParseManifestLimit(t *testing.T) {
+	require := require.New(t)
+
+	size := int64(len(testManifestBytes))
+
+	_, d, err := dockerutil.ParseManifestLimit(bytes.NewReader(testManifestBytes), size)
+	require.NoError(err)
+	_, expected, err := dockerutil.ParseManifestV2(testManifestBytes)
+	require.NoError(err)
+	require.Equal(expected, d)
+
+	_, _, err = dockerutil.ParseManifestLimit(bytes.NewReader(testManifestBytes), size-1)
+	require.True(errors.Is(err, dockerutil.ErrManifestTooLarge))
+}
+
+func TestParseManifestDefaultLimit(t *testing.T) {
+	defer func(max int64) { dockerutil.DefaultMaxManifestBytes = max }(dockerutil.DefaultMaxManifestBytes)
+	dockerutil.DefaultMaxManifestBytes = 10
+
+	_, _, err := dockerutil.ParseManifest(bytes.NewReader(testManifestBytes))
+	require.True(t, errors.Is(err, dockerutil.ErrManifestTooLarge))
+}
+
+func TestParseManifestBytes(t *testing.T) {
+	require := require.New(t)
+
+	manifest, d, err := dockerutil.ParseManifestBytes(testManifestBytes)
+	require.NoError(err)
+	_, ok := manifest.(*schema2.DeserializedManifest)
+	require.True(ok)
+	_, expected, err := dockerutil.ParseManifest(bytes.NewReader(testManifestBytes))
+	require.NoError(err)
+	require.Equal(expected, d)
+
+	manifest, _, err = dockerutil.ParseManifestBytes(testManifestListBytes)
+	require.NoError(err)
+	_, ok = manifest.(*manifestlist.DeserializedManifestList)
+	require.True(ok)
+
+	_, _, err = dockerutil.ParseManifestBytes([]byte("not json"))
+	require.True(errors.Is(err, dockerutil.ErrMalformedManifest))
+}
+
+func TestConfigOnlyArtifact(t *testing.T) {
+	require := require.New(t)
+
+	manifest := ociManifestFixture(
+		t, "application/vnd.dev.cosign.artifact.sig.v1+json", "application/vnd.oci.image.config.v1+json")
+
+	require.False(dockerutil.HasLayers(manifest))
+
+	config, layers, err := dockerutil.GetManifestBlobs(manifest)
+	require.NoError(err)
+	require.Empty(layers)
+
+	refs, err := dockerutil.GetManifestReferences(manifest)
+	require.NoError(err)
+	require.Equal([]core.Digest{config}, refs)
+
+	counts, err := dockerutil.LayerMediaTypeCounts(manifest)
+	require.NoError(err)
+	require.Empty(counts)
+
+	s, err := dockerutil.SummarizeImage(manifest, nil)
+	require.NoError(err)
+	require.Equal(0, s.LayerCount)
+}
+
+func TestGetConfigMediaType(t *testing.T) {
+	require := require.New(t)
+
+	_, manifest := manifestFixture(t, core.DigestFixture(), core.DigestFixture(), core.DigestFixture())
+	mediaType, err := dockerutil.GetConfigMediaType(manifest)
+	require.NoError(err)
+	require.Equal(schema2.MediaTypeImageConfig, mediaType)
+
+	helm := ociManifestFixture(
+		t, "", "application/vnd.cncf.helm.config.v1+json", "application/vnd.cncf.helm.chart.content.v1.tar+gzip")
+	mediaType, err = dockerutil.GetConfigMediaType(helm)
+	require.NoError(err)
+	require.Equal("application/vnd.cncf.helm.config.v1+json", mediaType)
+
+	list, _, err := dockerutil.ParseManifestV2List(testManifestListBytes)
+	require.NoError(err)
+	_, err = dockerutil.GetConfigMediaType(list)
+	require.Error(err)
+}
+
+func TestHasLayers(t *testing.T) {
+	require := require.New(t)
+
+	_, manifest := manifestFixture(t, core.DigestFixture(), core.DigestFixture(), core.DigestFixture())
+	require.True(dockerutil.HasLayers(manifest))
+
+	list, _, err := dockerutil.ParseManifestV2List(testManifestListBytes)
+	require.NoError(err)
+	require.False(dockerutil.HasLayers(list))
+}
@@ -15,12 +15,31 @@ package dockerutil
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/uber/kraken/core"
 )
 
 // ManifestFixture creates a manifest blob for testing purposes.
 func ManifestFixture(config core.Digest, layer1 core.Digest, layer2 core.Digest) (core.Digest, []byte) {
+	return ManifestFixtureWithLayers(config, layer1, layer2)
+}
+
+// _fixtureLayerSizes are the sizes of the layers of manifest fixtures, in turn.
+var _fixtureLayerSizes = []int64{1902063, 2345077}
+
+// ManifestFixtureWithLayers creates a manifest blob with any number of layers
+// for testing purposes.
+func ManifestFixtureWithLayers(config core.Digest, layers ...core.Digest) (core.Digest, []byte) {
+	var descs []string
+	for i, l := range layers {
+		descs = append(descs, fmt.Sprintf(`
+		  {
+			 "mediaType": "application/vnd.docker.image.rootfs.diff.tar.gzip",
+			 "size": %d,
+			 "digest": "%s"
+		  }`, _fixtureLayerSizes[i%len(_fixtureLayerSizes)], l))
+	}
 	raw := []byte(fmt.Sprintf(`{
 	   "schemaVersion": 2,
 	   "mediaType": "application/vnd.docker.distribution.manifest.v2+json",
@@ -29,19 +48,9 @@ func ManifestFixture(config core.Digest, layer1 core.Digest, layer2 core.Digest)
 		  "size": 2940,
 		  "digest": "%s"
 	   },
-	   "layers": [
-		  {
-			 "mediaType": "application/vnd.docker.image.rootfs.diff.tar.gzip",
-			 "size": 1902063,
-			 "digest": "%s"
-		  },
-		  {
-			 "mediaType": "application/vnd.docker.image.rootfs.diff.tar.gzip",
-			 "size": 2345077,
-			 "digest": "%s"
-		  }
+	   "layers": [%s
 	   ]
-	}`, config, layer1, layer2))
+	}`, config, strings.Join(descs, ",")))
 
 	d, err := core.NewDigester().FromBytes(raw)
 	if err != nil {
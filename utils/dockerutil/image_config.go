@@ -0,0 +1,157 @@
+// Copyright (c) 2016-2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package dockerutil
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/docker/distribution"
+)
+
+// imageConfig is the subset of the image config blob (the blob referenced by
+// a manifest's config descriptor) used by this package.
+type imageConfig struct {
+	Created      time.Time `json:"created"`
+	OS           string    `json:"os"`
+	Architecture string    `json:"architecture"`
+	Variant      string    `json:"variant"`
+	Config       struct {
+		Labels map[string]string `json:"Labels"`
+	} `json:"config"`
+	RootFS struct {
+		DiffIDs []string `json:"diff_ids"`
+	} `json:"rootfs"`
+	History []imageHistory `json:"history"`
+}
+
+// imageHistory is a single entry of an image config's build history.
+type imageHistory struct {
+	Created    time.Time `json:"created"`
+	CreatedBy  string    `json:"created_by"`
+	EmptyLayer bool      `json:"empty_layer,omitempty"`
+}
+
+func parseImageConfig(configBytes []byte) (*imageConfig, error) {
+	var c imageConfig
+	if err := json.Unmarshal(configBytes, &c); err != nil {
+		return nil, fmt.Errorf("unmarshal image config: %s", err)
+	}
+	return &c, nil
+}
+
+// GetConfigLabels returns the labels set in an image config. Returns an empty
+// map if the config has no labels.
+func GetConfigLabels(configBytes []byte) (map[string]string, error) {
+	c, err := parseImageConfig(configBytes)
+	if err != nil {
+		return nil, err
+	}
+	if c.Config.Labels == nil {
+		return make(map[string]string), nil
+	}
+	return c.Config.Labels, nil
+}
+
+// RequireLabels returns an error listing every label in required which is not
+// present in labels.
+func RequireLabels(labels map[string]string, required []string) error {
+	var missing []string
+	for _, l := range required {
+		if _, ok := labels[l]; !ok {
+			missing = append(missing, l)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return fmt.Errorf("missing required labels: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// ValidateLayerCount checks that manifest has one layer per diff ID in its
+// image config. History entries marked as empty layers (e.g. ENV or LABEL
+// instructions) have neither a layer nor a diff ID, and are excluded when
+// cross-checking the config's history against its diff IDs.
+func ValidateLayerCount(manifest distribution.Manifest, configBytes []byte) error {
+	layers, err := getLayers(manifest)
+	if err != nil {
+		return err
+	}
+	c, err := parseImageConfig(configBytes)
+	if err != nil {
+		return err
+	}
+	diffIDs := len(c.RootFS.DiffIDs)
+	if len(c.History) > 0 {
+		var nonEmpty int
+		for _, h := range c.History {
+			if !h.EmptyLayer {
+				nonEmpty++
+			}
+		}
+		if nonEmpty != diffIDs {
+			return fmt.Errorf(
+				"image config has %d non-empty history entries but %d diff ids", nonEmpty, diffIDs)
+		}
+	}
+	if len(layers) != diffIDs {
+		return fmt.Errorf(
+			"manifest has %d layers but image config has %d diff ids", len(layers), diffIDs)
+	}
+	return nil
+}
+
+// HistoryEntry is a single step of an image's build history.
+type HistoryEntry struct {
+	// Created is when the step ran. Zero if not recorded.
+	Created time.Time
+
+	// CreatedBy is the command which ran, e.g. a Dockerfile instruction.
+	CreatedBy string
+
+	// EmptyLayer is true if the step did not produce a layer.
+	EmptyLayer bool
+
+	// LayerIndex is the index of the manifest layer, and of the config diff
+	// id, produced by the step. -1 for empty layers.
+	LayerIndex int
+}
+
+// GetHistory returns the build history recorded in an image config, oldest
+// step first. Returns an empty slice if the config has no history.
+func GetHistory(configBytes []byte) ([]HistoryEntry, error) {
+	c, err := parseImageConfig(configBytes)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]HistoryEntry, len(c.History))
+	var layer int
+	for i, h := range c.History {
+		entries[i] = HistoryEntry{
+			Created:    h.Created,
+			CreatedBy:  h.CreatedBy,
+			EmptyLayer: h.EmptyLayer,
+			LayerIndex: -1,
+		}
+		if !h.EmptyLayer {
+			entries[i].LayerIndex = layer
+			layer++
+		}
+	}
+	return entries, nil
+}
@@ -0,0 +1,236 @@
+// Copyright (c) 2016-2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package dockerutil_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/uber/kraken/core"
+	"github.com/uber/kraken/utils/dockerutil"
+)
+
+var testImageConfigBytes = []byte(`{
+	"architecture": "amd64",
+	"os": "linux",
+	"config": {
+		"Env": ["PATH=/usr/local/bin:/usr/bin"],
+		"Labels": {
+			"team": "infra",
+			"approved": "true"
+		}
+	},
+	"rootfs": {
+		"type": "layers",
+		"diff_ids": [
+			"sha256:62d8908bee94c202b2d35224a221aaa2058318bfa9879fa541efaecba272331b"
+		]
+	}
+}`)
+
+func TestGetConfigLabels(t *testing.T) {
+	tests := []struct {
+		name        string
+		configBytes []byte
+		expected    map[string]string
+		hasError    bool
+	}{
+		{
+			name:        "labels",
+			configBytes: testImageConfigBytes,
+			expected:    map[string]string{"team": "infra", "approved": "true"},
+		},
+		{
+			name:        "no labels",
+			configBytes: []byte(`{"config": {"Env": []}}`),
+			expected:    map[string]string{},
+		},
+		{
+			name:        "no config",
+			configBytes: []byte(`{}`),
+			expected:    map[string]string{},
+		},
+		{
+			name:        "malformed",
+			configBytes: []byte(`{`),
+			hasError:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require := require.New(t)
+
+			labels, err := dockerutil.GetConfigLabels(tt.configBytes)
+			if tt.hasError {
+				require.Error(err)
+				return
+			}
+			require.NoError(err)
+			require.Equal(tt.expected, labels)
+		})
+	}
+}
+
+func TestRequireLabels(t *testing.T) {
+	labels := map[string]string{"team": "infra", "approved": ""}
+
+	tests := []struct {
+		name     string
+		required []string
+		hasError bool
+	}{
+		{"all present", []string{"team", "approved"}, false},
+		{"empty value counts as present", []string{"approved"}, false},
+		{"none required", nil, false},
+		{"missing", []string{"team", "owner"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := dockerutil.RequireLabels(labels, tt.required)
+			if tt.hasError {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestValidateLayerCount(t *testing.T) {
+	l := core.DigestListFixture(2)
+
+	tests := []struct {
+		name        string
+		layers      []core.Digest
+		configBytes []byte
+		hasError    bool
+	}{
+		{
+			name:        "match",
+			layers:      l[:1],
+			configBytes: testImageConfigBytes,
+		},
+		{
+			name:   "empty layers in history",
+			layers: l,
+			configBytes: []byte(`{
+				"rootfs": {"diff_ids": ["sha256:a", "sha256:b"]},
+				"history": [{}, {"empty_layer": true}, {}, {"empty_layer": true}]
+			}`),
+		},
+		{
+			name:        "no history",
+			layers:      l,
+			configBytes: []byte(`{"rootfs": {"diff_ids": ["sha256:a", "sha256:b"]}}`),
+		},
+		{
+			name:        "missing layer",
+			layers:      l[:1],
+			configBytes: []byte(`{"rootfs": {"diff_ids": ["sha256:a", "sha256:b"]}}`),
+			hasError:    true,
+		},
+		{
+			name:        "extra layer",
+			layers:      l,
+			configBytes: testImageConfigBytes,
+			hasError:    true,
+		},
+		{
+			name:   "history disagrees with diff ids",
+			layers: l,
+			configBytes: []byte(`{
+				"rootfs": {"diff_ids": ["sha256:a", "sha256:b"]},
+				"history": [{}, {"empty_layer": true}]
+			}`),
+			hasError: true,
+		},
+		{
+			name:        "malformed",
+			layers:      l,
+			configBytes: []byte(`{`),
+			hasError:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := dockerutil.ValidateLayerCount(layeredManifest(t, tt.layers...), tt.configBytes)
+			if tt.hasError {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestGetHistory(t *testing.T) {
+	created := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		configBytes []byte
+		expected    []dockerutil.HistoryEntry
+		hasError    bool
+	}{
+		{
+			name: "history",
+			configBytes: []byte(`{
+				"history": [
+					{"created": "2019-01-01T00:00:00Z", "created_by": "ADD file:abc in /"},
+					{"created": "2019-01-01T00:00:00Z", "created_by": "ENV A=b", "empty_layer": true},
+					{"created_by": "RUN make"}
+				]
+			}`),
+			expected: []dockerutil.HistoryEntry{
+				{Created: created, CreatedBy: "ADD file:abc in /", LayerIndex: 0},
+				{Created: created, CreatedBy: "ENV A=b", EmptyLayer: true, LayerIndex: -1},
+				{CreatedBy: "RUN make", LayerIndex: 1},
+			},
+		},
+		{
+			name:        "no history",
+			configBytes: testImageConfigBytes,
+			expected:    []dockerutil.HistoryEntry{},
+		},
+		{
+			name:        "invalid created",
+			configBytes: []byte(`{"history": [{"created": "yesterday"}]}`),
+			hasError:    true,
+		},
+		{
+			name:        "malformed",
+			configBytes: []byte(`{`),
+			hasError:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require := require.New(t)
+
+			history, err := dockerutil.GetHistory(tt.configBytes)
+			if tt.hasError {
+				require.Error(err)
+				return
+			}
+			require.NoError(err)
+			require.Len(history, len(tt.expected))
+			for i := range tt.expected {
+				require.True(tt.expected[i].Created.Equal(history[i].Created))
+				history[i].Created = tt.expected[i].Created
+			}
+			require.Equal(tt.expected, history)
+		})
+	}
+}
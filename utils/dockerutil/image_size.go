@@ -0,0 +1,103 @@
+// Copyright (c) 2016-2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package dockerutil
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/docker/distribution"
+	"github.com/docker/distribution/manifest/manifestlist"
+	"github.com/docker/distribution/manifest/schema1"
+	"github.com/uber/kraken/core"
+)
+
+// ErrManifestCycle is returned when resolving the manifests referenced by a
+// manifest leads back to a manifest currently being resolved.
+type ErrManifestCycle struct {
+	// Digest is the manifest which was reached a second time.
+	Digest core.Digest
+}
+
+func (e *ErrManifestCycle) Error() string {
+	return fmt.Sprintf("manifest cycle at %s", e.Digest)
+}
+
+// ComputeImageSize returns the sum of the sizes declared by every descriptor
+// referenced by manifest, i.e. its config and layers. Returns error for
+// manifest lists, whose size depends on their children, and for signed
+// schema1 manifests, which declare no sizes.
+func ComputeImageSize(manifest distribution.Manifest) (int64, error) {
+	return ComputeImageSizeRecursive(manifest, nil)
+}
+
+// ComputeImageSizeRecursive is like ComputeImageSize, but sizes manifest
+// lists by resolving each child manifest with resolve and summing their
+// sizes. Blobs shared by several children are counted once per child. If
+// resolve is nil, manifest lists return error.
+func ComputeImageSizeRecursive(
+	manifest distribution.Manifest, resolve ManifestFetcher) (int64, error) {
+
+	return computeImageSize(manifest, resolve, make(map[core.Digest]bool))
+}
+
+// computeImageSize sizes manifest, where visiting holds the manifest lists
+// currently being resolved, to detect cycles.
+func computeImageSize(
+	manifest distribution.Manifest, resolve ManifestFetcher, visiting map[core.Digest]bool) (int64, error) {
+
+	switch manifest.(type) {
+	case *schema1.SignedManifest:
+		return 0, errors.New("schema1 manifests do not declare sizes")
+	case *manifestlist.DeserializedManifestList:
+	default:
+		return sumDescriptorSizes(manifest.References())
+	}
+	if resolve == nil {
+		return 0, errors.New("cannot compute size of manifest list without resolving its manifests")
+	}
+	var total int64
+	for _, desc := range manifest.References() {
+		d, err := core.ParseDigest(string(desc.Digest))
+		if err != nil {
+			return 0, fmt.Errorf("parse digest: %s", err)
+		}
+		if visiting[d] {
+			return 0, &ErrManifestCycle{Digest: d}
+		}
+		child, err := resolve(d)
+		if err != nil {
+			return 0, fmt.Errorf("resolve manifest %s: %s", d, err)
+		}
+		visiting[d] = true
+		size, err := computeImageSize(child, resolve, visiting)
+		delete(visiting, d)
+		if err != nil {
+			return 0, fmt.Errorf("manifest %s: %w", d, err)
+		}
+		total += size
+	}
+	return total, nil
+}
+
+func sumDescriptorSizes(descs []distribution.Descriptor) (int64, error) {
+	var total int64
+	for _, desc := range descs {
+		if desc.Size < 0 {
+			return 0, fmt.Errorf("descriptor %s has negative size %d", desc.Digest, desc.Size)
+		}
+		total += desc.Size
+	}
+	return total, nil
+}
@@ -0,0 +1,136 @@
+// Copyright (c) 2016-2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package dockerutil_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/docker/distribution"
+	"github.com/docker/distribution/manifest/manifestlist"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/kraken/core"
+	"github.com/uber/kraken/utils/dockerutil"
+)
+
+// _fixtureImageSize is the config and layer sizes of manifestFixture.
+const _fixtureImageSize = 2940 + 1902063 + 2345077
+
+func indexFixture(t *testing.T, children ...core.Digest) (core.Digest, distribution.Manifest) {
+	var entries []dockerutil.IndexEntry
+	for i, d := range children {
+		entries = append(entries, dockerutil.IndexEntry{
+			Digest:    d.String(),
+			Size:      100,
+			MediaType: "application/vnd.docker.distribution.manifest.v2+json",
+			Platform:  manifestlist.PlatformSpec{OS: "linux", Architecture: fmt.Sprintf("arch%d", i)},
+		})
+	}
+	index, d, err := dockerutil.BuildOCIIndex(entries)
+	require.NoError(t, err)
+	return d, index
+}
+
+func fetcherFixture(manifests map[core.Digest]distribution.Manifest) dockerutil.ManifestFetcher {
+	return func(d core.Digest) (distribution.Manifest, error) {
+		m, ok := manifests[d]
+		if !ok {
+			return nil, fmt.Errorf("manifest %s not found", d)
+		}
+		return m, nil
+	}
+}
+
+func TestComputeImageSize(t *testing.T) {
+	require := require.New(t)
+
+	_, manifest := manifestFixture(t, core.DigestFixture(), core.DigestFixture(), core.DigestFixture())
+
+	size, err := dockerutil.ComputeImageSize(manifest)
+	require.NoError(err)
+	require.Equal(int64(_fixtureImageSize), size)
+
+	oci := ociManifestFixture(t, "", "application/vnd.oci.image.config.v1+json",
+		"application/vnd.oci.image.layer.v1.tar+gzip", "application/vnd.oci.image.layer.v1.tar+gzip")
+	size, err = dockerutil.ComputeImageSize(oci)
+	require.NoError(err)
+	require.Equal(int64(3), size)
+}
+
+func TestComputeImageSizeManifestListErrors(t *testing.T) {
+	list, _, err := dockerutil.ParseManifestV2List(testManifestListBytes)
+	require.NoError(t, err)
+
+	_, err = dockerutil.ComputeImageSize(list)
+	require.Error(t, err)
+}
+
+func TestComputeImageSizeRecursive(t *testing.T) {
+	require := require.New(t)
+
+	amd64, amd64Manifest := manifestFixture(t, core.DigestFixture(), core.DigestFixture(), core.DigestFixture())
+	arm64, arm64Manifest := manifestFixture(t, core.DigestFixture(), core.DigestFixture(), core.DigestFixture())
+	inner, innerIndex := indexFixture(t, amd64, arm64)
+	_, outerIndex := indexFixture(t, inner, amd64)
+
+	fetch := fetcherFixture(map[core.Digest]distribution.Manifest{
+		amd64: amd64Manifest,
+		arm64: arm64Manifest,
+		inner: innerIndex,
+	})
+
+	size, err := dockerutil.ComputeImageSizeRecursive(innerIndex, fetch)
+	require.NoError(err)
+	require.Equal(int64(2*_fixtureImageSize), size)
+
+	// amd64 is reachable twice and counted twice.
+	size, err = dockerutil.ComputeImageSizeRecursive(outerIndex, fetch)
+	require.NoError(err)
+	require.Equal(int64(3*_fixtureImageSize), size)
+
+	// Non-lists do not need resolve.
+	size, err = dockerutil.ComputeImageSizeRecursive(amd64Manifest, nil)
+	require.NoError(err)
+	require.Equal(int64(_fixtureImageSize), size)
+}
+
+func TestComputeImageSizeRecursiveErrors(t *testing.T) {
+	amd64, amd64Manifest := manifestFixture(t, core.DigestFixture(), core.DigestFixture(), core.DigestFixture())
+	missing := core.DigestFixture()
+	cyclic := core.DigestFixture()
+	_, index := indexFixture(t, amd64, cyclic)
+	_, cyclicIndex := indexFixture(t, cyclic)
+
+	t.Run("resolve error", func(t *testing.T) {
+		_, broken := indexFixture(t, amd64, missing)
+		_, err := dockerutil.ComputeImageSizeRecursive(broken, fetcherFixture(
+			map[core.Digest]distribution.Manifest{amd64: amd64Manifest}))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), missing.String())
+	})
+
+	t.Run("cycle", func(t *testing.T) {
+		_, err := dockerutil.ComputeImageSizeRecursive(index, fetcherFixture(
+			map[core.Digest]distribution.Manifest{amd64: amd64Manifest, cyclic: cyclicIndex}))
+		var cycle *dockerutil.ErrManifestCycle
+		require.True(t, errors.As(err, &cycle))
+		require.Equal(t, cyclic, cycle.Digest)
+	})
+
+	t.Run("no resolve", func(t *testing.T) {
+		_, err := dockerutil.ComputeImageSizeRecursive(index, nil)
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,106 @@
+// Copyright (c) 2016-2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package dockerutil
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/docker/distribution"
+	"github.com/docker/distribution/manifest/manifestlist"
+	"github.com/opencontainers/go-digest"
+	"github.com/uber/kraken/core"
+)
+
+// ParseOCIIndex returns a parsed OCI image index and its digest. Returns an
+// ErrMediaTypeMismatch error if the index does not declare the OCI index
+// media type, e.g. for Docker manifest lists. OCI indexes are not parsed by
+// ParseManifest unless registered with RegisterManifestType.
+func ParseOCIIndex(bytes []byte) (distribution.Manifest, core.Digest, error) {
+	return parseManifestList(bytes, _ociIndexType)
+}
+
+// IndexEntry is a platform manifest to be listed in an OCI index.
+type IndexEntry struct {
+	// Digest is the "sha256:<hex>" digest of the manifest.
+	Digest    string
+	Size      int64
+	MediaType string
+	Platform  manifestlist.PlatformSpec
+}
+
+// BuildOCIIndex assembles an OCI image index listing entries, in order, and
+// returns it along with the digest of its canonical payload. Every entry must
+// have a well-formed digest and a distinct platform.
+func BuildOCIIndex(entries []IndexEntry) (distribution.Manifest, core.Digest, error) {
+	if len(entries) == 0 {
+		return nil, core.Digest{}, errors.New("no entries")
+	}
+	platforms := make(map[string]int)
+	descs := make([]manifestlist.ManifestDescriptor, len(entries))
+	for i, e := range entries {
+		d, err := core.ParseDigest(e.Digest)
+		if err != nil {
+			return nil, core.Digest{}, fmt.Errorf("entry %d: parse digest: %s", i, err)
+		}
+		if e.Size <= 0 {
+			return nil, core.Digest{}, fmt.Errorf("entry %d: invalid size %d", i, e.Size)
+		}
+		if e.MediaType == "" {
+			return nil, core.Digest{}, fmt.Errorf("entry %d: missing media type", i)
+		}
+		if e.Platform.OS == "" || e.Platform.Architecture == "" {
+			return nil, core.Digest{}, fmt.Errorf("entry %d: platform os and architecture required", i)
+		}
+		p := platformKey(e.Platform)
+		if j, ok := platforms[p]; ok {
+			return nil, core.Digest{}, fmt.Errorf("entry %d: duplicate platform %s of entry %d", i, p, j)
+		}
+		platforms[p] = i
+		descs[i] = manifestlist.ManifestDescriptor{
+			Descriptor: distribution.Descriptor{
+				MediaType: e.MediaType,
+				Size:      e.Size,
+				Digest:    digest.Digest(d.String()),
+			},
+			Platform: e.Platform,
+		}
+	}
+	index, err := manifestlist.FromDescriptorsWithMediaType(descs, _ociIndexType)
+	if err != nil {
+		return nil, core.Digest{}, fmt.Errorf("build index: %s", err)
+	}
+	_, payload, err := index.Payload()
+	if err != nil {
+		return nil, core.Digest{}, fmt.Errorf("payload: %s", err)
+	}
+	d, err := core.NewDigester().FromBytes(payload)
+	if err != nil {
+		return nil, core.Digest{}, fmt.Errorf("digest payload: %s", err)
+	}
+	return index, d, nil
+}
+
+// platformKey identifies p for uniqueness checks. Features are not part of
+// the key, since they do not distinguish platforms in practice.
+func platformKey(p manifestlist.PlatformSpec) string {
+	k := p.OS + "/" + p.Architecture
+	if p.Variant != "" {
+		k += "/" + p.Variant
+	}
+	if p.OSVersion != "" {
+		k += ":" + p.OSVersion
+	}
+	return k
+}
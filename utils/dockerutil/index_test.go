@@ -0,0 +1,124 @@
+// Copyright (c) 2016-2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package dockerutil_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/docker/distribution"
+	"github.com/docker/distribution/manifest/manifestlist"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/kraken/core"
+	"github.com/uber/kraken/utils/dockerutil"
+)
+
+func TestBuildOCIIndex(t *testing.T) {
+	require := require.New(t)
+
+	amd64 := core.DigestFixture()
+	arm64 := core.DigestFixture()
+
+	index, d, err := dockerutil.BuildOCIIndex([]dockerutil.IndexEntry{{
+		Digest:    amd64.String(),
+		Size:      100,
+		MediaType: "application/vnd.oci.image.manifest.v1+json",
+		Platform:  manifestlist.PlatformSpec{OS: "linux", Architecture: "amd64"},
+	}, {
+		Digest:    arm64.String(),
+		Size:      200,
+		MediaType: "application/vnd.oci.image.manifest.v1+json",
+		Platform:  manifestlist.PlatformSpec{OS: "linux", Architecture: "arm64", Variant: "v8"},
+	}})
+	require.NoError(err)
+
+	mediaType, payload, err := index.Payload()
+	require.NoError(err)
+	require.Equal("application/vnd.oci.image.index.v1+json", mediaType)
+
+	expected, err := core.NewDigester().FromBytes(payload)
+	require.NoError(err)
+	require.Equal(expected, d)
+
+	// The index round trips through the registered OCI index schema.
+	parsed, desc, err := distribution.UnmarshalManifest(mediaType, payload)
+	require.NoError(err)
+	require.Equal(d.String(), desc.Digest.String())
+
+	refs, err := dockerutil.GetManifestReferences(parsed)
+	require.NoError(err)
+	require.Equal([]core.Digest{amd64, arm64}, refs)
+
+	list := parsed.(*manifestlist.DeserializedManifestList)
+	require.Equal("arm64", list.Manifests[1].Platform.Architecture)
+	require.Equal("v8", list.Manifests[1].Platform.Variant)
+}
+
+func TestBuildOCIIndexErrors(t *testing.T) {
+	linux := manifestlist.PlatformSpec{OS: "linux", Architecture: "amd64"}
+	entry := func(platform manifestlist.PlatformSpec) dockerutil.IndexEntry {
+		return dockerutil.IndexEntry{
+			Digest:    core.DigestFixture().String(),
+			Size:      100,
+			MediaType: "application/vnd.oci.image.manifest.v1+json",
+			Platform:  platform,
+		}
+	}
+	badDigest := entry(linux)
+	badDigest.Digest = "sha256:abc"
+	noMediaType := entry(linux)
+	noMediaType.MediaType = ""
+	noSize := entry(linux)
+	noSize.Size = 0
+
+	tests := []struct {
+		desc    string
+		entries []dockerutil.IndexEntry
+	}{
+		{"empty", nil},
+		{"malformed digest", []dockerutil.IndexEntry{badDigest}},
+		{"missing media type", []dockerutil.IndexEntry{noMediaType}},
+		{"invalid size", []dockerutil.IndexEntry{noSize}},
+		{"missing platform", []dockerutil.IndexEntry{entry(manifestlist.PlatformSpec{})}},
+		{"duplicate platform", []dockerutil.IndexEntry{entry(linux), entry(linux)}},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			_, _, err := dockerutil.BuildOCIIndex(test.entries)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestParseOCIIndex(t *testing.T) {
+	require := require.New(t)
+
+	d, index := indexFixture(t, core.DigestFixture(), core.DigestFixture())
+	_, payload, err := index.Payload()
+	require.NoError(err)
+
+	parsed, parsedDigest, err := dockerutil.ParseOCIIndex(payload)
+	require.NoError(err)
+	require.Equal(d, parsedDigest)
+	require.Equal(index.References(), parsed.References())
+
+	// Docker manifest lists and OCI indexes are not interchangeable.
+	_, _, err = dockerutil.ParseOCIIndex(testManifestListBytes)
+	require.True(errors.Is(err, dockerutil.ErrMediaTypeMismatch))
+	_, _, err = dockerutil.ParseManifestV2List(payload)
+	require.True(errors.Is(err, dockerutil.ErrMediaTypeMismatch))
+
+	_, _, err = dockerutil.ParseOCIIndex([]byte(`{"schemaVersion": 2, "manifests": []}`))
+	require.True(errors.Is(err, dockerutil.ErrMediaTypeMismatch))
+}
@@ -0,0 +1,117 @@
+// Copyright (c) 2016-2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package dockerutil
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/docker/distribution"
+	"github.com/docker/distribution/manifest/ocischema"
+	"github.com/docker/distribution/manifest/schema2"
+	"github.com/uber/kraken/core"
+	"github.com/uber/kraken/utils/stringset"
+)
+
+const _ociImageConfigType = "application/vnd.oci.image.config.v1+json"
+
+var (
+	_v2LayerTypes = stringset.New(
+		schema2.MediaTypeLayer,
+		schema2.MediaTypeForeignLayer,
+		schema2.MediaTypeUncompressedLayer)
+
+	_ociLayerTypes = stringset.New(
+		"application/vnd.oci.image.layer.v1.tar",
+		"application/vnd.oci.image.layer.v1.tar+gzip",
+		"application/vnd.oci.image.layer.v1.tar+zstd",
+		"application/vnd.oci.image.layer.nondistributable.v1.tar",
+		"application/vnd.oci.image.layer.nondistributable.v1.tar+gzip",
+		"application/vnd.oci.image.layer.nondistributable.v1.tar+zstd")
+)
+
+// BuildManifest assembles an image manifest referencing configDesc and
+// layerDescs, in order, and returns it along with the digest of its canonical
+// payload. If ociFormat is set, an OCI image manifest is built, else a docker
+// v2 manifest. Every descriptor must have a well-formed digest, a positive
+// size, and a config or layer media type of the chosen format.
+func BuildManifest(
+	configDesc distribution.Descriptor,
+	layerDescs []distribution.Descriptor,
+	ociFormat bool) (distribution.Manifest, core.Digest, error) {
+
+	configType, layerTypes := schema2.MediaTypeImageConfig, _v2LayerTypes
+	if ociFormat {
+		configType, layerTypes = _ociImageConfigType, _ociLayerTypes
+	}
+	if len(layerDescs) == 0 {
+		return nil, core.Digest{}, errors.New("no layers")
+	}
+	if err := validateDescriptor(configDesc); err != nil {
+		return nil, core.Digest{}, fmt.Errorf("config: %s", err)
+	}
+	if NormalizeMediaType(configDesc.MediaType) != configType {
+		return nil, core.Digest{}, fmt.Errorf(
+			"config: media type %q, expected %q", configDesc.MediaType, configType)
+	}
+	for i, desc := range layerDescs {
+		if err := validateDescriptor(desc); err != nil {
+			return nil, core.Digest{}, fmt.Errorf("layer %d: %s", i, err)
+		}
+		if !layerTypes.Has(NormalizeMediaType(desc.MediaType)) {
+			return nil, core.Digest{}, fmt.Errorf(
+				"layer %d: unsupported media type %q", i, desc.MediaType)
+		}
+	}
+
+	var manifest distribution.Manifest
+	var err error
+	if ociFormat {
+		manifest, err = ocischema.FromStruct(ocischema.Manifest{
+			Versioned: ocischema.SchemaVersion,
+			Config:    configDesc,
+			Layers:    layerDescs,
+		})
+	} else {
+		manifest, err = schema2.FromStruct(schema2.Manifest{
+			Versioned: schema2.SchemaVersion,
+			Config:    configDesc,
+			Layers:    layerDescs,
+		})
+	}
+	if err != nil {
+		return nil, core.Digest{}, fmt.Errorf("build manifest: %s", err)
+	}
+	_, payload, err := manifest.Payload()
+	if err != nil {
+		return nil, core.Digest{}, fmt.Errorf("payload: %s", err)
+	}
+	d, err := core.NewDigester().FromBytes(payload)
+	if err != nil {
+		return nil, core.Digest{}, fmt.Errorf("digest payload: %s", err)
+	}
+	return manifest, d, nil
+}
+
+// validateDescriptor checks that desc has a well-formed digest and a positive
+// size.
+func validateDescriptor(desc distribution.Descriptor) error {
+	if _, err := core.ParseDigest(desc.Digest.String()); err != nil {
+		return fmt.Errorf("parse digest: %s", err)
+	}
+	if desc.Size <= 0 {
+		return fmt.Errorf("invalid size %d", desc.Size)
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+// Copyright (c) 2016-2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package dockerutil
+
+import (
+	"sync"
+
+	"github.com/docker/distribution"
+	"github.com/docker/distribution/manifest/schema1"
+	"github.com/uber/kraken/core"
+)
+
+// ManifestParser parses manifest bytes of a single media type, returning the
+// manifest and its digest.
+type ManifestParser func(b []byte) (distribution.Manifest, core.Digest, error)
+
+type registeredManifestType struct {
+	mediaType string
+	parse     ManifestParser
+
+	// unmarshal, if set, parses b without computing its digest, for types
+	// whose digest is that of the bytes as given. Used when the digest is
+	// already known, e.g. computed while reading.
+	unmarshal func(b []byte) (distribution.Manifest, error)
+}
+
+// parseDigested parses b as t. If d, the digest of b, is given and t's digest
+// is that of b, b is unmarshaled without being hashed again.
+func (t registeredManifestType) parseDigested(
+	b []byte, d *core.Digest) (distribution.Manifest, core.Digest, error) {
+
+	if d == nil || t.unmarshal == nil {
+		return t.parse(b)
+	}
+	manifest, err := t.unmarshal(b)
+	if err != nil {
+		return nil, core.Digest{}, err
+	}
+	return manifest, *d, nil
+}
+
+func unmarshalV2ManifestList(b []byte) (distribution.Manifest, error) {
+	return unmarshalManifestList(b, _v2ManifestListType)
+}
+
+// _manifestRegistry holds the manifest types parsed by ParseManifest, in order
+// of preference.
+var _manifestRegistry = struct {
+	sync.RWMutex
+	types []registeredManifestType
+}{
+	types: []registeredManifestType{
+		{_v2ManifestType, ParseManifestV2, unmarshalManifestV2},
+		{_v2ManifestListType, ParseManifestV2List, unmarshalV2ManifestList},
+		// Signed schema1 digests are of the canonical payload, not the bytes.
+		{schema1.MediaTypeSignedManifest, ParseManifestV1, nil},
+	},
+}
+
+// RegisterManifestType registers parser for manifests of mediaType, which is
+// normalized with NormalizeMediaType first, so ParseManifest parses them and
+// GetSupportedManifestTypes lists them. Registering a media type again
+// replaces its parser but keeps its order of preference; new media types are
+// least preferred. Signed schema1 manifests remain subject to
+// ParserConfig.DisableSchema1.
+func RegisterManifestType(mediaType string, parser ManifestParser) {
+	mediaType = NormalizeMediaType(mediaType)
+
+	_manifestRegistry.Lock()
+	defer _manifestRegistry.Unlock()
+
+	for i, t := range _manifestRegistry.types {
+		if t.mediaType == mediaType {
+			_manifestRegistry.types[i] = registeredManifestType{mediaType: mediaType, parse: parser}
+			return
+		}
+	}
+	_manifestRegistry.types = append(
+		_manifestRegistry.types, registeredManifestType{mediaType: mediaType, parse: parser})
+}
+
+// UnregisterManifestType removes mediaType from the registered manifest types,
+// e.g. to reject a default type entirely. Unknown media types are ignored.
+func UnregisterManifestType(mediaType string) {
+	mediaType = NormalizeMediaType(mediaType)
+
+	_manifestRegistry.Lock()
+	defer _manifestRegistry.Unlock()
+
+	var types []registeredManifestType
+	for _, t := range _manifestRegistry.types {
+		if t.mediaType != mediaType {
+			types = append(types, t)
+		}
+	}
+	_manifestRegistry.types = types
+}
+
+// registeredManifestTypes returns the registered manifest types, in order of
+// preference.
+func registeredManifestTypes() []registeredManifestType {
+	_manifestRegistry.RLock()
+	defer _manifestRegistry.RUnlock()
+
+	types := make([]registeredManifestType, len(_manifestRegistry.types))
+	copy(types, _manifestRegistry.types)
+	return types
+}
@@ -0,0 +1,104 @@
+// Copyright (c) 2016-2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package dockerutil_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/docker/distribution"
+	"github.com/docker/distribution/manifest/ocischema"
+	"github.com/docker/distribution/manifest/schema1"
+	"github.com/docker/distribution/manifest/schema2"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/kraken/core"
+	"github.com/uber/kraken/utils/dockerutil"
+)
+
+func parseOCIManifest(b []byte) (distribution.Manifest, core.Digest, error) {
+	manifest, desc, err := distribution.UnmarshalManifest(ocischema.SchemaVersion.MediaType, b)
+	if err != nil {
+		return nil, core.Digest{}, err
+	}
+	d, err := core.ParseSHA256Digest(string(desc.Digest))
+	if err != nil {
+		return nil, core.Digest{}, err
+	}
+	return manifest, d, nil
+}
+
+func TestRegisterManifestType(t *testing.T) {
+	require := require.New(t)
+
+	b := []byte(fmt.Sprintf(`{
+		"schemaVersion": 2,
+		"mediaType": "application/vnd.oci.image.manifest.v1+json",
+		"config": {"mediaType": "application/vnd.oci.image.config.v1+json", "size": 1, "digest": %q},
+		"layers": []
+	}`, core.DigestFixture()))
+
+	_, _, err := dockerutil.ParseManifest(bytes.NewReader(b))
+	var unsupported *dockerutil.ErrUnsupportedMediaType
+	require.True(errors.As(err, &unsupported))
+
+	dockerutil.RegisterManifestType("application/vnd.oci.image.manifest.v1+json; charset=utf-8", parseOCIManifest)
+	defer dockerutil.UnregisterManifestType(ocischema.SchemaVersion.MediaType)
+
+	manifest, mediaType, _, err := dockerutil.ParseManifestWithMediaType(bytes.NewReader(b))
+	require.NoError(err)
+	require.Equal(ocischema.SchemaVersion.MediaType, mediaType)
+	_, ok := manifest.(*ocischema.DeserializedManifest)
+	require.True(ok)
+
+	require.Equal(
+		schema2.MediaTypeManifest+","+
+			"application/vnd.docker.distribution.manifest.list.v2+json,"+
+			schema1.MediaTypeSignedManifest+","+
+			ocischema.SchemaVersion.MediaType,
+		dockerutil.GetSupportedManifestTypes())
+	require.Equal([]dockerutil.ManifestType{
+		dockerutil.ManifestV2, dockerutil.ManifestV2List, dockerutil.OCIManifest,
+	}, dockerutil.SupportedManifestTypes())
+}
+
+func TestRegisterManifestTypeReplacesParser(t *testing.T) {
+	require := require.New(t)
+
+	parserErr := errors.New("replaced parser")
+	dockerutil.RegisterManifestType(schema2.MediaTypeManifest, func([]byte) (distribution.Manifest, core.Digest, error) {
+		return nil, core.Digest{}, parserErr
+	})
+	defer dockerutil.RegisterManifestType(schema2.MediaTypeManifest, dockerutil.ParseManifestV2)
+
+	_, _, err := dockerutil.ParseManifest(bytes.NewReader(testManifestBytes))
+	require.Equal(parserErr, err)
+
+	// Order of preference is kept.
+	require.Equal(dockerutil.ManifestV2, dockerutil.SupportedManifestTypes()[0])
+}
+
+func TestUnregisterManifestType(t *testing.T) {
+	require := require.New(t)
+
+	dockerutil.UnregisterManifestType(schema1.MediaTypeSignedManifest)
+	defer dockerutil.RegisterManifestType(schema1.MediaTypeSignedManifest, dockerutil.ParseManifestV1)
+
+	require.NotContains(dockerutil.GetSupportedManifestTypes(), schema1.MediaTypeSignedManifest)
+	_, _, err := dockerutil.ParseManifest(bytes.NewReader([]byte(`{"schemaVersion": 1}`)))
+	var unsupported *dockerutil.ErrUnsupportedMediaType
+	require.True(errors.As(err, &unsupported))
+	require.Equal(schema1.MediaTypeSignedManifest, unsupported.MediaType)
+}
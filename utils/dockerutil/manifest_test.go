@@ -0,0 +1,139 @@
+// Copyright (c) 2016-2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package dockerutil_test
+
+import (
+	"testing"
+
+	"github.com/docker/distribution"
+	"github.com/docker/distribution/manifest/ocischema"
+	"github.com/docker/distribution/manifest/schema2"
+	"github.com/opencontainers/go-digest"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/kraken/core"
+	"github.com/uber/kraken/utils/dockerutil"
+)
+
+func descriptorFixture(mediaType string) distribution.Descriptor {
+	return distribution.Descriptor{
+		MediaType: mediaType,
+		Size:      100,
+		Digest:    digest.Digest(core.DigestFixture().String()),
+	}
+}
+
+func TestBuildManifest(t *testing.T) {
+	tests := []struct {
+		desc       string
+		ociFormat  bool
+		configType string
+		layerType  string
+		mediaType  string
+	}{
+		{
+			"v2",
+			false,
+			"application/vnd.docker.container.image.v1+json",
+			"application/vnd.docker.image.rootfs.diff.tar.gzip",
+			"application/vnd.docker.distribution.manifest.v2+json",
+		}, {
+			"oci",
+			true,
+			"application/vnd.oci.image.config.v1+json",
+			"application/vnd.oci.image.layer.v1.tar+zstd",
+			"application/vnd.oci.image.manifest.v1+json",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			require := require.New(t)
+
+			config := descriptorFixture(test.configType)
+			layers := []distribution.Descriptor{
+				descriptorFixture(test.layerType),
+				descriptorFixture(test.layerType),
+			}
+			manifest, d, err := dockerutil.BuildManifest(config, layers, test.ociFormat)
+			require.NoError(err)
+
+			mediaType, payload, err := manifest.Payload()
+			require.NoError(err)
+			require.Equal(test.mediaType, mediaType)
+
+			expected, err := core.NewDigester().FromBytes(payload)
+			require.NoError(err)
+			require.Equal(expected, d)
+
+			// The manifest round trips through the registered schema.
+			parsed, desc, err := distribution.UnmarshalManifest(mediaType, payload)
+			require.NoError(err)
+			require.Equal(d.String(), desc.Digest.String())
+			require.Equal(append([]distribution.Descriptor{config}, layers...), parsed.References())
+		})
+	}
+}
+
+func TestBuildManifestTypes(t *testing.T) {
+	require := require.New(t)
+
+	manifest, _, err := dockerutil.BuildManifest(
+		descriptorFixture(schema2.MediaTypeImageConfig),
+		[]distribution.Descriptor{descriptorFixture(schema2.MediaTypeLayer)},
+		false)
+	require.NoError(err)
+	require.IsType(&schema2.DeserializedManifest{}, manifest)
+
+	manifest, _, err = dockerutil.BuildManifest(
+		descriptorFixture("application/vnd.oci.image.config.v1+json"),
+		[]distribution.Descriptor{descriptorFixture("application/vnd.oci.image.layer.v1.tar")},
+		true)
+	require.NoError(err)
+	require.IsType(&ocischema.DeserializedManifest{}, manifest)
+}
+
+func TestBuildManifestErrors(t *testing.T) {
+	v2Config := descriptorFixture(schema2.MediaTypeImageConfig)
+	v2Layer := descriptorFixture(schema2.MediaTypeLayer)
+
+	noDigest := v2Layer
+	noDigest.Digest = ""
+
+	noSize := v2Layer
+	noSize.Size = 0
+
+	tests := []struct {
+		desc      string
+		config    distribution.Descriptor
+		layers    []distribution.Descriptor
+		ociFormat bool
+	}{
+		{"no layers", v2Config, nil, false},
+		{"missing digest", v2Config, []distribution.Descriptor{noDigest}, false},
+		{"missing size", v2Config, []distribution.Descriptor{noSize}, false},
+		{"missing config digest", noDigest, []distribution.Descriptor{v2Layer}, false},
+		{"oci config in v2 manifest", descriptorFixture("application/vnd.oci.image.config.v1+json"),
+			[]distribution.Descriptor{v2Layer}, false},
+		{"v2 config in oci manifest", v2Config,
+			[]distribution.Descriptor{descriptorFixture("application/vnd.oci.image.layer.v1.tar")}, true},
+		{"v2 layer in oci manifest", descriptorFixture("application/vnd.oci.image.config.v1+json"),
+			[]distribution.Descriptor{v2Layer}, true},
+		{"missing layer media type", v2Config, []distribution.Descriptor{descriptorFixture("")}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			_, _, err := dockerutil.BuildManifest(test.config, test.layers, test.ociFormat)
+			require.Error(t, err)
+		})
+	}
+}
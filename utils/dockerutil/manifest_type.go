@@ -0,0 +1,63 @@
+// Copyright (c) 2016-2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package dockerutil
+
+// ManifestType identifies a manifest format by its media type.
+type ManifestType int
+
+// Manifest types. The zero value is not a valid type.
+const (
+	ManifestV2 ManifestType = iota + 1
+	ManifestV2List
+	OCIManifest
+	OCIIndex
+)
+
+var _manifestTypeMediaTypes = map[ManifestType]string{
+	ManifestV2:     _v2ManifestType,
+	ManifestV2List: _v2ManifestListType,
+	OCIManifest:    _ociManifestType,
+	OCIIndex:       _ociIndexType,
+}
+
+// String returns the media type of t, or empty string if t is invalid.
+func (t ManifestType) String() string {
+	return _manifestTypeMediaTypes[t]
+}
+
+// ManifestTypeFromMediaType returns the ManifestType of media type s, which is
+// normalized with NormalizeMediaType first. Returns false if s is unknown.
+func ManifestTypeFromMediaType(s string) (ManifestType, bool) {
+	s = NormalizeMediaType(s)
+	for t, mediaType := range _manifestTypeMediaTypes {
+		if mediaType == s {
+			return t, true
+		}
+	}
+	return 0, false
+}
+
+// SupportedManifestTypes returns the manifest types parsed by ParseManifest,
+// in order of preference, per RegisterManifestType. Signed schema1 manifests,
+// and other registered media types without a ManifestType, are only listed by
+// GetSupportedManifestTypes.
+func SupportedManifestTypes() []ManifestType {
+	var types []ManifestType
+	for _, r := range registeredManifestTypes() {
+		if t, ok := ManifestTypeFromMediaType(r.mediaType); ok {
+			types = append(types, t)
+		}
+	}
+	return types
+}
@@ -0,0 +1,70 @@
+// Copyright (c) 2016-2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package dockerutil_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/docker/distribution/manifest/manifestlist"
+	"github.com/docker/distribution/manifest/schema2"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/kraken/utils/dockerutil"
+)
+
+func TestManifestTypeString(t *testing.T) {
+	tests := []struct {
+		manifestType dockerutil.ManifestType
+		expected     string
+	}{
+		{dockerutil.ManifestV2, schema2.MediaTypeManifest},
+		{dockerutil.ManifestV2List, manifestlist.MediaTypeManifestList},
+		{dockerutil.OCIManifest, "application/vnd.oci.image.manifest.v1+json"},
+		{dockerutil.OCIIndex, "application/vnd.oci.image.index.v1+json"},
+		{dockerutil.ManifestType(0), ""},
+	}
+	for _, test := range tests {
+		t.Run(test.expected, func(t *testing.T) {
+			require.Equal(t, test.expected, test.manifestType.String())
+		})
+	}
+}
+
+func TestManifestTypeFromMediaType(t *testing.T) {
+	require := require.New(t)
+
+	for _, mt := range []dockerutil.ManifestType{
+		dockerutil.ManifestV2, dockerutil.ManifestV2List, dockerutil.OCIManifest, dockerutil.OCIIndex,
+	} {
+		result, ok := dockerutil.ManifestTypeFromMediaType(mt.String())
+		require.True(ok)
+		require.Equal(mt, result)
+	}
+
+	result, ok := dockerutil.ManifestTypeFromMediaType(schema2.MediaTypeManifest + "; charset=utf-8")
+	require.True(ok)
+	require.Equal(dockerutil.ManifestV2, result)
+
+	_, ok = dockerutil.ManifestTypeFromMediaType("application/json")
+	require.False(ok)
+}
+
+func TestSupportedManifestTypes(t *testing.T) {
+	require := require.New(t)
+
+	types := dockerutil.SupportedManifestTypes()
+	require.Equal([]dockerutil.ManifestType{dockerutil.ManifestV2, dockerutil.ManifestV2List}, types)
+	require.True(strings.HasPrefix(
+		dockerutil.GetSupportedManifestTypes(), types[0].String()+","+types[1].String()))
+}
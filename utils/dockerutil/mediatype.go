@@ -0,0 +1,147 @@
+// Copyright (c) 2016-2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package dockerutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/docker/distribution"
+	"github.com/docker/distribution/manifest/schema1"
+)
+
+// ErrManifestTypeNotAllowed is returned when a manifest's media type is
+// rejected by an allowlist.
+var ErrManifestTypeNotAllowed = errors.New("manifest type not allowed")
+
+// _mediaTypeAliases maps non-standard spellings of manifest media types, after
+// parameters are stripped and casing is normalized, to their canonical form.
+var _mediaTypeAliases = map[string]string{
+	// Structured syntax suffix omitted.
+	"application/vnd.docker.distribution.manifest.v2":      _v2ManifestType,
+	"application/vnd.docker.distribution.manifest.list.v2": _v2ManifestListType,
+}
+
+// NormalizeMediaType returns the canonical form of media type s, for exact
+// matching against known types. Parameters such as "; charset=utf-8" are
+// stripped, the type is lowercased, and known aliases are mapped to their
+// canonical type.
+func NormalizeMediaType(s string) string {
+	if i := strings.Index(s, ";"); i != -1 {
+		s = s[:i]
+	}
+	s = strings.ToLower(strings.TrimSpace(s))
+	if canonical, ok := _mediaTypeAliases[s]; ok {
+		return canonical
+	}
+	return s
+}
+
+// canonicalizeMediaType returns manifest b with its top-level "mediaType"
+// field rewritten to canonical if the field is a non-standard spelling of
+// canonical, e.g. with parameters, so that parsers which compare the field
+// exactly accept it. The rest of b is left byte-for-byte intact. Returns b
+// itself if the field is missing, already canonical, or a different type.
+func canonicalizeMediaType(b []byte, canonical string) []byte {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return b
+	}
+	// Like json.Unmarshal, the last of any duplicate fields wins.
+	var start, end int64
+	var value string
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return b
+		}
+		offset := dec.InputOffset()
+		if key != "mediaType" {
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				return b
+			}
+			continue
+		}
+		if err := dec.Decode(&value); err != nil {
+			return b
+		}
+		start, end = offset, dec.InputOffset()
+	}
+	if end == 0 || value == canonical || NormalizeMediaType(value) != canonical {
+		return b
+	}
+	// Keep the colon and any whitespace between the key and its value.
+	start += int64(bytes.IndexByte(b[start:end], '"'))
+	quoted, err := json.Marshal(canonical)
+	if err != nil {
+		return b
+	}
+	out := make([]byte, 0, len(b)-int(end-start)+len(quoted))
+	out = append(out, b[:start]...)
+	out = append(out, quoted...)
+	return append(out, b[end:]...)
+}
+
+// sniffMediaType returns the normalized "mediaType" field of manifest b, or
+// empty string if b has none, and its "schemaVersion" field. Returns error if
+// b is not a JSON object.
+func sniffMediaType(b []byte) (mediaType string, schemaVersion int, err error) {
+	var m struct {
+		MediaType     string `json:"mediaType"`
+		SchemaVersion int    `json:"schemaVersion"`
+	}
+	if err := json.Unmarshal(b, &m); err != nil {
+		return "", 0, err
+	}
+	return NormalizeMediaType(m.MediaType), m.SchemaVersion, nil
+}
+
+// SniffMediaType returns the normalized media type of manifest b without fully
+// deserializing it, e.g. to route a blob before paying for ParseManifest. Only
+// the top-level "mediaType" and "schemaVersion" fields are decoded, and b is
+// otherwise not validated. Signed schema1 manifests, which have no media type,
+// are inferred from their schema version. Returns empty string if b declares
+// no media type, and an ErrMalformedManifest error if b is not a JSON object.
+func SniffMediaType(b []byte) (string, error) {
+	mediaType, version, err := sniffMediaType(b)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", ErrMalformedManifest, err)
+	}
+	if mediaType == "" && version == 1 {
+		return schema1.MediaTypeSignedManifest, nil
+	}
+	return mediaType, nil
+}
+
+// ValidateManifestTypeAllowed checks the media type of manifest against
+// allowed, e.g. schema2.MediaTypeManifest, returning an
+// ErrManifestTypeNotAllowed error naming the media type otherwise. Both sides
+// are compared after NormalizeMediaType.
+func ValidateManifestTypeAllowed(manifest distribution.Manifest, allowed []string) error {
+	mediaType, _, err := manifest.Payload()
+	if err != nil {
+		return fmt.Errorf("payload: %s", err)
+	}
+	mediaType = NormalizeMediaType(mediaType)
+	for _, a := range allowed {
+		if NormalizeMediaType(a) == mediaType {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %q not in %q", ErrManifestTypeNotAllowed, mediaType, allowed)
+}
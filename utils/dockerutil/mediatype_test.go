@@ -0,0 +1,216 @@
+// Copyright (c) 2016-2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package dockerutil_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/docker/distribution/manifest/manifestlist"
+	"github.com/docker/distribution/manifest/schema1"
+	"github.com/docker/distribution/manifest/schema2"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/kraken/core"
+	"github.com/uber/kraken/utils/dockerutil"
+)
+
+func TestNormalizeMediaType(t *testing.T) {
+	const v2 = "application/vnd.docker.distribution.manifest.v2+json"
+	const list = "application/vnd.docker.distribution.manifest.list.v2+json"
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{v2, v2},
+		{list, list},
+		{v2 + "; charset=utf-8", v2},
+		{v2 + ";charset=UTF-8", v2},
+		{list + "; charset=utf-8; q=0.9", list},
+		{"Application/Vnd.Docker.Distribution.Manifest.V2+JSON", v2},
+		{"  " + v2 + "  ", v2},
+		{"application/vnd.docker.distribution.manifest.v2", v2},
+		{"application/vnd.docker.distribution.manifest.list.v2; charset=utf-8", list},
+		{"application/vnd.oci.image.manifest.v1+json; charset=utf-8", "application/vnd.oci.image.manifest.v1+json"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			require.Equal(t, tt.expected, dockerutil.NormalizeMediaType(tt.input))
+		})
+	}
+}
+
+func TestParseManifestDispatchesOnMediaType(t *testing.T) {
+	require := require.New(t)
+
+	manifest, _, err := dockerutil.ParseManifest(bytes.NewReader(testManifestListBytes))
+	require.NoError(err)
+	_, ok := manifest.(*manifestlist.DeserializedManifestList)
+	require.True(ok)
+}
+
+func TestParseManifestWithMediaType(t *testing.T) {
+	tests := []struct {
+		desc      string
+		b         []byte
+		mediaType string
+	}{
+		{"manifest", testManifestBytes, "application/vnd.docker.distribution.manifest.v2+json"},
+		{"manifest list", testManifestListBytes, "application/vnd.docker.distribution.manifest.list.v2+json"},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			require := require.New(t)
+
+			manifest, mediaType, d, err := dockerutil.ParseManifestWithMediaType(bytes.NewReader(test.b))
+			require.NoError(err)
+			require.Equal(test.mediaType, mediaType)
+
+			// Agrees with the payload and ParseManifest.
+			payloadType, _, err := manifest.Payload()
+			require.NoError(err)
+			require.Equal(payloadType, mediaType)
+			_, expected, err := dockerutil.ParseManifest(bytes.NewReader(test.b))
+			require.NoError(err)
+			require.Equal(expected, d)
+		})
+	}
+}
+
+func TestParseManifestNonStandardMediaType(t *testing.T) {
+	const v2 = "application/vnd.docker.distribution.manifest.v2+json"
+	const list = "application/vnd.docker.distribution.manifest.list.v2+json"
+
+	tests := []struct {
+		desc      string
+		b         []byte
+		from      string
+		to        string
+		mediaType string
+	}{
+		{"parameterized", testManifestBytes, `"` + v2 + `"`, `"` + v2 + `; charset=utf-8"`, v2},
+		{"uppercase", testManifestBytes, `"` + v2 + `"`, `"APPLICATION/VND.DOCKER.DISTRIBUTION.MANIFEST.V2+JSON"`, v2},
+		{"alias", testManifestBytes, `"` + v2 + `"`, `"application/vnd.docker.distribution.manifest.v2"`, v2},
+		{"list parameterized", testManifestListBytes, `"` + list + `"`, `"` + list + `; charset=utf-8"`, list},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			require := require.New(t)
+
+			// Only rewrite the top-level field, not descriptor media types.
+			b := bytes.Replace(test.b, []byte(test.from), []byte(test.to), 1)
+			require.NotEqual(test.b, b)
+
+			manifest, mediaType, d, err := dockerutil.ParseManifestWithMediaType(bytes.NewReader(b))
+			require.NoError(err)
+			require.Equal(test.mediaType, mediaType)
+
+			// The digest covers the bytes as received.
+			expected, err := core.NewDigester().FromBytes(b)
+			require.NoError(err)
+			require.Equal(expected, d)
+
+			payloadType, _, err := manifest.Payload()
+			require.NoError(err)
+			require.Equal(test.mediaType, payloadType)
+		})
+	}
+}
+
+func TestParseManifestWithMediaTypeError(t *testing.T) {
+	require := require.New(t)
+
+	_, mediaType, _, err := dockerutil.ParseManifestWithMediaType(bytes.NewReader([]byte("{}")))
+	require.Error(err)
+	require.Empty(mediaType)
+}
+
+func TestParseManifestMalformed(t *testing.T) {
+	for _, b := range []string{"", "not json", "[]"} {
+		t.Run(b, func(t *testing.T) {
+			_, _, err := dockerutil.ParseManifest(bytes.NewReader([]byte(b)))
+			require.True(t, errors.Is(err, dockerutil.ErrMalformedManifest))
+		})
+	}
+}
+
+func TestSniffMediaType(t *testing.T) {
+	for _, tc := range []struct {
+		desc     string
+		b        string
+		expected string
+	}{
+		{"manifest", string(testManifestBytes), schema2.MediaTypeManifest},
+		{"manifest list", string(testManifestListBytes), manifestlist.MediaTypeManifestList},
+		{"normalized", `{"mediaType": "Application/vnd.docker.distribution.manifest.v2"}`, schema2.MediaTypeManifest},
+		{"schema1", `{"schemaVersion": 1, "name": "foo"}`, schema1.MediaTypeSignedManifest},
+		{"no media type", `{"schemaVersion": 2}`, ""},
+		{"layers not validated", `{"mediaType": "application/vnd.oci.image.manifest.v1+json", "layers": [{}]}`,
+			"application/vnd.oci.image.manifest.v1+json"},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			mediaType, err := dockerutil.SniffMediaType([]byte(tc.b))
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, mediaType)
+		})
+	}
+}
+
+func TestSniffMediaTypeMalformed(t *testing.T) {
+	_, err := dockerutil.SniffMediaType([]byte("not json"))
+	require.True(t, errors.Is(err, dockerutil.ErrMalformedManifest))
+}
+
+func TestParseManifestUnsupportedMediaType(t *testing.T) {
+	require := require.New(t)
+
+	// A Helm chart, pushed as an OCI artifact.
+	b := []byte(`{
+		"schemaVersion": 2,
+		"mediaType": "application/vnd.oci.image.manifest.v1+json",
+		"config": {
+			"mediaType": "application/vnd.cncf.helm.config.v1+json",
+			"size": 1,
+			"digest": "sha256:1a9ec845ee94c202b2d5da74a24f0ed2058318bfa9879fa541efaecba272e86b"
+		},
+		"layers": []
+	}`)
+
+	_, _, err := dockerutil.ParseManifest(bytes.NewReader(b))
+	var unsupported *dockerutil.ErrUnsupportedMediaType
+	require.True(errors.As(err, &unsupported))
+	require.Equal("application/vnd.oci.image.manifest.v1+json", unsupported.MediaType)
+	require.False(errors.Is(err, dockerutil.ErrMalformedManifest))
+}
+
+func TestValidateManifestTypeAllowed(t *testing.T) {
+	require := require.New(t)
+
+	_, manifest := manifestFixture(t, core.DigestFixture(), core.DigestFixture(), core.DigestFixture())
+	list, _, err := dockerutil.ParseManifestV2List(testManifestListBytes)
+	require.NoError(err)
+
+	allowed := []string{schema2.MediaTypeManifest + "; charset=utf-8"}
+
+	require.NoError(dockerutil.ValidateManifestTypeAllowed(manifest, allowed))
+
+	err = dockerutil.ValidateManifestTypeAllowed(list, allowed)
+	require.True(errors.Is(err, dockerutil.ErrManifestTypeNotAllowed))
+	require.Contains(err.Error(), manifestlist.MediaTypeManifestList)
+
+	err = dockerutil.ValidateManifestTypeAllowed(manifest, nil)
+	require.True(errors.Is(err, dockerutil.ErrManifestTypeNotAllowed))
+}
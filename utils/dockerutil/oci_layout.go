@@ -0,0 +1,119 @@
+// Copyright (c) 2016-2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package dockerutil
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/docker/distribution"
+	"github.com/uber/kraken/core"
+)
+
+const (
+	_ociLayoutFile        = "oci-layout"
+	_ociIndexFile         = "index.json"
+	_ociLayoutVersion     = "1.0.0"
+	_ociRefNameAnnotation = "org.opencontainers.image.ref.name"
+)
+
+// TaggedManifest is a manifest parsed from an OCI image layout.
+type TaggedManifest struct {
+	// Tag is the org.opencontainers.image.ref.name annotation of the manifest
+	// in the layout's index. Empty if the manifest is untagged.
+	Tag      string
+	Digest   core.Digest
+	Manifest distribution.Manifest
+}
+
+type ociLayout struct {
+	Version string `json:"imageLayoutVersion"`
+}
+
+type ociIndex struct {
+	SchemaVersion int                       `json:"schemaVersion"`
+	Manifests     []distribution.Descriptor `json:"manifests"`
+}
+
+// ParseOCILayout parses every manifest referenced by the index.json of the OCI
+// image layout rooted at dir, e.g. the output of `skopeo copy oci:<dir>`.
+// Manifest blobs are verified against their digests.
+func ParseOCILayout(dir string) ([]TaggedManifest, error) {
+	b, err := os.ReadFile(filepath.Join(dir, _ociLayoutFile))
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %s", _ociLayoutFile, err)
+	}
+	var layout ociLayout
+	if err := json.Unmarshal(b, &layout); err != nil {
+		return nil, fmt.Errorf("unmarshal %s: %s", _ociLayoutFile, err)
+	}
+	if layout.Version != _ociLayoutVersion {
+		return nil, fmt.Errorf("unsupported oci layout version: %q", layout.Version)
+	}
+
+	b, err = os.ReadFile(filepath.Join(dir, _ociIndexFile))
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %s", _ociIndexFile, err)
+	}
+	var index ociIndex
+	if err := json.Unmarshal(b, &index); err != nil {
+		return nil, fmt.Errorf("unmarshal %s: %s", _ociIndexFile, err)
+	}
+	if index.SchemaVersion != 2 {
+		return nil, fmt.Errorf("unsupported index version: %d", index.SchemaVersion)
+	}
+
+	var manifests []TaggedManifest
+	for i, desc := range index.Manifests {
+		m, err := parseOCILayoutManifest(dir, desc)
+		if err != nil {
+			return nil, fmt.Errorf("manifest %d: %s", i, err)
+		}
+		manifests = append(manifests, m)
+	}
+	return manifests, nil
+}
+
+func parseOCILayoutManifest(dir string, desc distribution.Descriptor) (TaggedManifest, error) {
+	if desc.MediaType == "" {
+		return TaggedManifest{}, errors.New("missing media type")
+	}
+	d, err := core.ParseDigest(string(desc.Digest))
+	if err != nil {
+		return TaggedManifest{}, fmt.Errorf("parse digest: %s", err)
+	}
+	b, err := os.ReadFile(filepath.Join(dir, "blobs", d.Algo(), d.Hex()))
+	if err != nil {
+		return TaggedManifest{}, fmt.Errorf("read blob: %s", err)
+	}
+	actual, err := core.NewDigester().FromBytes(b)
+	if err != nil {
+		return TaggedManifest{}, fmt.Errorf("digest blob: %s", err)
+	}
+	if actual != d {
+		return TaggedManifest{}, fmt.Errorf("blob digest mismatch: expected %s, got %s", d, actual)
+	}
+	manifest, _, err := distribution.UnmarshalManifest(NormalizeMediaType(desc.MediaType), b)
+	if err != nil {
+		return TaggedManifest{}, fmt.Errorf("unmarshal manifest: %s", err)
+	}
+	return TaggedManifest{
+		Tag:      desc.Annotations[_ociRefNameAnnotation],
+		Digest:   d,
+		Manifest: manifest,
+	}, nil
+}
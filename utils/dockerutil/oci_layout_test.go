@@ -0,0 +1,141 @@
+// Copyright (c) 2016-2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package dockerutil_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/distribution/manifest/ocischema"
+	"github.com/docker/distribution/manifest/schema2"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/kraken/core"
+	"github.com/uber/kraken/utils/dockerutil"
+)
+
+type ociLayoutEntry struct {
+	mediaType string
+	raw       []byte
+	tag       string
+}
+
+// writeOCILayout writes entries as an OCI image layout into a new temp dir.
+func writeOCILayout(t *testing.T, version string, entries ...ociLayoutEntry) string {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "blobs", "sha256"), 0755))
+	require.NoError(t, os.WriteFile(
+		filepath.Join(dir, "oci-layout"),
+		[]byte(fmt.Sprintf(`{"imageLayoutVersion": %q}`, version)),
+		0644))
+
+	var manifests string
+	for i, e := range entries {
+		d, err := core.NewDigester().FromBytes(e.raw)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "blobs", "sha256", d.Hex()), e.raw, 0644))
+		if i > 0 {
+			manifests += ","
+		}
+		manifests += fmt.Sprintf(
+			`{"mediaType": %q, "digest": %q, "size": %d, "annotations": {"org.opencontainers.image.ref.name": %q}}`,
+			e.mediaType, d, len(e.raw), e.tag)
+	}
+	require.NoError(t, os.WriteFile(
+		filepath.Join(dir, "index.json"),
+		[]byte(fmt.Sprintf(`{"schemaVersion": 2, "manifests": [%s]}`, manifests)),
+		0644))
+	return dir
+}
+
+func TestParseOCILayout(t *testing.T) {
+	require := require.New(t)
+
+	dir := writeOCILayout(t, "1.0.0",
+		ociLayoutEntry{ocischema.SchemaVersion.MediaType, testOCIManifestBytes, "v1"},
+		ociLayoutEntry{schema2.MediaTypeManifest, testManifestBytes, ""})
+
+	manifests, err := dockerutil.ParseOCILayout(dir)
+	require.NoError(err)
+	require.Len(manifests, 2)
+
+	ociDigest, err := core.NewDigester().FromBytes(testOCIManifestBytes)
+	require.NoError(err)
+	require.Equal("v1", manifests[0].Tag)
+	require.Equal(ociDigest, manifests[0].Digest)
+	require.IsType(&ocischema.DeserializedManifest{}, manifests[0].Manifest)
+
+	require.Equal("", manifests[1].Tag)
+	require.IsType(&schema2.DeserializedManifest{}, manifests[1].Manifest)
+}
+
+func TestParseOCILayoutErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(t *testing.T) string
+	}{
+		{
+			name: "missing oci-layout",
+			setup: func(t *testing.T) string {
+				dir := writeOCILayout(t, "1.0.0")
+				require.NoError(t, os.Remove(filepath.Join(dir, "oci-layout")))
+				return dir
+			},
+		},
+		{
+			name: "unsupported version",
+			setup: func(t *testing.T) string {
+				return writeOCILayout(t, "2.0.0")
+			},
+		},
+		{
+			name: "malformed index",
+			setup: func(t *testing.T) string {
+				dir := writeOCILayout(t, "1.0.0")
+				require.NoError(t, os.WriteFile(filepath.Join(dir, "index.json"), []byte("{"), 0644))
+				return dir
+			},
+		},
+		{
+			name: "missing blob",
+			setup: func(t *testing.T) string {
+				dir := writeOCILayout(t, "1.0.0",
+					ociLayoutEntry{schema2.MediaTypeManifest, testManifestBytes, "v1"})
+				d, err := core.NewDigester().FromBytes(testManifestBytes)
+				require.NoError(t, err)
+				require.NoError(t, os.Remove(filepath.Join(dir, "blobs", "sha256", d.Hex())))
+				return dir
+			},
+		},
+		{
+			name: "digest mismatch",
+			setup: func(t *testing.T) string {
+				dir := writeOCILayout(t, "1.0.0",
+					ociLayoutEntry{schema2.MediaTypeManifest, testManifestBytes, "v1"})
+				d, err := core.NewDigester().FromBytes(testManifestBytes)
+				require.NoError(t, err)
+				require.NoError(t, os.WriteFile(
+					filepath.Join(dir, "blobs", "sha256", d.Hex()), testOCIManifestBytes, 0644))
+				return dir
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := dockerutil.ParseOCILayout(tt.setup(t))
+			require.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,130 @@
+// Copyright (c) 2016-2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package dockerutil
+
+import (
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/docker/distribution"
+	"github.com/docker/distribution/manifest/schema1"
+	"github.com/uber/kraken/core"
+)
+
+// ParserConfig configures a Parser.
+type ParserConfig struct {
+	// DisableSchema1 rejects legacy signed schema1 manifests, which are
+	// otherwise parsed as a last resort, and omits them from the supported
+	// manifest types.
+	DisableSchema1 bool `yaml:"disable_schema1"`
+}
+
+// Parser parses manifests of the registered manifest types, subject to its
+// config. The package-level parse functions use a Parser with the default
+// config.
+type Parser struct {
+	config ParserConfig
+}
+
+// NewParser creates a new Parser.
+func NewParser(config ParserConfig) *Parser {
+	return &Parser{config}
+}
+
+var _defaultParser = NewParser(ParserConfig{})
+
+// ParseManifest is ParseManifest, subject to p's config.
+func (p *Parser) ParseManifest(r io.Reader) (distribution.Manifest, core.Digest, error) {
+	b, err := readManifest(r, DefaultMaxManifestBytes)
+	if err != nil {
+		return nil, core.Digest{}, err
+	}
+	return p.ParseManifestBytes(b)
+}
+
+// ParseManifestBytes is ParseManifestBytes, subject to p's config.
+func (p *Parser) ParseManifestBytes(b []byte) (distribution.Manifest, core.Digest, error) {
+	manifest, _, d, err := p.parseManifest(b)
+	return manifest, d, err
+}
+
+// GetSupportedManifestTypes is GetSupportedManifestTypes, subject to p's
+// config.
+func (p *Parser) GetSupportedManifestTypes() string {
+	var types []string
+	for _, t := range p.types() {
+		types = append(types, t.mediaType)
+	}
+	return strings.Join(types, ",")
+}
+
+// types returns the registered manifest types p parses, in order of
+// preference.
+func (p *Parser) types() []registeredManifestType {
+	var types []registeredManifestType
+	for _, t := range registeredManifestTypes() {
+		if t.mediaType == schema1.MediaTypeSignedManifest && p.config.DisableSchema1 {
+			continue
+		}
+		types = append(types, t)
+	}
+	return types
+}
+
+// parseManifest detects the type of manifest b from its top-level fields and
+// parses it with the parser registered for its media type, returning
+// ErrMalformedManifest if b is not JSON and *ErrUnsupportedMediaType if b
+// declares a media type there is no parser for. Manifests without a media
+// type are tried with each registered parser in turn.
+func (p *Parser) parseManifest(b []byte) (distribution.Manifest, string, core.Digest, error) {
+	return p.parseManifestDigested(b, nil)
+}
+
+// parseManifestDigested is parseManifest, but reuses d, the digest of b if
+// known, for manifest types whose digest is that of their bytes.
+func (p *Parser) parseManifestDigested(
+	b []byte, d *core.Digest) (distribution.Manifest, string, core.Digest, error) {
+
+	mediaType, err := SniffMediaType(b)
+	if err != nil {
+		return nil, "", core.Digest{}, err
+	}
+	types := p.types()
+	if mediaType != "" {
+		for _, t := range types {
+			if t.mediaType == mediaType {
+				manifest, md, err := t.parseDigested(b, d)
+				if err != nil {
+					return nil, "", core.Digest{}, err
+				}
+				return manifest, mediaType, md, nil
+			}
+		}
+		return nil, "", core.Digest{}, &ErrUnsupportedMediaType{MediaType: mediaType}
+	}
+	err = errors.New("no supported manifest types")
+	for i, t := range types {
+		manifest, md, perr := t.parseDigested(b, d)
+		if perr == nil {
+			return manifest, t.mediaType, md, nil
+		}
+		// Signed schema1 is a last resort, so keep the previous error if it
+		// fails too.
+		if i == 0 || t.mediaType != schema1.MediaTypeSignedManifest {
+			err = perr
+		}
+	}
+	return nil, "", core.Digest{}, err
+}
@@ -0,0 +1,175 @@
+// Copyright (c) 2016-2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package dockerutil
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/docker/distribution"
+	"github.com/docker/distribution/manifest/manifestlist"
+	"github.com/docker/distribution/manifest/ocischema"
+	"github.com/docker/distribution/manifest/schema2"
+	"github.com/uber/kraken/core"
+)
+
+// ErrMultiPlatformManifest is returned when a single image manifest is
+// required but a manifest list or index was given.
+var ErrMultiPlatformManifest = errors.New("manifest is a multi-platform list")
+
+// ErrPlatformNotFound is returned when a manifest list or index has no entry
+// for a platform.
+var ErrPlatformNotFound = errors.New("platform not found")
+
+// Platform is a normalized "os/arch[/variant]" platform.
+type Platform struct {
+	OS           string
+	Architecture string
+	Variant      string
+}
+
+// ParsePlatform parses and normalizes s following containerd conventions:
+// components are lowercased, architecture aliases such as "x86_64" and
+// "aarch64" are mapped to their GOARCH names, and arm variants default to
+// "v8" for arm64 and "v7" for arm. Thus "linux/arm64" and "linux/aarch64/v8"
+// both parse to "linux/arm64/v8".
+func ParsePlatform(s string) (Platform, error) {
+	parts := strings.Split(strings.ToLower(strings.TrimSpace(s)), "/")
+	if len(parts) < 2 || len(parts) > 3 {
+		return Platform{}, fmt.Errorf("invalid platform %q: expected os/arch[/variant]", s)
+	}
+	for _, p := range parts {
+		if p == "" {
+			return Platform{}, fmt.Errorf("invalid platform %q: empty component", s)
+		}
+	}
+	var variant string
+	if len(parts) == 3 {
+		variant = parts[2]
+	}
+	return newPlatform(parts[0], parts[1], variant), nil
+}
+
+// newPlatform returns the normalized platform of os, arch and variant.
+func newPlatform(os, arch, variant string) Platform {
+	p := Platform{OS: normalizeOS(strings.ToLower(os))}
+	p.Architecture, p.Variant = normalizeArch(strings.ToLower(arch), strings.ToLower(variant))
+	return p
+}
+
+// String returns "os/arch" or "os/arch/variant".
+func (p Platform) String() string {
+	s := p.OS + "/" + p.Architecture
+	if p.Variant != "" {
+		s += "/" + p.Variant
+	}
+	return s
+}
+
+func normalizeOS(os string) string {
+	if os == "macos" {
+		return "darwin"
+	}
+	return os
+}
+
+func normalizeArch(arch, variant string) (string, string) {
+	switch arch {
+	case "i386":
+		return "386", ""
+	case "x86_64", "x86-64", "amd64":
+		// amd64 microarchitecture levels are not distinguished.
+		if variant == "v1" {
+			variant = ""
+		}
+		return "amd64", variant
+	case "aarch64", "arm64":
+		if variant == "" || variant == "8" {
+			variant = "v8"
+		}
+		return "arm64", variant
+	case "armhf":
+		return "arm", "v7"
+	case "armel":
+		return "arm", "v6"
+	case "arm":
+		switch variant {
+		case "", "7":
+			variant = "v7"
+		case "5", "6", "8":
+			variant = "v" + variant
+		}
+		return "arm", variant
+	default:
+		return arch, variant
+	}
+}
+
+// RequireSingleManifest returns an ErrMultiPlatformManifest error if manifest
+// is a manifest list or OCI index, naming the platforms it lists. Callers must
+// resolve a platform to one of its child manifests first. Returns error for
+// unsupported manifest types.
+func RequireSingleManifest(manifest distribution.Manifest) error {
+	switch m := manifest.(type) {
+	case *schema2.DeserializedManifest, *ocischema.DeserializedManifest:
+		return nil
+	case *manifestlist.DeserializedManifestList:
+		platforms := make([]string, len(m.Manifests))
+		for i, desc := range m.Manifests {
+			platforms[i] = Platform{
+				OS:           desc.Platform.OS,
+				Architecture: desc.Platform.Architecture,
+				Variant:      desc.Platform.Variant,
+			}.String()
+		}
+		return fmt.Errorf(
+			"%w: resolve one of [%s] before pulling",
+			ErrMultiPlatformManifest, strings.Join(platforms, ", "))
+	default:
+		return fmt.Errorf("unsupported manifest type %T", manifest)
+	}
+}
+
+// SelectManifestForPlatform returns the digest of the first manifest in a
+// manifest list or OCI index matching os, arch and variant. Platforms are
+// normalized as by ParsePlatform, and an empty variant matches any variant.
+// Returns an ErrPlatformNotFound error, naming the listed platforms, if none
+// match.
+func SelectManifestForPlatform(
+	manifest distribution.Manifest, os, arch, variant string) (core.Digest, error) {
+
+	list, ok := manifest.(*manifestlist.DeserializedManifestList)
+	if !ok {
+		return core.Digest{}, fmt.Errorf("unsupported manifest type %T", manifest)
+	}
+	want := newPlatform(os, arch, variant)
+	anyVariant := strings.TrimSpace(variant) == ""
+	var platforms []string
+	for _, desc := range list.Manifests {
+		p := newPlatform(desc.Platform.OS, desc.Platform.Architecture, desc.Platform.Variant)
+		if p.OS == want.OS && p.Architecture == want.Architecture &&
+			(anyVariant || p.Variant == want.Variant) {
+
+			d, err := core.ParseDigest(string(desc.Digest))
+			if err != nil {
+				return core.Digest{}, fmt.Errorf("parse digest: %s", err)
+			}
+			return d, nil
+		}
+		platforms = append(platforms, p.String())
+	}
+	return core.Digest{}, fmt.Errorf(
+		"%w: %s not in [%s]", ErrPlatformNotFound, want, strings.Join(platforms, ", "))
+}
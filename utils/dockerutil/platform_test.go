@@ -0,0 +1,187 @@
+// Copyright (c) 2016-2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package dockerutil_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/docker/distribution/manifest/manifestlist"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/kraken/core"
+	"github.com/uber/kraken/utils/dockerutil"
+)
+
+func TestParsePlatformNormalization(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"linux/amd64", "linux/amd64"},
+		{"linux/x86_64", "linux/amd64"},
+		{"linux/x86-64", "linux/amd64"},
+		{"linux/amd64/v1", "linux/amd64"},
+		{"linux/amd64/v3", "linux/amd64/v3"},
+		{"Linux/AMD64", "linux/amd64"},
+		{"linux/i386", "linux/386"},
+		{"linux/arm64", "linux/arm64/v8"},
+		{"linux/arm64/v8", "linux/arm64/v8"},
+		{"linux/arm64/8", "linux/arm64/v8"},
+		{"linux/aarch64", "linux/arm64/v8"},
+		{"linux/arm", "linux/arm/v7"},
+		{"linux/arm/7", "linux/arm/v7"},
+		{"linux/arm/v6", "linux/arm/v6"},
+		{"linux/arm/5", "linux/arm/v5"},
+		{"linux/armhf", "linux/arm/v7"},
+		{"linux/armel", "linux/arm/v6"},
+		{"macos/arm64", "darwin/arm64/v8"},
+		{"windows/amd64", "windows/amd64"},
+		{"linux/ppc64le", "linux/ppc64le"},
+		{" linux/s390x ", "linux/s390x"},
+	}
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			require := require.New(t)
+
+			p, err := dockerutil.ParsePlatform(test.input)
+			require.NoError(err)
+			require.Equal(test.expected, p.String())
+
+			// Normalization is idempotent.
+			again, err := dockerutil.ParsePlatform(p.String())
+			require.NoError(err)
+			require.Equal(p, again)
+		})
+	}
+}
+
+func TestParsePlatformFields(t *testing.T) {
+	p, err := dockerutil.ParsePlatform("linux/arm/v7")
+	require.NoError(t, err)
+	require.Equal(t, dockerutil.Platform{OS: "linux", Architecture: "arm", Variant: "v7"}, p)
+}
+
+func TestParsePlatformErrors(t *testing.T) {
+	for _, input := range []string{
+		"",
+		"linux",
+		"linux/",
+		"/amd64",
+		"linux/arm//",
+		"linux/arm64/v8/extra",
+	} {
+		t.Run(input, func(t *testing.T) {
+			_, err := dockerutil.ParsePlatform(input)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestRequireSingleManifest(t *testing.T) {
+	require := require.New(t)
+
+	_, schema2Manifest := manifestFixture(t, core.DigestFixture(), core.DigestFixture(), core.DigestFixture())
+	require.NoError(dockerutil.RequireSingleManifest(schema2Manifest))
+
+	ociManifest := ociManifestFixture(t, "", "application/vnd.oci.image.config.v1+json")
+	require.NoError(dockerutil.RequireSingleManifest(ociManifest))
+
+	list, _, err := dockerutil.ParseManifestV2List(testManifestListBytes)
+	require.NoError(err)
+	err = dockerutil.RequireSingleManifest(list)
+	require.True(errors.Is(err, dockerutil.ErrMultiPlatformManifest))
+	require.Contains(err.Error(), "linux/amd64")
+	require.Contains(err.Error(), "sunos/sun4m")
+
+	index, _, err := dockerutil.BuildOCIIndex([]dockerutil.IndexEntry{{
+		Digest:    core.DigestFixture().String(),
+		Size:      1,
+		MediaType: "application/vnd.oci.image.manifest.v1+json",
+		Platform:  manifestlist.PlatformSpec{OS: "linux", Architecture: "arm64", Variant: "v8"},
+	}})
+	require.NoError(err)
+	err = dockerutil.RequireSingleManifest(index)
+	require.True(errors.Is(err, dockerutil.ErrMultiPlatformManifest))
+	require.Contains(err.Error(), "linux/arm64/v8")
+}
+
+func TestSelectManifestForPlatform(t *testing.T) {
+	amd64 := core.DigestFixture()
+	arm64 := core.DigestFixture()
+	armv6 := core.DigestFixture()
+	armv7 := core.DigestFixture()
+
+	entry := func(d core.Digest, arch, variant string) dockerutil.IndexEntry {
+		return dockerutil.IndexEntry{
+			Digest:    d.String(),
+			Size:      1,
+			MediaType: "application/vnd.oci.image.manifest.v1+json",
+			Platform:  manifestlist.PlatformSpec{OS: "linux", Architecture: arch, Variant: variant},
+		}
+	}
+	index, _, err := dockerutil.BuildOCIIndex([]dockerutil.IndexEntry{
+		entry(amd64, "amd64", ""),
+		entry(arm64, "arm64", ""),
+		entry(armv6, "arm", "v6"),
+		entry(armv7, "arm", "v7"),
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		desc              string
+		os, arch, variant string
+		expected          core.Digest
+	}{
+		{"exact", "linux", "amd64", "", amd64},
+		{"alias", "linux", "aarch64", "", arm64},
+		{"default variant", "linux", "arm64", "v8", arm64},
+		{"variant", "linux", "arm", "v6", armv6},
+		{"any variant", "linux", "arm", "", armv6},
+		{"case insensitive", "Linux", "AMD64", "", amd64},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			d, err := dockerutil.SelectManifestForPlatform(index, test.os, test.arch, test.variant)
+			require.NoError(t, err)
+			require.Equal(t, test.expected, d)
+		})
+	}
+}
+
+func TestSelectManifestForPlatformManifestList(t *testing.T) {
+	require := require.New(t)
+
+	list, _, err := dockerutil.ParseManifestV2List(testManifestListBytes)
+	require.NoError(err)
+
+	d, err := dockerutil.SelectManifestForPlatform(list, "linux", "amd64", "")
+	require.NoError(err)
+	require.Equal(string(list.References()[0].Digest), d.String())
+}
+
+func TestSelectManifestForPlatformErrors(t *testing.T) {
+	require := require.New(t)
+
+	list, _, err := dockerutil.ParseManifestV2List(testManifestListBytes)
+	require.NoError(err)
+
+	_, err = dockerutil.SelectManifestForPlatform(list, "linux", "arm64", "")
+	require.True(errors.Is(err, dockerutil.ErrPlatformNotFound))
+	require.Contains(err.Error(), "linux/amd64")
+
+	_, manifest := manifestFixture(t, core.DigestFixture(), core.DigestFixture(), core.DigestFixture())
+	_, err = dockerutil.SelectManifestForPlatform(manifest, "linux", "amd64", "")
+	require.Error(err)
+	require.False(errors.Is(err, dockerutil.ErrPlatformNotFound))
+}
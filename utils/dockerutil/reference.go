@@ -0,0 +1,109 @@
+// Copyright (c) 2016-2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package dockerutil
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/docker/distribution/reference"
+	"github.com/uber/kraken/core"
+)
+
+// DigestPinnedReference returns the immutable "<repo>@<digest>" reference of
+// repo:tag, where digest is the manifest digest tag resolved to. The result
+// is verified to parse back to the same repo and digest.
+func DigestPinnedReference(repo, tag, digest string) (string, error) {
+	d, err := core.ParseDigest(digest)
+	if err != nil {
+		return "", fmt.Errorf("parse digest: %s", err)
+	}
+	named, err := reference.WithName(repo)
+	if err != nil {
+		return "", fmt.Errorf("invalid repo %q: %s", repo, err)
+	}
+	if _, err := reference.WithTag(named, tag); err != nil {
+		return "", fmt.Errorf("invalid tag %q: %s", tag, err)
+	}
+	s := fmt.Sprintf("%s@%s", repo, d)
+
+	ref, err := reference.Parse(s)
+	if err != nil {
+		return "", fmt.Errorf("parse reference %q: %s", s, err)
+	}
+	canonical, ok := ref.(reference.Canonical)
+	if !ok || canonical.Name() != repo || canonical.Digest().String() != d.String() {
+		return "", fmt.Errorf("reference %q does not round trip", s)
+	}
+	return s, nil
+}
+
+// Reference is a parsed "[registry/]repository[:tag][@digest]" image
+// reference.
+type Reference struct {
+	// Registry is the registry host, including port if any. Empty if the
+	// reference does not name a registry; no default registry is assumed.
+	Registry string
+
+	// Repository is the repository path, excluding registry.
+	Repository string
+
+	// Tag defaults to "latest" if neither tag nor digest is given.
+	Tag string
+
+	// Digest is the "<algorithm>:<hex>" digest of digest-pinned references,
+	// else empty.
+	Digest string
+}
+
+// ParseReference parses s into its registry, repository, tag and digest
+// components. The first path component is treated as a registry only if it
+// contains a "." or ":", or is "localhost", following docker conventions.
+func ParseReference(s string) (Reference, error) {
+	ref, err := reference.Parse(s)
+	if err != nil {
+		return Reference{}, fmt.Errorf("parse reference %q: %s", s, err)
+	}
+	named, ok := ref.(reference.Named)
+	if !ok {
+		return Reference{}, fmt.Errorf("reference %q has no repository", s)
+	}
+	var r Reference
+	r.Registry, r.Repository = splitRegistry(named.Name())
+	if tagged, ok := ref.(reference.Tagged); ok {
+		r.Tag = tagged.Tag()
+	}
+	if digested, ok := ref.(reference.Digested); ok {
+		r.Digest = digested.Digest().String()
+	}
+	if r.Tag == "" && r.Digest == "" {
+		r.Tag = "latest"
+	}
+	return r, nil
+}
+
+// splitRegistry splits name into registry and repository. reference.Parse
+// treats any leading path component as a domain, so docker's heuristic is
+// applied here instead.
+func splitRegistry(name string) (string, string) {
+	i := strings.IndexRune(name, '/')
+	if i == -1 {
+		return "", name
+	}
+	first := name[:i]
+	if first != "localhost" && !strings.ContainsAny(first, ".:") {
+		return "", name
+	}
+	return first, name[i+1:]
+}
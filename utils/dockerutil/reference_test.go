@@ -0,0 +1,90 @@
+package dockerutil_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/uber/kraken/utils/dockerutil"
+)
+
+const _testDigest = "sha256:1a9ec845ee94c202b2d5da74a24f0ed2058318bfa9879fa541efaecba272e86b"
+
+var _testSHA512Digest = "sha512:" + strings.Repeat("1a9ec845", 16)
+
+func TestDigestPinnedReference(t *testing.T) {
+	tests := []struct {
+		name     string
+		repo     string
+		tag      string
+		digest   string
+		expected string
+		hasError bool
+	}{
+		{"simple", "library/ubuntu", "latest", _testDigest, "library/ubuntu@" + _testDigest, false},
+		{"registry with port", "localhost:5000/uber/kraken", "v1.0", _testDigest, "localhost:5000/uber/kraken@" + _testDigest, false},
+		{"sha512", "library/ubuntu", "latest", _testSHA512Digest, "library/ubuntu@" + _testSHA512Digest, false},
+		{"invalid digest", "library/ubuntu", "latest", "sha256:invalid", "", true},
+		{"wrong algo", "library/ubuntu", "latest", "md5:1a9ec845ee94c202b2d5da74a24f0ed2", "", true},
+		{"invalid tag", "library/ubuntu", "-bad", _testDigest, "", true},
+		{"invalid repo", "Library/Ubuntu", "latest", _testDigest, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := dockerutil.DigestPinnedReference(tt.repo, tt.tag, tt.digest)
+			if tt.hasError {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, s)
+		})
+	}
+}
+
+func TestParseReference(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected dockerutil.Reference
+		hasError bool
+	}{
+		{"repo only", "ubuntu", dockerutil.Reference{
+			Repository: "ubuntu", Tag: "latest"}, false},
+		{"nested repo", "library/ubuntu", dockerutil.Reference{
+			Repository: "library/ubuntu", Tag: "latest"}, false},
+		{"tag", "library/ubuntu:18.04", dockerutil.Reference{
+			Repository: "library/ubuntu", Tag: "18.04"}, false},
+		{"registry", "docker.io/library/ubuntu:18.04", dockerutil.Reference{
+			Registry: "docker.io", Repository: "library/ubuntu", Tag: "18.04"}, false},
+		{"registry with port, no tag", "localhost:5000/uber/kraken", dockerutil.Reference{
+			Registry: "localhost:5000", Repository: "uber/kraken", Tag: "latest"}, false},
+		{"registry with port and tag", "registry.example.com:5000/uber/kraken:v1.0", dockerutil.Reference{
+			Registry: "registry.example.com:5000", Repository: "uber/kraken", Tag: "v1.0"}, false},
+		{"localhost", "localhost/kraken", dockerutil.Reference{
+			Registry: "localhost", Repository: "kraken", Tag: "latest"}, false},
+		{"first component is not a registry", "uber/kraken:v1", dockerutil.Reference{
+			Repository: "uber/kraken", Tag: "v1"}, false},
+		{"digest pinned", "library/ubuntu@" + _testDigest, dockerutil.Reference{
+			Repository: "library/ubuntu", Digest: _testDigest}, false},
+		{"tag and digest", "localhost:5000/ubuntu:18.04@" + _testDigest, dockerutil.Reference{
+			Registry: "localhost:5000", Repository: "ubuntu", Tag: "18.04", Digest: _testDigest}, false},
+		{"empty", "", dockerutil.Reference{}, true},
+		{"uppercase repo", "Library/Ubuntu", dockerutil.Reference{}, true},
+		{"invalid tag", "ubuntu:-bad", dockerutil.Reference{}, true},
+		{"invalid digest", "ubuntu@sha256:invalid", dockerutil.Reference{}, true},
+		{"trailing colon", "ubuntu:", dockerutil.Reference{}, true},
+		{"digest only", "@" + _testDigest, dockerutil.Reference{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ref, err := dockerutil.ParseReference(tt.input)
+			if tt.hasError {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, ref)
+		})
+	}
+}
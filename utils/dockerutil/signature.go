@@ -0,0 +1,56 @@
+// Copyright (c) 2016-2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package dockerutil
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/uber/kraken/core"
+)
+
+// ErrInvalidSignature is returned when a manifest digest signature does not
+// match the digest.
+var ErrInvalidSignature = errors.New("invalid manifest digest signature")
+
+// SignManifestDigest returns the signature of d under key, which is the hex
+// encoded HMAC-SHA256 of the "<algo>:<hex>" form of d. The signature is only
+// as strong as key, which should be at least 32 random bytes.
+func SignManifestDigest(d core.Digest, key []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(d.String()))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyManifestDigestSignature checks that sig is the signature of d under
+// key, as returned by SignManifestDigest, returning ErrInvalidSignature
+// otherwise. Signatures are compared in constant time.
+func VerifyManifestDigestSignature(d core.Digest, sig string, key []byte) error {
+	if len(key) == 0 {
+		return errors.New("empty signing key")
+	}
+	b, err := hex.DecodeString(sig)
+	if err != nil {
+		return fmt.Errorf("%w: decode hex: %s", ErrInvalidSignature, err)
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(d.String()))
+	if !hmac.Equal(b, mac.Sum(nil)) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
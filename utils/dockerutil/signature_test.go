@@ -0,0 +1,74 @@
+// Copyright (c) 2016-2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package dockerutil_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/uber/kraken/core"
+	"github.com/uber/kraken/utils/dockerutil"
+)
+
+func TestManifestDigestSignature(t *testing.T) {
+	require := require.New(t)
+
+	key := []byte("0123456789abcdef0123456789abcdef")
+	d := core.DigestFixture()
+
+	sig := dockerutil.SignManifestDigest(d, key)
+	require.Len(sig, 64)
+	require.Equal(sig, dockerutil.SignManifestDigest(d, key))
+	require.NoError(dockerutil.VerifyManifestDigestSignature(d, sig, key))
+}
+
+func TestManifestDigestSignatureKnownValue(t *testing.T) {
+	d, err := core.ParseSHA256Digest(
+		"sha256:0000000000000000000000000000000000000000000000000000000000000000")
+	require.NoError(t, err)
+
+	// echo -n "sha256:000...0" | openssl dgst -sha256 -hmac key
+	require.Equal(t,
+		"1f78e087a9d4d785244f8be2a67b877c1ef38c1c23903bd60cb6490f9a1c3656",
+		dockerutil.SignManifestDigest(d, []byte("key")))
+}
+
+func TestVerifyManifestDigestSignatureErrors(t *testing.T) {
+	key := []byte("0123456789abcdef0123456789abcdef")
+	d := core.DigestFixture()
+	sig := dockerutil.SignManifestDigest(d, key)
+
+	tests := []struct {
+		desc string
+		d    core.Digest
+		sig  string
+		key  []byte
+	}{
+		{"wrong digest", core.DigestFixture(), sig, key},
+		{"wrong key", d, sig, []byte("another key")},
+		{"truncated", d, sig[:62], key},
+		{"not hex", d, "zz" + sig[2:], key},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			err := dockerutil.VerifyManifestDigestSignature(test.d, test.sig, test.key)
+			require.True(t, errors.Is(err, dockerutil.ErrInvalidSignature))
+		})
+	}
+
+	t.Run("empty key", func(t *testing.T) {
+		require.Error(t, dockerutil.VerifyManifestDigestSignature(d, sig, nil))
+	})
+}
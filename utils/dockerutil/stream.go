@@ -0,0 +1,101 @@
+// Copyright (c) 2016-2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package dockerutil
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/docker/distribution"
+	"github.com/uber/kraken/core"
+	"github.com/uber/kraken/utils/closers"
+)
+
+// ParseManifestStream is ParseManifest, but hashes the payload while reading
+// it, such that manifest types whose digest is that of their bytes are only
+// decoded afterwards rather than hashed again. If r reports its length, as
+// bytes.Reader does, the payload is also read into a single allocation rather
+// than a buffer grown as r is read. It accepts the same manifest types and
+// returns the same errors as ParseManifest.
+func ParseManifestStream(r io.Reader) (distribution.Manifest, core.Digest, error) {
+	digester := core.NewDigester()
+	b, err := readManifestSized(r, digester.Tee(r), DefaultMaxManifestBytes)
+	if err != nil {
+		return nil, core.Digest{}, err
+	}
+	d := digester.Digest()
+	manifest, _, d, err := _defaultParser.parseManifestDigested(b, &d)
+	return manifest, d, err
+}
+
+// readManifestSized is readManifest of tee, a reader of r, but sizes its
+// buffer up front if r reports its length.
+func readManifestSized(r, tee io.Reader, maxBytes int64) ([]byte, error) {
+	var buf bytes.Buffer
+	if l, ok := r.(interface{ Len() int }); ok && int64(l.Len()) <= maxBytes {
+		// One extra byte lets ReadFrom see EOF without growing.
+		buf.Grow(l.Len() + bytes.MinRead)
+	}
+	if _, err := buf.ReadFrom(io.LimitReader(tee, maxBytes+1)); err != nil {
+		return nil, fmt.Errorf("read: %s", err)
+	}
+	if int64(buf.Len()) > maxBytes {
+		return nil, fmt.Errorf("%w: exceeds limit of %d bytes", ErrManifestTooLarge, maxBytes)
+	}
+	return buf.Bytes(), nil
+}
+
+// ParseManifestFile parses the manifest staged at path like ParseManifest,
+// including any registered manifest types, verifying that it hashes to
+// expected. Returns *ErrDigestMismatch if it does not.
+func ParseManifestFile(path string, expected core.Digest) (distribution.Manifest, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open: %s", err)
+	}
+	defer closers.Close(f)
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("stat: %s", err)
+	}
+	r := sizedReader{f, int(info.Size())}
+	digester := core.NewDigester()
+	b, err := readManifestSized(r, digester.Tee(r), DefaultMaxManifestBytes)
+	if err != nil {
+		return nil, err
+	}
+	d := digester.Digest()
+	manifest, _, d, err := _defaultParser.parseManifestDigested(b, &d)
+	if err != nil {
+		return nil, err
+	}
+	if d != expected {
+		return nil, &ErrDigestMismatch{Expected: expected, Actual: d}
+	}
+	return manifest, nil
+}
+
+// sizedReader reports the length of a reader whose size is known up front, so
+// readManifestSized can size its buffer.
+type sizedReader struct {
+	io.Reader
+	n int
+}
+
+func (r sizedReader) Len() int {
+	return r.n
+}
@@ -0,0 +1,221 @@
+// Copyright (c) 2016-2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package dockerutil_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/docker/distribution"
+	"github.com/docker/distribution/manifest/ocischema"
+	"github.com/docker/distribution/manifest/schema1"
+	"github.com/docker/libtrust"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/kraken/core"
+	"github.com/uber/kraken/utils/dockerutil"
+)
+
+func TestParseManifestStream(t *testing.T) {
+	for name, b := range map[string][]byte{
+		"manifest": testManifestBytes,
+		"list":     testManifestListBytes,
+	} {
+		t.Run(name, func(t *testing.T) {
+			require := require.New(t)
+
+			expected, expectedDigest, err := dockerutil.ParseManifest(bytes.NewReader(b))
+			require.NoError(err)
+
+			// Hide Len, so the unsized path is covered too.
+			for _, r := range []io.Reader{bytes.NewReader(b), io.MultiReader(bytes.NewReader(b))} {
+				manifest, d, err := dockerutil.ParseManifestStream(r)
+				require.NoError(err)
+				require.Equal(expectedDigest, d)
+				require.Equal(expected, manifest)
+			}
+		})
+	}
+}
+
+func TestParseManifestStreamErrors(t *testing.T) {
+	require := require.New(t)
+
+	_, _, err := dockerutil.ParseManifestStream(strings.NewReader("not json"))
+	require.True(errors.Is(err, dockerutil.ErrMalformedManifest))
+
+	_, _, err = dockerutil.ParseManifestStream(
+		strings.NewReader(`{"schemaVersion": 2, "mediaType": "application/vnd.oci.image.index.v1+json"}`))
+	var unsupported *dockerutil.ErrUnsupportedMediaType
+	require.True(errors.As(err, &unsupported))
+
+	defer func(max int64) { dockerutil.DefaultMaxManifestBytes = max }(dockerutil.DefaultMaxManifestBytes)
+	dockerutil.DefaultMaxManifestBytes = 10
+	_, _, err = dockerutil.ParseManifestStream(bytes.NewReader(testManifestBytes))
+	require.True(errors.Is(err, dockerutil.ErrManifestTooLarge))
+}
+
+// signedManifestFixture returns a signed schema1 manifest.
+func signedManifestFixture(t *testing.T) []byte {
+	key, err := libtrust.GenerateECP256PrivateKey()
+	require.NoError(t, err)
+	sm, err := schema1.Sign(&schema1.Manifest{
+		Versioned: schema1.SchemaVersion,
+		Name:      "library/hello-world",
+		Tag:       "latest",
+		FSLayers:  []schema1.FSLayer{{BlobSum: "sha256:2db29710123e3e53a794f2694094b9b4338aa9ee5c40b930cb8063a1be392c54"}},
+		History:   []schema1.History{{V1Compatibility: `{"id":"1"}`}},
+	}, key)
+	require.NoError(t, err)
+	_, b, err := sm.Payload()
+	require.NoError(t, err)
+	return b
+}
+
+func TestParseManifestStreamMatchesParseManifest(t *testing.T) {
+	v2 := "application/vnd.docker.distribution.manifest.v2+json"
+	for name, b := range map[string][]byte{
+		"schema1":       signedManifestFixture(t),
+		"parameterized": bytes.Replace(testManifestBytes, []byte(v2), []byte(v2+"; charset=utf-8"), 1),
+		"malformed":     []byte("not json"),
+		"empty object":  []byte("{}"),
+		"unsupported":   []byte(`{"schemaVersion": 2, "mediaType": "application/vnd.oci.image.index.v1+json"}`),
+	} {
+		t.Run(name, func(t *testing.T) {
+			require := require.New(t)
+
+			expected, expectedDigest, expectedErr := dockerutil.ParseManifest(bytes.NewReader(b))
+			manifest, d, err := dockerutil.ParseManifestStream(bytes.NewReader(b))
+			if expectedErr != nil {
+				require.EqualError(err, expectedErr.Error())
+				return
+			}
+			require.NoError(err)
+			require.Equal(expectedDigest, d)
+			require.Equal(expected, manifest)
+		})
+	}
+}
+
+func TestParseManifestStreamReregisteredType(t *testing.T) {
+	require := require.New(t)
+
+	// A parser registered in place of a default one computes its own digest.
+	custom := core.DigestFixture()
+	v2 := "application/vnd.docker.distribution.manifest.v2+json"
+	dockerutil.RegisterManifestType(v2, func(b []byte) (distribution.Manifest, core.Digest, error) {
+		manifest, _, err := dockerutil.ParseManifestV2(b)
+		return manifest, custom, err
+	})
+	defer dockerutil.RegisterManifestType(v2, dockerutil.ParseManifestV2)
+
+	_, d, err := dockerutil.ParseManifestStream(bytes.NewReader(testManifestBytes))
+	require.NoError(err)
+	require.Equal(custom, d)
+}
+
+func TestParseManifestFile(t *testing.T) {
+	require := require.New(t)
+
+	path := filepath.Join(t.TempDir(), "manifest")
+	require.NoError(os.WriteFile(path, testManifestBytes, 0644))
+	expected, d, err := dockerutil.ParseManifest(bytes.NewReader(testManifestBytes))
+	require.NoError(err)
+
+	manifest, err := dockerutil.ParseManifestFile(path, d)
+	require.NoError(err)
+	require.Equal(expected, manifest)
+
+	other := core.DigestFixture()
+	_, err = dockerutil.ParseManifestFile(path, other)
+	var mismatch *dockerutil.ErrDigestMismatch
+	require.True(errors.As(err, &mismatch))
+	require.Equal(other, mismatch.Expected)
+	require.Equal(d, mismatch.Actual)
+
+	_, err = dockerutil.ParseManifestFile(filepath.Join(t.TempDir(), "missing"), d)
+	require.Error(err)
+}
+
+func TestParseManifestFileRegisteredType(t *testing.T) {
+	require := require.New(t)
+
+	b := []byte(fmt.Sprintf(`{
+		"schemaVersion": 2,
+		"mediaType": "application/vnd.oci.image.manifest.v1+json",
+		"config": {"mediaType": "application/vnd.oci.image.config.v1+json", "size": 1, "digest": %q},
+		"layers": []
+	}`, core.DigestFixture()))
+	d, err := core.NewDigester().FromBytes(b)
+	require.NoError(err)
+	path := filepath.Join(t.TempDir(), "manifest")
+	require.NoError(os.WriteFile(path, b, 0644))
+
+	_, err = dockerutil.ParseManifestFile(path, d)
+	var unsupported *dockerutil.ErrUnsupportedMediaType
+	require.True(errors.As(err, &unsupported))
+
+	dockerutil.RegisterManifestType(ocischema.SchemaVersion.MediaType, parseOCIManifest)
+	defer dockerutil.UnregisterManifestType(ocischema.SchemaVersion.MediaType)
+
+	manifest, err := dockerutil.ParseManifestFile(path, d)
+	require.NoError(err)
+	_, ok := manifest.(*ocischema.DeserializedManifest)
+	require.True(ok)
+}
+
+// fatManifestList returns a manifest list with n entries.
+func fatManifestList(n int) []byte {
+	entries := make([]string, n)
+	for i := range entries {
+		entries[i] = fmt.Sprintf(`{
+			"mediaType": "application/vnd.docker.distribution.manifest.v2+json",
+			"size": 985,
+			"digest": %q,
+			"platform": {"architecture": "amd64", "os": "linux", "variant": "v%d"}
+		}`, core.DigestFixture(), i)
+	}
+	return []byte(fmt.Sprintf(`{
+		"schemaVersion": 2,
+		"mediaType": "application/vnd.docker.distribution.manifest.list.v2+json",
+		"manifests": [%s]
+	}`, strings.Join(entries, ",")))
+}
+
+func BenchmarkParseManifest(b *testing.B) {
+	raw := fatManifestList(1000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, _, err := dockerutil.ParseManifest(bytes.NewReader(raw)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkParseManifestStream(b *testing.B) {
+	raw := fatManifestList(1000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, _, err := dockerutil.ParseManifestStream(bytes.NewReader(raw)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
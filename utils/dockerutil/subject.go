@@ -0,0 +1,58 @@
+// Copyright (c) 2016-2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package dockerutil
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/docker/distribution"
+	"github.com/docker/distribution/manifest/manifestlist"
+	"github.com/docker/distribution/manifest/ocischema"
+	"github.com/uber/kraken/core"
+)
+
+// GetManifestSubject returns the digest of the subject descriptor of an OCI
+// manifest or index, i.e. the manifest an artifact such as a signature or
+// SBOM refers to. Returns false if manifest has no subject, including for
+// non-OCI manifests, which cannot have one.
+func GetManifestSubject(manifest distribution.Manifest) (core.Digest, bool, error) {
+	switch m := manifest.(type) {
+	case *ocischema.DeserializedManifest:
+	case *manifestlist.DeserializedManifestList:
+		if m.MediaType != _ociIndexType {
+			return core.Digest{}, false, nil
+		}
+	default:
+		return core.Digest{}, false, nil
+	}
+	_, payload, err := manifest.Payload()
+	if err != nil {
+		return core.Digest{}, false, fmt.Errorf("payload: %s", err)
+	}
+	var m struct {
+		Subject *distribution.Descriptor `json:"subject"`
+	}
+	if err := json.Unmarshal(payload, &m); err != nil {
+		return core.Digest{}, false, fmt.Errorf("unmarshal payload: %s", err)
+	}
+	if m.Subject == nil {
+		return core.Digest{}, false, nil
+	}
+	d, err := core.ParseDigest(string(m.Subject.Digest))
+	if err != nil {
+		return core.Digest{}, false, fmt.Errorf("parse subject digest: %s", err)
+	}
+	return d, true, nil
+}
@@ -0,0 +1,82 @@
+// Copyright (c) 2016-2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package dockerutil_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/docker/distribution"
+	"github.com/docker/distribution/manifest/manifestlist"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/kraken/core"
+	"github.com/uber/kraken/utils/dockerutil"
+)
+
+// signatureFixture returns a cosign signature manifest referring to subject.
+func signatureFixture(t *testing.T, subject string) distribution.Manifest {
+	return ociManifestWithSubjectFixture(
+		t, subject, "application/vnd.dev.cosign.artifact.sig.v1+json", "application/vnd.oci.empty.v1+json")
+}
+
+func TestGetManifestSubject(t *testing.T) {
+	require := require.New(t)
+
+	subject := core.DigestFixture()
+	d, ok, err := dockerutil.GetManifestSubject(signatureFixture(t, subject.String()))
+	require.NoError(err)
+	require.True(ok)
+	require.Equal(subject, d)
+}
+
+func TestGetManifestSubjectOCIIndex(t *testing.T) {
+	require := require.New(t)
+
+	subject := core.DigestFixture()
+	b := []byte(fmt.Sprintf(`{
+		"schemaVersion": 2,
+		"mediaType": "application/vnd.oci.image.index.v1+json",
+		"manifests": [],
+		"subject": {"mediaType": "application/vnd.oci.image.manifest.v1+json", "size": 1, "digest": %q}
+	}`, subject))
+	manifest, _, err := distribution.UnmarshalManifest("application/vnd.oci.image.index.v1+json", b)
+	require.NoError(err)
+	_, ok := manifest.(*manifestlist.DeserializedManifestList)
+	require.True(ok)
+
+	d, ok, err := dockerutil.GetManifestSubject(manifest)
+	require.NoError(err)
+	require.True(ok)
+	require.Equal(subject, d)
+}
+
+func TestGetManifestSubjectAbsent(t *testing.T) {
+	oci := ociManifestFixture(t, "", "application/vnd.oci.image.config.v1+json")
+	_, v2 := manifestFixture(t, core.DigestFixture(), core.DigestFixture(), core.DigestFixture())
+	list, _, err := dockerutil.ParseManifestV2List(testManifestListBytes)
+	require.NoError(t, err)
+
+	for name, manifest := range map[string]distribution.Manifest{"oci": oci, "v2": v2, "list": list} {
+		t.Run(name, func(t *testing.T) {
+			_, ok, err := dockerutil.GetManifestSubject(manifest)
+			require.NoError(t, err)
+			require.False(t, ok)
+		})
+	}
+}
+
+func TestGetManifestSubjectInvalidDigest(t *testing.T) {
+	_, _, err := dockerutil.GetManifestSubject(signatureFixture(t, "sha256:bad"))
+	require.Error(t, err)
+}
@@ -0,0 +1,129 @@
+// Copyright (c) 2016-2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package dockerutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/docker/distribution"
+	"github.com/docker/distribution/manifest/schema1"
+	"github.com/uber/kraken/core"
+)
+
+// SummaryMaxLabels is the maximum number of labels included in an
+// ImageSummary.
+const SummaryMaxLabels = 5
+
+// ImageSummary is a human-readable overview of an image, e.g. for table
+// output. Fields are only ever added, so output formats remain stable.
+type ImageSummary struct {
+	// Platform is "os/arch[/variant]" from the image config. Empty if the
+	// config was not available.
+	Platform string
+
+	// Created is when the image was built. Zero if the config was not
+	// available or does not record it.
+	Created time.Time
+
+	LayerCount int
+
+	// TotalSize is the sum of the compressed layer sizes declared by the
+	// manifest.
+	TotalSize int64
+
+	// Labels holds up to SummaryMaxLabels labels as "key=value", sorted by key,
+	// and OmittedLabels the number of further labels.
+	Labels        []string
+	OmittedLabels int
+
+	// HasConfig is false if the summary was built from the manifest alone.
+	HasConfig bool
+}
+
+// SummarizeImage summarizes the image described by manifest and its config.
+// configBytes may be empty if the config is not available, in which case only
+// fields derived from the manifest are set. Returns error for manifest lists,
+// which describe several images, and for malformed configs.
+func SummarizeImage(manifest distribution.Manifest, configBytes []byte) (ImageSummary, error) {
+	layers, err := getLayers(manifest)
+	if err != nil {
+		return ImageSummary{}, err
+	}
+	s := ImageSummary{LayerCount: len(layers)}
+	for _, desc := range layers {
+		s.TotalSize += desc.Size
+	}
+	if len(configBytes) == 0 {
+		return s, nil
+	}
+	c, err := parseImageConfig(configBytes)
+	if err != nil {
+		return ImageSummary{}, err
+	}
+	s.HasConfig = true
+	s.Created = c.Created
+	if c.OS != "" {
+		s.Platform = Platform{OS: c.OS, Architecture: c.Architecture, Variant: c.Variant}.String()
+	}
+	keys := make([]string, 0, len(c.Config.Labels))
+	for k := range c.Config.Labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	if len(keys) > SummaryMaxLabels {
+		s.OmittedLabels = len(keys) - SummaryMaxLabels
+		keys = keys[:SummaryMaxLabels]
+	}
+	for _, k := range keys {
+		s.Labels = append(s.Labels, k+"="+c.Config.Labels[k])
+	}
+	return s, nil
+}
+
+// FormatManifest returns the payload of manifest indented with 2 spaces,
+// preceded by a one-line header with its media type, digest and number of
+// references, e.g. for dumping manifests while debugging failed pulls.
+// Returns error if manifest is nil.
+func FormatManifest(manifest distribution.Manifest) (string, error) {
+	if err := checkNotNil(manifest); err != nil {
+		return "", err
+	}
+	mediaType, payload, err := manifest.Payload()
+	if err != nil {
+		return "", fmt.Errorf("payload: %s", err)
+	}
+	hashed := payload
+	if sm, ok := manifest.(*schema1.SignedManifest); ok {
+		hashed = sm.Canonical
+	}
+	d, err := core.NewDigester().FromBytes(hashed)
+	if err != nil {
+		return "", fmt.Errorf("compute digest: %s", err)
+	}
+	refs, err := GetManifestReferences(manifest)
+	if err != nil {
+		return "", fmt.Errorf("get references: %w", err)
+	}
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "# %s %s (%d references)\n", mediaType, d, len(refs))
+	if err := json.Indent(&b, payload, "", "  "); err != nil {
+		return "", fmt.Errorf("indent payload: %s", err)
+	}
+	b.WriteString("\n")
+	return b.String(), nil
+}
@@ -0,0 +1,102 @@
+// Copyright (c) 2016-2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package dockerutil_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/docker/distribution/manifest/schema2"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/kraken/core"
+	"github.com/uber/kraken/utils/dockerutil"
+)
+
+func TestSummarizeImage(t *testing.T) {
+	require := require.New(t)
+
+	_, manifest := manifestFixture(t, core.DigestFixture(), core.DigestFixture(), core.DigestFixture())
+	config := []byte(`{
+		"created": "2019-01-02T03:04:05Z",
+		"os": "linux",
+		"architecture": "arm64",
+		"variant": "v8",
+		"config": {
+			"Labels": {"g": "7", "f": "6", "e": "5", "d": "4", "c": "3", "b": "2", "a": "1"}
+		}
+	}`)
+
+	s, err := dockerutil.SummarizeImage(manifest, config)
+	require.NoError(err)
+	require.Equal(dockerutil.ImageSummary{
+		Platform:      "linux/arm64/v8",
+		Created:       time.Date(2019, 1, 2, 3, 4, 5, 0, time.UTC),
+		LayerCount:    2,
+		TotalSize:     1902063 + 2345077,
+		Labels:        []string{"a=1", "b=2", "c=3", "d=4", "e=5"},
+		OmittedLabels: 2,
+		HasConfig:     true,
+	}, s)
+}
+
+func TestSummarizeImageWithoutConfig(t *testing.T) {
+	require := require.New(t)
+
+	_, manifest := manifestFixture(t, core.DigestFixture(), core.DigestFixture(), core.DigestFixture())
+
+	s, err := dockerutil.SummarizeImage(manifest, nil)
+	require.NoError(err)
+	require.Equal(dockerutil.ImageSummary{
+		LayerCount: 2,
+		TotalSize:  1902063 + 2345077,
+	}, s)
+}
+
+func TestSummarizeImageErrors(t *testing.T) {
+	require := require.New(t)
+
+	list, _, err := dockerutil.ParseManifestV2List(testManifestListBytes)
+	require.NoError(err)
+	_, err = dockerutil.SummarizeImage(list, nil)
+	require.Error(err)
+
+	_, manifest := manifestFixture(t, core.DigestFixture(), core.DigestFixture(), core.DigestFixture())
+	_, err = dockerutil.SummarizeImage(manifest, []byte("not json"))
+	require.Error(err)
+}
+
+func TestFormatManifest(t *testing.T) {
+	require := require.New(t)
+
+	d, manifest := manifestFixture(t, core.DigestFixture(), core.DigestFixture(), core.DigestFixture())
+
+	s, err := dockerutil.FormatManifest(manifest)
+	require.NoError(err)
+	lines := strings.Split(s, "\n")
+	require.Equal(
+		"# application/vnd.docker.distribution.manifest.v2+json "+d.String()+" (3 references)", lines[0])
+	require.Equal("{", lines[1])
+	require.Equal(`  "schemaVersion": 2,`, lines[2])
+	require.Equal("}", lines[len(lines)-2])
+}
+
+func TestFormatManifestNil(t *testing.T) {
+	_, err := dockerutil.FormatManifest(nil)
+	require.Error(t, err)
+
+	var manifest *schema2.DeserializedManifest
+	_, err = dockerutil.FormatManifest(manifest)
+	require.Error(t, err)
+}
@@ -0,0 +1,105 @@
+// Copyright (c) 2016-2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package dockerutil
+
+import (
+	"fmt"
+
+	"github.com/docker/distribution"
+	"github.com/opencontainers/go-digest"
+	"github.com/uber/kraken/core"
+)
+
+// WalkManifestReferences calls visit with the digest of every descriptor
+// reachable from manifest, depth-first, including its subject if it has one.
+// Descriptors of manifests, e.g. the children of an index, are resolved with
+// resolve and walked in turn; if resolve is nil, only the references of
+// manifest itself are visited. Each digest is visited once, even if reachable
+// by several paths. Returns *ErrManifestCycle if a manifest references itself,
+// directly or transitively. Errors returned by visit stop the walk and are
+// returned as is.
+func WalkManifestReferences(
+	manifest distribution.Manifest, resolve ManifestFetcher, visit func(core.Digest) error) error {
+
+	_, payload, err := manifest.Payload()
+	if err != nil {
+		return fmt.Errorf("payload: %s", err)
+	}
+	root, err := core.NewDigester().FromBytes(payload)
+	if err != nil {
+		return fmt.Errorf("digest: %s", err)
+	}
+	w := &manifestWalker{
+		resolve:  resolve,
+		visit:    visit,
+		visiting: map[core.Digest]bool{root: true},
+		visited:  make(map[core.Digest]bool),
+	}
+	return w.walk(manifest)
+}
+
+type manifestWalker struct {
+	resolve ManifestFetcher
+	visit   func(core.Digest) error
+
+	// visiting holds the manifests on the current path, to detect cycles, and
+	// visited holds every digest already visited, to skip shared references.
+	visiting map[core.Digest]bool
+	visited  map[core.Digest]bool
+}
+
+func (w *manifestWalker) walk(manifest distribution.Manifest) error {
+	descs := manifest.References()
+	subject, ok, err := GetManifestSubject(manifest)
+	if err != nil {
+		return err
+	}
+	if ok {
+		// Subjects are always manifests, whatever their actual media type.
+		descs = append(descs, distribution.Descriptor{
+			MediaType: _ociManifestType,
+			Digest:    digest.Digest(subject.String()),
+		})
+	}
+	for i, desc := range descs {
+		d, err := parseReferenceDigest(i, desc)
+		if err != nil {
+			return err
+		}
+		if w.visiting[d] {
+			return &ErrManifestCycle{Digest: d}
+		}
+		if w.visited[d] {
+			continue
+		}
+		w.visited[d] = true
+		if err := w.visit(d); err != nil {
+			return err
+		}
+		if _, isManifest := ManifestTypeFromMediaType(desc.MediaType); !isManifest || w.resolve == nil {
+			continue
+		}
+		child, err := w.resolve(d)
+		if err != nil {
+			return fmt.Errorf("resolve manifest %s: %s", d, err)
+		}
+		w.visiting[d] = true
+		err = w.walk(child)
+		delete(w.visiting, d)
+		if err != nil {
+			return fmt.Errorf("manifest %s: %w", d, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+// Copyright (c) 2016-2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package dockerutil_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/docker/distribution"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/kraken/core"
+	"github.com/uber/kraken/utils/dockerutil"
+)
+
+func TestWalkManifestReferences(t *testing.T) {
+	require := require.New(t)
+
+	digests := core.DigestListFixture(5)
+	config, shared, l1, l2, config2 := digests[0], digests[1], digests[2], digests[3], digests[4]
+	amd64, amd64Manifest := manifestFixture(t, config, shared, l1)
+	arm64, arm64Manifest := manifestFixture(t, config2, shared, l2)
+	_, index := indexFixture(t, amd64, arm64)
+	resolve := fetcherFixture(map[core.Digest]distribution.Manifest{
+		amd64: amd64Manifest,
+		arm64: arm64Manifest,
+	})
+
+	var visited []core.Digest
+	err := dockerutil.WalkManifestReferences(index, resolve, func(d core.Digest) error {
+		visited = append(visited, d)
+		return nil
+	})
+	require.NoError(err)
+	// Depth-first, with the shared layer visited once.
+	require.Equal([]core.Digest{amd64, config, shared, l1, arm64, config2, l2}, visited)
+
+	// Without resolve, only the references of the index are visited.
+	visited = nil
+	err = dockerutil.WalkManifestReferences(index, nil, func(d core.Digest) error {
+		visited = append(visited, d)
+		return nil
+	})
+	require.NoError(err)
+	require.Equal([]core.Digest{amd64, arm64}, visited)
+}
+
+func TestWalkManifestReferencesSubject(t *testing.T) {
+	require := require.New(t)
+
+	config, layer := core.DigestFixture(), core.DigestFixture()
+	subject, subjectManifest := manifestFixture(t, config, layer, layer)
+	signature := signatureFixture(t, subject.String())
+
+	var visited []core.Digest
+	err := dockerutil.WalkManifestReferences(signature, fetcherFixture(
+		map[core.Digest]distribution.Manifest{subject: subjectManifest}), func(d core.Digest) error {
+		visited = append(visited, d)
+		return nil
+	})
+	require.NoError(err)
+	require.Contains(visited, subject)
+	require.Contains(visited, config)
+	require.Contains(visited, layer)
+}
+
+func TestWalkManifestReferencesErrors(t *testing.T) {
+	amd64, amd64Manifest := manifestFixture(t, core.DigestFixture(), core.DigestFixture(), core.DigestFixture())
+	cyclic := core.DigestFixture()
+	_, index := indexFixture(t, amd64, cyclic)
+	_, cyclicIndex := indexFixture(t, cyclic)
+	noop := func(core.Digest) error { return nil }
+
+	t.Run("cycle", func(t *testing.T) {
+		err := dockerutil.WalkManifestReferences(index, fetcherFixture(
+			map[core.Digest]distribution.Manifest{amd64: amd64Manifest, cyclic: cyclicIndex}), noop)
+		var cycle *dockerutil.ErrManifestCycle
+		require.True(t, errors.As(err, &cycle))
+		require.Equal(t, cyclic, cycle.Digest)
+	})
+
+	t.Run("resolve error", func(t *testing.T) {
+		err := dockerutil.WalkManifestReferences(index, fetcherFixture(
+			map[core.Digest]distribution.Manifest{amd64: amd64Manifest}), noop)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), cyclic.String())
+	})
+
+	t.Run("visit error", func(t *testing.T) {
+		stop := errors.New("stop")
+		var visited int
+		err := dockerutil.WalkManifestReferences(amd64Manifest, nil, func(core.Digest) error {
+			visited++
+			return stop
+		})
+		require.Equal(t, stop, err)
+		require.Equal(t, 1, visited)
+	})
+}
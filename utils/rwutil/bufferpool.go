@@ -0,0 +1,66 @@
+// Copyright (c) 2016-2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package rwutil
+
+import (
+	"io"
+	"sync"
+)
+
+// BufferPool recycles fixed size buffers for copying between readers and
+// writers. A nil or zero sized BufferPool copies with io.Copy.
+type BufferPool struct {
+	size int
+	pool sync.Pool
+}
+
+// NewBufferPool creates a new BufferPool of size byte buffers.
+func NewBufferPool(size int) *BufferPool {
+	p := &BufferPool{size: size}
+	p.pool.New = func() interface{} {
+		b := make([]byte, size)
+		return &b
+	}
+	return p
+}
+
+// Size returns the size of the buffers in p.
+func (p *BufferPool) Size() int {
+	if p == nil {
+		return 0
+	}
+	return p.size
+}
+
+// Copy copies src to dst through a pooled buffer. Unlike io.CopyBuffer, the
+// buffer is used even if src implements io.WriterTo or dst implements
+// io.ReaderFrom, such that reads and writes are sized by the pool.
+func (p *BufferPool) Copy(dst io.Writer, src io.Reader) (int64, error) {
+	if p.Size() <= 0 {
+		return io.Copy(dst, src)
+	}
+	b := p.pool.Get().(*[]byte)
+	defer p.pool.Put(b)
+	return io.CopyBuffer(writerOnly{dst}, readerOnly{src}, *b)
+}
+
+// readerOnly hides any io.WriterTo implementation of the wrapped reader.
+type readerOnly struct {
+	io.Reader
+}
+
+// writerOnly hides any io.ReaderFrom implementation of the wrapped writer.
+type writerOnly struct {
+	io.Writer
+}
@@ -0,0 +1,89 @@
+// Copyright (c) 2016-2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package rwutil
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/uber/kraken/utils/randutil"
+)
+
+// countingWriter records the size of every write.
+type countingWriter struct {
+	bytes.Buffer
+	writes []int
+}
+
+func (w *countingWriter) Write(b []byte) (int, error) {
+	w.writes = append(w.writes, len(b))
+	return w.Buffer.Write(b)
+}
+
+func TestBufferPoolCopy(t *testing.T) {
+	for _, size := range []int{0, 1, 7, 64, 4096} {
+		t.Run(fmt.Sprintf("size_%d", size), func(t *testing.T) {
+			require := require.New(t)
+
+			data := randutil.Text(1000)
+			p := NewBufferPool(size)
+
+			for i := 0; i < 3; i++ {
+				var dst countingWriter
+				n, err := p.Copy(&dst, bytes.NewReader(data))
+				require.NoError(err)
+				require.Equal(int64(len(data)), n)
+				require.Equal(data, dst.Bytes())
+				if size > 0 {
+					for _, w := range dst.writes {
+						require.True(w <= size)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestBufferPoolNilCopies(t *testing.T) {
+	require := require.New(t)
+
+	data := randutil.Text(32)
+
+	var p *BufferPool
+	var dst bytes.Buffer
+	_, err := p.Copy(&dst, bytes.NewReader(data))
+	require.NoError(err)
+	require.Equal(data, dst.Bytes())
+}
+
+func BenchmarkBufferPoolCopy(b *testing.B) {
+	data := randutil.Text(8 << 20)
+	for _, size := range []int{32 << 10, 1 << 20} {
+		b.Run(fmt.Sprintf("size_%d", size), func(b *testing.B) {
+			p := NewBufferPool(size)
+			b.SetBytes(int64(len(data)))
+			b.ReportAllocs()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					if _, err := p.Copy(io.Discard, bytes.NewReader(data)); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		})
+	}
+}